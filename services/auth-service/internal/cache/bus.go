@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/icl00ud/velure/shared/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// Channel is the Redis pub/sub channel invalidations travel on.
+const Channel = "auth:cache:invalidate"
+
+// Invalidation tells every replica to drop entries from the cache named
+// Cache: the given Keys, the entries belonging to UserID, or with All set
+// everything. What a UserID matches is up to the cache's handler.
+type Invalidation struct {
+	Cache  string   `json:"cache"`
+	Keys   []string `json:"keys,omitempty"`
+	UserID uint     `json:"userId,omitempty"`
+	All    bool     `json:"all,omitempty"`
+	Origin string   `json:"origin"`
+}
+
+// Bus carries invalidations between replicas over Redis pub/sub. Each
+// replica applies an invalidation to its own cache first and then publishes
+// it; Run delivers the ones published by other replicas to the handler
+// registered for the cache. Delivery is best effort: when the subscription
+// drops, every registered cache is flushed once it is back, since messages
+// sent in between are lost. A nil Bus, or one without a Redis client, only
+// keeps invalidations local.
+type Bus struct {
+	redis      *redis.Client
+	origin     string
+	retryDelay time.Duration
+
+	mu       sync.RWMutex
+	handlers map[string]func(Invalidation)
+}
+
+// NewBus returns a Bus publishing on client.
+func NewBus(client *redis.Client) *Bus {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return &Bus{
+		redis:      client,
+		origin:     hex.EncodeToString(id),
+		retryDelay: time.Second,
+		handlers:   make(map[string]func(Invalidation)),
+	}
+}
+
+func (b *Bus) enabled() bool {
+	return b != nil && b.redis != nil
+}
+
+// Handle registers fn to apply invalidations of the named cache on this
+// replica. Register handlers before calling Run.
+func (b *Bus) Handle(cacheName string, fn func(Invalidation)) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[cacheName] = fn
+}
+
+// Publish sends inv to the other replicas.
+func (b *Bus) Publish(ctx context.Context, inv Invalidation) error {
+	if !b.enabled() {
+		return nil
+	}
+	inv.Origin = b.origin
+	payload, err := json.Marshal(inv)
+	if err != nil {
+		return fmt.Errorf("encode cache invalidation: %w", err)
+	}
+	if err := b.redis.Publish(ctx, Channel, payload).Err(); err != nil {
+		return fmt.Errorf("publish cache invalidation: %w", err)
+	}
+	return nil
+}
+
+// Run subscribes to Channel and applies incoming invalidations until ctx is
+// done.
+func (b *Bus) Run(ctx context.Context) {
+	if !b.enabled() {
+		return
+	}
+
+	sub := b.redis.Subscribe(ctx, Channel)
+	defer sub.Close()
+
+	subscribed := false
+	for {
+		msg, err := sub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Warn("cache invalidation subscription failed", logger.Err(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(b.retryDelay):
+			}
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			// Receive resubscribes after a reconnect; whatever was published
+			// meanwhile is gone.
+			if m.Kind == "subscribe" {
+				if subscribed {
+					b.flushAll()
+				}
+				subscribed = true
+			}
+		case *redis.Message:
+			b.dispatch(m.Payload)
+		}
+	}
+}
+
+func (b *Bus) dispatch(payload string) {
+	var inv Invalidation
+	if err := json.Unmarshal([]byte(payload), &inv); err != nil {
+		logger.Warn("ignoring malformed cache invalidation", logger.Err(err))
+		return
+	}
+	if inv.Origin == b.origin {
+		return
+	}
+
+	b.mu.RLock()
+	fn := b.handlers[inv.Cache]
+	b.mu.RUnlock()
+	if fn != nil {
+		fn(inv)
+	}
+}
+
+func (b *Bus) flushAll() {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for name, fn := range b.handlers {
+		fn(Invalidation{Cache: name, All: true})
+	}
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func startBus(t *testing.T, ctx context.Context, mr *miniredis.Miniredis) (*Bus, chan Invalidation) {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	received := make(chan Invalidation, 10)
+	bus := NewBus(client)
+	bus.retryDelay = 10 * time.Millisecond
+	bus.Handle("token", func(inv Invalidation) { received <- inv })
+	go bus.Run(ctx)
+	return bus, received
+}
+
+func waitForSubscribers(t *testing.T, mr *miniredis.Miniredis, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for mr.PubSubNumSub(Channel)[Channel] < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d subscribers on %s", n, Channel)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func receive(t *testing.T, ch <-chan Invalidation) Invalidation {
+	t.Helper()
+	select {
+	case inv := <-ch:
+		return inv
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected an invalidation")
+		return Invalidation{}
+	}
+}
+
+func TestBus_DeliversToOtherReplicas(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first, fromFirst := startBus(t, ctx, mr)
+	_, fromSecond := startBus(t, ctx, mr)
+	waitForSubscribers(t, mr, 2)
+
+	if err := first.Publish(ctx, Invalidation{Cache: "token", Keys: []string{"a", "b"}}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if err := first.Publish(ctx, Invalidation{Cache: "other", UserID: 7}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if err := first.Publish(ctx, Invalidation{Cache: "token", UserID: 7}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	if inv := receive(t, fromSecond); len(inv.Keys) != 2 || inv.Keys[0] != "a" || inv.Keys[1] != "b" {
+		t.Fatalf("unexpected invalidation %+v", inv)
+	}
+	// Caches without a handler are skipped.
+	if inv := receive(t, fromSecond); inv.UserID != 7 || inv.Cache != "token" {
+		t.Fatalf("unexpected invalidation %+v", inv)
+	}
+	select {
+	case inv := <-fromFirst:
+		t.Fatalf("the publishing replica already applied %+v", inv)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestBus_FlushesAfterReconnect(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, received := startBus(t, ctx, mr)
+	waitForSubscribers(t, mr, 1)
+
+	// Invalidations published while the subscription is down are lost.
+	mr.Close()
+	if err := mr.Restart(); err != nil {
+		t.Fatal(err)
+	}
+
+	if inv := receive(t, received); !inv.All || inv.Cache != "token" {
+		t.Fatalf("expected the cache to be flushed, got %+v", inv)
+	}
+}
+
+func TestBus_Disabled(t *testing.T) {
+	var nilBus *Bus
+	nilBus.Handle("token", func(Invalidation) {})
+	if err := nilBus.Publish(context.Background(), Invalidation{Cache: "token"}); err != nil {
+		t.Fatalf("a nil bus must drop invalidations, got %v", err)
+	}
+	nilBus.Run(context.Background())
+}
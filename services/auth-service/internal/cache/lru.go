@@ -0,0 +1,185 @@
+// Package cache holds the in-process caches of auth-service. Every cache is
+// an LRU bounded in size whose entries also expire after a TTL, and reports
+// hits, misses and evictions under its own name. Replicas keep their caches
+// coherent through a Bus: an entry invalidated on one replica is invalidated
+// on all of them.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
+)
+
+// Eviction reasons, also used as the metric label.
+const (
+	EvictCapacity    = "capacity"
+	EvictExpired     = "expired"
+	EvictInvalidated = "invalidated"
+)
+
+// DefaultSize is used when a cache is created with a size <= 0.
+const DefaultSize = 10000
+
+// LRU is a string-keyed cache holding at most size entries, each for at most
+// ttl. When full, the least recently used entry makes room for a new one.
+// A nil LRU, or one with ttl <= 0, caches nothing.
+type LRU[V any] struct {
+	name string
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu    sync.Mutex
+	order *list.List // front is the most recently used
+	items map[string]*list.Element
+}
+
+type entry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+// New returns an empty cache reported under name.
+func New[V any](name string, size int, ttl time.Duration) *LRU[V] {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &LRU[V]{
+		name:  name,
+		size:  size,
+		ttl:   ttl,
+		now:   time.Now,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Name returns the name the cache is reported and invalidated under.
+func (c *LRU[V]) Name() string {
+	return c.name
+}
+
+func (c *LRU[V]) enabled() bool {
+	return c != nil && c.ttl > 0
+}
+
+// Get returns the value stored under key, if it has not expired.
+func (c *LRU[V]) Get(key string) (V, bool) {
+	var zero V
+	if !c.enabled() {
+		return zero, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		metrics.CacheMisses.WithLabelValues(c.name).Inc()
+		return zero, false
+	}
+	e := el.Value.(*entry[V])
+	if !c.now().Before(e.expiresAt) {
+		c.remove(el, EvictExpired)
+		metrics.CacheMisses.WithLabelValues(c.name).Inc()
+		return zero, false
+	}
+	c.order.MoveToFront(el)
+	metrics.CacheHits.WithLabelValues(c.name).Inc()
+	return e.value, true
+}
+
+// Set stores value under key for the cache's TTL, evicting the least
+// recently used entry if the cache is full.
+func (c *LRU[V]) Set(key string, value V) {
+	c.SetUntil(key, value, time.Time{})
+}
+
+// SetUntil is Set for a value that goes stale at until: the entry expires
+// then if that comes before the TTL runs out. A zero until sets no limit.
+func (c *LRU[V]) SetUntil(key string, value V, until time.Time) {
+	if !c.enabled() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(c.ttl)
+	if !until.IsZero() && until.Before(expiresAt) {
+		expiresAt = until
+	}
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[V])
+		e.value, e.expiresAt = value, expiresAt
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&entry[V]{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back(), EvictCapacity)
+	}
+}
+
+// Delete drops the given keys and returns how many were cached.
+func (c *LRU[V]) Delete(keys ...string) int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for _, key := range keys {
+		if el, ok := c.items[key]; ok {
+			c.remove(el, EvictInvalidated)
+			n++
+		}
+	}
+	return n
+}
+
+// DeleteFunc drops every entry for which match returns true and returns how
+// many were dropped. It walks the whole cache, so it suits invalidations
+// that cannot be expressed as keys, not the request path.
+func (c *LRU[V]) DeleteFunc(match func(key string, value V) bool) int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		if e := el.Value.(*entry[V]); match(e.key, e.value) {
+			c.remove(el, EvictInvalidated)
+			n++
+		}
+		el = next
+	}
+	return n
+}
+
+// Len returns the number of entries, including expired ones not yet dropped.
+func (c *LRU[V]) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// remove must be called with c.mu held.
+func (c *LRU[V]) remove(el *list.Element, reason string) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*entry[V]).key)
+	metrics.CacheEvictions.WithLabelValues(c.name, reason).Inc()
+}
@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := New[int]("test", 2, time.Minute)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+
+	c.Set("c", 3)
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected b, the least recently used entry, to be evicted")
+	}
+	for key, want := range map[string]int{"a": 1, "c": 3} {
+		if got, ok := c.Get(key); !ok || got != want {
+			t.Fatalf("Get(%q) = %d, %v; want %d", key, got, ok, want)
+		}
+	}
+	if c.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", c.Len())
+	}
+}
+
+func TestLRU_ExpiresEntries(t *testing.T) {
+	now := time.Now()
+	c := New[string]("test", 10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set("key", "value")
+	now = now.Add(59 * time.Second)
+	if _, ok := c.Get("key"); !ok {
+		t.Fatal("expected the entry to live for the whole TTL")
+	}
+
+	// Overwriting restarts the TTL.
+	c.Set("key", "value")
+	now = now.Add(59 * time.Second)
+	if _, ok := c.Get("key"); !ok {
+		t.Fatal("expected Set to refresh the TTL")
+	}
+
+	now = now.Add(time.Second)
+	if _, ok := c.Get("key"); ok {
+		t.Fatal("expected the entry to expire")
+	}
+	if c.Len() != 0 {
+		t.Fatal("expected the expired entry to be dropped")
+	}
+}
+
+func TestLRU_SetUntil(t *testing.T) {
+	now := time.Now()
+	c := New[string]("test", 10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.SetUntil("short", "value", now.Add(10*time.Second))
+	c.SetUntil("long", "value", now.Add(time.Hour))
+	now = now.Add(10 * time.Second)
+	if _, ok := c.Get("short"); ok {
+		t.Fatal("expected the entry to expire at its limit")
+	}
+	if _, ok := c.Get("long"); !ok {
+		t.Fatal("expected the entry to live for the TTL")
+	}
+
+	now = now.Add(50 * time.Second)
+	if _, ok := c.Get("long"); ok {
+		t.Fatal("expected the TTL to cap a later limit")
+	}
+}
+
+func TestLRU_Delete(t *testing.T) {
+	c := New[int]("test", 10, time.Minute)
+	for i := 0; i < 6; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	if n := c.Delete("0", "1", "missing"); n != 2 {
+		t.Fatalf("Delete() = %d, want 2", n)
+	}
+	if n := c.DeleteFunc(func(_ string, v int) bool { return v%2 == 0 }); n != 2 {
+		t.Fatalf("DeleteFunc() = %d, want 2", n)
+	}
+	if c.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", c.Len())
+	}
+	for _, key := range []string{"3", "5"} {
+		if _, ok := c.Get(key); !ok {
+			t.Fatalf("expected %q to survive", key)
+		}
+	}
+}
+
+func TestLRU_Disabled(t *testing.T) {
+	c := New[int]("test", 10, 0)
+	c.Set("key", 1)
+	if _, ok := c.Get("key"); ok {
+		t.Fatal("a cache without TTL must not store anything")
+	}
+
+	var nilCache *LRU[int]
+	nilCache.Set("key", 1)
+	if _, ok := nilCache.Get("key"); ok || nilCache.Delete("key") != 0 || nilCache.Len() != 0 {
+		t.Fatal("a nil cache must be empty")
+	}
+}
+
+func BenchmarkLRU_Get(b *testing.B) {
+	c := New[int]("bench", 1000, time.Minute)
+	for i := 0; i < 1000; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Get(strconv.Itoa(i % 1000))
+			i++
+		}
+	})
+}
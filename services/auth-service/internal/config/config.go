@@ -4,23 +4,173 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	Environment string
 	Port        string
+	GRPCPort    string
 	JWT         JWTConfig
 	Session     SessionConfig
 	Database    DatabaseConfig
 	Redis       RedisConfig
 	Performance PerformanceConfig
+
+	PasswordReset     PasswordResetConfig
+	EmailVerification EmailVerificationConfig
+	Notifier          NotifierConfig
+	Lockout           LockoutConfig
+	TwoFactor         TwoFactorConfig
+	Events            EventsConfig
+	OIDC              OIDCConfig
+	RateLimit         RateLimitConfig
+	PasswordHash      PasswordHashConfig
+	PasswordPolicy    PasswordPolicyConfig
+	ClientCredentials ClientCredentialsConfig
+	Impersonation     ImpersonationConfig
+}
+
+// ImpersonationConfig configures the tokens admins get to act as another
+// user. TokenTTL is how long they live; they cannot be refreshed.
+type ImpersonationConfig struct {
+	TokenTTL time.Duration
+}
+
+// ClientCredentialsConfig configures the OAuth2 client_credentials grant
+// other services get access tokens with. TokenTTL is how long those tokens
+// live. Clients are registered at startup, creating or updating them, so a
+// deployment can provision its own services.
+type ClientCredentialsConfig struct {
+	TokenTTL time.Duration
+	Clients  []ServiceClientConfig
+}
+
+type ServiceClientConfig struct {
+	ID     string
+	Secret string
+	Scopes []string
+}
+
+// PasswordPolicyConfig sets the rules new passwords must meet. HistorySize
+// counts the current password, so 5 refuses it and the four before it; 0
+// allows reuse. When BreachedDir is set it holds the Pwned Passwords range
+// files (<first five hex digits of the SHA-1>.txt), and passwords seen at
+// least BreachedMinCount times in breaches are refused.
+type PasswordPolicyConfig struct {
+	MinLength          int
+	MaxLength          int
+	RequireUppercase   bool
+	RequireLowercase   bool
+	RequireDigit       bool
+	RequireSymbol      bool
+	RejectPersonalInfo bool
+	HistorySize        int
+	BreachedDir        string
+	BreachedMinCount   int
+}
+
+// PasswordHashConfig selects how new passwords are hashed: "argon2id" with
+// the Argon2 costs (memory in KiB), or "bcrypt" with Performance.BcryptCost.
+// Stored hashes made with another algorithm or other costs keep working and
+// are replaced on the user's next login.
+type PasswordHashConfig struct {
+	Algorithm         string
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+}
+
+// RateLimitConfig sets the per-IP request limits. Most routes share the
+// Default rate; logins (password, two-factor and social) get LoginPerMinute,
+// registrations RegisterPerHour and token introspection, which other services
+// call on every request, IntrospectRPS.
+type RateLimitConfig struct {
+	DefaultRPS      int
+	DefaultBurst    int
+	LoginPerMinute  int
+	RegisterPerHour int
+	IntrospectRPS   int
+}
+
+// OIDCConfig lists the OpenID Connect providers users may sign in with.
+// StateTTL is how long a started login may take to come back from the
+// provider.
+type OIDCConfig struct {
+	Providers []OIDCProviderConfig
+	StateTTL  time.Duration
+}
+
+// OIDCProviderConfig is one provider from OIDC_PROVIDERS. Name is used in the
+// login URLs and to tell linked identities apart.
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// EventsConfig selects where user events (user.deleted, ...) are published.
+// The "log" driver only writes them to the service log.
+type EventsConfig struct {
+	Driver   string
+	URL      string
+	Exchange string
+}
+
+// TwoFactorConfig controls TOTP two-factor authentication. Issuer is the
+// account label authenticator apps display; ChallengeTTL is how long the
+// password step of a two-step login stays valid.
+type TwoFactorConfig struct {
+	Issuer       string
+	ChallengeTTL time.Duration
+}
+
+// LockoutConfig controls brute-force protection on login. Failures are
+// counted per account and per client IP within Window; reaching the limit
+// locks that account or IP for Duration. Every failure also delays the
+// response, starting at BaseDelay and doubling up to MaxDelay.
+type LockoutConfig struct {
+	MaxAttempts   int
+	MaxIPAttempts int
+	Window        time.Duration
+	Duration      time.Duration
+	BaseDelay     time.Duration
+	MaxDelay      time.Duration
+}
+
+// PasswordResetConfig controls reset tokens. A reset request takes at least
+// MinResponseTime whether or not the account exists, so the response time
+// does not give the account away; 0 turns the padding off.
+type PasswordResetConfig struct {
+	ExpiresIn       string // e.g. "1h", "30m"
+	MinResponseTime time.Duration
+}
+
+// EmailVerificationConfig controls the email confirmation sent on sign-up.
+// ExpiresIn is how long a verification link stays valid; ResendInterval is
+// the minimum time between two verification emails for the same user.
+type EmailVerificationConfig struct {
+	ExpiresIn      time.Duration
+	ResendInterval time.Duration
+}
+
+// NotifierConfig selects how user-facing tokens (password reset links, ...)
+// are delivered. "log" and "file" are for local runs only.
+type NotifierConfig struct {
+	Driver   string
+	FilePath string
 }
 
 type PerformanceConfig struct {
-	BcryptCost    int
-	BcryptWorkers int
-	TokenCacheTTL int // seconds
-	EnableCache   bool
+	BcryptCost     int
+	BcryptWorkers  int
+	TokenCacheTTL  int // seconds
+	TokenCacheSize int // entries per replica
+	EnableCache    bool
 }
 
 type JWTConfig struct {
@@ -28,6 +178,13 @@ type JWTConfig struct {
 	ExpiresIn        string
 	RefreshSecret    string
 	RefreshExpiresIn string
+
+	// Access tokens are signed with the asymmetric keys in SigningKeysDir
+	// (one PEM file per kid). Without a directory an ephemeral key using
+	// SigningAlgorithm is generated at startup, which is only fine locally.
+	SigningKeysDir   string
+	ActiveKeyID      string
+	SigningAlgorithm string
 }
 
 type SessionConfig struct {
@@ -56,7 +213,22 @@ func Load() *Config {
 	bcryptCost, _ := strconv.Atoi(getEnv("BCRYPT_COST", "10"))
 	bcryptWorkers, _ := strconv.Atoi(getEnv("BCRYPT_WORKERS", "10"))
 	tokenCacheTTL, _ := strconv.Atoi(getEnv("TOKEN_CACHE_TTL", "300"))
+	tokenCacheSize, _ := strconv.Atoi(getEnv("TOKEN_CACHE_SIZE", "10000"))
 	enableCache := getEnv("ENABLE_TOKEN_CACHE", "true") == "true"
+	maxLoginAttempts, _ := strconv.Atoi(getEnv("LOGIN_MAX_ATTEMPTS", "5"))
+	maxLoginIPAttempts, _ := strconv.Atoi(getEnv("LOGIN_MAX_ATTEMPTS_PER_IP", "20"))
+	rateLimitRPS, _ := strconv.Atoi(getEnv("RATE_LIMIT_RPS", "100"))
+	rateLimitBurst, _ := strconv.Atoi(getEnv("RATE_LIMIT_BURST", "200"))
+	rateLimitLogin, _ := strconv.Atoi(getEnv("RATE_LIMIT_LOGIN_PER_MINUTE", "20"))
+	rateLimitRegister, _ := strconv.Atoi(getEnv("RATE_LIMIT_REGISTER_PER_HOUR", "10"))
+	rateLimitIntrospect, _ := strconv.Atoi(getEnv("RATE_LIMIT_INTROSPECT_RPS", "500"))
+	argon2Memory, _ := strconv.ParseUint(getEnv("ARGON2_MEMORY_KIB", "19456"), 10, 32)
+	argon2Iterations, _ := strconv.ParseUint(getEnv("ARGON2_ITERATIONS", "2"), 10, 32)
+	argon2Parallelism, _ := strconv.ParseUint(getEnv("ARGON2_PARALLELISM", "1"), 10, 8)
+	passwordMinLength, _ := strconv.Atoi(getEnv("PASSWORD_MIN_LENGTH", "6"))
+	passwordMaxLength, _ := strconv.Atoi(getEnv("PASSWORD_MAX_LENGTH", "128"))
+	passwordHistory, _ := strconv.Atoi(getEnv("PASSWORD_HISTORY", "5"))
+	breachedMinCount, _ := strconv.Atoi(getEnv("PWNED_PASSWORDS_MIN_COUNT", "1"))
 
 	redisHost := getEnv("REDIS_HOST", "localhost")
 	redisPort := getEnv("REDIS_PORT", "6379")
@@ -65,11 +237,15 @@ func Load() *Config {
 	return &Config{
 		Environment: getEnv("ENVIRONMENT", "development"),
 		Port:        getEnv("AUTH_SERVICE_APP_PORT", "3020"),
+		GRPCPort:    getEnv("AUTH_SERVICE_GRPC_PORT", "3021"),
 		JWT: JWTConfig{
 			Secret:           getEnv("JWT_SECRET", "your-secret-key"),
 			ExpiresIn:        getEnv("JWT_EXPIRES_IN", "1h"),
 			RefreshSecret:    getEnv("JWT_REFRESH_TOKEN_SECRET", "your-refresh-secret"),
 			RefreshExpiresIn: getEnv("JWT_REFRESH_TOKEN_EXPIRES_IN", "7d"),
+			SigningKeysDir:   getEnv("JWT_SIGNING_KEYS_DIR", ""),
+			ActiveKeyID:      getEnv("JWT_ACTIVE_KID", ""),
+			SigningAlgorithm: getEnv("JWT_SIGNING_ALG", "RS256"),
 		},
 		Session: SessionConfig{
 			Secret:    getEnv("SESSION_SECRET", "session-secret"),
@@ -89,12 +265,121 @@ func Load() *Config {
 			DB:       0,
 		},
 		Performance: PerformanceConfig{
-			BcryptCost:    bcryptCost,
-			BcryptWorkers: bcryptWorkers,
-			TokenCacheTTL: tokenCacheTTL,
-			EnableCache:   enableCache,
+			BcryptCost:     bcryptCost,
+			BcryptWorkers:  bcryptWorkers,
+			TokenCacheTTL:  tokenCacheTTL,
+			TokenCacheSize: tokenCacheSize,
+			EnableCache:    enableCache,
+		},
+		PasswordReset: PasswordResetConfig{
+			ExpiresIn:       getEnv("PASSWORD_RESET_EXPIRES_IN", "1h"),
+			MinResponseTime: getDuration("PASSWORD_RESET_MIN_RESPONSE_TIME", 500*time.Millisecond),
+		},
+		EmailVerification: EmailVerificationConfig{
+			ExpiresIn:      getDuration("EMAIL_VERIFICATION_EXPIRES_IN", 24*time.Hour),
+			ResendInterval: getDuration("EMAIL_VERIFICATION_RESEND_INTERVAL", time.Minute),
+		},
+		Notifier: NotifierConfig{
+			Driver:   getEnv("NOTIFIER_DRIVER", "log"),
+			FilePath: getEnv("NOTIFIER_FILE_PATH", ""),
 		},
+		Lockout: LockoutConfig{
+			MaxAttempts:   maxLoginAttempts,
+			MaxIPAttempts: maxLoginIPAttempts,
+			Window:        getDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+			Duration:      getDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+			BaseDelay:     getDuration("LOGIN_FAILURE_DELAY", 250*time.Millisecond),
+			MaxDelay:      getDuration("LOGIN_FAILURE_MAX_DELAY", 4*time.Second),
+		},
+		TwoFactor: TwoFactorConfig{
+			Issuer:       getEnv("TWO_FACTOR_ISSUER", "Velure"),
+			ChallengeTTL: getDuration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
+		},
+		Events: EventsConfig{
+			Driver:   getEnv("EVENTS_DRIVER", "log"),
+			URL:      getEnv("AUTH_RABBITMQ_URL", ""),
+			Exchange: getEnv("USER_EXCHANGE", "users"),
+		},
+		OIDC: OIDCConfig{
+			Providers: loadOIDCProviders(),
+			StateTTL:  getDuration("OIDC_STATE_TTL", 10*time.Minute),
+		},
+		RateLimit: RateLimitConfig{
+			DefaultRPS:      rateLimitRPS,
+			DefaultBurst:    rateLimitBurst,
+			LoginPerMinute:  rateLimitLogin,
+			RegisterPerHour: rateLimitRegister,
+			IntrospectRPS:   rateLimitIntrospect,
+		},
+		PasswordHash: PasswordHashConfig{
+			Algorithm:         getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+			Argon2Memory:      uint32(argon2Memory),
+			Argon2Iterations:  uint32(argon2Iterations),
+			Argon2Parallelism: uint8(argon2Parallelism),
+		},
+		ClientCredentials: ClientCredentialsConfig{
+			TokenTTL: getDuration("CLIENT_TOKEN_TTL", 5*time.Minute),
+			Clients:  loadServiceClients(),
+		},
+		Impersonation: ImpersonationConfig{
+			TokenTTL: getDuration("IMPERSONATION_TOKEN_TTL", 15*time.Minute),
+		},
+		PasswordPolicy: PasswordPolicyConfig{
+			MinLength:          passwordMinLength,
+			MaxLength:          passwordMaxLength,
+			RequireUppercase:   getEnv("PASSWORD_REQUIRE_UPPERCASE", "false") == "true",
+			RequireLowercase:   getEnv("PASSWORD_REQUIRE_LOWERCASE", "false") == "true",
+			RequireDigit:       getEnv("PASSWORD_REQUIRE_DIGIT", "false") == "true",
+			RequireSymbol:      getEnv("PASSWORD_REQUIRE_SYMBOL", "false") == "true",
+			RejectPersonalInfo: getEnv("PASSWORD_REJECT_PERSONAL_INFO", "true") == "true",
+			HistorySize:        passwordHistory,
+			BreachedDir:        getEnv("PWNED_PASSWORDS_DIR", ""),
+			BreachedMinCount:   breachedMinCount,
+		},
+	}
+}
+
+// loadOIDCProviders reads the providers named in OIDC_PROVIDERS (comma
+// separated). Each one is configured by OIDC_<NAME>_ISSUER, _CLIENT_ID,
+// _CLIENT_SECRET, _REDIRECT_URL and optionally _SCOPES (space separated).
+func loadOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		providers = append(providers, OIDCProviderConfig{
+			Name:         name,
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", ""),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "")),
+		})
+	}
+	return providers
+}
+
+// loadServiceClients reads the clients named in SERVICE_CLIENTS (comma
+// separated). Each one is configured by SERVICE_CLIENT_<NAME>_SECRET and
+// _SCOPES (space separated); the name is the client ID.
+func loadServiceClients() []ServiceClientConfig {
+	var clients []ServiceClientConfig
+	for _, name := range strings.Split(getEnv("SERVICE_CLIENTS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "SERVICE_CLIENT_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		clients = append(clients, ServiceClientConfig{
+			ID:     name,
+			Secret: getEnv(prefix+"SECRET", ""),
+			Scopes: strings.Fields(getEnv(prefix+"SCOPES", "")),
+		})
 	}
+	return clients
 }
 
 // defaultSecrets are the placeholder values baked into Load. They are fine for
@@ -105,9 +390,39 @@ var defaultSecrets = map[string]string{
 	"SESSION_SECRET":           "session-secret",
 }
 
-// Validate rejects configurations that are unsafe to run in production:
-// any JWT/session secret left empty or at its development default.
+// Validate rejects OIDC providers and service clients with missing settings,
+// unknown hashing and signing algorithms, impossible password lengths, and
+// configurations that are unsafe to run in production: any JWT/session secret
+// left empty or at its development default, or no persistent signing keys.
 func (c *Config) Validate() error {
+	for _, p := range c.OIDC.Providers {
+		if p.Issuer == "" || p.ClientID == "" || p.RedirectURL == "" {
+			return fmt.Errorf("config: OIDC provider %q needs an issuer, client ID and redirect URL", p.Name)
+		}
+	}
+
+	switch c.PasswordHash.Algorithm {
+	case "argon2id", "bcrypt":
+	default:
+		return fmt.Errorf("config: PASSWORD_HASH_ALGORITHM must be argon2id or bcrypt, got %q", c.PasswordHash.Algorithm)
+	}
+
+	switch c.JWT.SigningAlgorithm {
+	case "RS256", "EdDSA":
+	default:
+		return fmt.Errorf("config: JWT_SIGNING_ALG must be RS256 or EdDSA, got %q", c.JWT.SigningAlgorithm)
+	}
+
+	for _, client := range c.ClientCredentials.Clients {
+		if client.Secret == "" || len(client.Scopes) == 0 {
+			return fmt.Errorf("config: service client %q needs a secret and scopes", client.ID)
+		}
+	}
+
+	if p := c.PasswordPolicy; p.MinLength < 1 || p.MaxLength < p.MinLength {
+		return fmt.Errorf("config: PASSWORD_MIN_LENGTH must be at least 1 and PASSWORD_MAX_LENGTH at least PASSWORD_MIN_LENGTH, got %d and %d", p.MinLength, p.MaxLength)
+	}
+
 	if c.Environment != "production" {
 		return nil
 	}
@@ -122,6 +437,9 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("config: %s must be set to a non-default value in production", name)
 		}
 	}
+	if c.JWT.SigningKeysDir == "" {
+		return fmt.Errorf("config: JWT_SIGNING_KEYS_DIR must be set in production")
+	}
 	return nil
 }
 
@@ -131,3 +449,11 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getDuration parses values like "15m"; empty or invalid values fall back.
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d >= 0 {
+		return d
+	}
+	return defaultValue
+}
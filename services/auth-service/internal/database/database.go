@@ -69,9 +69,55 @@ func Connect(cfg config.DatabaseConfig) (*gorm.DB, error) {
 }
 
 func Migrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	if err := db.AutoMigrate(
 		&models.User{},
 		&models.Session{},
 		&models.PasswordReset{},
-	)
+		&models.EmailVerification{},
+		&models.UserIdentity{},
+		&models.AuditEvent{},
+		&models.PasswordHistory{},
+		&models.OAuthClient{},
+	); err != nil {
+		return err
+	}
+	return protectAuditEvents(db)
+}
+
+// protectAuditEvents installs the trigger of migrations/008_audit_events that
+// makes audit_events append-only, so the table is protected however the
+// schema was created. SQLite, used in tests and local runs, gets its own
+// equivalent.
+func protectAuditEvents(db *gorm.DB) error {
+	var statements []string
+	switch db.Dialector.Name() {
+	case "postgres":
+		statements = []string{
+			`CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql`,
+			`DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events`,
+			`CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only()`,
+		}
+	case "sqlite":
+		statements = []string{
+			`CREATE TRIGGER IF NOT EXISTS audit_events_no_update
+    BEFORE UPDATE ON audit_events
+    BEGIN SELECT RAISE(ABORT, 'audit_events is append-only'); END`,
+			`CREATE TRIGGER IF NOT EXISTS audit_events_no_delete
+    BEFORE DELETE ON audit_events
+    BEGIN SELECT RAISE(ABORT, 'audit_events is append-only'); END`,
+		}
+	}
+
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to protect audit_events: %w", err)
+		}
+	}
+	return nil
 }
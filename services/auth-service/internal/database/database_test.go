@@ -33,6 +33,55 @@ func TestConnect_WithURL(t *testing.T) {
 	if !db.Migrator().HasTable(&models.PasswordReset{}) {
 		t.Error("PasswordReset table was not created")
 	}
+	if !db.Migrator().HasTable(&models.EmailVerification{}) {
+		t.Error("EmailVerification table was not created")
+	}
+	if !db.Migrator().HasTable(&models.UserIdentity{}) {
+		t.Error("UserIdentity table was not created")
+	}
+	if !db.Migrator().HasTable(&models.AuditEvent{}) {
+		t.Error("AuditEvent table was not created")
+	}
+	if !db.Migrator().HasTable(&models.PasswordHistory{}) {
+		t.Error("PasswordHistory table was not created")
+	}
+	if !db.Migrator().HasTable(&models.OAuthClient{}) {
+		t.Error("OAuthClient table was not created")
+	}
+}
+
+func TestMigrate_AuditEventsAreAppendOnly(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() failed: %v", err)
+	}
+	// Running it again, as every start does, must keep working.
+	if err := Migrate(db); err != nil {
+		t.Fatalf("second Migrate() failed: %v", err)
+	}
+
+	event := &models.AuditEvent{Type: "login.succeeded", Email: "user@example.com"}
+	if err := db.Create(event).Error; err != nil {
+		t.Fatalf("insert audit event: %v", err)
+	}
+
+	if err := db.Model(event).Update("detail", "tampered").Error; err == nil {
+		t.Error("expected UPDATE on audit_events to be rejected")
+	}
+	if err := db.Delete(event).Error; err == nil {
+		t.Error("expected DELETE on audit_events to be rejected")
+	}
+
+	var stored models.AuditEvent
+	if err := db.First(&stored, event.ID).Error; err != nil {
+		t.Fatalf("audit event gone: %v", err)
+	}
+	if stored.Detail != "" {
+		t.Fatalf("audit event changed: %+v", stored)
+	}
 }
 
 func TestConnect_WithDSNComponents(t *testing.T) {
@@ -96,6 +145,11 @@ func TestMigrate(t *testing.T) {
 		&models.User{},
 		&models.Session{},
 		&models.PasswordReset{},
+		&models.EmailVerification{},
+		&models.UserIdentity{},
+		&models.AuditEvent{},
+		&models.PasswordHistory{},
+		&models.OAuthClient{},
 	}
 
 	for _, table := range tables {
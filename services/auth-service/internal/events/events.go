@@ -0,0 +1,231 @@
+// Package events publishes domain events about users so other services can
+// react to them, e.g. drop data they keep for a deleted account. Events use
+// the same {"type","payload"} envelope as the order events and are routed on
+// a topic exchange by their type.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/icl00ud/velure/shared/logger"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	UserDeleted = "user.deleted"
+)
+
+// Event is the envelope consumers receive.
+type Event struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// UserDeletedPayload identifies the account that was closed. It carries no
+// personal data: consumers key their records by user ID.
+type UserDeletedPayload struct {
+	UserID    uint      `json:"userId"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// NewEvent wraps payload in an envelope of the given type.
+func NewEvent(eventType string, payload any) (Event, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, fmt.Errorf("events: encode %s payload: %w", eventType, err)
+	}
+	return Event{Type: eventType, Payload: body}, nil
+}
+
+// Publisher delivers events.
+type Publisher interface {
+	Publish(ctx context.Context, evt Event) error
+	Close() error
+}
+
+// Config selects and configures the publisher backend.
+type Config struct {
+	Driver   string // log (default) or rabbitmq
+	URL      string // AMQP URL, used by the rabbitmq driver
+	Exchange string // topic exchange, used by the rabbitmq driver
+}
+
+// New builds the publisher selected by cfg.Driver.
+func New(cfg Config) (Publisher, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Driver)) {
+	case "", "log":
+		return NewLogPublisher(), nil
+	case "rabbitmq":
+		if strings.TrimSpace(cfg.URL) == "" || strings.TrimSpace(cfg.Exchange) == "" {
+			return nil, fmt.Errorf("events: rabbitmq driver requires a URL and an exchange")
+		}
+		return NewRabbitMQPublisher(cfg.URL, cfg.Exchange)
+	default:
+		return nil, fmt.Errorf("events: unknown driver %q", cfg.Driver)
+	}
+}
+
+// LogPublisher writes events to the service log. It is meant for local runs
+// where no broker is available.
+type LogPublisher struct{}
+
+func NewLogPublisher() *LogPublisher {
+	return &LogPublisher{}
+}
+
+func (p *LogPublisher) Publish(_ context.Context, evt Event) error {
+	logger.Info("event", logger.String("type", evt.Type), logger.String("payload", string(evt.Payload)))
+	return nil
+}
+
+func (p *LogPublisher) Close() error {
+	return nil
+}
+
+type amqpChannel interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp091.Table) error
+	Confirm(noWait bool) error
+	NotifyPublish(confirm chan amqp091.Confirmation) chan amqp091.Confirmation
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp091.Publishing) error
+	Close() error
+}
+
+// dial opens a connection and a channel on it. Swapped out in tests.
+var dial = func(url string) (closer func() error, ch amqpChannel, err error) {
+	conn, err := amqp091.Dial(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn.Close, channel, nil
+}
+
+// RabbitMQPublisher publishes persistent messages with publisher confirms,
+// so Publish only returns nil once the broker has taken the event. A broken
+// connection is re-established once per Publish.
+type RabbitMQPublisher struct {
+	url            string
+	exchange       string
+	confirmTimeout time.Duration
+
+	mu        sync.Mutex
+	closeConn func() error
+	ch        amqpChannel
+	confirms  chan amqp091.Confirmation
+	closed    bool
+}
+
+func NewRabbitMQPublisher(url, exchange string) (*RabbitMQPublisher, error) {
+	p := &RabbitMQPublisher{url: url, exchange: exchange, confirmTimeout: 5 * time.Second}
+	if err := p.connect(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *RabbitMQPublisher) connect() error {
+	p.disconnect()
+
+	closeConn, ch, err := dial(p.url)
+	if err != nil {
+		return fmt.Errorf("events: dial rabbitmq: %w", err)
+	}
+	if err := ch.ExchangeDeclare(p.exchange, "topic", true, false, false, false, nil); err != nil {
+		ch.Close()
+		closeConn()
+		return fmt.Errorf("events: declare exchange: %w", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		closeConn()
+		return fmt.Errorf("events: enable confirms: %w", err)
+	}
+
+	p.closeConn = closeConn
+	p.ch = ch
+	p.confirms = ch.NotifyPublish(make(chan amqp091.Confirmation, 1))
+	return nil
+}
+
+func (p *RabbitMQPublisher) disconnect() {
+	if p.ch != nil {
+		_ = p.ch.Close()
+		p.ch = nil
+	}
+	if p.closeConn != nil {
+		_ = p.closeConn()
+		p.closeConn = nil
+	}
+}
+
+func (p *RabbitMQPublisher) Publish(ctx context.Context, evt Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return fmt.Errorf("events: publisher is closed")
+	}
+
+	body, err := json.Marshal(evt)
+	if err != nil {
+		return fmt.Errorf("events: encode event: %w", err)
+	}
+	msg := amqp091.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp091.Persistent,
+		Timestamp:    time.Now(),
+		Body:         body,
+	}
+
+	publish := func() error {
+		if p.ch == nil {
+			return amqp091.ErrClosed
+		}
+		return p.ch.PublishWithContext(ctx, p.exchange, evt.Type, false, false, msg)
+	}
+	if err := publish(); err != nil {
+		logger.Warn("event publish failed, reconnecting", logger.Err(err))
+		if recErr := p.connect(); recErr != nil {
+			return fmt.Errorf("events: publish %s: %w", evt.Type, err)
+		}
+		if err := publish(); err != nil {
+			return fmt.Errorf("events: publish %s: %w", evt.Type, err)
+		}
+	}
+
+	select {
+	case c, ok := <-p.confirms:
+		if !ok {
+			return fmt.Errorf("events: confirm channel closed")
+		}
+		if !c.Ack {
+			return fmt.Errorf("events: broker nacked %s", evt.Type)
+		}
+		return nil
+	case <-time.After(p.confirmTimeout):
+		return fmt.Errorf("events: confirm timeout after %s", p.confirmTimeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *RabbitMQPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	p.disconnect()
+	return nil
+}
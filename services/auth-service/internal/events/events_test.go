@@ -0,0 +1,176 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+type fakeChannel struct {
+	publishErrs []error // returned by successive publishes, then nil
+	nack        bool
+	published   []amqp091.Publishing
+	keys        []string
+	confirms    chan amqp091.Confirmation
+	closed      bool
+}
+
+func (f *fakeChannel) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp091.Table) error {
+	return nil
+}
+
+func (f *fakeChannel) Confirm(noWait bool) error { return nil }
+
+func (f *fakeChannel) NotifyPublish(confirm chan amqp091.Confirmation) chan amqp091.Confirmation {
+	f.confirms = confirm
+	return confirm
+}
+
+func (f *fakeChannel) PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp091.Publishing) error {
+	if len(f.publishErrs) > 0 {
+		err := f.publishErrs[0]
+		f.publishErrs = f.publishErrs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	f.published = append(f.published, msg)
+	f.keys = append(f.keys, key)
+	f.confirms <- amqp091.Confirmation{DeliveryTag: uint64(len(f.published)), Ack: !f.nack}
+	return nil
+}
+
+func (f *fakeChannel) Close() error {
+	f.closed = true
+	return nil
+}
+
+// stubDial makes every dial hand out the next channel.
+func stubDial(t *testing.T, channels ...*fakeChannel) *int {
+	t.Helper()
+	orig := dial
+	t.Cleanup(func() { dial = orig })
+
+	dials := 0
+	dial = func(string) (func() error, amqpChannel, error) {
+		if dials >= len(channels) {
+			return nil, nil, errors.New("broker down")
+		}
+		ch := channels[dials]
+		dials++
+		return func() error { return nil }, ch, nil
+	}
+	return &dials
+}
+
+func userDeleted(t *testing.T) Event {
+	t.Helper()
+	evt, err := NewEvent(UserDeleted, UserDeletedPayload{UserID: 7, DeletedAt: time.Unix(1700000000, 0).UTC()})
+	if err != nil {
+		t.Fatalf("NewEvent() error = %v", err)
+	}
+	return evt
+}
+
+func TestNew(t *testing.T) {
+	if p, err := New(Config{}); err != nil {
+		t.Fatalf("New() default error = %v", err)
+	} else if _, ok := p.(*LogPublisher); !ok {
+		t.Fatalf("New() default = %T, want *LogPublisher", p)
+	}
+
+	if _, err := New(Config{Driver: "rabbitmq", URL: "amqp://localhost"}); err == nil {
+		t.Fatal("New() rabbitmq driver without exchange should fail")
+	}
+	if _, err := New(Config{Driver: "kafka"}); err == nil {
+		t.Fatal("New() unknown driver should fail")
+	}
+}
+
+func TestRabbitMQPublisher_PublishesEnvelope(t *testing.T) {
+	ch := &fakeChannel{}
+	stubDial(t, ch)
+
+	p, err := NewRabbitMQPublisher("amqp://test", "users")
+	if err != nil {
+		t.Fatalf("NewRabbitMQPublisher() error = %v", err)
+	}
+	if err := p.Publish(context.Background(), userDeleted(t)); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	if len(ch.published) != 1 || ch.keys[0] != UserDeleted {
+		t.Fatalf("expected one message routed by type, got %v", ch.keys)
+	}
+	msg := ch.published[0]
+	if msg.DeliveryMode != amqp091.Persistent {
+		t.Fatal("events must be persistent")
+	}
+	var body struct {
+		Type    string `json:"type"`
+		Payload struct {
+			UserID uint `json:"userId"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if body.Type != UserDeleted || body.Payload.UserID != 7 {
+		t.Fatalf("unexpected body %s", msg.Body)
+	}
+}
+
+func TestRabbitMQPublisher_ReconnectsOnce(t *testing.T) {
+	broken := &fakeChannel{publishErrs: []error{amqp091.ErrClosed}}
+	fresh := &fakeChannel{}
+	dials := stubDial(t, broken, fresh)
+
+	p, err := NewRabbitMQPublisher("amqp://test", "users")
+	if err != nil {
+		t.Fatalf("NewRabbitMQPublisher() error = %v", err)
+	}
+	if err := p.Publish(context.Background(), userDeleted(t)); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if *dials != 2 || !broken.closed || len(fresh.published) != 1 {
+		t.Fatalf("expected the event on a fresh channel, dials=%d", *dials)
+	}
+
+	// With the broker gone for good the error is returned.
+	fresh.publishErrs = []error{amqp091.ErrClosed}
+	if err := p.Publish(context.Background(), userDeleted(t)); err == nil {
+		t.Fatal("expected an error when reconnecting fails")
+	}
+}
+
+func TestRabbitMQPublisher_Nack(t *testing.T) {
+	stubDial(t, &fakeChannel{nack: true})
+
+	p, _ := NewRabbitMQPublisher("amqp://test", "users")
+	if err := p.Publish(context.Background(), userDeleted(t)); err == nil {
+		t.Fatal("expected a nacked publish to fail")
+	}
+}
+
+func TestRabbitMQPublisher_Close(t *testing.T) {
+	ch := &fakeChannel{}
+	stubDial(t, ch)
+
+	p, _ := NewRabbitMQPublisher("amqp://test", "users")
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("second Close() error = %v", err)
+	}
+	if !ch.closed {
+		t.Fatal("expected the channel to be closed")
+	}
+	if err := p.Publish(context.Background(), userDeleted(t)); err == nil {
+		t.Fatal("expected Publish after Close to fail")
+	}
+}
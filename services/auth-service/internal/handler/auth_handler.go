@@ -1,17 +1,29 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/icl00ud/velure/services/auth-service/internal/lockout"
 	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
+	"github.com/icl00ud/velure/services/auth-service/internal/middleware"
 	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/passwordpolicy"
 	"github.com/icl00ud/velure/services/auth-service/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/icl00ud/velure/shared/auth"
 	"github.com/icl00ud/velure/shared/logger"
 )
 
@@ -61,12 +73,117 @@ func setAuthCookies(c *gin.Context, accessToken, refreshToken string) {
 		cookieMaxAge("JWT_REFRESH_TOKEN_EXPIRES_IN", 7*24*time.Hour), "/", "", cookieSecure(), true)
 }
 
+// oidcStateCookie binds a started social login to the browser that started
+// it. It holds a hash of the state so the state itself only travels through
+// the provider.
+const oidcStateCookie = "oidc_state"
+
+func hashOIDCState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+// oidcStateMatches reports whether state belongs to the login this browser
+// started.
+func oidcStateMatches(c *gin.Context, state string) bool {
+	cookie, err := c.Cookie(oidcStateCookie)
+	if err != nil || cookie == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(hashOIDCState(state))) == 1
+}
+
 func clearAuthCookies(c *gin.Context) {
 	c.SetSameSite(http.SameSiteLaxMode)
 	c.SetCookie(accessTokenCookie, "", -1, "/", "", cookieSecure(), true)
 	c.SetCookie(refreshTokenCookie, "", -1, "/", "", cookieSecure(), true)
 }
 
+// currentUserKey is the gin context key under which RequireRoles stores the
+// authenticated user.
+const currentUserKey = "currentUser"
+
+// accessTokenFromRequest reads the bearer token, falling back to the
+// access_token cookie set by Login.
+func accessTokenFromRequest(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); header != "" {
+		if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	if cookie, err := c.Cookie(accessTokenCookie); err == nil {
+		return cookie
+	}
+	return ""
+}
+
+// deviceFromRequest captures the client details recorded on a session and
+// in the audit trail.
+func deviceFromRequest(c *gin.Context) models.DeviceInfo {
+	return models.DeviceInfo{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+		RequestID: c.GetString(middleware.RequestIDKey),
+	}
+}
+
+// RequireRoles authenticates the request and aborts with 403 unless the user
+// holds one of roles (admin always passes). With no roles it only requires a
+// valid token. Roles are read from the user record, not the token, so a
+// demotion takes effect immediately.
+func (h *AuthHandler) RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := accessTokenFromRequest(c)
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		user, err := h.authService.ValidateAccessToken(token)
+		if err != nil || user == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		if !auth.HasAnyRole(user.Roles, roles...) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+
+		c.Set(currentUserKey, user)
+		c.Next()
+	}
+}
+
+// RequireSelfOrRoles is RequireRoles for per-user resources: the user named by
+// the :param path segment may access it regardless of roles.
+func (h *AuthHandler) RequireSelfOrRoles(param string, roles ...string) gin.HandlerFunc {
+	authenticate := h.RequireRoles()
+	return func(c *gin.Context) {
+		authenticate(c)
+		if c.IsAborted() {
+			return
+		}
+		user := c.MustGet(currentUserKey).(*models.User)
+		if strconv.FormatUint(uint64(user.ID), 10) != c.Param(param) && !auth.HasAnyRole(user.Roles, roles...) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+	}
+}
+
+// ForbidImpersonation keeps impersonation tokens away from routes that
+// change how the account signs in or whether it exists. It must run after
+// RequireRoles, which has verified the token, so the claims are only read.
+func (h *AuthHandler) ForbidImpersonation(c *gin.Context) {
+	claims := &auth.Claims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(accessTokenFromRequest(c), claims); err == nil && claims.IsImpersonated() {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not allowed while impersonating"})
+		return
+	}
+}
+
 // internalError logs the real cause and returns a generic 500 so database and
 // infrastructure details never reach the client.
 func internalError(c *gin.Context, err error) {
@@ -74,6 +191,18 @@ func internalError(c *gin.Context, err error) {
 	c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
 }
 
+// writePasswordViolation answers 400 with the broken password rules when err
+// is a password policy violation, and reports whether it did. "error" holds
+// the first message for clients that only show one.
+func writePasswordViolation(c *gin.Context, err error) bool {
+	var violation *passwordpolicy.ViolationError
+	if !errors.As(err, &violation) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "violations": violation.Violations})
+	return true
+}
+
 func (h *AuthHandler) Register(c *gin.Context) {
 	start := time.Now()
 
@@ -83,9 +212,15 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	req.Device = deviceFromRequest(c)
 
 	user, err := h.authService.CreateUser(req)
 	if err != nil {
+		if writePasswordViolation(c, err) {
+			metrics.RegistrationAttempts.WithLabelValues("invalid_request").Inc()
+			metrics.RegistrationDuration.Observe(time.Since(start).Seconds())
+			return
+		}
 		if err.Error() == "user already exists" {
 			metrics.RegistrationAttempts.WithLabelValues("conflict").Inc()
 			metrics.RegistrationDuration.Observe(time.Since(start).Seconds())
@@ -114,21 +249,43 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	req.Device = deviceFromRequest(c)
 
 	response, err := h.authService.Login(req)
 	if err != nil {
+		var locked *lockout.LockedError
+		if errors.As(err, &locked) {
+			metrics.LoginAttempts.WithLabelValues("locked").Inc()
+			metrics.LoginDuration.WithLabelValues("failure").Observe(time.Since(start).Seconds())
+			writeLocked(c, locked)
+			return
+		}
 		if err.Error() == "invalid credentials" {
 			metrics.LoginAttempts.WithLabelValues("invalid_credentials").Inc()
 			metrics.LoginDuration.WithLabelValues("failure").Observe(time.Since(start).Seconds())
 			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
 		}
+		if err.Error() == "account disabled" {
+			metrics.LoginAttempts.WithLabelValues("disabled").Inc()
+			metrics.LoginDuration.WithLabelValues("failure").Observe(time.Since(start).Seconds())
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		metrics.LoginAttempts.WithLabelValues("failure").Inc()
 		metrics.LoginDuration.WithLabelValues("failure").Observe(time.Since(start).Seconds())
 		internalError(c, err)
 		return
 	}
 
+	// Two-factor accounts get a challenge instead of tokens; no cookies yet.
+	if response.TwoFactorRequired {
+		metrics.LoginAttempts.WithLabelValues("two_factor_required").Inc()
+		metrics.LoginDuration.WithLabelValues("two_factor_required").Observe(time.Since(start).Seconds())
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
 	metrics.LoginAttempts.WithLabelValues("success").Inc()
 	metrics.LoginDuration.WithLabelValues("success").Observe(time.Since(start).Seconds())
 	metrics.TokenGenerations.Inc()
@@ -136,6 +293,247 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// CompleteTwoFactorLogin exchanges the challenge returned by Login plus a
+// TOTP or recovery code for the token pair.
+func (h *AuthHandler) CompleteTwoFactorLogin(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Device = deviceFromRequest(c)
+
+	response, err := h.authService.CompleteTwoFactorLogin(req)
+	if err != nil {
+		var locked *lockout.LockedError
+		if errors.As(err, &locked) {
+			writeLocked(c, locked)
+			return
+		}
+		switch err.Error() {
+		case "invalid or expired challenge", "invalid two-factor code":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		case "account disabled":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		internalError(c, err)
+		return
+	}
+
+	metrics.TokenGenerations.Inc()
+	setAuthCookies(c, response.AccessToken, response.RefreshToken)
+	c.JSON(http.StatusOK, response)
+}
+
+// StartOIDCLogin redirects the browser to the identity provider named in the
+// URL to start a social login, and remembers the login's state in a
+// short-lived cookie.
+func (h *AuthHandler) StartOIDCLogin(c *gin.Context) {
+	authURL, state, err := h.authService.StartOIDCLogin(c.Param("provider"))
+	if err != nil {
+		if err.Error() == "unknown identity provider" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		internalError(c, err)
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, hashOIDCState(state),
+		cookieMaxAge("OIDC_STATE_TTL", 10*time.Minute), "/", "", cookieSecure(), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// CompleteOIDCLogin exchanges the code and state the identity provider
+// redirected back with for the token pair, or a two-factor challenge. The
+// state must be the one StartOIDCLogin left in this browser's cookie, so an
+// attacker cannot sign a victim in by feeding them their own callback.
+func (h *AuthHandler) CompleteOIDCLogin(c *gin.Context) {
+	var req models.OIDCLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !oidcStateMatches(c, req.State) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired login state"})
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, "/", "", cookieSecure(), true)
+	req.Provider = c.Param("provider")
+	req.Device = deviceFromRequest(c)
+
+	response, err := h.authService.CompleteOIDCLogin(req)
+	if err != nil {
+		switch err.Error() {
+		case "unknown identity provider":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "invalid or expired login state":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "identity provider login failed":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case "identity provider did not verify the email", "account disabled":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case "account email not verified":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			internalError(c, err)
+		}
+		return
+	}
+
+	if response.TwoFactorRequired {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	metrics.TokenGenerations.Inc()
+	setAuthCookies(c, response.AccessToken, response.RefreshToken)
+	c.JSON(http.StatusOK, response)
+}
+
+// writeLocked answers a locked-out login with 423 and when to retry.
+func writeLocked(c *gin.Context, locked *lockout.LockedError) {
+	retryAfter := int(math.Ceil(locked.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusLocked, gin.H{"error": locked.Error(), "retryAfter": retryAfter})
+}
+
+// Refresh rotates the caller's refresh token and issues a new token pair.
+// Invalid and replayed tokens both clear the auth cookies so the SPA falls
+// back to the login screen.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	_ = c.ShouldBindJSON(&req) // body optional: cookie fallback below
+
+	if req.RefreshToken == "" {
+		if cookie, err := c.Cookie(refreshTokenCookie); err == nil {
+			req.RefreshToken = cookie
+		}
+	}
+	if req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh token is required"})
+		return
+	}
+
+	response, err := h.authService.RefreshSession(req.RefreshToken, deviceFromRequest(c))
+	if err != nil {
+		if err.Error() == "invalid refresh token" || err.Error() == "refresh token reuse detected" {
+			clearAuthCookies(c)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		internalError(c, err)
+		return
+	}
+
+	setAuthCookies(c, response.AccessToken, response.RefreshToken)
+	c.JSON(http.StatusOK, response)
+}
+
+// RequestPasswordReset always answers 202 for well-formed requests so the
+// response does not reveal whether the email is registered.
+func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
+	var req models.PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the account exists, a reset link has been sent"})
+}
+
+func (h *AuthHandler) ConfirmPasswordReset(c *gin.Context) {
+	var req models.ConfirmPasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ResetPassword(req.Token, req.Password, deviceFromRequest(c)); err != nil {
+		if writePasswordViolation(c, err) {
+			return
+		}
+		if err.Error() == "invalid or expired reset token" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		internalError(c, err)
+		return
+	}
+
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "password has been reset"})
+}
+
+// RequestEmailVerification sends the caller a new verification link. Resends
+// are throttled: 429 with Retry-After until the resend interval has passed.
+func (h *AuthHandler) RequestEmailVerification(c *gin.Context) {
+	user := c.MustGet(currentUserKey).(*models.User)
+
+	if err := h.authService.RequestEmailVerification(user.ID); err != nil {
+		var throttled *services.ThrottledError
+		if errors.As(err, &throttled) {
+			retryAfter := int(math.Ceil(throttled.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "retryAfter": retryAfter})
+			return
+		}
+		switch err.Error() {
+		case "email already verified":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			internalError(c, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "verification email sent"})
+}
+
+// ConfirmEmailVerification consumes a verification token from the email.
+func (h *AuthHandler) ConfirmEmailVerification(c *gin.Context) {
+	var req models.ConfirmEmailVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.VerifyEmail(req.Token); err != nil {
+		if err.Error() == "invalid or expired verification token" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email verified"})
+}
+
+// JWKS publishes the public keys access tokens are signed with so other
+// services can verify them without sharing a secret.
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	doc, err := h.authService.JWKS()
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, doc)
+}
+
 func (h *AuthHandler) ValidateToken(c *gin.Context) {
 	var req models.ValidateTokenRequest
 	_ = c.ShouldBindJSON(&req) // body optional: cookie fallback below
@@ -228,6 +626,177 @@ func (h *AuthHandler) GetUserByID(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
+func (h *AuthHandler) UpdateUserRoles(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req models.UpdateRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.authService.UpdateUserRoles(uint(id), req.Roles)
+	if err != nil {
+		switch {
+		case err.Error() == "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case strings.HasPrefix(err.Error(), "unknown role"), err.Error() == "at least one role is required":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			internalError(c, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func userFilterFromQuery(c *gin.Context) (models.UserFilter, error) {
+	filter := models.UserFilter{Query: strings.TrimSpace(c.Query("q"))}
+	for _, bound := range []struct {
+		param string
+		dst   *time.Time
+	}{{"createdSince", &filter.CreatedSince}, {"createdUntil", &filter.CreatedUntil}} {
+		if v := c.Query(bound.param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, errors.New("invalid " + bound.param + ": expected RFC 3339")
+			}
+			*bound.dst = t
+		}
+	}
+	for _, flag := range []struct {
+		param string
+		dst   **bool
+	}{{"verified", &filter.Verified}, {"disabled", &filter.Disabled}, {"locked", &filter.Locked}} {
+		if v := c.Query(flag.param); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return filter, errors.New("invalid " + flag.param + ": expected true or false")
+			}
+			*flag.dst = &b
+		}
+	}
+	return filter, nil
+}
+
+// SearchUsers pages through users for support staff. ?q= matches the start
+// of the email or name; pass nextCursor back as ?cursor= for the next page.
+func (h *AuthHandler) SearchUsers(c *gin.Context) {
+	filter, err := userFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit := 0
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+
+	page, err := h.authService.SearchUsers(filter, c.Query("sort"), c.Query("cursor"), limit)
+	if err != nil {
+		writeUserAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// DisableUser disables an account, signing it out everywhere at once. The
+// optional reason is kept in the audit trail.
+func (h *AuthHandler) DisableUser(c *gin.Context) {
+	admin := c.MustGet(currentUserKey).(*models.User)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req models.DisableUserRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	user, err := h.authService.DisableUser(admin.ID, uint(id), req.Reason, deviceFromRequest(c))
+	if err != nil {
+		writeUserAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// EnableUser re-enables a disabled account and lifts its login lockout.
+func (h *AuthHandler) EnableUser(c *gin.Context) {
+	admin := c.MustGet(currentUserKey).(*models.User)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	user, err := h.authService.EnableUser(admin.ID, uint(id), deviceFromRequest(c))
+	if err != nil {
+		writeUserAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ImpersonateUser issues the admin a short-lived access token to act as the
+// user. It is returned in the body only: setting it as a cookie would sign
+// the admin's browser out of their own account.
+func (h *AuthHandler) ImpersonateUser(c *gin.Context) {
+	admin := c.MustGet(currentUserKey).(*models.User)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req models.ImpersonateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+
+	token, err := h.authService.ImpersonateUser(admin.ID, uint(id), req.Reason, deviceFromRequest(c))
+	if err != nil {
+		writeUserAdminError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, token)
+}
+
+func writeUserAdminError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "invalid sort", "invalid cursor", "reason is required":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "user not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "cannot disable your own account", "cannot impersonate yourself",
+		"cannot impersonate a disabled account", "cannot impersonate an admin":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "audit log not enabled":
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		internalError(c, err)
+	}
+}
+
 func (h *AuthHandler) GetUserByEmail(c *gin.Context) {
 	email := c.Param("email")
 	if email == "" {
@@ -262,7 +831,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		return
 	}
 
-	if err := h.authService.Logout(req.RefreshToken); err != nil {
+	if err := h.authService.Logout(req.RefreshToken, deviceFromRequest(c)); err != nil {
 		internalError(c, err)
 		return
 	}
@@ -270,3 +839,420 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	clearAuthCookies(c)
 	c.JSON(http.StatusOK, gin.H{"message": "logout successful"})
 }
+
+// ListSessions returns the caller's signed-in devices.
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	user := c.MustGet(currentUserKey).(*models.User)
+
+	sessions, err := h.authService.ListSessions(user.ID, accessTokenFromRequest(c))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession signs one of the caller's devices out.
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	user := c.MustGet(currentUserKey).(*models.User)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session ID"})
+		return
+	}
+
+	if err := h.authService.RevokeSession(user.ID, uint(id)); err != nil {
+		if err.Error() == "session not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		internalError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeAllSessions signs the caller out on every device, this one included.
+func (h *AuthHandler) RevokeAllSessions(c *gin.Context) {
+	user := c.MustGet(currentUserKey).(*models.User)
+
+	if err := h.authService.RevokeAllSessions(user.ID); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "logged out everywhere"})
+}
+
+// UpdateProfile changes the caller's name, email or password. Changing the
+// password signs the caller's other devices out.
+func (h *AuthHandler) UpdateProfile(c *gin.Context) {
+	user := c.MustGet(currentUserKey).(*models.User)
+
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.Device = deviceFromRequest(c)
+	updated, err := h.authService.UpdateProfile(user.ID, accessTokenFromRequest(c), req)
+	if err != nil {
+		writeAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteAccount closes the caller's account and signs them out.
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	user := c.MustGet(currentUserKey).(*models.User)
+
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.DeleteAccount(user.ID, req.Password, deviceFromRequest(c)); err != nil {
+		writeAccountError(c, err)
+		return
+	}
+
+	clearAuthCookies(c)
+	c.Status(http.StatusNoContent)
+}
+
+func writeAccountError(c *gin.Context, err error) {
+	if writePasswordViolation(c, err) {
+		return
+	}
+	switch err.Error() {
+	case "current password is required",
+		"name is required",
+		"name must be at least 2 characters",
+		"name must be less than 100 characters",
+		"email is required",
+		"invalid email format",
+		"email must be less than 255 characters":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "current password is incorrect":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "email already in use":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "user not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		internalError(c, err)
+	}
+}
+
+// EnrollTwoFactor starts two-factor enrollment for the caller and returns the
+// secret to add to an authenticator app.
+func (h *AuthHandler) EnrollTwoFactor(c *gin.Context) {
+	user := c.MustGet(currentUserKey).(*models.User)
+
+	enrollment, err := h.authService.EnrollTwoFactor(user.ID)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTwoFactor enables two-factor authentication with a code from the
+// freshly enrolled app and returns the recovery codes.
+func (h *AuthHandler) ConfirmTwoFactor(c *gin.Context) {
+	user := c.MustGet(currentUserKey).(*models.User)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.authService.ConfirmTwoFactor(user.ID, req.Code)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, codes)
+}
+
+// DisableTwoFactor turns two-factor authentication off for the caller.
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	user := c.MustGet(currentUserKey).(*models.User)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.DisableTwoFactor(user.ID, req.Code); err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes replaces the caller's recovery codes.
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user := c.MustGet(currentUserKey).(*models.User)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(user.ID, req.Code)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, codes)
+}
+
+func writeTwoFactorError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "invalid two-factor code":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "two-factor authentication already enabled",
+		"two-factor authentication not enabled",
+		"two-factor authentication not enrolled":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "user not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		internalError(c, err)
+	}
+}
+
+// auditFilterFromQuery reads the filters shared by the audit endpoints:
+// userId, type and an RFC 3339 since/until window.
+func auditFilterFromQuery(c *gin.Context) (models.AuditEventFilter, error) {
+	var filter models.AuditEventFilter
+	if v := c.Query("userId"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || id == 0 {
+			return filter, errors.New("invalid userId")
+		}
+		filter.UserID = uint(id)
+	}
+	filter.Type = c.Query("type")
+	for _, bound := range []struct {
+		param string
+		dst   *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		if v := c.Query(bound.param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, errors.New("invalid " + bound.param + ": expected RFC 3339")
+			}
+			*bound.dst = t
+		}
+	}
+	return filter, nil
+}
+
+// ListAuditEvents returns a page of the security audit trail, newest first.
+// Pass nextCursor back as ?cursor= to get the following page.
+func (h *AuthHandler) ListAuditEvents(c *gin.Context) {
+	filter, err := auditFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit := 0
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+
+	page, err := h.authService.ListAuditEvents(filter, c.Query("cursor"), limit)
+	if err != nil {
+		writeAuditError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// ExportAuditEvents streams the audit trail as JSON lines, oldest first, for
+// ingestion by a SIEM. ?after= resumes an export after the last ID received.
+func (h *AuthHandler) ExportAuditEvents(c *gin.Context) {
+	filter, err := auditFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var after uint64
+	if v := c.Query("after"); v != "" {
+		if after, err = strconv.ParseUint(v, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid after"})
+			return
+		}
+	}
+
+	started := false
+	enc := json.NewEncoder(c.Writer)
+	written := 0
+	err = h.authService.ExportAuditEvents(filter, uint(after), func(event models.AuditEvent) error {
+		if !started {
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Status(http.StatusOK)
+			started = true
+		}
+		if err := enc.Encode(event); err != nil {
+			return err
+		}
+		if written++; written%100 == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		if !started {
+			writeAuditError(c, err)
+			return
+		}
+		// The status is already sent; the client sees a truncated stream and
+		// can resume with ?after= set to the last ID it received.
+		logger.Error("audit export aborted", logger.Err(err))
+		return
+	}
+	if !started {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+	}
+	c.Writer.Flush()
+}
+
+func writeAuditError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "invalid cursor":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "audit log not enabled":
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		internalError(c, err)
+	}
+}
+
+// IssueToken is the OAuth2 token endpoint for machine clients. Only the
+// client_credentials grant is supported. Clients authenticate with HTTP Basic
+// or the client_id and client_secret form fields, and errors carry the
+// RFC 6749 error codes.
+func (h *AuthHandler) IssueToken(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	switch c.PostForm("grant_type") {
+	case "client_credentials":
+	case "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": "grant_type is required"})
+		return
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+		return
+	}
+
+	// Basic credentials are form-encoded before being joined (RFC 6749 2.3.1).
+	clientID, clientSecret, basic := c.Request.BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = c.PostForm("client_id"), c.PostForm("client_secret")
+	}
+
+	token, err := h.authService.IssueClientToken(clientID, clientSecret, c.PostForm("scope"))
+	if err != nil {
+		switch err.Error() {
+		case "invalid client":
+			if basic {
+				c.Header("WWW-Authenticate", `Basic realm="velure"`)
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+		case "invalid scope":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_scope"})
+		default:
+			internalError(c, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
+
+// CreateClient registers a machine client. The response holds the client
+// secret, which cannot be retrieved later.
+func (h *AuthHandler) CreateClient(c *gin.Context) {
+	admin := c.MustGet(currentUserKey).(*models.User)
+
+	var req models.CreateOAuthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Device = deviceFromRequest(c)
+
+	client, err := h.authService.RegisterClient(admin.ID, req)
+	if err != nil {
+		writeClientError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, client)
+}
+
+func (h *AuthHandler) ListClients(c *gin.Context) {
+	clients, err := h.authService.ListClients()
+	if err != nil {
+		writeClientError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, clients)
+}
+
+// DeleteClient removes a machine client; it can no longer get tokens.
+func (h *AuthHandler) DeleteClient(c *gin.Context) {
+	admin := c.MustGet(currentUserKey).(*models.User)
+
+	if err := h.authService.DeleteClient(admin.ID, c.Param("clientId"), deviceFromRequest(c)); err != nil {
+		writeClientError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func writeClientError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "invalid client ID",
+		err.Error() == "at least one scope is required",
+		strings.HasPrefix(err.Error(), "unknown scope"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err.Error() == "client not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err.Error() == "client already exists":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err.Error() == "clients not enabled":
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		internalError(c, err)
+	}
+}
@@ -6,12 +6,20 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/icl00ud/velure/services/auth-service/internal/lockout"
 	"github.com/icl00ud/velure/services/auth-service/internal/mocks"
 	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/passwordpolicy"
+	"github.com/icl00ud/velure/services/auth-service/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/icl00ud/velure/shared/auth"
 	"go.uber.org/mock/gomock"
 )
 
@@ -84,6 +92,35 @@ func TestAuthHandler_Register(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "password policy violation",
+			requestBody: models.CreateUserRequest{
+				Name:     "Test User",
+				Email:    "test@example.com",
+				Password: "test",
+			},
+			setupMock: func() {
+				policy := passwordpolicy.Policy{MinLength: 8, RequireDigit: true, RejectPersonalInfo: true}
+				mockService.EXPECT().
+					CreateUser(gomock.Any()).
+					Return(nil, policy.Check("test", passwordpolicy.Account{Email: "test@example.com"}))
+			},
+			expectedStatus: http.StatusBadRequest,
+			checkResponse: func(t *testing.T, body map[string]interface{}) {
+				if body["error"] != "password must be at least 8 characters" {
+					t.Errorf("Expected the first violation as error, got %v", body["error"])
+				}
+				violations, _ := body["violations"].([]interface{})
+				var codes []string
+				for _, v := range violations {
+					codes = append(codes, v.(map[string]interface{})["code"].(string))
+				}
+				want := []string{passwordpolicy.CodeTooShort, passwordpolicy.CodeMissingDigit, passwordpolicy.CodeContainsEmail}
+				if strings.Join(codes, ",") != strings.Join(want, ",") {
+					t.Errorf("Expected violations %v, got %v", want, codes)
+				}
+			},
+		},
 		{
 			name:           "invalid request body",
 			requestBody:    "invalid json",
@@ -203,6 +240,24 @@ func TestAuthHandler_Login(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "disabled account",
+			requestBody: models.LoginRequest{
+				Email:    "user@example.com",
+				Password: "password123",
+			},
+			setupMock: func() {
+				mockService.EXPECT().
+					Login(gomock.Any()).
+					Return(nil, errors.New("account disabled"))
+			},
+			expectedStatus: http.StatusForbidden,
+			checkResponse: func(t *testing.T, body map[string]interface{}) {
+				if body["error"] != "account disabled" {
+					t.Errorf("Expected error 'account disabled', got %v", body["error"])
+				}
+			},
+		},
 		{
 			name:           "invalid request body",
 			requestBody:    "invalid json",
@@ -695,7 +750,7 @@ func TestAuthHandler_Logout(t *testing.T) {
 			},
 			setupMock: func() {
 				mockService.EXPECT().
-					Logout("valid-refresh-token").
+					Logout("valid-refresh-token", gomock.Any()).
 					Return(nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -723,7 +778,7 @@ func TestAuthHandler_Logout(t *testing.T) {
 			},
 			setupMock: func() {
 				mockService.EXPECT().
-					Logout("token").
+					Logout("token", gomock.Any()).
 					Return(errors.New("database error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
@@ -870,7 +925,7 @@ func TestAuthHandler_LogoutClearsCookiesAndReadsRefreshFromCookie(t *testing.T)
 	mockService := mocks.NewMockAuthServiceInterface(ctrl)
 	handler := NewAuthHandler(mockService)
 
-	mockService.EXPECT().Logout("ref-456").Return(nil)
+	mockService.EXPECT().Logout("ref-456", gomock.Any()).Return(nil)
 
 	router := setupTestRouter()
 	router.DELETE("/sessions/current", handler.Logout)
@@ -924,3 +979,1457 @@ func TestAuthHandler_ValidateTokenFallsBackToCookie(t *testing.T) {
 		t.Fatal("expected token from cookie to validate")
 	}
 }
+
+func TestAuthHandler_Refresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	tests := []struct {
+		name           string
+		requestBody    string
+		cookie         string
+		setupMock      func()
+		expectedStatus int
+		expectCleared  bool
+	}{
+		{
+			name:        "token from body",
+			requestBody: `{"refreshToken":"ref-body"}`,
+			setupMock: func() {
+				mockService.EXPECT().
+					RefreshSession("ref-body", gomock.Any()).
+					Return(&models.LoginResponse{AccessToken: "new-acc", RefreshToken: "new-ref"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "token from cookie",
+			requestBody: `{}`,
+			cookie:      "ref-cookie",
+			setupMock: func() {
+				mockService.EXPECT().
+					RefreshSession("ref-cookie", gomock.Any()).
+					Return(&models.LoginResponse{AccessToken: "new-acc", RefreshToken: "new-ref"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing token",
+			requestBody:    `{}`,
+			setupMock:      func() {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "reused token",
+			requestBody: `{"refreshToken":"ref-old"}`,
+			setupMock: func() {
+				mockService.EXPECT().
+					RefreshSession("ref-old", gomock.Any()).
+					Return(nil, errors.New("refresh token reuse detected"))
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectCleared:  true,
+		},
+		{
+			name:        "internal error",
+			requestBody: `{"refreshToken":"ref-err"}`,
+			setupMock: func() {
+				mockService.EXPECT().
+					RefreshSession("ref-err", gomock.Any()).
+					Return(nil, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			router := setupTestRouter()
+			router.POST("/sessions/refresh", handler.Refresh)
+
+			req := httptest.NewRequest(http.MethodPost, "/sessions/refresh", bytes.NewBufferString(tt.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "refresh_token", Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+
+			cookies := map[string]*http.Cookie{}
+			for _, c := range w.Result().Cookies() {
+				cookies[c.Name] = c
+			}
+			if tt.expectedStatus == http.StatusOK {
+				if c := cookies["refresh_token"]; c == nil || c.Value != "new-ref" {
+					t.Fatalf("expected rotated refresh_token cookie, got %+v", c)
+				}
+			}
+			if tt.expectCleared {
+				if c := cookies["refresh_token"]; c == nil || c.MaxAge != -1 {
+					t.Fatalf("expected refresh_token cookie cleared, got %+v", c)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthHandler_RequestPasswordReset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	router := setupTestRouter()
+	router.POST("/password-resets", handler.RequestPasswordReset)
+
+	mockService.EXPECT().RequestPasswordReset(gomock.Any(), "user@example.com").Return(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/password-resets", bytes.NewBufferString(`{"email":"user@example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/password-resets", bytes.NewBufferString(`{"email":"not-an-email"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid email, got %d", w.Code)
+	}
+}
+
+func TestAuthHandler_ConfirmPasswordReset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	tests := []struct {
+		name           string
+		body           string
+		setupMock      func()
+		expectedStatus int
+	}{
+		{
+			name: "success",
+			body: `{"token":"tok","password":"new-password"}`,
+			setupMock: func() {
+				mockService.EXPECT().ResetPassword("tok", "new-password", gomock.Any()).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "invalid token",
+			body: `{"token":"bad","password":"new-password"}`,
+			setupMock: func() {
+				mockService.EXPECT().ResetPassword("bad", "new-password", gomock.Any()).
+					Return(errors.New("invalid or expired reset token"))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "password too short",
+			body: `{"token":"tok","password":"123"}`,
+			setupMock: func() {
+				mockService.EXPECT().ResetPassword("tok", "123", gomock.Any()).
+					Return(passwordpolicy.Default.Check("123", passwordpolicy.Account{}))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "internal error",
+			body: `{"token":"tok","password":"new-password"}`,
+			setupMock: func() {
+				mockService.EXPECT().ResetPassword("tok", "new-password", gomock.Any()).
+					Return(errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			router := setupTestRouter()
+			router.POST("/password-resets/confirm", handler.ConfirmPasswordReset)
+
+			req := httptest.NewRequest(http.MethodPost, "/password-resets/confirm", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthHandler_RequireRoles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	mockService.EXPECT().ValidateAccessToken("customer-token").
+		Return(&models.User{ID: 1, Roles: models.Roles{"customer"}}, nil).AnyTimes()
+	mockService.EXPECT().ValidateAccessToken("admin-token").
+		Return(&models.User{ID: 2, Roles: models.Roles{"admin"}}, nil).AnyTimes()
+	mockService.EXPECT().ValidateAccessToken("bad-token").
+		Return(nil, errors.New("invalid token")).AnyTimes()
+
+	router := setupTestRouter()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/admin", handler.RequireRoles("admin"), ok)
+	router.GET("/users/:id", handler.RequireSelfOrRoles("id", "admin"), ok)
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		cookie string
+		want   int
+	}{
+		{"no token", "/admin", "", "", http.StatusUnauthorized},
+		{"malformed header", "/admin", "Token admin-token", "", http.StatusUnauthorized},
+		{"invalid token", "/admin", "Bearer bad-token", "", http.StatusUnauthorized},
+		{"missing role", "/admin", "Bearer customer-token", "", http.StatusForbidden},
+		{"admin via header", "/admin", "Bearer admin-token", "", http.StatusOK},
+		{"admin via cookie", "/admin", "", "admin-token", http.StatusOK},
+		{"self", "/users/1", "Bearer customer-token", "", http.StatusOK},
+		{"someone else", "/users/2", "Bearer customer-token", "", http.StatusForbidden},
+		{"admin on someone else", "/users/1", "Bearer admin-token", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "access_token", Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthHandler_UpdateUserRoles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	tests := []struct {
+		name      string
+		path      string
+		body      string
+		setupMock func()
+		want      int
+	}{
+		{
+			name: "success",
+			path: "/users/5/roles",
+			body: `{"roles":["catalog-manager"]}`,
+			setupMock: func() {
+				mockService.EXPECT().UpdateUserRoles(uint(5), []string{"catalog-manager"}).
+					Return(&models.UserResponse{ID: 5, Roles: []string{"catalog-manager"}}, nil)
+			},
+			want: http.StatusOK,
+		},
+		{
+			name:      "invalid id",
+			path:      "/users/abc/roles",
+			body:      `{"roles":["admin"]}`,
+			setupMock: func() {},
+			want:      http.StatusBadRequest,
+		},
+		{
+			name:      "empty roles",
+			path:      "/users/5/roles",
+			body:      `{"roles":[]}`,
+			setupMock: func() {},
+			want:      http.StatusBadRequest,
+		},
+		{
+			name: "unknown role",
+			path: "/users/5/roles",
+			body: `{"roles":["root"]}`,
+			setupMock: func() {
+				mockService.EXPECT().UpdateUserRoles(uint(5), []string{"root"}).
+					Return(nil, errors.New("unknown role: root"))
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "user not found",
+			path: "/users/9/roles",
+			body: `{"roles":["admin"]}`,
+			setupMock: func() {
+				mockService.EXPECT().UpdateUserRoles(uint(9), []string{"admin"}).
+					Return(nil, errors.New("user not found"))
+			},
+			want: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			router := setupTestRouter()
+			router.PUT("/users/:id/roles", handler.UpdateUserRoles)
+
+			req := httptest.NewRequest(http.MethodPut, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthHandler_JWKS(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	router := setupTestRouter()
+	router.GET("/.well-known/jwks.json", handler.JWKS)
+
+	t.Run("success", func(t *testing.T) {
+		mockService.EXPECT().JWKS().Return(auth.JWKS{Keys: []auth.JWK{{Kty: "OKP", Kid: "k1", Crv: "Ed25519", X: "abc"}}}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		if got := w.Header().Get("Cache-Control"); got != "public, max-age=300" {
+			t.Fatalf("unexpected Cache-Control %q", got)
+		}
+		var doc auth.JWKS
+		if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+			t.Fatalf("invalid JWKS body: %v", err)
+		}
+		if len(doc.Keys) != 1 || doc.Keys[0].Kid != "k1" {
+			t.Fatalf("unexpected JWKS %+v", doc)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockService.EXPECT().JWKS().Return(auth.JWKS{}, errors.New("boom"))
+
+		req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusInternalServerError {
+			t.Fatalf("expected 500, got %d", w.Code)
+		}
+	})
+}
+
+func TestAuthHandler_Sessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	mockService.EXPECT().ValidateAccessToken("user-token").
+		Return(&models.User{ID: 7, Roles: models.Roles{"customer"}}, nil).AnyTimes()
+
+	router := setupTestRouter()
+	router.GET("/sessions", handler.RequireRoles(), handler.ListSessions)
+	router.DELETE("/sessions", handler.RequireRoles(), handler.RevokeAllSessions)
+	router.DELETE("/sessions/:id", handler.RequireRoles(), handler.RevokeSession)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer user-token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("list", func(t *testing.T) {
+		mockService.EXPECT().ListSessions(uint(7), "user-token").
+			Return([]models.SessionResponse{{ID: 3, UserAgent: "phone", Current: true}, {ID: 2, UserAgent: "desktop"}}, nil)
+
+		w := do(http.MethodGet, "/sessions")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var sessions []models.SessionResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &sessions); err != nil {
+			t.Fatalf("invalid body: %v", err)
+		}
+		if len(sessions) != 2 || !sessions[0].Current {
+			t.Fatalf("unexpected sessions %+v", sessions)
+		}
+	})
+
+	t.Run("list requires auth", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/sessions", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401, got %d", w.Code)
+		}
+	})
+
+	t.Run("revoke one", func(t *testing.T) {
+		mockService.EXPECT().RevokeSession(uint(7), uint(2)).Return(nil)
+
+		if w := do(http.MethodDelete, "/sessions/2"); w.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("revoke unknown", func(t *testing.T) {
+		mockService.EXPECT().RevokeSession(uint(7), uint(99)).Return(errors.New("session not found"))
+
+		if w := do(http.MethodDelete, "/sessions/99"); w.Code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("revoke invalid id", func(t *testing.T) {
+		if w := do(http.MethodDelete, "/sessions/abc"); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("revoke all clears cookies", func(t *testing.T) {
+		mockService.EXPECT().RevokeAllSessions(uint(7)).Return(nil)
+
+		w := do(http.MethodDelete, "/sessions")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		cleared := 0
+		for _, cookie := range w.Result().Cookies() {
+			if (cookie.Name == "access_token" || cookie.Name == "refresh_token") && cookie.MaxAge < 0 {
+				cleared++
+			}
+		}
+		if cleared != 2 {
+			t.Fatalf("expected both auth cookies cleared, got %d", cleared)
+		}
+	})
+}
+
+func TestAuthHandler_LoginRecordsDevice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	mockService.EXPECT().Login(gomock.Any()).DoAndReturn(func(req models.LoginRequest) (*models.LoginResponse, error) {
+		if req.Device.UserAgent != "velure-ios/2.1" || req.Device.IPAddress != "203.0.113.9" {
+			t.Errorf("unexpected device %+v", req.Device)
+		}
+		return &models.LoginResponse{AccessToken: "acc", RefreshToken: "ref"}, nil
+	})
+
+	router := setupTestRouter()
+	router.POST("/sessions", handler.Login)
+
+	req := httptest.NewRequest(http.MethodPost, "/sessions", bytes.NewBufferString(`{"email":"a@b.com","password":"secret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "velure-ios/2.1")
+	req.RemoteAddr = "203.0.113.9:51234"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAuthHandler_LoginLocked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	mockService.EXPECT().Login(gomock.Any()).
+		Return(nil, &lockout.LockedError{Scope: lockout.ScopeAccount, RetryAfter: 90500 * time.Millisecond})
+
+	router := setupTestRouter()
+	router.POST("/sessions", handler.Login)
+
+	req := httptest.NewRequest(http.MethodPost, "/sessions", bytes.NewBufferString(`{"email":"a@b.com","password":"secret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusLocked {
+		t.Fatalf("expected 423, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Retry-After"); got != "91" {
+		t.Fatalf("expected Retry-After 91, got %q", got)
+	}
+	if w.Header().Get("Set-Cookie") != "" {
+		t.Fatal("a locked login must not set auth cookies")
+	}
+}
+
+func TestAuthHandler_LoginTwoFactorChallenge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	mockService.EXPECT().Login(gomock.Any()).
+		Return(&models.LoginResponse{TwoFactorRequired: true, ChallengeToken: "challenge"}, nil)
+
+	router := setupTestRouter()
+	router.POST("/sessions", handler.Login)
+
+	req := httptest.NewRequest(http.MethodPost, "/sessions", bytes.NewBufferString(`{"email":"a@b.com","password":"secret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var body map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body["twoFactorRequired"] != true || body["challengeToken"] != "challenge" {
+		t.Fatalf("unexpected body %v", body)
+	}
+	if _, ok := body["accessToken"]; ok {
+		t.Fatal("a challenge response must not carry an access token")
+	}
+	if w.Header().Get("Set-Cookie") != "" {
+		t.Fatal("a challenge response must not set auth cookies")
+	}
+}
+
+func TestAuthHandler_CompleteTwoFactorLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	router := setupTestRouter()
+	router.POST("/sessions/two-factor", handler.CompleteTwoFactorLogin)
+
+	do := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/sessions/two-factor", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "velure-web")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("success sets cookies", func(t *testing.T) {
+		mockService.EXPECT().CompleteTwoFactorLogin(gomock.Any()).DoAndReturn(func(req models.TwoFactorLoginRequest) (*models.LoginResponse, error) {
+			if req.ChallengeToken != "challenge" || req.Code != "123456" || req.Device.UserAgent != "velure-web" {
+				t.Errorf("unexpected request %+v", req)
+			}
+			return &models.LoginResponse{AccessToken: "acc", RefreshToken: "ref"}, nil
+		})
+
+		w := do(`{"challengeToken":"challenge","code":"123456"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if len(w.Result().Cookies()) != 2 {
+			t.Fatalf("expected both auth cookies, got %v", w.Result().Cookies())
+		}
+	})
+
+	t.Run("missing code", func(t *testing.T) {
+		if w := do(`{"challengeToken":"challenge"}`); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+
+	for _, msg := range []string{"invalid two-factor code", "invalid or expired challenge"} {
+		t.Run(msg, func(t *testing.T) {
+			mockService.EXPECT().CompleteTwoFactorLogin(gomock.Any()).Return(nil, errors.New(msg))
+
+			if w := do(`{"challengeToken":"challenge","code":"000000"}`); w.Code != http.StatusUnauthorized {
+				t.Fatalf("expected 401, got %d", w.Code)
+			}
+		})
+	}
+
+	t.Run("locked", func(t *testing.T) {
+		mockService.EXPECT().CompleteTwoFactorLogin(gomock.Any()).
+			Return(nil, &lockout.LockedError{Scope: lockout.ScopeAccount, RetryAfter: time.Minute})
+
+		w := do(`{"challengeToken":"challenge","code":"000000"}`)
+		if w.Code != http.StatusLocked || w.Header().Get("Retry-After") != "60" {
+			t.Fatalf("expected 423 with Retry-After 60, got %d %q", w.Code, w.Header().Get("Retry-After"))
+		}
+	})
+}
+
+func TestAuthHandler_TwoFactorManagement(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	mockService.EXPECT().ValidateAccessToken("user-token").
+		Return(&models.User{ID: 7, Roles: models.Roles{"admin"}}, nil).AnyTimes()
+
+	router := setupTestRouter()
+	router.POST("/users/me/two-factor", handler.RequireRoles(), handler.EnrollTwoFactor)
+	router.POST("/users/me/two-factor/confirm", handler.RequireRoles(), handler.ConfirmTwoFactor)
+	router.DELETE("/users/me/two-factor", handler.RequireRoles(), handler.DisableTwoFactor)
+	router.POST("/users/me/two-factor/recovery-codes", handler.RequireRoles(), handler.RegenerateRecoveryCodes)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer user-token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("enroll", func(t *testing.T) {
+		mockService.EXPECT().EnrollTwoFactor(uint(7)).
+			Return(&models.TwoFactorEnrollmentResponse{Secret: "JBSWY3DPEHPK3PXP", OTPAuthURI: "otpauth://totp/x"}, nil)
+
+		w := do(http.MethodPost, "/users/me/two-factor", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if body["secret"] != "JBSWY3DPEHPK3PXP" || body["otpauthUri"] != "otpauth://totp/x" {
+			t.Fatalf("unexpected body %v", body)
+		}
+	})
+
+	t.Run("enroll when enabled", func(t *testing.T) {
+		mockService.EXPECT().EnrollTwoFactor(uint(7)).Return(nil, errors.New("two-factor authentication already enabled"))
+
+		if w := do(http.MethodPost, "/users/me/two-factor", ""); w.Code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d", w.Code)
+		}
+	})
+
+	t.Run("confirm returns recovery codes", func(t *testing.T) {
+		mockService.EXPECT().ConfirmTwoFactor(uint(7), "123456").
+			Return(&models.RecoveryCodesResponse{RecoveryCodes: []string{"abcde-fghjk"}}, nil)
+
+		w := do(http.MethodPost, "/users/me/two-factor/confirm", `{"code":"123456"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("confirm wrong code", func(t *testing.T) {
+		mockService.EXPECT().ConfirmTwoFactor(uint(7), "000000").Return(nil, errors.New("invalid two-factor code"))
+
+		if w := do(http.MethodPost, "/users/me/two-factor/confirm", `{"code":"000000"}`); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("disable", func(t *testing.T) {
+		mockService.EXPECT().DisableTwoFactor(uint(7), "123456").Return(nil)
+
+		if w := do(http.MethodDelete, "/users/me/two-factor", `{"code":"123456"}`); w.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d", w.Code)
+		}
+	})
+
+	t.Run("disable requires a code", func(t *testing.T) {
+		if w := do(http.MethodDelete, "/users/me/two-factor", `{}`); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("regenerate when not enabled", func(t *testing.T) {
+		mockService.EXPECT().RegenerateRecoveryCodes(uint(7), "123456").Return(nil, errors.New("two-factor authentication not enabled"))
+
+		if w := do(http.MethodPost, "/users/me/two-factor/recovery-codes", `{"code":"123456"}`); w.Code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d", w.Code)
+		}
+	})
+}
+
+func TestAuthHandler_AccountSelfService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	mockService.EXPECT().ValidateAccessToken("user-token").
+		Return(&models.User{ID: 7, Roles: models.Roles{"user"}}, nil).AnyTimes()
+
+	router := setupTestRouter()
+	router.PATCH("/users/me", handler.RequireRoles(), handler.UpdateProfile)
+	router.DELETE("/users/me", handler.RequireRoles(), handler.DeleteAccount)
+
+	do := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/users/me", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer user-token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("update", func(t *testing.T) {
+		mockService.EXPECT().UpdateProfile(uint(7), "user-token", gomock.Any()).
+			DoAndReturn(func(_ uint, _ string, req models.UpdateProfileRequest) (*models.UserResponse, error) {
+				if req.Name == nil || *req.Name != "New Name" || req.Email != nil || req.Password != nil {
+					t.Errorf("unexpected request %+v", req)
+				}
+				return &models.UserResponse{ID: 7, Name: "New Name"}, nil
+			})
+
+		w := do(http.MethodPatch, `{"name":"New Name"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("update with invalid email", func(t *testing.T) {
+		if w := do(http.MethodPatch, `{"email":"not-an-email"}`); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("update errors", func(t *testing.T) {
+		cases := map[string]int{
+			"current password is required":  http.StatusBadRequest,
+			"invalid email format":          http.StatusBadRequest,
+			"current password is incorrect": http.StatusForbidden,
+			"email already in use":          http.StatusConflict,
+			"user not found":                http.StatusNotFound,
+			"database down":                 http.StatusInternalServerError,
+		}
+		for msg, status := range cases {
+			mockService.EXPECT().UpdateProfile(uint(7), "user-token", gomock.Any()).Return(nil, errors.New(msg))
+
+			if w := do(http.MethodPatch, `{"password":"x"}`); w.Code != status {
+				t.Errorf("%q: expected %d, got %d", msg, status, w.Code)
+			}
+		}
+	})
+
+	t.Run("update with a rejected password", func(t *testing.T) {
+		mockService.EXPECT().UpdateProfile(uint(7), "user-token", gomock.Any()).
+			Return(nil, &passwordpolicy.ViolationError{Violations: []passwordpolicy.Violation{
+				{Field: passwordpolicy.Field, Code: passwordpolicy.CodeReused, Message: "password was used recently"},
+			}})
+
+		w := do(http.MethodPatch, `{"password":"old-password","currentPassword":"secret"}`)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), passwordpolicy.CodeReused) {
+			t.Fatalf("expected 400 with the violation, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("delete requires password", func(t *testing.T) {
+		if w := do(http.MethodDelete, `{}`); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("delete with wrong password", func(t *testing.T) {
+		mockService.EXPECT().DeleteAccount(uint(7), "wrong", gomock.Any()).Return(errors.New("current password is incorrect"))
+
+		if w := do(http.MethodDelete, `{"password":"wrong"}`); w.Code != http.StatusForbidden {
+			t.Fatalf("expected 403, got %d", w.Code)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		mockService.EXPECT().DeleteAccount(uint(7), "password123", gomock.Any()).Return(nil)
+
+		w := do(http.MethodDelete, `{"password":"password123"}`)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+		}
+		if len(w.Result().Cookies()) == 0 {
+			t.Fatal("expected the auth cookies to be cleared")
+		}
+	})
+}
+
+func TestAuthHandler_EmailVerification(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	mockService.EXPECT().ValidateAccessToken("user-token").
+		Return(&models.User{ID: 7, Roles: models.Roles{"customer"}}, nil).AnyTimes()
+
+	router := setupTestRouter()
+	router.POST("/email-verifications", handler.RequireRoles(), handler.RequestEmailVerification)
+	router.POST("/email-verifications/confirm", handler.ConfirmEmailVerification)
+
+	do := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer user-token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("resend", func(t *testing.T) {
+		mockService.EXPECT().RequestEmailVerification(uint(7)).Return(nil)
+
+		if w := do("/email-verifications", ""); w.Code != http.StatusAccepted {
+			t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("resend throttled", func(t *testing.T) {
+		mockService.EXPECT().RequestEmailVerification(uint(7)).
+			Return(&services.ThrottledError{RetryAfter: 1500 * time.Millisecond})
+
+		w := do("/email-verifications", "")
+		if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+			t.Fatalf("expected 429 with Retry-After 2, got %d %q", w.Code, w.Header().Get("Retry-After"))
+		}
+	})
+
+	t.Run("resend when verified", func(t *testing.T) {
+		mockService.EXPECT().RequestEmailVerification(uint(7)).Return(errors.New("email already verified"))
+
+		if w := do("/email-verifications", ""); w.Code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d", w.Code)
+		}
+	})
+
+	t.Run("confirm", func(t *testing.T) {
+		mockService.EXPECT().VerifyEmail("verify-token").Return(nil)
+
+		if w := do("/email-verifications/confirm", `{"token":"verify-token"}`); w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("confirm with bad token", func(t *testing.T) {
+		mockService.EXPECT().VerifyEmail("stale").Return(errors.New("invalid or expired verification token"))
+
+		if w := do("/email-verifications/confirm", `{"token":"stale"}`); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+		if w := do("/email-verifications/confirm", `{}`); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for a missing token, got %d", w.Code)
+		}
+	})
+}
+
+func TestAuthHandler_OIDCLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	router := setupTestRouter()
+	router.GET("/oidc/:provider/authorize", handler.StartOIDCLogin)
+	router.POST("/sessions/oidc/:provider", handler.CompleteOIDCLogin)
+
+	// complete posts the callback from a browser that started the login
+	// for state.
+	complete := func(body, state string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/sessions/oidc/google", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if state != "" {
+			req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: hashOIDCState(state)})
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("authorize redirects to the provider", func(t *testing.T) {
+		mockService.EXPECT().StartOIDCLogin("google").Return("https://accounts.example.com/authorize?state=s", "s", nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oidc/google/authorize", nil))
+		if w.Code != http.StatusFound || w.Header().Get("Location") != "https://accounts.example.com/authorize?state=s" {
+			t.Fatalf("expected a redirect to the provider, got %d %q", w.Code, w.Header().Get("Location"))
+		}
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != oidcStateCookie || cookies[0].Value != hashOIDCState("s") ||
+			!cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode || cookies[0].MaxAge <= 0 {
+			t.Fatalf("expected a short-lived httpOnly state cookie, got %+v", cookies)
+		}
+	})
+
+	t.Run("authorize with unknown provider", func(t *testing.T) {
+		mockService.EXPECT().StartOIDCLogin("nope").Return("", "", errors.New("unknown identity provider"))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oidc/nope/authorize", nil))
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("callback sets the session cookies", func(t *testing.T) {
+		mockService.EXPECT().CompleteOIDCLogin(gomock.Any()).DoAndReturn(func(req models.OIDCLoginRequest) (*models.LoginResponse, error) {
+			if req.Provider != "google" || req.Code != "the-code" || req.State != "the-state" {
+				t.Errorf("unexpected request %+v", req)
+			}
+			return &models.LoginResponse{AccessToken: "access", RefreshToken: "refresh"}, nil
+		})
+
+		w := complete(`{"code":"the-code","state":"the-state"}`, "the-state")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		cookies := map[string]string{}
+		for _, c := range w.Result().Cookies() {
+			cookies[c.Name] = c.Value
+		}
+		if cookies[accessTokenCookie] != "access" || cookies[refreshTokenCookie] != "refresh" {
+			t.Fatalf("expected auth cookies, got %v", cookies)
+		}
+		if v, ok := cookies[oidcStateCookie]; !ok || v != "" {
+			t.Fatalf("expected the state cookie to be cleared, got %v", cookies)
+		}
+	})
+
+	t.Run("callback with two-factor sets no cookies", func(t *testing.T) {
+		mockService.EXPECT().CompleteOIDCLogin(gomock.Any()).
+			Return(&models.LoginResponse{TwoFactorRequired: true, ChallengeToken: "challenge"}, nil)
+
+		w := complete(`{"code":"c","state":"s"}`, "s")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected a challenge, got %d", w.Code)
+		}
+		for _, c := range w.Result().Cookies() {
+			if c.Name == accessTokenCookie || c.Name == refreshTokenCookie {
+				t.Fatalf("expected no auth cookies, got %v", w.Result().Cookies())
+			}
+		}
+	})
+
+	errorCases := map[string]int{
+		"unknown identity provider":                  http.StatusNotFound,
+		"invalid or expired login state":             http.StatusBadRequest,
+		"identity provider login failed":             http.StatusUnauthorized,
+		"identity provider did not verify the email": http.StatusForbidden,
+		"account email not verified":                 http.StatusConflict,
+		"database down":                              http.StatusInternalServerError,
+	}
+	for msg, status := range errorCases {
+		t.Run(msg, func(t *testing.T) {
+			mockService.EXPECT().CompleteOIDCLogin(gomock.Any()).Return(nil, errors.New(msg))
+
+			if w := complete(`{"code":"c","state":"s"}`, "s"); w.Code != status {
+				t.Fatalf("expected %d, got %d", status, w.Code)
+			}
+		})
+	}
+
+	t.Run("callback without code", func(t *testing.T) {
+		if w := complete(`{"state":"s"}`, "s"); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+
+	// The service is never asked to redeem a callback the browser did not
+	// start, so a forged one cannot burn the victim's state either.
+	t.Run("callback without the state cookie", func(t *testing.T) {
+		if w := complete(`{"code":"c","state":"s"}`, ""); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("callback for another browser's state", func(t *testing.T) {
+		if w := complete(`{"code":"c","state":"s"}`, "attacker-state"); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+}
+
+func TestAuthHandler_AuditEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	mockService.EXPECT().ValidateAccessToken("admin-token").
+		Return(&models.User{ID: 1, Roles: models.Roles{auth.RoleAdmin}}, nil).AnyTimes()
+	mockService.EXPECT().ValidateAccessToken("user-token").
+		Return(&models.User{ID: 7, Roles: models.Roles{auth.RoleCustomer}}, nil).AnyTimes()
+
+	router := setupTestRouter()
+	router.GET("/audit-events", handler.RequireRoles(auth.RoleAdmin), handler.ListAuditEvents)
+	router.GET("/audit-events/export", handler.RequireRoles(auth.RoleAdmin), handler.ExportAuditEvents)
+
+	get := func(url, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("admin only", func(t *testing.T) {
+		if w := get("/audit-events", "user-token"); w.Code != http.StatusForbidden {
+			t.Fatalf("expected 403, got %d", w.Code)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		mockService.EXPECT().ListAuditEvents(models.AuditEventFilter{UserID: 7, Type: "login.failed", Since: since}, "42", 10).
+			Return(&models.AuditEventPage{
+				Events:     []models.AuditEvent{{ID: 41, Type: "login.failed"}},
+				NextCursor: "41",
+			}, nil)
+
+		w := get("/audit-events?userId=7&type=login.failed&since=2026-01-01T00:00:00Z&cursor=42&limit=10", "admin-token")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var page models.AuditEventPage
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Events) != 1 || page.NextCursor != "41" {
+			t.Fatalf("unexpected page %+v", page)
+		}
+	})
+
+	t.Run("bad parameters", func(t *testing.T) {
+		for _, query := range []string{"userId=abc", "since=yesterday", "limit=0"} {
+			if w := get("/audit-events?"+query, "admin-token"); w.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected 400, got %d", query, w.Code)
+			}
+		}
+
+		mockService.EXPECT().ListAuditEvents(gomock.Any(), "bogus", 0).Return(nil, errors.New("invalid cursor"))
+		if w := get("/audit-events?cursor=bogus", "admin-token"); w.Code != http.StatusBadRequest {
+			t.Errorf("invalid cursor: expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("export", func(t *testing.T) {
+		mockService.EXPECT().ExportAuditEvents(models.AuditEventFilter{}, uint(5), gomock.Any()).
+			DoAndReturn(func(_ models.AuditEventFilter, _ uint, emit func(models.AuditEvent) error) error {
+				for _, id := range []uint{6, 7} {
+					if err := emit(models.AuditEvent{ID: id, Type: "logout"}); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+
+		w := get("/audit-events/export?after=5", "admin-token")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Errorf("unexpected content type %q", ct)
+		}
+		lines := bytes.Split(bytes.TrimSpace(w.Body.Bytes()), []byte("\n"))
+		if len(lines) != 2 {
+			t.Fatalf("expected 2 lines, got %q", w.Body.String())
+		}
+		var event models.AuditEvent
+		if err := json.Unmarshal(lines[1], &event); err != nil || event.ID != 7 {
+			t.Fatalf("unexpected line %q: %v", lines[1], err)
+		}
+	})
+
+	t.Run("export failure before any event", func(t *testing.T) {
+		mockService.EXPECT().ExportAuditEvents(gomock.Any(), uint(0), gomock.Any()).Return(errors.New("db down"))
+
+		if w := get("/audit-events/export", "admin-token"); w.Code != http.StatusInternalServerError {
+			t.Fatalf("expected 500, got %d", w.Code)
+		}
+	})
+}
+
+func TestAuthHandler_IssueToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	router := setupTestRouter()
+	router.POST("/oauth/token", handler.IssueToken)
+
+	post := func(form url.Values, basicUser, basicPass string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if basicUser != "" {
+			req.SetBasicAuth(url.QueryEscape(basicUser), url.QueryEscape(basicPass))
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("basic credentials", func(t *testing.T) {
+		mockService.EXPECT().IssueClientToken("orders", "s3cret:+", "inventory:write").
+			Return(&models.ClientTokenResponse{AccessToken: "tok", TokenType: "Bearer", ExpiresIn: 300, Scope: "inventory:write"}, nil)
+
+		w := post(url.Values{"grant_type": {"client_credentials"}, "scope": {"inventory:write"}}, "orders", "s3cret:+")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if w.Header().Get("Cache-Control") != "no-store" {
+			t.Error("token responses must not be cached")
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body["access_token"] != "tok" || body["token_type"] != "Bearer" || body["expires_in"] != float64(300) {
+			t.Fatalf("unexpected response %v", body)
+		}
+	})
+
+	t.Run("form credentials", func(t *testing.T) {
+		mockService.EXPECT().IssueClientToken("orders", "s3cret", "").
+			Return(&models.ClientTokenResponse{AccessToken: "tok"}, nil)
+
+		w := post(url.Values{"grant_type": {"client_credentials"}, "client_id": {"orders"}, "client_secret": {"s3cret"}}, "", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if w := post(url.Values{}, "orders", "s3cret"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid_request") {
+			t.Fatalf("missing grant_type: got %d %s", w.Code, w.Body.String())
+		}
+		if w := post(url.Values{"grant_type": {"password"}}, "orders", "s3cret"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "unsupported_grant_type") {
+			t.Fatalf("password grant: got %d %s", w.Code, w.Body.String())
+		}
+
+		cases := []struct {
+			err    string
+			status int
+			code   string
+		}{
+			{"invalid client", http.StatusUnauthorized, "invalid_client"},
+			{"invalid scope", http.StatusBadRequest, "invalid_scope"},
+			{"database down", http.StatusInternalServerError, "internal error"},
+		}
+		for _, tc := range cases {
+			mockService.EXPECT().IssueClientToken("orders", "wrong", "").Return(nil, errors.New(tc.err))
+
+			w := post(url.Values{"grant_type": {"client_credentials"}}, "orders", "wrong")
+			if w.Code != tc.status || !strings.Contains(w.Body.String(), tc.code) {
+				t.Errorf("%q: got %d %s", tc.err, w.Code, w.Body.String())
+			}
+		}
+	})
+}
+
+func TestAuthHandler_Clients(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	mockService.EXPECT().ValidateAccessToken("admin-token").
+		Return(&models.User{ID: 1, Roles: models.Roles{auth.RoleAdmin}}, nil).AnyTimes()
+	mockService.EXPECT().ValidateAccessToken("user-token").
+		Return(&models.User{ID: 7, Roles: models.Roles{auth.RoleCustomer}}, nil).AnyTimes()
+
+	router := setupTestRouter()
+	router.GET("/clients", handler.RequireRoles(auth.RoleAdmin), handler.ListClients)
+	router.POST("/clients", handler.RequireRoles(auth.RoleAdmin), handler.CreateClient)
+	router.DELETE("/clients/:clientId", handler.RequireRoles(auth.RoleAdmin), handler.DeleteClient)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("admin only", func(t *testing.T) {
+		if w := do(http.MethodGet, "/clients", "user-token", ""); w.Code != http.StatusForbidden {
+			t.Fatalf("expected 403, got %d", w.Code)
+		}
+	})
+
+	t.Run("create", func(t *testing.T) {
+		mockService.EXPECT().RegisterClient(uint(1), gomock.Any()).
+			DoAndReturn(func(_ uint, req models.CreateOAuthClientRequest) (*models.RegisteredOAuthClientResponse, error) {
+				if req.ClientID != "orders" || len(req.Scopes) != 1 || req.Scopes[0] != auth.ScopeInventoryWrite {
+					t.Errorf("unexpected request %+v", req)
+				}
+				return &models.RegisteredOAuthClientResponse{
+					OAuthClientResponse: models.OAuthClientResponse{ClientID: "orders"},
+					ClientSecret:        "s3cret",
+				}, nil
+			})
+
+		w := do(http.MethodPost, "/clients", "admin-token", `{"clientId":"orders","name":"Orders","scopes":["inventory:write"]}`)
+		if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"clientSecret":"s3cret"`) {
+			t.Fatalf("expected 201 with the secret, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("create errors", func(t *testing.T) {
+		if w := do(http.MethodPost, "/clients", "admin-token", `{"clientId":"orders","name":"Orders"}`); w.Code != http.StatusBadRequest {
+			t.Fatalf("missing scopes: expected 400, got %d", w.Code)
+		}
+
+		cases := map[string]int{
+			"unknown scope: orders:delete": http.StatusBadRequest,
+			"invalid client ID":            http.StatusBadRequest,
+			"client already exists":        http.StatusConflict,
+			"clients not enabled":          http.StatusServiceUnavailable,
+			"database down":                http.StatusInternalServerError,
+		}
+		for msg, status := range cases {
+			mockService.EXPECT().RegisterClient(uint(1), gomock.Any()).Return(nil, errors.New(msg))
+
+			if w := do(http.MethodPost, "/clients", "admin-token", `{"clientId":"orders","name":"Orders","scopes":["x"]}`); w.Code != status {
+				t.Errorf("%q: expected %d, got %d", msg, status, w.Code)
+			}
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		mockService.EXPECT().ListClients().Return([]models.OAuthClientResponse{{ClientID: "orders"}}, nil)
+
+		w := do(http.MethodGet, "/clients", "admin-token", "")
+		if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "ecret") {
+			t.Fatalf("expected 200 without secrets, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		mockService.EXPECT().DeleteClient(uint(1), "orders", gomock.Any()).Return(nil)
+		mockService.EXPECT().DeleteClient(uint(1), "missing", gomock.Any()).Return(errors.New("client not found"))
+
+		if w := do(http.MethodDelete, "/clients/orders", "admin-token", ""); w.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d", w.Code)
+		}
+		if w := do(http.MethodDelete, "/clients/missing", "admin-token", ""); w.Code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d", w.Code)
+		}
+	})
+}
+
+func TestAuthHandler_UserAdmin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	mockService.EXPECT().ValidateAccessToken("admin-token").
+		Return(&models.User{ID: 1, Roles: models.Roles{auth.RoleAdmin}}, nil).AnyTimes()
+	mockService.EXPECT().ValidateAccessToken("user-token").
+		Return(&models.User{ID: 7, Roles: models.Roles{auth.RoleCustomer}}, nil).AnyTimes()
+
+	router := setupTestRouter()
+	router.GET("/users/search", handler.RequireRoles(auth.RoleAdmin), handler.SearchUsers)
+	router.POST("/users/:id/disable", handler.RequireRoles(auth.RoleAdmin), handler.DisableUser)
+	router.POST("/users/:id/enable", handler.RequireRoles(auth.RoleAdmin), handler.EnableUser)
+	router.POST("/users/:id/impersonate", handler.RequireRoles(auth.RoleAdmin), handler.ForbidImpersonation, handler.ImpersonateUser)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("admin only", func(t *testing.T) {
+		if w := do(http.MethodGet, "/users/search", "user-token", ""); w.Code != http.StatusForbidden {
+			t.Fatalf("expected 403, got %d", w.Code)
+		}
+		if w := do(http.MethodPost, "/users/2/disable", "user-token", ""); w.Code != http.StatusForbidden {
+			t.Fatalf("expected 403, got %d", w.Code)
+		}
+	})
+
+	t.Run("search", func(t *testing.T) {
+		mockService.EXPECT().SearchUsers(gomock.Any(), "-email", "abc", 20).
+			DoAndReturn(func(filter models.UserFilter, _, _ string, _ int) (*models.UserSearchPage, error) {
+				if filter.Query != "ali" || filter.Verified == nil || *filter.Verified || filter.Locked == nil || !*filter.Locked ||
+					filter.Disabled != nil || !filter.CreatedSince.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+					t.Errorf("unexpected filter %+v", filter)
+				}
+				return &models.UserSearchPage{
+					Users:      []models.AdminUserResponse{{UserResponse: models.UserResponse{ID: 2}, Locked: true}},
+					NextCursor: "def",
+				}, nil
+			})
+
+		w := do(http.MethodGet, "/users/search?q=ali&verified=false&locked=true&createdSince=2026-01-01T00:00:00Z&sort=-email&cursor=abc&limit=20", "admin-token", "")
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"locked":true`) || !strings.Contains(w.Body.String(), `"nextCursor":"def"`) {
+			t.Fatalf("expected 200 with the page, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("search errors", func(t *testing.T) {
+		for _, query := range []string{"verified=maybe", "createdUntil=yesterday", "limit=0"} {
+			if w := do(http.MethodGet, "/users/search?"+query, "admin-token", ""); w.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected 400, got %d", query, w.Code)
+			}
+		}
+		for msg, status := range map[string]int{"invalid cursor": http.StatusBadRequest, "invalid sort": http.StatusBadRequest, "redis down": http.StatusInternalServerError} {
+			mockService.EXPECT().SearchUsers(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New(msg))
+			if w := do(http.MethodGet, "/users/search", "admin-token", ""); w.Code != status {
+				t.Errorf("%q: expected %d, got %d", msg, status, w.Code)
+			}
+		}
+	})
+
+	t.Run("disable", func(t *testing.T) {
+		now := time.Now()
+		mockService.EXPECT().DisableUser(uint(1), uint(2), "fraud", gomock.Any()).
+			Return(&models.UserResponse{ID: 2, DisabledAt: &now}, nil)
+		if w := do(http.MethodPost, "/users/2/disable", "admin-token", `{"reason":"fraud"}`); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "disabledAt") {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		// The body is optional.
+		mockService.EXPECT().DisableUser(uint(1), uint(2), "", gomock.Any()).
+			Return(&models.UserResponse{ID: 2, DisabledAt: &now}, nil)
+		if w := do(http.MethodPost, "/users/2/disable", "admin-token", ""); w.Code != http.StatusOK {
+			t.Fatalf("expected 200 without a body, got %d", w.Code)
+		}
+
+		for msg, status := range map[string]int{"user not found": http.StatusNotFound, "cannot disable your own account": http.StatusConflict} {
+			mockService.EXPECT().DisableUser(uint(1), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New(msg))
+			if w := do(http.MethodPost, "/users/3/disable", "admin-token", ""); w.Code != status {
+				t.Errorf("%q: expected %d, got %d", msg, status, w.Code)
+			}
+		}
+		if w := do(http.MethodPost, "/users/abc/disable", "admin-token", ""); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for a bad ID, got %d", w.Code)
+		}
+	})
+
+	t.Run("enable", func(t *testing.T) {
+		mockService.EXPECT().EnableUser(uint(1), uint(2), gomock.Any()).Return(&models.UserResponse{ID: 2}, nil)
+		if w := do(http.MethodPost, "/users/2/enable", "admin-token", ""); w.Code != http.StatusOK || strings.Contains(w.Body.String(), "disabledAt") {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("impersonate", func(t *testing.T) {
+		mockService.EXPECT().ImpersonateUser(uint(1), uint(2), "ticket 42", gomock.Any()).
+			Return(&models.ImpersonationResponse{AccessToken: "acting-token", TokenType: "Bearer", ExpiresIn: 900}, nil)
+		w := do(http.MethodPost, "/users/2/impersonate", "admin-token", `{"reason":"ticket 42"}`)
+		if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"accessToken":"acting-token"`) {
+			t.Fatalf("expected 201 with the token, got %d: %s", w.Code, w.Body.String())
+		}
+		if cookies := w.Result().Cookies(); len(cookies) != 0 {
+			t.Fatalf("the admin's own cookies must be left alone, got %v", cookies)
+		}
+
+		if w := do(http.MethodPost, "/users/2/impersonate", "admin-token", ""); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 without a reason, got %d", w.Code)
+		}
+		for msg, status := range map[string]int{
+			"user not found":                        http.StatusNotFound,
+			"cannot impersonate an admin":           http.StatusConflict,
+			"cannot impersonate a disabled account": http.StatusConflict,
+			"audit log not enabled":                 http.StatusServiceUnavailable,
+		} {
+			mockService.EXPECT().ImpersonateUser(uint(1), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New(msg))
+			if w := do(http.MethodPost, "/users/3/impersonate", "admin-token", `{"reason":"ticket"}`); w.Code != status {
+				t.Errorf("%q: expected %d, got %d", msg, status, w.Code)
+			}
+		}
+	})
+}
+
+func TestAuthHandler_ForbidImpersonation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockAuthServiceInterface(ctrl)
+	handler := NewAuthHandler(mockService)
+
+	sign := func(claims auth.Claims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	own := sign(auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "7"}})
+	acting := sign(auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "7"}, Actor: &auth.Actor{Subject: "1"}})
+	mockService.EXPECT().ValidateAccessToken(gomock.Any()).
+		Return(&models.User{ID: 7, Roles: models.Roles{auth.RoleCustomer}}, nil).AnyTimes()
+
+	router := setupTestRouter()
+	router.DELETE("/users/me", handler.RequireRoles(), handler.ForbidImpersonation, func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for token, want := range map[string]int{own: http.StatusNoContent, acting: http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodDelete, "/users/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("expected %d, got %d", want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// Package lockout protects login against password guessing. Failed attempts
+// are counted in Redis per account and per client IP; each failure earns a
+// growing delay and reaching the threshold locks that account or IP for a
+// while. Counting in Redis keeps the limits consistent across replicas.
+package lockout
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
+
+	"github.com/icl00ud/velure/shared/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// Scopes a lockout applies to, also used as the metric label.
+const (
+	ScopeAccount = "account"
+	ScopeIP      = "ip"
+)
+
+// LockedError is returned while an account or IP is locked out.
+type LockedError struct {
+	Scope      string
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return "account temporarily locked"
+}
+
+// Config tunes the guard. Zero values fall back to the defaults below.
+type Config struct {
+	MaxAttempts   int           // failures per account before it is locked
+	MaxIPAttempts int           // failures per client IP before it is locked
+	Window        time.Duration // how long a failure counts towards the threshold
+	Duration      time.Duration // how long a lockout lasts
+	BaseDelay     time.Duration // delay after the first failure, doubled each time
+	MaxDelay      time.Duration // cap on the per-failure delay
+}
+
+const (
+	DefaultMaxAttempts   = 5
+	DefaultMaxIPAttempts = 20
+	DefaultWindow        = 15 * time.Minute
+	DefaultDuration      = 15 * time.Minute
+	DefaultBaseDelay     = 250 * time.Millisecond
+	DefaultMaxDelay      = 4 * time.Second
+)
+
+// Guard tracks failed logins. A nil Guard, or one without a Redis client,
+// allows everything.
+type Guard struct {
+	redis *redis.Client
+	cfg   Config
+}
+
+func New(client *redis.Client, cfg Config) *Guard {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.MaxIPAttempts <= 0 {
+		cfg.MaxIPAttempts = DefaultMaxIPAttempts
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultWindow
+	}
+	if cfg.Duration <= 0 {
+		cfg.Duration = DefaultDuration
+	}
+	if cfg.BaseDelay < 0 {
+		cfg.BaseDelay = 0
+	}
+	if cfg.MaxDelay < cfg.BaseDelay {
+		cfg.MaxDelay = cfg.BaseDelay
+	}
+	return &Guard{redis: client, cfg: cfg}
+}
+
+func (g *Guard) enabled() bool {
+	return g != nil && g.redis != nil
+}
+
+// Check returns a *LockedError if the account or the IP is currently locked.
+// Redis failures are logged and let the attempt through: an outage of the
+// cache must not lock everybody out.
+func (g *Guard) Check(ctx context.Context, account, ip string) error {
+	if !g.enabled() {
+		return nil
+	}
+
+	pipe := g.redis.Pipeline()
+	accountTTL := pipe.PTTL(ctx, lockKey(ScopeAccount, account))
+	var ipTTL *redis.DurationCmd
+	if ip != "" {
+		ipTTL = pipe.PTTL(ctx, lockKey(ScopeIP, ip))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		logger.Warn("login lockout check failed", logger.Err(err))
+		return nil
+	}
+
+	if ttl := accountTTL.Val(); ttl > 0 {
+		return &LockedError{Scope: ScopeAccount, RetryAfter: ttl}
+	}
+	if ipTTL != nil {
+		if ttl := ipTTL.Val(); ttl > 0 {
+			return &LockedError{Scope: ScopeIP, RetryAfter: ttl}
+		}
+	}
+	return nil
+}
+
+// Fail records a failed attempt. It returns how long the caller should hold
+// the response back and, if this failure crossed a threshold, the resulting
+// lockout.
+func (g *Guard) Fail(ctx context.Context, account, ip string) (time.Duration, *LockedError) {
+	if !g.enabled() {
+		return 0, nil
+	}
+
+	accountFailures := g.count(ctx, ScopeAccount, account)
+	locked := g.lockIfExceeded(ctx, ScopeAccount, account, accountFailures, g.cfg.MaxAttempts)
+	if ip != "" {
+		ipFailures := g.count(ctx, ScopeIP, ip)
+		if ipLocked := g.lockIfExceeded(ctx, ScopeIP, ip, ipFailures, g.cfg.MaxIPAttempts); locked == nil {
+			locked = ipLocked
+		}
+	}
+
+	return g.delay(accountFailures), locked
+}
+
+// Succeed clears the account's failure count after a successful login. The
+// IP count is kept so that one valid account cannot be used to reset it.
+func (g *Guard) Succeed(ctx context.Context, account string) {
+	if !g.enabled() {
+		return
+	}
+	if err := g.redis.Del(ctx, failKey(ScopeAccount, account)).Err(); err != nil {
+		logger.Warn("failed to clear login failures", logger.Err(err))
+	}
+}
+
+// Unlock lifts an account lockout and forgets its failures, e.g. once the
+// owner proved control of the mailbox by resetting the password.
+func (g *Guard) Unlock(ctx context.Context, account string) {
+	if !g.enabled() {
+		return
+	}
+	if err := g.redis.Del(ctx, failKey(ScopeAccount, account), lockKey(ScopeAccount, account)).Err(); err != nil {
+		logger.Warn("failed to clear account lockout", logger.Err(err))
+	}
+}
+
+// LockedAccounts returns the accounts currently locked out. It walks the
+// lock keys, which only exist while a lockout lasts, so the result stays
+// small. Without Redis nothing is locked.
+func (g *Guard) LockedAccounts(ctx context.Context) ([]string, error) {
+	if !g.enabled() {
+		return nil, nil
+	}
+	prefix := lockKey(ScopeAccount, "")
+	var accounts []string
+	iter := g.redis.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		accounts = append(accounts, strings.TrimPrefix(iter.Val(), prefix))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// count increments the failure counter for key, starting its window on the
+// first failure. It returns 0 if Redis is unavailable.
+func (g *Guard) count(ctx context.Context, scope, id string) int64 {
+	key := failKey(scope, id)
+	n, err := g.redis.Incr(ctx, key).Result()
+	if err != nil {
+		logger.Warn("failed to record login failure", logger.String("scope", scope), logger.Err(err))
+		return 0
+	}
+	if n == 1 {
+		g.redis.PExpire(ctx, key, g.cfg.Window)
+	}
+	return n
+}
+
+func (g *Guard) lockIfExceeded(ctx context.Context, scope, id string, failures int64, max int) *LockedError {
+	if failures < int64(max) {
+		return nil
+	}
+
+	// The counter restarts once the lockout is over.
+	pipe := g.redis.TxPipeline()
+	pipe.Set(ctx, lockKey(scope, id), 1, g.cfg.Duration)
+	pipe.Del(ctx, failKey(scope, id))
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Warn("failed to lock out login", logger.String("scope", scope), logger.Err(err))
+		return nil
+	}
+
+	metrics.LoginLockouts.WithLabelValues(scope).Inc()
+	logger.Warn("login locked out after repeated failures",
+		logger.String("scope", scope),
+		logger.Int("failures", int(failures)))
+	return &LockedError{Scope: scope, RetryAfter: g.cfg.Duration}
+}
+
+// delay doubles BaseDelay for every failure after the first, up to MaxDelay.
+func (g *Guard) delay(failures int64) time.Duration {
+	if failures <= 0 || g.cfg.BaseDelay == 0 {
+		return 0
+	}
+	d := g.cfg.BaseDelay
+	for i := int64(1); i < failures && d < g.cfg.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > g.cfg.MaxDelay {
+		d = g.cfg.MaxDelay
+	}
+	return d
+}
+
+// NormalizeAccount maps an email to its counter key so that case and
+// surrounding spaces cannot be used to dodge the limit.
+func NormalizeAccount(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func failKey(scope, id string) string {
+	return "login:failures:" + scope + ":" + id
+}
+
+func lockKey(scope, id string) string {
+	return "login:locked:" + scope + ":" + id
+}
@@ -0,0 +1,188 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestGuard(t *testing.T, cfg Config) (*Guard, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return New(client, cfg), mr
+}
+
+func TestGuard_LocksAccountAfterThreshold(t *testing.T) {
+	ctx := context.Background()
+	g, mr := newTestGuard(t, Config{MaxAttempts: 3, MaxIPAttempts: 100, Duration: time.Minute})
+	before := promtest.ToFloat64(metrics.LoginLockouts.WithLabelValues(ScopeAccount))
+
+	for i := 1; i < 3; i++ {
+		if _, locked := g.Fail(ctx, "a@example.com", "198.51.100.1"); locked != nil {
+			t.Fatalf("locked after %d failures", i)
+		}
+		if err := g.Check(ctx, "a@example.com", "198.51.100.1"); err != nil {
+			t.Fatalf("Check() after %d failures = %v", i, err)
+		}
+	}
+
+	_, locked := g.Fail(ctx, "a@example.com", "198.51.100.1")
+	if locked == nil || locked.Scope != ScopeAccount || locked.RetryAfter != time.Minute {
+		t.Fatalf("expected account lockout, got %+v", locked)
+	}
+	if got := promtest.ToFloat64(metrics.LoginLockouts.WithLabelValues(ScopeAccount)); got != before+1 {
+		t.Fatalf("expected lockout metric to increase, got %v -> %v", before, got)
+	}
+
+	// The lock applies from any IP, and other accounts are unaffected.
+	var lockedErr *LockedError
+	if err := g.Check(ctx, "a@example.com", "203.0.113.7"); !errors.As(err, &lockedErr) || lockedErr.RetryAfter <= 0 {
+		t.Fatalf("expected locked account, got %v", err)
+	}
+	if err := g.Check(ctx, "b@example.com", "198.51.100.1"); err != nil {
+		t.Fatalf("other account should not be locked: %v", err)
+	}
+
+	mr.FastForward(time.Minute + time.Second)
+	if err := g.Check(ctx, "a@example.com", "198.51.100.1"); err != nil {
+		t.Fatalf("lock should expire, got %v", err)
+	}
+	// Failures before the lockout no longer count.
+	if _, locked := g.Fail(ctx, "a@example.com", "198.51.100.1"); locked != nil {
+		t.Fatal("counter should restart after the lockout")
+	}
+}
+
+func TestGuard_LocksIPAcrossAccounts(t *testing.T) {
+	ctx := context.Background()
+	g, _ := newTestGuard(t, Config{MaxAttempts: 100, MaxIPAttempts: 3})
+
+	for _, account := range []string{"a@example.com", "b@example.com"} {
+		if _, locked := g.Fail(ctx, account, "198.51.100.1"); locked != nil {
+			t.Fatalf("unexpected lockout for %s", account)
+		}
+	}
+	_, locked := g.Fail(ctx, "c@example.com", "198.51.100.1")
+	if locked == nil || locked.Scope != ScopeIP {
+		t.Fatalf("expected IP lockout, got %+v", locked)
+	}
+
+	if err := g.Check(ctx, "d@example.com", "198.51.100.1"); err == nil {
+		t.Fatal("any account from the locked IP should be rejected")
+	}
+	if err := g.Check(ctx, "d@example.com", "198.51.100.2"); err != nil {
+		t.Fatalf("other IPs should pass: %v", err)
+	}
+}
+
+func TestGuard_FailureWindowExpires(t *testing.T) {
+	ctx := context.Background()
+	g, mr := newTestGuard(t, Config{MaxAttempts: 2, Window: time.Minute})
+
+	g.Fail(ctx, "a@example.com", "")
+	mr.FastForward(2 * time.Minute)
+	if _, locked := g.Fail(ctx, "a@example.com", ""); locked != nil {
+		t.Fatal("failures outside the window should not count")
+	}
+}
+
+func TestGuard_ProgressiveDelay(t *testing.T) {
+	ctx := context.Background()
+	g, _ := newTestGuard(t, Config{MaxAttempts: 100, BaseDelay: 100 * time.Millisecond, MaxDelay: 350 * time.Millisecond})
+
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 350 * time.Millisecond, 350 * time.Millisecond}
+	for i, w := range want {
+		if delay, _ := g.Fail(ctx, "a@example.com", ""); delay != w {
+			t.Fatalf("failure %d: delay = %v, want %v", i+1, delay, w)
+		}
+	}
+}
+
+func TestGuard_SucceedAndUnlock(t *testing.T) {
+	ctx := context.Background()
+	g, _ := newTestGuard(t, Config{MaxAttempts: 2, MaxIPAttempts: 100})
+
+	g.Fail(ctx, "a@example.com", "198.51.100.1")
+	g.Succeed(ctx, "a@example.com")
+	if _, locked := g.Fail(ctx, "a@example.com", "198.51.100.1"); locked != nil {
+		t.Fatal("a successful login should reset the account counter")
+	}
+
+	if _, locked := g.Fail(ctx, "a@example.com", "198.51.100.1"); locked == nil {
+		t.Fatal("expected lockout")
+	}
+	g.Unlock(ctx, "a@example.com")
+	if err := g.Check(ctx, "a@example.com", "198.51.100.1"); err != nil {
+		t.Fatalf("Unlock() should lift the lockout, got %v", err)
+	}
+}
+
+func TestGuard_LockedAccounts(t *testing.T) {
+	ctx := context.Background()
+	g, mr := newTestGuard(t, Config{MaxAttempts: 1, MaxIPAttempts: 1, Duration: time.Minute})
+
+	g.Fail(ctx, "a@example.com", "198.51.100.1")
+	g.Fail(ctx, "b@example.com", "")
+	accounts, err := g.LockedAccounts(ctx)
+	if err != nil {
+		t.Fatalf("LockedAccounts() error = %v", err)
+	}
+	if len(accounts) != 2 || !slices.Contains(accounts, "a@example.com") || !slices.Contains(accounts, "b@example.com") {
+		t.Fatalf("LockedAccounts() = %v, want both accounts and no IPs", accounts)
+	}
+
+	mr.FastForward(time.Minute)
+	if accounts, _ := g.LockedAccounts(ctx); len(accounts) != 0 {
+		t.Fatalf("expired lockouts must not be listed, got %v", accounts)
+	}
+	var disabled *Guard
+	if accounts, err := disabled.LockedAccounts(ctx); accounts != nil || err != nil {
+		t.Fatalf("disabled guard LockedAccounts() = %v, %v", accounts, err)
+	}
+}
+
+func TestGuard_DisabledWithoutRedis(t *testing.T) {
+	ctx := context.Background()
+	for _, g := range []*Guard{nil, New(nil, Config{MaxAttempts: 1})} {
+		if delay, locked := g.Fail(ctx, "a@example.com", "198.51.100.1"); delay != 0 || locked != nil {
+			t.Fatalf("disabled guard Fail() = %v, %v", delay, locked)
+		}
+		if err := g.Check(ctx, "a@example.com", "198.51.100.1"); err != nil {
+			t.Fatalf("disabled guard Check() = %v", err)
+		}
+		g.Succeed(ctx, "a@example.com")
+		g.Unlock(ctx, "a@example.com")
+	}
+}
+
+func TestGuard_FailsOpenWhenRedisIsDown(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	defer client.Close()
+	g := New(client, Config{MaxAttempts: 1})
+	mr.Close()
+
+	if _, locked := g.Fail(ctx, "a@example.com", "198.51.100.1"); locked != nil {
+		t.Fatal("unreachable redis must not lock anyone out")
+	}
+	if err := g.Check(ctx, "a@example.com", "198.51.100.1"); err != nil {
+		t.Fatalf("Check() = %v, want nil", err)
+	}
+}
+
+func TestNormalizeAccount(t *testing.T) {
+	if got := NormalizeAccount("  Alice@Example.COM "); got != "alice@example.com" {
+		t.Fatalf("NormalizeAccount() = %q", got)
+	}
+}
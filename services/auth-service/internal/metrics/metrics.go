@@ -12,7 +12,15 @@ var (
 			Name: "auth_login_attempts_total",
 			Help: "Total number of login attempts",
 		},
-		[]string{"status"}, // status: success, failure
+		[]string{"status"}, // status: success, failure, locked, two_factor_required
+	)
+
+	LoginLockouts = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_login_lockouts_total",
+			Help: "Total number of lockouts triggered by repeated login failures",
+		},
+		[]string{"scope"}, // scope: account, ip
 	)
 
 	LoginDuration = promauto.NewHistogramVec(
@@ -47,7 +55,7 @@ var (
 			Name: "auth_token_validations_total",
 			Help: "Total number of token validation requests",
 		},
-		[]string{"result"}, // result: valid, invalid
+		[]string{"result"}, // result: valid, valid_cached, invalid, revoked
 	)
 
 	TokenGenerations = promauto.NewCounter(
@@ -65,6 +73,62 @@ var (
 		},
 	)
 
+	TokenRefreshes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_token_refreshes_total",
+			Help: "Total number of refresh token exchanges",
+		},
+		[]string{"result"}, // result: success, invalid, reuse_detected, failure
+	)
+
+	PasswordResets = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_password_resets_total",
+			Help: "Total number of password reset requests and confirmations",
+		},
+		[]string{"stage"}, // stage: requested, unknown_email, completed, invalid_token
+	)
+
+	EmailVerifications = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_email_verifications_total",
+			Help: "Total number of email verification requests and confirmations",
+		},
+		[]string{"stage"}, // stage: requested, throttled, verified, invalid_token
+	)
+
+	OIDCLogins = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_oidc_logins_total",
+			Help: "Total number of social logins through OpenID Connect providers",
+		},
+		[]string{"provider", "result"}, // result: success, linked, registered, invalid_state, provider_error, rejected
+	)
+
+	TwoFactorEvents = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_two_factor_events_total",
+			Help: "Total number of two-factor enrollment and verification events",
+		},
+		[]string{"event"}, // event: enrolled, enabled, disabled, verified, recovery_code_used, invalid_code
+	)
+
+	ClientTokens = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_client_tokens_total",
+			Help: "Total number of client_credentials token requests",
+		},
+		[]string{"result"}, // result: issued, invalid_client, invalid_scope
+	)
+
+	ImpersonationTokens = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_impersonation_tokens_total",
+			Help: "Total number of impersonation token requests from admins",
+		},
+		[]string{"result"}, // result: issued, refused, failure
+	)
+
 	// Session metrics
 	ActiveSessions = promauto.NewGauge(
 		prometheus.GaugeOpts{
@@ -120,7 +184,7 @@ var (
 			Name: "auth_errors_total",
 			Help: "Total number of errors",
 		},
-		[]string{"type"}, // type: validation, database, auth, internal
+		[]string{"type"}, // type: validation, database, auth, internal, events
 	)
 
 	// HTTP metrics
@@ -141,18 +205,54 @@ var (
 		[]string{"method", "path"},
 	)
 
+	GRPCRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_grpc_requests_total",
+			Help: "Total number of gRPC requests",
+		},
+		[]string{"method", "code"},
+	)
+
+	GRPCRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "auth_grpc_request_duration_seconds",
+			Help:    "Duration of gRPC requests in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method"},
+	)
+
+	// PasswordRehashes counts stored password hashes upgraded on login to
+	// the current algorithm or cost.
+	PasswordRehashes = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "auth_password_rehashes_total",
+			Help: "Total number of password hashes upgraded on login",
+		},
+	)
+
 	// Cache metrics
-	CacheHits = promauto.NewCounter(
+	CacheHits = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "auth_cache_hits_total",
 			Help: "Total number of cache hits",
 		},
+		[]string{"cache"},
 	)
 
-	CacheMisses = promauto.NewCounter(
+	CacheMisses = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "auth_cache_misses_total",
 			Help: "Total number of cache misses",
 		},
+		[]string{"cache"},
+	)
+
+	CacheEvictions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_cache_evictions_total",
+			Help: "Total number of entries dropped from an in-process cache",
+		},
+		[]string{"cache", "reason"},
 	)
 )
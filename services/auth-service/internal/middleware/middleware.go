@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"crypto/rand"
 	"net/http"
 	"os"
 	"strings"
@@ -18,7 +19,7 @@ func CORS() gin.HandlerFunc {
 			c.Header("Access-Control-Allow-Credentials", "true")
 		}
 
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, X-Request-ID, Authorization, accept, origin, Cache-Control, X-Requested-With")
 		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -53,3 +54,35 @@ func Logger() gin.HandlerFunc {
 		SkipPaths: []string{"/metrics", "/health"},
 	})
 }
+
+// RequestIDKey is the context key RequestID stores the request's ID under.
+const RequestIDKey = "requestID"
+
+const requestIDHeader = "X-Request-ID"
+
+// RequestID tags every request with an ID, taken from X-Request-ID when the
+// gateway set a usable one and generated otherwise, and echoes it in the
+// response so a client report can be matched with the audit trail.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = rand.Text()
+		}
+		c.Set(RequestIDKey, id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
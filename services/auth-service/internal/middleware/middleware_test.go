@@ -198,3 +198,35 @@ func TestLogger_WithMultipleRequests(t *testing.T) {
 		}
 	}
 }
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(RequestIDKey))
+	})
+
+	do := func(header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		if header != "" {
+			req.Header.Set("X-Request-ID", header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("gateway-abc-123")
+	if w.Body.String() != "gateway-abc-123" || w.Header().Get("X-Request-ID") != "gateway-abc-123" {
+		t.Fatalf("expected the incoming ID to be kept, got %q / %q", w.Body.String(), w.Header().Get("X-Request-ID"))
+	}
+
+	for _, header := range []string{"", strings.Repeat("a", 65), "has space"} {
+		w := do(header)
+		if id := w.Body.String(); id == "" || id == header || w.Header().Get("X-Request-ID") != id {
+			t.Errorf("%q: expected a generated ID, got %q", header, id)
+		}
+	}
+}
@@ -1,94 +1,226 @@
 package middleware
 
 import (
+	"context"
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/icl00ud/velure/shared/logger"
+	"github.com/redis/go-redis/v9"
 )
 
-type RateLimiter struct {
-	visitors map[string]*Visitor
-	mu       sync.RWMutex
-	rate     int
-	burst    int
-	ticker   *time.Ticker
-	stop     chan struct{}
+// RatePolicy allows Limit requests per Period on average per client IP, and
+// up to Burst (default Limit) in a row.
+type RatePolicy struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
 }
 
-type Visitor struct {
-	lastSeen time.Time
-	tokens   int
-	mu       sync.Mutex
+// PerSecond is a policy of rate requests per second with the given burst.
+func PerSecond(rate, burst int) RatePolicy {
+	return RatePolicy{Limit: rate, Period: time.Second, Burst: burst}
 }
 
-func NewRateLimiter(requestsPerSecond, burst int) *RateLimiter {
-	return newRateLimiterWithInterval(requestsPerSecond, burst, time.Minute)
+// PerMinute is a policy of limit requests per minute, all usable at once.
+func PerMinute(limit int) RatePolicy {
+	return RatePolicy{Limit: limit, Period: time.Minute}
 }
 
-func newRateLimiterWithInterval(requestsPerSecond, burst int, interval time.Duration) *RateLimiter {
-	rl := &RateLimiter{
-		visitors: make(map[string]*Visitor),
-		rate:     requestsPerSecond,
-		burst:    burst,
-		ticker:   time.NewTicker(interval),
-		stop:     make(chan struct{}),
+// PerHour is a policy of limit requests per hour, all usable at once.
+func PerHour(limit int) RatePolicy {
+	return RatePolicy{Limit: limit, Period: time.Hour}
+}
+
+func (p RatePolicy) normalized() RatePolicy {
+	if p.Limit <= 0 {
+		p.Limit = 1
 	}
+	if p.Period <= 0 {
+		p.Period = time.Second
+	}
+	if p.Burst <= 0 {
+		p.Burst = p.Limit
+	}
+	return p
+}
+
+// interval is the time one request "costs" (GCRA emission interval).
+func (p RatePolicy) interval() time.Duration {
+	return p.Period / time.Duration(p.Limit)
+}
 
-	// Goroutine para limpar visitantes inativos
-	go rl.cleanupVisitors()
+// RateLimitConfig holds the default policy and stricter or looser ones for
+// single routes, keyed by method and route pattern as registered with gin
+// ("POST /api/sessions"). A request counts against its route's policy only.
+type RateLimitConfig struct {
+	Default RatePolicy
+	Routes  map[string]RatePolicy
+}
 
-	return rl
+// decision is the outcome of one GCRA check.
+type decision struct {
+	allowed    bool
+	remaining  int
+	retryAfter time.Duration // until the next request would be allowed
+	reset      time.Duration // until the full burst is available again
 }
 
-func (rl *RateLimiter) getVisitor(ip string) *Visitor {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
+// gcra runs the generic cell rate algorithm on tat, the theoretical arrival
+// time of the next request, and returns the decision and the new tat.
+func gcra(p RatePolicy, tat, now time.Time) (decision, time.Time) {
+	interval := p.interval()
+	tolerance := interval * time.Duration(p.Burst)
+	if tat.Before(now) {
+		tat = now
+	}
 
-	v, exists := rl.visitors[ip]
-	if !exists {
-		v = &Visitor{
-			lastSeen: time.Now(),
-			tokens:   rl.burst,
-		}
-		rl.visitors[ip] = v
+	newTAT := tat.Add(interval)
+	used := newTAT.Sub(now)
+	if used > tolerance {
+		return decision{
+			retryAfter: used - tolerance,
+			reset:      tat.Sub(now),
+		}, tat
 	}
-	return v
+
+	return decision{
+		allowed:   true,
+		remaining: int((tolerance - used) / interval),
+		reset:     used,
+	}, newTAT
 }
 
-func (v *Visitor) allow(rate, burst int) bool {
-	v.mu.Lock()
-	defer v.mu.Unlock()
+// gcraScript is gcra for Redis, so every replica shares the same state. Times
+// are in microseconds; the key expires once the bucket is full again.
+var gcraScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local tolerance = tonumber(ARGV[3])
+
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then tat = now end
+
+local new_tat = tat + interval
+local used = new_tat - now
+if used > tolerance then
+  return {0, 0, used - tolerance, tat - now}
+end
+
+redis.call('SET', KEYS[1], new_tat, 'PX', math.ceil(used / 1000))
+return {1, math.floor((tolerance - used) / interval), 0, used}
+`)
+
+// RateLimiter limits requests per client IP. With Redis the limits are
+// shared by all replicas and survive deploys; while Redis is unreachable (or
+// without a client) each replica falls back to counting on its own.
+type RateLimiter struct {
+	redis    *redis.Client
+	config   RateLimitConfig
+	now      func() time.Time
+	redisErr atomic.Bool
+
+	mu     sync.Mutex
+	local  map[string]time.Time // key -> tat
+	ticker *time.Ticker
+	stop   chan struct{}
+}
+
+func NewRateLimiter(client *redis.Client, cfg RateLimitConfig) *RateLimiter {
+	return newRateLimiterWithInterval(client, cfg, time.Minute)
+}
+
+func newRateLimiterWithInterval(client *redis.Client, cfg RateLimitConfig, interval time.Duration) *RateLimiter {
+	cfg.Default = cfg.Default.normalized()
+	routes := make(map[string]RatePolicy, len(cfg.Routes))
+	for route, p := range cfg.Routes {
+		routes[route] = p.normalized()
+	}
+	cfg.Routes = routes
+
+	rl := &RateLimiter{
+		redis:  client,
+		config: cfg,
+		now:    time.Now,
+		local:  make(map[string]time.Time),
+		ticker: time.NewTicker(interval),
+		stop:   make(chan struct{}),
+	}
+
+	// Drop local entries whose bucket is full again.
+	go rl.cleanupLocal()
+
+	return rl
+}
 
-	now := time.Now()
-	elapsed := now.Sub(v.lastSeen).Seconds()
-	v.lastSeen = now
+// policy returns the policy for the request's route and the key its
+// counters live under.
+func (rl *RateLimiter) policy(c *gin.Context) (RatePolicy, string) {
+	route := c.Request.Method + " " + c.FullPath()
+	if p, ok := rl.config.Routes[route]; ok {
+		return p, "ratelimit:" + route + ":" + c.ClientIP()
+	}
+	return rl.config.Default, "ratelimit:default:" + c.ClientIP()
+}
 
-	// Adicionar tokens baseado no tempo decorrido
-	v.tokens += int(elapsed * float64(rate))
-	if v.tokens > burst {
-		v.tokens = burst
+func (rl *RateLimiter) allow(ctx context.Context, p RatePolicy, key string) decision {
+	if rl.redis != nil {
+		d, err := rl.allowRedis(ctx, p, key)
+		if err == nil {
+			if rl.redisErr.Swap(false) {
+				logger.Info("rate limiter using redis again")
+			}
+			return d
+		}
+		if !rl.redisErr.Swap(true) {
+			logger.Warn("rate limiter falling back to local counters", logger.Err(err))
+		}
 	}
+	return rl.allowLocal(p, key)
+}
 
-	if v.tokens > 0 {
-		v.tokens--
-		return true
+func (rl *RateLimiter) allowRedis(ctx context.Context, p RatePolicy, key string) (decision, error) {
+	interval := p.interval()
+	res, err := gcraScript.Run(ctx, rl.redis, []string{key},
+		rl.now().UnixMicro(),
+		interval.Microseconds(),
+		(interval * time.Duration(p.Burst)).Microseconds(),
+	).Int64Slice()
+	if err != nil {
+		return decision{}, err
 	}
-	return false
+	return decision{
+		allowed:    res[0] == 1,
+		remaining:  int(res[1]),
+		retryAfter: time.Duration(res[2]) * time.Microsecond,
+		reset:      time.Duration(res[3]) * time.Microsecond,
+	}, nil
 }
 
-func (rl *RateLimiter) cleanupVisitors() {
+func (rl *RateLimiter) allowLocal(p RatePolicy, key string) decision {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	d, tat := gcra(p, rl.local[key], rl.now())
+	rl.local[key] = tat
+	return d
+}
+
+func (rl *RateLimiter) cleanupLocal() {
 	for {
 		select {
 		case <-rl.ticker.C:
+			now := rl.now()
 			rl.mu.Lock()
-			for ip, v := range rl.visitors {
-				v.mu.Lock()
-				if time.Since(v.lastSeen) > 3*time.Minute {
-					delete(rl.visitors, ip)
+			for key, tat := range rl.local {
+				if !tat.After(now) {
+					delete(rl.local, key)
 				}
-				v.mu.Unlock()
 			}
 			rl.mu.Unlock()
 		case <-rl.stop:
@@ -97,13 +229,19 @@ func (rl *RateLimiter) cleanupVisitors() {
 	}
 }
 
+// Middleware rejects requests over their route's limit with 429 and tells
+// every client where it stands through the RateLimit-* headers.
 func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ip := c.ClientIP()
-		visitor := rl.getVisitor(ip)
+		p, key := rl.policy(c)
+		d := rl.allow(c.Request.Context(), p, key)
+
+		c.Header("RateLimit-Limit", strconv.Itoa(p.Burst))
+		c.Header("RateLimit-Remaining", strconv.Itoa(d.remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(d.reset)))
 
-		// Verificar rate limit (visitor.allow já é thread-safe com mutex)
-		if !visitor.allow(rl.rate, rl.burst) {
+		if !d.allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(d.retryAfter)))
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": "rate limit exceeded",
 			})
@@ -115,7 +253,11 @@ func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 	}
 }
 
-// Stop encerra a goroutine de limpeza (usado em testes)
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// Stop ends the cleanup goroutine (used in tests).
 func (rl *RateLimiter) Stop() {
 	if rl.ticker != nil {
 		rl.ticker.Stop()
@@ -126,35 +268,3 @@ func (rl *RateLimiter) Stop() {
 		close(rl.stop)
 	}
 }
-
-// RateLimitByEndpoint aplica rate limiting específico por endpoint
-func RateLimitByEndpoint(requestsPerSecond, burst int) gin.HandlerFunc {
-	limiters := make(map[string]*RateLimiter)
-	var mu sync.RWMutex
-
-	return func(c *gin.Context) {
-		key := c.ClientIP() + ":" + c.FullPath()
-
-		mu.RLock()
-		limiter, exists := limiters[key]
-		mu.RUnlock()
-
-		if !exists {
-			mu.Lock()
-			limiter = NewRateLimiter(requestsPerSecond, burst)
-			limiters[key] = limiter
-			mu.Unlock()
-		}
-
-		visitor := limiter.getVisitor(c.ClientIP())
-		if !visitor.allow(requestsPerSecond, burst) {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "rate limit exceeded for this endpoint",
-			})
-			c.Abort()
-			return
-		}
-
-		c.Next()
-	}
-}
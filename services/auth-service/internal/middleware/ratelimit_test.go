@@ -6,106 +6,160 @@ import (
 	"testing"
 	"time"
 
+	miniredis "github.com/alicebob/miniredis/v2"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 )
 
-func TestRateLimiterAllowRefill(t *testing.T) {
-	limiter := newRateLimiterWithInterval(100, 2, 10*time.Millisecond)
-	defer limiter.Stop()
+// fakeClock is a settable clock for the limiter.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter(t *testing.T, client *redis.Client, cfg RateLimitConfig) (*RateLimiter, *fakeClock) {
+	t.Helper()
+	limiter := newRateLimiterWithInterval(client, cfg, time.Hour)
+	t.Cleanup(limiter.Stop)
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	limiter.now = clock.now
+	return limiter, clock
+}
+
+func newLimitedRouter(limiter *RateLimiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(limiter.Middleware())
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	router.GET("/resource", ok)
+	router.POST("/login", ok)
+	router.GET("/other", ok)
+	return router
+}
+
+func serve(router *gin.Engine, method, path, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = ip + ":1234"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
 
-	visitor := limiter.getVisitor("127.0.0.1")
+func TestGCRA_BurstAndRefill(t *testing.T) {
+	p := PerSecond(10, 2).normalized()
+	now := time.Unix(0, 0)
+	var tat time.Time
 
-	if !visitor.allow(limiter.rate, limiter.burst) {
-		t.Fatalf("expected first request to be allowed")
+	d, tat := gcra(p, tat, now)
+	if !d.allowed || d.remaining != 1 {
+		t.Fatalf("expected first request allowed with 1 left, got %+v", d)
 	}
-	if !visitor.allow(limiter.rate, limiter.burst) {
-		t.Fatalf("expected second request to use burst token")
+	d, tat = gcra(p, tat, now)
+	if !d.allowed || d.remaining != 0 {
+		t.Fatalf("expected second request to use the burst, got %+v", d)
 	}
-	if visitor.allow(limiter.rate, limiter.burst) {
-		t.Fatalf("expected third request to be blocked due to rate limit")
+	d, tat = gcra(p, tat, now)
+	if d.allowed || d.retryAfter != 100*time.Millisecond {
+		t.Fatalf("expected third request blocked for 100ms, got %+v", d)
 	}
 
-	time.Sleep(20 * time.Millisecond)
-
-	if !visitor.allow(limiter.rate, limiter.burst) {
-		t.Fatalf("expected tokens to refill after interval")
+	d, _ = gcra(p, tat, now.Add(100*time.Millisecond))
+	if !d.allowed {
+		t.Fatalf("expected one request to be allowed again after the interval, got %+v", d)
 	}
 }
 
-func TestRateLimiterMiddlewareBlocks(t *testing.T) {
-	gin.SetMode(gin.TestMode)
-	limiter := newRateLimiterWithInterval(1, 1, time.Hour)
-	defer limiter.Stop()
+func TestRateLimiter_Redis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
 
-	router := gin.New()
-	router.Use(limiter.Middleware())
-	router.GET("/resource", func(c *gin.Context) {
-		c.String(http.StatusOK, "ok")
-	})
+	cfg := RateLimitConfig{Default: PerSecond(1, 2)}
+	limiter, clock := newTestLimiter(t, client, cfg)
+	router := newLimitedRouter(limiter)
 
-	req := httptest.NewRequest("GET", "/resource", nil)
-	w := httptest.NewRecorder()
-	router.ServeHTTP(w, req)
-	if w.Code != http.StatusOK {
-		t.Fatalf("expected first request to succeed, got %d", w.Code)
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if w := serve(router, http.MethodGet, "/resource", "10.0.0.1"); w.Code != want {
+			t.Fatalf("request %d: expected %d, got %d", i+1, want, w.Code)
+		}
 	}
 
-	w = httptest.NewRecorder()
-	router.ServeHTTP(w, req)
+	// A second replica sharing the same Redis sees the same budget.
+	replica, _ := newTestLimiter(t, client, cfg)
+	replica.now = clock.now
+	w := serve(newLimitedRouter(replica), http.MethodGet, "/resource", "10.0.0.1")
 	if w.Code != http.StatusTooManyRequests {
-		t.Fatalf("expected second request to be rate limited, got %d", w.Code)
+		t.Fatalf("expected the other replica to share the limit, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" || w.Header().Get("RateLimit-Remaining") != "0" || w.Header().Get("RateLimit-Limit") != "2" {
+		t.Fatalf("unexpected headers %v", w.Header())
 	}
-}
 
-func TestRateLimitByEndpoint(t *testing.T) {
-	gin.SetMode(gin.TestMode)
+	clock.advance(time.Second)
+	if w := serve(router, http.MethodGet, "/resource", "10.0.0.1"); w.Code != http.StatusOK {
+		t.Fatalf("expected a request to be allowed after a second, got %d", w.Code)
+	}
+	if w := serve(router, http.MethodGet, "/resource", "10.0.0.2"); w.Code != http.StatusOK {
+		t.Fatalf("expected another client to have its own budget, got %d", w.Code)
+	}
+}
 
-	router := gin.New()
-	router.Use(RateLimitByEndpoint(1, 1))
-	router.GET("/a", func(c *gin.Context) {
-		c.String(http.StatusOK, "a")
-	})
-	router.GET("/b", func(c *gin.Context) {
-		c.String(http.StatusOK, "b")
+func TestRateLimiter_RoutePolicies(t *testing.T) {
+	limiter, _ := newTestLimiter(t, nil, RateLimitConfig{
+		Default: PerSecond(100, 100),
+		Routes:  map[string]RatePolicy{"POST /login": PerMinute(1)},
 	})
+	router := newLimitedRouter(limiter)
 
-	reqA := httptest.NewRequest("GET", "/a", nil)
-	w := httptest.NewRecorder()
-	router.ServeHTTP(w, reqA)
-	if w.Code != http.StatusOK {
-		t.Fatalf("expected first request to /a to succeed, got %d", w.Code)
+	if w := serve(router, http.MethodPost, "/login", "10.0.0.1"); w.Code != http.StatusOK {
+		t.Fatalf("expected first login to pass, got %d", w.Code)
+	}
+	w := serve(router, http.MethodPost, "/login", "10.0.0.1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("expected second login to wait a minute, got %d %q", w.Code, w.Header().Get("Retry-After"))
 	}
 
-	w = httptest.NewRecorder()
-	router.ServeHTTP(w, reqA)
-	if w.Code != http.StatusTooManyRequests {
-		t.Fatalf("expected second request to /a to be limited, got %d", w.Code)
+	// Other routes are unaffected.
+	w = serve(router, http.MethodGet, "/other", "10.0.0.1")
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "100" || w.Header().Get("RateLimit-Remaining") != "99" {
+		t.Fatalf("expected the default policy on /other, got %d %v", w.Code, w.Header())
 	}
+}
 
-	reqB := httptest.NewRequest("GET", "/b", nil)
-	w = httptest.NewRecorder()
-	router.ServeHTTP(w, reqB)
-	if w.Code != http.StatusOK {
-		t.Fatalf("expected request to different endpoint to pass, got %d", w.Code)
+func TestRateLimiter_FallsBackWhenRedisIsDown(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	mr.Close()
+
+	limiter, _ := newTestLimiter(t, client, RateLimitConfig{Default: PerMinute(1)})
+	router := newLimitedRouter(limiter)
+
+	if w := serve(router, http.MethodGet, "/resource", "10.0.0.1"); w.Code != http.StatusOK {
+		t.Fatalf("expected first request to pass without redis, got %d", w.Code)
+	}
+	if w := serve(router, http.MethodGet, "/resource", "10.0.0.1"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the local fallback to enforce the limit, got %d", w.Code)
 	}
 }
 
-func TestRateLimiterCleanupVisitors(t *testing.T) {
-	limiter := newRateLimiterWithInterval(1, 1, 5*time.Millisecond)
+func TestRateLimiter_CleanupLocal(t *testing.T) {
+	limiter := newRateLimiterWithInterval(nil, RateLimitConfig{Default: PerSecond(1, 1)}, 5*time.Millisecond)
 	defer limiter.Stop()
 
-	limiter.visitors["127.0.0.1"] = &Visitor{
-		lastSeen: time.Now().Add(-4 * time.Minute),
-		tokens:   0,
-	}
+	limiter.mu.Lock()
+	limiter.local["ratelimit:default:127.0.0.1"] = time.Now().Add(-time.Minute)
+	limiter.local["ratelimit:default:127.0.0.2"] = time.Now().Add(time.Hour)
+	limiter.mu.Unlock()
 
-	time.Sleep(15 * time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
 
-	limiter.mu.RLock()
-	_, exists := limiter.visitors["127.0.0.1"]
-	limiter.mu.RUnlock()
+	limiter.mu.Lock()
+	_, stale := limiter.local["ratelimit:default:127.0.0.1"]
+	_, fresh := limiter.local["ratelimit:default:127.0.0.2"]
+	limiter.mu.Unlock()
 
-	if exists {
-		t.Fatalf("expected old visitor to be cleaned up")
+	if stale || !fresh {
+		t.Fatalf("expected only the refilled entry to be cleaned up (stale=%v fresh=%v)", stale, fresh)
 	}
 }
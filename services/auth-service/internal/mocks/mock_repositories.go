@@ -13,6 +13,7 @@ import (
 	context "context"
 	models "github.com/icl00ud/velure/services/auth-service/internal/model"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -159,6 +160,51 @@ func (mr *MockUserRepositoryInterfaceMockRecorder) CountUsers(ctx any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUsers", reflect.TypeOf((*MockUserRepositoryInterface)(nil).CountUsers), ctx)
 }
 
+// UpdatePasswordHash mocks base method.
+func (m *MockUserRepositoryInterface) UpdatePasswordHash(id uint, oldHash, newHash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePasswordHash", id, oldHash, newHash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdatePasswordHash indicates an expected call of UpdatePasswordHash.
+func (mr *MockUserRepositoryInterfaceMockRecorder) UpdatePasswordHash(id, oldHash, newHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasswordHash", reflect.TypeOf((*MockUserRepositoryInterface)(nil).UpdatePasswordHash), id, oldHash, newHash)
+}
+
+// Search mocks base method.
+func (m *MockUserRepositoryInterface) Search(filter models.UserFilter, sort models.UserSort, after *models.User, limit int) ([]models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", filter, sort, after, limit)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockUserRepositoryInterfaceMockRecorder) Search(filter, sort, after, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockUserRepositoryInterface)(nil).Search), filter, sort, after, limit)
+}
+
+// GetByIDs mocks base method.
+func (m *MockUserRepositoryInterface) GetByIDs(ids []uint) ([]models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByIDs", ids)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByIDs indicates an expected call of GetByIDs.
+func (mr *MockUserRepositoryInterfaceMockRecorder) GetByIDs(ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByIDs", reflect.TypeOf((*MockUserRepositoryInterface)(nil).GetByIDs), ids)
+}
+
 // MockSessionRepositoryInterface is a mock of SessionRepositoryInterface interface.
 type MockSessionRepositoryInterface struct {
 	ctrl     *gomock.Controller
@@ -226,21 +272,6 @@ func (mr *MockSessionRepositoryInterfaceMockRecorder) GetByRefreshToken(refreshT
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByRefreshToken", reflect.TypeOf((*MockSessionRepositoryInterface)(nil).GetByRefreshToken), refreshToken)
 }
 
-// GetByUserID mocks base method.
-func (m *MockSessionRepositoryInterface) GetByUserID(userID uint) (*models.Session, error) {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByUserID", userID)
-	ret0, _ := ret[0].(*models.Session)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
-}
-
-// GetByUserID indicates an expected call of GetByUserID.
-func (mr *MockSessionRepositoryInterfaceMockRecorder) GetByUserID(userID any) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserID", reflect.TypeOf((*MockSessionRepositoryInterface)(nil).GetByUserID), userID)
-}
-
 // InvalidateByRefreshToken mocks base method.
 func (m *MockSessionRepositoryInterface) InvalidateByRefreshToken(refreshToken string) error {
 	m.ctrl.T.Helper()
@@ -284,6 +315,136 @@ func (mr *MockSessionRepositoryInterfaceMockRecorder) Update(session any) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockSessionRepositoryInterface)(nil).Update), session)
 }
 
+// InvalidateFamily mocks base method.
+func (m *MockSessionRepositoryInterface) InvalidateFamily(familyID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InvalidateFamily", familyID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InvalidateFamily indicates an expected call of InvalidateFamily.
+func (mr *MockSessionRepositoryInterfaceMockRecorder) InvalidateFamily(familyID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateFamily", reflect.TypeOf((*MockSessionRepositoryInterface)(nil).InvalidateFamily), familyID)
+}
+
+// AssignFamily mocks base method.
+func (m *MockSessionRepositoryInterface) AssignFamily(session *models.Session, familyID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssignFamily", session, familyID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AssignFamily indicates an expected call of AssignFamily.
+func (mr *MockSessionRepositoryInterfaceMockRecorder) AssignFamily(session, familyID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignFamily", reflect.TypeOf((*MockSessionRepositoryInterface)(nil).AssignFamily), session, familyID)
+}
+
+// MarkRotated mocks base method.
+func (m *MockSessionRepositoryInterface) MarkRotated(session *models.Session) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkRotated", session)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkRotated indicates an expected call of MarkRotated.
+func (mr *MockSessionRepositoryInterfaceMockRecorder) MarkRotated(session any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkRotated", reflect.TypeOf((*MockSessionRepositoryInterface)(nil).MarkRotated), session)
+}
+
+// InvalidateByUserID mocks base method.
+func (m *MockSessionRepositoryInterface) InvalidateByUserID(userID uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InvalidateByUserID", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InvalidateByUserID indicates an expected call of InvalidateByUserID.
+func (mr *MockSessionRepositoryInterfaceMockRecorder) InvalidateByUserID(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateByUserID", reflect.TypeOf((*MockSessionRepositoryInterface)(nil).InvalidateByUserID), userID)
+}
+
+// GetByID mocks base method.
+func (m *MockSessionRepositoryInterface) GetByID(id uint) (*models.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", id)
+	ret0, _ := ret[0].(*models.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *MockSessionRepositoryInterfaceMockRecorder) GetByID(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockSessionRepositoryInterface)(nil).GetByID), id)
+}
+
+// Invalidate mocks base method.
+func (m *MockSessionRepositoryInterface) Invalidate(id uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Invalidate", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Invalidate indicates an expected call of Invalidate.
+func (mr *MockSessionRepositoryInterfaceMockRecorder) Invalidate(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Invalidate", reflect.TypeOf((*MockSessionRepositoryInterface)(nil).Invalidate), id)
+}
+
+// ListActiveByUserID mocks base method.
+func (m *MockSessionRepositoryInterface) ListActiveByUserID(userID uint) ([]models.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListActiveByUserID", userID)
+	ret0, _ := ret[0].([]models.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListActiveByUserID indicates an expected call of ListActiveByUserID.
+func (mr *MockSessionRepositoryInterfaceMockRecorder) ListActiveByUserID(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListActiveByUserID", reflect.TypeOf((*MockSessionRepositoryInterface)(nil).ListActiveByUserID), userID)
+}
+
+// ListIssuedSince mocks base method.
+func (m *MockSessionRepositoryInterface) ListIssuedSince(userID uint, since time.Time) ([]models.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListIssuedSince", userID, since)
+	ret0, _ := ret[0].([]models.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListIssuedSince indicates an expected call of ListIssuedSince.
+func (mr *MockSessionRepositoryInterfaceMockRecorder) ListIssuedSince(userID, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListIssuedSince", reflect.TypeOf((*MockSessionRepositoryInterface)(nil).ListIssuedSince), userID, since)
+}
+
+// Touch mocks base method.
+func (m *MockSessionRepositoryInterface) Touch(accessToken string, now time.Time, interval time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Touch", accessToken, now, interval)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Touch indicates an expected call of Touch.
+func (mr *MockSessionRepositoryInterfaceMockRecorder) Touch(accessToken, now, interval any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Touch", reflect.TypeOf((*MockSessionRepositoryInterface)(nil).Touch), accessToken, now, interval)
+}
+
 // MockPasswordResetRepositoryInterface is a mock of PasswordResetRepositoryInterface interface.
 type MockPasswordResetRepositoryInterface struct {
 	ctrl     *gomock.Controller
@@ -350,3 +511,383 @@ func (mr *MockPasswordResetRepositoryInterfaceMockRecorder) GetByToken(token any
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByToken", reflect.TypeOf((*MockPasswordResetRepositoryInterface)(nil).GetByToken), token)
 }
+
+// DeleteByUserID mocks base method.
+func (m *MockPasswordResetRepositoryInterface) DeleteByUserID(userID uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteByUserID", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteByUserID indicates an expected call of DeleteByUserID.
+func (mr *MockPasswordResetRepositoryInterfaceMockRecorder) DeleteByUserID(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByUserID", reflect.TypeOf((*MockPasswordResetRepositoryInterface)(nil).DeleteByUserID), userID)
+}
+
+// MockEmailVerificationRepositoryInterface is a mock of EmailVerificationRepositoryInterface interface.
+type MockEmailVerificationRepositoryInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockEmailVerificationRepositoryInterfaceMockRecorder
+	isgomock struct{}
+}
+
+// MockEmailVerificationRepositoryInterfaceMockRecorder is the mock recorder for MockEmailVerificationRepositoryInterface.
+type MockEmailVerificationRepositoryInterfaceMockRecorder struct {
+	mock *MockEmailVerificationRepositoryInterface
+}
+
+// NewMockEmailVerificationRepositoryInterface creates a new mock instance.
+func NewMockEmailVerificationRepositoryInterface(ctrl *gomock.Controller) *MockEmailVerificationRepositoryInterface {
+	mock := &MockEmailVerificationRepositoryInterface{ctrl: ctrl}
+	mock.recorder = &MockEmailVerificationRepositoryInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEmailVerificationRepositoryInterface) EXPECT() *MockEmailVerificationRepositoryInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockEmailVerificationRepositoryInterface) Create(verification *models.EmailVerification) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", verification)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockEmailVerificationRepositoryInterfaceMockRecorder) Create(verification any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockEmailVerificationRepositoryInterface)(nil).Create), verification)
+}
+
+// Delete mocks base method.
+func (m *MockEmailVerificationRepositoryInterface) Delete(id uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockEmailVerificationRepositoryInterfaceMockRecorder) Delete(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockEmailVerificationRepositoryInterface)(nil).Delete), id)
+}
+
+// DeleteByUserID mocks base method.
+func (m *MockEmailVerificationRepositoryInterface) DeleteByUserID(userID uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteByUserID", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteByUserID indicates an expected call of DeleteByUserID.
+func (mr *MockEmailVerificationRepositoryInterfaceMockRecorder) DeleteByUserID(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByUserID", reflect.TypeOf((*MockEmailVerificationRepositoryInterface)(nil).DeleteByUserID), userID)
+}
+
+// GetByToken mocks base method.
+func (m *MockEmailVerificationRepositoryInterface) GetByToken(token string) (*models.EmailVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByToken", token)
+	ret0, _ := ret[0].(*models.EmailVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByToken indicates an expected call of GetByToken.
+func (mr *MockEmailVerificationRepositoryInterfaceMockRecorder) GetByToken(token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByToken", reflect.TypeOf((*MockEmailVerificationRepositoryInterface)(nil).GetByToken), token)
+}
+
+// GetLatestByUserID mocks base method.
+func (m *MockEmailVerificationRepositoryInterface) GetLatestByUserID(userID uint) (*models.EmailVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatestByUserID", userID)
+	ret0, _ := ret[0].(*models.EmailVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLatestByUserID indicates an expected call of GetLatestByUserID.
+func (mr *MockEmailVerificationRepositoryInterfaceMockRecorder) GetLatestByUserID(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestByUserID", reflect.TypeOf((*MockEmailVerificationRepositoryInterface)(nil).GetLatestByUserID), userID)
+}
+
+// MockUserIdentityRepositoryInterface is a mock of UserIdentityRepositoryInterface interface.
+type MockUserIdentityRepositoryInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserIdentityRepositoryInterfaceMockRecorder
+	isgomock struct{}
+}
+
+// MockUserIdentityRepositoryInterfaceMockRecorder is the mock recorder for MockUserIdentityRepositoryInterface.
+type MockUserIdentityRepositoryInterfaceMockRecorder struct {
+	mock *MockUserIdentityRepositoryInterface
+}
+
+// NewMockUserIdentityRepositoryInterface creates a new mock instance.
+func NewMockUserIdentityRepositoryInterface(ctrl *gomock.Controller) *MockUserIdentityRepositoryInterface {
+	mock := &MockUserIdentityRepositoryInterface{ctrl: ctrl}
+	mock.recorder = &MockUserIdentityRepositoryInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserIdentityRepositoryInterface) EXPECT() *MockUserIdentityRepositoryInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockUserIdentityRepositoryInterface) Create(identity *models.UserIdentity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockUserIdentityRepositoryInterfaceMockRecorder) Create(identity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUserIdentityRepositoryInterface)(nil).Create), identity)
+}
+
+// GetByProviderSubject mocks base method.
+func (m *MockUserIdentityRepositoryInterface) GetByProviderSubject(provider, subject string) (*models.UserIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByProviderSubject", provider, subject)
+	ret0, _ := ret[0].(*models.UserIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByProviderSubject indicates an expected call of GetByProviderSubject.
+func (mr *MockUserIdentityRepositoryInterfaceMockRecorder) GetByProviderSubject(provider, subject any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByProviderSubject", reflect.TypeOf((*MockUserIdentityRepositoryInterface)(nil).GetByProviderSubject), provider, subject)
+}
+
+// MockAuditEventRepositoryInterface is a mock of AuditEventRepositoryInterface interface.
+type MockAuditEventRepositoryInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditEventRepositoryInterfaceMockRecorder
+	isgomock struct{}
+}
+
+// MockAuditEventRepositoryInterfaceMockRecorder is the mock recorder for MockAuditEventRepositoryInterface.
+type MockAuditEventRepositoryInterfaceMockRecorder struct {
+	mock *MockAuditEventRepositoryInterface
+}
+
+// NewMockAuditEventRepositoryInterface creates a new mock instance.
+func NewMockAuditEventRepositoryInterface(ctrl *gomock.Controller) *MockAuditEventRepositoryInterface {
+	mock := &MockAuditEventRepositoryInterface{ctrl: ctrl}
+	mock.recorder = &MockAuditEventRepositoryInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditEventRepositoryInterface) EXPECT() *MockAuditEventRepositoryInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockAuditEventRepositoryInterface) Create(event *models.AuditEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockAuditEventRepositoryInterfaceMockRecorder) Create(event any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockAuditEventRepositoryInterface)(nil).Create), event)
+}
+
+// List mocks base method.
+func (m *MockAuditEventRepositoryInterface) List(filter models.AuditEventFilter, beforeID uint, limit int) ([]models.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", filter, beforeID, limit)
+	ret0, _ := ret[0].([]models.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockAuditEventRepositoryInterfaceMockRecorder) List(filter, beforeID, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockAuditEventRepositoryInterface)(nil).List), filter, beforeID, limit)
+}
+
+// ListAfter mocks base method.
+func (m *MockAuditEventRepositoryInterface) ListAfter(filter models.AuditEventFilter, afterID uint, limit int) ([]models.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAfter", filter, afterID, limit)
+	ret0, _ := ret[0].([]models.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAfter indicates an expected call of ListAfter.
+func (mr *MockAuditEventRepositoryInterfaceMockRecorder) ListAfter(filter, afterID, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAfter", reflect.TypeOf((*MockAuditEventRepositoryInterface)(nil).ListAfter), filter, afterID, limit)
+}
+
+// MockPasswordHistoryRepositoryInterface is a mock of PasswordHistoryRepositoryInterface interface.
+type MockPasswordHistoryRepositoryInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockPasswordHistoryRepositoryInterfaceMockRecorder
+	isgomock struct{}
+}
+
+// MockPasswordHistoryRepositoryInterfaceMockRecorder is the mock recorder for MockPasswordHistoryRepositoryInterface.
+type MockPasswordHistoryRepositoryInterfaceMockRecorder struct {
+	mock *MockPasswordHistoryRepositoryInterface
+}
+
+// NewMockPasswordHistoryRepositoryInterface creates a new mock instance.
+func NewMockPasswordHistoryRepositoryInterface(ctrl *gomock.Controller) *MockPasswordHistoryRepositoryInterface {
+	mock := &MockPasswordHistoryRepositoryInterface{ctrl: ctrl}
+	mock.recorder = &MockPasswordHistoryRepositoryInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPasswordHistoryRepositoryInterface) EXPECT() *MockPasswordHistoryRepositoryInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockPasswordHistoryRepositoryInterface) Add(userID uint, hash string, keep int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", userID, hash, keep)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockPasswordHistoryRepositoryInterfaceMockRecorder) Add(userID, hash, keep any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockPasswordHistoryRepositoryInterface)(nil).Add), userID, hash, keep)
+}
+
+// ListRecent mocks base method.
+func (m *MockPasswordHistoryRepositoryInterface) ListRecent(userID uint, limit int) ([]models.PasswordHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRecent", userID, limit)
+	ret0, _ := ret[0].([]models.PasswordHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRecent indicates an expected call of ListRecent.
+func (mr *MockPasswordHistoryRepositoryInterfaceMockRecorder) ListRecent(userID, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRecent", reflect.TypeOf((*MockPasswordHistoryRepositoryInterface)(nil).ListRecent), userID, limit)
+}
+
+// MockOAuthClientRepositoryInterface is a mock of OAuthClientRepositoryInterface interface.
+type MockOAuthClientRepositoryInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuthClientRepositoryInterfaceMockRecorder
+	isgomock struct{}
+}
+
+// MockOAuthClientRepositoryInterfaceMockRecorder is the mock recorder for MockOAuthClientRepositoryInterface.
+type MockOAuthClientRepositoryInterfaceMockRecorder struct {
+	mock *MockOAuthClientRepositoryInterface
+}
+
+// NewMockOAuthClientRepositoryInterface creates a new mock instance.
+func NewMockOAuthClientRepositoryInterface(ctrl *gomock.Controller) *MockOAuthClientRepositoryInterface {
+	mock := &MockOAuthClientRepositoryInterface{ctrl: ctrl}
+	mock.recorder = &MockOAuthClientRepositoryInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuthClientRepositoryInterface) EXPECT() *MockOAuthClientRepositoryInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockOAuthClientRepositoryInterface) Create(client *models.OAuthClient) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", client)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockOAuthClientRepositoryInterfaceMockRecorder) Create(client any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockOAuthClientRepositoryInterface)(nil).Create), client)
+}
+
+// DeleteByClientID mocks base method.
+func (m *MockOAuthClientRepositoryInterface) DeleteByClientID(clientID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteByClientID", clientID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteByClientID indicates an expected call of DeleteByClientID.
+func (mr *MockOAuthClientRepositoryInterfaceMockRecorder) DeleteByClientID(clientID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByClientID", reflect.TypeOf((*MockOAuthClientRepositoryInterface)(nil).DeleteByClientID), clientID)
+}
+
+// GetByClientID mocks base method.
+func (m *MockOAuthClientRepositoryInterface) GetByClientID(clientID string) (*models.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByClientID", clientID)
+	ret0, _ := ret[0].(*models.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByClientID indicates an expected call of GetByClientID.
+func (mr *MockOAuthClientRepositoryInterfaceMockRecorder) GetByClientID(clientID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByClientID", reflect.TypeOf((*MockOAuthClientRepositoryInterface)(nil).GetByClientID), clientID)
+}
+
+// List mocks base method.
+func (m *MockOAuthClientRepositoryInterface) List() ([]models.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]models.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockOAuthClientRepositoryInterfaceMockRecorder) List() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockOAuthClientRepositoryInterface)(nil).List))
+}
+
+// Update mocks base method.
+func (m *MockOAuthClientRepositoryInterface) Update(client *models.OAuthClient) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", client)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockOAuthClientRepositoryInterfaceMockRecorder) Update(client any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockOAuthClientRepositoryInterface)(nil).Update), client)
+}
@@ -10,7 +10,9 @@
 package mocks
 
 import (
+	context "context"
 	models "github.com/icl00ud/velure/services/auth-service/internal/model"
+	auth "github.com/icl00ud/velure/shared/auth"
 	reflect "reflect"
 
 	gomock "go.uber.org/mock/gomock"
@@ -131,17 +133,17 @@ func (mr *MockAuthServiceInterfaceMockRecorder) Login(req any) *gomock.Call {
 }
 
 // Logout mocks base method.
-func (m *MockAuthServiceInterface) Logout(refreshToken string) error {
+func (m *MockAuthServiceInterface) Logout(refreshToken string, device models.DeviceInfo) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Logout", refreshToken)
+	ret := m.ctrl.Call(m, "Logout", refreshToken, device)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Logout indicates an expected call of Logout.
-func (mr *MockAuthServiceInterfaceMockRecorder) Logout(refreshToken any) *gomock.Call {
+func (mr *MockAuthServiceInterfaceMockRecorder) Logout(refreshToken, device any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockAuthServiceInterface)(nil).Logout), refreshToken)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockAuthServiceInterface)(nil).Logout), refreshToken, device)
 }
 
 // ValidateAccessToken mocks base method.
@@ -158,3 +160,459 @@ func (mr *MockAuthServiceInterfaceMockRecorder) ValidateAccessToken(token any) *
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateAccessToken", reflect.TypeOf((*MockAuthServiceInterface)(nil).ValidateAccessToken), token)
 }
+
+// RefreshSession mocks base method.
+func (m *MockAuthServiceInterface) RefreshSession(refreshToken string, device models.DeviceInfo) (*models.LoginResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshSession", refreshToken, device)
+	ret0, _ := ret[0].(*models.LoginResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshSession indicates an expected call of RefreshSession.
+func (mr *MockAuthServiceInterfaceMockRecorder) RefreshSession(refreshToken, device any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshSession", reflect.TypeOf((*MockAuthServiceInterface)(nil).RefreshSession), refreshToken, device)
+}
+
+// RequestPasswordReset mocks base method.
+func (m *MockAuthServiceInterface) RequestPasswordReset(ctx context.Context, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestPasswordReset", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RequestPasswordReset indicates an expected call of RequestPasswordReset.
+func (mr *MockAuthServiceInterfaceMockRecorder) RequestPasswordReset(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestPasswordReset", reflect.TypeOf((*MockAuthServiceInterface)(nil).RequestPasswordReset), ctx, email)
+}
+
+// ResetPassword mocks base method.
+func (m *MockAuthServiceInterface) ResetPassword(token, newPassword string, device models.DeviceInfo) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", token, newPassword, device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockAuthServiceInterfaceMockRecorder) ResetPassword(token, newPassword, device any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockAuthServiceInterface)(nil).ResetPassword), token, newPassword, device)
+}
+
+// UpdateUserRoles mocks base method.
+func (m *MockAuthServiceInterface) UpdateUserRoles(id uint, roles []string) (*models.UserResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserRoles", id, roles)
+	ret0, _ := ret[0].(*models.UserResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUserRoles indicates an expected call of UpdateUserRoles.
+func (mr *MockAuthServiceInterfaceMockRecorder) UpdateUserRoles(id, roles any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserRoles", reflect.TypeOf((*MockAuthServiceInterface)(nil).UpdateUserRoles), id, roles)
+}
+
+// JWKS mocks base method.
+func (m *MockAuthServiceInterface) JWKS() (auth.JWKS, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JWKS")
+	ret0, _ := ret[0].(auth.JWKS)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// JWKS indicates an expected call of JWKS.
+func (mr *MockAuthServiceInterfaceMockRecorder) JWKS() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JWKS", reflect.TypeOf((*MockAuthServiceInterface)(nil).JWKS))
+}
+
+// ListSessions mocks base method.
+func (m *MockAuthServiceInterface) ListSessions(userID uint, currentAccessToken string) ([]models.SessionResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions", userID, currentAccessToken)
+	ret0, _ := ret[0].([]models.SessionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockAuthServiceInterfaceMockRecorder) ListSessions(userID, currentAccessToken any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockAuthServiceInterface)(nil).ListSessions), userID, currentAccessToken)
+}
+
+// RevokeAllSessions mocks base method.
+func (m *MockAuthServiceInterface) RevokeAllSessions(userID uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAllSessions", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAllSessions indicates an expected call of RevokeAllSessions.
+func (mr *MockAuthServiceInterfaceMockRecorder) RevokeAllSessions(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAllSessions", reflect.TypeOf((*MockAuthServiceInterface)(nil).RevokeAllSessions), userID)
+}
+
+// RevokeSession mocks base method.
+func (m *MockAuthServiceInterface) RevokeSession(userID, sessionID uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", userID, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockAuthServiceInterfaceMockRecorder) RevokeSession(userID, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockAuthServiceInterface)(nil).RevokeSession), userID, sessionID)
+}
+
+// CompleteTwoFactorLogin mocks base method.
+func (m *MockAuthServiceInterface) CompleteTwoFactorLogin(req models.TwoFactorLoginRequest) (*models.LoginResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteTwoFactorLogin", req)
+	ret0, _ := ret[0].(*models.LoginResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompleteTwoFactorLogin indicates an expected call of CompleteTwoFactorLogin.
+func (mr *MockAuthServiceInterfaceMockRecorder) CompleteTwoFactorLogin(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteTwoFactorLogin", reflect.TypeOf((*MockAuthServiceInterface)(nil).CompleteTwoFactorLogin), req)
+}
+
+// ConfirmTwoFactor mocks base method.
+func (m *MockAuthServiceInterface) ConfirmTwoFactor(userID uint, code string) (*models.RecoveryCodesResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTwoFactor", userID, code)
+	ret0, _ := ret[0].(*models.RecoveryCodesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmTwoFactor indicates an expected call of ConfirmTwoFactor.
+func (mr *MockAuthServiceInterfaceMockRecorder) ConfirmTwoFactor(userID, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTwoFactor", reflect.TypeOf((*MockAuthServiceInterface)(nil).ConfirmTwoFactor), userID, code)
+}
+
+// DisableTwoFactor mocks base method.
+func (m *MockAuthServiceInterface) DisableTwoFactor(userID uint, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTwoFactor", userID, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTwoFactor indicates an expected call of DisableTwoFactor.
+func (mr *MockAuthServiceInterfaceMockRecorder) DisableTwoFactor(userID, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTwoFactor", reflect.TypeOf((*MockAuthServiceInterface)(nil).DisableTwoFactor), userID, code)
+}
+
+// EnrollTwoFactor mocks base method.
+func (m *MockAuthServiceInterface) EnrollTwoFactor(userID uint) (*models.TwoFactorEnrollmentResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrollTwoFactor", userID)
+	ret0, _ := ret[0].(*models.TwoFactorEnrollmentResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollTwoFactor indicates an expected call of EnrollTwoFactor.
+func (mr *MockAuthServiceInterfaceMockRecorder) EnrollTwoFactor(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollTwoFactor", reflect.TypeOf((*MockAuthServiceInterface)(nil).EnrollTwoFactor), userID)
+}
+
+// RegenerateRecoveryCodes mocks base method.
+func (m *MockAuthServiceInterface) RegenerateRecoveryCodes(userID uint, code string) (*models.RecoveryCodesResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegenerateRecoveryCodes", userID, code)
+	ret0, _ := ret[0].(*models.RecoveryCodesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RegenerateRecoveryCodes indicates an expected call of RegenerateRecoveryCodes.
+func (mr *MockAuthServiceInterfaceMockRecorder) RegenerateRecoveryCodes(userID, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegenerateRecoveryCodes", reflect.TypeOf((*MockAuthServiceInterface)(nil).RegenerateRecoveryCodes), userID, code)
+}
+
+// DeleteAccount mocks base method.
+func (m *MockAuthServiceInterface) DeleteAccount(userID uint, password string, device models.DeviceInfo) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccount", userID, password, device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAccount indicates an expected call of DeleteAccount.
+func (mr *MockAuthServiceInterfaceMockRecorder) DeleteAccount(userID, password, device any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockAuthServiceInterface)(nil).DeleteAccount), userID, password, device)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockAuthServiceInterface) UpdateProfile(userID uint, currentAccessToken string, req models.UpdateProfileRequest) (*models.UserResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", userID, currentAccessToken, req)
+	ret0, _ := ret[0].(*models.UserResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateProfile indicates an expected call of UpdateProfile.
+func (mr *MockAuthServiceInterfaceMockRecorder) UpdateProfile(userID, currentAccessToken, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockAuthServiceInterface)(nil).UpdateProfile), userID, currentAccessToken, req)
+}
+
+// RequestEmailVerification mocks base method.
+func (m *MockAuthServiceInterface) RequestEmailVerification(userID uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestEmailVerification", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RequestEmailVerification indicates an expected call of RequestEmailVerification.
+func (mr *MockAuthServiceInterfaceMockRecorder) RequestEmailVerification(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestEmailVerification", reflect.TypeOf((*MockAuthServiceInterface)(nil).RequestEmailVerification), userID)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockAuthServiceInterface) VerifyEmail(token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockAuthServiceInterfaceMockRecorder) VerifyEmail(token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockAuthServiceInterface)(nil).VerifyEmail), token)
+}
+
+// CompleteOIDCLogin mocks base method.
+func (m *MockAuthServiceInterface) CompleteOIDCLogin(req models.OIDCLoginRequest) (*models.LoginResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteOIDCLogin", req)
+	ret0, _ := ret[0].(*models.LoginResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompleteOIDCLogin indicates an expected call of CompleteOIDCLogin.
+func (mr *MockAuthServiceInterfaceMockRecorder) CompleteOIDCLogin(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteOIDCLogin", reflect.TypeOf((*MockAuthServiceInterface)(nil).CompleteOIDCLogin), req)
+}
+
+// StartOIDCLogin mocks base method.
+func (m *MockAuthServiceInterface) StartOIDCLogin(provider string) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartOIDCLogin", provider)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// StartOIDCLogin indicates an expected call of StartOIDCLogin.
+func (mr *MockAuthServiceInterfaceMockRecorder) StartOIDCLogin(provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartOIDCLogin", reflect.TypeOf((*MockAuthServiceInterface)(nil).StartOIDCLogin), provider)
+}
+
+// ExportAuditEvents mocks base method.
+func (m *MockAuthServiceInterface) ExportAuditEvents(filter models.AuditEventFilter, afterID uint, emit func(models.AuditEvent) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportAuditEvents", filter, afterID, emit)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportAuditEvents indicates an expected call of ExportAuditEvents.
+func (mr *MockAuthServiceInterfaceMockRecorder) ExportAuditEvents(filter, afterID, emit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportAuditEvents", reflect.TypeOf((*MockAuthServiceInterface)(nil).ExportAuditEvents), filter, afterID, emit)
+}
+
+// ListAuditEvents mocks base method.
+func (m *MockAuthServiceInterface) ListAuditEvents(filter models.AuditEventFilter, cursor string, limit int) (*models.AuditEventPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditEvents", filter, cursor, limit)
+	ret0, _ := ret[0].(*models.AuditEventPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditEvents indicates an expected call of ListAuditEvents.
+func (mr *MockAuthServiceInterfaceMockRecorder) ListAuditEvents(filter, cursor, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditEvents", reflect.TypeOf((*MockAuthServiceInterface)(nil).ListAuditEvents), filter, cursor, limit)
+}
+
+// DeleteClient mocks base method.
+func (m *MockAuthServiceInterface) DeleteClient(adminID uint, clientID string, device models.DeviceInfo) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteClient", adminID, clientID, device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteClient indicates an expected call of DeleteClient.
+func (mr *MockAuthServiceInterfaceMockRecorder) DeleteClient(adminID, clientID, device any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteClient", reflect.TypeOf((*MockAuthServiceInterface)(nil).DeleteClient), adminID, clientID, device)
+}
+
+// IssueClientToken mocks base method.
+func (m *MockAuthServiceInterface) IssueClientToken(clientID, clientSecret, scope string) (*models.ClientTokenResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueClientToken", clientID, clientSecret, scope)
+	ret0, _ := ret[0].(*models.ClientTokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueClientToken indicates an expected call of IssueClientToken.
+func (mr *MockAuthServiceInterfaceMockRecorder) IssueClientToken(clientID, clientSecret, scope any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueClientToken", reflect.TypeOf((*MockAuthServiceInterface)(nil).IssueClientToken), clientID, clientSecret, scope)
+}
+
+// ListClients mocks base method.
+func (m *MockAuthServiceInterface) ListClients() ([]models.OAuthClientResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListClients")
+	ret0, _ := ret[0].([]models.OAuthClientResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListClients indicates an expected call of ListClients.
+func (mr *MockAuthServiceInterfaceMockRecorder) ListClients() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListClients", reflect.TypeOf((*MockAuthServiceInterface)(nil).ListClients))
+}
+
+// RegisterClient mocks base method.
+func (m *MockAuthServiceInterface) RegisterClient(adminID uint, req models.CreateOAuthClientRequest) (*models.RegisteredOAuthClientResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterClient", adminID, req)
+	ret0, _ := ret[0].(*models.RegisteredOAuthClientResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RegisterClient indicates an expected call of RegisterClient.
+func (mr *MockAuthServiceInterfaceMockRecorder) RegisterClient(adminID, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterClient", reflect.TypeOf((*MockAuthServiceInterface)(nil).RegisterClient), adminID, req)
+}
+
+// DisableUser mocks base method.
+func (m *MockAuthServiceInterface) DisableUser(adminID, userID uint, reason string, device models.DeviceInfo) (*models.UserResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableUser", adminID, userID, reason, device)
+	ret0, _ := ret[0].(*models.UserResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DisableUser indicates an expected call of DisableUser.
+func (mr *MockAuthServiceInterfaceMockRecorder) DisableUser(adminID, userID, reason, device any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableUser", reflect.TypeOf((*MockAuthServiceInterface)(nil).DisableUser), adminID, userID, reason, device)
+}
+
+// EnableUser mocks base method.
+func (m *MockAuthServiceInterface) EnableUser(adminID, userID uint, device models.DeviceInfo) (*models.UserResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableUser", adminID, userID, device)
+	ret0, _ := ret[0].(*models.UserResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnableUser indicates an expected call of EnableUser.
+func (mr *MockAuthServiceInterfaceMockRecorder) EnableUser(adminID, userID, device any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableUser", reflect.TypeOf((*MockAuthServiceInterface)(nil).EnableUser), adminID, userID, device)
+}
+
+// SearchUsers mocks base method.
+func (m *MockAuthServiceInterface) SearchUsers(filter models.UserFilter, sort, cursor string, limit int) (*models.UserSearchPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchUsers", filter, sort, cursor, limit)
+	ret0, _ := ret[0].(*models.UserSearchPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchUsers indicates an expected call of SearchUsers.
+func (mr *MockAuthServiceInterfaceMockRecorder) SearchUsers(filter, sort, cursor, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchUsers", reflect.TypeOf((*MockAuthServiceInterface)(nil).SearchUsers), filter, sort, cursor, limit)
+}
+
+// GetUsersByIDs mocks base method.
+func (m *MockAuthServiceInterface) GetUsersByIDs(ids []uint) ([]models.UserResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsersByIDs", ids)
+	ret0, _ := ret[0].([]models.UserResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsersByIDs indicates an expected call of GetUsersByIDs.
+func (mr *MockAuthServiceInterfaceMockRecorder) GetUsersByIDs(ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsersByIDs", reflect.TypeOf((*MockAuthServiceInterface)(nil).GetUsersByIDs), ids)
+}
+
+// IntrospectToken mocks base method.
+func (m *MockAuthServiceInterface) IntrospectToken(token string) (*models.TokenIntrospection, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IntrospectToken", token)
+	ret0, _ := ret[0].(*models.TokenIntrospection)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IntrospectToken indicates an expected call of IntrospectToken.
+func (mr *MockAuthServiceInterfaceMockRecorder) IntrospectToken(token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IntrospectToken", reflect.TypeOf((*MockAuthServiceInterface)(nil).IntrospectToken), token)
+}
+
+// ImpersonateUser mocks base method.
+func (m *MockAuthServiceInterface) ImpersonateUser(adminID, userID uint, reason string, device models.DeviceInfo) (*models.ImpersonationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImpersonateUser", adminID, userID, reason, device)
+	ret0, _ := ret[0].(*models.ImpersonationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImpersonateUser indicates an expected call of ImpersonateUser.
+func (mr *MockAuthServiceInterfaceMockRecorder) ImpersonateUser(adminID, userID, reason, device any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImpersonateUser", reflect.TypeOf((*MockAuthServiceInterface)(nil).ImpersonateUser), adminID, userID, reason, device)
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Audit event types.
+const (
+	AuditLoginSucceeded     = "login.succeeded"
+	AuditLoginFailed        = "login.failed"
+	AuditLoginLockedOut     = "login.locked_out"
+	AuditLogout             = "logout"
+	AuditUserRegistered     = "user.registered"
+	AuditPasswordChanged    = "password.changed"
+	AuditTokenRefreshed     = "token.refreshed"
+	AuditTokenReuseDetected = "token.reuse_detected"
+	AuditAccountDeleted     = "account.deleted"
+	AuditClientRegistered   = "client.registered"
+	AuditClientDeleted      = "client.deleted"
+	AuditUserDisabled       = "user.disabled"
+	AuditUserEnabled        = "user.enabled"
+	AuditUserImpersonated   = "user.impersonated"
+)
+
+// AuditEvent is one entry of the security audit trail. Rows are only ever
+// inserted, and outlive the user they refer to, so UserID is not a foreign
+// key. Email is the account the event was about as given by the client, so
+// failed logins for unknown accounts are recorded too.
+type AuditEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Type      string    `json:"type" gorm:"type:varchar(64);not null;index"`
+	UserID    *uint     `json:"userId,omitempty" gorm:"index"`
+	Email     string    `json:"email,omitempty" gorm:"type:varchar(255);not null;default:''"`
+	IPAddress string    `json:"ipAddress" gorm:"type:varchar(45);not null;default:''"`
+	UserAgent string    `json:"userAgent" gorm:"type:varchar(255);not null;default:''"`
+	RequestID string    `json:"requestId" gorm:"type:varchar(64);not null;default:''"`
+	Detail    string    `json:"detail,omitempty" gorm:"type:varchar(255);not null;default:''"`
+	CreatedAt time.Time `json:"createdAt" gorm:"index"`
+}
+
+// AuditEventFilter narrows an audit query; zero fields match everything.
+type AuditEventFilter struct {
+	UserID uint
+	Type   string
+	Since  time.Time
+	Until  time.Time
+}
+
+// AuditEventPage is one page of audit events, newest first. NextCursor is
+// passed back as ?cursor= for the next page and is empty on the last one.
+type AuditEventPage struct {
+	Events     []AuditEvent `json:"events"`
+	NextCursor string       `json:"nextCursor,omitempty"`
+}
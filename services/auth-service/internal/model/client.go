@@ -0,0 +1,80 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Scopes are the scopes a machine client may be issued, stored like Roles.
+type Scopes []string
+
+func (s Scopes) Value() (driver.Value, error) {
+	return strings.Join(s, ","), nil
+}
+
+func (s *Scopes) Scan(value interface{}) error {
+	list, err := scanList(value)
+	if err != nil {
+		return fmt.Errorf("scopes: %w", err)
+	}
+	*s = list
+	return nil
+}
+
+// OAuthClient is a machine client, another service, that gets access tokens
+// with the OAuth2 client_credentials grant. Only a hash of its secret is kept.
+type OAuthClient struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ClientID   string    `json:"clientId" gorm:"type:varchar(64);not null;uniqueIndex"`
+	Name       string    `json:"name" gorm:"type:varchar(100);not null"`
+	SecretHash string    `json:"-" gorm:"type:varchar(64);not null"`
+	Scopes     Scopes    `json:"scopes" gorm:"type:text;not null;default:''"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// OAuthClientResponse describes a registered client; the secret is never
+// shown again after registration.
+type OAuthClientResponse struct {
+	ClientID  string    `json:"clientId"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (c *OAuthClient) ToResponse() OAuthClientResponse {
+	return OAuthClientResponse{
+		ClientID:  c.ClientID,
+		Name:      c.Name,
+		Scopes:    c.Scopes,
+		CreatedAt: c.CreatedAt,
+	}
+}
+
+// CreateOAuthClientRequest registers a machine client. ClientID becomes the
+// subject of its tokens.
+type CreateOAuthClientRequest struct {
+	ClientID string   `json:"clientId" binding:"required"`
+	Name     string   `json:"name" binding:"required,max=100"`
+	Scopes   []string `json:"scopes" binding:"required,min=1"`
+
+	Device DeviceInfo `json:"-"`
+}
+
+// RegisteredOAuthClientResponse is returned once, on registration, and is
+// the only time the client secret is shown.
+type RegisteredOAuthClientResponse struct {
+	OAuthClientResponse
+	ClientSecret string `json:"clientSecret"`
+}
+
+// ClientTokenResponse is the OAuth2 token response (RFC 6749 section 5.1),
+// hence the snake_case names.
+type ClientTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
@@ -1,8 +1,12 @@
 package models
 
 import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/icl00ud/velure/shared/auth"
 	"gorm.io/gorm"
 )
 
@@ -11,13 +15,97 @@ type User struct {
 	Name      string    `json:"name" gorm:"not null"`
 	Email     string    `json:"email" gorm:"unique;not null;index:idx_users_email"`
 	Password  string    `json:"-" gorm:"not null"`
+	Roles     Roles     `json:"roles" gorm:"type:varchar(255);not null;default:'customer'"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 
+	// VerifiedAt is when the user confirmed their email address; nil until
+	// then, and reset when the email changes.
+	VerifiedAt *time.Time `json:"verifiedAt,omitempty"`
+
+	// DisabledAt is when an admin disabled the account; nil while it is
+	// enabled. Disabled accounts cannot sign in and their tokens are refused.
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
+
+	// Two-factor authentication. TOTPSecret is set on enrollment but only
+	// enforced once a code has confirmed it (TOTPEnabled). TOTPLastStep is the
+	// time step of the last accepted code so a code cannot be used twice.
+	// RecoveryCodes holds hashes of the unused recovery codes.
+	TOTPSecret    string        `json:"-" gorm:"column:totp_secret;type:varchar(64);not null;default:''"`
+	TOTPEnabled   bool          `json:"twoFactorEnabled" gorm:"column:totp_enabled;not null;default:false"`
+	TOTPLastStep  int64         `json:"-" gorm:"column:totp_last_step;not null;default:0"`
+	RecoveryCodes RecoveryCodes `json:"-" gorm:"column:totp_recovery_codes;type:text;not null;default:''"`
+
 	Sessions       []Session       `json:"sessions,omitempty" gorm:"foreignKey:UserID"`
 	PasswordResets []PasswordReset `json:"passwordResets,omitempty" gorm:"foreignKey:UserID"`
 }
 
+// EmailVerified reports whether the user has confirmed their email address.
+func (u *User) EmailVerified() bool {
+	return u.VerifiedAt != nil
+}
+
+// Disabled reports whether an admin has disabled the account.
+func (u *User) Disabled() bool {
+	return u.DisabledAt != nil
+}
+
+// Roles is the set of roles granted to a user, stored as a comma-separated
+// list in a single column.
+type Roles []string
+
+func (r Roles) Value() (driver.Value, error) {
+	return strings.Join(r, ","), nil
+}
+
+func (r *Roles) Scan(value interface{}) error {
+	list, err := scanList(value)
+	if err != nil {
+		return fmt.Errorf("roles: %w", err)
+	}
+	*r = list
+	return nil
+}
+
+// RecoveryCodes are the hashes of a user's unused two-factor recovery codes,
+// stored like Roles.
+type RecoveryCodes []string
+
+func (c RecoveryCodes) Value() (driver.Value, error) {
+	return strings.Join(c, ","), nil
+}
+
+func (c *RecoveryCodes) Scan(value interface{}) error {
+	list, err := scanList(value)
+	if err != nil {
+		return fmt.Errorf("recovery codes: %w", err)
+	}
+	*c = list
+	return nil
+}
+
+// scanList splits a comma-separated column into its non-empty items.
+func scanList(value interface{}) ([]string, error) {
+	var raw string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return nil, fmt.Errorf("unsupported type %T", value)
+	}
+
+	var list []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list, nil
+}
+
 type Session struct {
 	ID           uint      `json:"id" gorm:"primaryKey"`
 	UserID       uint      `json:"userId" gorm:"not null;index"`
@@ -26,6 +114,21 @@ type Session struct {
 	ExpiresAt    time.Time `json:"expiresAt" gorm:"not null"`
 	CreatedAt    time.Time `json:"createdAt"`
 
+	// FamilyID ties together every session produced by refresh-token rotation
+	// from a single login. RotatedAt is set once the session's refresh token
+	// has been exchanged; presenting that token again is treated as theft.
+	FamilyID  string     `json:"familyId" gorm:"index"`
+	RotatedAt *time.Time `json:"rotatedAt,omitempty"`
+
+	// Device details, recorded at login and refreshed on every rotation.
+	// CreatedAt is carried over on rotation so it keeps the login time.
+	// LastUsedAt also advances when the access token is used, at most once
+	// a minute; IssuedAt is when the session's token pair was minted.
+	UserAgent  string    `json:"userAgent" gorm:"type:varchar(255);not null;default:''"`
+	IPAddress  string    `json:"ipAddress" gorm:"type:varchar(45);not null;default:''"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	IssuedAt   time.Time `json:"issuedAt"`
+
 	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
 }
 
@@ -39,21 +142,86 @@ type PasswordReset struct {
 	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
 }
 
+// EmailVerification is a pending email confirmation. Like password resets,
+// only the hash of the token is stored and only the newest one is valid.
+type EmailVerification struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"userId" gorm:"not null;index"`
+	Token     string    `json:"token" gorm:"unique;not null"`
+	ExpiresAt time.Time `json:"expiresAt" gorm:"not null"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// PasswordHistory keeps the hash of a password the user had before, so a
+// password change can refuse recently used ones.
+type PasswordHistory struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"userId" gorm:"not null;index"`
+	Hash      string    `json:"-" gorm:"type:varchar(255);not null"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// UserIdentity links an account at an external OpenID Connect provider to a
+// user. Subject is the provider's stable user ID; Email is what the provider
+// reported when the link was made.
+type UserIdentity struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"userId" gorm:"not null;index"`
+	Provider  string    `json:"provider" gorm:"type:varchar(64);not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Subject   string    `json:"subject" gorm:"type:varchar(255);not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Email     string    `json:"email" gorm:"type:varchar(255);not null;default:''"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// DeviceInfo describes the client a session is opened from. It is filled in
+// by the handler from the request, never from the JSON body. RequestID is
+// only recorded in the audit trail.
+type DeviceInfo struct {
+	UserAgent string
+	IPAddress string
+	RequestID string
+}
+
 // DTOs
 type CreateUserRequest struct {
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
+
+	Device DeviceInfo `json:"-"`
 }
 
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+
+	Device DeviceInfo `json:"-"`
 }
 
+// LoginResponse carries the token pair. For accounts with two-factor
+// authentication the password step returns only TwoFactorRequired and a
+// ChallengeToken, to be exchanged together with a code for the token pair.
 type LoginResponse struct {
-	AccessToken  string `json:"accessToken"`
-	RefreshToken string `json:"refreshToken"`
+	AccessToken  string `json:"accessToken,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+
+	TwoFactorRequired bool   `json:"twoFactorRequired,omitempty"`
+	ChallengeToken    string `json:"challengeToken,omitempty"`
+}
+
+// TwoFactorLoginRequest completes a login that returned a challenge. Code is
+// either the current TOTP code or one of the recovery codes.
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+
+	Device DeviceInfo `json:"-"`
 }
 
 type RegistrationResponse struct {
@@ -64,6 +232,8 @@ type RegistrationResponse struct {
 	UpdatedAt    time.Time `json:"updatedAt"`
 	AccessToken  string    `json:"accessToken"`
 	RefreshToken string    `json:"refreshToken"`
+
+	EmailVerified bool `json:"emailVerified"`
 }
 
 // ValidateTokenRequest carries the token to introspect. Optional in the body:
@@ -78,29 +248,148 @@ type LogoutRequest struct {
 	RefreshToken string `json:"refreshToken"`
 }
 
+// RefreshTokenRequest carries the refresh token to rotate. Optional in the
+// body: when omitted the handler falls back to the refresh_token httpOnly cookie.
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ConfirmPasswordResetRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ConfirmEmailVerificationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// OIDCLoginRequest completes a social login with the code and state the
+// identity provider redirected back with. Provider comes from the URL.
+type OIDCLoginRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+
+	Provider string     `json:"-"`
+	Device   DeviceInfo `json:"-"`
+}
+
+// TwoFactorCodeRequest proves possession of the second factor when
+// confirming, disabling or regenerating recovery codes.
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorEnrollmentResponse is shown once when enrollment starts. The URI
+// is what authenticator apps import, usually rendered as a QR code.
+type TwoFactorEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
+}
+
+// RecoveryCodesResponse lists freshly generated recovery codes. Only their
+// hashes are stored, so this is the only time they can be shown.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// UpdateProfileRequest changes the caller's own account; omitted fields are
+// left alone. Changing the email or the password requires CurrentPassword.
+type UpdateProfileRequest struct {
+	Name            *string `json:"name"`
+	Email           *string `json:"email" binding:"omitempty,email"`
+	Password        *string `json:"password"`
+	CurrentPassword string  `json:"currentPassword"`
+
+	Device DeviceInfo `json:"-"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type UpdateRolesRequest struct {
+	Roles []string `json:"roles" binding:"required,min=1"`
+}
+
+// SessionResponse is one signed-in device as shown to its owner. Current
+// marks the session the request was made with.
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	IPAddress  string    `json:"ipAddress"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"`
+}
+
 type ValidateTokenResponse struct {
 	IsValid bool `json:"isValid"`
 }
 
+// TokenIntrospection describes an access token to other services. Only
+// Active is set for a token that is not accepted.
+type TokenIntrospection struct {
+	Active        bool
+	Subject       string
+	ClientID      string
+	Roles         []string
+	Scopes        []string
+	SessionID     string
+	EmailVerified bool
+	IssuedAt      time.Time
+	ExpiresAt     time.Time
+	// Actor is the admin acting as the subject on impersonation tokens.
+	Actor string
+}
+
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID               uint      `json:"id"`
+	Name             string    `json:"name"`
+	Email            string    `json:"email"`
+	Roles            []string  `json:"roles"`
+	TwoFactorEnabled bool      `json:"twoFactorEnabled"`
+	EmailVerified    bool      `json:"emailVerified"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
 }
 
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
-		ID:        u.ID,
-		Name:      u.Name,
-		Email:     u.Email,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:               u.ID,
+		Name:             u.Name,
+		Email:            u.Email,
+		Roles:            u.Roles,
+		TwoFactorEnabled: u.TOTPEnabled,
+		EmailVerified:    u.EmailVerified(),
+		CreatedAt:        u.CreatedAt,
+		UpdatedAt:        u.UpdatedAt,
+		DisabledAt:       u.DisabledAt,
+	}
+}
+
+func (s *Session) ToResponse(currentAccessToken string) SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		UserAgent:  s.UserAgent,
+		IPAddress:  s.IPAddress,
+		CreatedAt:  s.CreatedAt,
+		LastUsedAt: s.LastUsedAt,
+		ExpiresAt:  s.ExpiresAt,
+		Current:    currentAccessToken != "" && s.AccessToken == currentAccessToken,
 	}
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
+	if len(u.Roles) == 0 {
+		u.Roles = Roles{auth.RoleCustomer}
+	}
 	u.CreatedAt = time.Now()
 	u.UpdatedAt = time.Now()
 	return nil
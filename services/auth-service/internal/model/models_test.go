@@ -94,3 +94,54 @@ func TestUser_BeforeUpdate(t *testing.T) {
 		t.Error("BeforeUpdate() should not modify CreatedAt")
 	}
 }
+
+func TestRoles_ValueAndScan(t *testing.T) {
+	value, err := Roles{"admin", "catalog-manager"}.Value()
+	if err != nil {
+		t.Fatalf("Value() error = %v", err)
+	}
+	if value != "admin,catalog-manager" {
+		t.Fatalf("Value() = %v, want admin,catalog-manager", value)
+	}
+
+	var roles Roles
+	if err := roles.Scan([]byte(" admin, ,customer ")); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(roles) != 2 || roles[0] != "admin" || roles[1] != "customer" {
+		t.Fatalf("Scan() = %v, want [admin customer]", roles)
+	}
+
+	if err := roles.Scan(nil); err != nil || roles != nil {
+		t.Fatalf("Scan(nil) = %v, %v; want nil, nil", roles, err)
+	}
+
+	if err := roles.Scan(42); err == nil {
+		t.Fatal("Scan() should reject unsupported types")
+	}
+}
+
+func TestRecoveryCodes_ValueAndScan(t *testing.T) {
+	value, err := RecoveryCodes{"aa", "bb"}.Value()
+	if err != nil || value != "aa,bb" {
+		t.Fatalf("Value() = %v, %v; want aa,bb", value, err)
+	}
+
+	var codes RecoveryCodes
+	if err := codes.Scan("aa,bb"); err != nil || len(codes) != 2 {
+		t.Fatalf("Scan() = %v, %v", codes, err)
+	}
+	if err := codes.Scan(""); err != nil || len(codes) != 0 {
+		t.Fatalf("Scan(\"\") = %v, %v; want empty", codes, err)
+	}
+}
+
+func TestUser_BeforeCreateDefaultsToCustomer(t *testing.T) {
+	user := &User{Name: "Test User", Email: "test@example.com"}
+	if err := user.BeforeCreate(nil); err != nil {
+		t.Fatalf("BeforeCreate() error = %v", err)
+	}
+	if len(user.Roles) != 1 || user.Roles[0] != "customer" {
+		t.Fatalf("expected default customer role, got %v", user.Roles)
+	}
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// UserFilter narrows an admin user search; zero fields match everything.
+// Query matches the start of the email or the name, ignoring case.
+type UserFilter struct {
+	Query        string
+	CreatedSince time.Time
+	CreatedUntil time.Time
+	Verified     *bool
+	Disabled     *bool
+	Locked       *bool
+
+	// LockedAccounts are the normalized emails currently locked out of
+	// login, filled in by the service since lockouts live in Redis. The
+	// repository matches Locked against them.
+	LockedAccounts []string
+}
+
+// Sortable user fields.
+const (
+	UserSortCreatedAt = "createdAt"
+	UserSortEmail     = "email"
+	UserSortName      = "name"
+)
+
+// UserSort orders a user search. Ties are broken by ID in the same
+// direction, which keeps keyset pagination stable.
+type UserSort struct {
+	Field string
+	Desc  bool
+}
+
+// AdminUserResponse is a user as shown to support staff. Locked reports a
+// login lockout after repeated failures, which ends on its own.
+type AdminUserResponse struct {
+	UserResponse
+	Locked bool `json:"locked"`
+}
+
+// UserSearchPage is one page of a user search. NextCursor is passed back as
+// ?cursor= for the next page and is empty on the last one.
+type UserSearchPage struct {
+	Users      []AdminUserResponse `json:"users"`
+	NextCursor string              `json:"nextCursor,omitempty"`
+}
+
+// DisableUserRequest optionally says why an account is disabled; the reason
+// is kept in the audit trail.
+type DisableUserRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ImpersonateUserRequest says why an admin acts as a user, typically the
+// support ticket; it is kept in the audit trail.
+type ImpersonateUserRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ImpersonationResponse holds an access token for acting as another user.
+// There is no refresh token: once it expires the admin asks for a new one.
+type ImpersonationResponse struct {
+	AccessToken string `json:"accessToken"`
+	TokenType   string `json:"tokenType"`
+	ExpiresIn   int    `json:"expiresIn"`
+}
@@ -0,0 +1,109 @@
+// Package notify delivers out-of-band messages (password reset and email
+// verification links) to users. Production deployments plug in a real
+// mail/SMS provider; the log and file implementations here are meant for
+// local runs and tests.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/icl00ud/velure/shared/logger"
+)
+
+// Kind identifies the template a delivery backend should use.
+type Kind string
+
+const (
+	KindPasswordReset     Kind = "password_reset"
+	KindEmailVerification Kind = "email_verification"
+)
+
+// Message is a single notification addressed to a user. Token is the secret
+// the user has to present back; backends must treat it as sensitive.
+type Message struct {
+	Kind      Kind      `json:"kind"`
+	To        string    `json:"to"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Notifier delivers messages to users.
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Config selects and configures the notifier backend.
+type Config struct {
+	Driver   string // log (default) or file
+	FilePath string // used by the file driver
+}
+
+// New builds the notifier selected by cfg.Driver.
+func New(cfg Config) (Notifier, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Driver)) {
+	case "", "log":
+		return NewLogNotifier(), nil
+	case "file":
+		if strings.TrimSpace(cfg.FilePath) == "" {
+			return nil, fmt.Errorf("notify: file driver requires a file path")
+		}
+		return NewFileNotifier(cfg.FilePath), nil
+	default:
+		return nil, fmt.Errorf("notify: unknown driver %q", cfg.Driver)
+	}
+}
+
+// LogNotifier writes messages to the service log. Tokens are logged in full
+// so a developer can complete the flow locally; never use it in production.
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Notify(_ context.Context, msg Message) error {
+	logger.Info("notification",
+		logger.String("kind", string(msg.Kind)),
+		logger.String("to", msg.To),
+		logger.String("token", msg.Token),
+		logger.String("expires_at", msg.ExpiresAt.Format(time.RFC3339)))
+	return nil
+}
+
+// FileNotifier appends messages as JSON lines to a file, which makes it easy
+// for end-to-end tests to pick tokens up.
+type FileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func (n *FileNotifier) Notify(_ context.Context, msg Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("notify: encode message: %w", err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("notify: open %s: %w", n.path, err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("notify: write %s: %w", n.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	if n, err := New(Config{}); err != nil {
+		t.Fatalf("New() default error = %v", err)
+	} else if _, ok := n.(*LogNotifier); !ok {
+		t.Fatalf("New() default = %T, want *LogNotifier", n)
+	}
+
+	if _, err := New(Config{Driver: "file"}); err == nil {
+		t.Fatal("New() file driver without path should fail")
+	}
+
+	if _, err := New(Config{Driver: "carrier-pigeon"}); err == nil {
+		t.Fatal("New() unknown driver should fail")
+	}
+}
+
+func TestFileNotifier_AppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	n := NewFileNotifier(path)
+
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	for _, token := range []string{"tok-1", "tok-2"} {
+		if err := n.Notify(context.Background(), Message{
+			Kind:      KindPasswordReset,
+			To:        "user@example.com",
+			Token:     token,
+			ExpiresAt: expires,
+		}); err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var got []Message
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("invalid JSON line: %v", err)
+		}
+		got = append(got, msg)
+	}
+
+	if len(got) != 2 || got[0].Token != "tok-1" || got[1].Token != "tok-2" {
+		t.Fatalf("unexpected messages: %+v", got)
+	}
+	if got[0].Kind != KindPasswordReset || !got[0].ExpiresAt.Equal(expires) {
+		t.Fatalf("unexpected message fields: %+v", got[0])
+	}
+}
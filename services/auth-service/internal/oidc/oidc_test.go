@@ -0,0 +1,150 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/oidc"
+	"github.com/icl00ud/velure/services/auth-service/internal/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const redirectURL = "http://localhost:3000/auth/callback"
+
+func authorize(t *testing.T, fake *oidctest.Provider, p *oidc.Provider, nonce string) (code, verifier string) {
+	t.Helper()
+
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+	authURL, err := p.AuthCodeURL(context.Background(), "some-state", nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	code, state := fake.Authorize(t, authURL)
+	if state != "some-state" {
+		t.Fatalf("expected the state to come back, got %q", state)
+	}
+	return code, verifier
+}
+
+func TestProvider_Flow(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+	p := oidc.New(fake.Config("test", redirectURL))
+
+	verifier, _ := oidc.NewVerifier()
+	authURL, err := p.AuthCodeURL(context.Background(), "some-state", "some-nonce", verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	q, _ := url.Parse(authURL)
+	if got := q.Query().Get("code_challenge"); got != oidc.Challenge(verifier) || got == verifier {
+		t.Fatalf("expected the S256 challenge of the verifier, got %q", got)
+	}
+	if q.Query().Get("scope") != "openid email profile" {
+		t.Fatalf("unexpected scope %q", q.Query().Get("scope"))
+	}
+
+	code, _ := fake.Authorize(t, authURL)
+	identity, err := p.Exchange(context.Background(), code, verifier, "some-nonce")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if identity.Subject != "alice-subject" || identity.Email != "alice@example.com" || !identity.EmailVerified || identity.Name != "Alice" {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+
+	// Codes are single-use.
+	if _, err := p.Exchange(context.Background(), code, verifier, "some-nonce"); err == nil {
+		t.Fatal("expected a redeemed code to be rejected")
+	}
+}
+
+func TestProvider_Exchange_Rejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(jwt.MapClaims)
+		setup  func(cfg *oidc.Config)
+		nonce  string
+		wrongV bool
+		idErr  bool
+	}{
+		{name: "nonce mismatch", nonce: "other-nonce", idErr: true},
+		{name: "wrong issuer", tamper: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, idErr: true},
+		{name: "wrong audience", tamper: func(c jwt.MapClaims) { c["aud"] = "someone-else" }, idErr: true},
+		{name: "expired", tamper: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, idErr: true},
+		{name: "other authorized party", tamper: func(c jwt.MapClaims) {
+			c["aud"] = []string{oidctest.ClientID, "someone-else"}
+			c["azp"] = "someone-else"
+		}, idErr: true},
+		{name: "wrong code verifier", wrongV: true},
+		{name: "wrong client secret", setup: func(cfg *oidc.Config) { cfg.ClientSecret = "nope" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := oidctest.NewProvider(t)
+			fake.Tamper = tt.tamper
+			cfg := fake.Config("test", redirectURL)
+			if tt.setup != nil {
+				tt.setup(&cfg)
+			}
+			p := oidc.New(cfg)
+
+			code, verifier := authorize(t, fake, p, "some-nonce")
+			if tt.wrongV {
+				verifier, _ = oidc.NewVerifier()
+			}
+			nonce := "some-nonce"
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+
+			_, err := p.Exchange(context.Background(), code, verifier, nonce)
+			if err == nil {
+				t.Fatal("expected the exchange to fail")
+			}
+			if tt.idErr != errors.Is(err, oidc.ErrInvalidIDToken) {
+				t.Fatalf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestProvider_EmailVerifiedAsString(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+	fake.Tamper = func(c jwt.MapClaims) { c["email_verified"] = "true" }
+	p := oidc.New(fake.Config("test", redirectURL))
+
+	code, verifier := authorize(t, fake, p, "n")
+	identity, err := p.Exchange(context.Background(), code, verifier, "n")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if !identity.EmailVerified {
+		t.Fatal(`expected "true" to count as verified`)
+	}
+}
+
+func TestProvider_DiscoveryIssuerMismatch(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+	cfg := fake.Config("test", redirectURL)
+	cfg.Issuer += "/"
+
+	if _, err := oidc.New(cfg).AuthCodeURL(context.Background(), "s", "n", "v"); err == nil {
+		t.Fatal("expected a discovery document for another issuer to be rejected")
+	}
+}
+
+func TestProvider_DiscoveryUnreachable(t *testing.T) {
+	p := oidc.New(oidc.Config{Name: "down", Issuer: "http://127.0.0.1:1"})
+
+	if _, err := p.AuthCodeURL(context.Background(), "s", "n", "v"); err == nil {
+		t.Fatal("expected an unreachable provider to fail")
+	}
+}
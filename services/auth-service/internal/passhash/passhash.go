@@ -0,0 +1,80 @@
+// Package passhash hashes passwords into self-describing strings: argon2id
+// in the PHC string format ($argon2id$v=19$m=…,t=…,p=…$salt$hash) and bcrypt
+// in its usual $2a$ form. Every hash names its algorithm and parameters, so
+// hashes made with older settings keep verifying and can be recognised for
+// an upgrade the next time the password is known.
+package passhash
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Algorithm names accepted by New.
+const (
+	AlgArgon2id = "argon2id"
+	AlgBcrypt   = "bcrypt"
+)
+
+var (
+	// ErrMismatch is returned by Verify when the password is wrong.
+	ErrMismatch = errors.New("passhash: password does not match")
+	// ErrUnsupported is returned by Verify for a hash in another format.
+	ErrUnsupported = errors.New("passhash: unsupported hash format")
+)
+
+// Hasher hashes and verifies passwords.
+type Hasher interface {
+	// Hash returns the encoded hash of password, with a fresh salt.
+	Hash(password string) (string, error)
+	// Verify returns nil if password matches encoded, ErrMismatch if it
+	// does not and ErrUnsupported if encoded was not made by this Hasher.
+	Verify(encoded, password string) error
+	// NeedsRehash reports whether encoded was made with another algorithm
+	// or other parameters than Hash uses now.
+	NeedsRehash(encoded string) bool
+}
+
+// New returns a Hasher that hashes new passwords with algorithm and still
+// verifies hashes made with the other supported one.
+func New(algorithm string, argon Argon2idParams, bcryptCost int) (Hasher, error) {
+	a, b := NewArgon2id(argon), NewBcrypt(bcryptCost)
+	switch algorithm {
+	case AlgArgon2id:
+		return Upgrading(a, b), nil
+	case AlgBcrypt:
+		return Upgrading(b, a), nil
+	default:
+		return nil, fmt.Errorf("passhash: unknown algorithm %q", algorithm)
+	}
+}
+
+// Upgrading returns a Hasher that hashes with preferred and verifies hashes
+// made by preferred or any of legacy. NeedsRehash is true for every hash
+// preferred would not have produced, so callers can replace legacy hashes
+// once the user has proven the password.
+func Upgrading(preferred Hasher, legacy ...Hasher) Hasher {
+	return &upgrading{preferred: preferred, all: append([]Hasher{preferred}, legacy...)}
+}
+
+type upgrading struct {
+	preferred Hasher
+	all       []Hasher
+}
+
+func (u *upgrading) Hash(password string) (string, error) {
+	return u.preferred.Hash(password)
+}
+
+func (u *upgrading) Verify(encoded, password string) error {
+	for _, h := range u.all {
+		if err := h.Verify(encoded, password); !errors.Is(err, ErrUnsupported) {
+			return err
+		}
+	}
+	return ErrUnsupported
+}
+
+func (u *upgrading) NeedsRehash(encoded string) bool {
+	return u.preferred.NeedsRehash(encoded)
+}
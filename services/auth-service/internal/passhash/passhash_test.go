@@ -0,0 +1,107 @@
+package passhash
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var fastArgon2id = Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestArgon2id(t *testing.T) {
+	h := NewArgon2id(fastArgon2id)
+
+	encoded, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("expected a PHC string, got %q", encoded)
+	}
+	if other, _ := h.Hash("correct horse"); other == encoded {
+		t.Fatal("expected a fresh salt per hash")
+	}
+
+	if err := h.Verify(encoded, "correct horse"); err != nil {
+		t.Fatalf("Verify() = %v", err)
+	}
+	if err := h.Verify(encoded, "wrong"); !errors.Is(err, ErrMismatch) {
+		t.Fatalf("Verify() with the wrong password = %v", err)
+	}
+	if h.NeedsRehash(encoded) {
+		t.Fatal("a hash with the current parameters needs no rehash")
+	}
+
+	stronger := NewArgon2id(Argon2idParams{Memory: 128, Iterations: 1, Parallelism: 1})
+	if err := stronger.Verify(encoded, "correct horse"); err != nil {
+		t.Fatalf("hashes must verify with the parameters they were made with, got %v", err)
+	}
+	if !stronger.NeedsRehash(encoded) {
+		t.Fatal("expected a rehash after the memory cost went up")
+	}
+
+	for _, bad := range []string{"", "$argon2id$v=19$m=64,t=1,p=1$!!$!!", "$argon2id$v=19$garbage$c2FsdA$a2V5"} {
+		if err := h.Verify(bad, "x"); err == nil || errors.Is(err, ErrMismatch) {
+			t.Errorf("Verify(%q) = %v, want a format error", bad, err)
+		}
+	}
+}
+
+func TestBcrypt(t *testing.T) {
+	h := NewBcrypt(bcrypt.MinCost)
+
+	encoded, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Verify(encoded, "correct horse"); err != nil {
+		t.Fatalf("Verify() = %v", err)
+	}
+	if err := h.Verify(encoded, "wrong"); !errors.Is(err, ErrMismatch) {
+		t.Fatalf("Verify() with the wrong password = %v", err)
+	}
+	if h.NeedsRehash(encoded) {
+		t.Fatal("a hash with the current cost needs no rehash")
+	}
+	if !NewBcrypt(bcrypt.MinCost + 1).NeedsRehash(encoded) {
+		t.Fatal("expected a rehash after the cost went up")
+	}
+	if err := h.Verify("$argon2id$v=19$m=64,t=1,p=1$c2FsdA$a2V5", "x"); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("Verify() of an argon2id hash = %v", err)
+	}
+}
+
+func TestNew_UpgradesLegacyHashes(t *testing.T) {
+	legacy, _ := NewBcrypt(bcrypt.MinCost).Hash("correct horse")
+
+	h, err := New(AlgArgon2id, fastArgon2id, bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Verify(legacy, "correct horse"); err != nil {
+		t.Fatalf("bcrypt hashes must keep verifying, got %v", err)
+	}
+	if err := h.Verify(legacy, "wrong"); !errors.Is(err, ErrMismatch) {
+		t.Fatalf("Verify() with the wrong password = %v", err)
+	}
+	if !h.NeedsRehash(legacy) {
+		t.Fatal("expected bcrypt hashes to be upgraded")
+	}
+
+	current, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(current, "$argon2id$") || h.NeedsRehash(current) {
+		t.Fatalf("expected new hashes to be current argon2id, got %q", current)
+	}
+	if err := h.Verify("plaintext", "plaintext"); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("Verify() of an unknown format = %v", err)
+	}
+
+	if _, err := New("md5", fastArgon2id, 0); err == nil {
+		t.Fatal("expected unknown algorithms to be rejected")
+	}
+}
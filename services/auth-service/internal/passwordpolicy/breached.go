@@ -0,0 +1,73 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// prefixLength is the length of the SHA-1 prefix a range file covers, as in
+// the Have I Been Pwned range API.
+const prefixLength = 5
+
+// BreachedPasswords looks passwords up in a local copy of a breached-password
+// dataset laid out for k-anonymity range queries, the way the Have I Been
+// Pwned downloader writes it: one file per five-character SHA-1 prefix
+// (00000.txt to FFFFF.txt), each line holding the other 35 characters of a
+// hash and how often it was seen, as SUFFIX:COUNT. Only the file for the
+// password's prefix is read, so the dataset never has to fit in memory and
+// the password itself is never written anywhere.
+type BreachedPasswords struct {
+	dir      string
+	minCount int
+}
+
+// OpenBreachedPasswords uses the range files in dir. Hashes seen fewer than
+// minCount times are ignored.
+func OpenBreachedPasswords(dir string, minCount int) (*BreachedPasswords, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("open breached passwords: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("open breached passwords: %s is not a directory", dir)
+	}
+	return &BreachedPasswords{dir: dir, minCount: max(minCount, 1)}, nil
+}
+
+// Contains reports whether password is in the dataset. A prefix without a
+// range file has no breached hashes.
+func (b *BreachedPasswords) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	f, err := os.Open(filepath.Join(b.dir, prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("read breached passwords: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		candidate, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		return err != nil || n >= b.minCount, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("read breached passwords: %w", err)
+	}
+	return false, nil
+}
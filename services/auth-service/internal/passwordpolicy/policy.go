@@ -0,0 +1,173 @@
+// Package passwordpolicy decides whether a new password is acceptable:
+// length, character classes, no parts of the account's email or name, not
+// one of the account's recent passwords and, with a dataset loaded, not a
+// known breached password. Every broken rule is reported as a Violation with
+// a stable code the UI can translate.
+package passwordpolicy
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/icl00ud/velure/shared/logger"
+)
+
+// Field is the request field violations refer to.
+const Field = "password"
+
+// Violation codes.
+const (
+	CodeRequired         = "password_required"
+	CodeTooShort         = "password_too_short"
+	CodeTooLong          = "password_too_long"
+	CodeMissingUppercase = "password_missing_uppercase"
+	CodeMissingLowercase = "password_missing_lowercase"
+	CodeMissingDigit     = "password_missing_digit"
+	CodeMissingSymbol    = "password_missing_symbol"
+	CodeContainsEmail    = "password_contains_email"
+	CodeContainsName     = "password_contains_name"
+	CodeReused           = "password_reused"
+	CodeBreached         = "password_breached"
+)
+
+// Violation is one rule a password breaks.
+type Violation struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ViolationError is returned for a password that breaks the policy. Its
+// message is the first violation's.
+type ViolationError struct {
+	Violations []Violation
+}
+
+func (e *ViolationError) Error() string {
+	return e.Violations[0].Message
+}
+
+// Account is what a new password is checked against.
+type Account struct {
+	Email string
+	Name  string
+	// Reused reports whether password is one of the account's recent
+	// passwords. Nil for new accounts.
+	Reused func(password string) bool
+}
+
+// Policy holds the rules a new password has to satisfy.
+type Policy struct {
+	MinLength          int
+	MaxLength          int
+	RequireUppercase   bool
+	RequireLowercase   bool
+	RequireDigit       bool
+	RequireSymbol      bool
+	RejectPersonalInfo bool
+	// Breached is the breached-password dataset; nil skips the check.
+	Breached *BreachedPasswords
+}
+
+// Default is the policy used without configuration: 6 to 128 characters.
+var Default = Policy{MinLength: 6, MaxLength: 128}
+
+// Check returns a *ViolationError listing every rule password breaks, or
+// nil. The breach lookup and the reuse check, which cost I/O and hashing,
+// only run once the cheap rules pass. A dataset that cannot be read lets the
+// password through rather than blocking every password change.
+func (p *Policy) Check(password string, account Account) error {
+	if password == "" {
+		return violations(violation(CodeRequired, "password is required"))
+	}
+
+	var found []Violation
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		found = append(found, violation(CodeTooShort, fmt.Sprintf("password must be at least %d characters", p.MinLength)))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		found = append(found, violation(CodeTooLong, fmt.Sprintf("password must be less than %d characters", p.MaxLength)))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUppercase && !upper {
+		found = append(found, violation(CodeMissingUppercase, "password must contain an uppercase letter"))
+	}
+	if p.RequireLowercase && !lower {
+		found = append(found, violation(CodeMissingLowercase, "password must contain a lowercase letter"))
+	}
+	if p.RequireDigit && !digit {
+		found = append(found, violation(CodeMissingDigit, "password must contain a digit"))
+	}
+	if p.RequireSymbol && !symbol {
+		found = append(found, violation(CodeMissingSymbol, "password must contain a symbol"))
+	}
+
+	if p.RejectPersonalInfo {
+		lowered := strings.ToLower(password)
+		if containsEmail(lowered, account.Email) {
+			found = append(found, violation(CodeContainsEmail, "password must not contain your email address"))
+		}
+		if containsName(lowered, account.Name) {
+			found = append(found, violation(CodeContainsName, "password must not contain your name"))
+		}
+	}
+	if len(found) > 0 {
+		return violations(found...)
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			logger.Warn("breached password lookup failed", logger.Err(err))
+		}
+		if breached {
+			return violations(violation(CodeBreached, "password has appeared in a data breach"))
+		}
+	}
+	if account.Reused != nil && account.Reused(password) {
+		return violations(violation(CodeReused, "password was used recently"))
+	}
+	return nil
+}
+
+// minPersonalPart is the shortest email local part or name word checked
+// for; shorter ones would reject too many unrelated passwords.
+const minPersonalPart = 3
+
+func containsEmail(password, email string) bool {
+	local, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	return len(local) >= minPersonalPart && strings.Contains(password, local)
+}
+
+func containsName(password, name string) bool {
+	for _, word := range strings.Fields(strings.ToLower(name)) {
+		if utf8.RuneCountInString(word) >= minPersonalPart && strings.Contains(password, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func violation(code, message string) Violation {
+	return Violation{Field: Field, Code: code, Message: message}
+}
+
+func violations(v ...Violation) error {
+	return &ViolationError{Violations: v}
+}
@@ -0,0 +1,128 @@
+package passwordpolicy
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func codes(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var v *ViolationError
+	if !errors.As(err, &v) {
+		t.Fatalf("expected a *ViolationError, got %v", err)
+	}
+	var out []string
+	for _, violation := range v.Violations {
+		if violation.Field != Field {
+			t.Errorf("violation %+v has the wrong field", violation)
+		}
+		out = append(out, violation.Code)
+	}
+	return out
+}
+
+func TestPolicy_Check(t *testing.T) {
+	strict := Policy{
+		MinLength:          10,
+		MaxLength:          64,
+		RequireUppercase:   true,
+		RequireLowercase:   true,
+		RequireDigit:       true,
+		RequireSymbol:      true,
+		RejectPersonalInfo: true,
+	}
+	account := Account{Email: "maria.silva@example.com", Name: "Maria Silva"}
+
+	tests := []struct {
+		password string
+		want     []string
+	}{
+		{"", []string{CodeRequired}},
+		{"short", []string{CodeTooShort, CodeMissingUppercase, CodeMissingDigit, CodeMissingSymbol}},
+		{strings.Repeat("Aa1!", 20), []string{CodeTooLong}},
+		{"Correct-Horse-7", nil},
+		{"Maria.Silva-2024", []string{CodeContainsEmail, CodeContainsName}},
+		{"Silva#Rocks#99", []string{CodeContainsName}},
+	}
+	for _, tt := range tests {
+		got := codes(t, strict.Check(tt.password, account))
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("Check(%q) = %v, want %v", tt.password, got, tt.want)
+		}
+	}
+
+	if err := Default.Check("12345", Account{}); err == nil || err.Error() != "password must be at least 6 characters" {
+		t.Fatalf("expected the default length message, got %v", err)
+	}
+}
+
+func TestPolicy_CheckReused(t *testing.T) {
+	reused := Account{Reused: func(password string) bool { return password == "old-password" }}
+
+	if got := codes(t, Default.Check("old-password", reused)); len(got) != 1 || got[0] != CodeReused {
+		t.Fatalf("Check() = %v, want %s", got, CodeReused)
+	}
+	if err := Default.Check("new-password", reused); err != nil {
+		t.Fatalf("Check() = %v", err)
+	}
+
+	// The reuse check is skipped while cheaper rules fail.
+	called := false
+	Default.Check("x", Account{Reused: func(string) bool { called = true; return true }})
+	if called {
+		t.Fatal("expected the reuse check to be skipped")
+	}
+}
+
+func writeRange(t *testing.T, dir, password, count string) {
+	t.Helper()
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	path := filepath.Join(dir, hash[:5]+".txt")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(hash[5:] + ":" + count + "\r\n"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBreachedPasswords(t *testing.T) {
+	dir := t.TempDir()
+	writeRange(t, dir, "hunter2hunter2", "24000")
+	writeRange(t, dir, "rarely-seen-pw", "1")
+
+	breached, err := OpenBreachedPasswords(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for password, want := range map[string]bool{
+		"hunter2hunter2": true,
+		"rarely-seen-pw": false, // below minCount
+		"never-breached": false, // no range file
+	} {
+		got, err := breached.Contains(password)
+		if err != nil || got != want {
+			t.Errorf("Contains(%q) = %v, %v; want %v", password, got, err, want)
+		}
+	}
+
+	policy := Policy{MinLength: 6, Breached: breached}
+	if got := codes(t, policy.Check("hunter2hunter2", Account{})); len(got) != 1 || got[0] != CodeBreached {
+		t.Fatalf("Check() = %v, want %s", got, CodeBreached)
+	}
+
+	if _, err := OpenBreachedPasswords(filepath.Join(dir, "missing"), 1); err == nil {
+		t.Fatal("expected a missing dataset to be rejected")
+	}
+}
@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// AuditEventRepository stores the audit trail. It can only add and read
+// events; there is deliberately no way to change or remove one.
+type AuditEventRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditEventRepository(db *gorm.DB) *AuditEventRepository {
+	return &AuditEventRepository{db: db}
+}
+
+func (r *AuditEventRepository) Create(event *models.AuditEvent) error {
+	return r.db.Create(event).Error
+}
+
+// List returns up to limit events matching filter with an ID below beforeID
+// (0 for no bound), newest first.
+func (r *AuditEventRepository) List(filter models.AuditEventFilter, beforeID uint, limit int) ([]models.AuditEvent, error) {
+	query := r.filtered(filter)
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+	var events []models.AuditEvent
+	err := query.Order("id DESC").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// ListAfter returns up to limit events matching filter with an ID above
+// afterID, oldest first.
+func (r *AuditEventRepository) ListAfter(filter models.AuditEventFilter, afterID uint, limit int) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	err := r.filtered(filter).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *AuditEventRepository) filtered(filter models.AuditEventFilter) *gorm.DB {
+	query := r.db.Model(&models.AuditEvent{})
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+	return query
+}
@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/testutil"
+)
+
+func TestAuditEventRepository_ListAndListAfter(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewAuditEventRepository(db)
+
+	userID := uint(7)
+	now := time.Now()
+	events := []*models.AuditEvent{
+		{Type: models.AuditLoginFailed, Email: "a@example.com", CreatedAt: now.Add(-2 * time.Hour)},
+		{Type: models.AuditLoginSucceeded, UserID: &userID, CreatedAt: now.Add(-time.Hour)},
+		{Type: models.AuditLogout, UserID: &userID, CreatedAt: now},
+		{Type: models.AuditLoginSucceeded, CreatedAt: now},
+	}
+	for _, e := range events {
+		if err := repo.Create(e); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	page, err := repo.List(models.AuditEventFilter{}, 0, 2)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(page) != 2 || page[0].ID != events[3].ID || page[1].ID != events[2].ID {
+		t.Fatalf("List() = %+v, want the two newest events", page)
+	}
+	page, _ = repo.List(models.AuditEventFilter{}, page[1].ID, 2)
+	if len(page) != 2 || page[0].ID != events[1].ID || page[1].ID != events[0].ID {
+		t.Fatalf("List() second page = %+v, want the two oldest events", page)
+	}
+
+	byUser, _ := repo.List(models.AuditEventFilter{UserID: userID, Type: models.AuditLoginSucceeded}, 0, 10)
+	if len(byUser) != 1 || byUser[0].ID != events[1].ID {
+		t.Fatalf("List() by user and type = %+v", byUser)
+	}
+	window, _ := repo.List(models.AuditEventFilter{Since: now.Add(-90 * time.Minute), Until: now.Add(-time.Minute)}, 0, 10)
+	if len(window) != 1 || window[0].ID != events[1].ID {
+		t.Fatalf("List() in time window = %+v", window)
+	}
+
+	after, err := repo.ListAfter(models.AuditEventFilter{}, events[1].ID, 10)
+	if err != nil {
+		t.Fatalf("ListAfter() error = %v", err)
+	}
+	if len(after) != 2 || after[0].ID != events[2].ID || after[1].ID != events[3].ID {
+		t.Fatalf("ListAfter() = %+v, want the last two events oldest first", after)
+	}
+}
@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type EmailVerificationRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailVerificationRepository(db *gorm.DB) *EmailVerificationRepository {
+	return &EmailVerificationRepository{db: db}
+}
+
+func (r *EmailVerificationRepository) Create(verification *models.EmailVerification) error {
+	return r.db.Create(verification).Error
+}
+
+func (r *EmailVerificationRepository) GetByToken(token string) (*models.EmailVerification, error) {
+	var verification models.EmailVerification
+	err := r.db.Where("token = ?", token).First(&verification).Error
+	if err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+// GetLatestByUserID returns the most recently issued verification of the user.
+func (r *EmailVerificationRepository) GetLatestByUserID(userID uint) (*models.EmailVerification, error) {
+	var verification models.EmailVerification
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").First(&verification).Error
+	if err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+func (r *EmailVerificationRepository) Delete(id uint) error {
+	return r.db.Delete(&models.EmailVerification{}, id).Error
+}
+
+func (r *EmailVerificationRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.EmailVerification{}).Error
+}
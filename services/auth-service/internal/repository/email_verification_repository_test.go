@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+func TestEmailVerificationRepository_CreateAndGetByToken(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewEmailVerificationRepository(db)
+	userRepo := NewUserRepository(db)
+
+	user := testutil.CreateTestUser()
+	user.ID = 0
+	userRepo.Create(user)
+
+	verification := &models.EmailVerification{UserID: user.ID, Token: "verify-token", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := repo.Create(verification); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	found, err := repo.GetByToken("verify-token")
+	if err != nil {
+		t.Fatalf("GetByToken() error = %v", err)
+	}
+	if found.UserID != user.ID {
+		t.Errorf("GetByToken() userID = %d, want %d", found.UserID, user.ID)
+	}
+
+	if _, err := repo.GetByToken("unknown"); err != gorm.ErrRecordNotFound {
+		t.Errorf("GetByToken() error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestEmailVerificationRepository_GetLatestByUserID(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewEmailVerificationRepository(db)
+	userRepo := NewUserRepository(db)
+
+	user := testutil.CreateTestUser()
+	user.ID = 0
+	userRepo.Create(user)
+
+	if _, err := repo.GetLatestByUserID(user.ID); err != gorm.ErrRecordNotFound {
+		t.Fatalf("GetLatestByUserID() error = %v, want ErrRecordNotFound", err)
+	}
+
+	now := time.Now()
+	repo.Create(&models.EmailVerification{UserID: user.ID, Token: "older", ExpiresAt: now.Add(time.Hour), CreatedAt: now.Add(-time.Minute)})
+	repo.Create(&models.EmailVerification{UserID: user.ID, Token: "newer", ExpiresAt: now.Add(time.Hour), CreatedAt: now})
+
+	latest, err := repo.GetLatestByUserID(user.ID)
+	if err != nil {
+		t.Fatalf("GetLatestByUserID() error = %v", err)
+	}
+	if latest.Token != "newer" {
+		t.Errorf("GetLatestByUserID() token = %s, want newer", latest.Token)
+	}
+}
+
+func TestEmailVerificationRepository_Delete(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewEmailVerificationRepository(db)
+	userRepo := NewUserRepository(db)
+
+	user := testutil.CreateTestUser()
+	user.ID = 0
+	userRepo.Create(user)
+
+	first := &models.EmailVerification{UserID: user.ID, Token: "first", ExpiresAt: time.Now().Add(time.Hour)}
+	second := &models.EmailVerification{UserID: user.ID, Token: "second", ExpiresAt: time.Now().Add(time.Hour)}
+	repo.Create(first)
+	repo.Create(second)
+
+	if err := repo.Delete(first.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := repo.GetByToken("first"); err == nil {
+		t.Error("Delete() should remove the verification")
+	}
+
+	if err := repo.DeleteByUserID(user.ID); err != nil {
+		t.Fatalf("DeleteByUserID() error = %v", err)
+	}
+	if _, err := repo.GetByToken("second"); err == nil {
+		t.Error("DeleteByUserID() should remove every verification of the user")
+	}
+}
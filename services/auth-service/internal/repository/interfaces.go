@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"github.com/icl00ud/velure/services/auth-service/internal/model"
 )
@@ -11,20 +12,31 @@ type UserRepositoryInterface interface {
 	Create(user *models.User) error
 	GetAll() ([]models.User, error)
 	GetByID(id uint) (*models.User, error)
+	GetByIDs(ids []uint) ([]models.User, error)
 	GetByEmail(email string) (*models.User, error)
 	Update(user *models.User) error
+	UpdatePasswordHash(id uint, oldHash, newHash string) (bool, error)
 	Delete(id uint) error
 	GetByPage(page, pageSize int) ([]models.User, int64, error)
+	Search(filter models.UserFilter, sort models.UserSort, after *models.User, limit int) ([]models.User, error)
 	CountUsers(ctx context.Context) (int64, error)
 }
 
 // SessionRepositoryInterface defines the interface for session repository operations
 type SessionRepositoryInterface interface {
 	Create(session *models.Session) error
-	GetByUserID(userID uint) (*models.Session, error)
+	GetByID(id uint) (*models.Session, error)
+	ListActiveByUserID(userID uint) ([]models.Session, error)
+	ListIssuedSince(userID uint, since time.Time) ([]models.Session, error)
 	GetByRefreshToken(refreshToken string) (*models.Session, error)
+	Touch(accessToken string, now time.Time, interval time.Duration) error
 	Update(session *models.Session) error
 	InvalidateByRefreshToken(refreshToken string) error
+	Invalidate(id uint) error
+	AssignFamily(session *models.Session, familyID string) error
+	MarkRotated(session *models.Session) (bool, error)
+	InvalidateByUserID(userID uint) error
+	InvalidateFamily(familyID string) error
 	CountActiveSessions(ctx context.Context) (int64, error)
 	Delete(id uint) error
 }
@@ -34,4 +46,42 @@ type PasswordResetRepositoryInterface interface {
 	Create(passwordReset *models.PasswordReset) error
 	GetByToken(token string) (*models.PasswordReset, error)
 	Delete(id uint) error
+	DeleteByUserID(userID uint) error
+}
+
+// EmailVerificationRepositoryInterface defines the interface for email verification repository operations
+type EmailVerificationRepositoryInterface interface {
+	Create(verification *models.EmailVerification) error
+	GetByToken(token string) (*models.EmailVerification, error)
+	GetLatestByUserID(userID uint) (*models.EmailVerification, error)
+	Delete(id uint) error
+	DeleteByUserID(userID uint) error
+}
+
+// UserIdentityRepositoryInterface defines the interface for linked external identity operations
+type UserIdentityRepositoryInterface interface {
+	Create(identity *models.UserIdentity) error
+	GetByProviderSubject(provider, subject string) (*models.UserIdentity, error)
+}
+
+// OAuthClientRepositoryInterface defines the interface for machine client repository operations
+type OAuthClientRepositoryInterface interface {
+	Create(client *models.OAuthClient) error
+	Update(client *models.OAuthClient) error
+	GetByClientID(clientID string) (*models.OAuthClient, error)
+	List() ([]models.OAuthClient, error)
+	DeleteByClientID(clientID string) error
+}
+
+// PasswordHistoryRepositoryInterface defines the interface for password history repository operations
+type PasswordHistoryRepositoryInterface interface {
+	Add(userID uint, hash string, keep int) error
+	ListRecent(userID uint, limit int) ([]models.PasswordHistory, error)
+}
+
+// AuditEventRepositoryInterface defines the interface for the append-only audit trail
+type AuditEventRepositoryInterface interface {
+	Create(event *models.AuditEvent) error
+	List(filter models.AuditEventFilter, beforeID uint, limit int) ([]models.AuditEvent, error)
+	ListAfter(filter models.AuditEventFilter, afterID uint, limit int) ([]models.AuditEvent, error)
 }
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type OAuthClientRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthClientRepository(db *gorm.DB) *OAuthClientRepository {
+	return &OAuthClientRepository{db: db}
+}
+
+func (r *OAuthClientRepository) Create(client *models.OAuthClient) error {
+	return r.db.Create(client).Error
+}
+
+func (r *OAuthClientRepository) Update(client *models.OAuthClient) error {
+	return r.db.Save(client).Error
+}
+
+func (r *OAuthClientRepository) GetByClientID(clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	err := r.db.Where("client_id = ?", clientID).First(&client).Error
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r *OAuthClientRepository) List() ([]models.OAuthClient, error) {
+	var clients []models.OAuthClient
+	err := r.db.Order("client_id").Find(&clients).Error
+	return clients, err
+}
+
+// DeleteByClientID removes the client, returning gorm.ErrRecordNotFound if
+// there is none.
+func (r *OAuthClientRepository) DeleteByClientID(clientID string) error {
+	result := r.db.Where("client_id = ?", clientID).Delete(&models.OAuthClient{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+func TestOAuthClientRepository(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewOAuthClientRepository(db)
+
+	client := &models.OAuthClient{
+		ClientID:   "process-order-service",
+		Name:       "Order processing",
+		SecretHash: "hash",
+		Scopes:     models.Scopes{"inventory:write"},
+	}
+	if err := repo.Create(client); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repo.Create(&models.OAuthClient{ClientID: client.ClientID, Name: "dup", SecretHash: "x"}); err == nil {
+		t.Fatal("expected client IDs to be unique")
+	}
+
+	got, err := repo.GetByClientID("process-order-service")
+	if err != nil {
+		t.Fatalf("GetByClientID() error = %v", err)
+	}
+	if len(got.Scopes) != 1 || got.Scopes[0] != "inventory:write" {
+		t.Fatalf("scopes = %v", got.Scopes)
+	}
+
+	got.SecretHash = "rotated"
+	if err := repo.Update(got); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	clients, err := repo.List()
+	if err != nil || len(clients) != 1 || clients[0].SecretHash != "rotated" {
+		t.Fatalf("List() = %+v, %v", clients, err)
+	}
+
+	if err := repo.DeleteByClientID("process-order-service"); err != nil {
+		t.Fatalf("DeleteByClientID() error = %v", err)
+	}
+	if err := repo.DeleteByClientID("process-order-service"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("deleting a missing client = %v, want ErrRecordNotFound", err)
+	}
+}
@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type PasswordHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordHistoryRepository(db *gorm.DB) *PasswordHistoryRepository {
+	return &PasswordHistoryRepository{db: db}
+}
+
+// Add records a hash the user no longer uses and keeps only the user's keep
+// most recent entries.
+func (r *PasswordHistoryRepository) Add(userID uint, hash string, keep int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.PasswordHistory{UserID: userID, Hash: hash}).Error; err != nil {
+			return err
+		}
+		kept := tx.Model(&models.PasswordHistory{}).
+			Select("id").
+			Where("user_id = ?", userID).
+			Order("id DESC").
+			Limit(keep)
+		return tx.Where("user_id = ? AND id NOT IN (?)", userID, kept).
+			Delete(&models.PasswordHistory{}).Error
+	})
+}
+
+// ListRecent returns up to limit of the user's previous hashes, newest first.
+func (r *PasswordHistoryRepository) ListRecent(userID uint, limit int) ([]models.PasswordHistory, error) {
+	var entries []models.PasswordHistory
+	err := r.db.Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/testutil"
+)
+
+func TestPasswordHistoryRepository_AddKeepsMostRecent(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	userRepo := NewUserRepository(db)
+	repo := NewPasswordHistoryRepository(db)
+
+	user := testutil.CreateTestUser()
+	user.ID = 0
+	if err := userRepo.Create(user); err != nil {
+		t.Fatalf("failed to setup test user: %v", err)
+	}
+
+	for _, hash := range []string{"hash-1", "hash-2", "hash-3", "hash-4"} {
+		if err := repo.Add(user.ID, hash, 2); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	entries, err := repo.ListRecent(user.ID, 10)
+	if err != nil {
+		t.Fatalf("ListRecent() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].Hash != "hash-4" || entries[1].Hash != "hash-3" {
+		t.Fatalf("ListRecent() = %+v, want hash-4 and hash-3", entries)
+	}
+
+	if err := userRepo.Delete(user.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	var count int64
+	db.Model(&models.PasswordHistory{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected the history to be deleted with the user, %d rows left", count)
+	}
+}
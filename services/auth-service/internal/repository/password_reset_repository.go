@@ -30,3 +30,7 @@ func (r *PasswordResetRepository) GetByToken(token string) (*models.PasswordRese
 func (r *PasswordResetRepository) Delete(id uint) error {
 	return r.db.Delete(&models.PasswordReset{}, id).Error
 }
+
+func (r *PasswordResetRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.PasswordReset{}).Error
+}
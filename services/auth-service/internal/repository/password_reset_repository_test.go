@@ -87,3 +87,31 @@ func TestPasswordResetRepository_Delete(t *testing.T) {
 		t.Error("Delete() password reset still exists after deletion")
 	}
 }
+
+func TestPasswordResetRepository_DeleteByUserID(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewPasswordResetRepository(db)
+	userRepo := NewUserRepository(db)
+
+	user := testutil.CreateTestUser()
+	user.ID = 0
+	userRepo.Create(user)
+
+	for _, token := range []string{"token-a", "token-b"} {
+		reset := testutil.CreateTestPasswordReset(user.ID, func(r *models.PasswordReset) {
+			r.ID = 0
+			r.Token = token
+		})
+		repo.Create(reset)
+	}
+
+	if err := repo.DeleteByUserID(user.ID); err != nil {
+		t.Fatalf("DeleteByUserID() error = %v", err)
+	}
+
+	for _, token := range []string{"token-a", "token-b"} {
+		if _, err := repo.GetByToken(token); err != gorm.ErrRecordNotFound {
+			t.Errorf("GetByToken(%q) error = %v, want ErrRecordNotFound", token, err)
+		}
+	}
+}
@@ -21,15 +21,37 @@ func (r *SessionRepository) Create(session *models.Session) error {
 	return r.db.Create(session).Error
 }
 
-func (r *SessionRepository) GetByUserID(userID uint) (*models.Session, error) {
+func (r *SessionRepository) GetByID(id uint) (*models.Session, error) {
 	var session models.Session
-	err := r.db.Where("user_id = ?", userID).First(&session).Error
+	err := r.db.First(&session, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &session, nil
 }
 
+// ListActiveByUserID returns one entry per signed-in device: sessions that
+// have neither expired nor been rotated, most recently used first.
+func (r *SessionRepository) ListActiveByUserID(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.
+		Where("user_id = ? AND rotated_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Order("id DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// ListIssuedSince returns every session of the user, rotated and expired
+// ones included, whose tokens were minted after since.
+func (r *SessionRepository) ListIssuedSince(userID uint, since time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.
+		Where("user_id = ? AND issued_at > ?", userID, since).
+		Find(&sessions).Error
+	return sessions, err
+}
+
 func (r *SessionRepository) GetByRefreshToken(refreshToken string) (*models.Session, error) {
 	var session models.Session
 	err := r.db.Where("refresh_token = ?", refreshToken).First(&session).Error
@@ -39,6 +61,14 @@ func (r *SessionRepository) GetByRefreshToken(refreshToken string) (*models.Sess
 	return &session, nil
 }
 
+// Touch records that the session holding accessToken was used at now,
+// unless a use less than interval before now is recorded already.
+func (r *SessionRepository) Touch(accessToken string, now time.Time, interval time.Duration) error {
+	return r.db.Model(&models.Session{}).
+		Where("access_token = ? AND (last_used_at IS NULL OR last_used_at < ?)", accessToken, now.Add(-interval)).
+		Update("last_used_at", now).Error
+}
+
 func (r *SessionRepository) Update(session *models.Session) error {
 	return r.db.Save(session).Error
 }
@@ -49,6 +79,71 @@ func (r *SessionRepository) InvalidateByRefreshToken(refreshToken string) error
 		Update("expires_at", time.Now()).Error
 }
 
+// Invalidate expires a single session.
+func (r *SessionRepository) Invalidate(id uint) error {
+	now := time.Now()
+	return r.db.Model(&models.Session{}).
+		Where("id = ? AND expires_at > ?", id, now).
+		Update("expires_at", now).Error
+}
+
+// AssignFamily gives a session created before rotation existed the family
+// familyID, unless a concurrent refresh gave it one first. Either way
+// session.FamilyID ends up as the stored family.
+func (r *SessionRepository) AssignFamily(session *models.Session, familyID string) error {
+	if err := r.db.Model(&models.Session{}).
+		Where("id = ? AND family_id = ''", session.ID).
+		Update("family_id", familyID).Error; err != nil {
+		return err
+	}
+
+	var stored models.Session
+	if err := r.db.Select("family_id").First(&stored, session.ID).Error; err != nil {
+		return err
+	}
+	session.FamilyID = stored.FamilyID
+	return nil
+}
+
+// MarkRotated retires a session whose refresh token has just been exchanged.
+// It only succeeds for a session that has not been rotated yet, so two
+// concurrent refreshes with the same token cannot both go through; the loser
+// gets false.
+func (r *SessionRepository) MarkRotated(session *models.Session) (bool, error) {
+	now := time.Now()
+	result := r.db.Model(&models.Session{}).
+		Where("id = ? AND rotated_at IS NULL", session.ID).
+		Updates(map[string]interface{}{
+			"rotated_at": now,
+			"expires_at": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	session.RotatedAt = &now
+	session.ExpiresAt = now
+	return true, nil
+}
+
+// InvalidateByUserID expires every still-active session of the user.
+func (r *SessionRepository) InvalidateByUserID(userID uint) error {
+	now := time.Now()
+	return r.db.Model(&models.Session{}).
+		Where("user_id = ? AND expires_at > ?", userID, now).
+		Update("expires_at", now).Error
+}
+
+// InvalidateFamily expires every still-active session of a rotation family.
+func (r *SessionRepository) InvalidateFamily(familyID string) error {
+	now := time.Now()
+	return r.db.Model(&models.Session{}).
+		Where("family_id = ? AND expires_at > ?", familyID, now).
+		Update("expires_at", now).Error
+}
+
 func (r *SessionRepository) CountActiveSessions(ctx context.Context) (int64, error) {
 	if ctx == nil {
 		ctx = context.Background()
@@ -34,7 +34,7 @@ func TestSessionRepository_Create(t *testing.T) {
 	}
 }
 
-func TestSessionRepository_GetByUserID(t *testing.T) {
+func TestSessionRepository_GetByID(t *testing.T) {
 	db := testutil.SetupTestDB(t)
 	repo := NewSessionRepository(db)
 	userRepo := NewUserRepository(db)
@@ -47,19 +47,156 @@ func TestSessionRepository_GetByUserID(t *testing.T) {
 	session.ID = 0
 	repo.Create(session)
 
-	found, err := repo.GetByUserID(user.ID)
+	found, err := repo.GetByID(session.ID)
 	if err != nil {
-		t.Fatalf("GetByUserID() error = %v", err)
+		t.Fatalf("GetByID() error = %v", err)
 	}
 
 	if found.UserID != user.ID {
-		t.Errorf("GetByUserID() userID = %d, want %d", found.UserID, user.ID)
+		t.Errorf("GetByID() userID = %d, want %d", found.UserID, user.ID)
 	}
 
 	// Test not found
-	_, err = repo.GetByUserID(999)
+	_, err = repo.GetByID(999)
 	if err == nil {
-		t.Error("GetByUserID() should return error for non-existent user")
+		t.Error("GetByID() should return error for non-existent session")
+	}
+}
+
+func TestSessionRepository_ListActiveByUserID(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewSessionRepository(db)
+	userRepo := NewUserRepository(db)
+
+	user := testutil.CreateTestUser()
+	user.ID = 0
+	userRepo.Create(user)
+
+	now := time.Now()
+	desktop := testutil.CreateTestSession(user.ID, func(s *models.Session) {
+		s.ID = 0
+		s.AccessToken, s.RefreshToken = "desktop-access", "desktop-refresh"
+		s.UserAgent = "desktop"
+		s.LastUsedAt = now.Add(-time.Hour)
+	})
+	phone := testutil.CreateTestSession(user.ID, func(s *models.Session) {
+		s.ID = 0
+		s.AccessToken, s.RefreshToken = "phone-access", "phone-refresh"
+		s.UserAgent = "phone"
+		s.LastUsedAt = now
+	})
+	expired := testutil.CreateTestSession(user.ID, func(s *models.Session) {
+		s.ID = 0
+		s.AccessToken, s.RefreshToken = "expired-access", "expired-refresh"
+		s.ExpiresAt = now.Add(-time.Minute)
+	})
+	rotated := testutil.CreateTestSession(user.ID, func(s *models.Session) {
+		s.ID = 0
+		s.AccessToken, s.RefreshToken = "rotated-access", "rotated-refresh"
+		s.RotatedAt = &now
+	})
+	for _, s := range []*models.Session{desktop, phone, expired, rotated} {
+		if err := repo.Create(s); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	sessions, err := repo.ListActiveByUserID(user.ID)
+	if err != nil {
+		t.Fatalf("ListActiveByUserID() error = %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].UserAgent != "phone" || sessions[1].UserAgent != "desktop" {
+		t.Fatalf("ListActiveByUserID() = %+v, want phone then desktop", sessions)
+	}
+
+	if err := repo.Invalidate(phone.ID); err != nil {
+		t.Fatalf("Invalidate() error = %v", err)
+	}
+	sessions, _ = repo.ListActiveByUserID(user.ID)
+	if len(sessions) != 1 || sessions[0].ID != desktop.ID {
+		t.Fatalf("after Invalidate() got %+v, want only desktop", sessions)
+	}
+}
+
+func TestSessionRepository_ListIssuedSince(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewSessionRepository(db)
+
+	now := time.Now()
+	sessions := []*models.Session{
+		testutil.CreateTestSession(1, func(s *models.Session) {
+			s.ID = 0
+			s.AccessToken, s.RefreshToken = "fresh-access", "fresh-refresh"
+			s.IssuedAt = now
+		}),
+		testutil.CreateTestSession(1, func(s *models.Session) {
+			s.ID = 0
+			s.AccessToken, s.RefreshToken = "rotated-access", "rotated-refresh"
+			s.RotatedAt = &now
+			s.ExpiresAt = now
+			s.IssuedAt = now.Add(-10 * time.Minute)
+		}),
+		testutil.CreateTestSession(1, func(s *models.Session) {
+			s.ID = 0
+			s.AccessToken, s.RefreshToken = "old-access", "old-refresh"
+			s.IssuedAt = now.Add(-2 * time.Hour)
+		}),
+		testutil.CreateTestSession(2, func(s *models.Session) {
+			s.ID = 0
+			s.AccessToken, s.RefreshToken = "other-access", "other-refresh"
+			s.IssuedAt = now
+		}),
+	}
+	for _, s := range sessions {
+		if err := repo.Create(s); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	got, err := repo.ListIssuedSince(1, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ListIssuedSince() error = %v", err)
+	}
+	tokens := map[string]bool{}
+	for _, s := range got {
+		tokens[s.AccessToken] = true
+	}
+	if len(got) != 2 || !tokens["fresh-access"] || !tokens["rotated-access"] {
+		t.Fatalf("ListIssuedSince() = %+v, want the fresh and rotated sessions", got)
+	}
+}
+
+func TestSessionRepository_Touch(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewSessionRepository(db)
+
+	used := time.Now().Add(-time.Hour)
+	session := testutil.CreateTestSession(1, func(s *models.Session) {
+		s.ID = 0
+		s.LastUsedAt, s.IssuedAt = used, used
+	})
+	if err := repo.Create(session); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	now := time.Now()
+	if err := repo.Touch(session.AccessToken, now, time.Minute); err != nil {
+		t.Fatalf("Touch() error = %v", err)
+	}
+	// A second use within the interval is not written.
+	if err := repo.Touch(session.AccessToken, now.Add(30*time.Second), time.Minute); err != nil {
+		t.Fatalf("Touch() error = %v", err)
+	}
+
+	got, err := repo.GetByID(session.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if !got.LastUsedAt.Equal(now) {
+		t.Fatalf("LastUsedAt = %v, want %v", got.LastUsedAt, now)
+	}
+	if !got.IssuedAt.Equal(used) {
+		t.Fatalf("IssuedAt changed to %v", got.IssuedAt)
 	}
 }
 
@@ -114,7 +251,7 @@ func TestSessionRepository_Update(t *testing.T) {
 		t.Fatalf("Update() error = %v", err)
 	}
 
-	updated, _ := repo.GetByUserID(user.ID)
+	updated, _ := repo.GetByID(session.ID)
 	if updated.AccessToken != "new-access-token" {
 		t.Errorf("Update() accessToken = %s, want new-access-token", updated.AccessToken)
 	}
@@ -240,3 +377,120 @@ func TestSessionRepository_Delete(t *testing.T) {
 		t.Error("Delete() session still exists after deletion")
 	}
 }
+
+func TestSessionRepository_AssignFamily(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewSessionRepository(db)
+	userRepo := NewUserRepository(db)
+
+	user := testutil.CreateTestUser()
+	user.ID = 0
+	userRepo.Create(user)
+
+	legacy := testutil.CreateTestSession(user.ID, func(s *models.Session) {
+		s.ID = 0
+		s.FamilyID = ""
+	})
+	repo.Create(legacy)
+	stale := *legacy
+
+	if err := repo.AssignFamily(legacy, "family-a"); err != nil || legacy.FamilyID != "family-a" {
+		t.Fatalf("AssignFamily() = %v, family %q; want family-a", err, legacy.FamilyID)
+	}
+
+	// A concurrent refresh that read the session before the family was
+	// stored ends up with the stored family, not its own.
+	if err := repo.AssignFamily(&stale, "family-b"); err != nil || stale.FamilyID != "family-a" {
+		t.Fatalf("second AssignFamily() = %v, family %q; want family-a", err, stale.FamilyID)
+	}
+}
+
+func TestSessionRepository_MarkRotatedAndInvalidateFamily(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewSessionRepository(db)
+	userRepo := NewUserRepository(db)
+
+	user := testutil.CreateTestUser()
+	user.ID = 0
+	userRepo.Create(user)
+
+	first := testutil.CreateTestSession(user.ID, func(s *models.Session) {
+		s.ID = 0
+		s.FamilyID = "family-a"
+	})
+	repo.Create(first)
+
+	rotated, err := repo.MarkRotated(first)
+	if err != nil || !rotated {
+		t.Fatalf("MarkRotated() = %v, %v; want true, nil", rotated, err)
+	}
+	if first.RotatedAt == nil {
+		t.Fatal("MarkRotated() should set RotatedAt")
+	}
+
+	// A session can only be rotated once.
+	rotated, err = repo.MarkRotated(first)
+	if err != nil || rotated {
+		t.Fatalf("second MarkRotated() = %v, %v; want false, nil", rotated, err)
+	}
+
+	// Rotated sessions no longer show up as a signed-in device.
+	if sessions, _ := repo.ListActiveByUserID(user.ID); len(sessions) != 0 {
+		t.Fatalf("ListActiveByUserID() = %+v, want none", sessions)
+	}
+
+	second := testutil.CreateTestSession(user.ID, func(s *models.Session) {
+		s.ID = 0
+		s.FamilyID = "family-a"
+		s.AccessToken = "second-access"
+		s.RefreshToken = "second-refresh"
+	})
+	repo.Create(second)
+	other := testutil.CreateTestSession(user.ID, func(s *models.Session) {
+		s.ID = 0
+		s.FamilyID = "family-b"
+		s.AccessToken = "other-access"
+		s.RefreshToken = "other-refresh"
+	})
+	repo.Create(other)
+
+	if err := repo.InvalidateFamily("family-a"); err != nil {
+		t.Fatalf("InvalidateFamily() error = %v", err)
+	}
+
+	count, _ := repo.CountActiveSessions(context.Background())
+	if count != 1 {
+		t.Fatalf("expected only the other family's session to stay active, got %d", count)
+	}
+}
+
+func TestSessionRepository_InvalidateByUserID(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewSessionRepository(db)
+	userRepo := NewUserRepository(db)
+
+	user := testutil.CreateTestUser()
+	user.ID = 0
+	userRepo.Create(user)
+	other := testutil.CreateTestUser(func(u *models.User) {
+		u.ID = 0
+		u.Email = "other@example.com"
+	})
+	userRepo.Create(other)
+
+	repo.Create(testutil.CreateTestSession(user.ID, func(s *models.Session) { s.ID = 0 }))
+	repo.Create(testutil.CreateTestSession(other.ID, func(s *models.Session) {
+		s.ID = 0
+		s.AccessToken = "other-access"
+		s.RefreshToken = "other-refresh"
+	}))
+
+	if err := repo.InvalidateByUserID(user.ID); err != nil {
+		t.Fatalf("InvalidateByUserID() error = %v", err)
+	}
+
+	count, _ := repo.CountActiveSessions(context.Background())
+	if count != 1 {
+		t.Fatalf("expected only the other user's session to stay active, got %d", count)
+	}
+}
@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+func TestUserIdentityRepository_CreateAndGetByProviderSubject(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewUserIdentityRepository(db)
+	userRepo := NewUserRepository(db)
+
+	user := testutil.CreateTestUser()
+	user.ID = 0
+	userRepo.Create(user)
+
+	identity := &models.UserIdentity{UserID: user.ID, Provider: "google", Subject: "sub-1", Email: user.Email}
+	if err := repo.Create(identity); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	found, err := repo.GetByProviderSubject("google", "sub-1")
+	if err != nil {
+		t.Fatalf("GetByProviderSubject() error = %v", err)
+	}
+	if found.UserID != user.ID {
+		t.Errorf("GetByProviderSubject() userID = %d, want %d", found.UserID, user.ID)
+	}
+
+	// The same subject at another provider is another identity.
+	if _, err := repo.GetByProviderSubject("okta", "sub-1"); err != gorm.ErrRecordNotFound {
+		t.Errorf("GetByProviderSubject() error = %v, want ErrRecordNotFound", err)
+	}
+
+	duplicate := &models.UserIdentity{UserID: user.ID, Provider: "google", Subject: "sub-1"}
+	if err := repo.Create(duplicate); err == nil {
+		t.Error("expected a second link of the same identity to fail")
+	}
+}
+
+func TestUserRepository_Delete_RemovesIdentities(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewUserIdentityRepository(db)
+	userRepo := NewUserRepository(db)
+
+	user := testutil.CreateTestUser()
+	user.ID = 0
+	userRepo.Create(user)
+	repo.Create(&models.UserIdentity{UserID: user.ID, Provider: "google", Subject: "sub-1"})
+
+	if err := userRepo.Delete(user.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := repo.GetByProviderSubject("google", "sub-1"); err != gorm.ErrRecordNotFound {
+		t.Errorf("GetByProviderSubject() error = %v, want ErrRecordNotFound", err)
+	}
+}
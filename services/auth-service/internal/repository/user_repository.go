@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"strings"
 
 	"github.com/icl00ud/velure/services/auth-service/internal/model"
 
@@ -35,6 +36,17 @@ func (r *UserRepository) GetByID(id uint) (*models.User, error) {
 	return &user, nil
 }
 
+// GetByIDs returns the users with the given IDs, ordered by ID. IDs without
+// a user are skipped.
+func (r *UserRepository) GetByIDs(ids []uint) ([]models.User, error) {
+	var users []models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.Where("id IN ?", ids).Order("id").Find(&users).Error
+	return users, err
+}
+
 func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	var user models.User
 	err := r.db.Where("email = ?", email).First(&user).Error
@@ -48,8 +60,38 @@ func (r *UserRepository) Update(user *models.User) error {
 	return r.db.Save(user).Error
 }
 
+// UpdatePasswordHash swaps the user's password hash for newHash, but only
+// while it is still oldHash, so a concurrent password change wins. It
+// reports whether the hash was replaced.
+func (r *UserRepository) UpdatePasswordHash(id uint, oldHash, newHash string) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND password = ?", id, oldHash).
+		Update("password", newHash)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Delete removes the user together with its sessions, password reset and
+// email verification tokens, linked identities and password history, which
+// reference it.
 func (r *UserRepository) Delete(id uint) error {
-	return r.db.Delete(&models.User{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", id).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.PasswordReset{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.EmailVerification{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.UserIdentity{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.PasswordHistory{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.User{}, id).Error
+	})
 }
 
 func (r *UserRepository) GetByPage(page, pageSize int) ([]models.User, int64, error) {
@@ -66,6 +108,90 @@ func (r *UserRepository) GetByPage(page, pageSize int) ([]models.User, int64, er
 	return users, total, err
 }
 
+// userSortColumns maps the sortable fields to their columns.
+var userSortColumns = map[string]string{
+	models.UserSortCreatedAt: "created_at",
+	models.UserSortEmail:     "email",
+	models.UserSortName:      "name",
+}
+
+// Search returns up to limit users matching filter in the given order. after
+// is the last user of the previous page (nil for the first); only its ID and
+// the sorted field are used.
+func (r *UserRepository) Search(filter models.UserFilter, sort models.UserSort, after *models.User, limit int) ([]models.User, error) {
+	column, ok := userSortColumns[sort.Field]
+	if !ok {
+		column = "created_at"
+	}
+	direction, cmp := "ASC", ">"
+	if sort.Desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	query := r.filtered(filter)
+	if after != nil {
+		var value any
+		switch column {
+		case "email":
+			value = after.Email
+		case "name":
+			value = after.Name
+		default:
+			value = after.CreatedAt
+		}
+		query = query.Where("("+column+" "+cmp+" ? OR ("+column+" = ? AND id "+cmp+" ?))", value, value, after.ID)
+	}
+
+	var users []models.User
+	err := query.Order(column + " " + direction).Order("id " + direction).Limit(limit).Find(&users).Error
+	return users, err
+}
+
+func (r *UserRepository) filtered(filter models.UserFilter) *gorm.DB {
+	query := r.db.Model(&models.User{})
+	if filter.Query != "" {
+		prefix := escapeLike(strings.ToLower(filter.Query)) + "%"
+		query = query.Where(`(LOWER(email) LIKE ? ESCAPE '\' OR LOWER(name) LIKE ? ESCAPE '\')`, prefix, prefix)
+	}
+	if !filter.CreatedSince.IsZero() {
+		query = query.Where("created_at >= ?", filter.CreatedSince)
+	}
+	if !filter.CreatedUntil.IsZero() {
+		query = query.Where("created_at < ?", filter.CreatedUntil)
+	}
+	if filter.Verified != nil {
+		query = query.Where(nullCheck("verified_at", *filter.Verified))
+	}
+	if filter.Disabled != nil {
+		query = query.Where(nullCheck("disabled_at", *filter.Disabled))
+	}
+	if filter.Locked != nil {
+		switch {
+		case *filter.Locked && len(filter.LockedAccounts) == 0:
+			query = query.Where("1 = 0")
+		case *filter.Locked:
+			query = query.Where("LOWER(email) IN ?", filter.LockedAccounts)
+		case len(filter.LockedAccounts) > 0:
+			query = query.Where("LOWER(email) NOT IN ?", filter.LockedAccounts)
+		}
+	}
+	return query
+}
+
+// nullCheck matches rows where column is set, or unset when set is false.
+func nullCheck(column string, set bool) string {
+	if set {
+		return column + " IS NOT NULL"
+	}
+	return column + " IS NULL"
+}
+
+// escapeLike escapes the LIKE wildcards in s, using a backslash as escape
+// character.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 func (r *UserRepository) CountUsers(ctx context.Context) (int64, error) {
 	if ctx == nil {
 		ctx = context.Background()
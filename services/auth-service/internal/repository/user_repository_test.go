@@ -2,7 +2,9 @@ package repositories
 
 import (
 	"context"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/icl00ud/velure/services/auth-service/internal/model"
 	"github.com/icl00ud/velure/services/auth-service/internal/testutil"
@@ -191,6 +193,30 @@ func TestUserRepository_GetByID(t *testing.T) {
 	}
 }
 
+func TestUserRepository_GetByIDs(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewUserRepository(db)
+
+	for _, user := range testutil.CreateTestUsers(3) {
+		if err := repo.Create(user); err != nil {
+			t.Fatalf("failed to setup test user: %v", err)
+		}
+	}
+
+	users, err := repo.GetByIDs([]uint{3, 1, 999})
+	if err != nil {
+		t.Fatalf("GetByIDs() error = %v", err)
+	}
+	if len(users) != 2 || users[0].ID != 1 || users[1].ID != 3 {
+		t.Fatalf("expected users 1 and 3 in ID order, got %+v", users)
+	}
+
+	users, err = repo.GetByIDs(nil)
+	if err != nil || len(users) != 0 {
+		t.Fatalf("expected no users for no IDs, got %d (err = %v)", len(users), err)
+	}
+}
+
 func TestUserRepository_GetAll(t *testing.T) {
 	db := testutil.SetupTestDB(t)
 	repo := NewUserRepository(db)
@@ -250,6 +276,32 @@ func TestUserRepository_Update(t *testing.T) {
 	}
 }
 
+func TestUserRepository_UpdatePasswordHash(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewUserRepository(db)
+
+	testUser := testutil.CreateTestUser()
+	testUser.ID = 0
+	if err := repo.Create(testUser); err != nil {
+		t.Fatalf("failed to setup test user: %v", err)
+	}
+
+	updated, err := repo.UpdatePasswordHash(testUser.ID, testUser.Password, "new-hash")
+	if err != nil || !updated {
+		t.Fatalf("UpdatePasswordHash() = %v, %v; want true, nil", updated, err)
+	}
+
+	// The hash changed since it was read: the newer one must survive.
+	updated, err = repo.UpdatePasswordHash(testUser.ID, testUser.Password, "stale-rehash")
+	if err != nil || updated {
+		t.Fatalf("UpdatePasswordHash() with a stale hash = %v, %v; want false, nil", updated, err)
+	}
+	stored, _ := repo.GetByID(testUser.ID)
+	if stored.Password != "new-hash" {
+		t.Errorf("stored hash = %q, want %q", stored.Password, "new-hash")
+	}
+}
+
 func TestUserRepository_Delete(t *testing.T) {
 	db := testutil.SetupTestDB(t)
 	repo := NewUserRepository(db)
@@ -280,6 +332,37 @@ func TestUserRepository_Delete(t *testing.T) {
 	}
 }
 
+func TestUserRepository_DeleteRemovesDependents(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewUserRepository(db)
+
+	user := testutil.CreateTestUser()
+	user.ID = 0
+	if err := repo.Create(user); err != nil {
+		t.Fatalf("failed to setup test user: %v", err)
+	}
+	session := testutil.CreateTestSession(user.ID)
+	session.ID = 0
+	if err := db.Create(session).Error; err != nil {
+		t.Fatalf("failed to setup session: %v", err)
+	}
+	reset := &models.PasswordReset{UserID: user.ID, Token: "hash", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := db.Create(reset).Error; err != nil {
+		t.Fatalf("failed to setup reset token: %v", err)
+	}
+
+	if err := repo.Delete(user.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	var sessions, resets int64
+	db.Model(&models.Session{}).Where("user_id = ?", user.ID).Count(&sessions)
+	db.Model(&models.PasswordReset{}).Where("user_id = ?", user.ID).Count(&resets)
+	if sessions != 0 || resets != 0 {
+		t.Fatalf("expected dependents to be deleted, got %d sessions and %d reset tokens", sessions, resets)
+	}
+}
+
 func TestUserRepository_GetByPage(t *testing.T) {
 	db := testutil.SetupTestDB(t)
 	repo := NewUserRepository(db)
@@ -357,6 +440,123 @@ func TestUserRepository_GetByPage(t *testing.T) {
 	}
 }
 
+func TestUserRepository_Search(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewUserRepository(db)
+
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	verified := base.Add(time.Hour)
+	seed := []struct {
+		name, email string
+		created     time.Time
+		verified    bool
+		disabled    bool
+	}{
+		{"Alice Smith", "alice@example.com", base, true, false},
+		{"Bob Alison", "bob@example.com", base.Add(time.Hour), false, true},
+		{"Carol", "ALI_carol@example.com", base.Add(time.Hour), true, false},
+		{"Dave", "dave@example.com", base.Add(2 * time.Hour), false, false},
+		{"Erin", "erin@example.com", base.Add(3 * time.Hour), true, false},
+	}
+	ids := map[string]uint{}
+	for _, u := range seed {
+		user := testutil.CreateTestUser(func(m *models.User) {
+			m.ID = 0
+			m.Name = u.name
+			m.Email = u.email
+		})
+		if err := repo.Create(user); err != nil {
+			t.Fatalf("create %s: %v", u.email, err)
+		}
+		columns := map[string]any{"created_at": u.created}
+		if u.verified {
+			columns["verified_at"] = verified
+		}
+		if u.disabled {
+			columns["disabled_at"] = verified
+		}
+		if err := db.Model(user).UpdateColumns(columns).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids[u.email] = user.ID
+	}
+	yes, no := true, false
+
+	emails := func(users []models.User) []string {
+		var out []string
+		for _, u := range users {
+			out = append(out, u.Email)
+		}
+		return out
+	}
+	search := func(filter models.UserFilter, sort models.UserSort, after *models.User, limit int) []string {
+		t.Helper()
+		users, err := repo.Search(filter, sort, after, limit)
+		if err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		return emails(users)
+	}
+	byEmail := models.UserSort{Field: models.UserSortEmail}
+
+	tests := []struct {
+		name   string
+		filter models.UserFilter
+		want   []string
+	}{
+		{"prefix of email or name, any case", models.UserFilter{Query: "Ali"}, []string{"ALI_carol@example.com", "alice@example.com"}},
+		{"wildcards are literal", models.UserFilter{Query: "ali_"}, []string{"ALI_carol@example.com"}},
+		{"percent matches nothing", models.UserFilter{Query: "%"}, nil},
+		{"created range", models.UserFilter{CreatedSince: base.Add(time.Hour), CreatedUntil: base.Add(3 * time.Hour)}, []string{"ALI_carol@example.com", "bob@example.com", "dave@example.com"}},
+		{"verified", models.UserFilter{Verified: &yes}, []string{"ALI_carol@example.com", "alice@example.com", "erin@example.com"}},
+		{"unverified", models.UserFilter{Verified: &no}, []string{"bob@example.com", "dave@example.com"}},
+		{"disabled", models.UserFilter{Disabled: &yes}, []string{"bob@example.com"}},
+		{"locked", models.UserFilter{Locked: &yes, LockedAccounts: []string{"dave@example.com", "ali_carol@example.com"}}, []string{"ALI_carol@example.com", "dave@example.com"}},
+		{"not locked", models.UserFilter{Locked: &no, LockedAccounts: []string{"dave@example.com"}}, []string{"ALI_carol@example.com", "alice@example.com", "bob@example.com", "erin@example.com"}},
+		{"locked without lockouts", models.UserFilter{Locked: &yes}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := search(tt.filter, byEmail, nil, 10)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Keyset pages never skip or repeat rows, even across equal sort keys.
+	for _, sort := range []models.UserSort{
+		{Field: models.UserSortCreatedAt, Desc: true},
+		{Field: models.UserSortCreatedAt},
+		{Field: models.UserSortName, Desc: true},
+		byEmail,
+	} {
+		var all []string
+		var after *models.User
+		for {
+			users, err := repo.Search(models.UserFilter{}, sort, after, 2)
+			if err != nil {
+				t.Fatalf("Search(%+v) error = %v", sort, err)
+			}
+			all = append(all, emails(users)...)
+			if len(users) < 2 {
+				break
+			}
+			after = &users[len(users)-1]
+		}
+		seen := map[string]bool{}
+		for _, email := range all {
+			seen[email] = true
+		}
+		if len(all) != len(seed) || len(seen) != len(seed) {
+			t.Fatalf("paging by %+v returned %v", sort, all)
+		}
+		if sort.Field == models.UserSortCreatedAt && sort.Desc && (all[0] != "erin@example.com" || all[4] != "alice@example.com") {
+			t.Fatalf("expected newest first, got %v", all)
+		}
+	}
+}
+
 func TestUserRepository_CountUsers(t *testing.T) {
 	db := testutil.SetupTestDB(t)
 	repo := NewUserRepository(db)
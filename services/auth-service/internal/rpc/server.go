@@ -0,0 +1,231 @@
+// Package rpc serves auth-service's gRPC API next to the HTTP one: token
+// introspection and user lookups for other services. The protocol and the Go
+// client live in shared/authgrpc.
+package rpc
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/service"
+
+	"github.com/icl00ud/velure/shared/auth"
+	"github.com/icl00ud/velure/shared/authgrpc"
+	"github.com/icl00ud/velure/shared/authgrpc/authv1"
+	"github.com/icl00ud/velure/shared/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server implements authv1.AuthServiceServer on top of the auth service.
+type Server struct {
+	authv1.UnimplementedAuthServiceServer
+	authService services.AuthServiceInterface
+}
+
+func NewServer(authService services.AuthServiceInterface) *Server {
+	return &Server{authService: authService}
+}
+
+// NewGRPCServer returns a gRPC server serving s and the standard health
+// service, with every call counted and timed.
+func NewGRPCServer(s *Server) *grpc.Server {
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(recoverPanics, observe))
+	authv1.RegisterAuthServiceServer(srv, s)
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	return srv
+}
+
+func (s *Server) Introspect(_ context.Context, req *authv1.IntrospectRequest) (*authv1.IntrospectResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	info, err := s.authService.IntrospectToken(req.GetToken())
+	if err != nil {
+		return nil, internalError(err)
+	}
+	if !info.Active {
+		return &authv1.IntrospectResponse{}, nil
+	}
+	return &authv1.IntrospectResponse{
+		Active:        true,
+		Subject:       info.Subject,
+		Roles:         info.Roles,
+		ExpiresAt:     timestamp(info.ExpiresAt),
+		SessionId:     info.SessionID,
+		ClientId:      info.ClientID,
+		Scopes:        info.Scopes,
+		EmailVerified: info.EmailVerified,
+		IssuedAt:      timestamp(info.IssuedAt),
+		Actor:         info.Actor,
+	}, nil
+}
+
+func (s *Server) GetUser(ctx context.Context, req *authv1.GetUserRequest) (*authv1.GetUserResponse, error) {
+	if err := s.requireUsersRead(ctx); err != nil {
+		return nil, err
+	}
+	id, ok := userID(req.GetId())
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "invalid user ID")
+	}
+
+	user, err := s.authService.GetUserByID(id)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, internalError(err)
+	}
+	return &authv1.GetUserResponse{User: toUser(user)}, nil
+}
+
+func (s *Server) BatchGetUsers(ctx context.Context, req *authv1.BatchGetUsersRequest) (*authv1.BatchGetUsersResponse, error) {
+	if err := s.requireUsersRead(ctx); err != nil {
+		return nil, err
+	}
+	if len(req.GetIds()) > authgrpc.MaxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d IDs per call", authgrpc.MaxBatchSize)
+	}
+
+	ids := make([]uint, 0, len(req.GetIds()))
+	for _, raw := range req.GetIds() {
+		id, ok := userID(raw)
+		if !ok {
+			return nil, status.Error(codes.InvalidArgument, "invalid user ID")
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	users, err := s.authService.GetUsersByIDs(ids)
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	resp := &authv1.BatchGetUsersResponse{Users: make([]*authv1.User, len(users))}
+	found := make(map[uint]bool, len(users))
+	for i := range users {
+		resp.Users[i] = toUser(&users[i])
+		found[users[i].ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			resp.MissingIds = append(resp.MissingIds, uint64(id))
+		}
+	}
+	return resp, nil
+}
+
+// requireUsersRead admits machine clients holding the users:read scope and
+// admins, identified by the bearer token in the call's metadata.
+func (s *Server) requireUsersRead(ctx context.Context) error {
+	token := bearerToken(ctx)
+	if token == "" {
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	}
+
+	caller, err := s.authService.IntrospectToken(token)
+	if err != nil {
+		return internalError(err)
+	}
+	if !caller.Active {
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	if caller.ClientID != "" {
+		if slices.Contains(caller.Scopes, auth.ScopeUsersRead) {
+			return nil
+		}
+		return status.Error(codes.PermissionDenied, "forbidden")
+	}
+
+	// As on the HTTP API, a user's current roles count, not the token's.
+	user, err := s.authService.ValidateAccessToken(token)
+	if err != nil || user == nil {
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	if !auth.HasAnyRole(user.Roles, auth.RoleAdmin) {
+		return status.Error(codes.PermissionDenied, "forbidden")
+	}
+	return nil
+}
+
+func bearerToken(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(value, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
+
+func userID(raw uint64) (uint, bool) {
+	if raw == 0 || raw > math.MaxUint32 {
+		return 0, false
+	}
+	return uint(raw), true
+}
+
+func toUser(u *models.UserResponse) *authv1.User {
+	user := &authv1.User{
+		Id:               uint64(u.ID),
+		Email:            u.Email,
+		Name:             u.Name,
+		Roles:            u.Roles,
+		EmailVerified:    u.EmailVerified,
+		TwoFactorEnabled: u.TwoFactorEnabled,
+		CreatedAt:        timestamp(u.CreatedAt),
+		UpdatedAt:        timestamp(u.UpdatedAt),
+	}
+	if u.DisabledAt != nil {
+		user.DisabledAt = timestamp(*u.DisabledAt)
+	}
+	return user
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// internalError logs the real cause and returns a generic Internal status so
+// database and infrastructure details never reach the caller.
+func internalError(err error) error {
+	logger.Error("internal error", logger.Err(err))
+	return status.Error(codes.Internal, "internal error")
+}
+
+func observe(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	method := info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
+	metrics.GRPCRequests.WithLabelValues(method, status.Code(err).String()).Inc()
+	metrics.GRPCRequestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	return resp, err
+}
+
+func recoverPanics(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("panic in gRPC handler", logger.String("method", info.FullMethod), logger.Any("panic", r))
+			err = status.Error(codes.Internal, "internal error")
+		}
+	}()
+	return handler(ctx, req)
+}
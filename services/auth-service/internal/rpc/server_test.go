@@ -0,0 +1,197 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/mocks"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+
+	"github.com/icl00ud/velure/shared/auth"
+	"github.com/icl00ud/velure/shared/authgrpc"
+	"github.com/icl00ud/velure/shared/authgrpc/authv1"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func startServer(t *testing.T, svc *mocks.MockAuthServiceInterface) *bufconn.Listener {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := NewGRPCServer(NewServer(svc))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis
+}
+
+func dial(t *testing.T, lis *bufconn.Listener, token string) *authgrpc.Client {
+	t.Helper()
+	var tokenFunc authgrpc.TokenFunc
+	if token != "" {
+		tokenFunc = func(context.Context) (string, error) { return token, nil }
+	}
+	client, err := authgrpc.Dial("passthrough:///bufnet", tokenFunc, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// expectCallers makes "admin-token", "user-token", "reader-token" (a client
+// with users:read) and "writer-token" (a client without it) known.
+func expectCallers(svc *mocks.MockAuthServiceInterface) {
+	svc.EXPECT().IntrospectToken(gomock.Any()).DoAndReturn(func(token string) (*models.TokenIntrospection, error) {
+		switch token {
+		case "admin-token":
+			return &models.TokenIntrospection{Active: true, Subject: "1", Roles: []string{auth.RoleAdmin}}, nil
+		case "user-token":
+			return &models.TokenIntrospection{Active: true, Subject: "7", Roles: []string{auth.RoleCustomer}}, nil
+		case "reader-token":
+			return &models.TokenIntrospection{Active: true, Subject: "reports", ClientID: "reports", Scopes: []string{auth.ScopeUsersRead}}, nil
+		case "writer-token":
+			return &models.TokenIntrospection{Active: true, Subject: "orders", ClientID: "orders", Scopes: []string{auth.ScopeInventoryWrite}}, nil
+		}
+		return &models.TokenIntrospection{}, nil
+	}).AnyTimes()
+	svc.EXPECT().ValidateAccessToken(gomock.Any()).DoAndReturn(func(token string) (*models.User, error) {
+		switch token {
+		case "admin-token":
+			return &models.User{ID: 1, Roles: []string{auth.RoleAdmin}}, nil
+		case "user-token":
+			return &models.User{ID: 7, Roles: []string{auth.RoleCustomer}}, nil
+		}
+		return nil, errors.New("invalid token")
+	}).AnyTimes()
+}
+
+func metadataCtx(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func TestServer_Introspect(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	svc := mocks.NewMockAuthServiceInterface(ctrl)
+	client := dial(t, startServer(t, svc), "")
+	ctx := context.Background()
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	svc.EXPECT().IntrospectToken("good").Return(&models.TokenIntrospection{
+		Active: true, Subject: "42", Roles: []string{auth.RoleCustomer}, SessionID: "family", ExpiresAt: expires, Actor: "1",
+	}, nil)
+	got, err := client.Introspect(ctx, "good")
+	if err != nil {
+		t.Fatalf("Introspect() error = %v", err)
+	}
+	if !got.Active || got.UserID != 42 || got.SessionID != "family" || !got.ExpiresAt.Equal(expires) || got.Actor != "1" {
+		t.Fatalf("unexpected introspection %+v", got)
+	}
+
+	svc.EXPECT().IntrospectToken("bad").Return(&models.TokenIntrospection{}, nil)
+	if got, err := client.Introspect(ctx, "bad"); err != nil || got.Active {
+		t.Fatalf("expected an inactive token, got %+v (err = %v)", got, err)
+	}
+
+	if _, err := client.Introspect(ctx, ""); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for an empty token, got %v", err)
+	}
+
+	svc.EXPECT().IntrospectToken("boom").Return(nil, errors.New("database is down"))
+	if _, err := client.Introspect(ctx, "boom"); status.Code(err) != codes.Internal || status.Convert(errors.Unwrap(err)).Message() != "internal error" {
+		t.Fatalf("expected a generic internal error, got %v", err)
+	}
+}
+
+func TestServer_GetUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	svc := mocks.NewMockAuthServiceInterface(ctrl)
+	expectCallers(svc)
+	lis := startServer(t, svc)
+	ctx := context.Background()
+
+	disabledAt := time.Now().Truncate(time.Second)
+	svc.EXPECT().GetUserByID(uint(42)).Return(&models.UserResponse{ID: 42, Email: "a@example.com", DisabledAt: &disabledAt}, nil).Times(2)
+	for _, token := range []string{"admin-token", "reader-token"} {
+		user, err := dial(t, lis, token).GetUser(ctx, 42)
+		if err != nil {
+			t.Fatalf("GetUser() with %s error = %v", token, err)
+		}
+		if user.ID != 42 || user.Email != "a@example.com" || user.DisabledAt == nil || !user.DisabledAt.Equal(disabledAt) {
+			t.Fatalf("unexpected user %+v", user)
+		}
+	}
+
+	tests := map[string]codes.Code{
+		"":             codes.Unauthenticated,
+		"garbage":      codes.Unauthenticated,
+		"user-token":   codes.PermissionDenied,
+		"writer-token": codes.PermissionDenied,
+	}
+	for token, want := range tests {
+		if _, err := dial(t, lis, token).GetUser(ctx, 42); status.Code(err) != want {
+			t.Fatalf("GetUser() with %q: expected %v, got %v", token, want, err)
+		}
+	}
+
+	admin := dial(t, lis, "admin-token")
+	svc.EXPECT().GetUserByID(uint(5)).Return(nil, errors.New("user not found"))
+	if _, err := admin.GetUser(ctx, 5); !errors.Is(err, authgrpc.ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+	if _, err := admin.GetUser(ctx, 0); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for ID 0, got %v", err)
+	}
+}
+
+func TestServer_BatchGetUsers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	svc := mocks.NewMockAuthServiceInterface(ctrl)
+	expectCallers(svc)
+	lis := startServer(t, svc)
+	ctx := context.Background()
+
+	svc.EXPECT().GetUsersByIDs([]uint{3, 1, 9}).Return([]models.UserResponse{{ID: 1}, {ID: 3}}, nil)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	raw := authv1.NewAuthServiceClient(conn)
+	authCtx := metadataCtx(ctx, "reader-token")
+
+	resp, err := raw.BatchGetUsers(authCtx, &authv1.BatchGetUsersRequest{Ids: []uint64{3, 1, 3, 9}})
+	if err != nil {
+		t.Fatalf("BatchGetUsers() error = %v", err)
+	}
+	if len(resp.GetUsers()) != 2 || len(resp.GetMissingIds()) != 1 || resp.GetMissingIds()[0] != 9 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	tooMany := make([]uint64, authgrpc.MaxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = uint64(i + 1)
+	}
+	if _, err := raw.BatchGetUsers(authCtx, &authv1.BatchGetUsersRequest{Ids: tooMany}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for too many IDs, got %v", err)
+	}
+	if _, err := raw.BatchGetUsers(metadataCtx(ctx, "user-token"), &authv1.BatchGetUsersRequest{Ids: []uint64{1}}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied for a customer, got %v", err)
+	}
+
+	health, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil || health.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("expected the server to report SERVING, got %v (err = %v)", health, err)
+	}
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/events"
+	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+
+	"github.com/icl00ud/velure/shared/logger"
+	"gorm.io/gorm"
+)
+
+// UpdateProfile changes the user's own name, email or password. Email and
+// password changes must be confirmed with the current password. A new email
+// has to be verified again. A new password signs every other device out; the
+// session holding currentAccessToken stays signed in. A new password has to
+// satisfy the password policy.
+func (s *AuthService) UpdateProfile(userID uint, currentAccessToken string, req models.UpdateProfileRequest) (*models.UserResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+	previous := *user
+
+	if req.Email != nil || req.Password != nil {
+		if req.CurrentPassword == "" {
+			return nil, errors.New("current password is required")
+		}
+		if s.comparePassword(user.Password, req.CurrentPassword) != nil {
+			return nil, errors.New("current password is incorrect")
+		}
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if result := validateName(name); !result.IsValid {
+			return nil, result.Error
+		}
+		user.Name = name
+	}
+
+	if req.Email != nil {
+		email := strings.TrimSpace(*req.Email)
+		if result := validateEmail(email); !result.IsValid {
+			return nil, result.Error
+		}
+		if email != user.Email {
+			existing, err := s.userRepo.GetByEmail(email)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				metrics.Errors.WithLabelValues("database").Inc()
+				return nil, fmt.Errorf("error checking existing user: %w", err)
+			}
+			if existing != nil {
+				return nil, errors.New("email already in use")
+			}
+			user.Email = email
+			user.VerifiedAt = nil
+		}
+	}
+
+	passwordChanged := false
+	if req.Password != nil {
+		if err := s.checkNewPassword(user, *req.Password); err != nil {
+			return nil, err
+		}
+		hashedPassword, err := s.hashPassword(*req.Password)
+		if err != nil {
+			metrics.Errors.WithLabelValues("internal").Inc()
+			return nil, fmt.Errorf("error hashing password: %w", err)
+		}
+		user.Password = hashedPassword
+		passwordChanged = true
+	}
+
+	if err := s.userRepo.Update(user); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+
+	// Drop the entries under the old email as well as the new one.
+	ctx := context.Background()
+	s.invalidateUserCache(ctx, &previous)
+	s.invalidateUserCache(ctx, user)
+	s.evictCachedTokens(user.ID)
+
+	if passwordChanged {
+		s.rememberPassword(user.ID, previous.Password)
+		s.recordAudit(models.AuditPasswordChanged, user.ID, user.Email, req.Device, "")
+		if err := s.revokeOtherSessions(user.ID, currentAccessToken); err != nil {
+			return nil, err
+		}
+	}
+
+	if user.Email != previous.Email {
+		if err := s.sendEmailVerification(user); err != nil {
+			logger.Error("failed to send verification email", logger.Uint("user_id", user.ID), logger.Err(err))
+		}
+	}
+
+	response := user.ToResponse()
+	return &response, nil
+}
+
+// DeleteAccount closes the user's account after checking the password. The
+// user's sessions and reset tokens go with it, and a user.deleted event tells
+// other services to drop what they keep for the user. The account is gone
+// even if the event cannot be published; the failure is logged.
+func (s *AuthService) DeleteAccount(userID uint, password string, device models.DeviceInfo) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("user not found")
+		}
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error getting user: %w", err)
+	}
+	if s.comparePassword(user.Password, password) != nil {
+		return errors.New("current password is incorrect")
+	}
+
+	// Listed before the sessions are deleted with the user.
+	sessions, err := s.sessionsWithLiveTokens(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.Delete(user.ID); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error deleting user: %w", err)
+	}
+
+	ctx := context.Background()
+	s.invalidateUserCache(ctx, user)
+	s.evictCachedTokens(user.ID)
+	s.revokeAccessTokens(sessions...)
+	s.revokeImpersonations(user.ID)
+	s.recordAudit(models.AuditAccountDeleted, user.ID, user.Email, device, "")
+	s.SyncActiveSessionsMetric(ctx)
+	s.SyncTotalUsersMetric(ctx)
+
+	evt, err := events.NewEvent(events.UserDeleted, events.UserDeletedPayload{
+		UserID:    user.ID,
+		DeletedAt: time.Now().UTC(),
+	})
+	if err == nil {
+		err = s.events.Publish(ctx, evt)
+	}
+	if err != nil {
+		metrics.Errors.WithLabelValues("events").Inc()
+		logger.Error("failed to publish user.deleted event", logger.Uint("user_id", user.ID), logger.Err(err))
+	}
+	return nil
+}
+
+// revokeOtherSessions signs the user out everywhere except the session
+// holding currentAccessToken, revoking the other sessions' access tokens.
+func (s *AuthService) revokeOtherSessions(userID uint, currentAccessToken string) error {
+	sessions, err := s.sessionRepo.ListActiveByUserID(userID)
+	if err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error listing sessions: %w", err)
+	}
+	live, err := s.sessionsWithLiveTokens(userID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if currentAccessToken != "" && session.AccessToken == currentAccessToken {
+			continue
+		}
+		if err := s.sessionRepo.Invalidate(session.ID); err != nil {
+			metrics.Errors.WithLabelValues("database").Inc()
+			return fmt.Errorf("error invalidating session: %w", err)
+		}
+	}
+	revoked := live[:0]
+	for _, session := range live {
+		if currentAccessToken == "" || session.AccessToken != currentAccessToken {
+			revoked = append(revoked, session)
+		}
+	}
+	s.revokeAccessTokens(revoked...)
+	s.SyncActiveSessionsMetric(context.Background())
+	return nil
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/events"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+)
+
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []events.Event
+	err    error
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, evt events.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.events = append(p.events, evt)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error { return nil }
+
+func strPtr(s string) *string { return &s }
+
+func TestAuthService_UpdateProfile_Name(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.tokenCache.Set(hashToken("cached-token"), f.user)
+
+	updated, err := f.service.UpdateProfile(f.user.ID, "", models.UpdateProfileRequest{Name: strPtr("  New Name ")})
+	if err != nil {
+		t.Fatalf("UpdateProfile() error = %v", err)
+	}
+	if updated.Name != "New Name" {
+		t.Fatalf("expected the trimmed name, got %q", updated.Name)
+	}
+	if _, ok := f.service.tokenCache.Get(hashToken("cached-token")); ok {
+		t.Fatal("expected the user's cached tokens to be evicted")
+	}
+
+	if _, err := f.service.UpdateProfile(f.user.ID, "", models.UpdateProfileRequest{Name: strPtr("x")}); err == nil || err.Error() != "name must be at least 2 characters" {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	if _, err := f.service.UpdateProfile(9999, "", models.UpdateProfileRequest{Name: strPtr("Someone")}); err == nil || err.Error() != "user not found" {
+		t.Fatalf("expected user not found, got %v", err)
+	}
+}
+
+func TestAuthService_UpdateProfile_Email(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	oldEmail := f.user.Email
+
+	// Cache the user under the old email.
+	if _, err := f.service.Login(models.LoginRequest{Email: oldEmail, Password: "password123"}); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	req := models.UpdateProfileRequest{Email: strPtr("renamed@example.com")}
+	if _, err := f.service.UpdateProfile(f.user.ID, "", req); err == nil || err.Error() != "current password is required" {
+		t.Fatalf("expected current password to be required, got %v", err)
+	}
+	req.CurrentPassword = "wrong-password"
+	if _, err := f.service.UpdateProfile(f.user.ID, "", req); err == nil || err.Error() != "current password is incorrect" {
+		t.Fatalf("expected incorrect password, got %v", err)
+	}
+
+	other := &models.User{Name: "Other", Email: "taken@example.com", Password: "x"}
+	if err := f.service.userRepo.Create(other); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	taken := models.UpdateProfileRequest{Email: strPtr(other.Email), CurrentPassword: "password123"}
+	if _, err := f.service.UpdateProfile(f.user.ID, "", taken); err == nil || err.Error() != "email already in use" {
+		t.Fatalf("expected email conflict, got %v", err)
+	}
+
+	req.CurrentPassword = "password123"
+	updated, err := f.service.UpdateProfile(f.user.ID, "", req)
+	if err != nil {
+		t.Fatalf("UpdateProfile() error = %v", err)
+	}
+	if updated.Email != "renamed@example.com" {
+		t.Fatalf("unexpected email %q", updated.Email)
+	}
+
+	if f.redis.Exists("user:email:" + oldEmail) {
+		t.Fatal("expected the old email cache entry to be dropped")
+	}
+	if _, err := f.service.Login(models.LoginRequest{Email: oldEmail, Password: "password123"}); err == nil {
+		t.Fatal("old email must no longer log in")
+	}
+	if _, err := f.service.Login(models.LoginRequest{Email: "renamed@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("new email should log in: %v", err)
+	}
+}
+
+func TestAuthService_UpdateProfile_PasswordRevokesOtherSessions(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	login := func(device string) *models.LoginResponse {
+		resp, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "password123", Device: models.DeviceInfo{UserAgent: device}})
+		if err != nil {
+			t.Fatalf("login failed: %v", err)
+		}
+		return resp
+	}
+	current := login("laptop")
+	other := login("phone")
+
+	req := models.UpdateProfileRequest{Password: strPtr("new-password-1"), CurrentPassword: "password123"}
+	if _, err := f.service.UpdateProfile(f.user.ID, current.AccessToken, req); err != nil {
+		t.Fatalf("UpdateProfile() error = %v", err)
+	}
+
+	if _, err := f.service.RefreshSession(other.RefreshToken, models.DeviceInfo{}); err == nil {
+		t.Fatal("expected the other device to be signed out")
+	}
+	if _, err := f.service.RefreshSession(current.RefreshToken, models.DeviceInfo{}); err != nil {
+		t.Fatalf("expected the current session to survive: %v", err)
+	}
+	if _, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "password123"}); err == nil {
+		t.Fatal("old password must no longer work")
+	}
+	if _, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "new-password-1"}); err != nil {
+		t.Fatalf("new password should work: %v", err)
+	}
+}
+
+func TestAuthService_DeleteAccount(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	publisher := &recordingPublisher{}
+	f.service.AttachEventPublisher(publisher)
+
+	login, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "password123"})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	f.service.tokenCache.Set(hashToken(login.AccessToken), f.user)
+
+	if err := f.service.DeleteAccount(f.user.ID, "wrong-password", models.DeviceInfo{}); err == nil || err.Error() != "current password is incorrect" {
+		t.Fatalf("expected incorrect password, got %v", err)
+	}
+	if len(publisher.events) != 0 {
+		t.Fatal("no event may be published for a rejected deletion")
+	}
+
+	if err := f.service.DeleteAccount(f.user.ID, "password123", models.DeviceInfo{}); err != nil {
+		t.Fatalf("DeleteAccount() error = %v", err)
+	}
+
+	if f.redis.Exists("user:email:" + f.user.Email) {
+		t.Fatal("expected user:email cache entry to be dropped")
+	}
+	if _, err := f.service.ValidateAccessToken(login.AccessToken); err == nil {
+		t.Fatal("access token of a deleted account must not validate")
+	}
+	if _, err := f.service.RefreshSession(login.RefreshToken, models.DeviceInfo{}); err == nil {
+		t.Fatal("expected the account's sessions to be gone")
+	}
+	if _, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "password123"}); err == nil {
+		t.Fatal("deleted account must not log in")
+	}
+
+	if len(publisher.events) != 1 || publisher.events[0].Type != events.UserDeleted {
+		t.Fatalf("expected one user.deleted event, got %+v", publisher.events)
+	}
+	var payload events.UserDeletedPayload
+	if err := json.Unmarshal(publisher.events[0].Payload, &payload); err != nil || payload.UserID != f.user.ID {
+		t.Fatalf("unexpected payload %s", publisher.events[0].Payload)
+	}
+
+	if err := f.service.DeleteAccount(f.user.ID, "password123", models.DeviceInfo{}); err == nil || err.Error() != "user not found" {
+		t.Fatalf("expected user not found, got %v", err)
+	}
+}
+
+func TestAuthService_DeleteAccount_PublishFailureStillDeletes(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.AttachEventPublisher(&recordingPublisher{err: errors.New("broker down")})
+
+	if err := f.service.DeleteAccount(f.user.ID, "password123", models.DeviceInfo{}); err != nil {
+		t.Fatalf("DeleteAccount() error = %v", err)
+	}
+	if _, err := f.service.userRepo.GetByID(f.user.ID); err == nil {
+		t.Fatal("expected the account to be deleted")
+	}
+}
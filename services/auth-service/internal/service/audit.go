@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/repository"
+
+	"github.com/icl00ud/velure/shared/logger"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+	auditExportBatchSize = 500
+)
+
+// AttachAuditLog enables the security audit trail. Without it no events are
+// recorded and the audit endpoints report the log as disabled.
+func (s *AuthService) AttachAuditLog(repo repositories.AuditEventRepositoryInterface) {
+	s.auditRepo = repo
+}
+
+// recordAudit appends an event to the audit trail. userID 0 means the event
+// could not be tied to an account. A failed write is logged and counted but
+// never fails the operation being audited.
+func (s *AuthService) recordAudit(eventType string, userID uint, email string, device models.DeviceInfo, detail string) {
+	if s.auditRepo == nil {
+		return
+	}
+	if err := s.auditRepo.Create(newAuditEvent(eventType, userID, email, device, detail)); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		logger.Error("failed to record audit event", logger.String("type", eventType), logger.Err(err))
+	}
+}
+
+func newAuditEvent(eventType string, userID uint, email string, device models.DeviceInfo, detail string) *models.AuditEvent {
+	event := &models.AuditEvent{
+		Type:      eventType,
+		Email:     truncate(email, 255),
+		IPAddress: truncate(device.IPAddress, 45),
+		UserAgent: truncate(device.UserAgent, 255),
+		RequestID: truncate(device.RequestID, 64),
+		Detail:    truncate(detail, 255),
+	}
+	if userID != 0 {
+		event.UserID = &userID
+	}
+	return event
+}
+
+// ListAuditEvents returns one page of audit events matching filter, newest
+// first. cursor is the NextCursor of the previous page, empty for the first.
+func (s *AuthService) ListAuditEvents(filter models.AuditEventFilter, cursor string, limit int) (*models.AuditEventPage, error) {
+	if s.auditRepo == nil {
+		return nil, errors.New("audit log not enabled")
+	}
+	var beforeID uint64
+	if cursor != "" {
+		var err error
+		beforeID, err = strconv.ParseUint(cursor, 10, 64)
+		if err != nil || beforeID == 0 {
+			return nil, errors.New("invalid cursor")
+		}
+	}
+	if limit <= 0 {
+		limit = defaultAuditPageSize
+	}
+	limit = min(limit, maxAuditPageSize)
+
+	// One extra row tells whether another page follows.
+	events, err := s.auditRepo.List(filter, uint(beforeID), limit+1)
+	if err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error listing audit events: %w", err)
+	}
+
+	page := &models.AuditEventPage{Events: events}
+	if len(events) > limit {
+		page.Events = events[:limit]
+		page.NextCursor = strconv.FormatUint(uint64(page.Events[limit-1].ID), 10)
+	}
+	return page, nil
+}
+
+// ExportAuditEvents passes every event matching filter with an ID above
+// afterID to emit, oldest first, reading the table in batches so an export
+// of the whole trail does not have to fit in memory. It stops at the first
+// error emit returns.
+func (s *AuthService) ExportAuditEvents(filter models.AuditEventFilter, afterID uint, emit func(models.AuditEvent) error) error {
+	if s.auditRepo == nil {
+		return errors.New("audit log not enabled")
+	}
+	for {
+		events, err := s.auditRepo.ListAfter(filter, afterID, auditExportBatchSize)
+		if err != nil {
+			metrics.Errors.WithLabelValues("database").Inc()
+			return fmt.Errorf("error exporting audit events: %w", err)
+		}
+		for _, event := range events {
+			if err := emit(event); err != nil {
+				return err
+			}
+			afterID = event.ID
+		}
+		if len(events) < auditExportBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/lockout"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/repository"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func withAuditLog(f *passwordResetFixture) *repositories.AuditEventRepository {
+	repo := repositories.NewAuditEventRepository(f.db)
+	f.service.AttachAuditLog(repo)
+	return repo
+}
+
+func auditTypes(t *testing.T, f *passwordResetFixture) []string {
+	t.Helper()
+	var types []string
+	err := f.service.ExportAuditEvents(models.AuditEventFilter{}, 0, func(e models.AuditEvent) error {
+		types = append(types, e.Type)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportAuditEvents() error = %v", err)
+	}
+	return types
+}
+
+func TestAuthService_AuditTrail(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	withAuditLog(f)
+	device := models.DeviceInfo{IPAddress: "203.0.113.9", UserAgent: "test-agent", RequestID: "req-1"}
+
+	if _, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "wrong", Device: device}); err == nil {
+		t.Fatal("expected the wrong password to fail")
+	}
+	if _, err := f.service.Login(models.LoginRequest{Email: "nobody@example.com", Password: "wrong", Device: device}); err == nil {
+		t.Fatal("expected an unknown account to fail")
+	}
+	session, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "password123", Device: device})
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := f.service.RefreshSession(session.RefreshToken, device)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.RefreshSession(session.RefreshToken, device); err == nil {
+		t.Fatal("expected the rotated token to be rejected")
+	}
+	if err := f.service.Logout(refreshed.RefreshToken, device); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		models.AuditLoginFailed,
+		models.AuditLoginFailed,
+		models.AuditLoginSucceeded,
+		models.AuditTokenRefreshed,
+		models.AuditTokenReuseDetected,
+		models.AuditLogout,
+	}
+	page, err := f.service.ListAuditEvents(models.AuditEventFilter{}, "", 0)
+	if err != nil {
+		t.Fatalf("ListAuditEvents() error = %v", err)
+	}
+	if len(page.Events) != len(want) {
+		t.Fatalf("recorded %d events, want %d: %+v", len(page.Events), len(want), page.Events)
+	}
+	for i, event := range page.Events {
+		// Newest first.
+		if event.Type != want[len(want)-1-i] {
+			t.Errorf("event %d type = %q, want %q", i, event.Type, want[len(want)-1-i])
+		}
+		if event.IPAddress != device.IPAddress || event.UserAgent != device.UserAgent || event.RequestID != device.RequestID {
+			t.Errorf("event %d lost the request details: %+v", i, event)
+		}
+	}
+
+	unknown := page.Events[len(want)-2]
+	if unknown.UserID != nil || unknown.Email != "nobody@example.com" {
+		t.Errorf("failed login for an unknown account = %+v", unknown)
+	}
+	wrongPassword := page.Events[len(want)-1]
+	if wrongPassword.UserID == nil || *wrongPassword.UserID != f.user.ID || wrongPassword.Detail != "invalid credentials" {
+		t.Errorf("failed login for the user = %+v", wrongPassword)
+	}
+}
+
+func TestAuthService_AuditTrail_Lockout(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	withAuditLog(f)
+	client := redis.NewClient(&redis.Options{Addr: f.redis.Addr()})
+	t.Cleanup(func() { client.Close() })
+	f.service.AttachLoginGuard(lockout.New(client, lockout.Config{MaxAttempts: 2, BaseDelay: time.Millisecond}))
+
+	wrong := models.LoginRequest{Email: f.user.Email, Password: "wrong"}
+	for i := 0; i < 3; i++ {
+		_, _ = f.service.Login(wrong)
+	}
+
+	got := auditTypes(t, f)
+	want := []string{models.AuditLoginFailed, models.AuditLoginFailed, models.AuditLoginLockedOut, models.AuditLoginFailed}
+	if len(got) != len(want) {
+		t.Fatalf("recorded %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("recorded %v, want %v", got, want)
+		}
+	}
+}
+
+func TestAuthService_AuditTrail_AccountChanges(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	withAuditLog(f)
+
+	registered, err := f.service.CreateUser(models.CreateUserRequest{Name: "New User", Email: "new@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.UpdateProfile(f.user.ID, "", models.UpdateProfileRequest{
+		CurrentPassword: "password123",
+		Password:        strPtr("new-password-1"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.service.RequestPasswordReset(context.Background(), f.user.Email); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.service.ResetPassword(f.notifier.last(t).Token, "new-password-2", models.DeviceInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.service.DeleteAccount(f.user.ID, "new-password-2", models.DeviceInfo{}); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := f.service.ListAuditEvents(models.AuditEventFilter{UserID: f.user.ID}, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0, len(page.Events))
+	for _, e := range page.Events {
+		got = append(got, e.Type+"/"+e.Detail)
+	}
+	want := []string{"account.deleted/", "password.changed/reset", "password.changed/"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("recorded %v, want %v", got, want)
+	}
+
+	// The trail outlives the deleted account.
+	registrations, _ := f.service.ListAuditEvents(models.AuditEventFilter{Type: models.AuditUserRegistered}, "", 10)
+	if len(registrations.Events) != 1 || *registrations.Events[0].UserID != registered.ID {
+		t.Fatalf("registration events = %+v", registrations.Events)
+	}
+}
+
+func TestAuthService_ListAuditEvents_Pagination(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	repo := withAuditLog(f)
+	for i := 0; i < 5; i++ {
+		if err := repo.Create(&models.AuditEvent{Type: models.AuditLogout}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ids []uint
+	cursor := ""
+	for {
+		page, err := f.service.ListAuditEvents(models.AuditEventFilter{}, cursor, 2)
+		if err != nil {
+			t.Fatalf("ListAuditEvents() error = %v", err)
+		}
+		for _, e := range page.Events {
+			ids = append(ids, e.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if len(ids) != 5 || ids[0] != 5 || ids[4] != 1 {
+		t.Fatalf("paged through %v, want ids 5..1", ids)
+	}
+
+	if _, err := f.service.ListAuditEvents(models.AuditEventFilter{}, "not-a-cursor", 2); err == nil || err.Error() != "invalid cursor" {
+		t.Fatalf("expected an invalid cursor error, got %v", err)
+	}
+
+	// Export resumes after the given ID and stops on the first emit error.
+	var exported []uint
+	stop := errors.New("stop")
+	err := f.service.ExportAuditEvents(models.AuditEventFilter{}, 2, func(e models.AuditEvent) error {
+		exported = append(exported, e.ID)
+		if len(exported) == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || len(exported) != 2 || exported[0] != 3 {
+		t.Fatalf("ExportAuditEvents() = %v, exported %v", err, exported)
+	}
+}
+
+func TestAuthService_AuditLogDisabled(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	loginFixtureUser(t, f)
+
+	if _, err := f.service.ListAuditEvents(models.AuditEventFilter{}, "", 0); err == nil || err.Error() != "audit log not enabled" {
+		t.Fatalf("expected the audit log to be disabled, got %v", err)
+	}
+}
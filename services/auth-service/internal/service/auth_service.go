@@ -2,77 +2,168 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/icl00ud/velure/services/auth-service/internal/cache"
 	"github.com/icl00ud/velure/services/auth-service/internal/config"
+	"github.com/icl00ud/velure/services/auth-service/internal/events"
+	"github.com/icl00ud/velure/services/auth-service/internal/lockout"
 	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
 	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/notify"
+	"github.com/icl00ud/velure/services/auth-service/internal/oidc"
+	"github.com/icl00ud/velure/services/auth-service/internal/passhash"
+	"github.com/icl00ud/velure/services/auth-service/internal/passwordpolicy"
 	"github.com/icl00ud/velure/services/auth-service/internal/repository"
+	"github.com/icl00ud/velure/services/auth-service/internal/signing"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/icl00ud/velure/shared/auth"
 	"github.com/icl00ud/velure/shared/logger"
 	"github.com/redis/go-redis/v9"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type AuthService struct {
-	userRepo          repositories.UserRepositoryInterface
-	sessionRepo       repositories.SessionRepositoryInterface
-	passwordResetRepo repositories.PasswordResetRepositoryInterface
-	config            *config.Config
-	bcryptWorkerPool  chan struct{}
-	redis             *redis.Client
-	tokenCache        sync.Map // fallback cache if redis fails
+	userRepo              repositories.UserRepositoryInterface
+	sessionRepo           repositories.SessionRepositoryInterface
+	passwordResetRepo     repositories.PasswordResetRepositoryInterface
+	emailVerificationRepo repositories.EmailVerificationRepositoryInterface
+	config                *config.Config
+	hashWorkerPool        chan struct{}
+	hasher                passhash.Hasher
+	redis                 *redis.Client
+	tokenCache            *cache.LRU[*models.User]
+	sessionUses           *cache.LRU[struct{}]
+	cacheBus              *cache.Bus
+	notifier              notify.Notifier
+	events                events.Publisher
+	keys                  *signing.KeyRing
+	loginGuard            *lockout.Guard
+	identityRepo          repositories.UserIdentityRepositoryInterface
+	identityProviders     map[string]*oidc.Provider
+	revocations           *auth.RevocationList
+	auditRepo             repositories.AuditEventRepositoryInterface
+	clientRepo            repositories.OAuthClientRepositoryInterface
+	passwordPolicy        passwordpolicy.Policy
+	passwordHistoryRepo   repositories.PasswordHistoryRepositoryInterface
 }
 
 // userCacheEntry serializes/deserializes users in the Redis cache.
 // Password is included here because models.User uses json:"-" to omit it.
 type userCacheEntry struct {
-	ID        uint      `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  string    `json:"password"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	Email       string     `json:"email"`
+	Password    string     `json:"password"`
+	Roles       []string   `json:"roles"`
+	TOTPEnabled bool       `json:"totpEnabled"`
+	VerifiedAt  *time.Time `json:"verifiedAt"`
+	DisabledAt  *time.Time `json:"disabledAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
 }
 
 func NewAuthService(
 	userRepo repositories.UserRepositoryInterface,
 	sessionRepo repositories.SessionRepositoryInterface,
 	passwordResetRepo repositories.PasswordResetRepositoryInterface,
+	emailVerificationRepo repositories.EmailVerificationRepositoryInterface,
 	config *config.Config,
 	redisClient *redis.Client,
 ) *AuthService {
-	// Worker pool caps concurrent bcrypt operations (CPU-bound)
+	// Worker pool caps concurrent password hashing (CPU- and memory-bound)
 	workerPoolSize := config.Performance.BcryptWorkers
 	if workerPoolSize <= 0 {
 		workerPoolSize = 10 // default
 	}
-	bcryptWorkerPool := make(chan struct{}, workerPoolSize)
+	hashWorkerPool := make(chan struct{}, workerPoolSize)
+
+	// Revoked access tokens are kept in Redis so other services see them too.
+	var revocations *auth.RevocationList
+	if redisClient != nil {
+		revocations = auth.NewRevocationList(redisClient)
+	}
+
+	// Until AttachKeyRing provides the configured keys, tokens are signed
+	// with an ephemeral key of the configured algorithm. Config validation
+	// rejects unsupported algorithms at startup; an unvalidated config
+	// that names one leaves the ring empty, so no token can be issued.
+	keys, err := signing.Generate(config.JWT.SigningAlgorithm)
+	if err != nil {
+		logger.Error("failed to generate ephemeral signing key", logger.Err(err))
+	}
 
 	return &AuthService{
-		userRepo:          userRepo,
-		sessionRepo:       sessionRepo,
-		passwordResetRepo: passwordResetRepo,
-		config:            config,
-		bcryptWorkerPool:  bcryptWorkerPool,
-		redis:             redisClient,
+		userRepo:              userRepo,
+		sessionRepo:           sessionRepo,
+		passwordResetRepo:     passwordResetRepo,
+		emailVerificationRepo: emailVerificationRepo,
+		config:                config,
+		hashWorkerPool:        hashWorkerPool,
+		hasher:                newPasswordHasher(config),
+		redis:                 redisClient,
+		tokenCache:            newTokenCache(config),
+		sessionUses:           newSessionUseCache(config),
+		notifier:              notify.NewLogNotifier(),
+		events:                events.NewLogPublisher(),
+		keys:                  keys,
+		revocations:           revocations,
+		passwordPolicy:        newPasswordPolicy(config),
+	}
+}
+
+// AttachNotifier replaces the default log notifier used to deliver password
+// reset tokens.
+func (s *AuthService) AttachNotifier(n notify.Notifier) {
+	if n != nil {
+		s.notifier = n
+	}
+}
+
+// AttachEventPublisher replaces the default log publisher used for domain
+// events such as user.deleted.
+func (s *AuthService) AttachEventPublisher(p events.Publisher) {
+	if p != nil {
+		s.events = p
+	}
+}
+
+// AttachLoginGuard enables brute-force protection on Login. Without a guard
+// failed logins are not tracked.
+func (s *AuthService) AttachLoginGuard(g *lockout.Guard) {
+	s.loginGuard = g
+}
+
+// AttachKeyRing replaces the ephemeral key ring access tokens are signed with.
+func (s *AuthService) AttachKeyRing(k *signing.KeyRing) {
+	if k != nil {
+		s.keys = k
 	}
 }
 
+// JWKS returns the public keys access tokens can be verified with.
+func (s *AuthService) JWKS() (auth.JWKS, error) {
+	return s.keys.JWKS()
+}
+
 func (s *AuthService) CreateUser(req models.CreateUserRequest) (*models.RegistrationResponse, error) {
 	start := time.Now()
 	defer func() {
 		metrics.RegistrationDuration.Observe(time.Since(start).Seconds())
 	}()
 
+	if err := s.checkNewPassword(&models.User{Name: req.Name, Email: req.Email}, req.Password); err != nil {
+		return nil, err
+	}
+
 	// Check if user already exists
 	existingUser, err := s.userRepo.GetByEmail(req.Email)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -85,11 +176,7 @@ func (s *AuthService) CreateUser(req models.CreateUserRequest) (*models.Registra
 		return nil, errors.New("user already exists")
 	}
 
-	// Hash password through the worker pool (caps CPU pressure)
-	s.bcryptWorkerPool <- struct{}{} // acquire worker
-	hashedPassword, err := s.hashPasswordOptimized(req.Password)
-	<-s.bcryptWorkerPool // release worker
-
+	hashedPassword, err := s.hashPassword(req.Password)
 	if err != nil {
 		metrics.RegistrationAttempts.WithLabelValues("failure").Inc()
 		metrics.Errors.WithLabelValues("internal").Inc()
@@ -100,7 +187,8 @@ func (s *AuthService) CreateUser(req models.CreateUserRequest) (*models.Registra
 	user := &models.User{
 		Name:     req.Name,
 		Email:    req.Email,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
+		Roles:    models.Roles{auth.RoleCustomer},
 	}
 
 	if err := s.userRepo.Create(user); err != nil {
@@ -110,7 +198,7 @@ func (s *AuthService) CreateUser(req models.CreateUserRequest) (*models.Registra
 	}
 
 	// Create the token session (synchronous, optimized).
-	session, err := s.updateOrCreateSession(user.ID)
+	session, err := s.createSession(user, req.Device)
 	if err != nil {
 		metrics.RegistrationAttempts.WithLabelValues("failure").Inc()
 		metrics.Errors.WithLabelValues("internal").Inc()
@@ -120,6 +208,13 @@ func (s *AuthService) CreateUser(req models.CreateUserRequest) (*models.Registra
 	// Cache the freshly-registered user to speed up the first login.
 	// Note: userCacheEntry is used to include the password in cache (json:"-" omits it from json.Marshal).
 	s.cacheUser(context.Background(), user)
+	s.recordAudit(models.AuditUserRegistered, user.ID, user.Email, req.Device, "")
+
+	// Registration does not wait for the address to be confirmed; only what
+	// needs a verified email (e.g. placing orders, if enabled) does.
+	if err := s.sendEmailVerification(user); err != nil {
+		logger.Error("failed to send verification email", logger.Uint("user_id", user.ID), logger.Err(err))
+	}
 
 	// User/session count metrics are refreshed by the 30s background sync in main.
 
@@ -147,6 +242,13 @@ func (s *AuthService) Login(req models.LoginRequest) (*models.LoginResponse, err
 	ctx := context.Background()
 	cacheKey := "user:email:" + req.Email
 
+	account := lockout.NormalizeAccount(req.Email)
+	if err := s.loginGuard.Check(ctx, account, req.Device.IPAddress); err != nil {
+		status = "locked"
+		s.recordAudit(models.AuditLoginFailed, 0, account, req.Device, err.Error())
+		return nil, err
+	}
+
 	// Try Redis cache first for user lookup
 	var user *models.User
 	fromCache := false
@@ -155,20 +257,24 @@ func (s *AuthService) Login(req models.LoginRequest) (*models.LoginResponse, err
 		if err == nil {
 			var cachedEntry userCacheEntry
 			if json.Unmarshal([]byte(cachedJSON), &cachedEntry) == nil && cachedEntry.Password != "" {
-				metrics.CacheHits.Inc()
+				metrics.CacheHits.WithLabelValues("user").Inc()
 				fromCache = true
 				user = &models.User{
-					ID:        cachedEntry.ID,
-					Name:      cachedEntry.Name,
-					Email:     cachedEntry.Email,
-					Password:  cachedEntry.Password,
-					CreatedAt: cachedEntry.CreatedAt,
-					UpdatedAt: cachedEntry.UpdatedAt,
+					ID:          cachedEntry.ID,
+					Name:        cachedEntry.Name,
+					Email:       cachedEntry.Email,
+					Password:    cachedEntry.Password,
+					Roles:       cachedEntry.Roles,
+					TOTPEnabled: cachedEntry.TOTPEnabled,
+					VerifiedAt:  cachedEntry.VerifiedAt,
+					DisabledAt:  cachedEntry.DisabledAt,
+					CreatedAt:   cachedEntry.CreatedAt,
+					UpdatedAt:   cachedEntry.UpdatedAt,
 				}
 			}
 		}
 		if user == nil {
-			metrics.CacheMisses.Inc()
+			metrics.CacheMisses.WithLabelValues("user").Inc()
 		}
 	}
 
@@ -179,7 +285,7 @@ func (s *AuthService) Login(req models.LoginRequest) (*models.LoginResponse, err
 		if err != nil {
 			status = "failure"
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return nil, errors.New("invalid credentials")
+				return nil, s.loginFailed(ctx, account, 0, req.Device, errors.New("invalid credentials"))
 			}
 			metrics.Errors.WithLabelValues("database").Inc()
 			return nil, fmt.Errorf("error getting user: %w", err)
@@ -202,7 +308,7 @@ func (s *AuthService) Login(req models.LoginRequest) (*models.LoginResponse, err
 		if err != nil {
 			status = "failure"
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return nil, errors.New("invalid credentials")
+				return nil, s.loginFailed(ctx, account, 0, req.Device, errors.New("invalid credentials"))
 			}
 			metrics.Errors.WithLabelValues("database").Inc()
 			return nil, fmt.Errorf("error getting user: %w", err)
@@ -217,17 +323,42 @@ func (s *AuthService) Login(req models.LoginRequest) (*models.LoginResponse, err
 
 	if passwordErr != nil {
 		status = "failure"
-		return nil, errors.New("invalid credentials")
+		return nil, s.loginFailed(ctx, account, user.ID, req.Device, errors.New("invalid credentials"))
+	}
+	// Checked after the password so it does not tell strangers the account
+	// exists.
+	if user.Disabled() {
+		status = "disabled"
+		s.recordAudit(models.AuditLoginFailed, user.ID, user.Email, req.Device, "account disabled")
+		return nil, errors.New("account disabled")
+	}
+	s.upgradePasswordHash(ctx, user, req.Password)
+
+	// The password alone is not enough: hand out a challenge for the second
+	// step. The failure count is only cleared once that step succeeds, so a
+	// known password cannot be used to keep resetting it while guessing codes.
+	if user.TOTPEnabled {
+		challenge, err := s.issueTwoFactorChallenge(user.ID)
+		if err != nil {
+			status = "failure"
+			metrics.Errors.WithLabelValues("internal").Inc()
+			return nil, fmt.Errorf("error generating two-factor challenge: %w", err)
+		}
+		status = "two_factor_required"
+		return &models.LoginResponse{TwoFactorRequired: true, ChallengeToken: challenge}, nil
 	}
+	s.loginGuard.Succeed(ctx, account)
 
 	// Create the token session (synchronous, optimized).
-	session, err := s.updateOrCreateSession(user.ID)
+	session, err := s.createSession(user, req.Device)
 	if err != nil {
 		status = "failure"
 		metrics.Errors.WithLabelValues("internal").Inc()
 		return nil, fmt.Errorf("error creating session: %w", err)
 	}
 
+	s.recordAudit(models.AuditLoginSucceeded, user.ID, user.Email, req.Device, "")
+
 	// PERFORMANCE: count metric removed — should be gathered by a periodic job
 	// to avoid hammering the DB with COUNT queries during traffic spikes.
 
@@ -238,33 +369,57 @@ func (s *AuthService) Login(req models.LoginRequest) (*models.LoginResponse, err
 	}, nil
 }
 
+// loginFailed counts a failed attempt towards the lockout, records it in the
+// audit trail, holds the response back by the delay it earned and returns the
+// error for the caller: err, or the lockout if this attempt triggered one.
+// userID is 0 when the account does not exist.
+func (s *AuthService) loginFailed(ctx context.Context, account string, userID uint, device models.DeviceInfo, err error) error {
+	delay, locked := s.loginGuard.Fail(ctx, account, device.IPAddress)
+	s.recordAudit(models.AuditLoginFailed, userID, account, device, err.Error())
+	if locked != nil {
+		s.recordAudit(models.AuditLoginLockedOut, userID, account, device, locked.Error())
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	if locked != nil {
+		return locked
+	}
+	return err
+}
+
 func (s *AuthService) ValidateAccessToken(token string) (*models.User, error) {
 	// Cache lookup (avoids repeated parsing and DB queries)
+	cacheKey := hashToken(token)
 	if s.config.Performance.EnableCache {
-		if cachedUser, ok := s.tokenCache.Load(token); ok {
-			if user, ok := cachedUser.(*models.User); ok {
-				metrics.TokenValidations.WithLabelValues("valid_cached").Inc()
-				return user, nil
+		if user, ok := s.tokenCache.Get(cacheKey); ok {
+			// The token was verified before it was cached; only its jti
+			// is needed to see whether it has been revoked since.
+			claims := &auth.Claims{}
+			if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err == nil && s.checkRevoked(claims) != nil {
+				s.tokenCache.Delete(cacheKey)
+				metrics.TokenValidations.WithLabelValues("revoked").Inc()
+				return nil, errors.New("token revoked")
 			}
+			s.recordSessionUse(token, cacheKey)
+			metrics.TokenValidations.WithLabelValues("valid_cached").Inc()
+			return user, nil
 		}
 	}
 
-	claims := &jwt.RegisteredClaims{}
-
-	parsedToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.config.JWT.Secret), nil
-	})
-
-	if err != nil || !parsedToken.Valid {
+	claims, err := auth.Parse(token, s.keys)
+	if err != nil {
 		metrics.TokenValidations.WithLabelValues("invalid").Inc()
 		return nil, errors.New("invalid token")
 	}
+	if err := s.checkRevoked(claims); err != nil {
+		metrics.TokenValidations.WithLabelValues("revoked").Inc()
+		return nil, errors.New("token revoked")
+	}
 
+	// Machine client tokens do not belong to a user.
 	userID, err := strconv.ParseUint(claims.Subject, 10, 32)
-	if err != nil {
+	if err != nil || claims.IsClient() {
 		metrics.TokenValidations.WithLabelValues("invalid").Inc()
 		return nil, errors.New("invalid user ID in token")
 	}
@@ -274,18 +429,21 @@ func (s *AuthService) ValidateAccessToken(token string) (*models.User, error) {
 		metrics.TokenValidations.WithLabelValues("invalid").Inc()
 		return nil, errors.New("user not found")
 	}
+	if user.Disabled() {
+		metrics.TokenValidations.WithLabelValues("invalid").Inc()
+		return nil, errors.New("account disabled")
+	}
 
-	// Cache token com TTL configurável
+	// A token must not outlive its exp in the cache.
 	if s.config.Performance.EnableCache {
-		go func() {
-			s.tokenCache.Store(token, user)
-			cacheTTL := time.Duration(s.config.Performance.TokenCacheTTL) * time.Second
-			time.AfterFunc(cacheTTL, func() {
-				s.tokenCache.Delete(token)
-			})
-		}()
+		var until time.Time
+		if claims.ExpiresAt != nil {
+			until = claims.ExpiresAt.Time
+		}
+		s.tokenCache.SetUntil(cacheKey, user, until)
 	}
 
+	s.recordSessionUse(token, cacheKey)
 	metrics.TokenValidations.WithLabelValues("valid").Inc()
 	return user, nil
 }
@@ -333,18 +491,22 @@ func (s *AuthService) GetUserByID(id uint) (*models.UserResponse, error) {
 		if err == nil {
 			var cachedEntry userCacheEntry
 			if json.Unmarshal([]byte(cachedJSON), &cachedEntry) == nil {
-				metrics.CacheHits.Inc()
+				metrics.CacheHits.WithLabelValues("user").Inc()
 				response := models.UserResponse{
-					ID:        cachedEntry.ID,
-					Name:      cachedEntry.Name,
-					Email:     cachedEntry.Email,
-					CreatedAt: cachedEntry.CreatedAt,
-					UpdatedAt: cachedEntry.UpdatedAt,
+					ID:               cachedEntry.ID,
+					Name:             cachedEntry.Name,
+					Email:            cachedEntry.Email,
+					Roles:            cachedEntry.Roles,
+					TwoFactorEnabled: cachedEntry.TOTPEnabled,
+					EmailVerified:    cachedEntry.VerifiedAt != nil,
+					CreatedAt:        cachedEntry.CreatedAt,
+					UpdatedAt:        cachedEntry.UpdatedAt,
+					DisabledAt:       cachedEntry.DisabledAt,
 				}
 				return &response, nil
 			}
 		}
-		metrics.CacheMisses.Inc()
+		metrics.CacheMisses.WithLabelValues("user").Inc()
 	}
 
 	user, err := s.userRepo.GetByID(id)
@@ -359,12 +521,16 @@ func (s *AuthService) GetUserByID(id uint) (*models.UserResponse, error) {
 	// Cache in Redis
 	if s.redis != nil {
 		cacheEntry := userCacheEntry{
-			ID:        user.ID,
-			Name:      user.Name,
-			Email:     user.Email,
-			Password:  user.Password,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
+			ID:          user.ID,
+			Name:        user.Name,
+			Email:       user.Email,
+			Password:    user.Password,
+			Roles:       user.Roles,
+			TOTPEnabled: user.TOTPEnabled,
+			VerifiedAt:  user.VerifiedAt,
+			DisabledAt:  user.DisabledAt,
+			CreatedAt:   user.CreatedAt,
+			UpdatedAt:   user.UpdatedAt,
 		}
 		if userJSON, err := json.Marshal(cacheEntry); err == nil {
 			s.redis.Set(ctx, cacheKey, userJSON, time.Duration(s.config.Performance.TokenCacheTTL)*time.Second)
@@ -375,6 +541,24 @@ func (s *AuthService) GetUserByID(id uint) (*models.UserResponse, error) {
 	return &response, nil
 }
 
+// GetUsersByIDs returns the users with the given IDs, ordered by ID. IDs
+// without a user are left out.
+func (s *AuthService) GetUsersByIDs(ids []uint) ([]models.UserResponse, error) {
+	metrics.UserQueries.WithLabelValues("batch").Inc()
+
+	users, err := s.userRepo.GetByIDs(ids)
+	if err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error getting users: %w", err)
+	}
+
+	responses := make([]models.UserResponse, len(users))
+	for i, user := range users {
+		responses[i] = user.ToResponse()
+	}
+	return responses, nil
+}
+
 func (s *AuthService) GetUserByEmail(email string) (*models.UserResponse, error) {
 	metrics.UserQueries.WithLabelValues("by_email").Inc()
 
@@ -387,18 +571,22 @@ func (s *AuthService) GetUserByEmail(email string) (*models.UserResponse, error)
 		if err == nil {
 			var cachedEntry userCacheEntry
 			if json.Unmarshal([]byte(cachedJSON), &cachedEntry) == nil {
-				metrics.CacheHits.Inc()
+				metrics.CacheHits.WithLabelValues("user").Inc()
 				response := models.UserResponse{
-					ID:        cachedEntry.ID,
-					Name:      cachedEntry.Name,
-					Email:     cachedEntry.Email,
-					CreatedAt: cachedEntry.CreatedAt,
-					UpdatedAt: cachedEntry.UpdatedAt,
+					ID:               cachedEntry.ID,
+					Name:             cachedEntry.Name,
+					Email:            cachedEntry.Email,
+					Roles:            cachedEntry.Roles,
+					TwoFactorEnabled: cachedEntry.TOTPEnabled,
+					EmailVerified:    cachedEntry.VerifiedAt != nil,
+					CreatedAt:        cachedEntry.CreatedAt,
+					UpdatedAt:        cachedEntry.UpdatedAt,
+					DisabledAt:       cachedEntry.DisabledAt,
 				}
 				return &response, nil
 			}
 		}
-		metrics.CacheMisses.Inc()
+		metrics.CacheMisses.WithLabelValues("user").Inc()
 	}
 
 	user, err := s.userRepo.GetByEmail(email)
@@ -413,12 +601,16 @@ func (s *AuthService) GetUserByEmail(email string) (*models.UserResponse, error)
 	// Cache in Redis
 	if s.redis != nil {
 		cacheEntry := userCacheEntry{
-			ID:        user.ID,
-			Name:      user.Name,
-			Email:     user.Email,
-			Password:  user.Password,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
+			ID:          user.ID,
+			Name:        user.Name,
+			Email:       user.Email,
+			Password:    user.Password,
+			Roles:       user.Roles,
+			TOTPEnabled: user.TOTPEnabled,
+			VerifiedAt:  user.VerifiedAt,
+			DisabledAt:  user.DisabledAt,
+			CreatedAt:   user.CreatedAt,
+			UpdatedAt:   user.UpdatedAt,
 		}
 		if userJSON, err := json.Marshal(cacheEntry); err == nil {
 			s.redis.Set(ctx, cacheKey, userJSON, time.Duration(s.config.Performance.TokenCacheTTL)*time.Second)
@@ -429,56 +621,271 @@ func (s *AuthService) GetUserByEmail(email string) (*models.UserResponse, error)
 	return &response, nil
 }
 
-func (s *AuthService) Logout(refreshToken string) error {
+// UpdateUserRoles replaces the user's roles. The user's cached token
+// validations are dropped so role checks see the new roles at once; the
+// roles claim in access tokens already issued changes only on refresh.
+func (s *AuthService) UpdateUserRoles(id uint, roles []string) (*models.UserResponse, error) {
+	normalized := make(models.Roles, 0, len(roles))
+	seen := make(map[string]struct{}, len(roles))
+	for _, role := range roles {
+		role = strings.TrimSpace(role)
+		if !auth.IsKnownRole(role) {
+			return nil, fmt.Errorf("unknown role: %s", role)
+		}
+		if _, dup := seen[role]; dup {
+			continue
+		}
+		seen[role] = struct{}{}
+		normalized = append(normalized, role)
+	}
+	if len(normalized) == 0 {
+		return nil, errors.New("at least one role is required")
+	}
+
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	user.Roles = normalized
+	if err := s.userRepo.Update(user); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error updating roles: %w", err)
+	}
+	s.invalidateUserCache(context.Background(), user)
+	s.evictCachedTokens(user.ID)
+
+	response := user.ToResponse()
+	return &response, nil
+}
+
+// Logout ends the session of refreshToken and revokes its access token.
+func (s *AuthService) Logout(refreshToken string, device models.DeviceInfo) error {
 	metrics.LogoutRequests.Inc()
 
+	session, err := s.sessionRepo.GetByRefreshToken(refreshToken)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error getting session: %w", err)
+	}
+
 	if err := s.sessionRepo.InvalidateByRefreshToken(refreshToken); err != nil {
 		metrics.Errors.WithLabelValues("database").Inc()
 		return fmt.Errorf("error invalidating session: %w", err)
 	}
+	if session != nil {
+		s.revokeAccessTokens(*session)
+		s.recordAudit(models.AuditLogout, session.UserID, "", device, "")
+	}
 
 	s.SyncActiveSessionsMetric(context.Background())
 	return nil
 }
 
-func (s *AuthService) updateOrCreateSession(userID uint) (*models.Session, error) {
-	// Generate tokens
-	accessToken, err := s.generateAccessToken(userID)
+// RefreshSession exchanges a refresh token for a new access/refresh pair. The
+// presented token's session is retired and a successor is created in the same
+// family. Presenting a token that was already rotated means it leaked (either
+// the attacker or the legitimate client is replaying it), so the whole family
+// is revoked and both parties have to log in again. device replaces the
+// recorded client details, since the IP in particular changes over time.
+func (s *AuthService) RefreshSession(refreshToken string, device models.DeviceInfo) (*models.LoginResponse, error) {
+	var result string
+	defer func() {
+		metrics.TokenRefreshes.WithLabelValues(result).Inc()
+	}()
+
+	userID, err := s.parseRefreshToken(refreshToken)
 	if err != nil {
-		return nil, fmt.Errorf("error generating access token: %w", err)
+		result = "invalid"
+		return nil, errors.New("invalid refresh token")
 	}
 
-	refreshToken, err := s.generateRefreshToken(userID)
+	session, err := s.sessionRepo.GetByRefreshToken(refreshToken)
 	if err != nil {
-		return nil, fmt.Errorf("error generating refresh token: %w", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			result = "invalid"
+			return nil, errors.New("invalid refresh token")
+		}
+		result = "failure"
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error getting session: %w", err)
+	}
+	if session.UserID != userID {
+		result = "invalid"
+		return nil, errors.New("invalid refresh token")
 	}
 
-	// Check if session already exists
-	existingSession, err := s.sessionRepo.GetByUserID(userID)
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, fmt.Errorf("error checking existing session: %w", err)
+	// Re-read the user so the new access token carries current roles.
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			result = "invalid"
+			return nil, errors.New("invalid refresh token")
+		}
+		result = "failure"
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+	if user.Disabled() {
+		result = "invalid"
+		return nil, errors.New("invalid refresh token")
+	}
+
+	if session.RotatedAt != nil {
+		return nil, s.revokeSessionFamily(user, session, device, &result)
+	}
+	if !session.ExpiresAt.After(time.Now()) {
+		result = "invalid"
+		return nil, errors.New("invalid refresh token")
+	}
+
+	// Sessions created before rotation existed have no family yet; the first
+	// rotation starts one. It is stored before rotating, so a concurrent
+	// refresh that loses the race revokes the family the winner continues.
+	if session.FamilyID == "" {
+		familyID, err := randomToken(16)
+		if err != nil {
+			result = "failure"
+			return nil, fmt.Errorf("error generating session family: %w", err)
+		}
+		if err := s.sessionRepo.AssignFamily(session, familyID); err != nil {
+			result = "failure"
+			metrics.Errors.WithLabelValues("database").Inc()
+			return nil, fmt.Errorf("error assigning session family: %w", err)
+		}
+	}
+
+	rotated, err := s.sessionRepo.MarkRotated(session)
+	if err != nil {
+		result = "failure"
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error rotating session: %w", err)
+	}
+	if !rotated {
+		// Another request rotated this token between our read and write.
+		return nil, s.revokeSessionFamily(user, session, device, &result)
+	}
+
+	next, err := s.newSession(user, session.FamilyID, device)
+	if err != nil {
+		result = "failure"
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return nil, err
+	}
+	next.CreatedAt = session.CreatedAt
+	if err := s.sessionRepo.Create(next); err != nil {
+		result = "failure"
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error creating session: %w", err)
+	}
+
+	result = "success"
+	s.recordAudit(models.AuditTokenRefreshed, user.ID, user.Email, device, "")
+	return &models.LoginResponse{
+		AccessToken:  next.AccessToken,
+		RefreshToken: next.RefreshToken,
+	}, nil
+}
+
+// revokeSessionFamily handles refresh-token reuse: every session in the
+// family is expired, the access tokens it handed out are revoked, and the
+// caller gets a reuse error.
+func (s *AuthService) revokeSessionFamily(user *models.User, session *models.Session, device models.DeviceInfo, result *string) error {
+	// Listed before the family is expired; rotated members count too, since
+	// the attacker may hold any of their access tokens.
+	live, err := s.sessionsWithLiveTokens(user.ID)
+	if err != nil {
+		*result = "failure"
+		return err
+	}
+	family := live[:0]
+	for _, member := range live {
+		if member.FamilyID == session.FamilyID {
+			family = append(family, member)
+		}
 	}
 
-	expiresAt := time.Now().Add(time.Duration(s.config.Session.ExpiresIn) * time.Millisecond)
+	if err := s.sessionRepo.InvalidateFamily(session.FamilyID); err != nil {
+		*result = "failure"
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error revoking session family: %w", err)
+	}
+	s.revokeAccessTokens(family...)
+	s.evictCachedTokens(user.ID)
+	logger.Warn("refresh token reuse detected, session family revoked",
+		logger.Uint("user_id", session.UserID),
+		logger.String("family_id", session.FamilyID))
+	*result = "reuse_detected"
+	s.recordAudit(models.AuditTokenReuseDetected, user.ID, user.Email, device, "")
+	s.SyncActiveSessionsMetric(context.Background())
+	return errors.New("refresh token reuse detected")
+}
 
-	if existingSession != nil {
-		// Update existing session
-		existingSession.AccessToken = accessToken
-		existingSession.RefreshToken = refreshToken
-		existingSession.ExpiresAt = expiresAt
+// parseRefreshToken verifies a refresh token's signature and expiry and
+// returns the user it was issued to.
+func (s *AuthService) parseRefreshToken(refreshToken string) (uint, error) {
+	claims := &jwt.RegisteredClaims{}
+	secret := s.config.JWT.Secret + s.config.JWT.RefreshSecret
 
-		if err := s.sessionRepo.Update(existingSession); err != nil {
-			return nil, fmt.Errorf("error updating session: %w", err)
+	parsedToken, err := jwt.ParseWithClaims(refreshToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return existingSession, nil
+		return []byte(secret), nil
+	})
+	if err != nil || !parsedToken.Valid {
+		return 0, errors.New("invalid token")
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		return 0, errors.New("invalid user ID in token")
+	}
+	return uint(userID), nil
+}
+
+// newSession mints a token pair and wraps it in an unsaved session.
+func (s *AuthService) newSession(user *models.User, familyID string, device models.DeviceInfo) (*models.Session, error) {
+	accessToken, err := s.generateAccessToken(user, familyID)
+	if err != nil {
+		return nil, fmt.Errorf("error generating access token: %w", err)
 	}
 
-	// Create new session
-	session := &models.Session{
-		UserID:       userID,
+	refreshToken, err := s.generateRefreshToken(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error generating refresh token: %w", err)
+	}
+
+	now := time.Now()
+	return &models.Session{
+		UserID:       user.ID,
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
-		ExpiresAt:    expiresAt,
+		ExpiresAt:    now.Add(time.Duration(s.config.Session.ExpiresIn) * time.Millisecond),
+		FamilyID:     familyID,
+		UserAgent:    truncate(device.UserAgent, maxUserAgentLength),
+		IPAddress:    truncate(device.IPAddress, maxIPAddressLength),
+		LastUsedAt:   now,
+		IssuedAt:     now,
+	}, nil
+}
+
+// createSession opens a new session for a login. Sessions on other devices
+// are left alone.
+func (s *AuthService) createSession(user *models.User, device models.DeviceInfo) (*models.Session, error) {
+	// Every login starts a new rotation family.
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("error generating session family: %w", err)
+	}
+
+	session, err := s.newSession(user, familyID, device)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := s.sessionRepo.Create(session); err != nil {
@@ -488,21 +895,93 @@ func (s *AuthService) updateOrCreateSession(userID uint) (*models.Session, error
 	return session, nil
 }
 
-func (s *AuthService) generateAccessToken(userID uint) (string, error) {
+// ListSessions returns the user's signed-in devices. The session holding
+// currentAccessToken is flagged as current.
+func (s *AuthService) ListSessions(userID uint, currentAccessToken string) ([]models.SessionResponse, error) {
+	sessions, err := s.sessionRepo.ListActiveByUserID(userID)
+	if err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error listing sessions: %w", err)
+	}
+
+	responses := make([]models.SessionResponse, len(sessions))
+	for i := range sessions {
+		responses[i] = sessions[i].ToResponse(currentAccessToken)
+	}
+	return responses, nil
+}
+
+// RevokeSession signs one of the user's devices out. Sessions of other users
+// are reported as not found so their IDs cannot be probed.
+func (s *AuthService) RevokeSession(userID, sessionID uint) error {
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("session not found")
+		}
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error getting session: %w", err)
+	}
+	if session.UserID != userID || session.RotatedAt != nil || !session.ExpiresAt.After(time.Now()) {
+		return errors.New("session not found")
+	}
+
+	if err := s.sessionRepo.Invalidate(session.ID); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error invalidating session: %w", err)
+	}
+	s.revokeAccessTokens(*session)
+
+	s.SyncActiveSessionsMetric(context.Background())
+	return nil
+}
+
+// RevokeAllSessions signs the user out on every device, including the one
+// making the request, and revokes the impersonation tokens issued for the
+// user or by the user.
+func (s *AuthService) RevokeAllSessions(userID uint) error {
+	sessions, err := s.sessionsWithLiveTokens(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.sessionRepo.InvalidateByUserID(userID); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error invalidating sessions: %w", err)
+	}
+	s.revokeAccessTokens(sessions...)
+	s.revokeImpersonations(userID)
+
+	s.SyncActiveSessionsMetric(context.Background())
+	return nil
+}
+
+// generateAccessToken mints an access token for user. familyID is the
+// session family, carried as sid so the login can be told apart from others.
+func (s *AuthService) generateAccessToken(user *models.User, familyID string) (string, error) {
 	start := time.Now()
 	defer func() {
 		metrics.TokenGenerationDuration.Observe(time.Since(start).Seconds())
 	}()
 
+	tokenID, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
 	now := time.Now()
-	claims := jwt.RegisteredClaims{
-		Subject:   strconv.FormatUint(uint64(userID), 10),
-		ExpiresAt: jwt.NewNumericDate(now.Add(parseExpiry(s.config.JWT.ExpiresIn, time.Hour))),
-		IssuedAt:  jwt.NewNumericDate(now),
+	claims := auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			ExpiresAt: jwt.NewNumericDate(now.Add(parseExpiry(s.config.JWT.ExpiresIn, time.Hour))),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Roles:         user.Roles,
+		EmailVerified: user.EmailVerified(),
+		SessionID:     familyID,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.config.JWT.Secret))
+	tokenString, err := s.keys.Sign(claims)
 	if err == nil {
 		metrics.TokenGenerations.Inc()
 	}
@@ -510,8 +989,16 @@ func (s *AuthService) generateAccessToken(userID uint) (string, error) {
 }
 
 func (s *AuthService) generateRefreshToken(userID uint) (string, error) {
+	// A unique ID keeps tokens minted in the same second for the same user
+	// distinct, which rotation depends on.
+	tokenID, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
 	now := time.Now()
 	claims := jwt.RegisteredClaims{
+		ID:        tokenID,
 		Subject:   strconv.FormatUint(uint64(userID), 10),
 		ExpiresAt: jwt.NewNumericDate(now.Add(parseExpiry(s.config.JWT.RefreshExpiresIn, 7*24*time.Hour))),
 		IssuedAt:  jwt.NewNumericDate(now),
@@ -522,6 +1009,29 @@ func (s *AuthService) generateRefreshToken(userID uint) (string, error) {
 	return token.SignedString([]byte(secret))
 }
 
+// Column widths of the session device details.
+const (
+	maxUserAgentLength = 255
+	maxIPAddressLength = 45
+)
+
+// truncate cuts s to at most n bytes without leaving a partial UTF-8 rune.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
+
+// randomToken returns n random bytes, hex encoded.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
 // parseExpiry parses durations like "1h", "30m" and the day shorthand "7d"
 // (which time.ParseDuration does not support). Invalid or empty values fall
 // back to the given default.
@@ -542,12 +1052,62 @@ func parseExpiry(value string, fallback time.Duration) time.Duration {
 	return fallback
 }
 
-// comparePassword runs the bcrypt comparison through the worker pool to bound
+// newPasswordHasher hashes new passwords with the configured algorithm,
+// argon2id unless bcrypt is asked for (config.Validate rejects the rest).
+func newPasswordHasher(cfg *config.Config) passhash.Hasher {
+	algorithm := passhash.AlgArgon2id
+	if cfg.PasswordHash.Algorithm == passhash.AlgBcrypt {
+		algorithm = passhash.AlgBcrypt
+	}
+	hasher, _ := passhash.New(algorithm, passhash.Argon2idParams{
+		Memory:      cfg.PasswordHash.Argon2Memory,
+		Iterations:  cfg.PasswordHash.Argon2Iterations,
+		Parallelism: cfg.PasswordHash.Argon2Parallelism,
+	}, cfg.Performance.BcryptCost)
+	return hasher
+}
+
+// hashPassword hashes through the worker pool to bound concurrent hashing
+// work.
+func (s *AuthService) hashPassword(password string) (string, error) {
+	s.hashWorkerPool <- struct{}{} // acquire worker
+	defer func() { <-s.hashWorkerPool }()
+	return s.hasher.Hash(password)
+}
+
+// comparePassword runs the comparison through the worker pool to bound
 // concurrent hashing work.
 func (s *AuthService) comparePassword(hash, password string) error {
-	s.bcryptWorkerPool <- struct{}{} // acquire worker
-	defer func() { <-s.bcryptWorkerPool }()
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	s.hashWorkerPool <- struct{}{} // acquire worker
+	defer func() { <-s.hashWorkerPool }()
+	return s.hasher.Verify(hash, password)
+}
+
+// upgradePasswordHash replaces the stored hash of a user who has just proven
+// password when it was made with an outdated algorithm or cost. The update
+// only applies if the hash has not changed in the meantime. Failures are
+// logged: the old hash still works and the next login tries again.
+func (s *AuthService) upgradePasswordHash(ctx context.Context, user *models.User, password string) {
+	if !s.hasher.NeedsRehash(user.Password) {
+		return
+	}
+	hash, err := s.hashPassword(password)
+	if err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		logger.Warn("failed to rehash password", logger.Uint("user_id", user.ID), logger.Err(err))
+		return
+	}
+	updated, err := s.userRepo.UpdatePasswordHash(user.ID, user.Password, hash)
+	if err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		logger.Warn("failed to store rehashed password", logger.Uint("user_id", user.ID), logger.Err(err))
+		return
+	}
+	if updated {
+		user.Password = hash
+		metrics.PasswordRehashes.Inc()
+	}
+	s.invalidateUserCache(ctx, user)
 }
 
 // cacheUser stores the user (including its password hash, needed for login
@@ -557,12 +1117,16 @@ func (s *AuthService) cacheUser(ctx context.Context, user *models.User) {
 		return
 	}
 	cacheEntry := userCacheEntry{
-		ID:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		Password:  user.Password,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:          user.ID,
+		Name:        user.Name,
+		Email:       user.Email,
+		Password:    user.Password,
+		Roles:       user.Roles,
+		TOTPEnabled: user.TOTPEnabled,
+		VerifiedAt:  user.VerifiedAt,
+		DisabledAt:  user.DisabledAt,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
 	}
 	userJSON, err := json.Marshal(cacheEntry)
 	if err != nil {
@@ -582,17 +1146,6 @@ func (s *AuthService) invalidateUserCache(ctx context.Context, user *models.User
 	s.redis.Del(ctx, "user:email:"+user.Email, fmt.Sprintf("user:id:%d", user.ID))
 }
 
-// hashPasswordOptimized usa bcrypt com cost ajustável
-func (s *AuthService) hashPasswordOptimized(password string) ([]byte, error) {
-	// Cost 10 = ~100ms, Cost 12 = ~400ms, Cost 14 = ~1.6s
-	// Para alta concorrência, usar cost menor (10) com worker pool
-	cost := s.config.Performance.BcryptCost
-	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
-		cost = bcrypt.DefaultCost
-	}
-	return bcrypt.GenerateFromPassword([]byte(password), cost)
-}
-
 func (s *AuthService) SyncActiveSessionsMetric(ctx context.Context) {
 	if s.sessionRepo == nil {
 		return
@@ -5,21 +5,26 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
+	"github.com/icl00ud/velure/services/auth-service/internal/lockout"
 	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
 	"github.com/icl00ud/velure/services/auth-service/internal/mocks"
 	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/repository"
+	"github.com/icl00ud/velure/services/auth-service/internal/signing"
 	"github.com/icl00ud/velure/services/auth-service/internal/testutil"
 
 	miniredis "github.com/alicebob/miniredis/v2"
 	"github.com/go-redis/redismock/v9"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/icl00ud/velure/shared/auth"
 	promtest "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/mock/gomock"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
@@ -52,10 +57,6 @@ func TestAuthService_CreateUser(t *testing.T) {
 						return nil
 					})
 
-				mockSessionRepo.EXPECT().
-					GetByUserID(uint(1)).
-					Return(nil, gorm.ErrRecordNotFound)
-
 				mockSessionRepo.EXPECT().
 					Create(gomock.Any()).
 					DoAndReturn(func(s *models.Session) error {
@@ -119,7 +120,7 @@ func TestAuthService_CreateUser(t *testing.T) {
 			errMsg:  "error creating user",
 		},
 		{
-			name: "session lookup error",
+			name: "session create error",
 			req: models.CreateUserRequest{
 				Name:     "Test User",
 				Email:    "test@example.com",
@@ -135,11 +136,11 @@ func TestAuthService_CreateUser(t *testing.T) {
 					Return(nil)
 
 				mockSessionRepo.EXPECT().
-					GetByUserID(uint(0)).
-					Return(nil, errors.New("session lookup failed"))
+					Create(gomock.Any()).
+					Return(errors.New("insert failed"))
 			},
 			wantErr: true,
-			errMsg:  "error checking existing session",
+			errMsg:  "error creating session",
 		},
 	}
 
@@ -151,11 +152,15 @@ func TestAuthService_CreateUser(t *testing.T) {
 			mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 			mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 			mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+			mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 			cfg := testutil.CreateTestConfig()
 
-			service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, nil)
+			service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, nil)
 
 			tt.setupMock(mockUserRepo, mockSessionRepo)
+			// Successful registrations send a verification email.
+			mockEmailVerificationRepo.EXPECT().DeleteByUserID(gomock.Any()).Return(nil).AnyTimes()
+			mockEmailVerificationRepo.EXPECT().Create(gomock.Any()).Return(nil).AnyTimes()
 
 			result, err := service.CreateUser(tt.req)
 
@@ -197,6 +202,7 @@ func TestAuthService_CreateUser_CachesUserInRedis(t *testing.T) {
 	mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 	mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 	mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+	mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 	cfg := testutil.CreateTestConfig()
 	cfg.Performance.TokenCacheTTL = 120
 
@@ -216,14 +222,13 @@ func TestAuthService_CreateUser_CachesUserInRedis(t *testing.T) {
 			return nil
 		})
 
-	mockSessionRepo.EXPECT().
-		GetByUserID(uint(42)).
-		Return(nil, gorm.ErrRecordNotFound)
 	mockSessionRepo.EXPECT().
 		Create(gomock.Any()).
 		Return(nil)
+	mockEmailVerificationRepo.EXPECT().DeleteByUserID(uint(42)).Return(nil)
+	mockEmailVerificationRepo.EXPECT().Create(gomock.Any()).Return(nil)
 
-	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, redisClient)
+	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, redisClient)
 
 	req := models.CreateUserRequest{
 		Name:     "Cache User",
@@ -264,7 +269,7 @@ func TestAuthService_CreateUser_CachesUserInRedis(t *testing.T) {
 }
 
 func TestAuthService_Login(t *testing.T) {
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	hashedPassword := testutil.HashPassword("password123")
 
 	tests := []struct {
 		name      string
@@ -289,10 +294,6 @@ func TestAuthService_Login(t *testing.T) {
 					GetByEmail("user@example.com").
 					Return(user, nil)
 
-				mockSessionRepo.EXPECT().
-					GetByUserID(uint(1)).
-					Return(nil, gorm.ErrRecordNotFound)
-
 				mockSessionRepo.EXPECT().
 					Create(gomock.Any()).
 					DoAndReturn(func(s *models.Session) error {
@@ -336,7 +337,7 @@ func TestAuthService_Login(t *testing.T) {
 			errMsg:  "invalid credentials",
 		},
 		{
-			name: "update existing session",
+			name: "second device gets its own session",
 			req: models.LoginRequest{
 				Email:    "user@example.com",
 				Password: "password123",
@@ -351,16 +352,9 @@ func TestAuthService_Login(t *testing.T) {
 					GetByEmail("user@example.com").
 					Return(user, nil)
 
-				existingSession := &models.Session{
-					ID:     1,
-					UserID: 1,
-				}
-				mockSessionRepo.EXPECT().
-					GetByUserID(uint(1)).
-					Return(existingSession, nil)
-
+				// Existing sessions are neither looked up nor updated.
 				mockSessionRepo.EXPECT().
-					Update(gomock.Any()).
+					Create(gomock.Any()).
 					Return(nil)
 			},
 			wantErr: false,
@@ -389,11 +383,12 @@ func TestAuthService_Login(t *testing.T) {
 			mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 			mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 			mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+			mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 			cfg := testutil.CreateTestConfig()
 
-			service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, nil)
+			service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, nil)
 
-			tt.setupMock(mockUserRepo, mockSessionRepo, string(hashedPassword))
+			tt.setupMock(mockUserRepo, mockSessionRepo, hashedPassword)
 
 			result, err := service.Login(tt.req)
 
@@ -426,38 +421,30 @@ func TestAuthService_Login_SessionErrors(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	hashedPassword := testutil.HashPassword("password123")
 
 	mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 	mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 	mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+	mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 	cfg := testutil.CreateTestConfig()
 
-	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, nil)
+	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, nil)
 
-	user := &models.User{ID: 1, Email: "user@example.com", Password: string(hashedPassword)}
+	user := &models.User{ID: 1, Email: "user@example.com", Password: hashedPassword}
 	mockUserRepo.EXPECT().GetByEmail("user@example.com").Return(user, nil)
-	mockSessionRepo.EXPECT().GetByUserID(uint(1)).Return(nil, gorm.ErrRecordNotFound)
 	mockSessionRepo.EXPECT().Create(gomock.Any()).Return(errors.New("create error"))
 
 	if _, err := service.Login(models.LoginRequest{Email: "user@example.com", Password: "password123"}); err == nil {
 		t.Fatalf("expected error when session creation fails")
 	}
-
-	mockUserRepo.EXPECT().GetByEmail("user@example.com").Return(user, nil)
-	existing := &models.Session{ID: 2, UserID: 1}
-	mockSessionRepo.EXPECT().GetByUserID(uint(1)).Return(existing, nil)
-	mockSessionRepo.EXPECT().Update(existing).Return(errors.New("update error"))
-
-	if _, err := service.Login(models.LoginRequest{Email: "user@example.com", Password: "password123"}); err == nil {
-		t.Fatalf("expected error when session update fails")
-	}
 }
 
 func TestAuthService_ValidateAccessToken(t *testing.T) {
 	cfg := testutil.CreateTestConfig()
-	validToken := generateTestToken(t, cfg.JWT.Secret, 1, time.Now().Add(1*time.Hour))
-	expiredToken := generateTestToken(t, cfg.JWT.Secret, 1, time.Now().Add(-1*time.Hour))
+	keys := signing.MustGenerate(signing.AlgEdDSA)
+	validToken := generateTestToken(t, keys, 1, time.Now().Add(1*time.Hour))
+	expiredToken := generateTestToken(t, keys, 1, time.Now().Add(-1*time.Hour))
 
 	tests := []struct {
 		name      string
@@ -527,10 +514,17 @@ func TestAuthService_ValidateAccessToken(t *testing.T) {
 			mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 			mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 			mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+			mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 
-			service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, nil)
+			service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, nil)
+			service.AttachKeyRing(keys)
 
 			tt.setupMock(mockUserRepo)
+			if !tt.wantErr {
+				mockSessionRepo.EXPECT().
+					Touch(tt.token, gomock.Any(), sessionUseInterval).
+					Return(nil)
+			}
 
 			result, err := service.ValidateAccessToken(tt.token)
 
@@ -604,9 +598,10 @@ func TestAuthService_GetUsers(t *testing.T) {
 			mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 			mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 			mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+			mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 			cfg := testutil.CreateTestConfig()
 
-			service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, nil)
+			service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, nil)
 
 			tt.setupMock(mockUserRepo)
 
@@ -683,9 +678,10 @@ func TestAuthService_GetUserByID(t *testing.T) {
 			mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 			mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 			mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+			mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 			cfg := testutil.CreateTestConfig()
 
-			service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, nil)
+			service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, nil)
 
 			tt.setupMock(mockUserRepo)
 
@@ -766,9 +762,10 @@ func TestAuthService_GetUserByEmail(t *testing.T) {
 			mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 			mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 			mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+			mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 			cfg := testutil.CreateTestConfig()
 
-			service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, nil)
+			service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, nil)
 
 			tt.setupMock(mockUserRepo)
 
@@ -805,6 +802,9 @@ func TestAuthService_Logout(t *testing.T) {
 			name:         "successful logout",
 			refreshToken: "valid-refresh-token",
 			setupMock: func(mockSessionRepo *mocks.MockSessionRepositoryInterface) {
+				mockSessionRepo.EXPECT().
+					GetByRefreshToken("valid-refresh-token").
+					Return(testutil.CreateTestSession(1), nil)
 				mockSessionRepo.EXPECT().
 					InvalidateByRefreshToken("valid-refresh-token").
 					Return(nil)
@@ -820,12 +820,25 @@ func TestAuthService_Logout(t *testing.T) {
 			name:         "database error",
 			refreshToken: "token",
 			setupMock: func(mockSessionRepo *mocks.MockSessionRepositoryInterface) {
+				mockSessionRepo.EXPECT().
+					GetByRefreshToken("token").
+					Return(nil, gorm.ErrRecordNotFound)
 				mockSessionRepo.EXPECT().
 					InvalidateByRefreshToken("token").
 					Return(errors.New("database error"))
 			},
 			wantErr: true,
 		},
+		{
+			name:         "session lookup error",
+			refreshToken: "token",
+			setupMock: func(mockSessionRepo *mocks.MockSessionRepositoryInterface) {
+				mockSessionRepo.EXPECT().
+					GetByRefreshToken("token").
+					Return(nil, errors.New("database error"))
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -836,13 +849,14 @@ func TestAuthService_Logout(t *testing.T) {
 			mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 			mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 			mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+			mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 			cfg := testutil.CreateTestConfig()
 
-			service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, nil)
+			service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, nil)
 
 			tt.setupMock(mockSessionRepo)
 
-			err := service.Logout(tt.refreshToken)
+			err := service.Logout(tt.refreshToken, models.DeviceInfo{})
 
 			if tt.wantErr {
 				if err == nil {
@@ -905,9 +919,10 @@ func TestAuthService_GetUsersByPage(t *testing.T) {
 			mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 			mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 			mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+			mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 			cfg := testutil.CreateTestConfig()
 
-			service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, nil)
+			service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, nil)
 
 			tt.setupMock(mockUserRepo)
 
@@ -936,20 +951,17 @@ func TestAuthService_GetUsersByPage(t *testing.T) {
 	}
 }
 
-func TestAuthService_UpdateOrCreateSessionAsync(t *testing.T) {
+func TestAuthService_createSession(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 	mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 	mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+	mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 	cfg := testutil.CreateTestConfig()
 
-	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, nil)
-
-	mockSessionRepo.EXPECT().
-		GetByUserID(uint(1)).
-		Return(nil, gorm.ErrRecordNotFound)
+	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, nil)
 
 	mockSessionRepo.EXPECT().
 		Create(gomock.Any()).
@@ -958,54 +970,39 @@ func TestAuthService_UpdateOrCreateSessionAsync(t *testing.T) {
 			return nil
 		})
 
-	session, err := service.updateOrCreateSession(1)
+	device := models.DeviceInfo{UserAgent: strings.Repeat("é", 200), IPAddress: "198.51.100.4"}
+	session, err := service.createSession(&models.User{ID: 1}, device)
 	if err != nil {
-		t.Fatalf("updateOrCreateSession() error = %v", err)
+		t.Fatalf("createSession() error = %v", err)
 	}
 	if session.ID != 99 {
 		t.Fatalf("expected session ID to be set, got %d", session.ID)
 	}
+	if session.IPAddress != "198.51.100.4" || session.LastUsedAt.IsZero() || session.IssuedAt.IsZero() {
+		t.Fatalf("device details not recorded: %+v", session)
+	}
+	if len(session.UserAgent) > 255 || !utf8.ValidString(session.UserAgent) {
+		t.Fatalf("user agent not truncated cleanly: %d bytes", len(session.UserAgent))
+	}
 }
 
-func TestAuthService_updateOrCreateSession_Errors(t *testing.T) {
+func TestAuthService_createSession_Errors(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 	mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 	mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+	mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 	cfg := testutil.CreateTestConfig()
 
-	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, nil)
-
-	mockSessionRepo.EXPECT().
-		GetByUserID(uint(2)).
-		Return(nil, errors.New("db error"))
-
-	if _, err := service.updateOrCreateSession(2); err == nil {
-		t.Fatalf("expected error from get session")
-	}
-
-	existing := &models.Session{ID: 1, UserID: 3}
-	mockSessionRepo.EXPECT().
-		GetByUserID(uint(3)).
-		Return(existing, nil)
-	mockSessionRepo.EXPECT().
-		Update(existing).
-		Return(errors.New("update failed"))
-
-	if _, err := service.updateOrCreateSession(3); err == nil {
-		t.Fatalf("expected error from update session")
-	}
+	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, nil)
 
-	mockSessionRepo.EXPECT().
-		GetByUserID(uint(4)).
-		Return(nil, gorm.ErrRecordNotFound)
 	mockSessionRepo.EXPECT().
 		Create(gomock.Any()).
 		Return(errors.New("create failed"))
 
-	if _, err := service.updateOrCreateSession(4); err == nil {
+	if _, err := service.createSession(&models.User{ID: 4}, models.DeviceInfo{}); err == nil {
 		t.Fatalf("expected error from create session")
 	}
 }
@@ -1017,6 +1014,7 @@ func TestAuthService_SyncTotalUsersMetric(t *testing.T) {
 	mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 	mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 	mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+	mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 	cfg := testutil.CreateTestConfig()
 
 	metrics.TotalUsers.Set(0)
@@ -1025,7 +1023,7 @@ func TestAuthService_SyncTotalUsersMetric(t *testing.T) {
 		CountUsers(gomock.Any()).
 		Return(int64(7), nil)
 
-	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, nil)
+	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, nil)
 	service.SyncTotalUsersMetric(context.Background())
 
 	if got := promtest.ToFloat64(metrics.TotalUsers); got != 7 {
@@ -1040,6 +1038,7 @@ func TestAuthService_SyncTotalUsersMetric_Error(t *testing.T) {
 	mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 	mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 	mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+	mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 	cfg := testutil.CreateTestConfig()
 
 	metrics.TotalUsers.Set(3)
@@ -1048,7 +1047,7 @@ func TestAuthService_SyncTotalUsersMetric_Error(t *testing.T) {
 		CountUsers(gomock.Any()).
 		Return(int64(0), errors.New("db error"))
 
-	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, nil)
+	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, nil)
 	service.SyncTotalUsersMetric(context.Background())
 
 	if got := promtest.ToFloat64(metrics.TotalUsers); got != 3 {
@@ -1063,17 +1062,22 @@ func TestAuthService_ValidateAccessToken_UsesCache(t *testing.T) {
 	mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 	mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 	mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+	mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 	cfg := testutil.CreateTestConfig()
 	cfg.Performance.EnableCache = true
 	cfg.Performance.TokenCacheTTL = 1
 
-	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, nil)
-	token := generateTestToken(t, cfg.JWT.Secret, 1, time.Now().Add(time.Hour))
+	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, nil)
+	token := generateTestToken(t, service.keys, 1, time.Now().Add(time.Hour))
 
 	user := &models.User{ID: 1, Email: "cached@example.com"}
 	mockUserRepo.EXPECT().
 		GetByID(uint(1)).
 		Return(user, nil)
+	// The second use falls within sessionUseInterval and is not written.
+	mockSessionRepo.EXPECT().
+		Touch(token, gomock.Any(), sessionUseInterval).
+		Return(nil)
 
 	firstUser, err := service.ValidateAccessToken(token)
 	if err != nil || firstUser.ID != 1 {
@@ -1091,6 +1095,38 @@ func TestAuthService_ValidateAccessToken_UsesCache(t *testing.T) {
 	}
 }
 
+func TestAuthService_ValidateAccessToken_CacheStopsAtExpiry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
+	mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
+	cfg := testutil.CreateTestConfig()
+	cfg.Performance.EnableCache = true
+	cfg.Performance.TokenCacheTTL = 60
+
+	service := NewAuthService(mockUserRepo, mockSessionRepo, mocks.NewMockPasswordResetRepositoryInterface(ctrl), mocks.NewMockEmailVerificationRepositoryInterface(ctrl), cfg, nil)
+	token := generateTestToken(t, service.keys, 1, time.Now().Add(1500*time.Millisecond))
+
+	mockUserRepo.EXPECT().
+		GetByID(uint(1)).
+		Return(&models.User{ID: 1, Email: "cached@example.com"}, nil)
+	mockSessionRepo.EXPECT().
+		Touch(token, gomock.Any(), sessionUseInterval).
+		Return(nil)
+
+	if _, err := service.ValidateAccessToken(token); err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+
+	// exp has whole-second precision, so wait out the rest of that second.
+	time.Sleep(2 * time.Second)
+
+	if _, err := service.ValidateAccessToken(token); err == nil || err.Error() != "invalid token" {
+		t.Fatalf("expected the expired token to be rejected despite the cache, got %v", err)
+	}
+}
+
 func TestAuthService_GetUserByID_FromRedisCache(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -1098,6 +1134,7 @@ func TestAuthService_GetUserByID_FromRedisCache(t *testing.T) {
 	mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 	mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 	mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+	mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 	cfg := testutil.CreateTestConfig()
 
 	user := &models.User{ID: 1, Email: "cached@example.com", Name: "Cached"}
@@ -1111,7 +1148,7 @@ func TestAuthService_GetUserByID_FromRedisCache(t *testing.T) {
 	redisClient, redisMock := redismock.NewClientMock()
 	redisMock.ExpectGet("user:id:1").SetVal(string(data))
 
-	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, redisClient)
+	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, redisClient)
 
 	result, err := service.GetUserByID(1)
 	if err != nil {
@@ -1133,6 +1170,7 @@ func TestAuthService_GetUserByEmail_FromRedisCache(t *testing.T) {
 	mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 	mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 	mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+	mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 	cfg := testutil.CreateTestConfig()
 
 	user := &models.User{ID: 2, Email: "cache@test.com", Name: "Cache User"}
@@ -1146,7 +1184,7 @@ func TestAuthService_GetUserByEmail_FromRedisCache(t *testing.T) {
 	redisClient, redisMock := redismock.NewClientMock()
 	redisMock.ExpectGet("user:email:cache@test.com").SetVal(string(data))
 
-	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, redisClient)
+	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, redisClient)
 
 	result, err := service.GetUserByEmail("cache@test.com")
 	if err != nil {
@@ -1168,6 +1206,7 @@ func TestAuthService_GetUserByID_CacheMissWithRedis(t *testing.T) {
 	mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 	mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 	mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+	mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 	cfg := testutil.CreateTestConfig()
 
 	redisClient, redisMock := redismock.NewClientMock()
@@ -1177,7 +1216,7 @@ func TestAuthService_GetUserByID_CacheMissWithRedis(t *testing.T) {
 		GetByID(uint(3)).
 		Return(&models.User{ID: 3, Email: "miss@example.com"}, nil)
 
-	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, redisClient)
+	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, redisClient)
 
 	_, err := service.GetUserByID(3)
 	if err != nil {
@@ -1196,6 +1235,7 @@ func TestAuthService_SyncActiveSessionsMetric(t *testing.T) {
 	mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 	mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 	mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+	mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 	cfg := testutil.CreateTestConfig()
 
 	metrics.ActiveSessions.Set(0)
@@ -1204,7 +1244,7 @@ func TestAuthService_SyncActiveSessionsMetric(t *testing.T) {
 		CountActiveSessions(gomock.Any()).
 		Return(int64(4), nil)
 
-	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, nil)
+	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, nil)
 	service.SyncActiveSessionsMetric(nil)
 
 	if got := promtest.ToFloat64(metrics.ActiveSessions); got != 4 {
@@ -1219,6 +1259,7 @@ func TestAuthService_SyncActiveSessionsMetric_Error(t *testing.T) {
 	mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 	mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 	mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+	mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 	cfg := testutil.CreateTestConfig()
 
 	metrics.ActiveSessions.Set(2)
@@ -1227,7 +1268,7 @@ func TestAuthService_SyncActiveSessionsMetric_Error(t *testing.T) {
 		CountActiveSessions(gomock.Any()).
 		Return(int64(0), errors.New("count error"))
 
-	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, nil)
+	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, nil)
 	service.SyncActiveSessionsMetric(nil)
 
 	if got := promtest.ToFloat64(metrics.ActiveSessions); got != 2 {
@@ -1251,15 +1292,14 @@ func containsHelper(s, substr string) bool {
 	return false
 }
 
-func generateTestToken(t *testing.T, secret string, userID uint, expiresAt time.Time) string {
+func generateTestToken(t *testing.T, keys *signing.KeyRing, userID uint, expiresAt time.Time) string {
 	claims := jwt.RegisteredClaims{
 		Subject:   "1",
 		ExpiresAt: jwt.NewNumericDate(expiresAt),
 		IssuedAt:  jwt.NewNumericDate(time.Now()),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(secret))
+	tokenString, err := keys.Sign(claims)
 	if err != nil {
 		t.Fatalf("Failed to generate test token: %v", err)
 	}
@@ -1273,6 +1313,7 @@ func TestLogin_StaleCachedPasswordFallsBackToDatabase(t *testing.T) {
 	mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 	mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 	mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+	mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 	cfg := testutil.CreateTestConfig()
 	cfg.Performance.TokenCacheTTL = 120
 
@@ -1280,30 +1321,27 @@ func TestLogin_StaleCachedPasswordFallsBackToDatabase(t *testing.T) {
 	redisClient := redis.NewClient(&redis.Options{Addr: mockRedis.Addr()})
 	defer redisClient.Close()
 
-	oldHash, _ := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
-	newHash, _ := bcrypt.GenerateFromPassword([]byte("new-password"), bcrypt.MinCost)
+	oldHash := testutil.HashPassword("old-password")
+	newHash := testutil.HashPassword("new-password")
 
 	// Seed cache with the stale (old) password hash.
 	staleEntry, _ := json.Marshal(map[string]interface{}{
 		"id":       42,
 		"email":    "stale@test.com",
-		"password": string(oldHash),
+		"password": oldHash,
 	})
 	mockRedis.Set("user:email:stale@test.com", string(staleEntry))
 
 	// DB has the new hash: a stale cache must not lock the user out.
 	mockUserRepo.EXPECT().
 		GetByEmail("stale@test.com").
-		Return(&models.User{ID: 42, Email: "stale@test.com", Password: string(newHash)}, nil)
+		Return(&models.User{ID: 42, Email: "stale@test.com", Password: newHash}, nil)
 
-	mockSessionRepo.EXPECT().
-		GetByUserID(uint(42)).
-		Return(nil, gorm.ErrRecordNotFound)
 	mockSessionRepo.EXPECT().
 		Create(gomock.Any()).
 		Return(nil)
 
-	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, redisClient)
+	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, redisClient)
 
 	resp, err := service.Login(models.LoginRequest{Email: "stale@test.com", Password: "new-password"})
 	if err != nil {
@@ -1321,26 +1359,27 @@ func TestLogin_WrongPasswordStillFailsWithCacheFallback(t *testing.T) {
 	mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 	mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 	mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+	mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 	cfg := testutil.CreateTestConfig()
 
 	mockRedis := miniredis.RunT(t)
 	redisClient := redis.NewClient(&redis.Options{Addr: mockRedis.Addr()})
 	defer redisClient.Close()
 
-	hash, _ := bcrypt.GenerateFromPassword([]byte("right-password"), bcrypt.MinCost)
+	hash := testutil.HashPassword("right-password")
 	entry, _ := json.Marshal(map[string]interface{}{
 		"id":       42,
 		"email":    "wrong@test.com",
-		"password": string(hash),
+		"password": hash,
 	})
 	mockRedis.Set("user:email:wrong@test.com", string(entry))
 
 	// Fallback hits the DB once; it returns the same hash, so login still fails.
 	mockUserRepo.EXPECT().
 		GetByEmail("wrong@test.com").
-		Return(&models.User{ID: 42, Email: "wrong@test.com", Password: string(hash)}, nil)
+		Return(&models.User{ID: 42, Email: "wrong@test.com", Password: hash}, nil)
 
-	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, redisClient)
+	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, redisClient)
 
 	_, err := service.Login(models.LoginRequest{Email: "wrong@test.com", Password: "bad-password"})
 	if err == nil || err.Error() != "invalid credentials" {
@@ -1355,34 +1394,30 @@ func TestLogin_TokenExpiryComesFromConfig(t *testing.T) {
 	mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
 	mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
 	mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+	mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
 	cfg := testutil.CreateTestConfig()
 	cfg.JWT.ExpiresIn = "2h"
 	cfg.JWT.RefreshExpiresIn = "2d"
 
-	hash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	hash := testutil.HashPassword("password123")
 	mockUserRepo.EXPECT().
 		GetByEmail("expiry@test.com").
-		Return(&models.User{ID: 7, Email: "expiry@test.com", Password: string(hash)}, nil)
-	mockSessionRepo.EXPECT().
-		GetByUserID(uint(7)).
-		Return(nil, gorm.ErrRecordNotFound)
+		Return(&models.User{ID: 7, Email: "expiry@test.com", Password: hash}, nil)
 	mockSessionRepo.EXPECT().
 		Create(gomock.Any()).
 		Return(nil)
 
-	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, cfg, nil)
+	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, nil)
 
 	resp, err := service.Login(models.LoginRequest{Email: "expiry@test.com", Password: "password123"})
 	if err != nil {
 		t.Fatalf("login failed: %v", err)
 	}
 
-	assertTokenLifetime := func(tokenStr, secret string, want time.Duration) {
+	assertTokenLifetime := func(tokenStr string, keyFunc jwt.Keyfunc, want time.Duration) {
 		t.Helper()
 		claims := &jwt.RegisteredClaims{}
-		if _, err := jwt.ParseWithClaims(tokenStr, claims, func(*jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		}); err != nil {
+		if _, err := jwt.ParseWithClaims(tokenStr, claims, keyFunc); err != nil {
 			t.Fatalf("parse token: %v", err)
 		}
 		got := claims.ExpiresAt.Sub(claims.IssuedAt.Time)
@@ -1391,6 +1426,458 @@ func TestLogin_TokenExpiryComesFromConfig(t *testing.T) {
 		}
 	}
 
-	assertTokenLifetime(resp.AccessToken, cfg.JWT.Secret, 2*time.Hour)
-	assertTokenLifetime(resp.RefreshToken, cfg.JWT.Secret+cfg.JWT.RefreshSecret, 48*time.Hour)
+	assertTokenLifetime(resp.AccessToken, func(*jwt.Token) (interface{}, error) {
+		return service.keys.Key(service.keys.ActiveKeyID())
+	}, 2*time.Hour)
+	assertTokenLifetime(resp.RefreshToken, func(*jwt.Token) (interface{}, error) {
+		return []byte(cfg.JWT.Secret + cfg.JWT.RefreshSecret), nil
+	}, 48*time.Hour)
+}
+
+func newRefreshTestService(t *testing.T) (*AuthService, *models.User) {
+	t.Helper()
+
+	db := testutil.SetupTestDB(t)
+	userRepo := repositories.NewUserRepository(db)
+	sessionRepo := repositories.NewSessionRepository(db)
+	cfg := testutil.CreateTestConfig()
+
+	user := testutil.CreateTestUser()
+	user.ID = 0
+	if err := userRepo.Create(user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	return NewAuthService(userRepo, sessionRepo, repositories.NewPasswordResetRepository(db), repositories.NewEmailVerificationRepository(db), cfg, nil), user
+}
+
+func TestAuthService_RefreshSession_RotatesTokens(t *testing.T) {
+	service, user := newRefreshTestService(t)
+
+	login, err := service.Login(models.LoginRequest{Email: user.Email, Password: "password123"})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	refreshed, err := service.RefreshSession(login.RefreshToken, models.DeviceInfo{})
+	if err != nil {
+		t.Fatalf("RefreshSession() error = %v", err)
+	}
+	if refreshed.RefreshToken == login.RefreshToken || refreshed.AccessToken == login.AccessToken {
+		t.Fatal("expected both tokens to be rotated")
+	}
+
+	// The successor token can itself be rotated.
+	if _, err := service.RefreshSession(refreshed.RefreshToken, models.DeviceInfo{}); err != nil {
+		t.Fatalf("second RefreshSession() error = %v", err)
+	}
+}
+
+func TestAuthService_RefreshSession_ReuseRevokesFamily(t *testing.T) {
+	service, user := newRefreshTestService(t)
+
+	login, err := service.Login(models.LoginRequest{Email: user.Email, Password: "password123"})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	refreshed, err := service.RefreshSession(login.RefreshToken, models.DeviceInfo{})
+	if err != nil {
+		t.Fatalf("RefreshSession() error = %v", err)
+	}
+
+	before := promtest.ToFloat64(metrics.TokenRefreshes.WithLabelValues("reuse_detected"))
+
+	_, err = service.RefreshSession(login.RefreshToken, models.DeviceInfo{})
+	if err == nil || err.Error() != "refresh token reuse detected" {
+		t.Fatalf("expected reuse detection, got %v", err)
+	}
+	if got := promtest.ToFloat64(metrics.TokenRefreshes.WithLabelValues("reuse_detected")); got != before+1 {
+		t.Fatalf("expected reuse_detected metric to increase, got %v -> %v", before, got)
+	}
+
+	// The legitimate-looking successor must be dead too.
+	_, err = service.RefreshSession(refreshed.RefreshToken, models.DeviceInfo{})
+	if err == nil || err.Error() != "invalid refresh token" {
+		t.Fatalf("expected successor token to be revoked, got %v", err)
+	}
+}
+
+// staleSessionRepository hands out a session as it was before a concurrent
+// refresh changed it.
+type staleSessionRepository struct {
+	repositories.SessionRepositoryInterface
+	stale models.Session
+}
+
+func (r *staleSessionRepository) GetByRefreshToken(string) (*models.Session, error) {
+	session := r.stale
+	return &session, nil
+}
+
+func TestAuthService_RefreshSession_LegacySessionRace(t *testing.T) {
+	service, user := newRefreshTestService(t)
+
+	login, err := service.Login(models.LoginRequest{Email: user.Email, Password: "password123"})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	// A session from before rotation existed has no family.
+	legacy, err := service.sessionRepo.GetByRefreshToken(login.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy.FamilyID = ""
+	if err := service.sessionRepo.Update(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	winner, err := service.RefreshSession(login.RefreshToken, models.DeviceInfo{})
+	if err != nil {
+		t.Fatalf("RefreshSession() error = %v", err)
+	}
+
+	// The losing refresh read the session before the winner wrote to it.
+	repo := service.sessionRepo
+	service.sessionRepo = &staleSessionRepository{SessionRepositoryInterface: repo, stale: *legacy}
+	if _, err := service.RefreshSession(login.RefreshToken, models.DeviceInfo{}); err == nil || err.Error() != "refresh token reuse detected" {
+		t.Fatalf("expected reuse detection, got %v", err)
+	}
+	service.sessionRepo = repo
+
+	// The loser revoked the family the winner continued, not one of its own.
+	if _, err := service.RefreshSession(winner.RefreshToken, models.DeviceInfo{}); err == nil || err.Error() != "invalid refresh token" {
+		t.Fatalf("expected the winner's successor to be revoked, got %v", err)
+	}
+}
+
+func TestAuthService_RefreshSession_RejectsLoggedOutToken(t *testing.T) {
+	service, user := newRefreshTestService(t)
+
+	login, err := service.Login(models.LoginRequest{Email: user.Email, Password: "password123"})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if err := service.Logout(login.RefreshToken, models.DeviceInfo{}); err != nil {
+		t.Fatalf("logout failed: %v", err)
+	}
+
+	_, err = service.RefreshSession(login.RefreshToken, models.DeviceInfo{})
+	if err == nil || err.Error() != "invalid refresh token" {
+		t.Fatalf("expected invalid refresh token, got %v", err)
+	}
+}
+
+func TestAuthService_SessionsPerDevice(t *testing.T) {
+	service, user := newRefreshTestService(t)
+
+	desktop, err := service.Login(models.LoginRequest{
+		Email: user.Email, Password: "password123",
+		Device: models.DeviceInfo{UserAgent: "desktop", IPAddress: "198.51.100.1"},
+	})
+	if err != nil {
+		t.Fatalf("desktop login failed: %v", err)
+	}
+	phone, err := service.Login(models.LoginRequest{
+		Email: user.Email, Password: "password123",
+		Device: models.DeviceInfo{UserAgent: "phone", IPAddress: "198.51.100.2"},
+	})
+	if err != nil {
+		t.Fatalf("phone login failed: %v", err)
+	}
+
+	// Logging in on the phone must not sign the desktop out.
+	desktop, err = service.RefreshSession(desktop.RefreshToken, models.DeviceInfo{UserAgent: "desktop", IPAddress: "198.51.100.9"})
+	if err != nil {
+		t.Fatalf("desktop refresh failed: %v", err)
+	}
+
+	sessions, err := service.ListSessions(user.ID, phone.AccessToken)
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %+v", sessions)
+	}
+	byAgent := map[string]models.SessionResponse{}
+	for _, s := range sessions {
+		byAgent[s.UserAgent] = s
+	}
+	if !byAgent["phone"].Current || byAgent["desktop"].Current {
+		t.Fatalf("expected only the phone session to be current: %+v", sessions)
+	}
+	if byAgent["desktop"].IPAddress != "198.51.100.9" {
+		t.Fatalf("refresh should record the new IP, got %q", byAgent["desktop"].IPAddress)
+	}
+
+	// Other users cannot revoke the phone session.
+	if err := service.RevokeSession(user.ID+1, byAgent["phone"].ID); err == nil || err.Error() != "session not found" {
+		t.Fatalf("expected session not found, got %v", err)
+	}
+	if err := service.RevokeSession(user.ID, byAgent["phone"].ID); err != nil {
+		t.Fatalf("RevokeSession() error = %v", err)
+	}
+	if _, err := service.RefreshSession(phone.RefreshToken, models.DeviceInfo{}); err == nil {
+		t.Fatal("revoked session must not refresh")
+	}
+	if err := service.RevokeSession(user.ID, byAgent["phone"].ID); err == nil || err.Error() != "session not found" {
+		t.Fatalf("expected revoked session to be gone, got %v", err)
+	}
+
+	if err := service.RevokeAllSessions(user.ID); err != nil {
+		t.Fatalf("RevokeAllSessions() error = %v", err)
+	}
+	if _, err := service.RefreshSession(desktop.RefreshToken, models.DeviceInfo{}); err == nil {
+		t.Fatal("log out everywhere must revoke the desktop session")
+	}
+	if sessions, _ := service.ListSessions(user.ID, ""); len(sessions) != 0 {
+		t.Fatalf("expected no sessions left, got %+v", sessions)
+	}
+}
+
+func TestAuthService_RefreshSession_KeepsLoginTime(t *testing.T) {
+	service, user := newRefreshTestService(t)
+
+	login, err := service.Login(models.LoginRequest{Email: user.Email, Password: "password123"})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	before, _ := service.ListSessions(user.ID, "")
+
+	time.Sleep(10 * time.Millisecond)
+	if _, err := service.RefreshSession(login.RefreshToken, models.DeviceInfo{}); err != nil {
+		t.Fatalf("RefreshSession() error = %v", err)
+	}
+	after, _ := service.ListSessions(user.ID, "")
+
+	if len(before) != 1 || len(after) != 1 {
+		t.Fatalf("expected one session before and after, got %d and %d", len(before), len(after))
+	}
+	if !after[0].CreatedAt.Equal(before[0].CreatedAt) {
+		t.Fatalf("createdAt changed on rotation: %v -> %v", before[0].CreatedAt, after[0].CreatedAt)
+	}
+	if !after[0].LastUsedAt.After(before[0].LastUsedAt) {
+		t.Fatalf("lastUsedAt not advanced: %v -> %v", before[0].LastUsedAt, after[0].LastUsedAt)
+	}
+}
+
+func TestAuthService_ValidateAccessToken_RecordsSessionUse(t *testing.T) {
+	service, user := newRefreshTestService(t)
+
+	login, err := service.Login(models.LoginRequest{Email: user.Email, Password: "password123"})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	session, err := service.sessionRepo.GetByRefreshToken(login.RefreshToken)
+	if err != nil {
+		t.Fatalf("GetByRefreshToken() error = %v", err)
+	}
+	idle := time.Now().Add(-time.Hour)
+	session.LastUsedAt = idle
+	if err := service.sessionRepo.Update(session); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	if _, err := service.ValidateAccessToken(login.AccessToken); err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+
+	sessions, _ := service.ListSessions(user.ID, "")
+	if len(sessions) != 1 || !sessions[0].LastUsedAt.After(idle.Add(59*time.Minute)) {
+		t.Fatalf("expected lastUsedAt to record the use, got %+v", sessions)
+	}
+}
+
+func TestAuthService_Login_LocksOutAfterRepeatedFailures(t *testing.T) {
+	service, user := newRefreshTestService(t)
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	service.AttachLoginGuard(lockout.New(client, lockout.Config{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+
+	wrong := models.LoginRequest{Email: user.Email, Password: "wrong-password", Device: models.DeviceInfo{IPAddress: "198.51.100.1"}}
+	for i := 0; i < 2; i++ {
+		if _, err := service.Login(wrong); err == nil || err.Error() != "invalid credentials" {
+			t.Fatalf("attempt %d: expected invalid credentials, got %v", i+1, err)
+		}
+	}
+
+	var locked *lockout.LockedError
+	if _, err := service.Login(wrong); !errors.As(err, &locked) {
+		t.Fatalf("expected the third failure to lock the account, got %v", err)
+	}
+
+	// Even the right password is refused while locked, in any spelling of the email.
+	right := models.LoginRequest{Email: strings.ToUpper(user.Email), Password: "password123"}
+	if _, err := service.Login(right); !errors.As(err, &locked) {
+		t.Fatalf("expected locked account, got %v", err)
+	}
+
+	mr.FastForward(lockout.DefaultDuration + time.Second)
+	right.Email = user.Email
+	if _, err := service.Login(right); err != nil {
+		t.Fatalf("login after lockout expired: %v", err)
+	}
+}
+
+func TestAuthService_RefreshSession_InvalidToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := mocks.NewMockUserRepositoryInterface(ctrl)
+	mockSessionRepo := mocks.NewMockSessionRepositoryInterface(ctrl)
+	mockPasswordResetRepo := mocks.NewMockPasswordResetRepositoryInterface(ctrl)
+	mockEmailVerificationRepo := mocks.NewMockEmailVerificationRepositoryInterface(ctrl)
+	cfg := testutil.CreateTestConfig()
+
+	service := NewAuthService(mockUserRepo, mockSessionRepo, mockPasswordResetRepo, mockEmailVerificationRepo, cfg, nil)
+
+	// Access tokens are signed with a different key and must not be accepted
+	// as refresh tokens.
+	accessToken, err := service.generateAccessToken(&models.User{ID: 1}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range []string{"not-a-jwt", accessToken} {
+		_, err := service.RefreshSession(token, models.DeviceInfo{})
+		if err == nil || err.Error() != "invalid refresh token" {
+			t.Fatalf("expected invalid refresh token for %q, got %v", token, err)
+		}
+	}
+}
+
+func TestAuthService_AccessTokenCarriesRoles(t *testing.T) {
+	service, user := newRefreshTestService(t)
+
+	if _, err := service.UpdateUserRoles(user.ID, []string{"catalog-manager", "catalog-manager"}); err != nil {
+		t.Fatalf("UpdateUserRoles() error = %v", err)
+	}
+
+	login, err := service.Login(models.LoginRequest{Email: user.Email, Password: "password123"})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	claims, err := auth.Parse(login.AccessToken, service.keys)
+	if err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+	if len(claims.Roles) != 1 || claims.Roles[0] != auth.RoleCatalogManager {
+		t.Fatalf("expected [catalog-manager] in token, got %v", claims.Roles)
+	}
+
+	// Refresh picks up role changes made after login.
+	if _, err := service.UpdateUserRoles(user.ID, []string{auth.RoleAdmin}); err != nil {
+		t.Fatalf("UpdateUserRoles() error = %v", err)
+	}
+	refreshed, err := service.RefreshSession(login.RefreshToken, models.DeviceInfo{})
+	if err != nil {
+		t.Fatalf("RefreshSession() error = %v", err)
+	}
+	claims, err = auth.Parse(refreshed.AccessToken, service.keys)
+	if err != nil {
+		t.Fatalf("parse refreshed token: %v", err)
+	}
+	if len(claims.Roles) != 1 || claims.Roles[0] != auth.RoleAdmin {
+		t.Fatalf("expected [admin] in refreshed token, got %v", claims.Roles)
+	}
+}
+
+func TestAuthService_UpdateUserRoles_EvictsCachedTokens(t *testing.T) {
+	service, user := newRefreshTestService(t)
+	service.config.Performance.EnableCache = true
+	service.config.Performance.TokenCacheTTL = 60
+	service.tokenCache = newTokenCache(service.config)
+
+	login, err := service.Login(models.LoginRequest{Email: user.Email, Password: "password123"})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if _, err := service.ValidateAccessToken(login.AccessToken); err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+
+	if _, err := service.UpdateUserRoles(user.ID, []string{auth.RoleAdmin}); err != nil {
+		t.Fatalf("UpdateUserRoles() error = %v", err)
+	}
+
+	validated, err := service.ValidateAccessToken(login.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() after role change error = %v", err)
+	}
+	if len(validated.Roles) != 1 || validated.Roles[0] != auth.RoleAdmin {
+		t.Fatalf("expected [admin] after role change, got %v", validated.Roles)
+	}
+}
+
+func TestAuthService_UpdateUserRoles_Validation(t *testing.T) {
+	service, user := newRefreshTestService(t)
+
+	if _, err := service.UpdateUserRoles(user.ID, []string{"superuser"}); err == nil || err.Error() != "unknown role: superuser" {
+		t.Fatalf("expected unknown role error, got %v", err)
+	}
+	if _, err := service.UpdateUserRoles(user.ID, nil); err == nil {
+		t.Fatal("expected error for empty roles")
+	}
+	if _, err := service.UpdateUserRoles(999, []string{auth.RoleAdmin}); err == nil || err.Error() != "user not found" {
+		t.Fatalf("expected user not found, got %v", err)
+	}
+}
+
+func TestNewAuthService_UnsupportedSigningAlgorithm(t *testing.T) {
+	service, user := newRefreshTestService(t)
+	service.config.JWT.SigningAlgorithm = "HS256"
+	service = NewAuthService(service.userRepo, service.sessionRepo, service.passwordResetRepo, service.emailVerificationRepo, service.config, nil)
+
+	// No key, so no tokens, but no panic either.
+	if _, err := service.Login(models.LoginRequest{Email: user.Email, Password: "password123"}); err == nil {
+		t.Fatal("expected login to fail without a signing key")
+	}
+}
+
+func TestAuthService_ValidateAccessToken_RejectsSharedSecretTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := testutil.CreateTestConfig()
+	service := NewAuthService(
+		mocks.NewMockUserRepositoryInterface(ctrl),
+		mocks.NewMockSessionRepositoryInterface(ctrl),
+		mocks.NewMockPasswordResetRepositoryInterface(ctrl),
+		mocks.NewMockEmailVerificationRepositoryInterface(ctrl),
+		cfg, nil,
+	)
+
+	// Anyone holding JWT_SECRET could mint this; it must not be accepted.
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   "1",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	token.Header["kid"] = service.keys.ActiveKeyID()
+	forged, err := token.SignedString([]byte(cfg.JWT.Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.ValidateAccessToken(forged); err == nil || err.Error() != "invalid token" {
+		t.Fatalf("expected invalid token, got %v", err)
+	}
+}
+
+func TestAuthService_JWKSPublishesSigningKey(t *testing.T) {
+	service, user := newRefreshTestService(t)
+
+	login, err := service.Login(models.LoginRequest{Email: user.Email, Password: "password123"})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	doc, err := service.JWKS()
+	if err != nil {
+		t.Fatalf("JWKS() error = %v", err)
+	}
+	if _, err := auth.Parse(login.AccessToken, doc); err != nil {
+		t.Fatalf("access token should verify against the published JWKS: %v", err)
+	}
 }
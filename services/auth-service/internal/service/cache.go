@@ -1,303 +0,0 @@
-package services
-
-import (
-	"context"
-	"encoding/json"
-	"sync"
-	"time"
-
-	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
-	"github.com/icl00ud/velure/services/auth-service/internal/model"
-)
-
-// CacheEntry representa uma entrada no cache
-type CacheEntry struct {
-	Data      interface{}
-	ExpiresAt time.Time
-}
-
-// DistributedCache é um cache em memória thread-safe com TTL
-type DistributedCache struct {
-	data    sync.Map
-	cleanup *time.Ticker
-	mu      sync.RWMutex
-	stop    chan struct{}
-}
-
-// NewDistributedCache cria um novo cache distribuído
-func NewDistributedCache(cleanupInterval time.Duration) *DistributedCache {
-	dc := &DistributedCache{
-		cleanup: time.NewTicker(cleanupInterval),
-		stop:    make(chan struct{}),
-	}
-
-	// Goroutine that evicts expired entries
-	go dc.cleanupExpired()
-
-	return dc
-}
-
-func (dc *DistributedCache) cleanupExpired() {
-	for {
-		select {
-		case <-dc.cleanup.C:
-			now := time.Now()
-			dc.data.Range(func(key, value interface{}) bool {
-				if entry, ok := value.(*CacheEntry); ok {
-					if now.After(entry.ExpiresAt) {
-						dc.data.Delete(key)
-					}
-				}
-				return true
-			})
-		case <-dc.stop:
-			return
-		}
-	}
-}
-
-// Set stores a value in the cache with a TTL
-func (dc *DistributedCache) Set(key string, value interface{}, ttl time.Duration) {
-	entry := &CacheEntry{
-		Data:      value,
-		ExpiresAt: time.Now().Add(ttl),
-	}
-	dc.data.Store(key, entry)
-}
-
-// Get retrieves a value from the cache
-func (dc *DistributedCache) Get(key string) (interface{}, bool) {
-	value, ok := dc.data.Load(key)
-	if !ok {
-		metrics.CacheMisses.Inc()
-		return nil, false
-	}
-
-	entry, ok := value.(*CacheEntry)
-	if !ok || time.Now().After(entry.ExpiresAt) {
-		dc.data.Delete(key)
-		metrics.CacheMisses.Inc()
-		return nil, false
-	}
-
-	metrics.CacheHits.Inc()
-	return entry.Data, true
-}
-
-// Delete removes a value from the cache
-func (dc *DistributedCache) Delete(key string) {
-	dc.data.Delete(key)
-}
-
-// GetOrCompute fetches from cache or computes the value in parallel
-func (dc *DistributedCache) GetOrCompute(
-	ctx context.Context,
-	key string,
-	ttl time.Duration,
-	compute func() (interface{}, error),
-) (interface{}, error) {
-	// Try the cache first
-	if value, ok := dc.Get(key); ok {
-		return value, nil
-	}
-
-	// Channel for the compute result
-	type result struct {
-		data interface{}
-		err  error
-	}
-	resultChan := make(chan result, 1)
-
-	// Compute the value in a goroutine
-	go func() {
-		data, err := compute()
-		resultChan <- result{data, err}
-	}()
-
-	// Wait for the result with a timeout
-	select {
-	case res := <-resultChan:
-		if res.err == nil {
-			dc.Set(key, res.data, ttl)
-		}
-		return res.data, res.err
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	}
-}
-
-// BatchGet retrieves multiple values from the cache in parallel
-func (dc *DistributedCache) BatchGet(keys []string) map[string]interface{} {
-	results := make(map[string]interface{}, len(keys))
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-
-	for _, key := range keys {
-		wg.Add(1)
-		go func(k string) {
-			defer wg.Done()
-			if value, ok := dc.Get(k); ok {
-				mu.Lock()
-				results[k] = value
-				mu.Unlock()
-			}
-		}(key)
-	}
-
-	wg.Wait()
-	return results
-}
-
-// BatchSet stores multiple values in the cache in parallel
-func (dc *DistributedCache) BatchSet(items map[string]interface{}, ttl time.Duration) {
-	var wg sync.WaitGroup
-
-	for key, value := range items {
-		wg.Add(1)
-		go func(k string, v interface{}) {
-			defer wg.Done()
-			dc.Set(k, v, ttl)
-		}(key, value)
-	}
-
-	wg.Wait()
-}
-
-// Stop halts the cleanup goroutine
-func (dc *DistributedCache) Stop() {
-	dc.cleanup.Stop()
-	select {
-	case <-dc.stop:
-	default:
-		close(dc.stop)
-	}
-}
-
-// UserCache is a cache specialized for users
-type UserCache struct {
-	cache *DistributedCache
-	ttl   time.Duration
-}
-
-func NewUserCache(ttl time.Duration) *UserCache {
-	return &UserCache{
-		cache: NewDistributedCache(1 * time.Minute),
-		ttl:   ttl,
-	}
-}
-
-func (uc *UserCache) GetUser(userID uint) (*models.User, bool) {
-	key := userCacheKey(userID)
-	value, ok := uc.cache.Get(key)
-	if !ok {
-		return nil, false
-	}
-
-	user, ok := value.(*models.User)
-	return user, ok
-}
-
-func (uc *UserCache) SetUser(user *models.User) {
-	key := userCacheKey(user.ID)
-	uc.cache.Set(key, user, uc.ttl)
-}
-
-func (uc *UserCache) GetUserByEmail(email string) (*models.User, bool) {
-	key := userEmailCacheKey(email)
-	value, ok := uc.cache.Get(key)
-	if !ok {
-		return nil, false
-	}
-
-	user, ok := value.(*models.User)
-	return user, ok
-}
-
-func (uc *UserCache) SetUserByEmail(user *models.User) {
-	key := userEmailCacheKey(user.Email)
-	uc.cache.Set(key, user, uc.ttl)
-}
-
-func (uc *UserCache) InvalidateUser(userID uint) {
-	key := userCacheKey(userID)
-	uc.cache.Delete(key)
-}
-
-func (uc *UserCache) InvalidateUserByEmail(email string) {
-	key := userEmailCacheKey(email)
-	uc.cache.Delete(key)
-}
-
-func userCacheKey(userID uint) string {
-	return "user:" + string(rune(userID))
-}
-
-func userEmailCacheKey(email string) string {
-	return "user:email:" + email
-}
-
-// SessionCache is a cache specialized for sessions
-type SessionCache struct {
-	cache *DistributedCache
-	ttl   time.Duration
-}
-
-func NewSessionCache(ttl time.Duration) *SessionCache {
-	return &SessionCache{
-		cache: NewDistributedCache(1 * time.Minute),
-		ttl:   ttl,
-	}
-}
-
-func (sc *SessionCache) GetSession(token string) (*models.Session, bool) {
-	key := sessionCacheKey(token)
-	value, ok := sc.cache.Get(key)
-	if !ok {
-		return nil, false
-	}
-
-	session, ok := value.(*models.Session)
-	return session, ok
-}
-
-func (sc *SessionCache) SetSession(session *models.Session) {
-	key := sessionCacheKey(session.AccessToken)
-	sc.cache.Set(key, session, sc.ttl)
-}
-
-func (sc *SessionCache) InvalidateSession(token string) {
-	key := sessionCacheKey(token)
-	sc.cache.Delete(key)
-}
-
-func sessionCacheKey(token string) string {
-	return "session:" + token
-}
-
-// SerializeToJSON marshals any struct to JSON in a goroutine
-func SerializeToJSON(v interface{}) ([]byte, error) {
-	type result struct {
-		data []byte
-		err  error
-	}
-	resultChan := make(chan result, 1)
-
-	go func() {
-		data, err := json.Marshal(v)
-		resultChan <- result{data, err}
-	}()
-
-	res := <-resultChan
-	return res.data, res.err
-}
-
-// DeserializeFromJSON unmarshals JSON into a struct in a goroutine
-func DeserializeFromJSON(data []byte, v interface{}) error {
-	errChan := make(chan error, 1)
-
-	go func() {
-		errChan <- json.Unmarshal(data, v)
-	}()
-
-	return <-errChan
-}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/config"
+	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/icl00ud/velure/shared/auth"
+	"gorm.io/gorm"
+)
+
+// clientIDPattern keeps client IDs readable and apart from user IDs, which
+// share the token subject.
+var clientIDPattern = regexp.MustCompile(`^[a-z][a-z0-9-]{2,63}$`)
+
+// AttachClients enables machine clients and the client_credentials grant.
+// Without it every client is unknown.
+func (s *AuthService) AttachClients(repo repositories.OAuthClientRepositoryInterface) {
+	s.clientRepo = repo
+}
+
+// RegisterClient creates a machine client and returns its secret, which is
+// shown only this once.
+func (s *AuthService) RegisterClient(adminID uint, req models.CreateOAuthClientRequest) (*models.RegisteredOAuthClientResponse, error) {
+	if s.clientRepo == nil {
+		return nil, errors.New("clients not enabled")
+	}
+	if err := validateClient(req.ClientID, req.Scopes); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.clientRepo.GetByClientID(req.ClientID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error checking existing client: %w", err)
+	}
+	if existing != nil {
+		return nil, errors.New("client already exists")
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return nil, fmt.Errorf("error generating client secret: %w", err)
+	}
+	client := &models.OAuthClient{
+		ClientID:   req.ClientID,
+		Name:       strings.TrimSpace(req.Name),
+		SecretHash: hashToken(secret),
+		Scopes:     models.Scopes(req.Scopes),
+	}
+	if err := s.clientRepo.Create(client); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error creating client: %w", err)
+	}
+	s.recordAudit(models.AuditClientRegistered, adminID, "", req.Device, client.ClientID)
+
+	return &models.RegisteredOAuthClientResponse{
+		OAuthClientResponse: client.ToResponse(),
+		ClientSecret:        secret,
+	}, nil
+}
+
+// EnsureClient creates or updates a client provisioned from configuration,
+// so its secret and scopes always match the deployment's.
+func (s *AuthService) EnsureClient(cfg config.ServiceClientConfig) error {
+	if s.clientRepo == nil {
+		return errors.New("clients not enabled")
+	}
+	if err := validateClient(cfg.ID, cfg.Scopes); err != nil {
+		return fmt.Errorf("client %q: %w", cfg.ID, err)
+	}
+
+	client, err := s.clientRepo.GetByClientID(cfg.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.clientRepo.Create(&models.OAuthClient{
+			ClientID:   cfg.ID,
+			Name:       cfg.ID,
+			SecretHash: hashToken(cfg.Secret),
+			Scopes:     models.Scopes(cfg.Scopes),
+		})
+	}
+	if err != nil {
+		return err
+	}
+	client.SecretHash = hashToken(cfg.Secret)
+	client.Scopes = models.Scopes(cfg.Scopes)
+	return s.clientRepo.Update(client)
+}
+
+// ListClients returns every registered machine client.
+func (s *AuthService) ListClients() ([]models.OAuthClientResponse, error) {
+	if s.clientRepo == nil {
+		return nil, errors.New("clients not enabled")
+	}
+	clients, err := s.clientRepo.List()
+	if err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error listing clients: %w", err)
+	}
+	responses := make([]models.OAuthClientResponse, len(clients))
+	for i := range clients {
+		responses[i] = clients[i].ToResponse()
+	}
+	return responses, nil
+}
+
+// DeleteClient removes a machine client. Tokens it already holds stay valid
+// until they expire, at most ClientCredentials.TokenTTL.
+func (s *AuthService) DeleteClient(adminID uint, clientID string, device models.DeviceInfo) error {
+	if s.clientRepo == nil {
+		return errors.New("clients not enabled")
+	}
+	if err := s.clientRepo.DeleteByClientID(clientID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("client not found")
+		}
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error deleting client: %w", err)
+	}
+	s.recordAudit(models.AuditClientDeleted, adminID, "", device, clientID)
+	return nil
+}
+
+// IssueClientToken implements the client_credentials grant: it checks the
+// client's secret and signs a short-lived access token carrying the
+// requested scopes, space separated, or all of the client's when scope is
+// empty.
+func (s *AuthService) IssueClientToken(clientID, clientSecret, scope string) (*models.ClientTokenResponse, error) {
+	var client *models.OAuthClient
+	if s.clientRepo != nil && clientID != "" {
+		found, err := s.clientRepo.GetByClientID(clientID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			metrics.Errors.WithLabelValues("database").Inc()
+			return nil, fmt.Errorf("error getting client: %w", err)
+		}
+		client = found
+	}
+	// Unknown clients are compared too, so timing does not tell them apart.
+	expected := strings.Repeat("0", 64)
+	if client != nil {
+		expected = client.SecretHash
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(clientSecret)), []byte(expected)) != 1 || client == nil {
+		metrics.ClientTokens.WithLabelValues("invalid_client").Inc()
+		return nil, errors.New("invalid client")
+	}
+
+	scopes := []string(client.Scopes)
+	if requested := strings.Fields(scope); len(requested) > 0 {
+		for _, sc := range requested {
+			if !slices.Contains(scopes, sc) {
+				metrics.ClientTokens.WithLabelValues("invalid_scope").Inc()
+				return nil, errors.New("invalid scope")
+			}
+		}
+		scopes = requested
+	}
+
+	tokenID, err := randomToken(16)
+	if err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return nil, err
+	}
+	ttl := s.config.ClientCredentials.TokenTTL
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	now := time.Now()
+	granted := strings.Join(scopes, " ")
+	token, err := s.keys.Sign(auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Subject:   client.ClientID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		ClientID: client.ClientID,
+		Scope:    granted,
+	})
+	if err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return nil, fmt.Errorf("error signing token: %w", err)
+	}
+
+	metrics.ClientTokens.WithLabelValues("issued").Inc()
+	return &models.ClientTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(ttl.Seconds()),
+		Scope:       granted,
+	}, nil
+}
+
+func validateClient(clientID string, scopes []string) error {
+	if !clientIDPattern.MatchString(clientID) {
+		return errors.New("invalid client ID")
+	}
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !auth.IsKnownScope(scope) {
+			return fmt.Errorf("unknown scope: %s", scope)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/config"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/repository"
+
+	"github.com/icl00ud/velure/shared/auth"
+)
+
+func TestAuthService_ClientCredentials(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.AttachClients(repositories.NewOAuthClientRepository(f.db))
+
+	registered, err := f.service.RegisterClient(1, models.CreateOAuthClientRequest{
+		ClientID: "process-order-service",
+		Name:     "Order processing",
+		Scopes:   []string{auth.ScopeInventoryWrite},
+	})
+	if err != nil {
+		t.Fatalf("RegisterClient() error = %v", err)
+	}
+	if registered.ClientSecret == "" {
+		t.Fatal("expected the secret to be returned on registration")
+	}
+
+	token, err := f.service.IssueClientToken("process-order-service", registered.ClientSecret, "")
+	if err != nil {
+		t.Fatalf("IssueClientToken() error = %v", err)
+	}
+	if token.TokenType != "Bearer" || token.ExpiresIn != 300 || token.Scope != auth.ScopeInventoryWrite {
+		t.Fatalf("unexpected token response %+v", token)
+	}
+	claims, err := auth.Parse(token.AccessToken, f.service.keys)
+	if err != nil {
+		t.Fatalf("the token must verify against the JWKS: %v", err)
+	}
+	if claims.Subject != "process-order-service" || !claims.IsClient() || !claims.HasScope(auth.ScopeInventoryWrite) || len(claims.EffectiveRoles()) != 0 {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+	if _, err := f.service.ValidateAccessToken(token.AccessToken); err == nil {
+		t.Fatal("client tokens must not authenticate as a user")
+	}
+
+	for name, tc := range map[string]struct{ id, secret, scope, want string }{
+		"wrong secret":      {"process-order-service", "wrong", "", "invalid client"},
+		"unknown client":    {"nobody", registered.ClientSecret, "", "invalid client"},
+		"scope not granted": {"process-order-service", registered.ClientSecret, "orders:write", "invalid scope"},
+	} {
+		if _, err := f.service.IssueClientToken(tc.id, tc.secret, tc.scope); err == nil || err.Error() != tc.want {
+			t.Errorf("%s: got %v, want %q", name, err, tc.want)
+		}
+	}
+
+	if err := f.service.DeleteClient(1, "process-order-service", models.DeviceInfo{}); err != nil {
+		t.Fatalf("DeleteClient() error = %v", err)
+	}
+	if _, err := f.service.IssueClientToken("process-order-service", registered.ClientSecret, ""); err == nil {
+		t.Fatal("a deleted client must not get tokens")
+	}
+	if err := f.service.DeleteClient(1, "process-order-service", models.DeviceInfo{}); err == nil || err.Error() != "client not found" {
+		t.Fatalf("deleting twice = %v", err)
+	}
+}
+
+func TestAuthService_RegisterClient_Validation(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	req := models.CreateOAuthClientRequest{ClientID: "orders", Name: "Orders", Scopes: []string{auth.ScopeInventoryWrite}}
+	if _, err := f.service.RegisterClient(1, req); err == nil || err.Error() != "clients not enabled" {
+		t.Fatalf("without a repository: %v", err)
+	}
+	f.service.AttachClients(repositories.NewOAuthClientRepository(f.db))
+
+	for name, tc := range map[string]struct {
+		req  models.CreateOAuthClientRequest
+		want string
+	}{
+		"numeric ID":    {models.CreateOAuthClientRequest{ClientID: "42", Name: "x", Scopes: req.Scopes}, "invalid client ID"},
+		"unknown scope": {models.CreateOAuthClientRequest{ClientID: "orders", Name: "x", Scopes: []string{"orders:delete"}}, "unknown scope: orders:delete"},
+	} {
+		if _, err := f.service.RegisterClient(1, tc.req); err == nil || err.Error() != tc.want {
+			t.Errorf("%s: got %v, want %q", name, err, tc.want)
+		}
+	}
+
+	if _, err := f.service.RegisterClient(1, req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.RegisterClient(1, req); err == nil || err.Error() != "client already exists" {
+		t.Fatalf("registering twice = %v", err)
+	}
+}
+
+func TestAuthService_EnsureClient(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.AttachClients(repositories.NewOAuthClientRepository(f.db))
+
+	cfg := config.ServiceClientConfig{ID: "process-order-service", Secret: "first", Scopes: []string{auth.ScopeInventoryWrite}}
+	if err := f.service.EnsureClient(cfg); err != nil {
+		t.Fatalf("EnsureClient() error = %v", err)
+	}
+	if _, err := f.service.IssueClientToken(cfg.ID, "first", ""); err != nil {
+		t.Fatalf("IssueClientToken() error = %v", err)
+	}
+
+	// A redeploy with a rotated secret replaces the old one.
+	cfg.Secret = "second"
+	if err := f.service.EnsureClient(cfg); err != nil {
+		t.Fatalf("EnsureClient() error = %v", err)
+	}
+	if _, err := f.service.IssueClientToken(cfg.ID, "first", ""); err == nil {
+		t.Fatal("the old secret must stop working")
+	}
+	if _, err := f.service.IssueClientToken(cfg.ID, "second", ""); err != nil {
+		t.Fatalf("IssueClientToken() error = %v", err)
+	}
+}
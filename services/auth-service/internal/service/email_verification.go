@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/notify"
+
+	"github.com/icl00ud/velure/shared/logger"
+	"gorm.io/gorm"
+)
+
+// ThrottledError is returned when a verification email was sent too recently
+// to send another one.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return "verification email recently sent"
+}
+
+// RequestEmailVerification sends the user a new verification link. Only the
+// newest link is valid, and a new one is sent at most once per
+// EmailVerification.ResendInterval.
+func (s *AuthService) RequestEmailVerification(userID uint) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("user not found")
+		}
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error getting user: %w", err)
+	}
+	if user.EmailVerified() {
+		return errors.New("email already verified")
+	}
+
+	latest, err := s.emailVerificationRepo.GetLatestByUserID(user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error getting verification: %w", err)
+	}
+	if latest != nil {
+		if wait := time.Until(latest.CreatedAt.Add(s.config.EmailVerification.ResendInterval)); wait > 0 {
+			metrics.EmailVerifications.WithLabelValues("throttled").Inc()
+			return &ThrottledError{RetryAfter: wait}
+		}
+	}
+
+	return s.sendEmailVerification(user)
+}
+
+// VerifyEmail consumes a verification token and marks the user's email as
+// verified. Access tokens carry the new state from the next login or refresh.
+func (s *AuthService) VerifyEmail(token string) error {
+	verification, err := s.emailVerificationRepo.GetByToken(hashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			metrics.EmailVerifications.WithLabelValues("invalid_token").Inc()
+			return errors.New("invalid or expired verification token")
+		}
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error getting verification: %w", err)
+	}
+
+	if !verification.ExpiresAt.After(time.Now()) {
+		if err := s.emailVerificationRepo.Delete(verification.ID); err != nil {
+			logger.Warn("failed to delete expired verification token", logger.Err(err))
+		}
+		metrics.EmailVerifications.WithLabelValues("invalid_token").Inc()
+		return errors.New("invalid or expired verification token")
+	}
+
+	user, err := s.userRepo.GetByID(verification.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			metrics.EmailVerifications.WithLabelValues("invalid_token").Inc()
+			return errors.New("invalid or expired verification token")
+		}
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error getting user: %w", err)
+	}
+
+	if err := s.emailVerificationRepo.DeleteByUserID(user.ID); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error consuming verification token: %w", err)
+	}
+
+	now := time.Now()
+	user.VerifiedAt = &now
+	if err := s.userRepo.Update(user); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error updating user: %w", err)
+	}
+	s.invalidateUserCache(context.Background(), user)
+	s.evictCachedTokens(user.ID)
+
+	metrics.EmailVerifications.WithLabelValues("verified").Inc()
+	return nil
+}
+
+// sendEmailVerification replaces the user's pending verification with a new
+// one and hands the token to the notifier.
+func (s *AuthService) sendEmailVerification(user *models.User) error {
+	token, err := randomToken(32)
+	if err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return fmt.Errorf("error generating verification token: %w", err)
+	}
+
+	if err := s.emailVerificationRepo.DeleteByUserID(user.ID); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error deleting previous verification tokens: %w", err)
+	}
+
+	ttl := s.config.EmailVerification.ExpiresIn
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	verification := &models.EmailVerification{
+		UserID:    user.ID,
+		Token:     hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.emailVerificationRepo.Create(verification); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error creating verification token: %w", err)
+	}
+
+	if err := s.notifier.Notify(context.Background(), notify.Message{
+		Kind:      notify.KindEmailVerification,
+		To:        user.Email,
+		Token:     token,
+		ExpiresAt: verification.ExpiresAt,
+	}); err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return fmt.Errorf("error sending verification token: %w", err)
+	}
+
+	metrics.EmailVerifications.WithLabelValues("requested").Inc()
+	return nil
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/notify"
+
+	"github.com/icl00ud/velure/shared/auth"
+)
+
+func emailVerifiedClaim(t *testing.T, s *AuthService, token string) bool {
+	t.Helper()
+	claims, err := auth.Parse(token, s.keys)
+	if err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+	return claims.EmailVerified
+}
+
+func TestAuthService_EmailVerification_FullFlow(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	registration, err := f.service.CreateUser(models.CreateUserRequest{Name: "New User", Email: "new@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if registration.EmailVerified || emailVerifiedClaim(t, f.service, registration.AccessToken) {
+		t.Fatal("a new account must start unverified")
+	}
+
+	msg := f.notifier.last(t)
+	if msg.Kind != notify.KindEmailVerification || msg.To != "new@example.com" || msg.Token == "" {
+		t.Fatalf("unexpected notification: %+v", msg)
+	}
+
+	if err := f.service.VerifyEmail("not-a-token"); err == nil || err.Error() != "invalid or expired verification token" {
+		t.Fatalf("expected invalid token, got %v", err)
+	}
+	if err := f.service.VerifyEmail(msg.Token); err != nil {
+		t.Fatalf("VerifyEmail() error = %v", err)
+	}
+
+	user, err := f.service.GetUserByID(registration.ID)
+	if err != nil || !user.EmailVerified {
+		t.Fatalf("expected a verified user, got %+v, %v", user, err)
+	}
+
+	// The next token pair carries the new state.
+	refreshed, err := f.service.RefreshSession(registration.RefreshToken, models.DeviceInfo{})
+	if err != nil {
+		t.Fatalf("RefreshSession() error = %v", err)
+	}
+	if !emailVerifiedClaim(t, f.service, refreshed.AccessToken) {
+		t.Fatal("expected the refreshed access token to carry email_verified")
+	}
+
+	// Tokens are single-use.
+	if err := f.service.VerifyEmail(msg.Token); err == nil {
+		t.Fatal("expected a used token to be rejected")
+	}
+	if err := f.service.RequestEmailVerification(registration.ID); err == nil || err.Error() != "email already verified" {
+		t.Fatalf("expected already verified, got %v", err)
+	}
+}
+
+func TestAuthService_VerifyEmail_Expired(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.config.EmailVerification.ExpiresIn = time.Millisecond
+
+	if err := f.service.RequestEmailVerification(f.user.ID); err != nil {
+		t.Fatalf("RequestEmailVerification() error = %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if err := f.service.VerifyEmail(f.notifier.last(t).Token); err == nil || err.Error() != "invalid or expired verification token" {
+		t.Fatalf("expected expired token, got %v", err)
+	}
+}
+
+func TestAuthService_RequestEmailVerification_Throttled(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.config.EmailVerification.ResendInterval = time.Minute
+
+	if err := f.service.RequestEmailVerification(f.user.ID); err != nil {
+		t.Fatalf("RequestEmailVerification() error = %v", err)
+	}
+	first := f.notifier.last(t).Token
+
+	err := f.service.RequestEmailVerification(f.user.ID)
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) || throttled.RetryAfter <= 0 || throttled.RetryAfter > time.Minute {
+		t.Fatalf("expected a throttled resend, got %v", err)
+	}
+	if len(f.notifier.messages) != 1 {
+		t.Fatalf("expected no second email, got %d", len(f.notifier.messages))
+	}
+
+	// Once the interval has passed a new link replaces the old one.
+	f.service.config.EmailVerification.ResendInterval = 0
+	if err := f.service.RequestEmailVerification(f.user.ID); err != nil {
+		t.Fatalf("RequestEmailVerification() error = %v", err)
+	}
+	if err := f.service.VerifyEmail(first); err == nil {
+		t.Fatal("expected the older link to stop working")
+	}
+	if err := f.service.VerifyEmail(f.notifier.last(t).Token); err != nil {
+		t.Fatalf("VerifyEmail() error = %v", err)
+	}
+}
+
+func TestAuthService_UpdateProfile_EmailChangeRequiresVerification(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	if err := f.service.RequestEmailVerification(f.user.ID); err != nil {
+		t.Fatalf("RequestEmailVerification() error = %v", err)
+	}
+	if err := f.service.VerifyEmail(f.notifier.last(t).Token); err != nil {
+		t.Fatalf("VerifyEmail() error = %v", err)
+	}
+
+	updated, err := f.service.UpdateProfile(f.user.ID, "", models.UpdateProfileRequest{
+		Email:           strPtr("moved@example.com"),
+		CurrentPassword: "password123",
+	})
+	if err != nil {
+		t.Fatalf("UpdateProfile() error = %v", err)
+	}
+	if updated.EmailVerified {
+		t.Fatal("a new email must be verified again")
+	}
+	if msg := f.notifier.last(t); msg.Kind != notify.KindEmailVerification || msg.To != "moved@example.com" {
+		t.Fatalf("expected a verification email to the new address, got %+v", msg)
+	}
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/icl00ud/velure/shared/auth"
+	"github.com/icl00ud/velure/shared/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// Impersonation tokens belong to no session, so their IDs are tracked in
+// Redis next to the revocation list: one sorted set per impersonated user
+// and one per admin, scored by expiry.
+const (
+	impersonatedKeyPrefix = "auth:impersonation:user:"
+	impersonatorKeyPrefix = "auth:impersonation:admin:"
+)
+
+// ImpersonateUser gives an admin a short-lived access token to act as
+// another user, so support staff can reproduce a customer's problem. The
+// token names the admin in its act claim and belongs to no session, so it
+// cannot be refreshed. It is only handed out once it is tracked for
+// revocation and the audit trail has recorded it; admins and disabled
+// accounts cannot be impersonated.
+func (s *AuthService) ImpersonateUser(adminID, userID uint, reason string, device models.DeviceInfo) (*models.ImpersonationResponse, error) {
+	result := "refused"
+	defer func() {
+		metrics.ImpersonationTokens.WithLabelValues(result).Inc()
+	}()
+
+	if strings.TrimSpace(reason) == "" {
+		return nil, errors.New("reason is required")
+	}
+	if adminID == userID {
+		return nil, errors.New("cannot impersonate yourself")
+	}
+	if s.auditRepo == nil {
+		result = "failure"
+		return nil, errors.New("audit log not enabled")
+	}
+
+	user, err := s.getUserForAdmin(userID)
+	if err != nil {
+		if err.Error() != "user not found" {
+			result = "failure"
+		}
+		return nil, err
+	}
+	if user.Disabled() {
+		return nil, errors.New("cannot impersonate a disabled account")
+	}
+	if auth.HasAnyRole(user.Roles, auth.RoleAdmin) {
+		return nil, errors.New("cannot impersonate an admin")
+	}
+
+	tokenID, err := randomToken(16)
+	if err != nil {
+		result = "failure"
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return nil, err
+	}
+	ttl := s.config.Impersonation.TokenTTL
+	if ttl <= 0 {
+		ttl = 15 * time.Minute
+	}
+	now := time.Now()
+	token, err := s.keys.Sign(auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Roles:         user.Roles,
+		EmailVerified: user.EmailVerified(),
+		Actor:         &auth.Actor{Subject: strconv.FormatUint(uint64(adminID), 10)},
+	})
+	if err != nil {
+		result = "failure"
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return nil, fmt.Errorf("error signing token: %w", err)
+	}
+
+	// Disabling or signing out either account must be able to find the
+	// token, so it is not handed out untracked.
+	if err := s.trackImpersonation(adminID, user.ID, tokenID, now.Add(ttl)); err != nil {
+		result = "failure"
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return nil, fmt.Errorf("error tracking impersonation: %w", err)
+	}
+
+	// Unlike other events this one must not go unrecorded.
+	event := newAuditEvent(models.AuditUserImpersonated, user.ID, user.Email, device, adminAuditDetail(adminID, reason))
+	if err := s.auditRepo.Create(event); err != nil {
+		result = "failure"
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error recording impersonation: %w", err)
+	}
+
+	result = "issued"
+	logger.Warn("impersonation token issued",
+		logger.Uint("admin_id", adminID),
+		logger.Uint("user_id", user.ID),
+		logger.String("token_id", tokenID))
+	return &models.ImpersonationResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(ttl.Seconds()),
+	}, nil
+}
+
+// trackImpersonation records the token under both the impersonated user and
+// the admin. Without Redis there is no revocation list to put it on later,
+// so there is nothing to track either.
+func (s *AuthService) trackImpersonation(adminID, userID uint, tokenID string, expiresAt time.Time) error {
+	if s.redis == nil {
+		return nil
+	}
+	ctx := context.Background()
+	keys := []string{
+		impersonatedKeyPrefix + strconv.FormatUint(uint64(userID), 10),
+		impersonatorKeyPrefix + strconv.FormatUint(uint64(adminID), 10),
+	}
+	entry := redis.Z{Score: float64(expiresAt.Unix()), Member: tokenID}
+	_, err := s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.ZAdd(ctx, key, entry)
+			pipe.Expire(ctx, key, time.Until(expiresAt))
+		}
+		return nil
+	})
+	return err
+}
+
+// revokeImpersonations puts the unexpired impersonation tokens issued for
+// the user, or by the user as an admin, on the revocation list. Like
+// revokeAccessTokens it only logs failures.
+func (s *AuthService) revokeImpersonations(userID uint) {
+	if s.redis == nil || s.revocations == nil {
+		return
+	}
+
+	ctx := context.Background()
+	id := strconv.FormatUint(uint64(userID), 10)
+	for _, key := range []string{impersonatedKeyPrefix + id, impersonatorKeyPrefix + id} {
+		entries, err := s.redis.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+			Min: strconv.FormatInt(time.Now().Unix(), 10),
+			Max: "+inf",
+		}).Result()
+		if err != nil {
+			metrics.Errors.WithLabelValues("internal").Inc()
+			logger.Warn("failed to list impersonation tokens", logger.Uint("user_id", userID), logger.Err(err))
+			continue
+		}
+		for _, entry := range entries {
+			tokenID, _ := entry.Member.(string)
+			if err := s.revocations.Revoke(ctx, tokenID, time.Unix(int64(entry.Score), 0)); err != nil {
+				metrics.Errors.WithLabelValues("internal").Inc()
+				logger.Warn("failed to revoke impersonation token", logger.Uint("user_id", userID), logger.Err(err))
+			}
+		}
+	}
+}
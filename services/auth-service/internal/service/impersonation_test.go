@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/mocks"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/repository"
+	"github.com/icl00ud/velure/services/auth-service/internal/testutil"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/icl00ud/velure/shared/auth"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAuthService_ImpersonateUser(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.AttachAuditLog(repositories.NewAuditEventRepository(f.db))
+	const adminID = 999
+
+	resp, err := f.service.ImpersonateUser(adminID, f.user.ID, "ticket #4521: checkout fails", models.DeviceInfo{})
+	if err != nil {
+		t.Fatalf("ImpersonateUser() error = %v", err)
+	}
+	if resp.TokenType != "Bearer" || resp.ExpiresIn != 15*60 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	claims := &auth.Claims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(resp.AccessToken, claims); err != nil {
+		t.Fatal(err)
+	}
+	if !claims.IsImpersonated() || claims.Actor.Subject != "999" || claims.Subject != strconv.FormatUint(uint64(f.user.ID), 10) || claims.SessionID != "" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+
+	// The token acts as the user and says who is behind it.
+	user, err := f.service.ValidateAccessToken(resp.AccessToken)
+	if err != nil || user.ID != f.user.ID {
+		t.Fatalf("expected the token to act as the user, got %+v (err = %v)", user, err)
+	}
+	info, err := f.service.IntrospectToken(resp.AccessToken)
+	if err != nil || !info.Active || info.Actor != "999" {
+		t.Fatalf("expected the actor in the introspection, got %+v (err = %v)", info, err)
+	}
+
+	// No session was opened, so there is nothing to refresh.
+	if sessions, err := f.service.ListSessions(f.user.ID, ""); err != nil || len(sessions) != 0 {
+		t.Fatalf("expected no session, got %v (err = %v)", sessions, err)
+	}
+
+	page, err := f.service.ListAuditEvents(models.AuditEventFilter{Type: models.AuditUserImpersonated}, "", 10)
+	if err != nil || len(page.Events) != 1 || page.Events[0].Detail != "admin 999: ticket #4521: checkout fails" {
+		t.Fatalf("expected one user.impersonated event, got %+v, %v", page, err)
+	}
+}
+
+func TestAuthService_ImpersonateUser_Refused(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	const adminID = 999
+
+	if _, err := f.service.ImpersonateUser(adminID, f.user.ID, "ticket", models.DeviceInfo{}); err == nil || err.Error() != "audit log not enabled" {
+		t.Fatalf("expected impersonation to need the audit log, got %v", err)
+	}
+	f.service.AttachAuditLog(repositories.NewAuditEventRepository(f.db))
+
+	admin := testutil.CreateTestUser(func(u *models.User) {
+		u.ID = 0
+		u.Email = "admin@example.com"
+		u.Roles = []string{auth.RoleAdmin}
+	})
+	if err := repositories.NewUserRepository(f.db).Create(admin); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		userID uint
+		reason string
+		want   string
+	}{
+		{"no reason", f.user.ID, "  ", "reason is required"},
+		{"self", adminID, "ticket", "cannot impersonate yourself"},
+		{"unknown user", 12345, "ticket", "user not found"},
+		{"admin", admin.ID, "ticket", "cannot impersonate an admin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := f.service.ImpersonateUser(adminID, tt.userID, tt.reason, models.DeviceInfo{}); err == nil || err.Error() != tt.want {
+				t.Fatalf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := f.service.DisableUser(adminID, f.user.ID, "", models.DeviceInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.ImpersonateUser(adminID, f.user.ID, "ticket", models.DeviceInfo{}); err == nil || err.Error() != "cannot impersonate a disabled account" {
+		t.Fatalf("expected a disabled account to be refused, got %v", err)
+	}
+}
+
+func TestAuthService_ImpersonateUser_AuditFailure(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	auditRepo := mocks.NewMockAuditEventRepositoryInterface(gomock.NewController(t))
+	auditRepo.EXPECT().Create(gomock.Any()).Return(errors.New("database is down"))
+	f.service.AttachAuditLog(auditRepo)
+
+	// A token that was not recorded must not be handed out.
+	if resp, err := f.service.ImpersonateUser(999, f.user.ID, "ticket", models.DeviceInfo{}); err == nil || resp != nil {
+		t.Fatalf("expected the impersonation to fail, got %+v (err = %v)", resp, err)
+	}
+}
+
+func TestAuthService_ImpersonationTokens_Revoked(t *testing.T) {
+	tests := []struct {
+		name   string
+		revoke func(f *passwordResetFixture, admin *models.User) error
+	}{
+		{
+			name: "impersonated user disabled",
+			revoke: func(f *passwordResetFixture, admin *models.User) error {
+				_, err := f.service.DisableUser(admin.ID+1, f.user.ID, "fraud", models.DeviceInfo{})
+				return err
+			},
+		},
+		{
+			name: "admin disabled",
+			revoke: func(f *passwordResetFixture, admin *models.User) error {
+				_, err := f.service.DisableUser(admin.ID+1, admin.ID, "left the company", models.DeviceInfo{})
+				return err
+			},
+		},
+		{
+			name: "impersonated user signed out everywhere",
+			revoke: func(f *passwordResetFixture, admin *models.User) error {
+				return f.service.RevokeAllSessions(f.user.ID)
+			},
+		},
+		{
+			name: "admin signed out everywhere",
+			revoke: func(f *passwordResetFixture, admin *models.User) error {
+				return f.service.RevokeAllSessions(admin.ID)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPasswordResetFixture(t)
+			f.service.AttachAuditLog(repositories.NewAuditEventRepository(f.db))
+			admin := testutil.CreateTestUser(func(u *models.User) {
+				u.ID = 0
+				u.Email = "admin@example.com"
+				u.Roles = []string{auth.RoleAdmin}
+			})
+			if err := repositories.NewUserRepository(f.db).Create(admin); err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := f.service.ImpersonateUser(admin.ID, f.user.ID, "ticket", models.DeviceInfo{})
+			if err != nil {
+				t.Fatalf("ImpersonateUser() error = %v", err)
+			}
+			if err := tt.revoke(f, admin); err != nil {
+				t.Fatalf("revoke error = %v", err)
+			}
+
+			// Other services verify offline, so the token must be on the
+			// shared list, not only refused by auth-service.
+			claims, err := auth.Parse(resp.AccessToken, f.service.keys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := redis.NewClient(&redis.Options{Addr: f.redis.Addr()})
+			defer client.Close()
+			if err := auth.NewRevocationList(client).Check(context.Background(), claims); !errors.Is(err, auth.ErrTokenRevoked) {
+				t.Fatalf("expected the impersonation token to be revoked, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAuthService_ImpersonationTokens_OtherUsersUnaffected(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.AttachAuditLog(repositories.NewAuditEventRepository(f.db))
+	const adminID = 999
+
+	resp, err := f.service.ImpersonateUser(adminID, f.user.ID, "ticket", models.DeviceInfo{})
+	if err != nil {
+		t.Fatalf("ImpersonateUser() error = %v", err)
+	}
+	if err := f.service.RevokeAllSessions(adminID + 1); err != nil {
+		t.Fatalf("RevokeAllSessions() error = %v", err)
+	}
+
+	if _, err := f.service.ValidateAccessToken(resp.AccessToken); err != nil {
+		t.Fatalf("expected the impersonation token to stay valid, got %v", err)
+	}
+}
@@ -1,15 +1,52 @@
 package services
 
-import "github.com/icl00ud/velure/services/auth-service/internal/model"
+import (
+	"context"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+
+	"github.com/icl00ud/velure/shared/auth"
+)
 
 // AuthServiceInterface defines the interface for authentication service operations
 type AuthServiceInterface interface {
 	CreateUser(req models.CreateUserRequest) (*models.RegistrationResponse, error)
 	Login(req models.LoginRequest) (*models.LoginResponse, error)
 	ValidateAccessToken(token string) (*models.User, error)
+	IntrospectToken(token string) (*models.TokenIntrospection, error)
 	GetUsers() ([]models.UserResponse, error)
 	GetUsersByPage(page, pageSize int) (*models.PaginatedUsersResponse, error)
+	SearchUsers(filter models.UserFilter, sort, cursor string, limit int) (*models.UserSearchPage, error)
+	DisableUser(adminID, userID uint, reason string, device models.DeviceInfo) (*models.UserResponse, error)
+	EnableUser(adminID, userID uint, device models.DeviceInfo) (*models.UserResponse, error)
+	ImpersonateUser(adminID, userID uint, reason string, device models.DeviceInfo) (*models.ImpersonationResponse, error)
 	GetUserByID(id uint) (*models.UserResponse, error)
+	GetUsersByIDs(ids []uint) ([]models.UserResponse, error)
 	GetUserByEmail(email string) (*models.UserResponse, error)
-	Logout(refreshToken string) error
+	UpdateUserRoles(id uint, roles []string) (*models.UserResponse, error)
+	Logout(refreshToken string, device models.DeviceInfo) error
+	RefreshSession(refreshToken string, device models.DeviceInfo) (*models.LoginResponse, error)
+	ListSessions(userID uint, currentAccessToken string) ([]models.SessionResponse, error)
+	RevokeSession(userID, sessionID uint) error
+	RevokeAllSessions(userID uint) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(token, newPassword string, device models.DeviceInfo) error
+	RequestEmailVerification(userID uint) error
+	VerifyEmail(token string) error
+	EnrollTwoFactor(userID uint) (*models.TwoFactorEnrollmentResponse, error)
+	ConfirmTwoFactor(userID uint, code string) (*models.RecoveryCodesResponse, error)
+	DisableTwoFactor(userID uint, code string) error
+	RegenerateRecoveryCodes(userID uint, code string) (*models.RecoveryCodesResponse, error)
+	CompleteTwoFactorLogin(req models.TwoFactorLoginRequest) (*models.LoginResponse, error)
+	StartOIDCLogin(provider string) (authURL, state string, err error)
+	CompleteOIDCLogin(req models.OIDCLoginRequest) (*models.LoginResponse, error)
+	UpdateProfile(userID uint, currentAccessToken string, req models.UpdateProfileRequest) (*models.UserResponse, error)
+	DeleteAccount(userID uint, password string, device models.DeviceInfo) error
+	ListAuditEvents(filter models.AuditEventFilter, cursor string, limit int) (*models.AuditEventPage, error)
+	ExportAuditEvents(filter models.AuditEventFilter, afterID uint, emit func(models.AuditEvent) error) error
+	RegisterClient(adminID uint, req models.CreateOAuthClientRequest) (*models.RegisteredOAuthClientResponse, error)
+	ListClients() ([]models.OAuthClientResponse, error)
+	DeleteClient(adminID uint, clientID string, device models.DeviceInfo) error
+	IssueClientToken(clientID, clientSecret, scope string) (*models.ClientTokenResponse, error)
+	JWKS() (auth.JWKS, error)
 }
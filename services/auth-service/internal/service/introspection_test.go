@@ -0,0 +1,92 @@
+package services
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/repository"
+
+	"github.com/icl00ud/velure/shared/auth"
+)
+
+func TestAuthService_IntrospectToken_User(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	session := loginFixtureUser(t, f)
+
+	info, err := f.service.IntrospectToken(session.AccessToken)
+	if err != nil {
+		t.Fatalf("IntrospectToken() error = %v", err)
+	}
+	if !info.Active || info.Subject != strconv.FormatUint(uint64(f.user.ID), 10) || info.ClientID != "" {
+		t.Fatalf("unexpected introspection %+v", info)
+	}
+	if len(info.Roles) != 1 || info.Roles[0] != auth.RoleCustomer {
+		t.Fatalf("expected the token's roles, got %v", info.Roles)
+	}
+	if info.SessionID == "" || time.Until(info.ExpiresAt) <= 0 || info.IssuedAt.IsZero() {
+		t.Fatalf("expected session and lifetime, got %+v", info)
+	}
+
+	// The session ID survives refreshes.
+	refreshed, err := f.service.RefreshSession(session.RefreshToken, models.DeviceInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := f.service.IntrospectToken(refreshed.AccessToken)
+	if err != nil || again.SessionID != info.SessionID {
+		t.Fatalf("expected session %q after refresh, got %+v (err = %v)", info.SessionID, again, err)
+	}
+	other := loginFixtureUser(t, f)
+	if third, _ := f.service.IntrospectToken(other.AccessToken); third.SessionID == info.SessionID {
+		t.Fatal("a new login must get a new session ID")
+	}
+
+	if err := f.service.Logout(refreshed.RefreshToken, models.DeviceInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{refreshed.AccessToken, "not-a-jwt"} {
+		info, err := f.service.IntrospectToken(token)
+		if err != nil || info.Active {
+			t.Fatalf("expected an inactive token, got %+v (err = %v)", info, err)
+		}
+	}
+}
+
+func TestAuthService_IntrospectToken_Client(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.AttachClients(repositories.NewOAuthClientRepository(f.db))
+
+	registered, err := f.service.RegisterClient(1, models.CreateOAuthClientRequest{
+		ClientID: "process-order-service",
+		Name:     "Process order service",
+		Scopes:   []string{auth.ScopeUsersRead, auth.ScopeInventoryWrite},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := f.service.IssueClientToken("process-order-service", registered.ClientSecret, auth.ScopeUsersRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := f.service.IntrospectToken(token.AccessToken)
+	if err != nil {
+		t.Fatalf("IntrospectToken() error = %v", err)
+	}
+	if !info.Active || info.ClientID != "process-order-service" || len(info.Roles) != 0 {
+		t.Fatalf("unexpected introspection %+v", info)
+	}
+	if len(info.Scopes) != 1 || info.Scopes[0] != auth.ScopeUsersRead {
+		t.Fatalf("expected only the granted scope, got %v", info.Scopes)
+	}
+
+	// Tokens of deleted clients stop being active straight away.
+	if err := f.service.DeleteClient(1, "process-order-service", models.DeviceInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := f.service.IntrospectToken(token.AccessToken); err != nil || info.Active {
+		t.Fatalf("expected an inactive token, got %+v (err = %v)", info, err)
+	}
+}
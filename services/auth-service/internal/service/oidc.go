@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/oidc"
+	"github.com/icl00ud/velure/services/auth-service/internal/repository"
+
+	"github.com/icl00ud/velure/shared/auth"
+	"github.com/icl00ud/velure/shared/logger"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const oidcStateKeyPrefix = "oidc:state:"
+
+// oidcLoginState is what a started social login keeps in Redis until the
+// browser comes back from the provider.
+type oidcLoginState struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// AttachIdentityProviders enables social login with the given OpenID Connect
+// providers; identities records which provider account belongs to which
+// user. Without providers every social login answers "unknown identity
+// provider".
+func (s *AuthService) AttachIdentityProviders(identities repositories.UserIdentityRepositoryInterface, providers ...*oidc.Provider) {
+	s.identityRepo = identities
+	s.identityProviders = make(map[string]*oidc.Provider, len(providers))
+	for _, p := range providers {
+		s.identityProviders[p.Name()] = p
+	}
+}
+
+// StartOIDCLogin returns the provider URL to send the browser to and the
+// state it carries, which the caller binds to the browser so a callback
+// cannot be replayed into another one. The state, nonce and PKCE verifier
+// are kept in Redis for OIDC.StateTTL and can be used once.
+func (s *AuthService) StartOIDCLogin(provider string) (string, string, error) {
+	p, ok := s.identityProviders[provider]
+	if !ok || s.identityRepo == nil {
+		return "", "", errors.New("unknown identity provider")
+	}
+	if s.redis == nil {
+		return "", "", errors.New("social login requires redis")
+	}
+
+	state, err := randomToken(32)
+	if err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return "", "", fmt.Errorf("error generating login state: %w", err)
+	}
+	nonce, err := randomToken(32)
+	if err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return "", "", fmt.Errorf("error generating login nonce: %w", err)
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return "", "", fmt.Errorf("error generating code verifier: %w", err)
+	}
+
+	ctx := context.Background()
+	authURL, err := p.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		metrics.OIDCLogins.WithLabelValues(provider, "provider_error").Inc()
+		return "", "", fmt.Errorf("error starting login with %s: %w", provider, err)
+	}
+
+	ttl := s.config.OIDC.StateTTL
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	payload, err := json.Marshal(oidcLoginState{Provider: provider, Nonce: nonce, Verifier: verifier})
+	if err != nil {
+		return "", "", fmt.Errorf("error encoding login state: %w", err)
+	}
+	if err := s.redis.Set(ctx, oidcStateKeyPrefix+state, payload, ttl).Err(); err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return "", "", fmt.Errorf("error storing login state: %w", err)
+	}
+
+	return authURL, state, nil
+}
+
+// CompleteOIDCLogin finishes a social login: it checks the state, redeems
+// the code with the provider and signs in the user the identity is linked
+// to. An identity seen for the first time is linked to the account with the
+// same email, or gets a new account, but only when the provider vouches for
+// the email. Accounts with two-factor authentication still get a challenge.
+func (s *AuthService) CompleteOIDCLogin(req models.OIDCLoginRequest) (*models.LoginResponse, error) {
+	p, ok := s.identityProviders[req.Provider]
+	if !ok || s.identityRepo == nil {
+		return nil, errors.New("unknown identity provider")
+	}
+	if s.redis == nil {
+		return nil, errors.New("social login requires redis")
+	}
+
+	ctx := context.Background()
+	// GETDEL makes the state single-use even with concurrent callbacks.
+	raw, err := s.redis.GetDel(ctx, oidcStateKeyPrefix+req.State).Bytes()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return nil, fmt.Errorf("error reading login state: %w", err)
+	}
+	var state oidcLoginState
+	if err != nil || json.Unmarshal(raw, &state) != nil || state.Provider != req.Provider {
+		metrics.OIDCLogins.WithLabelValues(req.Provider, "invalid_state").Inc()
+		return nil, errors.New("invalid or expired login state")
+	}
+
+	identity, err := p.Exchange(ctx, req.Code, state.Verifier, state.Nonce)
+	if err != nil {
+		logger.Warn("social login rejected by provider", logger.String("provider", req.Provider), logger.Err(err))
+		metrics.OIDCLogins.WithLabelValues(req.Provider, "provider_error").Inc()
+		return nil, errors.New("identity provider login failed")
+	}
+
+	user, result, err := s.userForIdentity(req.Provider, identity)
+	if err != nil {
+		return nil, err
+	}
+	if result == "registered" {
+		s.recordAudit(models.AuditUserRegistered, user.ID, user.Email, req.Device, req.Provider)
+	}
+	if user.Disabled() {
+		metrics.OIDCLogins.WithLabelValues(req.Provider, "disabled").Inc()
+		s.recordAudit(models.AuditLoginFailed, user.ID, user.Email, req.Device, "account disabled")
+		return nil, errors.New("account disabled")
+	}
+
+	if user.TOTPEnabled {
+		challenge, err := s.issueTwoFactorChallenge(user.ID)
+		if err != nil {
+			metrics.Errors.WithLabelValues("internal").Inc()
+			return nil, fmt.Errorf("error generating two-factor challenge: %w", err)
+		}
+		metrics.OIDCLogins.WithLabelValues(req.Provider, result).Inc()
+		return &models.LoginResponse{TwoFactorRequired: true, ChallengeToken: challenge}, nil
+	}
+
+	session, err := s.createSession(user, req.Device)
+	if err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return nil, fmt.Errorf("error creating session: %w", err)
+	}
+
+	metrics.OIDCLogins.WithLabelValues(req.Provider, result).Inc()
+	s.recordAudit(models.AuditLoginSucceeded, user.ID, user.Email, req.Device, req.Provider)
+	return &models.LoginResponse{
+		AccessToken:  session.AccessToken,
+		RefreshToken: session.RefreshToken,
+	}, nil
+}
+
+// userForIdentity returns the user a provider identity belongs to, linking
+// or creating it on first sight, and the metric result for the login.
+func (s *AuthService) userForIdentity(provider string, identity *oidc.Identity) (*models.User, string, error) {
+	link, err := s.identityRepo.GetByProviderSubject(provider, identity.Subject)
+	if err == nil {
+		user, err := s.userRepo.GetByID(link.UserID)
+		if err != nil {
+			metrics.Errors.WithLabelValues("database").Inc()
+			return nil, "", fmt.Errorf("error getting user: %w", err)
+		}
+		return user, "success", nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, "", fmt.Errorf("error getting identity: %w", err)
+	}
+
+	// Linking by email is only safe when the provider has checked that the
+	// person signing in owns it.
+	if identity.Email == "" || !identity.EmailVerified {
+		metrics.OIDCLogins.WithLabelValues(provider, "rejected").Inc()
+		return nil, "", errors.New("identity provider did not verify the email")
+	}
+
+	result := "linked"
+	user, err := s.userRepo.GetByEmail(identity.Email)
+	switch {
+	case err == nil:
+		// Someone may have registered the address without owning it; the
+		// account has to be verified before another login can be attached.
+		if !user.EmailVerified() {
+			metrics.OIDCLogins.WithLabelValues(provider, "rejected").Inc()
+			return nil, "", errors.New("account email not verified")
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		result = "registered"
+		if user, err = s.createUserForIdentity(identity); err != nil {
+			return nil, "", err
+		}
+	default:
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, "", fmt.Errorf("error getting user: %w", err)
+	}
+
+	if err := s.identityRepo.Create(&models.UserIdentity{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, "", fmt.Errorf("error linking identity: %w", err)
+	}
+
+	return user, result, nil
+}
+
+// createUserForIdentity registers a customer for a first-time social login.
+// The email counts as verified, and the password is random so the account
+// can only be signed into with a password after a reset.
+func (s *AuthService) createUserForIdentity(identity *oidc.Identity) (*models.User, error) {
+	password, err := randomToken(32)
+	if err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return nil, fmt.Errorf("error generating password: %w", err)
+	}
+	hashedPassword, err := s.hashPassword(password)
+	if err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return nil, fmt.Errorf("error hashing password: %w", err)
+	}
+
+	name := strings.TrimSpace(identity.Name)
+	if name == "" {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+	now := time.Now()
+	user := &models.User{
+		Name:       name,
+		Email:      identity.Email,
+		Password:   hashedPassword,
+		Roles:      models.Roles{auth.RoleCustomer},
+		VerifiedAt: &now,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error creating user: %w", err)
+	}
+
+	metrics.RegistrationAttempts.WithLabelValues("success").Inc()
+	return user, nil
+}
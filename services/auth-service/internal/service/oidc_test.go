@@ -0,0 +1,177 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/oidc"
+	"github.com/icl00ud/velure/services/auth-service/internal/oidc/oidctest"
+	"github.com/icl00ud/velure/services/auth-service/internal/repository"
+)
+
+// withIdentityProvider attaches a fake provider named "test" to the fixture.
+func withIdentityProvider(t *testing.T, f *passwordResetFixture) *oidctest.Provider {
+	t.Helper()
+
+	fake := oidctest.NewProvider(t)
+	f.service.AttachIdentityProviders(
+		repositories.NewUserIdentityRepository(f.db),
+		oidc.New(fake.Config("test", "http://localhost:3000/auth/callback")),
+	)
+	return fake
+}
+
+// socialLogin runs the whole browser round trip for the signed-in identity.
+func socialLogin(t *testing.T, f *passwordResetFixture, fake *oidctest.Provider) (*models.LoginResponse, error) {
+	t.Helper()
+
+	authURL, _, err := f.service.StartOIDCLogin("test")
+	if err != nil {
+		t.Fatalf("StartOIDCLogin() error = %v", err)
+	}
+	code, state := fake.Authorize(t, authURL)
+	return f.service.CompleteOIDCLogin(models.OIDCLoginRequest{Provider: "test", Code: code, State: state})
+}
+
+func TestAuthService_OIDCLogin_RegistersNewUser(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	fake := withIdentityProvider(t, f)
+
+	resp, err := socialLogin(t, f, fake)
+	if err != nil {
+		t.Fatalf("CompleteOIDCLogin() error = %v", err)
+	}
+	if resp.AccessToken == "" || resp.RefreshToken == "" {
+		t.Fatalf("expected a token pair, got %+v", resp)
+	}
+	if !emailVerifiedClaim(t, f.service, resp.AccessToken) {
+		t.Fatal("the provider verified the email, so the account starts verified")
+	}
+
+	user, err := f.service.userRepo.GetByEmail("alice@example.com")
+	if err != nil {
+		t.Fatalf("expected a new account: %v", err)
+	}
+	if user.Name != "Alice" || !user.EmailVerified() {
+		t.Fatalf("unexpected account %+v", user)
+	}
+
+	// The second login finds the linked identity instead of registering again.
+	if _, err := socialLogin(t, f, fake); err != nil {
+		t.Fatalf("second CompleteOIDCLogin() error = %v", err)
+	}
+	if users, _ := f.service.userRepo.GetAll(); len(users) != 2 {
+		t.Fatalf("expected the fixture user and one new user, got %d", len(users))
+	}
+}
+
+func TestAuthService_OIDCLogin_LinksByVerifiedEmail(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	fake := withIdentityProvider(t, f)
+	fake.SignIn(oidc.Identity{Subject: "sub-1", Email: f.user.Email, EmailVerified: true})
+
+	// An account nobody has proven to own the address of is not linked.
+	if _, err := socialLogin(t, f, fake); err == nil || err.Error() != "account email not verified" {
+		t.Fatalf("expected unverified account to be refused, got %v", err)
+	}
+
+	now := time.Now()
+	f.user.VerifiedAt = &now
+	if err := f.service.userRepo.Update(f.user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := socialLogin(t, f, fake); err != nil {
+		t.Fatalf("CompleteOIDCLogin() error = %v", err)
+	}
+
+	// Once linked the provider's email no longer matters.
+	fake.SignIn(oidc.Identity{Subject: "sub-1", Email: "changed@example.com"})
+	resp, err := socialLogin(t, f, fake)
+	if err != nil {
+		t.Fatalf("CompleteOIDCLogin() error = %v", err)
+	}
+	user, err := f.service.ValidateAccessToken(resp.AccessToken)
+	if err != nil || user.ID != f.user.ID {
+		t.Fatalf("expected a token for the linked account, got %+v, %v", user, err)
+	}
+}
+
+func TestAuthService_OIDCLogin_RejectsUnverifiedProviderEmail(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	fake := withIdentityProvider(t, f)
+	fake.SignIn(oidc.Identity{Subject: "sub-2", Email: "new@example.com", EmailVerified: false})
+
+	if _, err := socialLogin(t, f, fake); err == nil || err.Error() != "identity provider did not verify the email" {
+		t.Fatalf("expected unverified provider email to be refused, got %v", err)
+	}
+	if _, err := f.service.userRepo.GetByEmail("new@example.com"); err == nil {
+		t.Fatal("no account may be created for an unverified email")
+	}
+}
+
+func TestAuthService_OIDCLogin_State(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	fake := withIdentityProvider(t, f)
+
+	authURL, _, err := f.service.StartOIDCLogin("test")
+	if err != nil {
+		t.Fatalf("StartOIDCLogin() error = %v", err)
+	}
+	code, state := fake.Authorize(t, authURL)
+
+	if _, err := f.service.CompleteOIDCLogin(models.OIDCLoginRequest{Provider: "test", Code: code, State: "forged"}); err == nil || err.Error() != "invalid or expired login state" {
+		t.Fatalf("expected forged state to be rejected, got %v", err)
+	}
+	if _, err := f.service.CompleteOIDCLogin(models.OIDCLoginRequest{Provider: "test", Code: code, State: state}); err != nil {
+		t.Fatalf("CompleteOIDCLogin() error = %v", err)
+	}
+	// A state is single-use.
+	if _, err := f.service.CompleteOIDCLogin(models.OIDCLoginRequest{Provider: "test", Code: code, State: state}); err == nil || err.Error() != "invalid or expired login state" {
+		t.Fatalf("expected replayed state to be rejected, got %v", err)
+	}
+
+	// States expire.
+	authURL, _, _ = f.service.StartOIDCLogin("test")
+	code, state = fake.Authorize(t, authURL)
+	f.redis.FastForward(11 * time.Minute)
+	if _, err := f.service.CompleteOIDCLogin(models.OIDCLoginRequest{Provider: "test", Code: code, State: state}); err == nil || err.Error() != "invalid or expired login state" {
+		t.Fatalf("expected expired state to be rejected, got %v", err)
+	}
+
+	if _, _, err := f.service.StartOIDCLogin("unknown"); err == nil || err.Error() != "unknown identity provider" {
+		t.Fatalf("expected unknown provider, got %v", err)
+	}
+}
+
+func TestAuthService_OIDCLogin_ProviderError(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	fake := withIdentityProvider(t, f)
+
+	authURL, _, _ := f.service.StartOIDCLogin("test")
+	_, state := fake.Authorize(t, authURL)
+
+	if _, err := f.service.CompleteOIDCLogin(models.OIDCLoginRequest{Provider: "test", Code: "made-up", State: state}); err == nil || err.Error() != "identity provider login failed" {
+		t.Fatalf("expected provider failure, got %v", err)
+	}
+}
+
+func TestAuthService_OIDCLogin_TwoFactorStillRequired(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	fake := withIdentityProvider(t, f)
+	now := time.Now()
+	f.user.VerifiedAt = &now
+	if err := f.service.userRepo.Update(f.user); err != nil {
+		t.Fatal(err)
+	}
+	enableTwoFactor(t, f)
+	fake.SignIn(oidc.Identity{Subject: "sub-3", Email: f.user.Email, EmailVerified: true})
+
+	resp, err := socialLogin(t, f, fake)
+	if err != nil {
+		t.Fatalf("CompleteOIDCLogin() error = %v", err)
+	}
+	if !resp.TwoFactorRequired || resp.ChallengeToken == "" || resp.AccessToken != "" {
+		t.Fatalf("expected a two-factor challenge, got %+v", resp)
+	}
+}
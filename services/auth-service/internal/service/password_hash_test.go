@@ -0,0 +1,57 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/passhash"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthService_Login_RehashesOutdatedPasswordHash(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	f.user.Password = string(legacy)
+	if err := f.db.Save(f.user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	loginFixtureUser(t, f)
+
+	var stored models.User
+	if err := f.db.First(&stored, f.user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stored.Password, "$argon2id$") {
+		t.Fatalf("expected the bcrypt hash to be upgraded to argon2id, got %q", stored.Password)
+	}
+	loginFixtureUser(t, f)
+	if _, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "wrong"}); err == nil {
+		t.Fatal("the upgraded hash must still reject wrong passwords")
+	}
+}
+
+func TestAuthService_Login_RehashesOnCostChange(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.config.Performance.BcryptCost = bcrypt.MinCost
+	f.service.config.PasswordHash.Algorithm = passhash.AlgBcrypt
+	f.service.hasher = newPasswordHasher(f.service.config)
+
+	// The fixture user has a current argon2id hash, which bcrypt replaces.
+	loginFixtureUser(t, f)
+	var stored models.User
+	f.db.First(&stored, f.user.ID)
+	if cost, err := bcrypt.Cost([]byte(stored.Password)); err != nil || cost != bcrypt.MinCost {
+		t.Fatalf("expected a bcrypt hash with cost %d, got %q", bcrypt.MinCost, stored.Password)
+	}
+
+	f.service.config.Performance.BcryptCost = bcrypt.MinCost + 1
+	f.service.hasher = newPasswordHasher(f.service.config)
+	loginFixtureUser(t, f)
+	f.db.First(&stored, f.user.ID)
+	if cost, _ := bcrypt.Cost([]byte(stored.Password)); cost != bcrypt.MinCost+1 {
+		t.Fatalf("expected the raised cost to be applied on login, got cost %d", cost)
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"github.com/icl00ud/velure/services/auth-service/internal/config"
+	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/passwordpolicy"
+	"github.com/icl00ud/velure/services/auth-service/internal/repository"
+
+	"github.com/icl00ud/velure/shared/logger"
+)
+
+// newPasswordPolicy builds the policy new passwords are checked against.
+// Lengths left at zero fall back to passwordpolicy.Default.
+func newPasswordPolicy(cfg *config.Config) passwordpolicy.Policy {
+	p := cfg.PasswordPolicy
+	policy := passwordpolicy.Policy{
+		MinLength:          p.MinLength,
+		MaxLength:          p.MaxLength,
+		RequireUppercase:   p.RequireUppercase,
+		RequireLowercase:   p.RequireLowercase,
+		RequireDigit:       p.RequireDigit,
+		RequireSymbol:      p.RequireSymbol,
+		RejectPersonalInfo: p.RejectPersonalInfo,
+	}
+	if policy.MinLength <= 0 {
+		policy.MinLength = passwordpolicy.Default.MinLength
+	}
+	if policy.MaxLength <= 0 {
+		policy.MaxLength = passwordpolicy.Default.MaxLength
+	}
+	return policy
+}
+
+// AttachBreachedPasswords enables the breached-password check on every new
+// password.
+func (s *AuthService) AttachBreachedPasswords(b *passwordpolicy.BreachedPasswords) {
+	s.passwordPolicy.Breached = b
+}
+
+// AttachPasswordHistory keeps the hashes of replaced passwords so they cannot
+// be chosen again. Without it only the current password counts as reused.
+func (s *AuthService) AttachPasswordHistory(repo repositories.PasswordHistoryRepositoryInterface) {
+	s.passwordHistoryRepo = repo
+}
+
+// checkNewPassword applies the password policy to a password user is about
+// to get. A user without an ID is still being registered and has no
+// previous passwords.
+func (s *AuthService) checkNewPassword(user *models.User, password string) error {
+	account := passwordpolicy.Account{Email: user.Email, Name: user.Name}
+	if user.ID != 0 && s.config.PasswordPolicy.HistorySize > 0 {
+		account.Reused = func(password string) bool {
+			return s.passwordReused(user, password)
+		}
+	}
+	if err := s.passwordPolicy.Check(password, account); err != nil {
+		metrics.Errors.WithLabelValues("validation").Inc()
+		return err
+	}
+	return nil
+}
+
+// passwordReused reports whether password is the user's current password or
+// one of the previous ones the history keeps. A history that cannot be read
+// is skipped.
+func (s *AuthService) passwordReused(user *models.User, password string) bool {
+	if s.comparePassword(user.Password, password) == nil {
+		return true
+	}
+	keep := s.config.PasswordPolicy.HistorySize - 1
+	if s.passwordHistoryRepo == nil || keep <= 0 {
+		return false
+	}
+	previous, err := s.passwordHistoryRepo.ListRecent(user.ID, keep)
+	if err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		logger.Warn("failed to read password history", logger.Uint("user_id", user.ID), logger.Err(err))
+		return false
+	}
+	for _, entry := range previous {
+		if s.comparePassword(entry.Hash, password) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// rememberPassword adds a replaced password hash to the user's history.
+// Failures are logged: the password change itself has gone through.
+func (s *AuthService) rememberPassword(userID uint, hash string) {
+	keep := s.config.PasswordPolicy.HistorySize - 1
+	if s.passwordHistoryRepo == nil || keep <= 0 {
+		return
+	}
+	if err := s.passwordHistoryRepo.Add(userID, hash, keep); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		logger.Warn("failed to store password history", logger.Uint("user_id", userID), logger.Err(err))
+	}
+}
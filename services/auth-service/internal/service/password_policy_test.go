@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/passwordpolicy"
+	"github.com/icl00ud/velure/services/auth-service/internal/repository"
+)
+
+func violationCodes(err error) []string {
+	var violation *passwordpolicy.ViolationError
+	if !errors.As(err, &violation) {
+		return nil
+	}
+	var codes []string
+	for _, v := range violation.Violations {
+		codes = append(codes, v.Code)
+	}
+	return codes
+}
+
+func TestAuthService_UpdateProfile_RejectsRecentPasswords(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.config.PasswordPolicy.HistorySize = 3
+	f.service.AttachPasswordHistory(repositories.NewPasswordHistoryRepository(f.db))
+
+	current := "password123"
+	change := func(password string) error {
+		_, err := f.service.UpdateProfile(f.user.ID, "", models.UpdateProfileRequest{
+			CurrentPassword: current,
+			Password:        strPtr(password),
+		})
+		if err == nil {
+			current = password
+		}
+		return err
+	}
+
+	for _, password := range []string{"new-password-1", "new-password-2"} {
+		if err := change(password); err != nil {
+			t.Fatalf("changing to %q: %v", password, err)
+		}
+	}
+	for _, password := range []string{"new-password-2", "new-password-1", "password123"} {
+		if codes := violationCodes(change(password)); len(codes) != 1 || codes[0] != passwordpolicy.CodeReused {
+			t.Fatalf("changing to %q: got %v, want %s", password, codes, passwordpolicy.CodeReused)
+		}
+	}
+
+	// The history keeps the current password and the two before it.
+	if err := change("new-password-3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := change("password123"); err != nil {
+		t.Fatalf("the oldest password should be usable again, got %v", err)
+	}
+}
+
+func TestAuthService_ResetPassword_AppliesPolicy(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.config.PasswordPolicy.RejectPersonalInfo = true
+	f.service.passwordPolicy = newPasswordPolicy(f.service.config)
+
+	if err := f.service.RequestPasswordReset(context.Background(), f.user.Email); err != nil {
+		t.Fatal(err)
+	}
+	token := f.notifier.last(t).Token
+
+	err := f.service.ResetPassword(token, "test-and-more", models.DeviceInfo{})
+	// "test" is both the email's local part and a word of "Test User".
+	if codes := violationCodes(err); strings.Join(codes, ",") != passwordpolicy.CodeContainsEmail+","+passwordpolicy.CodeContainsName {
+		t.Fatalf("ResetPassword() = %v (%v), want the email and name violations", err, codes)
+	}
+
+	// The rejected attempt leaves the token usable.
+	if err := f.service.ResetPassword(token, "unrelated-secret", models.DeviceInfo{}); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+}
+
+func TestAuthService_CreateUser_RejectsBreachedPassword(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	dir := t.TempDir()
+	sum := sha1.Sum([]byte("qwerty123"))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	if err := os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(hash[5:]+":3912816\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	breached, err := passwordpolicy.OpenBreachedPasswords(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.service.AttachBreachedPasswords(breached)
+
+	_, err = f.service.CreateUser(models.CreateUserRequest{Name: "New User", Email: "new@example.com", Password: "qwerty123"})
+	if codes := violationCodes(err); len(codes) != 1 || codes[0] != passwordpolicy.CodeBreached {
+		t.Fatalf("CreateUser() = %v, want %s", err, passwordpolicy.CodeBreached)
+	}
+	if _, err := f.service.CreateUser(models.CreateUserRequest{Name: "New User", Email: "new@example.com", Password: "qwerty1234"}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/lockout"
+	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/notify"
+
+	"github.com/icl00ud/velure/shared/logger"
+	"gorm.io/gorm"
+)
+
+// RequestPasswordReset issues a single-use reset token for the account and
+// hands it to the notifier. Unknown emails succeed silently so the endpoint
+// cannot be used to enumerate accounts, and both outcomes are held back to
+// PasswordReset.MinResponseTime so timing cannot tell them apart either. The
+// wait ends early once ctx is done, e.g. when the client has gone away.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	defer padResponseTime(ctx, time.Now(), s.config.PasswordReset.MinResponseTime)
+
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			metrics.PasswordResets.WithLabelValues("unknown_email").Inc()
+			return nil
+		}
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error getting user: %w", err)
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return fmt.Errorf("error generating reset token: %w", err)
+	}
+
+	// Only the newest token is valid.
+	if err := s.passwordResetRepo.DeleteByUserID(user.ID); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error deleting previous reset tokens: %w", err)
+	}
+
+	reset := &models.PasswordReset{
+		UserID:    user.ID,
+		Token:     hashToken(token),
+		ExpiresAt: time.Now().Add(parseExpiry(s.config.PasswordReset.ExpiresIn, time.Hour)),
+	}
+	if err := s.passwordResetRepo.Create(reset); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error creating reset token: %w", err)
+	}
+
+	if err := s.notifier.Notify(ctx, notify.Message{
+		Kind:      notify.KindPasswordReset,
+		To:        user.Email,
+		Token:     token,
+		ExpiresAt: reset.ExpiresAt,
+	}); err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return fmt.Errorf("error sending reset token: %w", err)
+	}
+
+	metrics.PasswordResets.WithLabelValues("requested").Inc()
+	return nil
+}
+
+// padResponseTime waits until min has passed since start or ctx is done. A
+// timer rather than a sleep, so a request whose client disconnected does not
+// hold on for the rest of the window. Work that took longer is logged: it
+// stands out from the padded responses.
+func padResponseTime(ctx context.Context, start time.Time, min time.Duration) {
+	if min <= 0 {
+		return
+	}
+	elapsed := time.Since(start)
+	if elapsed > min {
+		logger.Warn("password reset request exceeded its minimum response time",
+			logger.Duration("elapsed", elapsed), logger.Duration("min", min))
+		return
+	}
+
+	timer := time.NewTimer(min - elapsed)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// ResetPassword consumes a reset token and sets the new password. Every
+// session of the user is revoked and the cached credentials are dropped so
+// the old password stops working on all replicas.
+func (s *AuthService) ResetPassword(token, newPassword string, device models.DeviceInfo) error {
+	reset, err := s.passwordResetRepo.GetByToken(hashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			metrics.PasswordResets.WithLabelValues("invalid_token").Inc()
+			return errors.New("invalid or expired reset token")
+		}
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error getting reset token: %w", err)
+	}
+
+	if !reset.ExpiresAt.After(time.Now()) {
+		if err := s.passwordResetRepo.Delete(reset.ID); err != nil {
+			logger.Warn("failed to delete expired reset token", logger.Err(err))
+		}
+		metrics.PasswordResets.WithLabelValues("invalid_token").Inc()
+		return errors.New("invalid or expired reset token")
+	}
+
+	user, err := s.userRepo.GetByID(reset.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			metrics.PasswordResets.WithLabelValues("invalid_token").Inc()
+			return errors.New("invalid or expired reset token")
+		}
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error getting user: %w", err)
+	}
+
+	// A rejected password leaves the token usable for another try.
+	if err := s.checkNewPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	// Burn the token before touching the password so it cannot be replayed
+	// even if a later step fails.
+	if err := s.passwordResetRepo.DeleteByUserID(user.ID); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error consuming reset token: %w", err)
+	}
+
+	hashedPassword, err := s.hashPassword(newPassword)
+	if err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return fmt.Errorf("error hashing password: %w", err)
+	}
+
+	previousHash := user.Password
+	user.Password = hashedPassword
+	if err := s.userRepo.Update(user); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error updating password: %w", err)
+	}
+	s.rememberPassword(user.ID, previousHash)
+
+	sessions, err := s.sessionsWithLiveTokens(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.sessionRepo.InvalidateByUserID(user.ID); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error invalidating sessions: %w", err)
+	}
+	s.revokeAccessTokens(sessions...)
+	s.invalidateUserCache(context.Background(), user)
+	s.loginGuard.Unlock(context.Background(), lockout.NormalizeAccount(user.Email))
+	s.SyncActiveSessionsMetric(context.Background())
+	s.recordAudit(models.AuditPasswordChanged, user.ID, user.Email, device, "reset")
+
+	metrics.PasswordResets.WithLabelValues("completed").Inc()
+	return nil
+}
+
+// hashToken is what gets stored for reset and verification tokens: a
+// database leak must not hand out working links.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/notify"
+	"github.com/icl00ud/velure/services/auth-service/internal/repository"
+	"github.com/icl00ud/velure/services/auth-service/internal/testutil"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+type recordingNotifier struct {
+	mu       sync.Mutex
+	messages []notify.Message
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, msg notify.Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.messages = append(n.messages, msg)
+	return nil
+}
+
+func (n *recordingNotifier) last(t *testing.T) notify.Message {
+	t.Helper()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.messages) == 0 {
+		t.Fatal("expected a notification to be sent")
+	}
+	return n.messages[len(n.messages)-1]
+}
+
+type passwordResetFixture struct {
+	service     *AuthService
+	user        *models.User
+	notifier    *recordingNotifier
+	resetRepo   *repositories.PasswordResetRepository
+	sessionRepo *repositories.SessionRepository
+	redis       *miniredis.Miniredis
+	db          *gorm.DB
+}
+
+func newPasswordResetFixture(t *testing.T) *passwordResetFixture {
+	t.Helper()
+
+	db := testutil.SetupTestDB(t)
+	userRepo := repositories.NewUserRepository(db)
+	sessionRepo := repositories.NewSessionRepository(db)
+	resetRepo := repositories.NewPasswordResetRepository(db)
+	cfg := testutil.CreateTestConfig()
+	cfg.Performance.TokenCacheTTL = 60
+
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	user := testutil.CreateTestUser()
+	user.ID = 0
+	if err := userRepo.Create(user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	notifier := &recordingNotifier{}
+	service := NewAuthService(userRepo, sessionRepo, resetRepo, repositories.NewEmailVerificationRepository(db), cfg, redisClient)
+	service.AttachNotifier(notifier)
+
+	return &passwordResetFixture{
+		service:     service,
+		user:        user,
+		notifier:    notifier,
+		resetRepo:   resetRepo,
+		sessionRepo: sessionRepo,
+		redis:       mr,
+		db:          db,
+	}
+}
+
+func TestAuthService_ResetPassword_FullFlow(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	login, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "password123"})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if !f.redis.Exists("user:email:" + f.user.Email) {
+		t.Fatal("expected login to cache the user")
+	}
+
+	if err := f.service.RequestPasswordReset(context.Background(), f.user.Email); err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+	msg := f.notifier.last(t)
+	if msg.Kind != notify.KindPasswordReset || msg.To != f.user.Email || msg.Token == "" {
+		t.Fatalf("unexpected notification: %+v", msg)
+	}
+
+	// Only the hash of the token is stored.
+	if _, err := f.resetRepo.GetByToken(msg.Token); err == nil {
+		t.Fatal("raw reset token must not be stored")
+	}
+
+	if err := f.service.ResetPassword(msg.Token, "new-password-1", models.DeviceInfo{}); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+
+	if f.redis.Exists("user:email:" + f.user.Email) {
+		t.Fatal("expected user:email cache entry to be dropped")
+	}
+	if _, err := f.service.RefreshSession(login.RefreshToken, models.DeviceInfo{}); err == nil {
+		t.Fatal("expected existing sessions to be revoked")
+	}
+	if _, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "password123"}); err == nil {
+		t.Fatal("old password must no longer work")
+	}
+	if _, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "new-password-1"}); err != nil {
+		t.Fatalf("new password should work: %v", err)
+	}
+
+	// Tokens are single-use.
+	if err := f.service.ResetPassword(msg.Token, "another-password", models.DeviceInfo{}); err == nil || err.Error() != "invalid or expired reset token" {
+		t.Fatalf("expected reused token to be rejected, got %v", err)
+	}
+}
+
+func TestAuthService_RequestPasswordReset_UnknownEmailIsSilent(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	if err := f.service.RequestPasswordReset(context.Background(), "nobody@example.com"); err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+	if len(f.notifier.messages) != 0 {
+		t.Fatalf("expected no notification, got %+v", f.notifier.messages)
+	}
+}
+
+func TestAuthService_RequestPasswordReset_PadsResponseTime(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.config.PasswordReset.MinResponseTime = 100 * time.Millisecond
+
+	for _, email := range []string{f.user.Email, "nobody@example.com"} {
+		start := time.Now()
+		if err := f.service.RequestPasswordReset(context.Background(), email); err != nil {
+			t.Fatalf("RequestPasswordReset(%q) error = %v", email, err)
+		}
+		if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+			t.Fatalf("RequestPasswordReset(%q) answered after %v, want at least 100ms", email, elapsed)
+		}
+	}
+	f.notifier.last(t)
+}
+
+func TestAuthService_RequestPasswordReset_StopsPaddingWhenClientLeaves(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.config.PasswordReset.MinResponseTime = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := f.service.RequestPasswordReset(ctx, "nobody@example.com"); err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("RequestPasswordReset() kept waiting %v after the context ended", elapsed)
+	}
+}
+
+func TestAuthService_RequestPasswordReset_SupersedesOlderTokens(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	if err := f.service.RequestPasswordReset(context.Background(), f.user.Email); err != nil {
+		t.Fatal(err)
+	}
+	first := f.notifier.last(t)
+	if err := f.service.RequestPasswordReset(context.Background(), f.user.Email); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.service.ResetPassword(first.Token, "new-password-1", models.DeviceInfo{}); err == nil {
+		t.Fatal("expected superseded token to be rejected")
+	}
+}
+
+func TestAuthService_ResetPassword_ExpiredToken(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	token := "expired-token"
+	if err := f.resetRepo.Create(&models.PasswordReset{
+		UserID:    f.user.ID,
+		Token:     hashToken(token),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := f.service.ResetPassword(token, "new-password-1", models.DeviceInfo{})
+	if err == nil || err.Error() != "invalid or expired reset token" {
+		t.Fatalf("expected expired token error, got %v", err)
+	}
+	if _, err := f.resetRepo.GetByToken(hashToken(token)); err == nil {
+		t.Fatal("expected expired token to be deleted")
+	}
+}
+
+func TestAuthService_ResetPassword_RejectsWeakPassword(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	if err := f.service.RequestPasswordReset(context.Background(), f.user.Email); err != nil {
+		t.Fatal(err)
+	}
+	msg := f.notifier.last(t)
+
+	if err := f.service.ResetPassword(msg.Token, "123", models.DeviceInfo{}); err == nil {
+		t.Fatal("expected short password to be rejected")
+	}
+	// A validation failure must not burn the token.
+	if err := f.service.ResetPassword(msg.Token, "long-enough", models.DeviceInfo{}); err != nil {
+		t.Fatalf("expected token to remain usable, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/icl00ud/velure/shared/auth"
+	"github.com/icl00ud/velure/shared/logger"
+)
+
+// checkRevoked rejects access tokens on the revocation list. When Redis
+// cannot be asked the token is accepted: it still expires on its own, and
+// refusing every token would turn a Redis outage into a full one.
+func (s *AuthService) checkRevoked(claims *auth.Claims) error {
+	err := s.revocations.Check(context.Background(), claims)
+	if errors.Is(err, auth.ErrTokenRevoked) {
+		return err
+	}
+	if err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		logger.Warn("token revocation check failed", logger.Err(err))
+	}
+	return nil
+}
+
+// sessionsWithLiveTokens returns the user's sessions whose access tokens may
+// not have expired yet, including rotated ones.
+func (s *AuthService) sessionsWithLiveTokens(userID uint) ([]models.Session, error) {
+	since := time.Now().Add(-parseExpiry(s.config.JWT.ExpiresIn, time.Hour))
+	sessions, err := s.sessionRepo.ListIssuedSince(userID, since)
+	if err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error listing sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// revokeAccessTokens puts the access tokens of sessions that were ended early
+// on the revocation list, so every service stops accepting them, and drops
+// them from the token cache of every replica. Failures are only logged: the
+// sessions are already gone and the tokens expire on their own.
+func (s *AuthService) revokeAccessTokens(sessions ...models.Session) {
+	tokens := make([]string, len(sessions))
+	for i, session := range sessions {
+		tokens[i] = session.AccessToken
+	}
+	s.evictCachedAccessTokens(tokens...)
+
+	ctx := context.Background()
+	for _, session := range sessions {
+		if s.revocations == nil {
+			continue
+		}
+
+		// The token was signed here; only its jti and expiry are needed.
+		claims := &auth.Claims{}
+		if _, _, err := jwt.NewParser().ParseUnverified(session.AccessToken, claims); err != nil || claims.ExpiresAt == nil {
+			continue
+		}
+		if err := s.revocations.Revoke(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			metrics.Errors.WithLabelValues("internal").Inc()
+			logger.Warn("failed to revoke access token", logger.Uint("session_id", session.ID), logger.Err(err))
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+
+	"github.com/icl00ud/velure/shared/auth"
+	"github.com/redis/go-redis/v9"
+)
+
+func loginFixtureUser(t *testing.T, f *passwordResetFixture) *models.LoginResponse {
+	t.Helper()
+	resp, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "password123"})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	return resp
+}
+
+func TestAuthService_Logout_RevokesAccessToken(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.config.Performance.EnableCache = true
+	session := loginFixtureUser(t, f)
+
+	if _, err := f.service.ValidateAccessToken(session.AccessToken); err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+	if err := f.service.Logout(session.RefreshToken, models.DeviceInfo{}); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+
+	if _, err := f.service.ValidateAccessToken(session.AccessToken); err == nil || err.Error() != "token revoked" {
+		t.Fatalf("expected the logged-out token to be revoked, got %v", err)
+	}
+	// Another replica may still have the token cached.
+	f.service.tokenCache.Set(hashToken(session.AccessToken), f.user)
+	if _, err := f.service.ValidateAccessToken(session.AccessToken); err == nil || err.Error() != "token revoked" {
+		t.Fatalf("expected the cached token to be revoked, got %v", err)
+	}
+
+	// Other services verify offline and see the same list.
+	claims, err := auth.Parse(session.AccessToken, f.service.keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(&redis.Options{Addr: f.redis.Addr()})
+	defer client.Close()
+	if err := auth.NewRevocationList(client).Check(context.Background(), claims); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Fatalf("expected the shared list to report the token, got %v", err)
+	}
+}
+
+func TestAuthService_PasswordChange_RevokesOtherTokens(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	current := loginFixtureUser(t, f)
+	other := loginFixtureUser(t, f)
+
+	if _, err := f.service.UpdateProfile(f.user.ID, current.AccessToken, models.UpdateProfileRequest{
+		CurrentPassword: "password123",
+		Password:        strPtr("new-password-1"),
+	}); err != nil {
+		t.Fatalf("UpdateProfile() error = %v", err)
+	}
+
+	if _, err := f.service.ValidateAccessToken(current.AccessToken); err != nil {
+		t.Fatalf("the device changing the password stays signed in, got %v", err)
+	}
+	if _, err := f.service.ValidateAccessToken(other.AccessToken); err == nil || err.Error() != "token revoked" {
+		t.Fatalf("expected the other device's token to be revoked, got %v", err)
+	}
+}
+
+func TestAuthService_RefreshReuse_RevokesFamilyAccessTokens(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.config.Performance.EnableCache = true
+	session := loginFixtureUser(t, f)
+	otherDevice := loginFixtureUser(t, f)
+
+	refreshed, err := f.service.RefreshSession(session.RefreshToken, models.DeviceInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.ValidateAccessToken(refreshed.AccessToken); err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+
+	if _, err := f.service.RefreshSession(session.RefreshToken, models.DeviceInfo{}); err == nil || err.Error() != "refresh token reuse detected" {
+		t.Fatalf("expected reuse detection, got %v", err)
+	}
+
+	for _, token := range []string{session.AccessToken, refreshed.AccessToken} {
+		if _, err := f.service.ValidateAccessToken(token); err == nil || err.Error() != "token revoked" {
+			t.Fatalf("expected every access token of the family to be revoked, got %v", err)
+		}
+	}
+	if _, err := f.service.ValidateAccessToken(otherDevice.AccessToken); err != nil {
+		t.Fatalf("other logins are not part of the family, got %v", err)
+	}
+}
+
+func TestAuthService_ResetPassword_RevokesAccessTokens(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	session := loginFixtureUser(t, f)
+
+	// A refreshed session leaves the old access token valid until it expires.
+	refreshed, err := f.service.RefreshSession(session.RefreshToken, models.DeviceInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.service.RequestPasswordReset(context.Background(), f.user.Email); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.service.ResetPassword(f.notifier.last(t).Token, "new-password-1", models.DeviceInfo{}); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+
+	for _, token := range []string{session.AccessToken, refreshed.AccessToken} {
+		if _, err := f.service.ValidateAccessToken(token); err == nil || err.Error() != "token revoked" {
+			t.Fatalf("expected every access token to be revoked, got %v", err)
+		}
+	}
+}
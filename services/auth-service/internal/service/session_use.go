@@ -0,0 +1,37 @@
+package services
+
+import (
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/cache"
+	"github.com/icl00ud/velure/services/auth-service/internal/config"
+	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
+
+	"github.com/icl00ud/velure/shared/logger"
+)
+
+// sessionUseInterval is how often a session's last use is written: a token
+// used on every request would otherwise cost a write per request.
+const sessionUseInterval = time.Minute
+
+// newSessionUseCache returns the hashes of access tokens whose use was
+// recorded within the last sessionUseInterval on this replica.
+func newSessionUseCache(cfg *config.Config) *cache.LRU[struct{}] {
+	return cache.New[struct{}]("session_use", cfg.Performance.TokenCacheSize, sessionUseInterval)
+}
+
+// recordSessionUse advances the last use of the session holding token. The
+// in-process cache skips most writes, and the repository skips the rest when
+// another replica recorded a use less than sessionUseInterval ago. A failure
+// is only logged: the token was valid either way.
+func (s *AuthService) recordSessionUse(token, cacheKey string) {
+	if _, ok := s.sessionUses.Get(cacheKey); ok {
+		return
+	}
+	s.sessionUses.Set(cacheKey, struct{}{})
+
+	if err := s.sessionRepo.Touch(token, time.Now(), sessionUseInterval); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		logger.Warn("failed to record session use", logger.Err(err))
+	}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/cache"
+	"github.com/icl00ud/velure/services/auth-service/internal/config"
+	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+
+	"github.com/icl00ud/velure/shared/logger"
+)
+
+// tokenCacheName names the validated token cache in metrics and on the bus.
+const tokenCacheName = "token"
+
+// newTokenCache returns the cache of users behind validated access tokens,
+// keyed by the token's hash so tokens never leave the process in
+// invalidations.
+func newTokenCache(cfg *config.Config) *cache.LRU[*models.User] {
+	ttl := time.Duration(cfg.Performance.TokenCacheTTL) * time.Second
+	return cache.New[*models.User](tokenCacheName, cfg.Performance.TokenCacheSize, ttl)
+}
+
+// AttachCacheBus shares token cache invalidations with the other replicas.
+// Without it a logout or account change only evicts this replica's entries
+// and the others serve them until TokenCacheTTL runs out. The caller runs
+// the bus.
+func (s *AuthService) AttachCacheBus(bus *cache.Bus) {
+	s.cacheBus = bus
+	bus.Handle(tokenCacheName, s.applyTokenInvalidation)
+}
+
+func (s *AuthService) applyTokenInvalidation(inv cache.Invalidation) {
+	switch {
+	case inv.All:
+		s.tokenCache.DeleteFunc(func(string, *models.User) bool { return true })
+	case inv.UserID != 0:
+		s.tokenCache.DeleteFunc(func(_ string, user *models.User) bool { return user.ID == inv.UserID })
+	default:
+		s.tokenCache.Delete(inv.Keys...)
+	}
+}
+
+// invalidateTokenCache applies inv here and publishes it to the other
+// replicas. A failed publish is logged: those replicas catch up when their
+// entries expire.
+func (s *AuthService) invalidateTokenCache(inv cache.Invalidation) {
+	inv.Cache = tokenCacheName
+	s.applyTokenInvalidation(inv)
+	if err := s.cacheBus.Publish(context.Background(), inv); err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		logger.Warn("failed to publish token cache invalidation", logger.Err(err))
+	}
+}
+
+// evictCachedTokens drops the user's validated tokens from every replica's
+// cache so a changed, disabled or deleted account is not served from it.
+func (s *AuthService) evictCachedTokens(userID uint) {
+	s.invalidateTokenCache(cache.Invalidation{UserID: userID})
+}
+
+// evictCachedAccessTokens drops the given access tokens from every
+// replica's cache.
+func (s *AuthService) evictCachedAccessTokens(tokens ...string) {
+	if len(tokens) == 0 {
+		return
+	}
+	keys := make([]string, len(tokens))
+	for i, token := range tokens {
+		keys[i] = hashToken(token)
+	}
+	s.invalidateTokenCache(cache.Invalidation{Keys: keys})
+}
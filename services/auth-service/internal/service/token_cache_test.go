@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/cache"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/repository"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// newReplica returns a second AuthService sharing the fixture's database,
+// Redis and signing keys, with both joined to the cache bus.
+func newReplica(t *testing.T, f *passwordResetFixture) *AuthService {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	client := redis.NewClient(&redis.Options{Addr: f.redis.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	replica := NewAuthService(
+		repositories.NewUserRepository(f.db),
+		repositories.NewSessionRepository(f.db),
+		repositories.NewPasswordResetRepository(f.db),
+		repositories.NewEmailVerificationRepository(f.db),
+		f.service.config,
+		client,
+	)
+	replica.AttachKeyRing(f.service.keys)
+
+	for _, s := range []*AuthService{f.service, replica} {
+		bus := cache.NewBus(client)
+		s.AttachCacheBus(bus)
+		go bus.Run(ctx)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for f.redis.PubSubNumSub(cache.Channel)[cache.Channel] < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("cache buses did not subscribe")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return replica
+}
+
+func waitForEviction(t *testing.T, s *AuthService, token string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, ok := s.tokenCache.Get(hashToken(token)); !ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the token to be evicted on the other replica")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAuthService_TokenCache_InvalidatesEveryReplica(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.config.Performance.EnableCache = true
+	replica := newReplica(t, f)
+
+	first := loginFixtureUser(t, f)
+	second := loginFixtureUser(t, f)
+	for _, s := range []*AuthService{f.service, replica} {
+		for _, token := range []string{first.AccessToken, second.AccessToken} {
+			if _, err := s.ValidateAccessToken(token); err != nil {
+				t.Fatalf("ValidateAccessToken() error = %v", err)
+			}
+		}
+	}
+	if _, ok := replica.tokenCache.Get(hashToken(first.AccessToken)); !ok {
+		t.Fatal("expected the replica to cache the token")
+	}
+
+	// Logging out on one replica evicts the token on the other.
+	if err := f.service.Logout(first.RefreshToken, models.DeviceInfo{}); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	waitForEviction(t, replica, first.AccessToken)
+	if _, ok := replica.tokenCache.Get(hashToken(second.AccessToken)); !ok {
+		t.Fatal("other sessions must stay cached")
+	}
+
+	// Account changes evict every token of the user.
+	if _, err := f.service.UpdateProfile(f.user.ID, "", models.UpdateProfileRequest{Name: strPtr("New Name")}); err != nil {
+		t.Fatalf("UpdateProfile() error = %v", err)
+	}
+	waitForEviction(t, replica, second.AccessToken)
+	user, err := replica.ValidateAccessToken(second.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+	if user.Name != "New Name" {
+		t.Fatalf("expected the replica to reload the user, got %q", user.Name)
+	}
+}
@@ -0,0 +1,368 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/lockout"
+	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/totp"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	// twoFactorChallengeAudience keeps challenge tokens from being accepted
+	// anywhere else, and other tokens from being accepted as challenges.
+	twoFactorChallengeAudience = "two-factor-challenge"
+
+	// totpSkew is how many 30-second steps of clock drift are tolerated.
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+)
+
+// EnrollTwoFactor starts enrollment by generating a new TOTP secret. It is
+// not enforced until ConfirmTwoFactor has seen a code from it, so an
+// abandoned enrollment cannot lock the user out. Starting over replaces a
+// pending secret.
+func (s *AuthService) EnrollTwoFactor(userID uint) (*models.TwoFactorEnrollmentResponse, error) {
+	user, err := s.getUserForTwoFactor(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return nil, fmt.Errorf("error generating two-factor secret: %w", err)
+	}
+
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	if err := s.userRepo.Update(user); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error saving two-factor secret: %w", err)
+	}
+
+	metrics.TwoFactorEvents.WithLabelValues("enrolled").Inc()
+	return &models.TwoFactorEnrollmentResponse{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(s.config.TwoFactor.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables two-factor authentication once code proves the
+// authenticator app holds the enrolled secret, and returns the recovery codes.
+func (s *AuthService) ConfirmTwoFactor(userID uint, code string) (*models.RecoveryCodesResponse, error) {
+	user, err := s.getUserForTwoFactor(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.New("two-factor authentication not enrolled")
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), totpSkew)
+	if !ok {
+		metrics.TwoFactorEvents.WithLabelValues("invalid_code").Inc()
+		return nil, errors.New("invalid two-factor code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return nil, fmt.Errorf("error generating recovery codes: %w", err)
+	}
+
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+	user.RecoveryCodes = hashes
+	if err := s.userRepo.Update(user); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error enabling two-factor authentication: %w", err)
+	}
+	// Cached entries drive Login and would still say 2FA is off.
+	s.invalidateUserCache(context.Background(), user)
+
+	metrics.TwoFactorEvents.WithLabelValues("enabled").Inc()
+	return &models.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off. A current code or a
+// recovery code is required so a hijacked session alone cannot remove it.
+func (s *AuthService) DisableTwoFactor(userID uint, code string) error {
+	user, err := s.getUserForTwoFactor(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return errors.New("two-factor authentication not enabled")
+	}
+	if !s.verifySecondFactor(user, code) {
+		return errors.New("invalid two-factor code")
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	user.RecoveryCodes = nil
+	if err := s.userRepo.Update(user); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return fmt.Errorf("error disabling two-factor authentication: %w", err)
+	}
+	s.invalidateUserCache(context.Background(), user)
+
+	metrics.TwoFactorEvents.WithLabelValues("disabled").Inc()
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code, used or not.
+func (s *AuthService) RegenerateRecoveryCodes(userID uint, code string) (*models.RecoveryCodesResponse, error) {
+	user, err := s.getUserForTwoFactor(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication not enabled")
+	}
+	if !s.verifySecondFactor(user, code) {
+		return nil, errors.New("invalid two-factor code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return nil, fmt.Errorf("error generating recovery codes: %w", err)
+	}
+
+	user.RecoveryCodes = hashes
+	if err := s.userRepo.Update(user); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error saving recovery codes: %w", err)
+	}
+
+	return &models.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// CompleteTwoFactorLogin is the second step of a login for accounts with
+// two-factor authentication: it exchanges the challenge from Login plus a
+// TOTP or recovery code for the session token pair. Wrong codes count
+// towards the same lockout as wrong passwords.
+func (s *AuthService) CompleteTwoFactorLogin(req models.TwoFactorLoginRequest) (*models.LoginResponse, error) {
+	start := time.Now()
+	var status string
+	defer func() {
+		metrics.LoginDuration.WithLabelValues(status).Observe(time.Since(start).Seconds())
+		metrics.LoginAttempts.WithLabelValues(status).Inc()
+	}()
+
+	ctx := context.Background()
+
+	userID, err := s.parseTwoFactorChallenge(req.ChallengeToken)
+	if err != nil {
+		status = "failure"
+		return nil, errors.New("invalid or expired challenge")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		status = "failure"
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid or expired challenge")
+		}
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+	// Disabled since the challenge was issued.
+	if !user.TOTPEnabled {
+		status = "failure"
+		return nil, errors.New("invalid or expired challenge")
+	}
+	if user.Disabled() {
+		status = "disabled"
+		return nil, errors.New("account disabled")
+	}
+
+	account := lockout.NormalizeAccount(user.Email)
+	if err := s.loginGuard.Check(ctx, account, req.Device.IPAddress); err != nil {
+		status = "locked"
+		s.recordAudit(models.AuditLoginFailed, user.ID, user.Email, req.Device, err.Error())
+		return nil, err
+	}
+
+	if !s.verifySecondFactor(user, req.Code) {
+		status = "failure"
+		return nil, s.loginFailed(ctx, account, user.ID, req.Device, errors.New("invalid two-factor code"))
+	}
+	s.loginGuard.Succeed(ctx, account)
+
+	session, err := s.createSession(user, req.Device)
+	if err != nil {
+		status = "failure"
+		metrics.Errors.WithLabelValues("internal").Inc()
+		return nil, fmt.Errorf("error creating session: %w", err)
+	}
+
+	status = "success"
+	s.recordAudit(models.AuditLoginSucceeded, user.ID, user.Email, req.Device, "two_factor")
+	return &models.LoginResponse{
+		AccessToken:  session.AccessToken,
+		RefreshToken: session.RefreshToken,
+	}, nil
+}
+
+func (s *AuthService) getUserForTwoFactor(userID uint) (*models.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+	return user, nil
+}
+
+// verifySecondFactor accepts a TOTP code that has not been used before or an
+// unused recovery code, and records its use on the user. A failure to record
+// the use rejects the code: accepting it would allow replaying it.
+func (s *AuthService) verifySecondFactor(user *models.User, code string) bool {
+	if step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), totpSkew); ok {
+		if step <= user.TOTPLastStep {
+			metrics.TwoFactorEvents.WithLabelValues("invalid_code").Inc()
+			return false
+		}
+		user.TOTPLastStep = step
+		if err := s.userRepo.Update(user); err != nil {
+			metrics.Errors.WithLabelValues("database").Inc()
+			return false
+		}
+		metrics.TwoFactorEvents.WithLabelValues("verified").Inc()
+		return true
+	}
+
+	hash := hashRecoveryCode(code)
+	for i, stored := range user.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(hash)) != 1 {
+			continue
+		}
+		remaining := make(models.RecoveryCodes, 0, len(user.RecoveryCodes)-1)
+		remaining = append(remaining, user.RecoveryCodes[:i]...)
+		remaining = append(remaining, user.RecoveryCodes[i+1:]...)
+		user.RecoveryCodes = remaining
+		if err := s.userRepo.Update(user); err != nil {
+			metrics.Errors.WithLabelValues("database").Inc()
+			return false
+		}
+		metrics.TwoFactorEvents.WithLabelValues("recovery_code_used").Inc()
+		return true
+	}
+
+	metrics.TwoFactorEvents.WithLabelValues("invalid_code").Inc()
+	return false
+}
+
+// issueTwoFactorChallenge returns a short-lived token proving the password
+// step succeeded. It is signed with a key of its own so it is useless as an
+// access or refresh token.
+func (s *AuthService) issueTwoFactorChallenge(userID uint) (string, error) {
+	tokenID, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	ttl := s.config.TwoFactor.ChallengeTTL
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	claims := jwt.RegisteredClaims{
+		ID:        tokenID,
+		Subject:   strconv.FormatUint(uint64(userID), 10),
+		Audience:  jwt.ClaimStrings{twoFactorChallengeAudience},
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(s.twoFactorChallengeSecret()))
+}
+
+// parseTwoFactorChallenge verifies a challenge token and returns the user it
+// was issued to.
+func (s *AuthService) parseTwoFactorChallenge(challenge string) (uint, error) {
+	claims := &jwt.RegisteredClaims{}
+	parsedToken, err := jwt.ParseWithClaims(challenge, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.twoFactorChallengeSecret()), nil
+	}, jwt.WithAudience(twoFactorChallengeAudience))
+	if err != nil || !parsedToken.Valid {
+		return 0, errors.New("invalid token")
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		return 0, errors.New("invalid user ID in token")
+	}
+	return uint(userID), nil
+}
+
+func (s *AuthService) twoFactorChallengeSecret() string {
+	return s.config.JWT.Secret + s.config.Session.Secret
+}
+
+// recoveryCodeAlphabet has 32 characters, so every random byte maps onto it
+// without bias, and leaves out i, l, o and 1, which are easily confused when
+// copied by hand.
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz023456789"
+
+// generateRecoveryCodes returns the codes to show the user, formatted as
+// xxxxx-xxxxx, and the hashes to store.
+func generateRecoveryCodes() ([]string, models.RecoveryCodes, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make(models.RecoveryCodes, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		for j := range b {
+			b[j] = recoveryCodeAlphabet[b[j]&31]
+		}
+		codes[i] = string(b[:5]) + "-" + string(b[5:])
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes so codes can be typed the
+// way they were written down.
+func hashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/lockout"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/totp"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// enableTwoFactor enrolls and confirms the fixture user and returns the
+// secret, the step of the confirming code and the recovery codes.
+func enableTwoFactor(t *testing.T, f *passwordResetFixture) (string, int64, []string) {
+	t.Helper()
+
+	enrollment, err := f.service.EnrollTwoFactor(f.user.ID)
+	if err != nil {
+		t.Fatalf("EnrollTwoFactor() error = %v", err)
+	}
+	step := totp.Step(time.Now())
+	code, _ := totp.Code(enrollment.Secret, step)
+	codes, err := f.service.ConfirmTwoFactor(f.user.ID, code)
+	if err != nil {
+		t.Fatalf("ConfirmTwoFactor() error = %v", err)
+	}
+	return enrollment.Secret, step, codes.RecoveryCodes
+}
+
+func TestAuthService_EnrollTwoFactor(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	enrollment, err := f.service.EnrollTwoFactor(f.user.ID)
+	if err != nil {
+		t.Fatalf("EnrollTwoFactor() error = %v", err)
+	}
+	uri, err := url.Parse(enrollment.OTPAuthURI)
+	if err != nil || uri.Scheme != "otpauth" || uri.Query().Get("secret") != enrollment.Secret {
+		t.Fatalf("unexpected otpauth URI %q", enrollment.OTPAuthURI)
+	}
+	if !strings.Contains(uri.Path, f.user.Email) {
+		t.Fatalf("expected the account email in the label, got %q", uri.Path)
+	}
+
+	// A pending enrollment is not enforced yet.
+	login, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "password123"})
+	if err != nil || login.TwoFactorRequired || login.AccessToken == "" {
+		t.Fatalf("expected a normal login before confirmation, got %+v, %v", login, err)
+	}
+
+	if _, err := f.service.ConfirmTwoFactor(f.user.ID, "000000"); err == nil || err.Error() != "invalid two-factor code" {
+		t.Fatalf("expected wrong code to be rejected, got %v", err)
+	}
+}
+
+func TestAuthService_ConfirmTwoFactor_RequiresEnrollment(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	if _, err := f.service.ConfirmTwoFactor(f.user.ID, "123456"); err == nil || err.Error() != "two-factor authentication not enrolled" {
+		t.Fatalf("expected not enrolled error, got %v", err)
+	}
+
+	enableTwoFactor(t, f)
+	if _, err := f.service.EnrollTwoFactor(f.user.ID); err == nil || err.Error() != "two-factor authentication already enabled" {
+		t.Fatalf("expected already enabled error, got %v", err)
+	}
+}
+
+func TestAuthService_TwoFactorLogin(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	// Cache the user first: enabling 2FA must not be bypassed by a stale entry.
+	if _, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "password123"}); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	secret, step, recoveryCodes := enableTwoFactor(t, f)
+	if len(recoveryCodes) != recoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %d", recoveryCodeCount, len(recoveryCodes))
+	}
+
+	login, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if !login.TwoFactorRequired || login.ChallengeToken == "" || login.AccessToken != "" || login.RefreshToken != "" {
+		t.Fatalf("expected a challenge and no tokens, got %+v", login)
+	}
+
+	// The challenge is not an access token.
+	if _, err := f.service.ValidateAccessToken(login.ChallengeToken); err == nil {
+		t.Fatal("challenge token must not validate as an access token")
+	}
+
+	complete := func(challenge, code string) (*models.LoginResponse, error) {
+		return f.service.CompleteTwoFactorLogin(models.TwoFactorLoginRequest{ChallengeToken: challenge, Code: code})
+	}
+
+	if _, err := complete("not-a-challenge", "123456"); err == nil || err.Error() != "invalid or expired challenge" {
+		t.Fatalf("expected invalid challenge, got %v", err)
+	}
+	// The code used for confirmation cannot be replayed.
+	used, _ := totp.Code(secret, step)
+	if _, err := complete(login.ChallengeToken, used); err == nil || err.Error() != "invalid two-factor code" {
+		t.Fatalf("expected replayed code to be rejected, got %v", err)
+	}
+
+	next, _ := totp.Code(secret, step+1)
+	tokens, err := complete(login.ChallengeToken, next)
+	if err != nil {
+		t.Fatalf("CompleteTwoFactorLogin() error = %v", err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" || tokens.TwoFactorRequired {
+		t.Fatalf("expected a token pair, got %+v", tokens)
+	}
+	if _, err := f.service.ValidateAccessToken(tokens.AccessToken); err != nil {
+		t.Fatalf("issued access token is invalid: %v", err)
+	}
+
+	// Recovery codes work once, in any case and with or without the dash.
+	code := strings.ToUpper(strings.ReplaceAll(recoveryCodes[0], "-", ""))
+	if _, err := complete(login.ChallengeToken, code); err != nil {
+		t.Fatalf("recovery code rejected: %v", err)
+	}
+	if _, err := complete(login.ChallengeToken, recoveryCodes[0]); err == nil {
+		t.Fatal("recovery code must be single-use")
+	}
+	user, _ := f.service.userRepo.GetByID(f.user.ID)
+	if len(user.RecoveryCodes) != recoveryCodeCount-1 {
+		t.Fatalf("expected %d recovery codes left, got %d", recoveryCodeCount-1, len(user.RecoveryCodes))
+	}
+	for _, stored := range user.RecoveryCodes {
+		for _, plain := range recoveryCodes {
+			if stored == plain {
+				t.Fatal("recovery codes must be stored hashed")
+			}
+		}
+	}
+}
+
+func TestAuthService_TwoFactorLogin_ExpiredChallenge(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.config.TwoFactor.ChallengeTTL = time.Millisecond
+	enableTwoFactor(t, f)
+
+	login, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	time.Sleep(1100 * time.Millisecond) // JWT expiry has second precision
+
+	_, err = f.service.CompleteTwoFactorLogin(models.TwoFactorLoginRequest{ChallengeToken: login.ChallengeToken, Code: "123456"})
+	if err == nil || err.Error() != "invalid or expired challenge" {
+		t.Fatalf("expected expired challenge, got %v", err)
+	}
+}
+
+func TestAuthService_TwoFactorLogin_WrongCodesLockOut(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	client := redis.NewClient(&redis.Options{Addr: f.redis.Addr()})
+	defer client.Close()
+	f.service.AttachLoginGuard(lockout.New(client, lockout.Config{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+	enableTwoFactor(t, f)
+
+	var locked *lockout.LockedError
+	for i := 0; i < 3; i++ {
+		// Each correct password hands out a challenge without resetting the
+		// failure count.
+		login, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "password123"})
+		if err != nil {
+			t.Fatalf("attempt %d: Login() error = %v", i+1, err)
+		}
+		_, err = f.service.CompleteTwoFactorLogin(models.TwoFactorLoginRequest{ChallengeToken: login.ChallengeToken, Code: "000000"})
+		if i < 2 && (err == nil || err.Error() != "invalid two-factor code") {
+			t.Fatalf("attempt %d: expected invalid code, got %v", i+1, err)
+		}
+		if i == 2 && !errors.As(err, &locked) {
+			t.Fatalf("expected the third wrong code to lock the account, got %v", err)
+		}
+	}
+
+	if _, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "password123"}); !errors.As(err, &locked) {
+		t.Fatalf("expected locked account, got %v", err)
+	}
+}
+
+func TestAuthService_DisableTwoFactor(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	if err := f.service.DisableTwoFactor(f.user.ID, "123456"); err == nil || err.Error() != "two-factor authentication not enabled" {
+		t.Fatalf("expected not enabled error, got %v", err)
+	}
+
+	secret, step, _ := enableTwoFactor(t, f)
+	if err := f.service.DisableTwoFactor(f.user.ID, "000000"); err == nil || err.Error() != "invalid two-factor code" {
+		t.Fatalf("expected wrong code to be rejected, got %v", err)
+	}
+
+	code, _ := totp.Code(secret, step+1)
+	if err := f.service.DisableTwoFactor(f.user.ID, code); err != nil {
+		t.Fatalf("DisableTwoFactor() error = %v", err)
+	}
+
+	login, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "password123"})
+	if err != nil || login.TwoFactorRequired || login.AccessToken == "" {
+		t.Fatalf("expected a normal login after disabling, got %+v, %v", login, err)
+	}
+}
+
+func TestAuthService_RegenerateRecoveryCodes(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	secret, step, old := enableTwoFactor(t, f)
+
+	code, _ := totp.Code(secret, step+1)
+	fresh, err := f.service.RegenerateRecoveryCodes(f.user.ID, code)
+	if err != nil {
+		t.Fatalf("RegenerateRecoveryCodes() error = %v", err)
+	}
+	if len(fresh.RecoveryCodes) != recoveryCodeCount || fresh.RecoveryCodes[0] == old[0] {
+		t.Fatalf("expected a new set of codes, got %v", fresh.RecoveryCodes)
+	}
+
+	user, _ := f.service.userRepo.GetByID(f.user.ID)
+	if f.service.verifySecondFactor(user, old[1]) {
+		t.Fatal("old recovery codes must stop working")
+	}
+	if !f.service.verifySecondFactor(user, fresh.RecoveryCodes[1]) {
+		t.Fatal("new recovery codes must work")
+	}
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/lockout"
+	"github.com/icl00ud/velure/services/auth-service/internal/metrics"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+
+	"github.com/icl00ud/velure/shared/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 200
+)
+
+// userCursor is the position after the last user of a search page: its ID
+// and the value of the sorted field. The sort is kept too so a cursor
+// cannot be replayed against a different order.
+type userCursor struct {
+	Sort  string `json:"s"`
+	ID    uint   `json:"id"`
+	Value string `json:"v"`
+}
+
+// parseUserSort reads a sort such as "email" or "-createdAt" (descending).
+// The default is newest first.
+func parseUserSort(sort string) (models.UserSort, error) {
+	if sort == "" {
+		return models.UserSort{Field: models.UserSortCreatedAt, Desc: true}, nil
+	}
+	field, desc := strings.CutPrefix(sort, "-")
+	switch field {
+	case models.UserSortCreatedAt, models.UserSortEmail, models.UserSortName:
+		return models.UserSort{Field: field, Desc: desc}, nil
+	}
+	return models.UserSort{}, errors.New("invalid sort")
+}
+
+func encodeUserCursor(sort string, user *models.User) string {
+	cursor := userCursor{Sort: sort, ID: user.ID}
+	switch sort {
+	case models.UserSortEmail, "-" + models.UserSortEmail:
+		cursor.Value = user.Email
+	case models.UserSortName, "-" + models.UserSortName:
+		cursor.Value = user.Name
+	default:
+		cursor.Value = user.CreatedAt.Format(time.RFC3339Nano)
+	}
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeUserCursor turns a cursor back into the partial user the repository
+// continues after.
+func decodeUserCursor(sort, field, value string) (*models.User, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var cursor userCursor
+	if json.Unmarshal(raw, &cursor) != nil || cursor.Sort != sort || cursor.ID == 0 {
+		return nil, errors.New("invalid cursor")
+	}
+	after := &models.User{ID: cursor.ID}
+	switch field {
+	case models.UserSortEmail:
+		after.Email = cursor.Value
+	case models.UserSortName:
+		after.Name = cursor.Value
+	default:
+		if after.CreatedAt, err = time.Parse(time.RFC3339Nano, cursor.Value); err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+	}
+	return after, nil
+}
+
+// SearchUsers returns one page of users matching filter for support staff.
+// sort is a field name, prefixed with "-" for descending order; cursor is
+// the NextCursor of the previous page, empty for the first.
+func (s *AuthService) SearchUsers(filter models.UserFilter, sort, cursor string, limit int) (*models.UserSearchPage, error) {
+	metrics.UserQueries.WithLabelValues("search").Inc()
+
+	order, err := parseUserSort(sort)
+	if err != nil {
+		return nil, err
+	}
+	if sort == "" {
+		sort = "-" + models.UserSortCreatedAt
+	}
+	var after *models.User
+	if cursor != "" {
+		if after, err = decodeUserCursor(sort, order.Field, cursor); err != nil {
+			return nil, err
+		}
+	}
+	if limit <= 0 {
+		limit = defaultUserPageSize
+	}
+	limit = min(limit, maxUserPageSize)
+
+	// Lockouts only exist in Redis. Without them the locked flags are
+	// unknown, which is only an error when filtering on them.
+	locked, err := s.loginGuard.LockedAccounts(context.Background())
+	if err != nil {
+		if filter.Locked != nil {
+			metrics.Errors.WithLabelValues("internal").Inc()
+			return nil, fmt.Errorf("error listing locked accounts: %w", err)
+		}
+		logger.Warn("failed to list locked accounts", logger.Err(err))
+	}
+	filter.LockedAccounts = locked
+
+	// One extra row tells whether another page follows.
+	users, err := s.userRepo.Search(filter, order, after, limit+1)
+	if err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error searching users: %w", err)
+	}
+
+	page := &models.UserSearchPage{Users: make([]models.AdminUserResponse, 0, min(len(users), limit))}
+	if len(users) > limit {
+		users = users[:limit]
+		page.NextCursor = encodeUserCursor(sort, &users[limit-1])
+	}
+	isLocked := make(map[string]bool, len(locked))
+	for _, account := range locked {
+		isLocked[account] = true
+	}
+	for i := range users {
+		page.Users = append(page.Users, models.AdminUserResponse{
+			UserResponse: users[i].ToResponse(),
+			Locked:       isLocked[lockout.NormalizeAccount(users[i].Email)],
+		})
+	}
+	return page, nil
+}
+
+// DisableUser stops an account from being used: it cannot sign in, every
+// session is ended and every access token it holds is revoked at once,
+// including impersonation tokens issued for it or, for an admin, by it.
+// Disabling an already disabled account changes nothing.
+func (s *AuthService) DisableUser(adminID, userID uint, reason string, device models.DeviceInfo) (*models.UserResponse, error) {
+	if adminID == userID {
+		return nil, errors.New("cannot disable your own account")
+	}
+	user, err := s.getUserForAdmin(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Disabled() {
+		response := user.ToResponse()
+		return &response, nil
+	}
+
+	sessions, err := s.sessionsWithLiveTokens(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	user.DisabledAt = &now
+	if err := s.userRepo.Update(user); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error disabling user: %w", err)
+	}
+	if err := s.sessionRepo.InvalidateByUserID(user.ID); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error invalidating sessions: %w", err)
+	}
+
+	s.invalidateUserCache(context.Background(), user)
+	s.evictCachedTokens(user.ID)
+	s.revokeAccessTokens(sessions...)
+	s.revokeImpersonations(user.ID)
+	s.recordAudit(models.AuditUserDisabled, user.ID, user.Email, device, adminAuditDetail(adminID, reason))
+	s.SyncActiveSessionsMetric(context.Background())
+
+	response := user.ToResponse()
+	return &response, nil
+}
+
+// EnableUser lets a disabled account sign in again and lifts any login
+// lockout it has. Its old sessions stay ended.
+func (s *AuthService) EnableUser(adminID, userID uint, device models.DeviceInfo) (*models.UserResponse, error) {
+	user, err := s.getUserForAdmin(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if user.Disabled() {
+		user.DisabledAt = nil
+		if err := s.userRepo.Update(user); err != nil {
+			metrics.Errors.WithLabelValues("database").Inc()
+			return nil, fmt.Errorf("error enabling user: %w", err)
+		}
+		s.invalidateUserCache(ctx, user)
+		s.recordAudit(models.AuditUserEnabled, user.ID, user.Email, device, adminAuditDetail(adminID, ""))
+	}
+	s.loginGuard.Unlock(ctx, lockout.NormalizeAccount(user.Email))
+
+	response := user.ToResponse()
+	return &response, nil
+}
+
+func (s *AuthService) getUserForAdmin(userID uint) (*models.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+	return user, nil
+}
+
+// adminAuditDetail names the admin behind an account change, followed by
+// the reason they gave.
+func adminAuditDetail(adminID uint, reason string) string {
+	detail := fmt.Sprintf("admin %d", adminID)
+	if reason = strings.TrimSpace(reason); reason != "" {
+		detail += ": " + reason
+	}
+	return detail
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/lockout"
+	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/repository"
+	"github.com/icl00ud/velure/services/auth-service/internal/testutil"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestAuthService_DisableUser(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	f.service.config.Performance.EnableCache = true
+	f.service.AttachAuditLog(repositories.NewAuditEventRepository(f.db))
+	const adminID = 999
+	session := loginFixtureUser(t, f)
+	if _, err := f.service.ValidateAccessToken(session.AccessToken); err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+
+	if _, err := f.service.DisableUser(f.user.ID, f.user.ID, "", models.DeviceInfo{}); err == nil || err.Error() != "cannot disable your own account" {
+		t.Fatalf("expected self-disable to be refused, got %v", err)
+	}
+	if _, err := f.service.DisableUser(adminID, 12345, "", models.DeviceInfo{}); err == nil || err.Error() != "user not found" {
+		t.Fatalf("expected user not found, got %v", err)
+	}
+
+	resp, err := f.service.DisableUser(adminID, f.user.ID, "chargeback fraud", models.DeviceInfo{})
+	if err != nil {
+		t.Fatalf("DisableUser() error = %v", err)
+	}
+	if resp.DisabledAt == nil {
+		t.Fatal("expected the response to carry disabledAt")
+	}
+
+	// The token was cached by the validation above and is refused anyway.
+	if _, err := f.service.ValidateAccessToken(session.AccessToken); err == nil {
+		t.Fatal("expected the disabled user's token to be refused")
+	}
+	if _, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "password123"}); err == nil || err.Error() != "account disabled" {
+		t.Fatalf("expected login to fail with account disabled, got %v", err)
+	}
+	if _, err := f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "wrong-password"}); err == nil || err.Error() != "invalid credentials" {
+		t.Fatalf("a wrong password must not reveal the account is disabled, got %v", err)
+	}
+	if _, err := f.service.RefreshSession(session.RefreshToken, models.DeviceInfo{}); err == nil || err.Error() != "invalid refresh token" {
+		t.Fatalf("expected refresh to fail, got %v", err)
+	}
+
+	// Without the revocation list the account itself is checked.
+	token, err := f.service.generateAccessToken(f.user, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.ValidateAccessToken(token); err == nil || err.Error() != "account disabled" {
+		t.Fatalf("expected a fresh token of a disabled account to be refused, got %v", err)
+	}
+
+	page, err := f.service.ListAuditEvents(models.AuditEventFilter{Type: models.AuditUserDisabled}, "", 10)
+	if err != nil || len(page.Events) != 1 || page.Events[0].Detail != "admin 999: chargeback fraud" {
+		t.Fatalf("expected one user.disabled event, got %+v, %v", page, err)
+	}
+
+	if _, err := f.service.EnableUser(adminID, f.user.ID, models.DeviceInfo{}); err != nil {
+		t.Fatalf("EnableUser() error = %v", err)
+	}
+	relogin := loginFixtureUser(t, f)
+	if _, err := f.service.ValidateAccessToken(relogin.AccessToken); err != nil {
+		t.Fatalf("expected the re-enabled account to work, got %v", err)
+	}
+}
+
+func TestAuthService_SearchUsers(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	client := redis.NewClient(&redis.Options{Addr: f.redis.Addr()})
+	t.Cleanup(func() { client.Close() })
+	f.service.AttachLoginGuard(lockout.New(client, lockout.Config{MaxAttempts: 1, MaxIPAttempts: 100}))
+
+	for _, email := range []string{"other1@example.com", "other2@example.com"} {
+		user := testutil.CreateTestUser(func(u *models.User) {
+			u.ID = 0
+			u.Email = email
+		})
+		if err := f.service.userRepo.Create(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// One wrong password locks the fixture user out.
+	f.service.Login(models.LoginRequest{Email: f.user.Email, Password: "wrong-password"})
+
+	locked := true
+	page, err := f.service.SearchUsers(models.UserFilter{Locked: &locked}, "", "", 0)
+	if err != nil {
+		t.Fatalf("SearchUsers() error = %v", err)
+	}
+	if len(page.Users) != 1 || page.Users[0].ID != f.user.ID || !page.Users[0].Locked {
+		t.Fatalf("expected only the locked user, got %+v", page.Users)
+	}
+
+	first, err := f.service.SearchUsers(models.UserFilter{}, "email", "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Users) != 2 || first.NextCursor == "" || first.Users[0].Email != "other1@example.com" {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+	second, err := f.service.SearchUsers(models.UserFilter{}, "email", first.NextCursor, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Users) != 1 || second.NextCursor != "" || second.Users[0].Email != f.user.Email || !second.Users[0].Locked {
+		t.Fatalf("unexpected last page: %+v", second)
+	}
+
+	if _, err := f.service.SearchUsers(models.UserFilter{}, "-email", first.NextCursor, 2); err == nil || err.Error() != "invalid cursor" {
+		t.Fatalf("a cursor must not be reused with another sort, got %v", err)
+	}
+	if _, err := f.service.SearchUsers(models.UserFilter{}, "", "garbage", 2); err == nil || err.Error() != "invalid cursor" {
+		t.Fatalf("expected invalid cursor, got %v", err)
+	}
+	if _, err := f.service.SearchUsers(models.UserFilter{}, "password", "", 2); err == nil || err.Error() != "invalid sort" {
+		t.Fatalf("expected invalid sort, got %v", err)
+	}
+
+	// Enabling an account also lifts its lockout.
+	if _, err := f.service.EnableUser(999, f.user.ID, models.DeviceInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if page, _ := f.service.SearchUsers(models.UserFilter{Locked: &locked}, "", "", 0); len(page.Users) != 0 {
+		t.Fatalf("expected no locked users after enabling, got %+v", page.Users)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/icl00ud/velure/services/auth-service/internal/passwordpolicy"
 )
 
 var (
@@ -95,15 +97,11 @@ func validateEmail(email string) ValidationResult {
 	return ValidationResult{IsValid: true, Field: "email"}
 }
 
+// validatePassword checks the password against the default policy. The
+// configured policy is applied by AuthService when a password is set.
 func validatePassword(password string) ValidationResult {
-	if password == "" {
-		return ValidationResult{IsValid: false, Error: fmt.Errorf("password is required"), Field: "password"}
-	}
-	if len(password) < 6 {
-		return ValidationResult{IsValid: false, Error: fmt.Errorf("password must be at least 6 characters"), Field: "password"}
-	}
-	if len(password) > 128 {
-		return ValidationResult{IsValid: false, Error: fmt.Errorf("password must be less than 128 characters"), Field: "password"}
+	if err := passwordpolicy.Default.Check(password, passwordpolicy.Account{}); err != nil {
+		return ValidationResult{IsValid: false, Error: err, Field: "password"}
 	}
 	return ValidationResult{IsValid: true, Field: "password"}
 }
@@ -0,0 +1,221 @@
+// Package signing holds the asymmetric keys auth-service signs access tokens
+// with. Every key in the ring is published through the JWKS endpoint so tokens
+// signed by a retired key keep verifying until they expire; only the active
+// key signs new tokens.
+package signing
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/icl00ud/velure/shared/auth"
+)
+
+// Supported signing algorithms.
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+type key struct {
+	kid    string
+	method jwt.SigningMethod
+	signer crypto.Signer
+}
+
+// ErrNoKeys is returned when signing with a nil KeyRing.
+var ErrNoKeys = errors.New("signing: no signing key")
+
+// KeyRing signs tokens with its active key and resolves any of its keys by
+// kid. It implements auth.KeySet. A nil KeyRing holds no keys: it signs
+// nothing and resolves no kid.
+type KeyRing struct {
+	active *key
+	keys   map[string]*key
+	order  []string
+}
+
+// LoadDir reads every *.pem private key in dir (PKCS#8, or PKCS#1 for RSA).
+// The file name without extension becomes the key ID. activeKID selects the
+// signing key; when empty the key whose ID sorts last is used, so naming keys
+// by date (2026-10-16.pem) makes the newest one active.
+func LoadDir(dir, activeKID string) (*KeyRing, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("signing: list keys: %w", err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("signing: no *.pem keys in %s", dir)
+	}
+	sort.Strings(paths)
+
+	ring := &KeyRing{keys: make(map[string]*key, len(paths))}
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("signing: read %s: %w", path, err)
+		}
+		signer, err := parsePrivateKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("signing: %s: %w", path, err)
+		}
+		kid := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if err := ring.add(kid, signer); err != nil {
+			return nil, err
+		}
+	}
+
+	if activeKID == "" {
+		activeKID = ring.order[len(ring.order)-1]
+	}
+	active, ok := ring.keys[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("signing: active key %q not found in %s", activeKID, dir)
+	}
+	ring.active = active
+	return ring, nil
+}
+
+// Generate returns a ring holding a single freshly generated key. Tokens it
+// signs stop verifying when the process exits, so it is only meant for local
+// runs and tests.
+func Generate(alg string) (*KeyRing, error) {
+	var signer crypto.Signer
+	switch alg {
+	case AlgEdDSA:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signer = priv
+	case AlgRS256, "":
+		priv, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		signer = priv
+	default:
+		return nil, fmt.Errorf("signing: unsupported algorithm %q", alg)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	ring := &KeyRing{keys: map[string]*key{}}
+	if err := ring.add("ephemeral-"+hex.EncodeToString(id), signer); err != nil {
+		return nil, err
+	}
+	ring.active = ring.keys[ring.order[0]]
+	return ring, nil
+}
+
+func (r *KeyRing) add(kid string, signer crypto.Signer) error {
+	var method jwt.SigningMethod
+	switch signer.(type) {
+	case *rsa.PrivateKey:
+		method = jwt.SigningMethodRS256
+	case ed25519.PrivateKey:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return fmt.Errorf("signing: key %s has unsupported type %T", kid, signer)
+	}
+	if _, dup := r.keys[kid]; dup {
+		return fmt.Errorf("signing: duplicate key id %q", kid)
+	}
+	r.keys[kid] = &key{kid: kid, method: method, signer: signer}
+	r.order = append(r.order, kid)
+	return nil
+}
+
+// ActiveKeyID returns the kid new tokens are signed with.
+func (r *KeyRing) ActiveKeyID() string {
+	if r == nil {
+		return ""
+	}
+	return r.active.kid
+}
+
+// Sign signs claims with the active key and stamps its kid in the header.
+func (r *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	if r == nil {
+		return "", ErrNoKeys
+	}
+	token := jwt.NewWithClaims(r.active.method, claims)
+	token.Header["kid"] = r.active.kid
+	return token.SignedString(r.active.signer)
+}
+
+// Key returns the public key for kid.
+func (r *KeyRing) Key(kid string) (crypto.PublicKey, error) {
+	if r == nil {
+		return nil, auth.ErrUnknownKey
+	}
+	k, ok := r.keys[kid]
+	if !ok {
+		return nil, auth.ErrUnknownKey
+	}
+	return k.signer.Public(), nil
+}
+
+// JWKS returns the public halves of every key in the ring.
+func (r *KeyRing) JWKS() (auth.JWKS, error) {
+	if r == nil {
+		return auth.JWKS{Keys: []auth.JWK{}}, nil
+	}
+	doc := auth.JWKS{Keys: make([]auth.JWK, 0, len(r.order))}
+	for _, kid := range r.order {
+		jwk, err := auth.NewJWK(kid, r.keys[kid].signer.Public())
+		if err != nil {
+			return auth.JWKS{}, err
+		}
+		doc.Keys = append(doc.Keys, jwk)
+	}
+	return doc, nil
+}
+
+func parsePrivateKey(raw []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", parsed)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// MustGenerate is like Generate but panics on error.
+func MustGenerate(alg string) *KeyRing {
+	ring, err := Generate(alg)
+	if err != nil {
+		panic(err)
+	}
+	return ring
+}
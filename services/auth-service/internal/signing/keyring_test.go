@@ -0,0 +1,170 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/icl00ud/velure/shared/auth"
+)
+
+func writeKey(t *testing.T, dir, name string, block *pem.Block) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeKeys(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeKey(t, dir, "2026-01-01.pem", &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeKey(t, dir, "2026-06-01.pem", &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	return dir
+}
+
+func testClaims() auth.Claims {
+	return auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Roles: []string{auth.RoleCustomer},
+	}
+}
+
+func TestLoadDir_NewestKeySigns(t *testing.T) {
+	ring, err := LoadDir(writeKeys(t), "")
+	if err != nil {
+		t.Fatalf("LoadDir() error = %v", err)
+	}
+	if ring.ActiveKeyID() != "2026-06-01" {
+		t.Fatalf("expected newest key to be active, got %s", ring.ActiveKeyID())
+	}
+
+	token, err := ring.Sign(testClaims())
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	claims, err := auth.Parse(token, ring)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if claims.Subject != "1" {
+		t.Fatalf("unexpected subject %q", claims.Subject)
+	}
+
+	doc, err := ring.JWKS()
+	if err != nil {
+		t.Fatalf("JWKS() error = %v", err)
+	}
+	if len(doc.Keys) != 2 {
+		t.Fatalf("expected both keys published, got %d", len(doc.Keys))
+	}
+	// Verifiers only see the JWKS, not the ring.
+	if _, err := auth.Parse(token, doc); err != nil {
+		t.Fatalf("Parse() via JWKS error = %v", err)
+	}
+}
+
+func TestLoadDir_RotationKeepsOldTokensValid(t *testing.T) {
+	dir := writeKeys(t)
+
+	old, err := LoadDir(dir, "2026-01-01")
+	if err != nil {
+		t.Fatalf("LoadDir() error = %v", err)
+	}
+	token, err := old.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &auth.Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Method.Alg() != AlgRS256 || parsed.Header["kid"] != "2026-01-01" {
+		t.Fatalf("unexpected header %v", parsed.Header)
+	}
+
+	rotated, err := LoadDir(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.Parse(token, rotated); err != nil {
+		t.Fatalf("token signed by the previous key should still verify: %v", err)
+	}
+}
+
+func TestLoadDir_Errors(t *testing.T) {
+	if _, err := LoadDir(t.TempDir(), ""); err == nil {
+		t.Fatal("expected error for an empty directory")
+	}
+
+	if _, err := LoadDir(writeKeys(t), "missing"); err == nil {
+		t.Fatal("expected error for an unknown active kid")
+	}
+
+	dir := t.TempDir()
+	writeKey(t, dir, "bad.pem", &pem.Block{Type: "CERTIFICATE", Bytes: []byte("nope")})
+	if _, err := LoadDir(dir, ""); err == nil {
+		t.Fatal("expected error for a non-key PEM block")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	for _, alg := range []string{AlgRS256, AlgEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			ring, err := Generate(alg)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			token, err := ring.Sign(testClaims())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := auth.Parse(token, ring); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+		})
+	}
+
+	if _, err := Generate("HS256"); err == nil {
+		t.Fatal("expected error for HS256")
+	}
+}
+
+func TestKeyRing_Nil(t *testing.T) {
+	var ring *KeyRing
+	if _, err := ring.Sign(testClaims()); !errors.Is(err, ErrNoKeys) {
+		t.Fatalf("Sign() error = %v, want ErrNoKeys", err)
+	}
+	if _, err := ring.Key("any"); !errors.Is(err, auth.ErrUnknownKey) {
+		t.Fatalf("Key() error = %v, want ErrUnknownKey", err)
+	}
+	if doc, err := ring.JWKS(); err != nil || len(doc.Keys) != 0 {
+		t.Fatalf("JWKS() = %+v, %v; want no keys", doc, err)
+	}
+}
@@ -6,8 +6,8 @@ import (
 
 	"github.com/icl00ud/velure/services/auth-service/internal/config"
 	"github.com/icl00ud/velure/services/auth-service/internal/model"
+	"github.com/icl00ud/velure/services/auth-service/internal/passhash"
 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -25,7 +25,7 @@ func SetupTestDB(t *testing.T) *gorm.DB {
 	}
 
 	// Auto-migrate all models
-	err = db.AutoMigrate(&models.User{}, &models.Session{}, &models.PasswordReset{})
+	err = db.AutoMigrate(&models.User{}, &models.Session{}, &models.PasswordReset{}, &models.EmailVerification{}, &models.UserIdentity{}, &models.AuditEvent{}, &models.PasswordHistory{}, &models.OAuthClient{})
 	if err != nil {
 		t.Fatalf("failed to migrate database: %v", err)
 	}
@@ -35,7 +35,7 @@ func SetupTestDB(t *testing.T) *gorm.DB {
 
 // CreateTestUser creates a user with default values for testing
 func CreateTestUser(overrides ...func(*models.User)) *models.User {
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	hashedPassword := HashPassword("password123")
 
 	user := &models.User{
 		ID:        1,
@@ -57,7 +57,7 @@ func CreateTestUser(overrides ...func(*models.User)) *models.User {
 func CreateTestUsers(count int) []*models.User {
 	users := make([]*models.User, count)
 	for i := 0; i < count; i++ {
-		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+		hashedPassword := HashPassword("password123")
 		users[i] = &models.User{
 			ID:        uint(i + 1),
 			Name:      "Test User " + string(rune('A'+i)),
@@ -144,6 +144,8 @@ func CreateTestConfig() *config.Config {
 			ExpiresIn:        "1h",
 			RefreshSecret:    "test-refresh-secret-key-for-testing-purposes-only",
 			RefreshExpiresIn: "7d",
+			// Ed25519 keys are generated much faster than RSA ones.
+			SigningAlgorithm: "EdDSA",
 		},
 		Session: config.SessionConfig{
 			Secret:    "test-session-secret",
@@ -160,13 +162,15 @@ func CreateTestConfig() *config.Config {
 	}
 }
 
-// HashPassword is a helper to hash passwords for testing
+// HashPassword is a helper to hash passwords for testing. The hash uses the
+// service's default algorithm and costs, so logging in does not rehash it.
 func HashPassword(password string) string {
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(hashedPassword)
+	hashedPassword, _ := passhash.NewArgon2id(passhash.DefaultArgon2idParams).Hash(password)
+	return hashedPassword
 }
 
 // ComparePasswords is a helper to compare passwords in tests
 func ComparePasswords(hashedPassword, password string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)) == nil
+	hasher, _ := passhash.New(passhash.AlgArgon2id, passhash.DefaultArgon2idParams, 0)
+	return hasher.Verify(hashedPassword, password) == nil
 }
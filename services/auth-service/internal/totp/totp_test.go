@@ -0,0 +1,94 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 seed from the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode_RFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; the 6-digit code is their last six digits.
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		got, err := Code(rfcSecret, Step(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatalf("Code() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("Code(%d) = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, _ := Code(rfcSecret, Step(now))
+
+	step, ok := Validate(rfcSecret, code, now, 1)
+	if !ok || step != Step(now) {
+		t.Fatalf("Validate() = %d, %v; want %d, true", step, ok, Step(now))
+	}
+
+	// One step of drift is tolerated, two are not.
+	if _, ok := Validate(rfcSecret, code, now.Add(Period), 1); !ok {
+		t.Fatal("expected code from the previous step to pass")
+	}
+	if _, ok := Validate(rfcSecret, code, now.Add(2*Period), 1); ok {
+		t.Fatal("expected code two steps old to fail")
+	}
+
+	spaced := code[:3] + " " + code[3:]
+	if _, ok := Validate(rfcSecret, spaced, now, 0); !ok {
+		t.Fatal("expected spaces in the code to be ignored")
+	}
+
+	for _, bad := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, ok := Validate(rfcSecret, bad, now, 1); ok {
+			t.Fatalf("Validate(%q) should fail", bad)
+		}
+	}
+	if _, ok := Validate("not base32!", code, now, 1); ok {
+		t.Fatal("invalid secret should fail")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+	b, _ := GenerateSecret()
+	if a == b {
+		t.Fatal("secrets should be random")
+	}
+	if _, err := Code(a, 1); err != nil {
+		t.Fatalf("generated secret is not usable: %v", err)
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Velure", "ops@example.com", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/Velure:ops@example.com?") {
+		t.Fatalf("unexpected URI %s", uri)
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("URI does not parse: %v", err)
+	}
+	q := u.Query()
+	if q.Get("secret") != "JBSWY3DPEHPK3PXP" || q.Get("issuer") != "Velure" || q.Get("digits") != "6" || q.Get("period") != "30" {
+		t.Fatalf("unexpected query %v", q)
+	}
+}
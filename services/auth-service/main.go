@@ -3,18 +3,28 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"time"
 
+	"github.com/icl00ud/velure/services/auth-service/internal/cache"
 	"github.com/icl00ud/velure/services/auth-service/internal/config"
 	"github.com/icl00ud/velure/services/auth-service/internal/database"
+	"github.com/icl00ud/velure/services/auth-service/internal/events"
 	"github.com/icl00ud/velure/services/auth-service/internal/handler"
+	"github.com/icl00ud/velure/services/auth-service/internal/lockout"
 	"github.com/icl00ud/velure/services/auth-service/internal/middleware"
+	"github.com/icl00ud/velure/services/auth-service/internal/notify"
+	"github.com/icl00ud/velure/services/auth-service/internal/oidc"
+	"github.com/icl00ud/velure/services/auth-service/internal/passwordpolicy"
 	"github.com/icl00ud/velure/services/auth-service/internal/repository"
+	"github.com/icl00ud/velure/services/auth-service/internal/rpc"
 	"github.com/icl00ud/velure/services/auth-service/internal/service"
+	"github.com/icl00ud/velure/services/auth-service/internal/signing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/icl00ud/velure/shared/auth"
 	"github.com/icl00ud/velure/shared/logger"
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -73,9 +83,81 @@ func run(log *logger.Logger) error {
 	userRepo := repositories.NewUserRepository(db)
 	sessionRepo := repositories.NewSessionRepository(db)
 	passwordResetRepo := repositories.NewPasswordResetRepository(db)
+	emailVerificationRepo := repositories.NewEmailVerificationRepository(db)
 
 	log.Info("Initializing services")
-	authService := services.NewAuthService(userRepo, sessionRepo, passwordResetRepo, cfg, redisClient)
+	authService := services.NewAuthService(userRepo, sessionRepo, passwordResetRepo, emailVerificationRepo, cfg, redisClient)
+
+	notifier, err := notify.New(notify.Config{
+		Driver:   cfg.Notifier.Driver,
+		FilePath: cfg.Notifier.FilePath,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to configure notifier: %w", err)
+	}
+	authService.AttachNotifier(notifier)
+
+	publisher, err := events.New(events.Config{
+		Driver:   cfg.Events.Driver,
+		URL:      cfg.Events.URL,
+		Exchange: cfg.Events.Exchange,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to configure event publisher: %w", err)
+	}
+	defer publisher.Close()
+	authService.AttachEventPublisher(publisher)
+	authService.AttachLoginGuard(lockout.New(redisClient, lockout.Config{
+		MaxAttempts:   cfg.Lockout.MaxAttempts,
+		MaxIPAttempts: cfg.Lockout.MaxIPAttempts,
+		Window:        cfg.Lockout.Window,
+		Duration:      cfg.Lockout.Duration,
+		BaseDelay:     cfg.Lockout.BaseDelay,
+		MaxDelay:      cfg.Lockout.MaxDelay,
+	}))
+
+	var providers []*oidc.Provider
+	for _, p := range cfg.OIDC.Providers {
+		providers = append(providers, oidc.New(oidc.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}))
+		log.Info("Social login enabled", logger.String("provider", p.Name), logger.String("issuer", p.Issuer))
+	}
+	authService.AttachIdentityProviders(repositories.NewUserIdentityRepository(db), providers...)
+	authService.AttachAuditLog(repositories.NewAuditEventRepository(db))
+	authService.AttachPasswordHistory(repositories.NewPasswordHistoryRepository(db))
+	authService.AttachClients(repositories.NewOAuthClientRepository(db))
+	for _, client := range cfg.ClientCredentials.Clients {
+		if err := authService.EnsureClient(client); err != nil {
+			return fmt.Errorf("failed to register service client: %w", err)
+		}
+		log.Info("Service client registered", logger.String("client_id", client.ID))
+	}
+	if dir := cfg.PasswordPolicy.BreachedDir; dir != "" {
+		breached, err := passwordpolicy.OpenBreachedPasswords(dir, cfg.PasswordPolicy.BreachedMinCount)
+		if err != nil {
+			return fmt.Errorf("failed to open breached password dataset: %w", err)
+		}
+		authService.AttachBreachedPasswords(breached)
+		log.Info("Breached password check enabled", logger.String("dir", dir))
+	}
+
+	keys, err := loadSigningKeys(log, cfg.JWT)
+	if err != nil {
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
+	authService.AttachKeyRing(keys)
+
+	cacheBus := cache.NewBus(redisClient)
+	authService.AttachCacheBus(cacheBus)
+	go cacheBus.Run(context.Background())
+	log.Info("Token cache invalidation bus started", logger.String("channel", cache.Channel))
+
 	authService.SyncActiveSessionsMetric(context.Background())
 	authService.SyncTotalUsersMetric(context.Background())
 
@@ -92,7 +174,7 @@ func run(log *logger.Logger) error {
 	authHandler := handlers.NewAuthHandler(authService)
 
 	log.Info("Setting up HTTP router")
-	router := setupRouter(cfg, authHandler)
+	router := setupRouter(cfg, authHandler, redisClient)
 
 	port := os.Getenv("AUTH_SERVICE_APP_PORT")
 	if port == "" {
@@ -104,12 +186,40 @@ func run(log *logger.Logger) error {
 		return nil
 	}
 
+	grpcListener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+	if err != nil {
+		return fmt.Errorf("failed to listen for gRPC: %w", err)
+	}
+	grpcServer := rpc.NewGRPCServer(rpc.NewServer(authService))
+	defer grpcServer.Stop()
+	go func() {
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Error("gRPC server stopped", logger.Err(err))
+		}
+	}()
+	log.Info("Starting gRPC server", logger.String("port", cfg.GRPCPort))
+
 	if err := router.Run(":" + port); err != nil {
 		return fmt.Errorf("failed to start server: %w", err)
 	}
 	return nil
 }
 
+func loadSigningKeys(log *logger.Logger, cfg config.JWTConfig) (*signing.KeyRing, error) {
+	if cfg.SigningKeysDir == "" {
+		log.Warn("JWT_SIGNING_KEYS_DIR not set; signing with an ephemeral key (tokens die with this process)",
+			logger.String("alg", cfg.SigningAlgorithm))
+		return signing.Generate(cfg.SigningAlgorithm)
+	}
+
+	keys, err := signing.LoadDir(cfg.SigningKeysDir, cfg.ActiveKeyID)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Signing keys loaded", logger.String("active_kid", keys.ActiveKeyID()))
+	return keys, nil
+}
+
 func connectRedis(cfg config.RedisConfig) (*redis.Client, error) {
 	client := redis.NewClient(&redis.Options{
 		Addr:     cfg.Addr,
@@ -129,15 +239,64 @@ func setupRoutes(router *gin.Engine, authHandler *handlers.AuthHandler) {
 	api := router.Group("/api")
 	{
 		api.POST("/sessions", authHandler.Login)
+		api.GET("/sessions", authHandler.RequireRoles(), authHandler.ListSessions)
+		api.DELETE("/sessions", authHandler.RequireRoles(), authHandler.ForbidImpersonation, authHandler.RevokeAllSessions)
 		api.DELETE("/sessions/current", authHandler.Logout)
+		api.DELETE("/sessions/:id", authHandler.RequireRoles(), authHandler.ForbidImpersonation, authHandler.RevokeSession)
+		api.POST("/sessions/refresh", authHandler.Refresh)
+		api.POST("/sessions/two-factor", authHandler.CompleteTwoFactorLogin)
+		api.POST("/sessions/oidc/:provider", authHandler.CompleteOIDCLogin)
+		api.GET("/oidc/:provider/authorize", authHandler.StartOIDCLogin)
+		api.POST("/password-resets", authHandler.RequestPasswordReset)
+		api.POST("/password-resets/confirm", authHandler.ConfirmPasswordReset)
+		api.POST("/email-verifications", authHandler.RequireRoles(), authHandler.RequestEmailVerification)
+		api.POST("/email-verifications/confirm", authHandler.ConfirmEmailVerification)
 		api.POST("/users", authHandler.Register)
-		api.GET("/users", authHandler.GetUsers)
-		api.GET("/users/:id", authHandler.GetUserByID)
+		api.GET("/users", authHandler.RequireRoles(auth.RoleAdmin), authHandler.GetUsers)
+		api.GET("/users/:id", authHandler.RequireSelfOrRoles("id", auth.RoleAdmin), authHandler.GetUserByID)
+		api.GET("/users/search", authHandler.RequireRoles(auth.RoleAdmin), authHandler.SearchUsers)
+		api.PUT("/users/:id/roles", authHandler.RequireRoles(auth.RoleAdmin), authHandler.UpdateUserRoles)
+		api.POST("/users/:id/disable", authHandler.RequireRoles(auth.RoleAdmin), authHandler.DisableUser)
+		api.POST("/users/:id/enable", authHandler.RequireRoles(auth.RoleAdmin), authHandler.EnableUser)
+		api.POST("/users/:id/impersonate", authHandler.RequireRoles(auth.RoleAdmin), authHandler.ForbidImpersonation, authHandler.ImpersonateUser)
+		api.PATCH("/users/me", authHandler.RequireRoles(), authHandler.ForbidImpersonation, authHandler.UpdateProfile)
+		api.DELETE("/users/me", authHandler.RequireRoles(), authHandler.ForbidImpersonation, authHandler.DeleteAccount)
+		api.POST("/users/me/two-factor", authHandler.RequireRoles(), authHandler.ForbidImpersonation, authHandler.EnrollTwoFactor)
+		api.POST("/users/me/two-factor/confirm", authHandler.RequireRoles(), authHandler.ForbidImpersonation, authHandler.ConfirmTwoFactor)
+		api.DELETE("/users/me/two-factor", authHandler.RequireRoles(), authHandler.ForbidImpersonation, authHandler.DisableTwoFactor)
+		api.POST("/users/me/two-factor/recovery-codes", authHandler.RequireRoles(), authHandler.ForbidImpersonation, authHandler.RegenerateRecoveryCodes)
 		api.POST("/tokens/introspect", authHandler.ValidateToken)
+		api.GET("/audit-events", authHandler.RequireRoles(auth.RoleAdmin), authHandler.ListAuditEvents)
+		api.GET("/audit-events/export", authHandler.RequireRoles(auth.RoleAdmin), authHandler.ExportAuditEvents)
+		api.POST("/oauth/token", authHandler.IssueToken)
+		api.GET("/clients", authHandler.RequireRoles(auth.RoleAdmin), authHandler.ListClients)
+		api.POST("/clients", authHandler.RequireRoles(auth.RoleAdmin), authHandler.CreateClient)
+		api.DELETE("/clients/:clientId", authHandler.RequireRoles(auth.RoleAdmin), authHandler.DeleteClient)
+	}
+}
+
+// rateLimitPolicies keeps credential endpoints much tighter than the rest and
+// gives token introspection, called by other services, more room.
+func rateLimitPolicies(cfg config.RateLimitConfig) middleware.RateLimitConfig {
+	login := middleware.PerMinute(cfg.LoginPerMinute)
+	return middleware.RateLimitConfig{
+		Default: middleware.PerSecond(cfg.DefaultRPS, cfg.DefaultBurst),
+		Routes: map[string]middleware.RatePolicy{
+			"POST /api/sessions":                    login,
+			"POST /api/sessions/two-factor":         login,
+			"POST /api/sessions/oidc/:provider":     login,
+			"POST /api/password-resets":             login,
+			"POST /api/password-resets/confirm":     login,
+			"POST /api/email-verifications":         login,
+			"POST /api/email-verifications/confirm": login,
+			"POST /api/oauth/token":                 login,
+			"POST /api/users":                       middleware.PerHour(cfg.RegisterPerHour),
+			"POST /api/tokens/introspect":           middleware.PerSecond(cfg.IntrospectRPS, 2*cfg.IntrospectRPS),
+		},
 	}
 }
 
-func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler) *gin.Engine {
+func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, redisClient *redis.Client) *gin.Engine {
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -145,14 +304,16 @@ func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler) *gin.Eng
 	router := gin.New()
 	router.Use(gin.Recovery())
 
-	rateLimiter := middleware.NewRateLimiter(100, 200)
+	rateLimiter := middleware.NewRateLimiter(redisClient, rateLimitPolicies(cfg.RateLimit))
 
+	router.Use(middleware.RequestID())
 	router.Use(middleware.CORS())
 	router.Use(middleware.Logger())
 	router.Use(middleware.PrometheusMiddleware())
 	router.Use(rateLimiter.Middleware())
 
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	router.GET("/.well-known/jwks.json", authHandler.JWKS)
 
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
@@ -7,6 +7,7 @@ import (
 
 	"github.com/icl00ud/velure/services/auth-service/internal/config"
 	"github.com/icl00ud/velure/services/auth-service/internal/handler"
+	"github.com/icl00ud/velure/services/auth-service/internal/middleware"
 	"github.com/icl00ud/velure/services/auth-service/internal/mocks"
 
 	miniredis "github.com/alicebob/miniredis/v2"
@@ -33,12 +34,36 @@ func TestSetupRoutes(t *testing.T) {
 	}
 
 	expectedRoutes := map[string]string{
-		"POST /api/sessions":           "POST",
-		"DELETE /api/sessions/current": "DELETE",
-		"POST /api/users":              "POST",
-		"GET /api/users":               "GET",
-		"GET /api/users/:id":           "GET",
-		"POST /api/tokens/introspect":  "POST",
+		"POST /api/sessions":                    "POST",
+		"GET /api/sessions":                     "GET",
+		"DELETE /api/sessions":                  "DELETE",
+		"DELETE /api/sessions/current":          "DELETE",
+		"DELETE /api/sessions/:id":              "DELETE",
+		"POST /api/sessions/refresh":            "POST",
+		"POST /api/sessions/two-factor":         "POST",
+		"POST /api/sessions/oidc/:provider":     "POST",
+		"GET /api/oidc/:provider/authorize":     "GET",
+		"POST /api/password-resets":             "POST",
+		"POST /api/password-resets/confirm":     "POST",
+		"POST /api/email-verifications":         "POST",
+		"POST /api/email-verifications/confirm": "POST",
+		"POST /api/users":                       "POST",
+		"GET /api/users":                        "GET",
+		"GET /api/users/:id":                    "GET",
+		"GET /api/users/search":                 "GET",
+		"POST /api/users/:id/disable":           "POST",
+		"POST /api/users/:id/enable":            "POST",
+		"PATCH /api/users/me":                   "PATCH",
+		"DELETE /api/users/me":                  "DELETE",
+		"POST /api/users/me/two-factor":         "POST",
+		"DELETE /api/users/me/two-factor":       "DELETE",
+		"POST /api/tokens/introspect":           "POST",
+		"GET /api/audit-events":                 "GET",
+		"GET /api/audit-events/export":          "GET",
+		"POST /api/oauth/token":                 "POST",
+		"GET /api/clients":                      "GET",
+		"POST /api/clients":                     "POST",
+		"DELETE /api/clients/:clientId":         "DELETE",
 	}
 
 	foundRoutes := make(map[string]bool)
@@ -54,6 +79,18 @@ func TestSetupRoutes(t *testing.T) {
 	}
 }
 
+func TestRateLimitPolicies_PasswordResets(t *testing.T) {
+	policies := rateLimitPolicies(config.RateLimitConfig{DefaultRPS: 100, DefaultBurst: 200, LoginPerMinute: 20})
+
+	// Padded password reset requests hold a connection open for the whole
+	// window, so the per-IP login limit must stay in front of them.
+	for _, route := range []string{"POST /api/password-resets", "POST /api/password-resets/confirm"} {
+		if got := policies.Routes[route]; got != middleware.PerMinute(20) {
+			t.Errorf("expected %s to be limited to 20 per minute, got %+v", route, got)
+		}
+	}
+}
+
 func TestSetupRouter_Development(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	ctrl := gomock.NewController(t)
@@ -66,7 +103,7 @@ func TestSetupRouter_Development(t *testing.T) {
 	mockService := mocks.NewMockAuthServiceInterface(ctrl)
 	handler := handlers.NewAuthHandler(mockService)
 
-	router := setupRouter(cfg, handler)
+	router := setupRouter(cfg, handler, nil)
 
 	if router == nil {
 		t.Fatal("setupRouter() returned nil")
@@ -91,7 +128,7 @@ func TestSetupRouter_Production(t *testing.T) {
 	mockService := mocks.NewMockAuthServiceInterface(ctrl)
 	handler := handlers.NewAuthHandler(mockService)
 
-	router := setupRouter(cfg, handler)
+	router := setupRouter(cfg, handler, nil)
 
 	if router == nil {
 		t.Fatal("setupRouter() returned nil")
@@ -116,7 +153,7 @@ func TestSetupRouter_HealthEndpoint(t *testing.T) {
 	mockService := mocks.NewMockAuthServiceInterface(ctrl)
 	handler := handlers.NewAuthHandler(mockService)
 
-	router := setupRouter(cfg, handler)
+	router := setupRouter(cfg, handler, nil)
 
 	// Test health endpoint
 	req := httptest.NewRequest("GET", "/health", nil)
@@ -146,7 +183,7 @@ func TestSetupRouter_MetricsEndpoint(t *testing.T) {
 	mockService := mocks.NewMockAuthServiceInterface(ctrl)
 	handler := handlers.NewAuthHandler(mockService)
 
-	router := setupRouter(cfg, handler)
+	router := setupRouter(cfg, handler, nil)
 
 	// Test metrics endpoint exists
 	req := httptest.NewRequest("GET", "/metrics", nil)
@@ -178,7 +215,7 @@ func TestSetupRouter_HasMiddleware(t *testing.T) {
 	mockService := mocks.NewMockAuthServiceInterface(ctrl)
 	handler := handlers.NewAuthHandler(mockService)
 
-	router := setupRouter(cfg, handler)
+	router := setupRouter(cfg, handler, nil)
 
 	// Make a request to test that middleware is applied
 	req := httptest.NewRequest("OPTIONS", "/health", nil)
@@ -230,3 +267,17 @@ func TestConnectRedis_Error(t *testing.T) {
 		t.Fatalf("expected error connecting to invalid redis address")
 	}
 }
+
+func TestLoadSigningKeys(t *testing.T) {
+	keys, err := loadSigningKeys(logger.NewNop(), config.JWTConfig{SigningAlgorithm: "EdDSA"})
+	if err != nil {
+		t.Fatalf("expected an ephemeral key ring, got %v", err)
+	}
+	if keys.ActiveKeyID() == "" {
+		t.Fatal("expected an active key id")
+	}
+
+	if _, err := loadSigningKeys(logger.NewNop(), config.JWTConfig{SigningKeysDir: t.TempDir()}); err == nil {
+		t.Fatal("expected error for a key directory without keys")
+	}
+}
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS roles VARCHAR(255) NOT NULL DEFAULT 'customer';
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+DROP FUNCTION IF EXISTS audit_events_append_only();
+DROP INDEX IF EXISTS idx_audit_events_created_at;
+DROP INDEX IF EXISTS idx_audit_events_user_id;
+DROP INDEX IF EXISTS idx_audit_events_type;
+DROP TABLE IF EXISTS audit_events;
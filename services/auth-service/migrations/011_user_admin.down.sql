@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_created_at;
+DROP INDEX IF EXISTS idx_users_name_prefix;
+DROP INDEX IF EXISTS idx_users_email_prefix;
+
+ALTER TABLE users DROP COLUMN IF EXISTS disabled_at;
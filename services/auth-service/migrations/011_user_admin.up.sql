@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP;
+
+-- Admin user search: prefix matches on email and name, newest first by default.
+CREATE INDEX IF NOT EXISTS idx_users_email_prefix ON users (LOWER(email) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_users_name_prefix ON users (LOWER(name) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at, id);
@@ -0,0 +1,5 @@
+-- last_used_at follows access token use; issued_at keeps when the session's
+-- tokens were minted, which is what revoking live tokens filters on.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS issued_at TIMESTAMP;
+
+UPDATE sessions SET issued_at = last_used_at WHERE issued_at IS NULL;
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// newTokenServer answers client_credentials requests for client "svc" with
+// secret "s3cret", numbering the tokens it hands out.
+func newTokenServer(t *testing.T, expiresIn int) (*httptest.Server, *int32) {
+	t.Helper()
+	var issued int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "svc" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		if r.PostFormValue("grant_type") != "client_credentials" || r.PostFormValue("scope") != "inventory:write" {
+			t.Errorf("unexpected token request form: %v", r.PostForm)
+		}
+		n := atomic.AddInt32(&issued, 1)
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   expiresIn,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &issued
+}
+
+func TestClientCredentials_Token(t *testing.T) {
+	server, issued := newTokenServer(t, 300)
+	tokens := NewClientCredentials(server.URL, "svc", "s3cret", "inventory:write")
+
+	for i := 0; i < 3; i++ {
+		token, err := tokens.Token(context.Background())
+		if err != nil {
+			t.Fatalf("Token() error = %v", err)
+		}
+		if token != "token-1" {
+			t.Fatalf("expected the cached token, got %s", token)
+		}
+	}
+	if *issued != 1 {
+		t.Fatalf("expected one token request, got %d", *issued)
+	}
+
+	tokens.Invalidate()
+	if token, _ := tokens.Token(context.Background()); token != "token-2" {
+		t.Fatalf("expected a new token after Invalidate, got %s", token)
+	}
+
+	// Tokens about to expire are replaced right away.
+	short, issuedShort := newTokenServer(t, 10)
+	tokens = NewClientCredentials(short.URL, "svc", "s3cret", "inventory:write")
+	tokens.Token(context.Background())
+	tokens.Token(context.Background())
+	if *issuedShort != 2 {
+		t.Fatalf("expected short-lived tokens not to be cached, got %d requests", *issuedShort)
+	}
+
+	tokens = NewClientCredentials(server.URL, "svc", "wrong", "inventory:write")
+	if _, err := tokens.Token(context.Background()); err == nil {
+		t.Fatal("expected an error for rejected credentials")
+	}
+}
+
+func TestProductClient_ApplyInventory_SendsToken(t *testing.T) {
+	tokenServer, issued := newTokenServer(t, 300)
+	tokens := NewClientCredentials(tokenServer.URL, "svc", "s3cret", "inventory:write")
+
+	var rejectFirst int32 = 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first token is refused once, as after a key rotation.
+		if r.Header.Get("Authorization") == "Bearer token-1" && atomic.CompareAndSwapInt32(&rejectFirst, 1, 0) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token-1" && got != "Bearer token-2" {
+			t.Errorf("unexpected Authorization header %q", got)
+		}
+		w.Write([]byte(`{"applied":true}`))
+	}))
+	defer server.Close()
+
+	changes := []InventoryChange{{ProductID: "product123", QuantityChange: -1}}
+	client := NewProductClient(server.URL, tokens)
+	if _, err := client.ApplyInventory(context.Background(), "order-1", changes); err != nil {
+		t.Fatalf("ApplyInventory() error = %v", err)
+	}
+	if *issued != 2 {
+		t.Fatalf("expected the rejected token to be replaced, got %d token requests", *issued)
+	}
+
+	// auth-service being down is retried later.
+	down := NewProductClient(server.URL, NewClientCredentials("http://127.0.0.1:1/token", "svc", "s3cret", "inventory:write"))
+	var transient *TransientError
+	if _, err := down.ApplyInventory(context.Background(), "order-1", changes); !errors.As(err, &transient) {
+		t.Fatalf("expected a TransientError, got %v", err)
+	}
+}
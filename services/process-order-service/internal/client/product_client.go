@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
@@ -30,17 +31,24 @@ func (e *TransientError) Error() string {
 }
 
 type ProductClient interface {
-	UpdateQuantity(ctx context.Context, productID string, quantityChange int) error
+	ApplyInventory(ctx context.Context, orderID string, changes []InventoryChange) (*InventoryBatchResult, error)
+	Reserve(ctx context.Context, orderID string, items []ReservationItem, ttl time.Duration) (*ReservationResult, error)
+	ReleaseReservation(ctx context.Context, orderID string) error
 }
 
 type productClient struct {
 	baseURL    string
 	httpClient *http.Client
+	tokens     *ClientCredentials
 }
 
-func NewProductClient(baseURL string) ProductClient {
+// NewProductClient returns a client for product-service. Inventory updates
+// need a token with the inventory:write scope, which tokens supplies; with
+// nil tokens requests are sent without credentials.
+func NewProductClient(baseURL string, tokens *ClientCredentials) ProductClient {
 	return &productClient{
 		baseURL: baseURL,
+		tokens:  tokens,
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 			// Propagates the W3C trace context and records a client span per call.
@@ -49,37 +57,115 @@ func NewProductClient(baseURL string) ProductClient {
 	}
 }
 
-type UpdateQuantityRequest struct {
-	QuantityChange int `json:"quantity_change"`
+type InventoryChange struct {
+	ProductID      string `json:"product_id"`
+	QuantityChange int    `json:"quantity_change"`
 }
 
-func (c *productClient) UpdateQuantity(ctx context.Context, productID string, quantityChange int) error {
-	req := UpdateQuantityRequest{
-		QuantityChange: quantityChange,
-	}
+type InventoryBatchRequest struct {
+	OrderID string            `json:"order_id"`
+	Items   []InventoryChange `json:"items"`
+}
+
+// InventoryLineResult is product-service's outcome for one change: applied,
+// not_applied, insufficient_stock or not_found. Quantity is the stock after
+// an applied change.
+type InventoryLineResult struct {
+	ProductID      string `json:"product_id"`
+	QuantityChange int    `json:"quantity_change"`
+	Status         string `json:"status"`
+	Quantity       *int   `json:"quantity,omitempty"`
+}
+
+type InventoryBatchResult struct {
+	OrderID  string                `json:"order_id"`
+	Applied  bool                  `json:"applied"`
+	Replayed bool                  `json:"replayed"`
+	Items    []InventoryLineResult `json:"items"`
+}
 
-	body, err := json.Marshal(req)
+type ReservationItem struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+type ReserveRequest struct {
+	OrderID    string            `json:"order_id"`
+	Items      []ReservationItem `json:"items"`
+	TTLSeconds int               `json:"ttl_seconds,omitempty"`
+}
+
+// ReservationResult is product-service's answer to a reservation. Committed
+// means the order's stock was deducted already, so nothing was held.
+type ReservationResult struct {
+	OrderID   string `json:"order_id"`
+	Committed bool   `json:"committed"`
+}
+
+// ApplyInventory applies an order's stock changes in one transaction: all
+// of them or none. A deduction uses up what the order holds of the product
+// first. Sending the same order again returns the recorded result without
+// changing stock. A rejected batch is a PermanentError (409) naming the
+// product that failed.
+func (c *productClient) ApplyInventory(ctx context.Context, orderID string, changes []InventoryChange) (*InventoryBatchResult, error) {
+	body, err := json.Marshal(InventoryBatchRequest{
+		OrderID: orderID,
+		Items:   changes,
+	})
 	if err != nil {
-		return fmt.Errorf("marshal request: %w", err)
+		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "PATCH", c.baseURL+"/api/products/"+productID+"/inventory", bytes.NewBuffer(body))
+	var result InventoryBatchResult
+	if err := c.call(ctx, http.MethodPost, "/api/products/inventory/batch", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Reserve holds stock for every item of an order until ttl passes (zero
+// leaves the expiry to product-service). Reserving an order again is safe,
+// and reserving one whose stock was deducted already reports it as committed.
+func (c *productClient) Reserve(ctx context.Context, orderID string, items []ReservationItem, ttl time.Duration) (*ReservationResult, error) {
+	body, err := json.Marshal(ReserveRequest{
+		OrderID:    orderID,
+		Items:      items,
+		TTLSeconds: int(ttl.Seconds()),
+	})
 	if err != nil {
-		return fmt.Errorf("create request: %w", err)
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	var result ReservationResult
+	if err := c.call(ctx, http.MethodPost, "/api/products/reservations", body, &result); err != nil {
+		return nil, err
 	}
+	return &result, nil
+}
 
-	httpReq.Header.Set("Content-Type", "application/json")
+// ReleaseReservation hands the order's uncommitted holds back.
+func (c *productClient) ReleaseReservation(ctx context.Context, orderID string) error {
+	return c.call(ctx, http.MethodDelete, "/api/products/reservations/"+url.PathEscape(orderID), nil, nil)
+}
 
-	resp, err := c.httpClient.Do(httpReq)
+// call sends a request to product-service and maps a failed response to a
+// PermanentError or TransientError. A successful response is decoded into
+// out unless it is nil.
+func (c *productClient) call(ctx context.Context, method, path string, body []byte, out any) error {
+	resp, err := c.send(ctx, method, path, body)
+	// A token rejected before its expiry (e.g. after a key rotation) is
+	// replaced once.
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && c.tokens != nil {
+		resp.Body.Close()
+		c.tokens.Invalidate()
+		resp, err = c.send(ctx, method, path, body)
+	}
 	if err != nil {
-		return &TransientError{
-			Message:    err.Error(),
-			StatusCode: 0,
-		}
+		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		var errResp struct {
 			Error string `json:"error"`
 		}
@@ -113,5 +199,38 @@ func (c *productClient) UpdateQuantity(ctx context.Context, productID string, qu
 		}
 	}
 
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+	}
 	return nil
 }
+
+func (c *productClient) send(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if c.tokens != nil {
+		token, err := c.tokens.Token(ctx)
+		if err != nil {
+			// auth-service being unreachable is worth retrying later.
+			return nil, &TransientError{Message: err.Error()}
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, &TransientError{
+			Message:    err.Error(),
+			StatusCode: 0,
+		}
+	}
+	return resp, nil
+}
@@ -7,22 +7,23 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestNewProductClient(t *testing.T) {
-	client := NewProductClient("http://example.com")
+	client := NewProductClient("http://example.com", nil)
 	if client == nil {
 		t.Fatal("expected non-nil client")
 	}
 }
 
-func TestProductClient_UpdateQuantity_Success(t *testing.T) {
+func TestProductClient_ApplyInventory_Success(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPatch {
-			t.Errorf("expected PATCH request, got %s", r.Method)
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST request, got %s", r.Method)
 		}
-		if r.URL.Path != "/api/products/product123/inventory" {
-			t.Errorf("expected path /api/products/product123/inventory, got %s", r.URL.Path)
+		if r.URL.Path != "/api/products/inventory/batch" {
+			t.Errorf("expected path /api/products/inventory/batch, got %s", r.URL.Path)
 		}
 		if r.Header.Get("Content-Type") != "application/json" {
 			t.Errorf("expected Content-Type application/json, got %s", r.Header.Get("Content-Type"))
@@ -32,39 +33,62 @@ func TestProductClient_UpdateQuantity_Success(t *testing.T) {
 		if err != nil {
 			t.Errorf("failed reading body: %v", err)
 		}
-		var payload map[string]int
+		var payload InventoryBatchRequest
 		if err := json.Unmarshal(body, &payload); err != nil {
 			t.Errorf("failed unmarshalling body: %v", err)
 		}
-		if _, exists := payload["product_id"]; exists {
-			t.Errorf("product_id should not be sent in body")
+		if payload.OrderID != "order-1" || len(payload.Items) != 2 {
+			t.Errorf("unexpected batch %+v", payload)
 		}
-		if payload["quantity_change"] != -2 {
-			t.Errorf("expected quantity_change -2, got %d", payload["quantity_change"])
+		if payload.Items[0].ProductID != "product123" || payload.Items[0].QuantityChange != -2 {
+			t.Errorf("expected product123 -2 first, got %+v", payload.Items[0])
 		}
 
 		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok"}`))
+		w.Write([]byte(`{"order_id":"order-1","applied":true,"items":[` +
+			`{"product_id":"product123","quantity_change":-2,"status":"applied","quantity":8},` +
+			`{"product_id":"product456","quantity_change":1,"status":"applied","quantity":4}]}`))
 	}))
 	defer server.Close()
 
-	client := NewProductClient(server.URL)
-	err := client.UpdateQuantity(context.Background(), "product123", -2)
+	client := NewProductClient(server.URL, nil)
+	result, err := client.ApplyInventory(context.Background(), "order-1", []InventoryChange{
+		{ProductID: "product123", QuantityChange: -2},
+		{ProductID: "product456", QuantityChange: 1},
+	})
 
 	if err != nil {
-		t.Errorf("unexpected error: %v", err)
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Applied || len(result.Items) != 2 || result.Items[0].Quantity == nil || *result.Items[0].Quantity != 8 {
+		t.Errorf("unexpected result %+v", result)
 	}
 }
 
-func TestProductClient_UpdateQuantity_ErrorResponse(t *testing.T) {
+func TestProductClient_ApplyInventory_Rejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"order_id":"order-1","applied":false,"items":[{"product_id":"product123","quantity_change":-2,"status":"insufficient_stock"}],"error":"insufficient stock for product product123"}`))
+	}))
+	defer server.Close()
+
+	client := NewProductClient(server.URL, nil)
+	_, err := client.ApplyInventory(context.Background(), "order-1", []InventoryChange{{ProductID: "product123", QuantityChange: -2}})
+	pe, ok := err.(*PermanentError)
+	if !ok || pe.StatusCode != http.StatusConflict || pe.Message != "insufficient stock for product product123" {
+		t.Fatalf("expected a PermanentError with status 409, got %T: %v", err, err)
+	}
+}
+
+func TestProductClient_ApplyInventory_ErrorResponse(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(`{"error":"insufficient stock"}`))
 	}))
 	defer server.Close()
 
-	client := NewProductClient(server.URL)
-	err := client.UpdateQuantity(context.Background(), "product123", -10)
+	client := NewProductClient(server.URL, nil)
+	_, err := client.ApplyInventory(context.Background(), "order-1", []InventoryChange{{ProductID: "product123", QuantityChange: -10}})
 
 	if err == nil {
 		t.Error("expected error, got nil")
@@ -74,15 +98,15 @@ func TestProductClient_UpdateQuantity_ErrorResponse(t *testing.T) {
 	}
 }
 
-func TestProductClient_UpdateQuantity_NonJSONError(t *testing.T) {
+func TestProductClient_ApplyInventory_NonJSONError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(`Internal Server Error`))
 	}))
 	defer server.Close()
 
-	client := NewProductClient(server.URL)
-	err := client.UpdateQuantity(context.Background(), "product123", -2)
+	client := NewProductClient(server.URL, nil)
+	_, err := client.ApplyInventory(context.Background(), "order-1", []InventoryChange{{ProductID: "product123", QuantityChange: -2}})
 
 	if err == nil {
 		t.Error("expected error, got nil")
@@ -92,53 +116,54 @@ func TestProductClient_UpdateQuantity_NonJSONError(t *testing.T) {
 	}
 }
 
-func TestProductClient_UpdateQuantity_InvalidURL(t *testing.T) {
-	client := NewProductClient("http://invalid-url-that-does-not-exist.local:99999")
-	err := client.UpdateQuantity(context.Background(), "product123", -2)
+func TestProductClient_ApplyInventory_InvalidURL(t *testing.T) {
+	client := NewProductClient("http://invalid-url-that-does-not-exist.local:99999", nil)
+	_, err := client.ApplyInventory(context.Background(), "order-1", []InventoryChange{{ProductID: "product123", QuantityChange: -2}})
 
 	if err == nil {
 		t.Error("expected error, got nil")
 	}
 }
 
-func TestProductClient_UpdateQuantity_Timeout(t *testing.T) {
+func TestProductClient_ApplyInventory_Timeout(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"applied":true}`))
 	}))
 	defer server.Close()
 
-	client := NewProductClient(server.URL)
-	err := client.UpdateQuantity(context.Background(), "product123", -2)
+	client := NewProductClient(server.URL, nil)
+	_, err := client.ApplyInventory(context.Background(), "order-1", []InventoryChange{{ProductID: "product123", QuantityChange: -2}})
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
 
-func TestProductClient_UpdateQuantity_PositiveChange(t *testing.T) {
+func TestProductClient_ApplyInventory_PositiveChange(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"status":"ok"}`))
 	}))
 	defer server.Close()
 
-	client := NewProductClient(server.URL)
-	err := client.UpdateQuantity(context.Background(), "product456", 5)
+	client := NewProductClient(server.URL, nil)
+	_, err := client.ApplyInventory(context.Background(), "order-1", []InventoryChange{{ProductID: "product456", QuantityChange: 5}})
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
 
-func TestProductClient_UpdateQuantity_TooManyRequests(t *testing.T) {
+func TestProductClient_ApplyInventory_TooManyRequests(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusTooManyRequests)
 		w.Write([]byte(`{"error":"retry later"}`))
 	}))
 	defer server.Close()
 
-	client := NewProductClient(server.URL)
-	err := client.UpdateQuantity(context.Background(), "product789", -1)
+	client := NewProductClient(server.URL, nil)
+	_, err := client.ApplyInventory(context.Background(), "order-1", []InventoryChange{{ProductID: "product789", QuantityChange: -1}})
 	if err == nil {
 		t.Fatal("expected transient error")
 	}
@@ -147,14 +172,14 @@ func TestProductClient_UpdateQuantity_TooManyRequests(t *testing.T) {
 	}
 }
 
-func TestProductClient_UpdateQuantity_UnexpectedStatusDefaultsToPermanent(t *testing.T) {
+func TestProductClient_ApplyInventory_UnexpectedStatusDefaultsToPermanent(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusTeapot)
 	}))
 	defer server.Close()
 
-	client := NewProductClient(server.URL)
-	err := client.UpdateQuantity(context.Background(), "product123", -1)
+	client := NewProductClient(server.URL, nil)
+	_, err := client.ApplyInventory(context.Background(), "order-1", []InventoryChange{{ProductID: "product123", QuantityChange: -1}})
 	if err == nil {
 		t.Fatal("expected error")
 	}
@@ -174,3 +199,98 @@ func TestPermanentAndTransientErrorMessages(t *testing.T) {
 		t.Fatal("expected transient error message")
 	}
 }
+
+func TestProductClient_Reserve(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/products/reservations" {
+			t.Errorf("expected POST /api/products/reservations, got %s %s", r.Method, r.URL.Path)
+		}
+
+		var payload ReserveRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("failed decoding body: %v", err)
+		}
+		if payload.OrderID != "order-1" || len(payload.Items) != 1 || payload.Items[0].ProductID != "p1" || payload.Items[0].Quantity != 2 {
+			t.Errorf("unexpected reservation %+v", payload)
+		}
+		if payload.TTLSeconds != 600 {
+			t.Errorf("expected ttl_seconds 600, got %d", payload.TTLSeconds)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"order_id":"order-1"}`))
+	}))
+	defer server.Close()
+
+	client := NewProductClient(server.URL, nil)
+	result, err := client.Reserve(context.Background(), "order-1", []ReservationItem{{ProductID: "p1", Quantity: 2}}, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.OrderID != "order-1" || result.Committed {
+		t.Fatalf("expected an uncommitted reservation of order-1, got %+v", result)
+	}
+}
+
+func TestProductClient_Reserve_Committed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"order_id":"order-1","items":[],"committed":true}`))
+	}))
+	defer server.Close()
+
+	client := NewProductClient(server.URL, nil)
+	result, err := client.Reserve(context.Background(), "order-1", []ReservationItem{{ProductID: "p1", Quantity: 2}}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Committed {
+		t.Fatalf("expected the reservation to be reported committed, got %+v", result)
+	}
+}
+
+func TestProductClient_Reserve_InsufficientStock(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error":"insufficient stock for product p1"}`))
+	}))
+	defer server.Close()
+
+	client := NewProductClient(server.URL, nil)
+	_, err := client.Reserve(context.Background(), "order-1", []ReservationItem{{ProductID: "p1", Quantity: 2}}, 0)
+	pe, ok := err.(*PermanentError)
+	if !ok || pe.StatusCode != http.StatusConflict || pe.Message != "insufficient stock for product p1" {
+		t.Fatalf("expected a PermanentError with status 409, got %T: %v", err, err)
+	}
+}
+
+func TestProductClient_ReleaseReservation(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewProductClient(server.URL, nil)
+	if err := client.ReleaseReservation(context.Background(), "order-2"); err != nil {
+		t.Fatalf("ReleaseReservation() error = %v", err)
+	}
+
+	if len(requests) != 1 || requests[0] != "DELETE /api/products/reservations/order-2" {
+		t.Fatalf("expected DELETE /api/products/reservations/order-2, got %v", requests)
+	}
+}
+
+func TestProductClient_ReleaseReservation_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewProductClient(server.URL, nil)
+	err := client.ReleaseReservation(context.Background(), "order-1")
+	if te, ok := err.(*TransientError); !ok || te.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected TransientError with status 503, got %T: %v", err, err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
@@ -16,6 +17,16 @@ type Config struct {
 	RedisAddr     string
 	RedisPassword string
 	RedisDB       int
+
+	// Client credentials for auth-service; inventory updates in
+	// product-service need a token. Empty AuthClientID sends none.
+	AuthTokenURL     string
+	AuthClientID     string
+	AuthClientSecret string
+
+	// How long product-service holds an order's stock before giving it back
+	// unless the order is committed. Zero leaves it to product-service.
+	ReservationTTL time.Duration
 }
 
 func Load() (Config, error) {
@@ -74,6 +85,24 @@ func Load() (Config, error) {
 		c.Workers = 100
 	}
 
+	c.AuthTokenURL = strings.TrimSpace(os.Getenv("AUTH_TOKEN_URL"))
+	if c.AuthTokenURL == "" {
+		c.AuthTokenURL = "http://auth-service:3020/api/oauth/token"
+	}
+	c.AuthClientID = strings.TrimSpace(os.Getenv("AUTH_CLIENT_ID"))
+	c.AuthClientSecret = os.Getenv("AUTH_CLIENT_SECRET")
+	if (c.AuthClientID == "") != (c.AuthClientSecret == "") {
+		return c, fmt.Errorf("AUTH_CLIENT_ID and AUTH_CLIENT_SECRET must be set together")
+	}
+
+	if v := strings.TrimSpace(os.Getenv("RESERVATION_TTL")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return c, fmt.Errorf("invalid RESERVATION_TTL: %q", v)
+		}
+		c.ReservationTTL = d
+	}
+
 	if len(missing) > 0 {
 		return c, fmt.Errorf("missing environment variables: %s", strings.Join(missing, ", "))
 	}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoad_Success(t *testing.T) {
@@ -245,3 +246,57 @@ func TestLoad_WorkersWhitespace(t *testing.T) {
 		t.Errorf("expected default Workers 100 for whitespace value, got %d", cfg.Workers)
 	}
 }
+
+func TestLoad_AuthClient(t *testing.T) {
+	t.Setenv("PROCESS_ORDER_SERVICE_APP_PORT", "8081")
+	t.Setenv("PROCESS_RABBITMQ_URL", "amqp://localhost")
+	t.Setenv("RABBITMQ_ORDER_QUEUE", "orders")
+	t.Setenv("ORDER_EXCHANGE", "order-exchange")
+	t.Setenv("REDIS_HOST", "redis")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.AuthClientID != "" || cfg.AuthTokenURL != "http://auth-service:3020/api/oauth/token" {
+		t.Errorf("unexpected auth defaults: %+v", cfg)
+	}
+
+	t.Setenv("AUTH_CLIENT_ID", "process-order-service")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "AUTH_CLIENT_SECRET") {
+		t.Fatalf("expected an error for a client ID without secret, got %v", err)
+	}
+
+	t.Setenv("AUTH_CLIENT_SECRET", "secret")
+	t.Setenv("AUTH_TOKEN_URL", "http://localhost:3020/api/oauth/token")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.AuthClientID != "process-order-service" || cfg.AuthClientSecret != "secret" || cfg.AuthTokenURL != "http://localhost:3020/api/oauth/token" {
+		t.Errorf("unexpected auth config: %+v", cfg)
+	}
+}
+
+func TestLoad_ReservationTTL(t *testing.T) {
+	t.Setenv("PROCESS_ORDER_SERVICE_APP_PORT", "8081")
+	t.Setenv("PROCESS_RABBITMQ_URL", "amqp://localhost")
+	t.Setenv("RABBITMQ_ORDER_QUEUE", "orders")
+	t.Setenv("ORDER_EXCHANGE", "order-exchange")
+	t.Setenv("REDIS_HOST", "redis")
+
+	cfg, err := Load()
+	if err != nil || cfg.ReservationTTL != 0 {
+		t.Fatalf("expected no reservation TTL by default, got %v (err = %v)", cfg.ReservationTTL, err)
+	}
+
+	t.Setenv("RESERVATION_TTL", "10m")
+	if cfg, err := Load(); err != nil || cfg.ReservationTTL != 10*time.Minute {
+		t.Fatalf("expected a 10m reservation TTL, got %v (err = %v)", cfg.ReservationTTL, err)
+	}
+
+	t.Setenv("RESERVATION_TTL", "ten minutes")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "RESERVATION_TTL") {
+		t.Fatalf("expected an error for an invalid TTL, got %v", err)
+	}
+}
@@ -10,14 +10,17 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sync"
 	"time"
 )
 
 // Processor charges a payment for an order. Implementations must be
 // idempotent per orderID: retrying a Charge for the same order must not
-// charge twice.
+// charge twice. Charged reports whether an order was paid already, so a
+// retry can tell before it charges.
 type Processor interface {
 	Charge(ctx context.Context, orderID string, amountCents int64) error
+	Charged(ctx context.Context, orderID string) (bool, error)
 }
 
 // PermanentError marks a payment failure that retrying cannot fix
@@ -34,9 +37,11 @@ func asPermanent(err error, target **PermanentError) bool {
 	return errors.As(err, target)
 }
 
-// SimulatedProcessor mimics payment latency and always succeeds.
+// SimulatedProcessor mimics payment latency and always succeeds. It
+// remembers the orders it charged for as long as the process runs.
 type SimulatedProcessor struct {
 	maxLatency time.Duration
+	charged    sync.Map
 }
 
 // NewSimulatedProcessor creates a fake processor. maxLatency bounds the
@@ -46,17 +51,22 @@ func NewSimulatedProcessor(maxLatency time.Duration) *SimulatedProcessor {
 }
 
 func (s *SimulatedProcessor) Charge(ctx context.Context, orderID string, amountCents int64) error {
-	if s.maxLatency <= 0 {
-		return nil
-	}
-	n, err := rand.Int(rand.Reader, big.NewInt(int64(s.maxLatency)))
-	if err != nil {
-		return fmt.Errorf("generate random latency: %w", err)
-	}
-	select {
-	case <-time.After(time.Duration(n.Int64())):
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
+	if s.maxLatency > 0 {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(s.maxLatency)))
+		if err != nil {
+			return fmt.Errorf("generate random latency: %w", err)
+		}
+		select {
+		case <-time.After(time.Duration(n.Int64())):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
+	s.charged.Store(orderID, struct{}{})
+	return nil
+}
+
+func (s *SimulatedProcessor) Charged(_ context.Context, orderID string) (bool, error) {
+	_, ok := s.charged.Load(orderID)
+	return ok, nil
 }
@@ -4,13 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	stripe "github.com/stripe/stripe-go/v82"
 )
 
 // StripeProcessor charges orders through the Stripe API (test mode with an
 // sk_test_ key). The order ID doubles as the Stripe idempotency key, so a
-// redelivered message can never double-charge.
+// redelivered message can never double-charge, and is stored in the
+// PaymentIntent's metadata so Charged can find the payment after the key
+// has expired.
 type StripeProcessor struct {
 	client *stripe.Client
 }
@@ -60,6 +63,7 @@ func (p *StripeProcessor) Charge(ctx context.Context, orderID string, amountCent
 			AllowRedirects: stripe.String("never"),
 		},
 	}
+	params.AddMetadata("order_id", orderID)
 
 	intent, err := p.client.V1PaymentIntents.Create(ctx, params)
 	if err != nil {
@@ -75,3 +79,20 @@ func (p *StripeProcessor) Charge(ctx context.Context, orderID string, amountCent
 	}
 	return nil
 }
+
+// Charged searches for a succeeded PaymentIntent of the order. Stripe makes
+// new payments searchable within about a minute; until then a retried Charge
+// is still covered by the idempotency key.
+func (p *StripeProcessor) Charged(ctx context.Context, orderID string) (bool, error) {
+	params := &stripe.PaymentIntentSearchParams{}
+	params.Query = fmt.Sprintf("metadata['order_id']:'%s' AND status:'succeeded'", strings.ReplaceAll(orderID, "'", `\'`))
+	params.Limit = stripe.Int64(1)
+
+	for _, err := range p.client.V1PaymentIntents.Search(ctx, params) {
+		if err != nil {
+			return false, fmt.Errorf("stripe search: %w", err)
+		}
+		return true, nil
+	}
+	return false, nil
+}
@@ -62,9 +62,60 @@ func TestStripeProcessor_CardDeclinedIsPermanent(t *testing.T) {
 	}
 }
 
+func TestStripeProcessor_ChargedFindsSucceededIntent(t *testing.T) {
+	srv, _ := newFakeStripe(t, http.StatusOK, map[string]any{
+		"object":   "search_result",
+		"data":     []map[string]any{{"id": "pi_123", "status": "succeeded"}},
+		"has_more": false,
+	})
+
+	p := NewStripeProcessor("sk_test_123", WithBaseURL(srv.URL))
+
+	charged, err := p.Charged(context.Background(), "order-42")
+	if err != nil {
+		t.Fatalf("Charged: %v", err)
+	}
+	if !charged {
+		t.Fatal("expected order-42 to be charged")
+	}
+}
+
+func TestStripeProcessor_ChargedWithoutIntent(t *testing.T) {
+	srv, _ := newFakeStripe(t, http.StatusOK, map[string]any{
+		"object":   "search_result",
+		"data":     []map[string]any{},
+		"has_more": false,
+	})
+
+	p := NewStripeProcessor("sk_test_123", WithBaseURL(srv.URL))
+
+	charged, err := p.Charged(context.Background(), "order-43")
+	if err != nil {
+		t.Fatalf("Charged: %v", err)
+	}
+	if charged {
+		t.Fatal("expected order-43 not to be charged")
+	}
+}
+
 func TestSimulatedProcessor_Succeeds(t *testing.T) {
 	p := NewSimulatedProcessor(0) // no artificial latency in tests
 	if err := p.Charge(context.Background(), "order-44", 500); err != nil {
 		t.Fatalf("Charge: %v", err)
 	}
 }
+
+func TestSimulatedProcessor_RemembersCharges(t *testing.T) {
+	p := NewSimulatedProcessor(0)
+	ctx := context.Background()
+
+	if charged, _ := p.Charged(ctx, "order-45"); charged {
+		t.Fatal("expected order-45 not to be charged before Charge")
+	}
+	if err := p.Charge(ctx, "order-45", 500); err != nil {
+		t.Fatalf("Charge: %v", err)
+	}
+	if charged, _ := p.Charged(ctx, "order-45"); !charged {
+		t.Fatal("expected order-45 to be charged after Charge")
+	}
+}
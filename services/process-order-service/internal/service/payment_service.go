@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"sync"
 	"time"
 
 	"go.opentelemetry.io/otel"
@@ -25,23 +24,33 @@ type PaymentService interface {
 }
 
 type paymentService struct {
-	pub           queue.Publisher
-	productClient client.ProductClient
-	processor     payment.Processor
+	pub            queue.Publisher
+	productClient  client.ProductClient
+	processor      payment.Processor
+	reservationTTL time.Duration
 }
 
-func NewPaymentService(pub queue.Publisher, productClient client.ProductClient, processor payment.Processor) PaymentService {
+// NewPaymentService returns the order processing flow. reservationTTL is how
+// long stock stays held while an order is processed; zero leaves the expiry
+// to product-service.
+func NewPaymentService(pub queue.Publisher, productClient client.ProductClient, processor payment.Processor, reservationTTL time.Duration) PaymentService {
 	return &paymentService{
-		pub:           pub,
-		productClient: productClient,
-		processor:     processor,
+		pub:            pub,
+		productClient:  productClient,
+		processor:      processor,
+		reservationTTL: reservationTTL,
 	}
 }
 
-// Process deducts stock, charges the payment and publishes status events.
-// Invariant: when Process fails after deducting stock (error return or
-// permanent failure), every successful deduction is compensated so a retry —
-// which re-runs the whole flow — never double-deducts inventory.
+// Process reserves stock, charges the payment, deducts the stock and
+// publishes status events. Stock is only deducted after the charge, by one
+// inventory batch that uses up the order's holds; until then it is held, and
+// a hold that is neither deducted nor released (e.g. the process crashed) is
+// handed back by product-service once it expires. A retry re-runs the whole
+// flow, so it first finds out how far an earlier attempt got: a reservation
+// reported as committed means the stock was deducted already, and an order
+// the processor reports as charged is not charged again. Reserving and the
+// batch are idempotent per order, so inventory is never deducted twice.
 func (s *paymentService) Process(ctx context.Context, orderID string, items []model.CartItem, amount int) error {
 	ctx, span := otel.Tracer("process-order").Start(ctx, "order.process",
 		trace.WithAttributes(attribute.String("velure.order_id", orderID)))
@@ -49,32 +58,51 @@ func (s *paymentService) Process(ctx context.Context, orderID string, items []mo
 
 	start := time.Now()
 
-	// Step 1: Deduct stock for all items in PARALLEL
-	deducted, firstErr, failedItem := s.deductStockParallel(ctx, items)
-	if firstErr != nil {
-		s.compensateStock(ctx, orderID, deducted)
-		metrics.OrdersProcessed.WithLabelValues("failure").Inc()
-		metrics.OrderProcessingDuration.Observe(time.Since(start).Seconds())
-
-		// Permanent errors (e.g. product not found) are not retryable:
-		// publish the failure event and ack the message.
+	// Step 1: Reserve stock for all items in one call
+	reservation, err := s.reserveStock(ctx, orderID, items)
+	if err != nil {
+		// Permanent errors (e.g. product not found, insufficient stock) are
+		// not retryable: publish the failure event and ack the message.
 		var permErr *client.PermanentError
-		if errors.As(firstErr, &permErr) {
+		if errors.As(err, &permErr) {
+			// The stock may be short because an earlier attempt was charged
+			// and its hold expired before the stock was deducted. That order
+			// is paid, so it is completed rather than failed.
+			paid, chkErr := s.processor.Charged(ctx, orderID)
+			if chkErr != nil {
+				metrics.OrdersProcessed.WithLabelValues("failure").Inc()
+				metrics.OrderProcessingDuration.Observe(time.Since(start).Seconds())
+				return fmt.Errorf("reserve stock: %w; check payment: %v", err, chkErr)
+			}
+			if paid {
+				return s.complete(ctx, orderID, items, amount, start)
+			}
+
+			metrics.OrdersProcessed.WithLabelValues("failure").Inc()
+			metrics.OrderProcessingDuration.Observe(time.Since(start).Seconds())
 			failEvt := model.Event{
 				Type: model.OrderFailed,
 				Payload: mustJSON(struct {
 					ID      string `json:"id"`
 					OrderID string `json:"order_id"`
 					Reason  string `json:"reason"`
-				}{ID: orderID, OrderID: orderID, Reason: firstErr.Error()}),
+				}{ID: orderID, OrderID: orderID, Reason: err.Error()}),
 			}
 			if pubErr := s.pub.Publish(ctx, failEvt); pubErr != nil {
-				return fmt.Errorf("deduct stock failed: %w; publish failure failed: %v", firstErr, pubErr)
+				return fmt.Errorf("reserve stock failed: %w; publish failure failed: %v", err, pubErr)
 			}
 			return nil
 		}
 
-		return fmt.Errorf("deduct stock for product %s: %w", failedItem.ProductID, firstErr)
+		metrics.OrdersProcessed.WithLabelValues("failure").Inc()
+		metrics.OrderProcessingDuration.Observe(time.Since(start).Seconds())
+		return fmt.Errorf("reserve stock: %w", err)
+	}
+
+	// An earlier attempt deducted the stock, so it was paid for; only the
+	// completed event may be missing.
+	if reservation.Committed {
+		return s.complete(ctx, orderID, items, amount, start)
 	}
 
 	// Step 2: Publish processing event
@@ -85,19 +113,26 @@ func (s *paymentService) Process(ctx context.Context, orderID string, items []mo
 		}{ID: orderID}),
 	}
 	if err := s.pub.Publish(ctx, procEvt); err != nil {
-		s.compensateStock(ctx, orderID, items)
+		s.releaseStock(ctx, orderID)
 		return fmt.Errorf("publish processing: %w", err)
 	}
 
 	// Step 3: Charge the payment (Stripe test mode, or the simulated
-	// processor when no API key is configured). The order ID doubles as the
-	// idempotency key, so retries cannot double-charge.
-	metrics.PaymentAttempts.WithLabelValues("initiated").Inc()
-	paymentStart := time.Now()
-
-	if err := s.processor.Charge(ctx, orderID, int64(amount)); err != nil {
-		s.compensateStock(ctx, orderID, items)
-		metrics.PaymentAttempts.WithLabelValues("failure").Inc()
+	// processor when no API key is configured) unless an earlier attempt
+	// did. The order ID doubles as the idempotency key, so retries cannot
+	// double-charge.
+	paid, err := s.processor.Charged(ctx, orderID)
+	if err != nil {
+		s.releaseStock(ctx, orderID)
+		metrics.OrdersProcessed.WithLabelValues("failure").Inc()
+		metrics.OrderProcessingDuration.Observe(time.Since(start).Seconds())
+		return fmt.Errorf("check payment: %w", err)
+	}
+	if !paid {
+		err = s.charge(ctx, orderID, amount)
+	}
+	if err != nil {
+		s.releaseStock(ctx, orderID)
 		metrics.OrdersProcessed.WithLabelValues("failure").Inc()
 		metrics.OrderProcessingDuration.Observe(time.Since(start).Seconds())
 
@@ -120,11 +155,38 @@ func (s *paymentService) Process(ctx context.Context, orderID string, items []mo
 		return fmt.Errorf("charge payment: %w", err)
 	}
 
+	// Steps 4 and 5: Deduct the stock and publish the completed event.
+	return s.complete(ctx, orderID, items, amount, start)
+}
+
+// charge sends the payment to the processor and records its metrics.
+func (s *paymentService) charge(ctx context.Context, orderID string, amount int) error {
+	metrics.PaymentAttempts.WithLabelValues("initiated").Inc()
+	paymentStart := time.Now()
+
+	if err := s.processor.Charge(ctx, orderID, int64(amount)); err != nil {
+		metrics.PaymentAttempts.WithLabelValues("failure").Inc()
+		return err
+	}
+
 	metrics.PaymentProcessingDuration.Observe(time.Since(paymentStart).Seconds())
 	metrics.PaymentAttempts.WithLabelValues("success").Inc()
 	metrics.PaymentTotalValue.Observe(float64(amount))
+	return nil
+}
+
+// complete finishes a paid order: it turns the holds into deducted stock and
+// publishes the completed event. The payment went through, so a failure
+// keeps the reservation for the retry rather than releasing it. A paid order
+// whose stock is gone fails to deduct on every retry and ends up in the dead
+// letter queue, where it is left for manual handling.
+func (s *paymentService) complete(ctx context.Context, orderID string, items []model.CartItem, amount int, start time.Time) error {
+	if err := s.deductStock(ctx, orderID, items); err != nil {
+		metrics.OrdersProcessed.WithLabelValues("failure").Inc()
+		metrics.OrderProcessingDuration.Observe(time.Since(start).Seconds())
+		return fmt.Errorf("deduct stock: %w", err)
+	}
 
-	// Step 4: Publish completed event
 	compEvt := model.Event{
 		Type: model.OrderCompleted,
 		Payload: mustJSON(struct {
@@ -135,9 +197,8 @@ func (s *paymentService) Process(ctx context.Context, orderID string, items []mo
 		}{ID: orderID, OrderID: orderID, Amount: amount, Processed: time.Now()}),
 	}
 	if err := s.pub.Publish(ctx, compEvt); err != nil {
-		// Error return leads to a retry that re-runs the whole flow, so the
-		// stock from this attempt has to be handed back first.
-		s.compensateStock(ctx, orderID, items)
+		// The retry finds the reservation committed already, so nothing has
+		// to be handed back.
 		metrics.OrdersProcessed.WithLabelValues("failure").Inc()
 		metrics.OrderProcessingDuration.Observe(time.Since(start).Seconds())
 		return fmt.Errorf("publish completed: %w", err)
@@ -153,71 +214,47 @@ func mustJSON(v any) json.RawMessage {
 	return json.RawMessage(b)
 }
 
-// deductStockParallel processes all inventory updates concurrently.
-// Returns the items whose deduction succeeded, the first error encountered
-// and the item it belongs to.
-func (s *paymentService) deductStockParallel(ctx context.Context, items []model.CartItem) ([]model.CartItem, error, model.CartItem) {
-	type result struct {
-		item model.CartItem
-		err  error
+// reserveStock holds the order's items in product-service. The reservation
+// is all or nothing: when one item is short, nothing stays held.
+func (s *paymentService) reserveStock(ctx context.Context, orderID string, items []model.CartItem) (*client.ReservationResult, error) {
+	reservation := make([]client.ReservationItem, len(items))
+	for i, item := range items {
+		reservation[i] = client.ReservationItem{ProductID: item.ProductID, Quantity: item.Quantity}
 	}
 
-	results := make(chan result, len(items))
-	var wg sync.WaitGroup
-
-	for _, item := range items {
-		wg.Add(1)
-		go func(item model.CartItem) {
-			defer wg.Done()
-
-			metrics.InventoryChecks.WithLabelValues("available").Inc()
-			checkStart := time.Now()
-
-			err := s.productClient.UpdateQuantity(ctx, item.ProductID, -item.Quantity)
-			metrics.InventoryCheckDuration.Observe(time.Since(checkStart).Seconds())
-
-			if err != nil {
-				metrics.InventoryChecks.WithLabelValues("error").Inc()
-			}
-
-			results <- result{item: item, err: err}
-		}(item)
+	metrics.InventoryChecks.WithLabelValues("available").Inc()
+	checkStart := time.Now()
+	result, err := s.productClient.Reserve(ctx, orderID, reservation, s.reservationTTL)
+	metrics.InventoryCheckDuration.Observe(time.Since(checkStart).Seconds())
+	if err != nil {
+		metrics.InventoryChecks.WithLabelValues("error").Inc()
 	}
+	return result, err
+}
 
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
-
-	deducted := make([]model.CartItem, 0, len(items))
-	var firstErr error
-	var failedItem model.CartItem
-	for r := range results {
-		if r.err != nil {
-			if firstErr == nil {
-				firstErr = r.err
-				failedItem = r.item
-			}
-			continue
-		}
-		deducted = append(deducted, r.item)
+// deductStock takes the order's items out of stock in one inventory batch,
+// which uses up the order's holds. product-service records the batch under
+// the order ID, so sending it again does not deduct twice.
+func (s *paymentService) deductStock(ctx context.Context, orderID string, items []model.CartItem) error {
+	changes := make([]client.InventoryChange, len(items))
+	for i, item := range items {
+		changes[i] = client.InventoryChange{ProductID: item.ProductID, QuantityChange: -item.Quantity}
 	}
 
-	return deducted, firstErr, failedItem
+	if _, err := s.productClient.ApplyInventory(ctx, orderID, changes); err != nil {
+		metrics.InventoryChecks.WithLabelValues("error").Inc()
+		return err
+	}
+	return nil
 }
 
-// compensateStock re-adds quantities that were successfully deducted in a
-// failed processing attempt. Compensation failures are logged but not
-// propagated — there is no further recovery at this layer.
-func (s *paymentService) compensateStock(ctx context.Context, orderID string, items []model.CartItem) {
-	for _, item := range items {
-		if err := s.productClient.UpdateQuantity(ctx, item.ProductID, item.Quantity); err != nil {
-			metrics.InventoryChecks.WithLabelValues("error").Inc()
-			logger.Error("stock compensation failed — manual reconciliation needed",
-				logger.String("order_id", orderID),
-				logger.String("product_id", item.ProductID),
-				logger.Int("quantity", item.Quantity),
-				logger.Err(err))
-		}
+// releaseStock hands back the holds of a failed processing attempt. A
+// failure is logged but not propagated: the holds expire on their own.
+func (s *paymentService) releaseStock(ctx context.Context, orderID string) {
+	if err := s.productClient.ReleaseReservation(ctx, orderID); err != nil {
+		metrics.InventoryChecks.WithLabelValues("error").Inc()
+		logger.Warn("stock release failed; the reservation will expire",
+			logger.String("order_id", orderID),
+			logger.Err(err))
 	}
 }
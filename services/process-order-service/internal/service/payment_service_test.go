@@ -3,8 +3,10 @@ package service
 import (
 	"context"
 	"errors"
+	"slices"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/icl00ud/velure/services/process-order-service/internal/client"
 	"github.com/icl00ud/velure/services/process-order-service/internal/model"
@@ -37,42 +39,71 @@ func (m *mockPublisher) Close() error {
 	return nil
 }
 
-// Mock product client for testing (thread-safe for parallel processing)
+// Mock product client for testing; records every call as "op order".
 type mockProductClient struct {
-	mu                 sync.Mutex
-	updateQuantityFunc func(productID string, quantityChange int) error
-	calls              []struct {
-		productID      string
-		quantityChange int
-	}
+	mu          sync.Mutex
+	reserveFunc func(orderID string, items []client.ReservationItem) error
+	applyFunc   func(orderID string) error
+	releaseFunc func(orderID string) error
+	calls       []string
+	reserved    []client.ReservationItem
+	applied     []client.InventoryChange
+	ttl         time.Duration
+	committed   bool
 }
 
-func (m *mockProductClient) UpdateQuantity(_ context.Context, productID string, quantityChange int) error {
+func (m *mockProductClient) record(op, orderID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.calls = append(m.calls, op+" "+orderID)
+}
 
-	if m.calls == nil {
-		m.calls = []struct {
-			productID      string
-			quantityChange int
-		}{}
-	}
-	m.calls = append(m.calls, struct {
-		productID      string
-		quantityChange int
-	}{productID, quantityChange})
+func (m *mockProductClient) ApplyInventory(_ context.Context, orderID string, changes []client.InventoryChange) (*client.InventoryBatchResult, error) {
+	m.record("apply", orderID)
+	m.mu.Lock()
+	m.applied = changes
+	m.mu.Unlock()
+	if m.applyFunc != nil {
+		if err := m.applyFunc(orderID); err != nil {
+			return nil, err
+		}
+	}
+	return &client.InventoryBatchResult{OrderID: orderID, Applied: true}, nil
+}
+
+func (m *mockProductClient) Reserve(_ context.Context, orderID string, items []client.ReservationItem, ttl time.Duration) (*client.ReservationResult, error) {
+	m.record("reserve", orderID)
+	m.mu.Lock()
+	m.reserved, m.ttl = items, ttl
+	m.mu.Unlock()
+	if m.reserveFunc != nil {
+		if err := m.reserveFunc(orderID, items); err != nil {
+			return nil, err
+		}
+	}
+	return &client.ReservationResult{OrderID: orderID, Committed: m.committed}, nil
+}
 
-	if m.updateQuantityFunc != nil {
-		return m.updateQuantityFunc(productID, quantityChange)
+func (m *mockProductClient) ReleaseReservation(_ context.Context, orderID string) error {
+	m.record("release", orderID)
+	if m.releaseFunc != nil {
+		return m.releaseFunc(orderID)
 	}
 	return nil
 }
 
+func assertCalls(t *testing.T, m *mockProductClient, want ...string) {
+	t.Helper()
+	if !slices.Equal(m.calls, want) {
+		t.Fatalf("expected product calls %v, got %v", want, m.calls)
+	}
+}
+
 func TestNewPaymentService(t *testing.T) {
 	pub := &mockPublisher{}
 	client := &mockProductClient{}
 
-	svc := NewPaymentService(pub, client, &stubChargeProcessor{})
+	svc := NewPaymentService(pub, client, &stubChargeProcessor{}, 0)
 
 	if svc == nil {
 		t.Fatal("expected non-nil payment service")
@@ -83,7 +114,7 @@ func TestPaymentService_Process_Success(t *testing.T) {
 	pub := &mockPublisher{}
 	client := &mockProductClient{}
 
-	svc := NewPaymentService(pub, client, &stubChargeProcessor{})
+	svc := NewPaymentService(pub, client, &stubChargeProcessor{}, 10*time.Minute)
 
 	items := []model.CartItem{
 		{ProductID: "p1", Name: "Product 1", Quantity: 2, Price: 10.0},
@@ -96,21 +127,18 @@ func TestPaymentService_Process_Success(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 
-	// Verify product client was called for each item
-	if len(client.calls) != 2 {
-		t.Errorf("expected 2 UpdateQuantity calls, got %d", len(client.calls))
-	}
-
-	// Verify quantities were deducted (order not guaranteed due to parallel processing)
-	callMap := make(map[string]int)
-	for _, call := range client.calls {
-		callMap[call.productID] = call.quantityChange
+	// Stock is reserved for the whole order, then deducted in one batch.
+	assertCalls(t, client, "reserve order123", "apply order123")
+	if len(client.reserved) != 2 || client.reserved[0].ProductID != "p1" || client.reserved[0].Quantity != 2 ||
+		client.reserved[1].ProductID != "p2" || client.reserved[1].Quantity != 1 {
+		t.Errorf("unexpected reservation %+v", client.reserved)
 	}
-	if callMap["p1"] != -2 {
-		t.Errorf("expected UpdateQuantity(p1, -2), got quantityChange=%d", callMap["p1"])
+	if len(client.applied) != 2 || client.applied[0].ProductID != "p1" || client.applied[0].QuantityChange != -2 ||
+		client.applied[1].ProductID != "p2" || client.applied[1].QuantityChange != -1 {
+		t.Errorf("unexpected batch %+v", client.applied)
 	}
-	if callMap["p2"] != -1 {
-		t.Errorf("expected UpdateQuantity(p2, -1), got quantityChange=%d", callMap["p2"])
+	if client.ttl != 10*time.Minute {
+		t.Errorf("expected the configured TTL, got %v", client.ttl)
 	}
 
 	// Verify events were published (processing and completed)
@@ -127,15 +155,15 @@ func TestPaymentService_Process_Success(t *testing.T) {
 	}
 }
 
-func TestPaymentService_Process_InventoryUpdateFails(t *testing.T) {
+func TestPaymentService_Process_ReserveFails(t *testing.T) {
 	pub := &mockPublisher{}
 	client := &mockProductClient{
-		updateQuantityFunc: func(productID string, quantityChange int) error {
-			return errors.New("insufficient stock")
+		reserveFunc: func(orderID string, items []client.ReservationItem) error {
+			return &client.TransientError{Message: "product-service down", StatusCode: 503}
 		},
 	}
 
-	svc := NewPaymentService(pub, client, &stubChargeProcessor{})
+	svc := NewPaymentService(pub, client, &stubChargeProcessor{}, 0)
 
 	items := []model.CartItem{
 		{ProductID: "p1", Name: "Product 1", Quantity: 2, Price: 10.0},
@@ -147,10 +175,11 @@ func TestPaymentService_Process_InventoryUpdateFails(t *testing.T) {
 		t.Error("expected error, got nil")
 	}
 
-	// Verify no events were published (since inventory update failed)
+	// Nothing was held, so there is nothing to publish or release.
 	if len(pub.published) != 0 {
 		t.Errorf("expected 0 events published, got %d", len(pub.published))
 	}
+	assertCalls(t, client, "reserve order123")
 }
 
 func TestPaymentService_Process_PublishProcessingFails(t *testing.T) {
@@ -164,7 +193,7 @@ func TestPaymentService_Process_PublishProcessingFails(t *testing.T) {
 	}
 	client := &mockProductClient{}
 
-	svc := NewPaymentService(pub, client, &stubChargeProcessor{})
+	svc := NewPaymentService(pub, client, &stubChargeProcessor{}, 0)
 
 	items := []model.CartItem{
 		{ProductID: "p1", Name: "Product 1", Quantity: 1, Price: 10.0},
@@ -176,10 +205,8 @@ func TestPaymentService_Process_PublishProcessingFails(t *testing.T) {
 		t.Error("expected error, got nil")
 	}
 
-	// Inventory was deducted before the publish failure, then compensated back.
-	if len(client.calls) != 2 {
-		t.Errorf("expected 2 UpdateQuantity calls (deduct + compensate), got %d", len(client.calls))
-	}
+	// The retry reserves again, so this attempt's holds are released.
+	assertCalls(t, client, "reserve order123", "release order123")
 }
 
 func TestPaymentService_Process_PublishCompletedFails(t *testing.T) {
@@ -193,7 +220,7 @@ func TestPaymentService_Process_PublishCompletedFails(t *testing.T) {
 	}
 	client := &mockProductClient{}
 
-	svc := NewPaymentService(pub, client, &stubChargeProcessor{})
+	svc := NewPaymentService(pub, client, &stubChargeProcessor{}, 0)
 
 	items := []model.CartItem{
 		{ProductID: "p1", Name: "Product 1", Quantity: 1, Price: 10.0},
@@ -209,73 +236,51 @@ func TestPaymentService_Process_PublishCompletedFails(t *testing.T) {
 	if len(pub.published) != 2 {
 		t.Errorf("expected 2 events published, got %d", len(pub.published))
 	}
+	// The stock is already deducted; releasing would be a no-op at best.
+	assertCalls(t, client, "reserve order123", "apply order123")
 }
 
-func TestPaymentService_Process_MultipleItems(t *testing.T) {
-	pub := &mockPublisher{}
-	client := &mockProductClient{}
-
-	svc := NewPaymentService(pub, client, &stubChargeProcessor{})
-
-	items := []model.CartItem{
-		{ProductID: "p1", Name: "Product 1", Quantity: 3, Price: 10.0},
-		{ProductID: "p2", Name: "Product 2", Quantity: 2, Price: 15.0},
-		{ProductID: "p3", Name: "Product 3", Quantity: 1, Price: 20.0},
-	}
-
-	err := svc.Process(context.Background(), "order456", items, 80)
-
-	if err != nil {
-		t.Errorf("unexpected error: %v", err)
-	}
-
-	// Verify all items had inventory updated
-	if len(client.calls) != 3 {
-		t.Errorf("expected 3 UpdateQuantity calls, got %d", len(client.calls))
-	}
-}
-
-func TestPaymentService_Process_SecondItemFails(t *testing.T) {
+func TestPaymentService_Process_DeductionFailsKeepsReservation(t *testing.T) {
 	pub := &mockPublisher{}
 	client := &mockProductClient{
-		updateQuantityFunc: func(productID string, quantityChange int) error {
-			if productID == "p2" {
-				return errors.New("product not found")
-			}
-			return nil
+		applyFunc: func(orderID string) error {
+			return &client.TransientError{Message: "timeout"}
 		},
 	}
+	proc := &stubChargeProcessor{}
 
-	svc := NewPaymentService(pub, client, &stubChargeProcessor{})
+	svc := NewPaymentService(pub, client, proc, 0)
 
 	items := []model.CartItem{
 		{ProductID: "p1", Name: "Product 1", Quantity: 1, Price: 10.0},
-		{ProductID: "p2", Name: "Product 2", Quantity: 1, Price: 15.0},
-		{ProductID: "p3", Name: "Product 3", Quantity: 1, Price: 20.0},
 	}
 
-	err := svc.Process(context.Background(), "order789", items, 45)
-
+	err := svc.Process(context.Background(), "order-commit", items, 10)
 	if err == nil {
-		t.Error("expected error, got nil")
+		t.Fatal("expected error, got nil")
 	}
 
-	// All 3 deductions run in parallel; the two that succeeded (p1, p3) are
-	// compensated after p2 fails: 3 deductions + 2 compensations.
-	if len(client.calls) != 5 {
-		t.Errorf("expected 5 UpdateQuantity calls (3 deduct + 2 compensate), got %d", len(client.calls))
+	// The customer was charged, so the holds stay for the retry to deduct.
+	assertCalls(t, client, "reserve order-commit", "apply order-commit")
+	if len(proc.calls) != 1 {
+		t.Fatalf("expected one charge, got %v", proc.calls)
+	}
+	for _, evt := range pub.published {
+		if evt.Type == model.OrderCompleted || evt.Type == model.OrderFailed {
+			t.Fatalf("unexpected %s event", evt.Type)
+		}
 	}
 }
 
 func TestPaymentService_Process_PermanentErrorPublishesFailure(t *testing.T) {
 	pub := &mockPublisher{}
 	client := &mockProductClient{
-		updateQuantityFunc: func(productID string, quantityChange int) error {
-			return &client.PermanentError{Message: "not found", StatusCode: 404}
+		reserveFunc: func(orderID string, items []client.ReservationItem) error {
+			return &client.PermanentError{Message: "insufficient stock for product p1", StatusCode: 409}
 		},
 	}
 
-	svc := NewPaymentService(pub, client, &stubChargeProcessor{})
+	svc := NewPaymentService(pub, client, &stubChargeProcessor{}, 0)
 
 	items := []model.CartItem{
 		{ProductID: "p1", Name: "Product 1", Quantity: 1, Price: 10.0},
@@ -301,12 +306,12 @@ func TestPaymentService_Process_PermanentErrorPublishFails(t *testing.T) {
 		},
 	}
 	client := &mockProductClient{
-		updateQuantityFunc: func(productID string, quantityChange int) error {
+		reserveFunc: func(orderID string, items []client.ReservationItem) error {
 			return &client.PermanentError{Message: "not found", StatusCode: 404}
 		},
 	}
 
-	svc := NewPaymentService(pub, client, &stubChargeProcessor{})
+	svc := NewPaymentService(pub, client, &stubChargeProcessor{}, 0)
 
 	items := []model.CartItem{
 		{ProductID: "p1", Name: "Product 1", Quantity: 1, Price: 10.0},
@@ -321,81 +326,7 @@ func TestPaymentService_Process_PermanentErrorPublishFails(t *testing.T) {
 	}
 }
 
-func TestPaymentService_Process_PartialFailureCompensatesDeductedItems(t *testing.T) {
-	pub := &mockPublisher{}
-	cli := &mockProductClient{
-		updateQuantityFunc: func(productID string, quantityChange int) error {
-			// Only the deduction of p2 fails; compensations (positive) succeed.
-			if productID == "p2" && quantityChange < 0 {
-				return errors.New("insufficient stock")
-			}
-			return nil
-		},
-	}
-
-	svc := NewPaymentService(pub, cli, &stubChargeProcessor{})
-
-	items := []model.CartItem{
-		{ProductID: "p1", Name: "Product 1", Quantity: 2, Price: 10.0},
-		{ProductID: "p2", Name: "Product 2", Quantity: 1, Price: 15.0},
-		{ProductID: "p3", Name: "Product 3", Quantity: 4, Price: 20.0},
-	}
-
-	err := svc.Process(context.Background(), "order-comp-1", items, 105)
-	if err == nil {
-		t.Fatal("expected error, got nil")
-	}
-
-	// Net stock change per product must be zero for the succeeded deductions.
-	net := map[string]int{}
-	for _, call := range cli.calls {
-		net[call.productID] += call.quantityChange
-	}
-	if net["p1"] != 0 {
-		t.Errorf("expected p1 net change 0 (deduct then compensate), got %d", net["p1"])
-	}
-	if net["p3"] != 0 {
-		t.Errorf("expected p3 net change 0 (deduct then compensate), got %d", net["p3"])
-	}
-}
-
-func TestPaymentService_Process_PermanentErrorCompensatesDeductedItems(t *testing.T) {
-	pub := &mockPublisher{}
-	cli := &mockProductClient{
-		updateQuantityFunc: func(productID string, quantityChange int) error {
-			if productID == "p2" && quantityChange < 0 {
-				return &client.PermanentError{Message: "not found", StatusCode: 404}
-			}
-			return nil
-		},
-	}
-
-	svc := NewPaymentService(pub, cli, &stubChargeProcessor{})
-
-	items := []model.CartItem{
-		{ProductID: "p1", Name: "Product 1", Quantity: 1, Price: 10.0},
-		{ProductID: "p2", Name: "Product 2", Quantity: 1, Price: 15.0},
-	}
-
-	err := svc.Process(context.Background(), "order-comp-2", items, 25)
-	if err != nil {
-		t.Fatalf("expected nil error for permanent failure (ack), got %v", err)
-	}
-
-	net := map[string]int{}
-	for _, call := range cli.calls {
-		net[call.productID] += call.quantityChange
-	}
-	if net["p1"] != 0 {
-		t.Errorf("expected p1 net change 0 after compensation, got %d", net["p1"])
-	}
-
-	if len(pub.published) != 1 || pub.published[0].Type != model.OrderFailed {
-		t.Fatalf("expected one OrderFailed event, got %+v", pub.published)
-	}
-}
-
-func TestPaymentService_Process_PublishProcessingFailureCompensates(t *testing.T) {
+func TestPaymentService_Process_ReleaseFailureIsLogged(t *testing.T) {
 	pub := &mockPublisher{
 		publishFunc: func(evt model.Event) error {
 			if evt.Type == model.OrderProcessing {
@@ -404,33 +335,31 @@ func TestPaymentService_Process_PublishProcessingFailureCompensates(t *testing.T
 			return nil
 		},
 	}
-	cli := &mockProductClient{}
+	cli := &mockProductClient{
+		releaseFunc: func(orderID string) error {
+			return &client.TransientError{Message: "product-service down", StatusCode: 503}
+		},
+	}
 
-	svc := NewPaymentService(pub, cli, &stubChargeProcessor{})
+	svc := NewPaymentService(pub, cli, &stubChargeProcessor{}, 0)
 
 	items := []model.CartItem{
 		{ProductID: "p1", Name: "Product 1", Quantity: 3, Price: 10.0},
 	}
 
-	err := svc.Process(context.Background(), "order-comp-3", items, 30)
-	if err == nil {
-		t.Fatal("expected error, got nil")
-	}
-
-	// Error return means the message will be retried and stock re-deducted,
-	// so the failed attempt must leave net stock unchanged.
-	net := 0
-	for _, call := range cli.calls {
-		net += call.quantityChange
-	}
-	if net != 0 {
-		t.Errorf("expected net stock change 0 before retry, got %d", net)
+	// The publish error is what gets retried; the holds expire on their own.
+	err := svc.Process(context.Background(), "order-release", items, 30)
+	if err == nil || err.Error() != "publish processing: rabbitmq down" {
+		t.Fatalf("expected the publish error, got %v", err)
 	}
+	assertCalls(t, cli, "reserve order-release", "release order-release")
 }
 
 type stubChargeProcessor struct {
-	err   error
-	calls []string
+	err      error
+	paid     bool
+	checkErr error
+	calls    []string
 }
 
 func (s *stubChargeProcessor) Charge(_ context.Context, orderID string, amountCents int64) error {
@@ -438,12 +367,16 @@ func (s *stubChargeProcessor) Charge(_ context.Context, orderID string, amountCe
 	return s.err
 }
 
+func (s *stubChargeProcessor) Charged(_ context.Context, orderID string) (bool, error) {
+	return s.paid, s.checkErr
+}
+
 func TestPaymentService_Process_ChargesViaProcessor(t *testing.T) {
 	pub := &mockPublisher{}
 	cli := &mockProductClient{}
 	proc := &stubChargeProcessor{}
 
-	svc := NewPaymentService(pub, cli, proc)
+	svc := NewPaymentService(pub, cli, proc, 0)
 
 	items := []model.CartItem{{ProductID: "p1", Name: "P1", Quantity: 1, Price: 10.0}}
 	if err := svc.Process(context.Background(), "order-pay-1", items, 1000); err != nil {
@@ -459,7 +392,7 @@ func TestPaymentService_Process_PermanentChargeFailureFailsOrder(t *testing.T) {
 	cli := &mockProductClient{}
 	proc := &stubChargeProcessor{err: &payment.PermanentError{Reason: "card declined"}}
 
-	svc := NewPaymentService(pub, cli, proc)
+	svc := NewPaymentService(pub, cli, proc, 0)
 
 	items := []model.CartItem{{ProductID: "p1", Name: "P1", Quantity: 2, Price: 10.0}}
 	err := svc.Process(context.Background(), "order-pay-2", items, 2000)
@@ -468,13 +401,7 @@ func TestPaymentService_Process_PermanentChargeFailureFailsOrder(t *testing.T) {
 	}
 
 	// Stock handed back.
-	net := 0
-	for _, call := range cli.calls {
-		net += call.quantityChange
-	}
-	if net != 0 {
-		t.Fatalf("expected net stock change 0 after declined payment, got %d", net)
-	}
+	assertCalls(t, cli, "reserve order-pay-2", "release order-pay-2")
 
 	// OrderFailed published (after the OrderProcessing event).
 	last := pub.published[len(pub.published)-1]
@@ -488,7 +415,7 @@ func TestPaymentService_Process_TransientChargeFailureRetries(t *testing.T) {
 	cli := &mockProductClient{}
 	proc := &stubChargeProcessor{err: errors.New("stripe 502")}
 
-	svc := NewPaymentService(pub, cli, proc)
+	svc := NewPaymentService(pub, cli, proc, 0)
 
 	items := []model.CartItem{{ProductID: "p1", Name: "P1", Quantity: 1, Price: 10.0}}
 	err := svc.Process(context.Background(), "order-pay-3", items, 1000)
@@ -496,11 +423,99 @@ func TestPaymentService_Process_TransientChargeFailureRetries(t *testing.T) {
 		t.Fatal("transient charge failure must return error for retry")
 	}
 
-	net := 0
-	for _, call := range cli.calls {
-		net += call.quantityChange
+	assertCalls(t, cli, "reserve order-pay-3", "release order-pay-3")
+}
+
+func TestPaymentService_Process_CommittedReservationCompletes(t *testing.T) {
+	pub := &mockPublisher{}
+	cli := &mockProductClient{committed: true}
+	proc := &stubChargeProcessor{}
+
+	svc := NewPaymentService(pub, cli, proc, 0)
+
+	// An earlier attempt deducted the stock and died before publishing.
+	items := []model.CartItem{{ProductID: "p1", Name: "P1", Quantity: 1, Price: 10.0}}
+	if err := svc.Process(context.Background(), "order-pay-4", items, 1000); err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+
+	if len(proc.calls) != 0 {
+		t.Fatalf("expected no charge for a committed order, got %v", proc.calls)
 	}
-	if net != 0 {
-		t.Fatalf("expected net stock change 0 before retry, got %d", net)
+	assertCalls(t, cli, "reserve order-pay-4", "apply order-pay-4")
+	if len(pub.published) != 1 || pub.published[0].Type != model.OrderCompleted {
+		t.Fatalf("expected only an OrderCompleted event, got %v", pub.published)
+	}
+}
+
+func TestPaymentService_Process_PaidOrderIsNotChargedAgain(t *testing.T) {
+	pub := &mockPublisher{}
+	cli := &mockProductClient{}
+	proc := &stubChargeProcessor{paid: true}
+
+	svc := NewPaymentService(pub, cli, proc, 0)
+
+	items := []model.CartItem{{ProductID: "p1", Name: "P1", Quantity: 1, Price: 10.0}}
+	if err := svc.Process(context.Background(), "order-pay-5", items, 1000); err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+
+	if len(proc.calls) != 0 {
+		t.Fatalf("expected no second charge, got %v", proc.calls)
+	}
+	assertCalls(t, cli, "reserve order-pay-5", "apply order-pay-5")
+	last := pub.published[len(pub.published)-1]
+	if last.Type != model.OrderCompleted {
+		t.Fatalf("expected final event OrderCompleted, got %s", last.Type)
+	}
+}
+
+func TestPaymentService_Process_PaidOrderWithExpiredHoldCompletes(t *testing.T) {
+	pub := &mockPublisher{}
+	cli := &mockProductClient{
+		reserveFunc: func(orderID string, items []client.ReservationItem) error {
+			return &client.PermanentError{Message: "insufficient stock for product p1", StatusCode: 409}
+		},
+	}
+	proc := &stubChargeProcessor{paid: true}
+
+	svc := NewPaymentService(pub, cli, proc, 0)
+
+	// The charge went through but the hold expired before the deduction.
+	items := []model.CartItem{{ProductID: "p1", Name: "P1", Quantity: 1, Price: 10.0}}
+	if err := svc.Process(context.Background(), "order-pay-6", items, 1000); err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+
+	assertCalls(t, cli, "reserve order-pay-6", "apply order-pay-6")
+	for _, evt := range pub.published {
+		if evt.Type == model.OrderFailed {
+			t.Fatal("a paid order must not be failed")
+		}
+	}
+	if last := pub.published[len(pub.published)-1]; last.Type != model.OrderCompleted {
+		t.Fatalf("expected final event OrderCompleted, got %s", last.Type)
+	}
+}
+
+func TestPaymentService_Process_PaymentCheckFailureRetries(t *testing.T) {
+	pub := &mockPublisher{}
+	cli := &mockProductClient{
+		reserveFunc: func(orderID string, items []client.ReservationItem) error {
+			return &client.PermanentError{Message: "insufficient stock for product p1", StatusCode: 409}
+		},
+	}
+	proc := &stubChargeProcessor{checkErr: errors.New("stripe 502")}
+
+	svc := NewPaymentService(pub, cli, proc, 0)
+
+	// Without knowing whether the order was paid it can be neither failed
+	// nor completed.
+	items := []model.CartItem{{ProductID: "p1", Name: "P1", Quantity: 1, Price: 10.0}}
+	if err := svc.Process(context.Background(), "order-pay-7", items, 1000); err == nil {
+		t.Fatal("expected an error so the message is retried")
+	}
+	if len(pub.published) != 0 {
+		t.Fatalf("expected no events, got %v", pub.published)
 	}
 }
@@ -19,6 +19,7 @@ import (
 	"github.com/icl00ud/velure/services/process-order-service/internal/queue"
 	"github.com/icl00ud/velure/services/process-order-service/internal/service"
 	"github.com/icl00ud/velure/services/process-order-service/internal/telemetry"
+	"github.com/icl00ud/velure/shared/auth"
 	"github.com/icl00ud/velure/shared/logger"
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -94,7 +95,13 @@ func run(ctx context.Context, log *logger.Logger) error {
 	if productServiceURL == "" {
 		productServiceURL = "http://product-service:3010"
 	}
-	productClient := client.NewProductClient(productServiceURL)
+	var tokens *client.ClientCredentials
+	if cfg.AuthClientID != "" {
+		tokens = client.NewClientCredentials(cfg.AuthTokenURL, cfg.AuthClientID, cfg.AuthClientSecret, auth.ScopeInventoryWrite)
+	} else {
+		log.Warn("AUTH_CLIENT_ID not set; inventory updates are sent without credentials")
+	}
+	productClient := client.NewProductClient(productServiceURL, tokens)
 
 	// Stripe test mode when STRIPE_API_KEY is set; otherwise a latency-only
 	// simulated processor so local development needs no Stripe account.
@@ -107,7 +114,7 @@ func run(ctx context.Context, log *logger.Logger) error {
 		processor = payment.NewSimulatedProcessor(3 * time.Second)
 	}
 
-	paySvc := service.NewPaymentService(publisher, productClient, processor)
+	paySvc := service.NewPaymentService(publisher, productClient, processor, cfg.ReservationTTL)
 
 	rdb := redis.NewClient(&redis.Options{
 		Addr:     cfg.RedisAddr,
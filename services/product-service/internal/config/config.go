@@ -17,6 +17,14 @@ type Config struct {
 	RedisAddr     string
 	RedisPassword string
 	Port          string
+	JWKSURL       string
+
+	// ReservationSweepInterval is how often expired stock holds are
+	// returned to available stock.
+	ReservationSweepInterval time.Duration
+	// IdempotencyTTL is how long responses to requests with an
+	// Idempotency-Key are kept for replay.
+	IdempotencyTTL time.Duration
 }
 
 func New() *Config {
@@ -48,6 +56,10 @@ func New() *Config {
 		RedisAddr:     redisAddr,
 		RedisPassword: getEnv("REDIS_PASSWORD", ""),
 		Port:          getEnv("PRODUCT_SERVICE_APP_PORT", "3010"),
+		JWKSURL:       getEnv("AUTH_JWKS_URL", ""),
+
+		ReservationSweepInterval: getDuration("RESERVATION_SWEEP_INTERVAL", time.Minute),
+		IdempotencyTTL:           getDuration("IDEMPOTENCY_TTL", 24*time.Hour),
 	}
 }
 
@@ -58,6 +70,16 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getDuration reads a duration such as "30s"; unset, invalid or non-positive
+// values fall back to defaultValue.
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d <= 0 {
+		return defaultValue
+	}
+	return d
+}
+
 var (
 	mongoConnect = mongo.Connect
 	mongoPing    = func(ctx context.Context, client *mongo.Client) error {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -282,6 +283,18 @@ func TestConfig_Defaults(t *testing.T) {
 	assert.Equal(t, "localhost:6379", cfg.RedisAddr)
 	assert.Equal(t, "", cfg.RedisPassword)
 	assert.Equal(t, "3010", cfg.Port)
+	assert.Equal(t, "", cfg.JWKSURL)
+
+	os.Clearenv()
+}
+
+func TestConfig_JWKSURL(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("AUTH_JWKS_URL", "http://auth-service:3020/.well-known/jwks.json")
+
+	cfg := New()
+
+	assert.Equal(t, "http://auth-service:3020/.well-known/jwks.json", cfg.JWKSURL)
 
 	os.Clearenv()
 }
@@ -301,3 +314,22 @@ func TestConfig_MongoURIPriority(t *testing.T) {
 
 	os.Clearenv()
 }
+
+func TestNew_ReservationSweepInterval(t *testing.T) {
+	t.Setenv("RESERVATION_SWEEP_INTERVAL", "")
+	assert.Equal(t, time.Minute, New().ReservationSweepInterval)
+
+	t.Setenv("RESERVATION_SWEEP_INTERVAL", "15s")
+	assert.Equal(t, 15*time.Second, New().ReservationSweepInterval)
+
+	t.Setenv("RESERVATION_SWEEP_INTERVAL", "soon")
+	assert.Equal(t, time.Minute, New().ReservationSweepInterval)
+}
+
+func TestNew_IdempotencyTTL(t *testing.T) {
+	t.Setenv("IDEMPOTENCY_TTL", "")
+	assert.Equal(t, 24*time.Hour, New().IdempotencyTTL)
+
+	t.Setenv("IDEMPOTENCY_TTL", "1h")
+	assert.Equal(t, time.Hour, New().IdempotencyTTL)
+}
@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/icl00ud/velure/services/product-service/internal/metrics"
+	"github.com/icl00ud/velure/services/product-service/internal/middleware"
 	"github.com/icl00ud/velure/services/product-service/internal/model"
 	"github.com/icl00ud/velure/services/product-service/internal/service"
 
@@ -25,6 +30,15 @@ func internalError(err error) error {
 	return fiber.NewError(fiber.StatusInternalServerError, "internal error")
 }
 
+// actorContext returns the request context carrying the token subject, so
+// the stock changes made with it are attributed in the inventory ledger.
+func actorContext(c *fiber.Ctx) context.Context {
+	if claims, ok := middleware.ClaimsFromCtx(c); ok {
+		return models.WithActor(c.Context(), claims.Subject)
+	}
+	return c.Context()
+}
+
 func NewProductHandler(service services.ProductService) *ProductHandler {
 	return &ProductHandler{
 		service: service,
@@ -44,6 +58,7 @@ func (h *ProductHandler) GetProductById(c *fiber.Ctx) error {
 		}
 		return internalError(err)
 	}
+	setETag(c, product.Version)
 	return c.JSON(product)
 }
 
@@ -142,7 +157,7 @@ func (h *ProductHandler) CreateProduct(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	product, err := h.service.CreateProduct(c.Context(), req)
+	product, err := h.service.CreateProduct(actorContext(c), req)
 	if err != nil {
 		return internalError(err)
 	}
@@ -161,17 +176,115 @@ func (h *ProductHandler) DeleteProductById(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+type updateProductRequest struct {
+	models.UpdateProductRequest
+	Version *int64 `json:"version"`
+}
+
+// UpdateProduct replaces a product's catalog fields. The client must say which
+// version it is replacing, in If-Match or the body's version field; read-only
+// fields such as quantity are ignored so a fetched product can be sent back.
 func (h *ProductHandler) UpdateProduct(c *fiber.Ctx) error {
 	id := c.Params("id")
 	if id == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "Product ID is required")
 	}
 
-	return fiber.NewError(fiber.StatusNotImplemented, "product update is not implemented yet")
+	var req updateProductRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	version, err := requestVersion(c, req.Version)
+	if err != nil {
+		return err
+	}
+
+	product, err := h.service.UpdateProduct(c.Context(), id, version, req.UpdateProductRequest)
+	if err != nil {
+		return writeUpdateError(err)
+	}
+	setETag(c, product.Version)
+	return c.JSON(product)
+}
+
+// PatchProduct applies a JSON Merge Patch to a product's catalog fields. The
+// version goes in If-Match or in the patch's version member.
+func (h *ProductHandler) PatchProduct(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Product ID is required")
+	}
+
+	var patch map[string]any
+	if err := json.Unmarshal(c.Body(), &patch); err != nil || patch == nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	var bodyVersion *int64
+	if raw, ok := patch["version"]; ok {
+		v, ok := raw.(float64)
+		if !ok || v != float64(int64(v)) {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid version")
+		}
+		n := int64(v)
+		bodyVersion = &n
+		delete(patch, "version")
+	}
+	version, err := requestVersion(c, bodyVersion)
+	if err != nil {
+		return err
+	}
+
+	product, err := h.service.PatchProduct(c.Context(), id, version, patch)
+	if err != nil {
+		return writeUpdateError(err)
+	}
+	setETag(c, product.Version)
+	return c.JSON(product)
+}
+
+// requestVersion returns the product version an update is based on. If-Match
+// wins over the body; an entity tag that is not one of ours can never match.
+func requestVersion(c *fiber.Ctx, bodyVersion *int64) (int64, error) {
+	ifMatch := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if ifMatch == "*" {
+		return models.AnyVersion, nil
+	}
+	if ifMatch != "" {
+		tag := strings.TrimPrefix(ifMatch, "W/")
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			return 0, fiber.NewError(fiber.StatusPreconditionFailed, "product version mismatch")
+		}
+		version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+		if err != nil || version < 0 {
+			return 0, fiber.NewError(fiber.StatusPreconditionFailed, "product version mismatch")
+		}
+		return version, nil
+	}
+	if bodyVersion == nil {
+		return 0, fiber.NewError(fiber.StatusPreconditionRequired, "If-Match header or version is required")
+	}
+	if *bodyVersion < 0 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "Invalid version")
+	}
+	return *bodyVersion, nil
+}
+
+func writeUpdateError(err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidProduct):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case err.Error() == "product not found":
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case err.Error() == "product version mismatch":
+		return fiber.NewError(fiber.StatusPreconditionFailed, err.Error())
+	case strings.HasPrefix(err.Error(), "invalid product ID"):
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid product ID")
+	}
+	return internalError(err)
 }
 
-type updateInventoryRequest struct {
-	QuantityChange int `json:"quantity_change"`
+func setETag(c *fiber.Ctx, version int64) {
+	c.Set(fiber.HeaderETag, `"`+strconv.FormatInt(version, 10)+`"`)
 }
 
 func (h *ProductHandler) PatchProductInventory(c *fiber.Ctx) error {
@@ -180,13 +293,13 @@ func (h *ProductHandler) PatchProductInventory(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Product ID is required")
 	}
 
-	var req updateInventoryRequest
+	var req models.InventoryAdjustment
 	if err := c.BodyParser(&req); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
 	opStart := time.Now()
-	if err := h.service.UpdateProductQuantity(c.Context(), id, req.QuantityChange); err != nil {
+	if err := h.service.UpdateProductQuantity(actorContext(c), id, req); err != nil {
 		metrics.Errors.WithLabelValues("inventory").Inc()
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
@@ -198,3 +311,96 @@ func (h *ProductHandler) PatchProductInventory(c *fiber.Ctx) error {
 		"message": "Product quantity updated successfully",
 	})
 }
+
+// CreateReservation holds stock for an order until it is committed, released
+// or expires. An order whose stock was deducted already answers 200 with
+// committed set.
+func (h *ProductHandler) CreateReservation(c *fiber.Ctx) error {
+	var req models.CreateReservationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	reservation, err := h.service.ReserveStock(c.Context(), req)
+	if err != nil {
+		return writeReservationError(err)
+	}
+	if reservation.Committed {
+		return c.JSON(reservation)
+	}
+	return c.Status(fiber.StatusCreated).JSON(reservation)
+}
+
+func (h *ProductHandler) CommitReservation(c *fiber.Ctx) error {
+	if err := h.service.CommitReservation(actorContext(c), c.Params("orderId")); err != nil {
+		return writeReservationError(err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *ProductHandler) ReleaseReservation(c *fiber.Ctx) error {
+	if err := h.service.ReleaseReservation(c.Context(), c.Params("orderId")); err != nil {
+		return writeReservationError(err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ApplyInventoryBatch answers 200 when the batch was applied (now or
+// before) and 409 with the per-line outcomes when it was rejected.
+func (h *ProductHandler) ApplyInventoryBatch(c *fiber.Ctx) error {
+	var req models.InventoryBatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	resp, err := h.service.ApplyInventoryBatch(actorContext(c), req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInventoryBatch) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return internalError(err)
+	}
+	if !resp.Applied {
+		return c.Status(fiber.StatusConflict).JSON(resp)
+	}
+	return c.JSON(resp)
+}
+
+// GetInventoryHistory lists a product's stock movements, newest first, a
+// page at a time: page defaults to 1 and limit to 20.
+func (h *ProductHandler) GetInventoryHistory(c *fiber.Ctx) error {
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid page parameter: must be greater than or equal to 1")
+	}
+	pageSize, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid limit parameter: must be between 1 and 100")
+	}
+
+	history, err := h.service.GetInventoryHistory(c.Context(), c.Params("id"), page, pageSize)
+	if err != nil {
+		switch {
+		case err.Error() == "product not found":
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		case strings.HasPrefix(err.Error(), "invalid product ID"):
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid product ID")
+		}
+		return internalError(err)
+	}
+	return c.JSON(history)
+}
+
+func writeReservationError(err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidReservation):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrInsufficientStock):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case err.Error() == "product not found", err.Error() == "reservation not found":
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case strings.HasPrefix(err.Error(), "invalid product ID"):
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid product ID")
+	}
+	return internalError(err)
+}
@@ -5,13 +5,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/icl00ud/velure/services/product-service/internal/middleware"
 	"github.com/icl00ud/velure/services/product-service/internal/model"
+	"github.com/icl00ud/velure/services/product-service/internal/service"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/icl00ud/velure/shared/auth"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -96,11 +100,74 @@ func (m *MockProductService) DeleteProductById(ctx context.Context, id string) e
 	return args.Error(0)
 }
 
-func (m *MockProductService) UpdateProductQuantity(ctx context.Context, productID string, quantityChange int) error {
-	args := m.Called(ctx, productID, quantityChange)
+func (m *MockProductService) UpdateProduct(ctx context.Context, id string, version int64, req models.UpdateProductRequest) (*models.ProductResponse, error) {
+	args := m.Called(ctx, id, version, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ProductResponse), args.Error(1)
+}
+
+func (m *MockProductService) PatchProduct(ctx context.Context, id string, version int64, patch map[string]any) (*models.ProductResponse, error) {
+	args := m.Called(ctx, id, version, patch)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ProductResponse), args.Error(1)
+}
+
+func (m *MockProductService) UpdateProductQuantity(ctx context.Context, productID string, change models.InventoryAdjustment) error {
+	args := m.Called(ctx, productID, change)
+	return args.Error(0)
+}
+
+func (m *MockProductService) ReserveStock(ctx context.Context, req models.CreateReservationRequest) (*models.ReservationResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ReservationResponse), args.Error(1)
+}
+
+func (m *MockProductService) CommitReservation(ctx context.Context, orderID string) error {
+	args := m.Called(ctx, orderID)
+	return args.Error(0)
+}
+
+func (m *MockProductService) ReleaseReservation(ctx context.Context, orderID string) error {
+	args := m.Called(ctx, orderID)
 	return args.Error(0)
 }
 
+func (m *MockProductService) ExpireReservations(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockProductService) GetInventoryHistory(ctx context.Context, productID string, page, pageSize int) (*models.StockMovementsPage, error) {
+	args := m.Called(ctx, productID, page, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.StockMovementsPage), args.Error(1)
+}
+
+func (m *MockProductService) ReconcileInventory(ctx context.Context, baseline bool) (*models.ReconciliationReport, error) {
+	args := m.Called(ctx, baseline)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ReconciliationReport), args.Error(1)
+}
+
+func (m *MockProductService) ApplyInventoryBatch(ctx context.Context, req models.InventoryBatchRequest) (*models.InventoryBatchResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.InventoryBatchResponse), args.Error(1)
+}
+
 func TestGetProducts_ListAll(t *testing.T) {
 	mockService := new(MockProductService)
 	mockService.On("GetAllProducts", mock.Anything).Return([]models.ProductResponse{{ID: "1", Name: "p1"}}, nil)
@@ -216,7 +283,7 @@ func TestGetProducts_ValidationErrors(t *testing.T) {
 
 func TestGetProductByID(t *testing.T) {
 	mockService := new(MockProductService)
-	mockService.On("GetProductById", mock.Anything, "123").Return(&models.ProductResponse{ID: "123", Name: "item"}, nil)
+	mockService.On("GetProductById", mock.Anything, "123").Return(&models.ProductResponse{ID: "123", Name: "item", Version: 4}, nil)
 
 	handler := NewProductHandler(mockService)
 	app := fiber.New()
@@ -227,6 +294,7 @@ func TestGetProductByID(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"4"`, resp.Header.Get("ETag"))
 	mockService.AssertExpectations(t)
 }
 
@@ -330,7 +398,8 @@ func TestPatchProductInventory(t *testing.T) {
 			mockService := new(MockProductService)
 			if tt.pathID != "" {
 				if payload, ok := tt.requestBody.(map[string]int); ok {
-					mockService.On("UpdateProductQuantity", mock.Anything, tt.pathID, payload["quantity_change"]).Return(tt.mockError)
+					change := models.InventoryAdjustment{QuantityChange: payload["quantity_change"]}
+					mockService.On("UpdateProductQuantity", mock.Anything, tt.pathID, change).Return(tt.mockError)
 				}
 			}
 
@@ -356,20 +425,367 @@ func TestPatchProductInventory(t *testing.T) {
 	}
 }
 
-func TestUpdateProduct_NotImplemented(t *testing.T) {
-	handler := NewProductHandler(new(MockProductService))
-	app := fiber.New()
-	app.Put("/products/:id", handler.UpdateProduct)
+func TestPatchProductInventory_RecordsWhoAndWhy(t *testing.T) {
+	mockService := new(MockProductService)
+	change := models.InventoryAdjustment{QuantityChange: 12, Type: models.MovementRestock, Reason: "delivery 4411"}
+	byWarehouse := mock.MatchedBy(func(ctx context.Context) bool {
+		return models.ActorFromContext(ctx) == "warehouse"
+	})
+	mockService.On("UpdateProductQuantity", byWarehouse, "123", change).Return(nil)
 
-	req := httptest.NewRequest("PUT", "/products/123", nil)
+	handler := NewProductHandler(mockService)
+	app := fiber.New()
+	app.Patch("/products/:id/inventory", func(c *fiber.Ctx) error {
+		claims := &auth.Claims{}
+		claims.Subject = "warehouse"
+		c.Locals(middleware.ClaimsKey, claims)
+		return c.Next()
+	}, handler.PatchProductInventory)
+
+	req := httptest.NewRequest("PATCH", "/products/123/inventory", bytes.NewBufferString(`{"quantity_change":12,"type":"restock","reason":"delivery 4411"}`))
+	req.Header.Set("Content-Type", "application/json")
 	resp, err := app.Test(req)
 
 	assert.NoError(t, err)
-	assert.Equal(t, fiber.StatusNotImplemented, resp.StatusCode)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	mockService.AssertExpectations(t)
+}
+
+func TestGetInventoryHistoryHandler(t *testing.T) {
+	page := &models.StockMovementsPage{
+		Movements:  []models.StockMovement{{Type: models.MovementOrderDeduction, QuantityChange: -2, Quantity: 8, OrderID: "order-1"}},
+		TotalCount: 1, Page: 1, PageSize: 20, TotalPages: 1,
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		setup          func(m *MockProductService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "defaults to the first page",
+			setup: func(m *MockProductService) {
+				m.On("GetInventoryHistory", mock.Anything, "123", 1, 20).Return(page, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   `"order_id":"order-1"`,
+		},
+		{
+			name:  "explicit page",
+			query: "?page=3&limit=5",
+			setup: func(m *MockProductService) {
+				m.On("GetInventoryHistory", mock.Anything, "123", 3, 5).Return(page, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{name: "limit too large", query: "?limit=101", setup: func(m *MockProductService) {}, expectedStatus: fiber.StatusBadRequest},
+		{name: "bad page", query: "?page=0", setup: func(m *MockProductService) {}, expectedStatus: fiber.StatusBadRequest},
+		{
+			name: "unknown product",
+			setup: func(m *MockProductService) {
+				m.On("GetInventoryHistory", mock.Anything, "123", 1, 20).Return(nil, errors.New("product not found"))
+			},
+			expectedStatus: fiber.StatusNotFound,
+		},
+		{
+			name: "database error",
+			setup: func(m *MockProductService) {
+				m.On("GetInventoryHistory", mock.Anything, "123", 1, 20).Return(nil, errors.New("connection reset"))
+			},
+			expectedStatus: fiber.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockProductService)
+			tt.setup(mockService)
+
+			handler := NewProductHandler(mockService)
+			app := fiber.New()
+			app.Get("/products/:id/inventory/history", handler.GetInventoryHistory)
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/products/123/inventory/history"+tt.query, nil))
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedBody != "" {
+				respBody, _ := io.ReadAll(resp.Body)
+				assert.Contains(t, string(respBody), tt.expectedBody)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdateProduct(t *testing.T) {
+	update := models.UpdateProductRequest{Name: "Toy", Price: 12.5}
+	invalid := fmt.Errorf("%w: name is required", services.ErrInvalidProduct)
+
+	tests := []struct {
+		name           string
+		ifMatch        string
+		body           string
+		version        int64
+		handlerOnly    bool
+		mockError      error
+		expectedStatus int
+	}{
+		{name: "version in If-Match", ifMatch: `"3"`, body: `{"name":"Toy","price":12.5}`, version: 3, expectedStatus: fiber.StatusOK},
+		{name: "weak tag", ifMatch: `W/"3"`, body: `{"name":"Toy","price":12.5}`, version: 3, expectedStatus: fiber.StatusOK},
+		{name: "If-Match wins over the body", ifMatch: `"3"`, body: `{"name":"Toy","price":12.5,"version":1}`, version: 3, expectedStatus: fiber.StatusOK},
+		{name: "version in body", body: `{"name":"Toy","price":12.5,"version":3,"quantity":99}`, version: 3, expectedStatus: fiber.StatusOK},
+		{name: "any version", ifMatch: "*", body: `{"name":"Toy","price":12.5}`, version: models.AnyVersion, expectedStatus: fiber.StatusOK},
+		{name: "no version", body: `{"name":"Toy","price":12.5}`, handlerOnly: true, expectedStatus: fiber.StatusPreconditionRequired},
+		{name: "foreign tag", ifMatch: `"abc"`, body: `{"name":"Toy","price":12.5}`, handlerOnly: true, expectedStatus: fiber.StatusPreconditionFailed},
+		{name: "invalid body", ifMatch: `"3"`, body: "invalid json", handlerOnly: true, expectedStatus: fiber.StatusBadRequest},
+		{name: "stale version", ifMatch: `"3"`, body: `{"name":"Toy","price":12.5}`, version: 3, mockError: errors.New("product version mismatch"), expectedStatus: fiber.StatusPreconditionFailed},
+		{name: "not found", ifMatch: `"3"`, body: `{"name":"Toy","price":12.5}`, version: 3, mockError: errors.New("product not found"), expectedStatus: fiber.StatusNotFound},
+		{name: "invalid product", ifMatch: `"3"`, body: `{"name":"Toy","price":12.5}`, version: 3, mockError: invalid, expectedStatus: fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockProductService)
+			switch {
+			case tt.handlerOnly:
+			case tt.mockError != nil:
+				mockService.On("UpdateProduct", mock.Anything, "123", tt.version, update).Return(nil, tt.mockError)
+			default:
+				mockService.On("UpdateProduct", mock.Anything, "123", tt.version, update).
+					Return(&models.ProductResponse{ID: "123", Name: "Toy", Price: 12.5, Version: tt.version + 1}, nil)
+			}
+
+			handler := NewProductHandler(mockService)
+			app := fiber.New()
+			app.Put("/products/:id", handler.UpdateProduct)
+
+			req := httptest.NewRequest("PUT", "/products/123", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			resp, err := app.Test(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedStatus == fiber.StatusOK {
+				assert.Equal(t, fmt.Sprintf(`"%d"`, tt.version+1), resp.Header.Get("ETag"))
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPatchProduct(t *testing.T) {
+	tests := []struct {
+		name           string
+		ifMatch        string
+		body           string
+		version        int64
+		patch          map[string]any
+		mockError      error
+		expectedStatus int
+	}{
+		{name: "version in If-Match", ifMatch: `"2"`, body: `{"price":15,"brand":null}`, version: 2, patch: map[string]any{"price": 15.0, "brand": nil}, expectedStatus: fiber.StatusOK},
+		{name: "version in patch", body: `{"price":15,"version":2}`, version: 2, patch: map[string]any{"price": 15.0}, expectedStatus: fiber.StatusOK},
+		{name: "no version", body: `{"price":15}`, expectedStatus: fiber.StatusPreconditionRequired},
+		{name: "invalid version", body: `{"price":15,"version":"2"}`, expectedStatus: fiber.StatusBadRequest},
+		{name: "not an object", ifMatch: `"2"`, body: `[{"op":"replace"}]`, expectedStatus: fiber.StatusBadRequest},
+		{name: "read-only field", ifMatch: `"2"`, body: `{"quantity":5}`, version: 2, patch: map[string]any{"quantity": 5.0}, mockError: fmt.Errorf("%w: quantity cannot be changed", services.ErrInvalidProduct), expectedStatus: fiber.StatusBadRequest},
+		{name: "stale version", ifMatch: `"1"`, body: `{"price":15}`, version: 1, patch: map[string]any{"price": 15.0}, mockError: errors.New("product version mismatch"), expectedStatus: fiber.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockProductService)
+			if tt.patch != nil {
+				if tt.mockError != nil {
+					mockService.On("PatchProduct", mock.Anything, "123", tt.version, tt.patch).Return(nil, tt.mockError)
+				} else {
+					mockService.On("PatchProduct", mock.Anything, "123", tt.version, tt.patch).
+						Return(&models.ProductResponse{ID: "123", Price: 15, Version: tt.version + 1}, nil)
+				}
+			}
+
+			handler := NewProductHandler(mockService)
+			app := fiber.New()
+			app.Patch("/products/:id", handler.PatchProduct)
+
+			req := httptest.NewRequest("PATCH", "/products/123", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			resp, err := app.Test(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedStatus == fiber.StatusOK {
+				assert.Equal(t, fmt.Sprintf(`"%d"`, tt.version+1), resp.Header.Get("ETag"))
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
 
-	body, readErr := io.ReadAll(resp.Body)
-	assert.NoError(t, readErr)
-	assert.Contains(t, string(body), "product update is not implemented yet")
+func TestReservationHandlers(t *testing.T) {
+	req := models.CreateReservationRequest{OrderID: "order-1", Items: []models.ReservationItem{{ProductID: "p1", Quantity: 2}}}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		setup          func(m *MockProductService)
+		expectedStatus int
+	}{
+		{
+			name: "reserve", method: "POST", path: "/reservations", body: `{"order_id":"order-1","items":[{"product_id":"p1","quantity":2}]}`,
+			setup: func(m *MockProductService) {
+				m.On("ReserveStock", mock.Anything, req).Return(&models.ReservationResponse{OrderID: "order-1", Items: req.Items}, nil)
+			},
+			expectedStatus: fiber.StatusCreated,
+		},
+		{
+			name: "reserve a committed order", method: "POST", path: "/reservations", body: `{"order_id":"order-1","items":[{"product_id":"p1","quantity":2}]}`,
+			setup: func(m *MockProductService) {
+				m.On("ReserveStock", mock.Anything, req).Return(&models.ReservationResponse{OrderID: "order-1", Items: req.Items, Committed: true}, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name: "reserve short of stock", method: "POST", path: "/reservations", body: `{"order_id":"order-1","items":[{"product_id":"p1","quantity":2}]}`,
+			setup: func(m *MockProductService) {
+				m.On("ReserveStock", mock.Anything, req).Return(nil, fmt.Errorf("%w for product p1", services.ErrInsufficientStock))
+			},
+			expectedStatus: fiber.StatusConflict,
+		},
+		{
+			name: "reserve invalid", method: "POST", path: "/reservations", body: `{"order_id":"order-1","items":[{"product_id":"p1","quantity":2}]}`,
+			setup: func(m *MockProductService) {
+				m.On("ReserveStock", mock.Anything, req).Return(nil, fmt.Errorf("%w: items are required", services.ErrInvalidReservation))
+			},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name: "commit", method: "POST", path: "/reservations/order-1/commit",
+			setup:          func(m *MockProductService) { m.On("CommitReservation", mock.Anything, "order-1").Return(nil) },
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name: "commit unknown", method: "POST", path: "/reservations/order-1/commit",
+			setup: func(m *MockProductService) {
+				m.On("CommitReservation", mock.Anything, "order-1").Return(errors.New("reservation not found"))
+			},
+			expectedStatus: fiber.StatusNotFound,
+		},
+		{
+			name: "release", method: "DELETE", path: "/reservations/order-1",
+			setup:          func(m *MockProductService) { m.On("ReleaseReservation", mock.Anything, "order-1").Return(nil) },
+			expectedStatus: fiber.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockProductService)
+			tt.setup(mockService)
+
+			handler := NewProductHandler(mockService)
+			app := fiber.New()
+			app.Post("/reservations", handler.CreateReservation)
+			app.Post("/reservations/:orderId/commit", handler.CommitReservation)
+			app.Delete("/reservations/:orderId", handler.ReleaseReservation)
+
+			httpReq := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			httpReq.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(httpReq)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApplyInventoryBatchHandler(t *testing.T) {
+	req := models.InventoryBatchRequest{OrderID: "order-1", Items: []models.InventoryChange{{ProductID: "p1", QuantityChange: -2}}}
+	body := `{"order_id":"order-1","items":[{"product_id":"p1","quantity_change":-2}]}`
+
+	tests := []struct {
+		name           string
+		body           string
+		setup          func(m *MockProductService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "applied", body: body,
+			setup: func(m *MockProductService) {
+				m.On("ApplyInventoryBatch", mock.Anything, req).Return(&models.InventoryBatchResponse{OrderID: "order-1", Applied: true}, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   `"applied":true`,
+		},
+		{
+			name: "rejected", body: body,
+			setup: func(m *MockProductService) {
+				m.On("ApplyInventoryBatch", mock.Anything, req).Return(&models.InventoryBatchResponse{
+					OrderID: "order-1",
+					Items:   []models.InventoryLineResult{{ProductID: "p1", QuantityChange: -2, Status: models.InventoryLineInsufficientStock}},
+					Error:   "insufficient stock for product p1",
+				}, nil)
+			},
+			expectedStatus: fiber.StatusConflict,
+			expectedBody:   `"status":"insufficient_stock"`,
+		},
+		{
+			name: "invalid", body: body,
+			setup: func(m *MockProductService) {
+				m.On("ApplyInventoryBatch", mock.Anything, req).Return(nil, fmt.Errorf("%w: items are required", services.ErrInvalidInventoryBatch))
+			},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name: "database error", body: body,
+			setup: func(m *MockProductService) {
+				m.On("ApplyInventoryBatch", mock.Anything, req).Return(nil, errors.New("connection reset"))
+			},
+			expectedStatus: fiber.StatusInternalServerError,
+		},
+		{
+			name:           "bad body",
+			body:           `{`,
+			setup:          func(m *MockProductService) {},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockProductService)
+			tt.setup(mockService)
+
+			handler := NewProductHandler(mockService)
+			app := fiber.New()
+			app.Post("/inventory/batch", handler.ApplyInventoryBatch)
+
+			httpReq := httptest.NewRequest("POST", "/inventory/batch", bytes.NewBufferString(tt.body))
+			httpReq.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(httpReq)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedBody != "" {
+				respBody, _ := io.ReadAll(resp.Body)
+				assert.Contains(t, string(respBody), tt.expectedBody)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
 }
 
 func TestGetProductById_InternalErrorNotLeaked(t *testing.T) {
@@ -47,9 +47,36 @@ func (s *stubProductService) DeleteProductsByName(ctx context.Context, name stri
 	return s.err
 }
 func (s *stubProductService) DeleteProductById(ctx context.Context, id string) error { return s.err }
-func (s *stubProductService) UpdateProductQuantity(ctx context.Context, productID string, quantityChange int) error {
+func (s *stubProductService) UpdateProduct(ctx context.Context, id string, version int64, req models.UpdateProductRequest) (*models.ProductResponse, error) {
+	return nil, s.err
+}
+func (s *stubProductService) PatchProduct(ctx context.Context, id string, version int64, patch map[string]any) (*models.ProductResponse, error) {
+	return nil, s.err
+}
+func (s *stubProductService) UpdateProductQuantity(ctx context.Context, productID string, change models.InventoryAdjustment) error {
+	return s.err
+}
+func (s *stubProductService) ReserveStock(ctx context.Context, req models.CreateReservationRequest) (*models.ReservationResponse, error) {
+	return nil, s.err
+}
+func (s *stubProductService) CommitReservation(ctx context.Context, orderID string) error {
+	return s.err
+}
+func (s *stubProductService) ReleaseReservation(ctx context.Context, orderID string) error {
 	return s.err
 }
+func (s *stubProductService) ExpireReservations(ctx context.Context) (int64, error) {
+	return 0, s.err
+}
+func (s *stubProductService) ApplyInventoryBatch(ctx context.Context, req models.InventoryBatchRequest) (*models.InventoryBatchResponse, error) {
+	return nil, s.err
+}
+func (s *stubProductService) GetInventoryHistory(ctx context.Context, productID string, page, pageSize int) (*models.StockMovementsPage, error) {
+	return nil, s.err
+}
+func (s *stubProductService) ReconcileInventory(ctx context.Context, baseline bool) (*models.ReconciliationReport, error) {
+	return nil, s.err
+}
 func (s *stubProductService) GetProductQuantity(ctx context.Context, productID string) (int, error) {
 	return 0, s.err
 }
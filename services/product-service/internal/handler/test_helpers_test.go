@@ -34,7 +34,7 @@ func TestStubProductService_ReturnsConfiguredError(t *testing.T) {
 	_, err = svc.CreateProduct(context.Background(), models.CreateProductRequest{Name: "name"})
 	assert.Equal(t, errExpected, err)
 
-	err = svc.UpdateProductQuantity(context.Background(), "1", 1)
+	err = svc.UpdateProductQuantity(context.Background(), "1", models.InventoryAdjustment{QuantityChange: 1})
 	assert.Equal(t, errExpected, err)
 
 	_, err = svc.GetProductQuantity(context.Background(), "1")
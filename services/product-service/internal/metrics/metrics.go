@@ -20,7 +20,7 @@ var (
 			Name: "product_mutations_total",
 			Help: "Total number of product mutations",
 		},
-		[]string{"operation", "status"}, // operation: create, update, delete; status: success, failure
+		[]string{"operation", "status"}, // operation: create, update, patch, delete; status: success, failure
 	)
 
 	ProductOperationDuration = promauto.NewHistogramVec(
@@ -73,6 +73,30 @@ var (
 		[]string{"status"}, // status: success, failure, insufficient_stock
 	)
 
+	Reservations = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "product_stock_reservations_total",
+			Help: "Total number of stock reservation operations",
+		},
+		[]string{"operation", "status"}, // operation: reserve, commit, release, expire; status: success, failure, insufficient_stock, committed
+	)
+
+	InventoryBatches = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "product_inventory_batches_total",
+			Help: "Total number of batch inventory operations",
+		},
+		[]string{"result"}, // result: applied, rejected, replayed, failure
+	)
+
+	IdempotentRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "product_idempotent_requests_total",
+			Help: "Total number of requests carrying an Idempotency-Key",
+		},
+		[]string{"result"}, // result: stored, replayed, mismatch, in_progress, unavailable
+	)
+
 	CurrentProductCount = promauto.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "product_catalog_total",
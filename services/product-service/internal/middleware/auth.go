@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/icl00ud/velure/shared/auth"
+)
+
+// ClaimsKey is the Fiber locals key under which RequireRoles and
+// RequireScope store the verified access-token claims.
+const ClaimsKey = "authClaims"
+
+// RequireRoles verifies the caller's access token (Authorization bearer header
+// or access_token cookie) against keys, normally auth-service's JWKS, and
+// rejects it unless the token grants at least one of roles. With no roles it
+// only requires a valid token.
+func RequireRoles(keys auth.KeySet, roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, err := verify(c, keys)
+		if err != nil {
+			return err
+		}
+
+		if !auth.HasAnyRole(claims.EffectiveRoles(), roles...) {
+			return fiber.NewError(fiber.StatusForbidden, "Insufficient permissions")
+		}
+
+		c.Locals(ClaimsKey, claims)
+		return c.Next()
+	}
+}
+
+// RequireScope verifies the caller's access token like RequireRoles and
+// rejects it unless it was issued to a machine client granted scope. User
+// tokens never carry scopes, whatever their roles.
+func RequireScope(keys auth.KeySet, scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, err := verify(c, keys)
+		if err != nil {
+			return err
+		}
+
+		if !claims.HasScope(scope) {
+			return fiber.NewError(fiber.StatusForbidden, "Insufficient scope")
+		}
+
+		c.Locals(ClaimsKey, claims)
+		return c.Next()
+	}
+}
+
+func verify(c *fiber.Ctx, keys auth.KeySet) (*auth.Claims, error) {
+	token := bearerToken(c)
+	if token == "" {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Authorization required")
+	}
+
+	claims, err := auth.Parse(token, keys)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+	return claims, nil
+}
+
+// ClaimsFromCtx returns the claims stored by RequireRoles or RequireScope, if
+// any.
+func ClaimsFromCtx(c *fiber.Ctx) (*auth.Claims, bool) {
+	claims, ok := c.Locals(ClaimsKey).(*auth.Claims)
+	return claims, ok
+}
+
+func bearerToken(c *fiber.Ctx) string {
+	if header := c.Get(fiber.HeaderAuthorization); header != "" {
+		parts := strings.SplitN(header, " ", 2)
+		if len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
+			return strings.TrimSpace(parts[1])
+		}
+		return ""
+	}
+	return c.Cookies("access_token")
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/icl00ud/velure/shared/auth"
+	"github.com/icl00ud/velure/shared/auth/authtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireRoles(t *testing.T) {
+	issuer := authtest.NewIssuer(t)
+	app := fiber.New()
+	app.Post("/products", RequireRoles(issuer.Keys(), auth.RoleCatalogManager), func(c *fiber.Ctx) error {
+		claims, ok := ClaimsFromCtx(c)
+		if !ok {
+			return fiber.ErrInternalServerError
+		}
+		return c.SendString(claims.Subject)
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		cookie string
+		want   int
+	}{
+		{name: "no token", want: fiber.StatusUnauthorized},
+		{name: "not a bearer header", header: "Basic abc", want: fiber.StatusUnauthorized},
+		{name: "garbage token", header: "Bearer not-a-jwt", want: fiber.StatusUnauthorized},
+		{name: "unknown signing key", header: "Bearer " + authtest.NewIssuer(t).Token(t, "7", auth.RoleAdmin), want: fiber.StatusUnauthorized},
+		{name: "customer", header: "Bearer " + issuer.Token(t, "7", auth.RoleCustomer), want: fiber.StatusForbidden},
+		{name: "legacy token without roles", header: "Bearer " + issuer.Token(t, "7"), want: fiber.StatusForbidden},
+		{name: "catalog manager", header: "Bearer " + issuer.Token(t, "7", auth.RoleCatalogManager), want: fiber.StatusOK},
+		{name: "admin", header: "Bearer " + issuer.Token(t, "7", auth.RoleAdmin), want: fiber.StatusOK},
+		{name: "admin via cookie", cookie: issuer.Token(t, "7", auth.RoleAdmin), want: fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/products", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "access_token", Value: tt.cookie})
+			}
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, resp.StatusCode)
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	issuer := authtest.NewIssuer(t)
+	app := fiber.New()
+	app.Patch("/inventory", RequireScope(issuer.Keys(), auth.ScopeInventoryWrite), func(c *fiber.Ctx) error {
+		claims, ok := ClaimsFromCtx(c)
+		if !ok {
+			return fiber.ErrInternalServerError
+		}
+		return c.SendString(claims.ClientID)
+	})
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{name: "no token", want: fiber.StatusUnauthorized},
+		{name: "unknown signing key", token: authtest.NewIssuer(t).ClientToken(t, "orders", auth.ScopeInventoryWrite), want: fiber.StatusUnauthorized},
+		{name: "client without the scope", token: issuer.ClientToken(t, "orders", "orders:read"), want: fiber.StatusForbidden},
+		{name: "admin user", token: issuer.Token(t, "7", auth.RoleAdmin), want: fiber.StatusForbidden},
+		{name: "client with the scope", token: issuer.ClientToken(t, "orders", auth.ScopeInventoryWrite), want: fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/inventory", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, resp.StatusCode)
+		})
+	}
+}
+
+func TestRequireRoles_NoKeysRejectsEverything(t *testing.T) {
+	app := fiber.New()
+	app.Post("/products", RequireRoles(nil), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/products", nil)
+	req.Header.Set("Authorization", "Bearer "+authtest.NewIssuer(t).Token(t, "7", auth.RoleAdmin))
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/icl00ud/velure/services/product-service/internal/metrics"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/icl00ud/velure/shared/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// IdempotencyKeyHeader names the client-chosen key of a retryable request.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from the store.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// idempotencyLockTTL bounds how long a key stays claimed by a request
+	// that never finished, e.g. because the process died.
+	idempotencyLockTTL = time.Minute
+)
+
+// idempotencyRecord is what is stored per key. Status is 0 while the first
+// request is still running.
+type idempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// Idempotency makes a mutation safe to retry. A request with an
+// Idempotency-Key header runs once per caller and key within ttl; repeating
+// it replays the stored response with Idempotent-Replayed: true. Reusing a
+// key for a different request (method, path or body) fails with 422, and a
+// repeat arriving while the first request still runs with 409. Server
+// errors are not stored, so the request can be retried with the same key.
+// Requests without the header, or while Redis is unreachable, run as usual.
+// It must follow RequireRoles or RequireScope, whose claims scope the keys.
+func Idempotency(rdb *redis.Client, ttl time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return fiber.NewError(fiber.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+		}
+
+		ctx := c.UserContext()
+		storeKey := "idempotency:" + caller(c) + ":" + key
+		fingerprint := requestFingerprint(c)
+
+		pending, _ := json.Marshal(idempotencyRecord{Fingerprint: fingerprint})
+		claimed, err := rdb.SetNX(ctx, storeKey, pending, idempotencyLockTTL).Result()
+		if err != nil {
+			logger.Warn("idempotency store unavailable; running request without it", logger.Err(err))
+			metrics.IdempotentRequests.WithLabelValues("unavailable").Inc()
+			return c.Next()
+		}
+		if !claimed {
+			return replay(c, rdb, storeKey, fingerprint)
+		}
+
+		if err := c.Next(); err != nil {
+			// Write the error response now so it can be stored.
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				rdb.Del(ctx, storeKey)
+				return err
+			}
+		}
+
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError {
+			rdb.Del(ctx, storeKey)
+			return nil
+		}
+		record, _ := json.Marshal(idempotencyRecord{
+			Fingerprint: fingerprint,
+			Status:      status,
+			ContentType: string(c.Response().Header.ContentType()),
+			Body:        c.Response().Body(),
+		})
+		if err := rdb.Set(ctx, storeKey, record, ttl).Err(); err != nil {
+			// The response stands; a retry may run the request again.
+			logger.Error("failed to store idempotent response", logger.Err(err))
+			metrics.IdempotentRequests.WithLabelValues("unavailable").Inc()
+			return nil
+		}
+		metrics.IdempotentRequests.WithLabelValues("stored").Inc()
+		return nil
+	}
+}
+
+func replay(c *fiber.Ctx, rdb *redis.Client, storeKey, fingerprint string) error {
+	data, err := rdb.Get(c.UserContext(), storeKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// Expired or abandoned since SETNX; the caller can simply retry.
+		metrics.IdempotentRequests.WithLabelValues("in_progress").Inc()
+		return fiber.NewError(fiber.StatusConflict, "A request with this Idempotency-Key is in progress")
+	}
+	if err != nil {
+		logger.Error("failed to load idempotent response", logger.Err(err))
+		metrics.IdempotentRequests.WithLabelValues("unavailable").Inc()
+		return fiber.NewError(fiber.StatusServiceUnavailable, "Idempotency store unavailable")
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+	switch {
+	case record.Fingerprint != fingerprint:
+		metrics.IdempotentRequests.WithLabelValues("mismatch").Inc()
+		return fiber.NewError(fiber.StatusUnprocessableEntity, "Idempotency-Key was used for a different request")
+	case record.Status == 0:
+		metrics.IdempotentRequests.WithLabelValues("in_progress").Inc()
+		return fiber.NewError(fiber.StatusConflict, "A request with this Idempotency-Key is in progress")
+	}
+
+	metrics.IdempotentRequests.WithLabelValues("replayed").Inc()
+	c.Set(IdempotentReplayedHeader, "true")
+	if record.ContentType != "" {
+		c.Set(fiber.HeaderContentType, record.ContentType)
+	}
+	return c.Status(record.Status).Send(record.Body)
+}
+
+// caller is the token subject: a user ID or a client ID.
+func caller(c *fiber.Ctx) string {
+	if claims, ok := ClaimsFromCtx(c); ok {
+		return claims.Subject
+	}
+	return "anonymous"
+}
+
+func requestFingerprint(c *fiber.Ctx) string {
+	h := sha256.New()
+	h.Write([]byte(c.Method() + " " + c.Path() + "\n"))
+	h.Write(c.Body())
+	return hex.EncodeToString(h.Sum(nil))
+}
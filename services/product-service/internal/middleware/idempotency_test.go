@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/icl00ud/velure/shared/auth"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// idempotentApp serves POST /stock through Idempotency. The caller is taken
+// from the X-Subject header, and the handler answers with the number of
+// times it ran, or with the status in the X-Fail header.
+func idempotentApp(t *testing.T, rdb *redis.Client) (*fiber.App, *int) {
+	t.Helper()
+	calls := 0
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			code := fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				code = e.Code
+			}
+			return c.Status(code).JSON(fiber.Map{"error": err.Error()})
+		},
+	})
+	app.Post("/stock", func(c *fiber.Ctx) error {
+		claims := &auth.Claims{}
+		claims.Subject = c.Get("X-Subject")
+		c.Locals(ClaimsKey, claims)
+		return c.Next()
+	}, Idempotency(rdb, time.Hour), func(c *fiber.Ctx) error {
+		calls++
+		if code := c.Get("X-Fail"); code != "" {
+			status, _ := strconv.Atoi(code)
+			return fiber.NewError(status, "failed")
+		}
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"calls": calls})
+	})
+	return app, &calls
+}
+
+func send(t *testing.T, app *fiber.App, key, body string, headers ...string) (*http.Response, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/stock", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Subject", "process-order-service")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	respBody, _ := io.ReadAll(resp.Body)
+	return resp, string(respBody)
+}
+
+func TestIdempotency(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	t.Run("replays the stored response", func(t *testing.T) {
+		app, calls := idempotentApp(t, rdb)
+		first, firstBody := send(t, app, "key-1", `{"quantity_change":-1}`)
+		again, againBody := send(t, app, "key-1", `{"quantity_change":-1}`)
+
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, fiber.StatusCreated, again.StatusCode)
+		assert.Equal(t, firstBody, againBody)
+		assert.Empty(t, first.Header.Get(IdempotentReplayedHeader))
+		assert.Equal(t, "true", again.Header.Get(IdempotentReplayedHeader))
+		assert.Equal(t, fiber.MIMEApplicationJSON, again.Header.Get(fiber.HeaderContentType))
+		assert.InDelta(t, time.Hour, mr.TTL("idempotency:process-order-service:key-1"), float64(time.Second))
+	})
+
+	t.Run("rejects a key reused for another body", func(t *testing.T) {
+		app, calls := idempotentApp(t, rdb)
+		send(t, app, "key-2", `{"quantity_change":-1}`)
+		resp, body := send(t, app, "key-2", `{"quantity_change":-5}`)
+
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+		assert.Contains(t, body, "different request")
+	})
+
+	t.Run("keys are scoped to the caller", func(t *testing.T) {
+		app, calls := idempotentApp(t, rdb)
+		send(t, app, "key-3", `{}`)
+		send(t, app, "key-3", `{}`, "X-Subject", "other-client")
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("client errors are stored, server errors are not", func(t *testing.T) {
+		app, calls := idempotentApp(t, rdb)
+		send(t, app, "key-4", `{}`, "X-Fail", "409")
+		resp, body := send(t, app, "key-4", `{}`)
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+		assert.Contains(t, body, "failed")
+
+		send(t, app, "key-5", `{}`, "X-Fail", "503")
+		resp, _ = send(t, app, "key-5", `{}`)
+		assert.Equal(t, 3, *calls)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	})
+
+	t.Run("a request still running blocks its repeats", func(t *testing.T) {
+		app, calls := idempotentApp(t, rdb)
+		sum := sha256.Sum256([]byte("POST /stock\n{}"))
+		require.NoError(t, mr.Set("idempotency:process-order-service:key-6", `{"fingerprint":"`+hex.EncodeToString(sum[:])+`"}`))
+
+		resp, _ := send(t, app, "key-6", `{}`)
+		assert.Equal(t, 0, *calls)
+		assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("requests without a key always run", func(t *testing.T) {
+		app, calls := idempotentApp(t, rdb)
+		send(t, app, "", `{}`)
+		send(t, app, "", `{}`)
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("overlong keys are rejected", func(t *testing.T) {
+		app, calls := idempotentApp(t, rdb)
+		resp, _ := send(t, app, strings.Repeat("k", 256), `{}`)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, 0, *calls)
+	})
+}
+
+func TestIdempotency_StoreDown(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	mr.Close()
+
+	app, calls := idempotentApp(t, rdb)
+	send(t, app, "key-1", `{}`)
+	resp, _ := send(t, app, "key-1", `{}`)
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -19,10 +20,23 @@ type Product struct {
 	Brand       string             `json:"brand,omitempty" bson:"brand"`
 	Colors      []string           `json:"colors" bson:"colors"`
 	SKU         string             `json:"sku,omitempty" bson:"sku"`
+	Version     int64              `json:"version" bson:"version"`
+	Holds       []StockHold        `json:"-" bson:"holds,omitempty"`
 	DateCreated time.Time          `json:"dt_created" bson:"dt_created"`
 	DateUpdated time.Time          `json:"dt_updated" bson:"dt_updated"`
 }
 
+// Available returns the units that are on hand and not held for an order.
+func (p Product) Available() int {
+	available := p.Quantity
+	for _, hold := range p.Holds {
+		if hold.Status == HoldActive {
+			available -= hold.Quantity
+		}
+	}
+	return available
+}
+
 type Dimensions struct {
 	Height float64 `json:"height,omitempty" bson:"height"`
 	Width  float64 `json:"width,omitempty" bson:"width"`
@@ -44,6 +58,24 @@ type CreateProductRequest struct {
 	SKU         string     `json:"sku,omitempty"`
 }
 
+// UpdateProductRequest holds the catalog fields a product update may change.
+// Stock and rating are not among them: quantity is owned by the inventory
+// endpoint and rating by reviews.
+type UpdateProductRequest struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Price       float64    `json:"price"`
+	Category    string     `json:"category,omitempty"`
+	Images      []string   `json:"images"`
+	Dimensions  Dimensions `json:"dimensions"`
+	Brand       string     `json:"brand,omitempty"`
+	Colors      []string   `json:"colors"`
+	SKU         string     `json:"sku,omitempty"`
+}
+
+// AnyVersion skips the version check of an update, as `If-Match: *` does.
+const AnyVersion int64 = -1
+
 type ProductResponse struct {
 	ID          string     `json:"_id"`
 	Name        string     `json:"name"`
@@ -52,11 +84,13 @@ type ProductResponse struct {
 	Rating      float64    `json:"rating,omitempty"`
 	Category    string     `json:"category,omitempty"`
 	Quantity    int        `json:"quantity"`
+	Available   int        `json:"available"`
 	Images      []string   `json:"images"`
 	Dimensions  Dimensions `json:"dimensions"`
 	Brand       string     `json:"brand,omitempty"`
 	Colors      []string   `json:"colors"`
 	SKU         string     `json:"sku,omitempty"`
+	Version     int64      `json:"version"`
 	DateCreated time.Time  `json:"dt_created"`
 	DateUpdated time.Time  `json:"dt_updated"`
 }
@@ -77,3 +111,164 @@ type UpdateQuantityRequest struct {
 	ProductID      string `json:"product_id" validate:"required"`
 	QuantityChange int    `json:"quantity_change" validate:"required"`
 }
+
+// HoldActive is the status of a hold whose units are set aside. A hold is
+// removed when its stock is deducted, released or expires.
+const HoldActive = "active"
+
+// StockHold sets units of a product aside for an order until ExpiresAt.
+// Active holds count against available stock but not against quantity.
+type StockHold struct {
+	OrderID   string    `json:"order_id" bson:"order_id"`
+	Quantity  int       `json:"quantity" bson:"quantity"`
+	Status    string    `json:"status" bson:"status"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+type ReservationItem struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+type CreateReservationRequest struct {
+	OrderID    string            `json:"order_id"`
+	Items      []ReservationItem `json:"items"`
+	TTLSeconds int               `json:"ttl_seconds,omitempty"`
+}
+
+// ReservationResponse describes the holds of an order. Committed means the
+// order's stock was deducted already, so nothing was held.
+type ReservationResponse struct {
+	OrderID   string            `json:"order_id"`
+	Items     []ReservationItem `json:"items"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	Committed bool              `json:"committed,omitempty"`
+}
+
+// Outcomes of a line in an inventory batch. A line that could have been
+// applied is reported as not applied when another line of the batch failed.
+const (
+	InventoryLineApplied           = "applied"
+	InventoryLineNotApplied        = "not_applied"
+	InventoryLineInsufficientStock = "insufficient_stock"
+	InventoryLineNotFound          = "not_found"
+)
+
+type InventoryChange struct {
+	ProductID      string `json:"product_id" bson:"product_id"`
+	QuantityChange int    `json:"quantity_change" bson:"quantity_change"`
+}
+
+type InventoryBatchRequest struct {
+	OrderID string            `json:"order_id"`
+	Items   []InventoryChange `json:"items"`
+}
+
+// InventoryLineResult is the outcome of one line of a batch. Quantity is the
+// product's stock after the change and only set for applied lines.
+type InventoryLineResult struct {
+	ProductID      string `json:"product_id" bson:"product_id"`
+	QuantityChange int    `json:"quantity_change" bson:"quantity_change"`
+	Status         string `json:"status" bson:"status"`
+	Quantity       *int   `json:"quantity,omitempty" bson:"quantity,omitempty"`
+}
+
+type InventoryBatchResponse struct {
+	OrderID  string                `json:"order_id"`
+	Applied  bool                  `json:"applied"`
+	Replayed bool                  `json:"replayed,omitempty"`
+	Items    []InventoryLineResult `json:"items"`
+	Error    string                `json:"error,omitempty"`
+}
+
+// InventoryOperation records an applied batch or a committed reservation
+// under its order ID, so the order's stock is deducted once however often
+// either is sent.
+type InventoryOperation struct {
+	OrderID     string                `bson:"_id"`
+	Items       []InventoryLineResult `bson:"items"`
+	DateCreated time.Time             `bson:"dt_created"`
+}
+
+// Stock movement types. Opening stock is what a product was created with,
+// or, for products older than the ledger, the quantity it was baselined at.
+const (
+	MovementOpening        = "opening"
+	MovementOrderDeduction = "order_deduction"
+	MovementCompensation   = "compensation"
+	MovementAdjustment     = "adjustment"
+	MovementRestock        = "restock"
+)
+
+// IsManualMovement reports whether a single-product inventory update may
+// record a movement of this type.
+func IsManualMovement(movementType string) bool {
+	switch movementType {
+	case MovementOrderDeduction, MovementCompensation, MovementAdjustment, MovementRestock:
+		return true
+	}
+	return false
+}
+
+// StockMovement is an entry of the append-only inventory ledger. Quantity is
+// the product's stock right after the movement, so summing QuantityChange
+// over a product's movements gives its current quantity.
+type StockMovement struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ProductID      primitive.ObjectID `json:"product_id" bson:"product_id"`
+	Type           string             `json:"type" bson:"type"`
+	QuantityChange int                `json:"quantity_change" bson:"quantity_change"`
+	Quantity       int                `json:"quantity" bson:"quantity"`
+	Actor          string             `json:"actor,omitempty" bson:"actor,omitempty"`
+	Reason         string             `json:"reason,omitempty" bson:"reason,omitempty"`
+	OrderID        string             `json:"order_id,omitempty" bson:"order_id,omitempty"`
+	DateCreated    time.Time          `json:"dt_created" bson:"dt_created"`
+}
+
+// InventoryAdjustment is a single-product stock change and why it happened.
+// An empty Type is derived from the sign of the change and the order ID.
+type InventoryAdjustment struct {
+	QuantityChange int    `json:"quantity_change"`
+	Type           string `json:"type,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+	OrderID        string `json:"order_id,omitempty"`
+}
+
+type StockMovementsPage struct {
+	Movements  []StockMovement `json:"movements"`
+	TotalCount int64           `json:"totalCount"`
+	Page       int             `json:"page"`
+	PageSize   int             `json:"pageSize"`
+	TotalPages int             `json:"totalPages"`
+}
+
+// InventoryDrift is a product whose quantity disagrees with its ledger.
+// Drift is Quantity minus LedgerQuantity.
+type InventoryDrift struct {
+	ProductID      string `json:"product_id"`
+	Name           string `json:"name"`
+	Quantity       int    `json:"quantity"`
+	LedgerQuantity int    `json:"ledger_quantity"`
+	Drift          int    `json:"drift"`
+	Movements      int    `json:"movements"`
+}
+
+type ReconciliationReport struct {
+	Products  int              `json:"products"`
+	Baselined int              `json:"baselined"`
+	Drift     []InventoryDrift `json:"drift"`
+}
+
+type actorKey struct{}
+
+// WithActor returns ctx carrying the party responsible for the stock
+// changes made with it, recorded on their ledger entries.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by WithActor, or "".
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
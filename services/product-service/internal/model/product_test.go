@@ -208,3 +208,16 @@ func TestProduct_WithEmptyFields(t *testing.T) {
 	assert.Empty(t, product.Brand)
 	assert.Empty(t, product.SKU)
 }
+
+func TestProduct_Available(t *testing.T) {
+	product := Product{
+		Quantity: 10,
+		Holds: []StockHold{
+			{OrderID: "a", Quantity: 3, Status: HoldActive},
+			{OrderID: "c", Quantity: 1, Status: HoldActive},
+		},
+	}
+
+	assert.Equal(t, 6, product.Available())
+	assert.Equal(t, 4, Product{Quantity: 4}.Available())
+}
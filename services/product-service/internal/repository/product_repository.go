@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -25,7 +26,10 @@ type mongoCollection interface {
 	DeleteMany(context.Context, interface{}, ...*options.DeleteOptions) (*mongo.DeleteResult, error)
 	DeleteOne(context.Context, interface{}, ...*options.DeleteOptions) (*mongo.DeleteResult, error)
 	UpdateOne(context.Context, interface{}, interface{}, ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	UpdateMany(context.Context, interface{}, interface{}, ...*options.UpdateOptions) (*mongo.UpdateResult, error)
 	FindOne(context.Context, interface{}, ...*options.FindOneOptions) *mongo.SingleResult
+	FindOneAndUpdate(context.Context, interface{}, interface{}, ...*options.FindOneAndUpdateOptions) *mongo.SingleResult
+	Aggregate(context.Context, interface{}, ...*options.AggregateOptions) (*mongo.Cursor, error)
 }
 
 type ProductRepository interface {
@@ -40,20 +44,58 @@ type ProductRepository interface {
 	CreateProduct(ctx context.Context, product models.CreateProductRequest) (*models.ProductResponse, error)
 	DeleteProductsByName(ctx context.Context, name string) error
 	DeleteProductById(ctx context.Context, id string) error
-	UpdateProductQuantity(ctx context.Context, productID string, quantityChange int) error
+	LoadProduct(ctx context.Context, id string) (*models.Product, error)
+	UpdateProduct(ctx context.Context, id string, version int64, req models.UpdateProductRequest) (*models.ProductResponse, error)
+	UpdateProductQuantity(ctx context.Context, productID string, change models.InventoryAdjustment) error
 	GetProductQuantity(ctx context.Context, productID string) (int, error)
+	ReserveStock(ctx context.Context, orderID string, items []models.ReservationItem, expiresAt time.Time) (bool, error)
+	CommitStock(ctx context.Context, orderID string) error
+	ReleaseStock(ctx context.Context, orderID string) error
+	ExpireHolds(ctx context.Context, now time.Time) (int64, error)
+	ApplyInventoryBatch(ctx context.Context, orderID string, changes []models.InventoryChange) (*models.InventoryBatchResponse, error)
+	GetStockMovements(ctx context.Context, productID string, page, pageSize int) (*models.StockMovementsPage, error)
+	InventoryDrift(ctx context.Context) (int, []models.InventoryDrift, error)
+	RecordOpeningStock(ctx context.Context, productID string) (bool, error)
 	WarmupCache(ctx context.Context) error
 }
 
 type productRepository struct {
 	collection mongoCollection
-	redis      *redis.Client
+	// operations records, by order ID, the orders whose stock was deducted:
+	// applied inventory batches and committed reservations.
+	operations mongoCollection
+	// movements is the inventory ledger: one entry per stock change.
+	movements mongoCollection
+	redis     *redis.Client
+	// transact runs fn in a transaction; ctx passed to fn carries it.
+	transact func(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 func NewProductRepository(db *mongo.Database, redisClient *redis.Client) ProductRepository {
 	return &productRepository{
 		collection: db.Collection("products"),
+		operations: db.Collection("inventory_operations"),
+		movements:  db.Collection("stock_movements"),
 		redis:      redisClient,
+		transact:   withTransaction(db.Client()),
+	}
+}
+
+// withTransaction runs fn in a MongoDB transaction, which the driver retries
+// on transient errors and aborts when fn fails. Transactions need a replica
+// set; a standalone server rejects them.
+func withTransaction(client *mongo.Client) func(context.Context, func(context.Context) error) error {
+	return func(ctx context.Context, fn func(context.Context) error) error {
+		session, err := client.StartSession()
+		if err != nil {
+			return fmt.Errorf("failed to start session: %w", err)
+		}
+		defer session.EndSession(ctx)
+
+		_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+			return nil, fn(sc)
+		})
+		return err
 	}
 }
 
@@ -401,11 +443,25 @@ func (r *productRepository) CreateProduct(ctx context.Context, req models.Create
 		Brand:       req.Brand,
 		Colors:      req.Colors,
 		SKU:         req.SKU,
+		Version:     1,
 		DateCreated: time.Now(),
 		DateUpdated: time.Now(),
 	}
 
-	_, err := r.collection.InsertOne(ctx, product)
+	err := r.transact(ctx, func(ctx context.Context) error {
+		if _, err := r.collection.InsertOne(ctx, product); err != nil {
+			return err
+		}
+		if product.Quantity == 0 {
+			return nil
+		}
+		return r.recordMovement(ctx, models.StockMovement{
+			ProductID:      product.ID,
+			Type:           models.MovementOpening,
+			QuantityChange: product.Quantity,
+			Quantity:       product.Quantity,
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -444,6 +500,83 @@ func (r *productRepository) DeleteProductById(ctx context.Context, id string) er
 	return nil
 }
 
+// LoadProduct reads a product straight from MongoDB, bypassing the cache, so
+// an update starts from the stored version.
+func (r *productRepository) LoadProduct(ctx context.Context, id string) (*models.Product, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid product ID: %w", err)
+	}
+
+	var product models.Product
+	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&product)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("product not found")
+		}
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	return &product, nil
+}
+
+// UpdateProduct replaces the editable fields of a product if it is still at
+// the given version, and bumps the version. Products stored before versions
+// existed count as version 0. models.AnyVersion skips the check.
+func (r *productRepository) UpdateProduct(ctx context.Context, id string, version int64, req models.UpdateProductRequest) (*models.ProductResponse, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid product ID: %w", err)
+	}
+
+	filter := bson.M{"_id": objectID}
+	switch {
+	case version == 0:
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	case version > 0:
+		filter["version"] = version
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"name":        req.Name,
+			"description": req.Description,
+			"price":       req.Price,
+			"category":    req.Category,
+			"images":      req.Images,
+			"dimensions":  req.Dimensions,
+			"brand":       req.Brand,
+			"colors":      req.Colors,
+			"sku":         req.SKU,
+			"dt_updated":  time.Now(),
+		},
+		"$inc": bson.M{"version": 1},
+	}
+
+	var product models.Product
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&product)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("failed to update product: %w", err)
+		}
+		// Nothing matched: either the product is gone or someone else
+		// updated it first.
+		if _, err := r.LoadProduct(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("product version mismatch")
+	}
+
+	// Name, price and category show up in every listing, so unlike stock
+	// changes an update clears the list caches too.
+	if r.redis != nil {
+		r.redis.Del(ctx, fmt.Sprintf("product:%s", id))
+	}
+	r.clearProductCaches(ctx)
+
+	response := r.toProductResponse(product)
+	return &response, nil
+}
+
 func (r *productRepository) toProductResponse(product models.Product) models.ProductResponse {
 	return models.ProductResponse{
 		ID:          product.ID.Hex(),
@@ -453,60 +586,671 @@ func (r *productRepository) toProductResponse(product models.Product) models.Pro
 		Rating:      product.Rating,
 		Category:    product.Category,
 		Quantity:    product.Quantity,
+		Available:   product.Available(),
 		Images:      product.Images,
 		Dimensions:  product.Dimensions,
 		Brand:       product.Brand,
 		Colors:      product.Colors,
 		SKU:         product.SKU,
+		Version:     product.Version,
 		DateCreated: product.DateCreated,
 		DateUpdated: product.DateUpdated,
 	}
 }
 
-func (r *productRepository) UpdateProductQuantity(ctx context.Context, productID string, quantityChange int) error {
+// UpdateProductQuantity applies a stock change to one product and records it
+// in the ledger, in one transaction. change.Type must already be set.
+func (r *productRepository) UpdateProductQuantity(ctx context.Context, productID string, change models.InventoryAdjustment) error {
 	objectID, err := primitive.ObjectIDFromHex(productID)
 	if err != nil {
 		logger.Error("invalid product ID format in UpdateProductQuantity",
 			logger.String("product_id", productID),
-			logger.Int("quantity_change", quantityChange),
+			logger.Int("quantity_change", change.QuantityChange),
 			logger.Err(err))
 		return fmt.Errorf("invalid product ID %s: %w", productID, err)
 	}
 
 	filter := bson.M{"_id": objectID}
-	// If deducting, ensure we don't go below zero
-	if quantityChange < 0 {
-		filter["quantity"] = bson.M{"$gte": -quantityChange}
+	// If deducting, ensure we don't go below zero or into held stock
+	if change.QuantityChange < 0 {
+		filter["$expr"] = bson.M{"$gte": bson.A{availableExpr, -change.QuantityChange}}
 	}
 
 	// Use $inc to atomically increment/decrement the quantity
 	update := bson.M{
-		"$inc": bson.M{"quantity": quantityChange},
+		"$inc": bson.M{"quantity": change.QuantityChange},
+		"$set": bson.M{"dt_updated": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	err = r.transact(ctx, func(ctx context.Context) error {
+		var product models.Product
+		err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&product)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			if change.QuantityChange < 0 {
+				return fmt.Errorf("insufficient stock or product not found")
+			}
+			return fmt.Errorf("product not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update quantity: %w", err)
+		}
+
+		return r.recordMovement(ctx, models.StockMovement{
+			ProductID:      objectID,
+			Type:           change.Type,
+			QuantityChange: change.QuantityChange,
+			Quantity:       product.Quantity,
+			Reason:         change.Reason,
+			OrderID:        change.OrderID,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	r.clearStockCaches(ctx, productID)
+	return nil
+}
+
+// recordMovement appends a ledger entry for a stock change just made with
+// ctx, which must carry the change's transaction.
+func (r *productRepository) recordMovement(ctx context.Context, movement models.StockMovement) error {
+	movement.Actor = models.ActorFromContext(ctx)
+	movement.DateCreated = time.Now()
+	if _, err := r.movements.InsertOne(ctx, movement); err != nil {
+		return fmt.Errorf("failed to record stock movement: %w", err)
+	}
+	return nil
+}
+
+// errBatchRejected aborts the transaction of a batch with a failed line.
+var errBatchRejected = errors.New("inventory batch rejected")
+
+// ApplyInventoryBatch applies an order's stock changes in one transaction:
+// all of them, or none when a line cannot be applied. Every line gets an
+// outcome either way. An applied batch is recorded under the order ID and
+// sending it again returns the recorded outcome without touching stock;
+// rejected batches are not recorded, so they can be retried after a restock.
+func (r *productRepository) ApplyInventoryBatch(ctx context.Context, orderID string, changes []models.InventoryChange) (*models.InventoryBatchResponse, error) {
+	var resp *models.InventoryBatchResponse
+	err := r.transact(ctx, func(ctx context.Context) error {
+		var recorded models.InventoryOperation
+		err := r.operations.FindOne(ctx, bson.M{"_id": orderID}).Decode(&recorded)
+		if err == nil {
+			if !sameInventoryChanges(recorded.Items, changes) {
+				return fmt.Errorf("inventory batch mismatch")
+			}
+			resp = &models.InventoryBatchResponse{OrderID: orderID, Applied: true, Replayed: true, Items: recorded.Items}
+			return nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("failed to load inventory operation: %w", err)
+		}
+
+		lines := make([]models.InventoryLineResult, len(changes))
+		applied := true
+		for i, change := range changes {
+			if lines[i], err = r.applyInventoryLine(ctx, orderID, change); err != nil {
+				return err
+			}
+			applied = applied && lines[i].Status == models.InventoryLineApplied
+		}
+
+		resp = &models.InventoryBatchResponse{OrderID: orderID, Applied: applied, Items: lines}
+		if !applied {
+			for i := range lines {
+				if lines[i].Status == models.InventoryLineApplied {
+					lines[i].Status = models.InventoryLineNotApplied
+					lines[i].Quantity = nil
+				}
+			}
+			return errBatchRejected
+		}
+
+		if _, err := r.operations.InsertOne(ctx, models.InventoryOperation{
+			OrderID:     orderID,
+			Items:       lines,
+			DateCreated: time.Now(),
+		}); err != nil {
+			return fmt.Errorf("failed to record inventory operation: %w", err)
+		}
+		return nil
+	})
+	if errors.Is(err, errBatchRejected) {
+		return resp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.Replayed {
+		for _, change := range changes {
+			r.clearStockCaches(ctx, change.ProductID)
+		}
+	}
+	return resp, nil
+}
+
+// applyInventoryLine applies one line of a batch and records it in the
+// ledger. A deduction uses up the order's own hold on the product and drops
+// it; like UpdateProductQuantity, it cannot go into stock other orders hold.
+func (r *productRepository) applyInventoryLine(ctx context.Context, orderID string, change models.InventoryChange) (models.InventoryLineResult, error) {
+	line := models.InventoryLineResult{ProductID: change.ProductID, QuantityChange: change.QuantityChange}
+	objectID, err := primitive.ObjectIDFromHex(change.ProductID)
+	if err != nil {
+		line.Status = models.InventoryLineNotFound
+		return line, nil
+	}
+
+	filter := bson.M{"_id": objectID}
+	update := bson.M{
+		"$inc": bson.M{"quantity": change.QuantityChange},
 		"$set": bson.M{"dt_updated": time.Now()},
 	}
+	if change.QuantityChange < 0 {
+		filter["$expr"] = bson.M{"$gte": bson.A{availableToOrder(orderID), -change.QuantityChange}}
+		update["$pull"] = bson.M{"holds": bson.M{"order_id": orderID}}
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var product models.Product
+	err = r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&product)
+	switch {
+	case err == nil:
+		line.Status = models.InventoryLineApplied
+		line.Quantity = &product.Quantity
+		movementType := models.MovementOrderDeduction
+		if change.QuantityChange > 0 {
+			movementType = models.MovementCompensation
+		}
+		if err := r.recordMovement(ctx, models.StockMovement{
+			ProductID:      objectID,
+			Type:           movementType,
+			QuantityChange: change.QuantityChange,
+			Quantity:       product.Quantity,
+			OrderID:        orderID,
+		}); err != nil {
+			return line, err
+		}
+	case errors.Is(err, mongo.ErrNoDocuments):
+		line.Status = models.InventoryLineNotFound
+		if change.QuantityChange < 0 {
+			count, err := r.collection.CountDocuments(ctx, bson.M{"_id": objectID})
+			if err != nil {
+				return line, fmt.Errorf("failed to update quantity: %w", err)
+			}
+			if count > 0 {
+				line.Status = models.InventoryLineInsufficientStock
+			}
+		}
+	default:
+		return line, fmt.Errorf("failed to update quantity: %w", err)
+	}
+	return line, nil
+}
+
+// sameInventoryChanges reports whether changes are the recorded ones, in any
+// order: a committed reservation lists its products in the order they were
+// stored.
+func sameInventoryChanges(recorded []models.InventoryLineResult, changes []models.InventoryChange) bool {
+	if len(recorded) != len(changes) {
+		return false
+	}
+	want := make(map[string]int, len(recorded))
+	for _, line := range recorded {
+		want[line.ProductID] = line.QuantityChange
+	}
+	for _, change := range changes {
+		if quantityChange, ok := want[change.ProductID]; !ok || quantityChange != change.QuantityChange {
+			return false
+		}
+	}
+	return true
+}
+
+// availableExpr computes a product's available stock inside a query: the
+// quantity on hand minus its active holds.
+var availableExpr = bson.M{"$subtract": bson.A{"$quantity", bson.M{"$sum": bson.M{"$map": bson.M{
+	"input": bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$holds", bson.A{}}},
+		"cond":  bson.M{"$eq": bson.A{"$$this.status", models.HoldActive}},
+	}},
+	"in": "$$this.quantity",
+}}}}}
+
+// availableToOrder computes inside a query the stock an order can deduct:
+// the quantity on hand minus the active holds of other orders.
+func availableToOrder(orderID string) bson.M {
+	return bson.M{"$subtract": bson.A{"$quantity", bson.M{"$sum": bson.M{"$map": bson.M{
+		"input": bson.M{"$filter": bson.M{
+			"input": bson.M{"$ifNull": bson.A{"$holds", bson.A{}}},
+			"cond": bson.M{"$and": bson.A{
+				bson.M{"$eq": bson.A{"$$this.status", models.HoldActive}},
+				bson.M{"$ne": bson.A{"$$this.order_id", orderID}},
+			}},
+		}},
+		"in": "$$this.quantity",
+	}}}}}
+}
+
+// ReservationError names the product a reservation could not hold. Reason
+// is "insufficient stock" or "product not found".
+type ReservationError struct {
+	ProductID string
+	Reason    string
+}
+
+func (e *ReservationError) Error() string {
+	return e.Reason
+}
+
+// ReserveStock holds the items of an order until expiresAt, in one
+// transaction: every item or none. Products the order already holds are
+// skipped, so reserving again is a no-op. An order whose stock was already
+// deducted (see CommitStock) is left alone and reported as committed.
+func (r *productRepository) ReserveStock(ctx context.Context, orderID string, items []models.ReservationItem, expiresAt time.Time) (bool, error) {
+	ids := make([]primitive.ObjectID, len(items))
+	for i, item := range items {
+		objectID, err := primitive.ObjectIDFromHex(item.ProductID)
+		if err != nil {
+			return false, fmt.Errorf("invalid product ID: %w", err)
+		}
+		ids[i] = objectID
+	}
+
+	var committed bool
+	err := r.transact(ctx, func(ctx context.Context) error {
+		var err error
+		if committed, err = r.orderRecorded(ctx, orderID); err != nil || committed {
+			return err
+		}
+		for i, item := range items {
+			if err := r.holdStock(ctx, ids[i], orderID, item.Quantity, expiresAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil || committed {
+		return committed, err
+	}
+
+	for _, item := range items {
+		r.clearStockCaches(ctx, item.ProductID)
+	}
+	return false, nil
+}
 
+// holdStock holds quantity units of a product for an order if that many
+// are available and the order holds none of it yet.
+func (r *productRepository) holdStock(ctx context.Context, productID primitive.ObjectID, orderID string, quantity int, expiresAt time.Time) error {
+	filter := bson.M{
+		"_id":            productID,
+		"holds.order_id": bson.M{"$ne": orderID},
+		"$expr":          bson.M{"$gte": bson.A{availableExpr, quantity}},
+	}
+	update := bson.M{
+		"$push": bson.M{"holds": models.StockHold{
+			OrderID:   orderID,
+			Quantity:  quantity,
+			Status:    models.HoldActive,
+			ExpiresAt: expiresAt,
+		}},
+		"$set": bson.M{"dt_updated": time.Now()},
+	}
 	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return fmt.Errorf("failed to update quantity: %w", err)
+		return fmt.Errorf("failed to reserve stock: %w", err)
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	product, err := r.LoadProduct(ctx, productID.Hex())
+	if err != nil {
+		if err.Error() == "product not found" {
+			return &ReservationError{ProductID: productID.Hex(), Reason: "product not found"}
+		}
+		return err
+	}
+	for _, hold := range product.Holds {
+		if hold.OrderID == orderID {
+			return nil
+		}
+	}
+	return &ReservationError{ProductID: productID.Hex(), Reason: "insufficient stock"}
+}
+
+// orderRecorded reports whether stock was already deducted for an order,
+// by a committed reservation or an applied batch.
+func (r *productRepository) orderRecorded(ctx context.Context, orderID string) (bool, error) {
+	count, err := r.operations.CountDocuments(ctx, bson.M{"_id": orderID})
+	if err != nil {
+		return false, fmt.Errorf("failed to load inventory operation: %w", err)
 	}
+	return count > 0, nil
+}
+
+// CommitStock deducts the stock an order holds from each product's
+// quantity, records each deduction in the ledger and records the order in
+// inventory_operations, all in one transaction. The record is kept for good,
+// so committing again is a no-op however late it comes.
+func (r *productRepository) CommitStock(ctx context.Context, orderID string) error {
+	var committed []string
+	err := r.transact(ctx, func(ctx context.Context) error {
+		committed = nil
+		recorded, err := r.orderRecorded(ctx, orderID)
+		if err != nil || recorded {
+			return err
+		}
+
+		active := bson.M{"order_id": orderID, "status": models.HoldActive}
+		cursor, err := r.collection.Find(ctx, bson.M{"holds": bson.M{"$elemMatch": active}})
+		if err != nil {
+			return fmt.Errorf("failed to find reservation: %w", err)
+		}
+		var products []models.Product
+		if err := cursor.All(ctx, &products); err != nil {
+			return fmt.Errorf("failed to find reservation: %w", err)
+		}
+		if len(products) == 0 {
+			return fmt.Errorf("reservation not found")
+		}
+
+		var lines []models.InventoryLineResult
+		for _, product := range products {
+			for _, hold := range product.Holds {
+				if hold.OrderID != orderID || hold.Status != models.HoldActive {
+					continue
+				}
+				line, err := r.commitHold(ctx, product.ID, hold)
+				if err != nil {
+					return err
+				}
+				lines = append(lines, line)
+				committed = append(committed, product.ID.Hex())
+			}
+		}
 
-	if result.MatchedCount == 0 {
-		if quantityChange < 0 {
-			return fmt.Errorf("insufficient stock or product not found")
+		if _, err := r.operations.InsertOne(ctx, models.InventoryOperation{
+			OrderID:     orderID,
+			Items:       lines,
+			DateCreated: time.Now(),
+		}); err != nil {
+			return fmt.Errorf("failed to record inventory operation: %w", err)
 		}
-		return fmt.Errorf("product not found")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, productID := range committed {
+		r.clearStockCaches(ctx, productID)
+	}
+	return nil
+}
+
+// commitHold deducts an active hold from its product's quantity, drops the
+// hold and records the deduction in the ledger.
+func (r *productRepository) commitHold(ctx context.Context, productID primitive.ObjectID, hold models.StockHold) (models.InventoryLineResult, error) {
+	filter := bson.M{
+		"_id": productID,
+		"holds": bson.M{"$elemMatch": bson.M{
+			"order_id": hold.OrderID,
+			"status":   models.HoldActive,
+			"quantity": hold.Quantity,
+		}},
+	}
+	update := bson.M{
+		"$inc":  bson.M{"quantity": -hold.Quantity},
+		"$pull": bson.M{"holds": bson.M{"order_id": hold.OrderID}},
+		"$set":  bson.M{"dt_updated": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated models.Product
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated)
+	// Released or expired since it was read.
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.InventoryLineResult{}, fmt.Errorf("reservation not found")
+	}
+	if err != nil {
+		return models.InventoryLineResult{}, fmt.Errorf("failed to commit reservation: %w", err)
+	}
+
+	if err := r.recordMovement(ctx, models.StockMovement{
+		ProductID:      productID,
+		Type:           models.MovementOrderDeduction,
+		QuantityChange: -hold.Quantity,
+		Quantity:       updated.Quantity,
+		Reason:         "reservation committed",
+		OrderID:        hold.OrderID,
+	}); err != nil {
+		return models.InventoryLineResult{}, err
+	}
+	return models.InventoryLineResult{
+		ProductID:      productID.Hex(),
+		QuantityChange: -hold.Quantity,
+		Status:         models.InventoryLineApplied,
+		Quantity:       &updated.Quantity,
+	}, nil
+}
+
+// ReleaseStock drops the active holds of an order, returning their units to
+// available stock. Releasing an order without active holds is a no-op.
+func (r *productRepository) ReleaseStock(ctx context.Context, orderID string) error {
+	active := bson.M{"order_id": orderID, "status": models.HoldActive}
+	ids, err := r.productIDs(ctx, bson.M{"holds": bson.M{"$elemMatch": active}})
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	_, err = r.collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.M{"$pull": bson.M{"holds": active}})
+	if err != nil {
+		return fmt.Errorf("failed to release reservation: %w", err)
+	}
+	for _, id := range ids {
+		r.clearStockCaches(ctx, id.Hex())
+	}
+	return nil
+}
+
+// ExpireHolds drops every hold that expired by now, returning its units to
+// available stock. It returns the number of products changed.
+func (r *productRepository) ExpireHolds(ctx context.Context, now time.Time) (int64, error) {
+	expired := bson.M{"expires_at": bson.M{"$lte": now}}
+	ids, err := r.productIDs(ctx, bson.M{"holds": bson.M{"$elemMatch": expired}})
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	result, err := r.collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.M{"$pull": bson.M{"holds": expired}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire holds: %w", err)
+	}
+	for _, id := range ids {
+		r.clearStockCaches(ctx, id.Hex())
+	}
+	return result.ModifiedCount, nil
+}
+
+// GetStockMovements returns a page of a product's ledger, newest first.
+func (r *productRepository) GetStockMovements(ctx context.Context, productID string, page, pageSize int) (*models.StockMovementsPage, error) {
+	objectID, err := primitive.ObjectIDFromHex(productID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid product ID: %w", err)
 	}
 
-	// PERFORMANCE: Only invalidate specific product caches, not all products
-	// This dramatically improves cache hit rate during high-frequency quantity updates
+	filter := bson.M{"product_id": objectID}
+	totalCount, err := r.movements.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count stock movements: %w", err)
+	}
+	if totalCount == 0 {
+		// No history yet, or no such product.
+		if _, err := r.LoadProduct(ctx, productID); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "dt_created", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+	cursor, err := r.movements.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stock movements: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	movements := []models.StockMovement{}
+	if err := cursor.All(ctx, &movements); err != nil {
+		return nil, fmt.Errorf("failed to decode stock movements: %w", err)
+	}
+
+	return &models.StockMovementsPage{
+		Movements:  movements,
+		TotalCount: totalCount,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: int((totalCount + int64(pageSize) - 1) / int64(pageSize)),
+	}, nil
+}
+
+// ledgerBalance is the stock a product's movements add up to.
+type ledgerBalance struct {
+	ProductID primitive.ObjectID `bson:"_id"`
+	Quantity  int                `bson:"quantity"`
+	Movements int                `bson:"movements"`
+}
+
+// InventoryDrift recomputes every product's quantity from the ledger and
+// returns the number of products checked and those that disagree. Both are
+// read in one transaction so changes made meanwhile do not show up as drift.
+func (r *productRepository) InventoryDrift(ctx context.Context) (int, []models.InventoryDrift, error) {
+	var checked int
+	var drift []models.InventoryDrift
+	err := r.transact(ctx, func(ctx context.Context) error {
+		cursor, err := r.movements.Aggregate(ctx, mongo.Pipeline{
+			{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: "$product_id"},
+				{Key: "quantity", Value: bson.D{{Key: "$sum", Value: "$quantity_change"}}},
+				{Key: "movements", Value: bson.D{{Key: "$sum", Value: 1}}},
+			}}},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to sum stock movements: %w", err)
+		}
+		var balances []ledgerBalance
+		if err := cursor.All(ctx, &balances); err != nil {
+			return fmt.Errorf("failed to sum stock movements: %w", err)
+		}
+		ledger := make(map[primitive.ObjectID]ledgerBalance, len(balances))
+		for _, balance := range balances {
+			ledger[balance.ProductID] = balance
+		}
+
+		cursor, err = r.collection.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"name": 1, "quantity": 1}))
+		if err != nil {
+			return fmt.Errorf("failed to find products: %w", err)
+		}
+		var products []models.Product
+		if err := cursor.All(ctx, &products); err != nil {
+			return fmt.Errorf("failed to find products: %w", err)
+		}
+
+		checked, drift = len(products), nil
+		for _, product := range products {
+			balance := ledger[product.ID]
+			if product.Quantity == balance.Quantity {
+				continue
+			}
+			drift = append(drift, models.InventoryDrift{
+				ProductID:      product.ID.Hex(),
+				Name:           product.Name,
+				Quantity:       product.Quantity,
+				LedgerQuantity: balance.Quantity,
+				Drift:          product.Quantity - balance.Quantity,
+				Movements:      balance.Movements,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return checked, drift, nil
+}
+
+// RecordOpeningStock starts the ledger of a product that has none, which is
+// the case for products created before it existed, with an opening entry
+// for its current quantity. It reports whether an entry was recorded.
+func (r *productRepository) RecordOpeningStock(ctx context.Context, productID string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(productID)
+	if err != nil {
+		return false, fmt.Errorf("invalid product ID: %w", err)
+	}
+
+	recorded := false
+	err = r.transact(ctx, func(ctx context.Context) error {
+		recorded = false
+		count, err := r.movements.CountDocuments(ctx, bson.M{"product_id": objectID})
+		if err != nil {
+			return fmt.Errorf("failed to count stock movements: %w", err)
+		}
+		if count > 0 {
+			return nil
+		}
+
+		product, err := r.LoadProduct(ctx, productID)
+		if err != nil || product.Quantity == 0 {
+			return err
+		}
+		if err := r.recordMovement(ctx, models.StockMovement{
+			ProductID:      objectID,
+			Type:           models.MovementOpening,
+			QuantityChange: product.Quantity,
+			Quantity:       product.Quantity,
+			Reason:         "baseline",
+		}); err != nil {
+			return err
+		}
+		recorded = true
+		return nil
+	})
+	return recorded, err
+}
+
+func (r *productRepository) productIDs(ctx context.Context, filter bson.M) ([]primitive.ObjectID, error) {
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find products: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var products []models.Product
+	if err := cursor.All(ctx, &products); err != nil {
+		return nil, fmt.Errorf("failed to find products: %w", err)
+	}
+	ids := make([]primitive.ObjectID, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+	return ids, nil
+}
+
+// clearStockCaches drops the cache entries showing a product's stock.
+// PERFORMANCE: Only invalidate specific product caches, not all products
+// This dramatically improves cache hit rate during high-frequency stock changes
+func (r *productRepository) clearStockCaches(ctx context.Context, productID string) {
 	if r.redis != nil {
 		r.redis.Del(ctx,
 			fmt.Sprintf("productQty:%s", productID),
 			fmt.Sprintf("product:%s", productID),
 		)
 	}
-
-	return nil
 }
 
 func (r *productRepository) GetProductQuantity(ctx context.Context, productID string) (int, error) {
@@ -567,22 +1311,25 @@ func (r *productRepository) clearProductCaches(ctx context.Context) {
 	// Clear counts cache
 	r.redis.Del(ctx, "productsCount")
 
-	// Clear all pagination caches (pattern delete)
-	// Note: This is a simple approach. For production, consider using Redis SCAN
-	keys, err := r.redis.Keys(ctx, "productsPage:*").Result()
-	if err == nil && len(keys) > 0 {
-		r.redis.Del(ctx, keys...)
-	}
-
-	// Clear category pagination caches
-	keys, err = r.redis.Keys(ctx, "productsPageCat:*").Result()
-	if err == nil && len(keys) > 0 {
-		r.redis.Del(ctx, keys...)
-	}
+	// Clear all pagination caches, including the per-category ones
+	r.deleteKeys(ctx, "productsPage*")
 
 	// Clear category count caches
-	keys, err = r.redis.Keys(ctx, "productsCountCat:*").Result()
-	if err == nil && len(keys) > 0 {
+	r.deleteKeys(ctx, "productsCountCat:*")
+}
+
+// deleteKeys deletes the keys matching pattern, using SCAN rather than KEYS so
+// Redis is not blocked while it walks the keyspace.
+func (r *productRepository) deleteKeys(ctx context.Context, pattern string) {
+	iter := r.redis.Scan(ctx, 0, pattern, 100).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		logger.Error("failed to scan cache keys", logger.String("pattern", pattern), logger.Err(err))
+	}
+	if len(keys) > 0 {
 		r.redis.Del(ctx, keys...)
 	}
 }
@@ -45,18 +45,22 @@ func TestCreateProduct_SuccessClearsCache(t *testing.T) {
 		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
 		require.NoError(mt, rdb.Set(ctx, "allProducts", "value", 0).Err())
 
-		repo := &productRepository{collection: mt.Coll, redis: rdb}
-		mt.AddMockResponses(mtest.CreateSuccessResponse(
-			bson.E{Key: "n", Value: 1},
-			bson.E{Key: "ok", Value: 1},
-			bson.E{Key: "insertedId", Value: primitive.NewObjectID()},
-		))
+		repo := &productRepository{collection: mt.Coll, movements: mt.Coll, redis: rdb, transact: inline}
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
 
 		req := models.CreateProductRequest{Name: "Toy", Price: 9.9, Quantity: 3}
-		res, err := repo.CreateProduct(ctx, req)
+		res, err := repo.CreateProduct(models.WithActor(ctx, "1"), req)
 		require.NoError(mt, err)
 		require.Equal(mt, req.Name, res.Name)
 		require.False(mt, mr.Exists("allProducts"))
+
+		opening := lastInsert(mt)
+		require.Equal(mt, models.MovementOpening, opening.Lookup("type").StringValue())
+		require.Equal(mt, int32(3), opening.Lookup("quantity_change").Int32())
+		require.Equal(mt, "1", opening.Lookup("actor").StringValue())
 	})
 }
 
@@ -110,11 +114,21 @@ func TestUpdateProductQuantity_Paths(t *testing.T) {
 		_ = rdb.Set(ctx, "allProducts", "value", 0)
 		_ = rdb.Set(ctx, "productCategories", "value", 0)
 
-		repo := &productRepository{collection: mt.Coll, redis: rdb}
-		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+		repo := &productRepository{collection: mt.Coll, movements: mt.Coll, redis: rdb, transact: inline}
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{{Key: "quantity", Value: 12}}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
 
-		err = repo.UpdateProductQuantity(ctx, productID, 2)
+		change := models.InventoryAdjustment{QuantityChange: 2, Type: models.MovementRestock, Reason: "delivery"}
+		err = repo.UpdateProductQuantity(models.WithActor(ctx, "warehouse"), productID, change)
 		require.NoError(mt, err)
+		movement := lastInsert(mt)
+		require.Equal(mt, productID, movement.Lookup("product_id").ObjectID().Hex())
+		require.Equal(mt, models.MovementRestock, movement.Lookup("type").StringValue())
+		require.Equal(mt, int32(12), movement.Lookup("quantity").Int32())
+		require.Equal(mt, "warehouse", movement.Lookup("actor").StringValue())
+		require.Equal(mt, "delivery", movement.Lookup("reason").StringValue())
 		// Product-specific caches should be cleared
 		require.False(mt, mr.Exists(fmt.Sprintf("productQty:%s", productID)))
 		require.False(mt, mr.Exists(fmt.Sprintf("product:%s", productID)))
@@ -124,11 +138,20 @@ func TestUpdateProductQuantity_Paths(t *testing.T) {
 	})
 
 	mt.Run("not found returns error", func(mt *mtest.T) {
-		repo := &productRepository{collection: mt.Coll}
-		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+		repo := &productRepository{collection: mt.Coll, movements: mt.Coll, transact: inline}
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}))
+
+		err := repo.UpdateProductQuantity(ctx, primitive.NewObjectID().Hex(), models.InventoryAdjustment{QuantityChange: 1, Type: models.MovementRestock})
+		require.EqualError(mt, err, "product not found")
+	})
+
+	mt.Run("short stock records nothing", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, movements: mt.Coll, transact: inline}
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}))
 
-		err := repo.UpdateProductQuantity(ctx, primitive.NewObjectID().Hex(), 1)
-		require.Error(mt, err)
+		err := repo.UpdateProductQuantity(ctx, primitive.NewObjectID().Hex(), models.InventoryAdjustment{QuantityChange: -1, Type: models.MovementAdjustment})
+		require.EqualError(mt, err, "insufficient stock or product not found")
+		require.Len(mt, mt.GetAllStartedEvents(), 1)
 	})
 }
 
@@ -264,3 +287,72 @@ func TestGetProductsByPageAndCategory_SuccessfulFetch(t *testing.T) {
 		require.Equal(mt, "Cats", resp.Products[0].Category)
 	})
 }
+
+func TestUpdateProduct_Paths(t *testing.T) {
+	ctx := context.Background()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	req := models.UpdateProductRequest{Name: "Toy", Price: 12}
+
+	mt.Run("success clears product and list caches", func(mt *mtest.T) {
+		mr, err := miniredis.Run()
+		require.NoError(mt, err)
+		defer mr.Close()
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+		id := primitive.NewObjectID()
+		for _, key := range []string{"product:" + id.Hex(), "allProducts", "productsPage:1:20", "productsPageCat:1:20:toys", "productsCountCat:toys"} {
+			require.NoError(mt, rdb.Set(ctx, key, "value", 0).Err())
+		}
+
+		repo := &productRepository{collection: mt.Coll, redis: rdb}
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+			{Key: "_id", Value: id},
+			{Key: "name", Value: "Toy"},
+			{Key: "price", Value: 12.0},
+			{Key: "version", Value: int64(3)},
+		}}))
+
+		res, err := repo.UpdateProduct(ctx, id.Hex(), 2, req)
+		require.NoError(mt, err)
+		require.Equal(mt, "Toy", res.Name)
+		require.Equal(mt, int64(3), res.Version)
+		keys, err := rdb.Keys(ctx, "*").Result()
+		require.NoError(mt, err)
+		require.Empty(mt, keys)
+	})
+
+	mt.Run("stale version", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll}
+		id := primitive.NewObjectID()
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}),
+			mtest.CreateCursorResponse(1, ns, mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: id},
+				{Key: "version", Value: int64(5)},
+			}),
+			mtest.CreateCursorResponse(0, ns, mtest.NextBatch),
+		)
+
+		_, err := repo.UpdateProduct(ctx, id.Hex(), 2, req)
+		require.EqualError(mt, err, "product version mismatch")
+	})
+
+	mt.Run("not found", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+		)
+
+		_, err := repo.UpdateProduct(ctx, primitive.NewObjectID().Hex(), 2, req)
+		require.EqualError(mt, err, "product not found")
+	})
+
+	mt.Run("invalid id", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll}
+		_, err := repo.UpdateProduct(ctx, "bad-id", 2, req)
+		require.ErrorContains(mt, err, "invalid product ID")
+	})
+}
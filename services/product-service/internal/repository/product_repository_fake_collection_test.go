@@ -80,6 +80,10 @@ func (f *fakeCollection) UpdateOne(ctx context.Context, filter interface{}, upda
 	return &mongo.UpdateResult{MatchedCount: 1, ModifiedCount: 1}, nil
 }
 
+func (f *fakeCollection) UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return &mongo.UpdateResult{MatchedCount: 1, ModifiedCount: 1}, nil
+}
+
 func (f *fakeCollection) FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult {
 	filtered := f.filterProducts(filter)
 	if len(filtered) == 0 {
@@ -88,6 +92,14 @@ func (f *fakeCollection) FindOne(ctx context.Context, filter interface{}, opts .
 	return mongo.NewSingleResultFromDocument(filtered[0], nil, nil)
 }
 
+func (f *fakeCollection) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	return f.FindOne(ctx, filter)
+}
+
+func (f *fakeCollection) Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	return mongo.NewCursorFromDocuments(nil, nil, nil)
+}
+
 func (f *fakeCollection) filterProducts(filter interface{}) []models.Product {
 	if filter == nil {
 		return f.products
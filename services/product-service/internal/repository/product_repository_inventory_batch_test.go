@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/icl00ud/velure/services/product-service/internal/model"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// inline runs the transaction body directly; the mock deployment has no
+// sessions.
+func inline(ctx context.Context, fn func(context.Context) error) error {
+	return fn(ctx)
+}
+
+func TestApplyInventoryBatch_Paths(t *testing.T) {
+	ctx := context.Background()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	p1, p2 := primitive.NewObjectID(), primitive.NewObjectID()
+	changes := []models.InventoryChange{
+		{ProductID: p1.Hex(), QuantityChange: -2},
+		{ProductID: p2.Hex(), QuantityChange: 3},
+	}
+
+	mt.Run("applies every line and records the batch", func(mt *mtest.T) {
+		mr, err := miniredis.Run()
+		require.NoError(mt, err)
+		defer mr.Close()
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		_ = rdb.Set(ctx, "productQty:"+p1.Hex(), "value", 0)
+		_ = rdb.Set(ctx, "product:"+p2.Hex(), "value", 0)
+
+		repo := &productRepository{collection: mt.Coll, operations: mt.Coll, movements: mt.Coll, redis: rdb, transact: inline}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{{Key: "_id", Value: p1}, {Key: "quantity", Value: 8}}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{{Key: "_id", Value: p2}, {Key: "quantity", Value: 3}}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		resp, err := repo.ApplyInventoryBatch(ctx, "order-1", changes)
+		require.NoError(mt, err)
+		require.True(mt, resp.Applied)
+		require.False(mt, resp.Replayed)
+		require.Len(mt, resp.Items, 2)
+		require.Equal(mt, models.InventoryLineApplied, resp.Items[0].Status)
+		require.Equal(mt, 8, *resp.Items[0].Quantity)
+		require.Equal(mt, 3, *resp.Items[1].Quantity)
+		require.False(mt, mr.Exists("productQty:"+p1.Hex()))
+		require.False(mt, mr.Exists("product:"+p2.Hex()))
+
+		var movements []bson.Raw
+		for _, evt := range mt.GetAllStartedEvents() {
+			if evt.CommandName == "insert" {
+				movements = append(movements, evt.Command.Lookup("documents").Array().Index(0).Value().Document())
+			}
+		}
+		require.Len(mt, movements, 3)
+		require.Equal(mt, models.MovementOrderDeduction, movements[0].Lookup("type").StringValue())
+		require.Equal(mt, "order-1", movements[0].Lookup("order_id").StringValue())
+		require.Equal(mt, models.MovementCompensation, movements[1].Lookup("type").StringValue())
+
+		// The deduction uses up the order's own hold; the increase leaves
+		// holds alone.
+		var updates []bson.Raw
+		for _, evt := range mt.GetAllStartedEvents() {
+			if evt.CommandName == "findAndModify" {
+				updates = append(updates, evt.Command.Lookup("update").Document())
+			}
+		}
+		require.Len(mt, updates, 2)
+		require.Equal(mt, "order-1", updates[0].Lookup("$pull", "holds", "order_id").StringValue())
+		_, err = updates[1].LookupErr("$pull")
+		require.Error(mt, err)
+	})
+
+	mt.Run("a batch matches a committed reservation in any order", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, operations: mt.Coll, movements: mt.Coll, transact: inline}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "order-1"},
+			{Key: "items", Value: bson.A{
+				bson.D{{Key: "product_id", Value: p2.Hex()}, {Key: "quantity_change", Value: 3}, {Key: "status", Value: models.InventoryLineApplied}},
+				bson.D{{Key: "product_id", Value: p1.Hex()}, {Key: "quantity_change", Value: -2}, {Key: "status", Value: models.InventoryLineApplied}},
+			}},
+		}))
+
+		resp, err := repo.ApplyInventoryBatch(ctx, "order-1", changes)
+		require.NoError(mt, err)
+		require.True(mt, resp.Replayed)
+	})
+
+	mt.Run("a recorded batch is replayed", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, operations: mt.Coll, movements: mt.Coll, transact: inline}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "order-1"},
+			{Key: "items", Value: bson.A{
+				bson.D{{Key: "product_id", Value: p1.Hex()}, {Key: "quantity_change", Value: -2}, {Key: "status", Value: models.InventoryLineApplied}, {Key: "quantity", Value: 8}},
+				bson.D{{Key: "product_id", Value: p2.Hex()}, {Key: "quantity_change", Value: 3}, {Key: "status", Value: models.InventoryLineApplied}, {Key: "quantity", Value: 3}},
+			}},
+		}))
+
+		resp, err := repo.ApplyInventoryBatch(ctx, "order-1", changes)
+		require.NoError(mt, err)
+		require.True(mt, resp.Applied)
+		require.True(mt, resp.Replayed)
+		require.Equal(mt, 8, *resp.Items[0].Quantity)
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "order-1"},
+			{Key: "items", Value: bson.A{
+				bson.D{{Key: "product_id", Value: p1.Hex()}, {Key: "quantity_change", Value: -5}, {Key: "status", Value: models.InventoryLineApplied}},
+			}},
+		}))
+		_, err = repo.ApplyInventoryBatch(ctx, "order-1", changes)
+		require.EqualError(mt, err, "inventory batch mismatch")
+	})
+
+	mt.Run("a short line rejects the batch", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, operations: mt.Coll, movements: mt.Coll, transact: inline}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		reversed := []models.InventoryChange{changes[1], changes[0]}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{{Key: "_id", Value: p2}, {Key: "quantity", Value: 3}}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: int32(1)}}),
+		)
+
+		resp, err := repo.ApplyInventoryBatch(ctx, "order-2", reversed)
+		require.NoError(mt, err)
+		require.False(mt, resp.Applied)
+		require.Equal(mt, models.InventoryLineNotApplied, resp.Items[0].Status)
+		require.Nil(mt, resp.Items[0].Quantity)
+		require.Equal(mt, models.InventoryLineInsufficientStock, resp.Items[1].Status)
+	})
+
+	mt.Run("unknown products are not found", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, operations: mt.Coll, movements: mt.Coll, transact: inline}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}),
+		)
+
+		resp, err := repo.ApplyInventoryBatch(ctx, "order-3", []models.InventoryChange{
+			{ProductID: "not-an-id", QuantityChange: -1},
+			{ProductID: p2.Hex(), QuantityChange: 1},
+		})
+		require.NoError(mt, err)
+		require.False(mt, resp.Applied)
+		require.Equal(mt, models.InventoryLineNotFound, resp.Items[0].Status)
+		require.Equal(mt, models.InventoryLineNotFound, resp.Items[1].Status)
+	})
+
+	mt.Run("database errors abort", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, operations: mt.Coll, movements: mt.Coll, transact: inline}
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Message: "boom"}))
+
+		_, err := repo.ApplyInventoryBatch(ctx, "order-4", changes)
+		require.ErrorContains(mt, err, "failed to load inventory operation")
+	})
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/icl00ud/velure/services/product-service/internal/model"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// lastInsert returns the document of the last insert command sent.
+func lastInsert(mt *mtest.T) bson.Raw {
+	mt.Helper()
+	events := mt.GetAllStartedEvents()
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].CommandName == "insert" {
+			return events[i].Command.Lookup("documents").Array().Index(0).Value().Document()
+		}
+	}
+	mt.Fatal("no insert was sent")
+	return nil
+}
+
+func TestGetStockMovements(t *testing.T) {
+	ctx := context.Background()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	productID := primitive.NewObjectID()
+
+	mt.Run("returns a page, newest first", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, movements: mt.Coll}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: int32(3)}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+				bson.D{{Key: "product_id", Value: productID}, {Key: "type", Value: models.MovementOrderDeduction}, {Key: "quantity_change", Value: -1}, {Key: "quantity", Value: 4}},
+				bson.D{{Key: "product_id", Value: productID}, {Key: "type", Value: models.MovementRestock}, {Key: "quantity_change", Value: 3}, {Key: "quantity", Value: 5}},
+			),
+		)
+
+		page, err := repo.GetStockMovements(ctx, productID.Hex(), 1, 2)
+		require.NoError(mt, err)
+		require.Equal(mt, int64(3), page.TotalCount)
+		require.Equal(mt, 2, page.TotalPages)
+		require.Len(mt, page.Movements, 2)
+		require.Equal(mt, 4, page.Movements[0].Quantity)
+
+		find := mt.GetStartedEvent()
+		for find.CommandName != "find" {
+			find = mt.GetStartedEvent()
+		}
+		require.Equal(mt, int32(-1), find.Command.Lookup("sort", "dt_created").Int32())
+		require.Equal(mt, int64(2), find.Command.Lookup("limit").Int64())
+	})
+
+	mt.Run("a product without movements has an empty history", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, movements: mt.Coll}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: productID}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+		)
+
+		page, err := repo.GetStockMovements(ctx, productID.Hex(), 1, 20)
+		require.NoError(mt, err)
+		require.NotNil(mt, page.Movements)
+		require.Empty(mt, page.Movements)
+	})
+
+	mt.Run("unknown product", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, movements: mt.Coll}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+		)
+
+		_, err := repo.GetStockMovements(ctx, productID.Hex(), 1, 20)
+		require.EqualError(mt, err, "product not found")
+	})
+
+	mt.Run("invalid id", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, movements: mt.Coll}
+		_, err := repo.GetStockMovements(ctx, "bad-id", 1, 20)
+		require.ErrorContains(mt, err, "invalid product ID")
+	})
+}
+
+func TestInventoryDrift(t *testing.T) {
+	ctx := context.Background()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	balanced, short, legacy := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	mt.Run("compares quantities with the ledger", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, movements: mt.Coll, transact: inline}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: balanced}, {Key: "quantity", Value: 7}, {Key: "movements", Value: 3}},
+				bson.D{{Key: "_id", Value: short}, {Key: "quantity", Value: 10}, {Key: "movements", Value: 2}},
+			),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: balanced}, {Key: "name", Value: "Bowl"}, {Key: "quantity", Value: 7}},
+				bson.D{{Key: "_id", Value: short}, {Key: "name", Value: "Leash"}, {Key: "quantity", Value: 8}},
+				bson.D{{Key: "_id", Value: legacy}, {Key: "name", Value: "Toy"}, {Key: "quantity", Value: 4}},
+			),
+		)
+
+		checked, drift, err := repo.InventoryDrift(ctx)
+		require.NoError(mt, err)
+		require.Equal(mt, 3, checked)
+		require.Equal(mt, []models.InventoryDrift{
+			{ProductID: short.Hex(), Name: "Leash", Quantity: 8, LedgerQuantity: 10, Drift: -2, Movements: 2},
+			{ProductID: legacy.Hex(), Name: "Toy", Quantity: 4, Drift: 4},
+		}, drift)
+	})
+
+	mt.Run("database error", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, movements: mt.Coll, transact: inline}
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Message: "boom"}))
+
+		_, _, err := repo.InventoryDrift(ctx)
+		require.ErrorContains(mt, err, "failed to sum stock movements")
+	})
+}
+
+func TestRecordOpeningStock(t *testing.T) {
+	ctx := context.Background()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	productID := primitive.NewObjectID()
+
+	mt.Run("records the current quantity", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, movements: mt.Coll, transact: inline}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: productID}, {Key: "quantity", Value: 40}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+
+		recorded, err := repo.RecordOpeningStock(models.WithActor(ctx, "reconcile"), productID.Hex())
+		require.NoError(mt, err)
+		require.True(mt, recorded)
+		opening := lastInsert(mt)
+		require.Equal(mt, models.MovementOpening, opening.Lookup("type").StringValue())
+		require.Equal(mt, int32(40), opening.Lookup("quantity_change").Int32())
+		require.Equal(mt, "reconcile", opening.Lookup("actor").StringValue())
+	})
+
+	mt.Run("leaves a started ledger alone", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, movements: mt.Coll, transact: inline}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: int32(1)}}))
+
+		recorded, err := repo.RecordOpeningStock(ctx, productID.Hex())
+		require.NoError(mt, err)
+		require.False(mt, recorded)
+	})
+}
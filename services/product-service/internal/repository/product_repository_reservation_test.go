@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/icl00ud/velure/services/product-service/internal/model"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func holdDoc(orderID string, quantity int, status string) bson.D {
+	return bson.D{
+		{Key: "order_id", Value: orderID},
+		{Key: "quantity", Value: quantity},
+		{Key: "status", Value: status},
+		{Key: "expires_at", Value: time.Now().Add(time.Minute)},
+	}
+}
+
+// commandsSent lists the names of the commands sent to the mock server.
+func commandsSent(mt *mtest.T) []string {
+	var names []string
+	for _, evt := range mt.GetAllStartedEvents() {
+		names = append(names, evt.CommandName)
+	}
+	return names
+}
+
+func TestReserveStock_Paths(t *testing.T) {
+	ctx := context.Background()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	expiresAt := time.Now().Add(15 * time.Minute)
+	p1, p2 := primitive.NewObjectID(), primitive.NewObjectID()
+	items := []models.ReservationItem{{ProductID: p1.Hex(), Quantity: 2}, {ProductID: p2.Hex(), Quantity: 1}}
+
+	mt.Run("holds every item and clears stock caches", func(mt *mtest.T) {
+		mr, err := miniredis.Run()
+		require.NoError(mt, err)
+		defer mr.Close()
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		_ = rdb.Set(ctx, "product:"+p1.Hex(), "value", 0)
+		_ = rdb.Set(ctx, "productQty:"+p2.Hex(), "value", 0)
+
+		repo := &productRepository{collection: mt.Coll, operations: mt.Coll, redis: rdb, transact: inline}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		committed, err := repo.ReserveStock(ctx, "order-1", items, expiresAt)
+		require.NoError(mt, err)
+		require.False(mt, committed)
+		require.Equal(mt, []string{"aggregate", "update", "update"}, commandsSent(mt))
+		require.False(mt, mr.Exists("product:"+p1.Hex()))
+		require.False(mt, mr.Exists("productQty:"+p2.Hex()))
+	})
+
+	mt.Run("an order deducted already is committed", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, operations: mt.Coll, transact: inline}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: int32(1)}}))
+
+		committed, err := repo.ReserveStock(ctx, "order-1", items, expiresAt)
+		require.NoError(mt, err)
+		require.True(mt, committed)
+		require.Equal(mt, []string{"aggregate"}, commandsSent(mt))
+	})
+
+	mt.Run("repeated reservation is a no-op", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, operations: mt.Coll, transact: inline}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}),
+			mtest.CreateCursorResponse(1, ns, mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: p1},
+				{Key: "quantity", Value: 2},
+				{Key: "holds", Value: bson.A{holdDoc("order-1", 2, models.HoldActive)}},
+			}),
+			mtest.CreateCursorResponse(0, ns, mtest.NextBatch),
+		)
+
+		committed, err := repo.ReserveStock(ctx, "order-1", items[:1], expiresAt)
+		require.NoError(mt, err)
+		require.False(mt, committed)
+	})
+
+	mt.Run("a short item fails the whole reservation", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, operations: mt.Coll, transact: inline}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}),
+			mtest.CreateCursorResponse(1, ns, mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: p2},
+				{Key: "quantity", Value: 3},
+				{Key: "holds", Value: bson.A{holdDoc("order-2", 3, models.HoldActive)}},
+			}),
+			mtest.CreateCursorResponse(0, ns, mtest.NextBatch),
+		)
+
+		_, err := repo.ReserveStock(ctx, "order-1", items, expiresAt)
+		require.EqualError(mt, err, "insufficient stock")
+		var reservationErr *ReservationError
+		require.ErrorAs(mt, err, &reservationErr)
+		require.Equal(mt, p2.Hex(), reservationErr.ProductID)
+	})
+
+	mt.Run("not found", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, operations: mt.Coll, transact: inline}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+		)
+
+		_, err := repo.ReserveStock(ctx, "order-1", items, expiresAt)
+		require.EqualError(mt, err, "product not found")
+	})
+
+	mt.Run("invalid id", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, operations: mt.Coll, transact: inline}
+		_, err := repo.ReserveStock(ctx, "order-1", []models.ReservationItem{{ProductID: "bad-id", Quantity: 2}}, expiresAt)
+		require.ErrorContains(mt, err, "invalid product ID")
+		require.Empty(mt, commandsSent(mt))
+	})
+}
+
+func TestCommitStock_Paths(t *testing.T) {
+	ctx := context.Background()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("deducts every hold and records the order", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, operations: mt.Coll, movements: mt.Coll, transact: inline}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		p1, p2 := primitive.NewObjectID(), primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			mtest.CreateCursorResponse(1, ns, mtest.FirstBatch,
+				bson.D{
+					{Key: "_id", Value: p1},
+					{Key: "holds", Value: bson.A{holdDoc("order-1", 2, models.HoldActive), holdDoc("order-2", 1, models.HoldActive)}},
+				},
+				bson.D{
+					{Key: "_id", Value: p2},
+					{Key: "holds", Value: bson.A{holdDoc("order-1", 1, models.HoldActive)}},
+				},
+			),
+			mtest.CreateCursorResponse(0, ns, mtest.NextBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{{Key: "quantity", Value: 5}}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{{Key: "quantity", Value: 7}}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+
+		require.NoError(mt, repo.CommitStock(models.WithActor(ctx, "process-order-service"), "order-1"))
+
+		var inserts []bson.Raw
+		for _, evt := range mt.GetAllStartedEvents() {
+			switch evt.CommandName {
+			case "findAndModify":
+				require.Equal(mt, "order-1", evt.Command.Lookup("update", "$pull", "holds", "order_id").StringValue())
+			case "insert":
+				inserts = append(inserts, evt.Command.Lookup("documents").Array().Index(0).Value().Document())
+			}
+		}
+		require.Len(mt, inserts, 3)
+		deduction := inserts[0]
+		require.Equal(mt, models.MovementOrderDeduction, deduction.Lookup("type").StringValue())
+		require.Equal(mt, int32(-2), deduction.Lookup("quantity_change").Int32())
+		require.Equal(mt, int32(5), deduction.Lookup("quantity").Int32())
+		require.Equal(mt, "order-1", deduction.Lookup("order_id").StringValue())
+		require.Equal(mt, "process-order-service", deduction.Lookup("actor").StringValue())
+
+		operation := inserts[2]
+		require.Equal(mt, "order-1", operation.Lookup("_id").StringValue())
+		lines, err := operation.Lookup("items").Array().Values()
+		require.NoError(mt, err)
+		require.Len(mt, lines, 2)
+		require.Equal(mt, p2.Hex(), lines[1].Document().Lookup("product_id").StringValue())
+		require.Equal(mt, int32(7), lines[1].Document().Lookup("quantity").Int32())
+	})
+
+	mt.Run("an order deducted already is a no-op", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, operations: mt.Coll, movements: mt.Coll, transact: inline}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: int32(1)}}))
+
+		require.NoError(mt, repo.CommitStock(ctx, "order-1"))
+		require.Equal(mt, []string{"aggregate"}, commandsSent(mt))
+	})
+
+	mt.Run("hold gone since it was read", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, operations: mt.Coll, movements: mt.Coll, transact: inline}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			mtest.CreateCursorResponse(1, ns, mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "holds", Value: bson.A{holdDoc("order-1", 2, models.HoldActive)}},
+			}),
+			mtest.CreateCursorResponse(0, ns, mtest.NextBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}),
+		)
+
+		require.EqualError(mt, repo.CommitStock(ctx, "order-1"), "reservation not found")
+	})
+
+	mt.Run("unknown order", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll, operations: mt.Coll, transact: inline}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+		)
+
+		require.EqualError(mt, repo.CommitStock(ctx, "order-1"), "reservation not found")
+	})
+}
+
+func TestReleaseStockAndExpireHolds(t *testing.T) {
+	ctx := context.Background()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("release clears stock caches", func(mt *mtest.T) {
+		mr, err := miniredis.Run()
+		require.NoError(mt, err)
+		defer mr.Close()
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+		id := primitive.NewObjectID()
+		_ = rdb.Set(ctx, "product:"+id.Hex(), "value", 0)
+
+		repo := &productRepository{collection: mt.Coll, redis: rdb}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: id}}),
+			mtest.CreateCursorResponse(0, ns, mtest.NextBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		require.NoError(mt, repo.ReleaseStock(ctx, "order-1"))
+		require.False(mt, mr.Exists("product:"+id.Hex()))
+	})
+
+	mt.Run("release without holds", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch))
+
+		require.NoError(mt, repo.ReleaseStock(ctx, "order-1"))
+	})
+
+	mt.Run("expire reports changed products", func(mt *mtest.T) {
+		repo := &productRepository{collection: mt.Coll}
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, ns, mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: primitive.NewObjectID()}},
+				bson.D{{Key: "_id", Value: primitive.NewObjectID()}},
+			),
+			mtest.CreateCursorResponse(0, ns, mtest.NextBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}, bson.E{Key: "nModified", Value: 2}),
+		)
+
+		changed, err := repo.ExpireHolds(ctx, time.Now())
+		require.NoError(mt, err)
+		require.Equal(mt, int64(2), changed)
+	})
+}
@@ -45,7 +45,7 @@ func TestToProductResponse(t *testing.T) {
 
 func TestUpdateProductQuantity_InvalidID_NoDB(t *testing.T) {
 	repo := &productRepository{}
-	err := repo.UpdateProductQuantity(context.Background(), "bad-id", 1)
+	err := repo.UpdateProductQuantity(context.Background(), "bad-id", models.InventoryAdjustment{QuantityChange: 1})
 	if err == nil {
 		t.Fatal("expected error for invalid id")
 	}
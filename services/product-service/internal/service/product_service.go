@@ -1,8 +1,12 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/icl00ud/velure/services/product-service/internal/metrics"
@@ -22,10 +26,59 @@ type ProductService interface {
 	CreateProduct(ctx context.Context, product models.CreateProductRequest) (*models.ProductResponse, error)
 	DeleteProductsByName(ctx context.Context, name string) error
 	DeleteProductById(ctx context.Context, id string) error
-	UpdateProductQuantity(ctx context.Context, productID string, quantityChange int) error
+	UpdateProduct(ctx context.Context, id string, version int64, req models.UpdateProductRequest) (*models.ProductResponse, error)
+	PatchProduct(ctx context.Context, id string, version int64, patch map[string]any) (*models.ProductResponse, error)
+	UpdateProductQuantity(ctx context.Context, productID string, change models.InventoryAdjustment) error
+	ReserveStock(ctx context.Context, req models.CreateReservationRequest) (*models.ReservationResponse, error)
+	CommitReservation(ctx context.Context, orderID string) error
+	ReleaseReservation(ctx context.Context, orderID string) error
+	ExpireReservations(ctx context.Context) (int64, error)
+	ApplyInventoryBatch(ctx context.Context, req models.InventoryBatchRequest) (*models.InventoryBatchResponse, error)
+	GetInventoryHistory(ctx context.Context, productID string, page, pageSize int) (*models.StockMovementsPage, error)
+	ReconcileInventory(ctx context.Context, baseline bool) (*models.ReconciliationReport, error)
 	SyncProductCatalogMetric(ctx context.Context)
 }
 
+// ErrInvalidProduct wraps the reason a product update was rejected.
+var ErrInvalidProduct = errors.New("invalid product")
+
+// readOnlyProductFields are product fields an update cannot touch: stock goes
+// through the inventory endpoint and the rest is managed by the service.
+var readOnlyProductFields = map[string]bool{
+	"_id":        true,
+	"id":         true,
+	"quantity":   true,
+	"rating":     true,
+	"version":    true,
+	"dt_created": true,
+	"dt_updated": true,
+}
+
+const (
+	// DefaultReservationTTL applies when a reservation does not ask for one.
+	DefaultReservationTTL = 15 * time.Minute
+	// MaxReservationTTL caps how long stock can be held for an order.
+	MaxReservationTTL = time.Hour
+)
+
+var (
+	// ErrInvalidReservation wraps the reason a reservation was rejected.
+	ErrInvalidReservation = errors.New("invalid reservation")
+	// ErrInsufficientStock means a product has fewer units available than
+	// the reservation asked for.
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrInvalidInventoryBatch wraps the reason an inventory batch was
+	// rejected before touching stock.
+	ErrInvalidInventoryBatch = errors.New("invalid inventory batch")
+	// ErrInvalidInventoryUpdate wraps the reason a single-product stock
+	// change was rejected before touching stock.
+	ErrInvalidInventoryUpdate = errors.New("invalid inventory update")
+)
+
+// MaxInventoryBatchLines caps the lines of one inventory batch, which all
+// run in a single transaction.
+const MaxInventoryBatchLines = 100
+
 type productService struct {
 	repo repository.ProductRepository
 }
@@ -204,7 +257,147 @@ func (s *productService) DeleteProductById(ctx context.Context, id string) error
 	return nil
 }
 
-func (s *productService) UpdateProductQuantity(ctx context.Context, productID string, quantityChange int) error {
+// UpdateProduct replaces the editable fields of a product at the given version.
+func (s *productService) UpdateProduct(ctx context.Context, id string, version int64, req models.UpdateProductRequest) (*models.ProductResponse, error) {
+	start := time.Now()
+	defer func() {
+		metrics.ProductOperationDuration.WithLabelValues("update").Observe(time.Since(start).Seconds())
+	}()
+
+	if err := validateProduct(req); err != nil {
+		metrics.ProductMutations.WithLabelValues("update", "failure").Inc()
+		metrics.Errors.WithLabelValues("validation").Inc()
+		return nil, err
+	}
+	return s.writeProduct(ctx, "update", id, version, req)
+}
+
+// PatchProduct applies a JSON Merge Patch (RFC 7396) to the editable fields of
+// a product at the given version.
+func (s *productService) PatchProduct(ctx context.Context, id string, version int64, patch map[string]any) (*models.ProductResponse, error) {
+	start := time.Now()
+	defer func() {
+		metrics.ProductOperationDuration.WithLabelValues("patch").Observe(time.Since(start).Seconds())
+	}()
+
+	req, current, err := s.applyPatch(ctx, id, version, patch)
+	if err != nil {
+		metrics.ProductMutations.WithLabelValues("patch", "failure").Inc()
+		if errors.Is(err, ErrInvalidProduct) {
+			metrics.Errors.WithLabelValues("validation").Inc()
+		} else if err.Error() != "product not found" && err.Error() != "product version mismatch" {
+			metrics.Errors.WithLabelValues("database").Inc()
+		}
+		return nil, err
+	}
+	// Write against the version the patch was applied to, so a concurrent
+	// update in between is detected even when the caller sent If-Match: *.
+	return s.writeProduct(ctx, "patch", id, current, *req)
+}
+
+func (s *productService) applyPatch(ctx context.Context, id string, version int64, patch map[string]any) (*models.UpdateProductRequest, int64, error) {
+	for key := range patch {
+		if readOnlyProductFields[key] {
+			return nil, 0, fmt.Errorf("%w: %s cannot be changed", ErrInvalidProduct, key)
+		}
+	}
+
+	product, err := s.repo.LoadProduct(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if version != models.AnyVersion && version != product.Version {
+		return nil, 0, fmt.Errorf("product version mismatch")
+	}
+
+	current, err := json.Marshal(models.UpdateProductRequest{
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       product.Price,
+		Category:    product.Category,
+		Images:      product.Images,
+		Dimensions:  product.Dimensions,
+		Brand:       product.Brand,
+		Colors:      product.Colors,
+		SKU:         product.SKU,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(current, &doc); err != nil {
+		return nil, 0, err
+	}
+	merged, err := json.Marshal(mergePatch(doc, patch))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var req models.UpdateProductRequest
+	dec := json.NewDecoder(bytes.NewReader(merged))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidProduct, err)
+	}
+	if err := validateProduct(req); err != nil {
+		return nil, 0, err
+	}
+	return &req, product.Version, nil
+}
+
+func (s *productService) writeProduct(ctx context.Context, op, id string, version int64, req models.UpdateProductRequest) (*models.ProductResponse, error) {
+	result, err := s.repo.UpdateProduct(ctx, id, version, req)
+	if err != nil {
+		metrics.ProductMutations.WithLabelValues(op, "failure").Inc()
+		if err.Error() != "product not found" && err.Error() != "product version mismatch" {
+			metrics.Errors.WithLabelValues("database").Inc()
+		}
+		return nil, err
+	}
+
+	metrics.ProductMutations.WithLabelValues(op, "success").Inc()
+	return result, nil
+}
+
+// mergePatch applies patch to target as RFC 7396 describes: null removes a
+// member, objects merge recursively and anything else replaces the value.
+func mergePatch(target, patch map[string]any) map[string]any {
+	for key, value := range patch {
+		switch v := value.(type) {
+		case nil:
+			delete(target, key)
+		case map[string]any:
+			existing, _ := target[key].(map[string]any)
+			if existing == nil {
+				existing = map[string]any{}
+			}
+			target[key] = mergePatch(existing, v)
+		default:
+			target[key] = v
+		}
+	}
+	return target
+}
+
+func validateProduct(req models.UpdateProductRequest) error {
+	if strings.TrimSpace(req.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidProduct)
+	}
+	if req.Price <= 0 {
+		return fmt.Errorf("%w: price must be greater than 0", ErrInvalidProduct)
+	}
+	d := req.Dimensions
+	if d.Height < 0 || d.Width < 0 || d.Length < 0 || d.Weight < 0 {
+		return fmt.Errorf("%w: dimensions cannot be negative", ErrInvalidProduct)
+	}
+	return nil
+}
+
+// UpdateProductQuantity applies a stock change to one product and records it
+// in the ledger. Without a type, an increase is a restock and a decrease an
+// adjustment, or, when an order ID is given, a compensation and an order
+// deduction.
+func (s *productService) UpdateProductQuantity(ctx context.Context, productID string, change models.InventoryAdjustment) error {
 	start := time.Now()
 	var status string
 	defer func() {
@@ -212,8 +405,15 @@ func (s *productService) UpdateProductQuantity(ctx context.Context, productID st
 		metrics.InventoryUpdates.WithLabelValues(status).Inc()
 	}()
 
+	change, err := resolveInventoryAdjustment(change)
+	if err != nil {
+		status = "failure"
+		metrics.Errors.WithLabelValues("validation").Inc()
+		return err
+	}
+
 	// Attempt atomic update directly
-	err := s.repo.UpdateProductQuantity(ctx, productID, quantityChange)
+	err = s.repo.UpdateProductQuantity(ctx, productID, change)
 	if err != nil {
 		if err.Error() == "insufficient stock or product not found" {
 			// Fetch current quantity to provide a detailed error message
@@ -227,7 +427,7 @@ func (s *productService) UpdateProductQuantity(ctx context.Context, productID st
 
 			status = "insufficient_stock"
 			metrics.Errors.WithLabelValues("validation").Inc()
-			return fmt.Errorf("insufficient stock: current quantity is %d, cannot deduct %d", currentQuantity, -quantityChange)
+			return fmt.Errorf("insufficient stock: current quantity is %d, cannot deduct %d", currentQuantity, -change.QuantityChange)
 		}
 
 		status = "failure"
@@ -239,6 +439,291 @@ func (s *productService) UpdateProductQuantity(ctx context.Context, productID st
 	return nil
 }
 
+func resolveInventoryAdjustment(change models.InventoryAdjustment) (models.InventoryAdjustment, error) {
+	change.OrderID = strings.TrimSpace(change.OrderID)
+	change.Reason = strings.TrimSpace(change.Reason)
+	if change.Type == "" {
+		switch {
+		case change.OrderID != "" && change.QuantityChange < 0:
+			change.Type = models.MovementOrderDeduction
+		case change.OrderID != "":
+			change.Type = models.MovementCompensation
+		case change.QuantityChange > 0:
+			change.Type = models.MovementRestock
+		default:
+			change.Type = models.MovementAdjustment
+		}
+	}
+
+	switch {
+	case !models.IsManualMovement(change.Type):
+		return change, fmt.Errorf("%w: unknown type %q", ErrInvalidInventoryUpdate, change.Type)
+	case change.Type == models.MovementRestock && change.QuantityChange <= 0,
+		change.Type == models.MovementCompensation && change.QuantityChange <= 0:
+		return change, fmt.Errorf("%w: a %s must add stock", ErrInvalidInventoryUpdate, change.Type)
+	case change.Type == models.MovementOrderDeduction && change.QuantityChange >= 0:
+		return change, fmt.Errorf("%w: an order deduction must remove stock", ErrInvalidInventoryUpdate)
+	case (change.Type == models.MovementOrderDeduction || change.Type == models.MovementCompensation) && change.OrderID == "":
+		return change, fmt.Errorf("%w: order_id is required for a %s", ErrInvalidInventoryUpdate, change.Type)
+	}
+	return change, nil
+}
+
+// ReserveStock holds the items of an order, all of them or none. An order
+// whose stock was deducted already is answered as committed.
+func (s *productService) ReserveStock(ctx context.Context, req models.CreateReservationRequest) (*models.ReservationResponse, error) {
+	start := time.Now()
+	status := "failure"
+	defer func() {
+		metrics.ProductOperationDuration.WithLabelValues("reserve").Observe(time.Since(start).Seconds())
+		metrics.Reservations.WithLabelValues("reserve", status).Inc()
+	}()
+
+	ttl, err := validateReservation(req)
+	if err != nil {
+		metrics.Errors.WithLabelValues("validation").Inc()
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	committed, err := s.repo.ReserveStock(ctx, req.OrderID, req.Items, expiresAt)
+	if err != nil {
+		var reservationErr *repository.ReservationError
+		switch {
+		case errors.As(err, &reservationErr) && reservationErr.Reason == "insufficient stock":
+			status = "insufficient_stock"
+			return nil, fmt.Errorf("%w for product %s", ErrInsufficientStock, reservationErr.ProductID)
+		case err.Error() == "product not found":
+			metrics.Errors.WithLabelValues("not_found").Inc()
+		case strings.HasPrefix(err.Error(), "invalid product ID"):
+			metrics.Errors.WithLabelValues("validation").Inc()
+		default:
+			metrics.Errors.WithLabelValues("database").Inc()
+		}
+		return nil, err
+	}
+
+	if committed {
+		status = "committed"
+		return &models.ReservationResponse{OrderID: req.OrderID, Items: req.Items, Committed: true}, nil
+	}
+	status = "success"
+	return &models.ReservationResponse{
+		OrderID:   req.OrderID,
+		Items:     req.Items,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// CommitReservation deducts the stock held for an order. Committing again is
+// a no-op.
+func (s *productService) CommitReservation(ctx context.Context, orderID string) error {
+	status := "failure"
+	defer func() {
+		metrics.Reservations.WithLabelValues("commit", status).Inc()
+	}()
+
+	if strings.TrimSpace(orderID) == "" {
+		return fmt.Errorf("%w: order_id is required", ErrInvalidReservation)
+	}
+	if err := s.repo.CommitStock(ctx, orderID); err != nil {
+		if err.Error() != "reservation not found" {
+			metrics.Errors.WithLabelValues("database").Inc()
+		}
+		return err
+	}
+
+	status = "success"
+	return nil
+}
+
+// ReleaseReservation returns the stock held for an order that will not go
+// ahead. Stock already committed stays deducted.
+func (s *productService) ReleaseReservation(ctx context.Context, orderID string) error {
+	status := "failure"
+	defer func() {
+		metrics.Reservations.WithLabelValues("release", status).Inc()
+	}()
+
+	if strings.TrimSpace(orderID) == "" {
+		return fmt.Errorf("%w: order_id is required", ErrInvalidReservation)
+	}
+	if err := s.repo.ReleaseStock(ctx, orderID); err != nil {
+		metrics.Errors.WithLabelValues("database").Inc()
+		return err
+	}
+
+	status = "success"
+	return nil
+}
+
+// ExpireReservations drops expired holds, returning abandoned stock, and
+// reports how many products it changed.
+func (s *productService) ExpireReservations(ctx context.Context) (int64, error) {
+	changed, err := s.repo.ExpireHolds(ctx, time.Now())
+	if err != nil {
+		metrics.Reservations.WithLabelValues("expire", "failure").Inc()
+		metrics.Errors.WithLabelValues("database").Inc()
+		return 0, err
+	}
+
+	metrics.Reservations.WithLabelValues("expire", "success").Inc()
+	return changed, nil
+}
+
+// ApplyInventoryBatch applies the stock changes of an order all together or
+// not at all. A rejected batch is not an error: the response says which
+// lines failed. Sending an applied batch again returns its recorded outcome.
+func (s *productService) ApplyInventoryBatch(ctx context.Context, req models.InventoryBatchRequest) (*models.InventoryBatchResponse, error) {
+	start := time.Now()
+	result := "failure"
+	defer func() {
+		metrics.ProductOperationDuration.WithLabelValues("inventory_batch").Observe(time.Since(start).Seconds())
+		metrics.InventoryBatches.WithLabelValues(result).Inc()
+	}()
+
+	if err := validateInventoryBatch(req); err != nil {
+		metrics.Errors.WithLabelValues("validation").Inc()
+		return nil, err
+	}
+
+	resp, err := s.repo.ApplyInventoryBatch(ctx, req.OrderID, req.Items)
+	if err != nil {
+		if err.Error() == "inventory batch mismatch" {
+			metrics.Errors.WithLabelValues("validation").Inc()
+			return nil, fmt.Errorf("%w: order %s was applied with different items", ErrInvalidInventoryBatch, req.OrderID)
+		}
+		metrics.Errors.WithLabelValues("database").Inc()
+		return nil, err
+	}
+
+	switch {
+	case resp.Replayed:
+		result = "replayed"
+	case resp.Applied:
+		result = "applied"
+	default:
+		result = "rejected"
+		for _, line := range resp.Items {
+			if line.Status == models.InventoryLineInsufficientStock || line.Status == models.InventoryLineNotFound {
+				resp.Error = fmt.Sprintf("%s for product %s", strings.ReplaceAll(line.Status, "_", " "), line.ProductID)
+				break
+			}
+		}
+	}
+	return resp, nil
+}
+
+// GetInventoryHistory returns a page of a product's stock movements, newest
+// first.
+func (s *productService) GetInventoryHistory(ctx context.Context, productID string, page, pageSize int) (*models.StockMovementsPage, error) {
+	start := time.Now()
+	defer func() {
+		metrics.ProductOperationDuration.WithLabelValues("inventory_history").Observe(time.Since(start).Seconds())
+	}()
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+
+	history, err := s.repo.GetStockMovements(ctx, productID, page, pageSize)
+	if err != nil {
+		if err.Error() == "product not found" {
+			metrics.Errors.WithLabelValues("not_found").Inc()
+		} else if !strings.HasPrefix(err.Error(), "invalid product ID") {
+			metrics.Errors.WithLabelValues("database").Inc()
+		}
+		return nil, err
+	}
+	return history, nil
+}
+
+// ReconcileInventory compares every product's quantity with the sum of its
+// stock movements. With baseline, products that have no movements at all get
+// an opening entry for their quantity instead of being reported.
+func (s *productService) ReconcileInventory(ctx context.Context, baseline bool) (*models.ReconciliationReport, error) {
+	checked, drift, err := s.repo.InventoryDrift(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ReconciliationReport{Products: checked, Drift: []models.InventoryDrift{}}
+	for _, product := range drift {
+		if baseline && product.Movements == 0 {
+			recorded, err := s.repo.RecordOpeningStock(ctx, product.ProductID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to baseline product %s: %w", product.ProductID, err)
+			}
+			if recorded {
+				report.Baselined++
+				continue
+			}
+		}
+		report.Drift = append(report.Drift, product)
+	}
+	return report, nil
+}
+
+func validateInventoryBatch(req models.InventoryBatchRequest) error {
+	if strings.TrimSpace(req.OrderID) == "" {
+		return fmt.Errorf("%w: order_id is required", ErrInvalidInventoryBatch)
+	}
+	if len(req.Items) == 0 {
+		return fmt.Errorf("%w: items are required", ErrInvalidInventoryBatch)
+	}
+	if len(req.Items) > MaxInventoryBatchLines {
+		return fmt.Errorf("%w: at most %d items per batch", ErrInvalidInventoryBatch, MaxInventoryBatchLines)
+	}
+	seen := make(map[string]bool, len(req.Items))
+	for _, item := range req.Items {
+		if item.ProductID == "" {
+			return fmt.Errorf("%w: product_id is required", ErrInvalidInventoryBatch)
+		}
+		if item.QuantityChange == 0 {
+			return fmt.Errorf("%w: quantity_change cannot be 0", ErrInvalidInventoryBatch)
+		}
+		if seen[item.ProductID] {
+			return fmt.Errorf("%w: product %s is listed twice", ErrInvalidInventoryBatch, item.ProductID)
+		}
+		seen[item.ProductID] = true
+	}
+	return nil
+}
+
+func validateReservation(req models.CreateReservationRequest) (time.Duration, error) {
+	if strings.TrimSpace(req.OrderID) == "" {
+		return 0, fmt.Errorf("%w: order_id is required", ErrInvalidReservation)
+	}
+	if len(req.Items) == 0 {
+		return 0, fmt.Errorf("%w: items are required", ErrInvalidReservation)
+	}
+	seen := make(map[string]bool, len(req.Items))
+	for _, item := range req.Items {
+		if item.ProductID == "" {
+			return 0, fmt.Errorf("%w: product_id is required", ErrInvalidReservation)
+		}
+		if item.Quantity <= 0 {
+			return 0, fmt.Errorf("%w: quantity must be greater than 0", ErrInvalidReservation)
+		}
+		if seen[item.ProductID] {
+			return 0, fmt.Errorf("%w: product %s is listed twice", ErrInvalidReservation, item.ProductID)
+		}
+		seen[item.ProductID] = true
+	}
+
+	maxSeconds := int(MaxReservationTTL.Seconds())
+	switch {
+	case req.TTLSeconds == 0:
+		return DefaultReservationTTL, nil
+	case req.TTLSeconds < 0 || req.TTLSeconds > maxSeconds:
+		return 0, fmt.Errorf("%w: ttl_seconds must be between 1 and %d", ErrInvalidReservation, maxSeconds)
+	}
+	return time.Duration(req.TTLSeconds) * time.Second, nil
+}
+
 func (s *productService) SyncProductCatalogMetric(ctx context.Context) {
 	if ctx == nil {
 		ctx = context.Background()
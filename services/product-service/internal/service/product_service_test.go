@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/icl00ud/velure/services/product-service/internal/model"
+	"github.com/icl00ud/velure/services/product-service/internal/repository"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -92,8 +94,24 @@ func (m *MockProductRepository) DeleteProductById(ctx context.Context, id string
 	return args.Error(0)
 }
 
-func (m *MockProductRepository) UpdateProductQuantity(ctx context.Context, productID string, quantityChange int) error {
-	args := m.Called(ctx, productID, quantityChange)
+func (m *MockProductRepository) LoadProduct(ctx context.Context, id string) (*models.Product, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockProductRepository) UpdateProduct(ctx context.Context, id string, version int64, req models.UpdateProductRequest) (*models.ProductResponse, error) {
+	args := m.Called(ctx, id, version, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ProductResponse), args.Error(1)
+}
+
+func (m *MockProductRepository) UpdateProductQuantity(ctx context.Context, productID string, change models.InventoryAdjustment) error {
+	args := m.Called(ctx, productID, change)
 	return args.Error(0)
 }
 
@@ -102,6 +120,53 @@ func (m *MockProductRepository) GetProductQuantity(ctx context.Context, productI
 	return args.Get(0).(int), args.Error(1)
 }
 
+func (m *MockProductRepository) ReserveStock(ctx context.Context, orderID string, items []models.ReservationItem, expiresAt time.Time) (bool, error) {
+	args := m.Called(ctx, orderID, items, expiresAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockProductRepository) CommitStock(ctx context.Context, orderID string) error {
+	args := m.Called(ctx, orderID)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) ReleaseStock(ctx context.Context, orderID string) error {
+	args := m.Called(ctx, orderID)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) ExpireHolds(ctx context.Context, now time.Time) (int64, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockProductRepository) ApplyInventoryBatch(ctx context.Context, orderID string, changes []models.InventoryChange) (*models.InventoryBatchResponse, error) {
+	args := m.Called(ctx, orderID, changes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.InventoryBatchResponse), args.Error(1)
+}
+
+func (m *MockProductRepository) GetStockMovements(ctx context.Context, productID string, page, pageSize int) (*models.StockMovementsPage, error) {
+	args := m.Called(ctx, productID, page, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.StockMovementsPage), args.Error(1)
+}
+
+func (m *MockProductRepository) InventoryDrift(ctx context.Context) (int, []models.InventoryDrift, error) {
+	args := m.Called(ctx)
+	drift, _ := args.Get(1).([]models.InventoryDrift)
+	return args.Int(0), drift, args.Error(2)
+}
+
+func (m *MockProductRepository) RecordOpeningStock(ctx context.Context, productID string) (bool, error) {
+	args := m.Called(ctx, productID)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockProductRepository) WarmupCache(ctx context.Context) error {
 	args := m.Called(ctx)
 	return args.Error(0)
@@ -589,7 +654,8 @@ func TestUpdateProductQuantity(t *testing.T) {
 			mockRepo := new(MockProductRepository)
 
 			// Expect UpdateProductQuantity to be called first
-			mockRepo.On("UpdateProductQuantity", mock.Anything, tt.productID, tt.quantityChange).Return(tt.updateErr)
+			change := mock.MatchedBy(func(c models.InventoryAdjustment) bool { return c.QuantityChange == tt.quantityChange })
+			mockRepo.On("UpdateProductQuantity", mock.Anything, tt.productID, change).Return(tt.updateErr)
 
 			// If update fails with specific error, we expect GetProductQuantity
 			if tt.updateErr != nil && tt.updateErr.Error() == "insufficient stock or product not found" {
@@ -597,7 +663,7 @@ func TestUpdateProductQuantity(t *testing.T) {
 			}
 
 			service := NewProductService(mockRepo)
-			err := service.UpdateProductQuantity(context.Background(), tt.productID, tt.quantityChange)
+			err := service.UpdateProductQuantity(context.Background(), tt.productID, models.InventoryAdjustment{QuantityChange: tt.quantityChange})
 
 			if tt.wantError {
 				assert.Error(t, err)
@@ -612,6 +678,380 @@ func TestUpdateProductQuantity(t *testing.T) {
 	}
 }
 
+func TestUpdateProductQuantity_MovementType(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   models.InventoryAdjustment
+		wantType string
+		wantErr  string
+	}{
+		{name: "increase is a restock", change: models.InventoryAdjustment{QuantityChange: 5}, wantType: models.MovementRestock},
+		{name: "decrease is an adjustment", change: models.InventoryAdjustment{QuantityChange: -1}, wantType: models.MovementAdjustment},
+		{name: "decrease for an order", change: models.InventoryAdjustment{QuantityChange: -1, OrderID: "order-1"}, wantType: models.MovementOrderDeduction},
+		{name: "increase for an order", change: models.InventoryAdjustment{QuantityChange: 1, OrderID: " order-1 "}, wantType: models.MovementCompensation},
+		{name: "explicit adjustment up", change: models.InventoryAdjustment{QuantityChange: 3, Type: models.MovementAdjustment}, wantType: models.MovementAdjustment},
+		{name: "unknown type", change: models.InventoryAdjustment{QuantityChange: 3, Type: "gift"}, wantErr: `unknown type "gift"`},
+		{name: "opening is not manual", change: models.InventoryAdjustment{QuantityChange: 3, Type: models.MovementOpening}, wantErr: "unknown type"},
+		{name: "restock must add", change: models.InventoryAdjustment{QuantityChange: -3, Type: models.MovementRestock}, wantErr: "a restock must add stock"},
+		{name: "deduction must remove", change: models.InventoryAdjustment{QuantityChange: 3, Type: models.MovementOrderDeduction, OrderID: "order-1"}, wantErr: "must remove stock"},
+		{name: "compensation needs an order", change: models.InventoryAdjustment{QuantityChange: 3, Type: models.MovementCompensation}, wantErr: "order_id is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockProductRepository)
+			if tt.wantErr == "" {
+				mockRepo.On("UpdateProductQuantity", mock.Anything, "123", mock.MatchedBy(func(c models.InventoryAdjustment) bool {
+					return c.Type == tt.wantType && (c.OrderID == "" || c.OrderID == "order-1")
+				})).Return(nil)
+			}
+
+			err := NewProductService(mockRepo).UpdateProductQuantity(context.Background(), "123", tt.change)
+
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, ErrInvalidInventoryUpdate)
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetInventoryHistory(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	page := &models.StockMovementsPage{Page: 1, PageSize: 20}
+	mockRepo.On("GetStockMovements", mock.Anything, "123", 1, 20).Return(page, nil)
+	mockRepo.On("GetStockMovements", mock.Anything, "gone", 2, 5).Return(nil, errors.New("product not found"))
+	service := NewProductService(mockRepo)
+
+	got, err := service.GetInventoryHistory(context.Background(), "123", 0, 0)
+	assert.NoError(t, err)
+	assert.Same(t, page, got)
+
+	_, err = service.GetInventoryHistory(context.Background(), "gone", 2, 5)
+	assert.EqualError(t, err, "product not found")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestReconcileInventory(t *testing.T) {
+	drift := []models.InventoryDrift{
+		{ProductID: "legacy", Quantity: 40, Drift: 40},
+		{ProductID: "raced", Quantity: 3, Drift: 3},
+		{ProductID: "tracked", Quantity: 9, LedgerQuantity: 10, Drift: -1, Movements: 4},
+	}
+
+	t.Run("reports drift", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		mockRepo.On("InventoryDrift", mock.Anything).Return(12, drift, nil)
+
+		report, err := NewProductService(mockRepo).ReconcileInventory(context.Background(), false)
+		assert.NoError(t, err)
+		assert.Equal(t, 12, report.Products)
+		assert.Equal(t, 0, report.Baselined)
+		assert.Equal(t, drift, report.Drift)
+		mockRepo.AssertNotCalled(t, "RecordOpeningStock", mock.Anything, mock.Anything)
+	})
+
+	t.Run("baselines products without movements", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		mockRepo.On("InventoryDrift", mock.Anything).Return(12, drift, nil)
+		mockRepo.On("RecordOpeningStock", mock.Anything, "legacy").Return(true, nil)
+		// Got a movement between the drift check and the baseline.
+		mockRepo.On("RecordOpeningStock", mock.Anything, "raced").Return(false, nil)
+
+		report, err := NewProductService(mockRepo).ReconcileInventory(context.Background(), true)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Baselined)
+		assert.Equal(t, drift[1:], report.Drift)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("clean ledger", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		mockRepo.On("InventoryDrift", mock.Anything).Return(3, nil, nil)
+
+		report, err := NewProductService(mockRepo).ReconcileInventory(context.Background(), true)
+		assert.NoError(t, err)
+		assert.Empty(t, report.Drift)
+		assert.NotNil(t, report.Drift)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		mockRepo.On("InventoryDrift", mock.Anything).Return(0, nil, errors.New("connection reset"))
+
+		_, err := NewProductService(mockRepo).ReconcileInventory(context.Background(), false)
+		assert.EqualError(t, err, "connection reset")
+	})
+}
+
+func TestUpdateProduct(t *testing.T) {
+	valid := models.UpdateProductRequest{Name: "Toy", Price: 10}
+
+	tests := []struct {
+		name          string
+		req           models.UpdateProductRequest
+		repoErr       error
+		wantInvalid   bool
+		errorContains string
+	}{
+		{name: "success", req: valid},
+		{name: "missing name", req: models.UpdateProductRequest{Name: "  ", Price: 10}, wantInvalid: true, errorContains: "name is required"},
+		{name: "zero price", req: models.UpdateProductRequest{Name: "Toy"}, wantInvalid: true, errorContains: "price must be greater than 0"},
+		{name: "negative dimensions", req: models.UpdateProductRequest{Name: "Toy", Price: 10, Dimensions: models.Dimensions{Weight: -1}}, wantInvalid: true, errorContains: "dimensions cannot be negative"},
+		{name: "stale version", req: valid, repoErr: errors.New("product version mismatch"), errorContains: "product version mismatch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockProductRepository)
+			if !tt.wantInvalid {
+				if tt.repoErr != nil {
+					mockRepo.On("UpdateProduct", mock.Anything, "123", int64(2), tt.req).Return(nil, tt.repoErr)
+				} else {
+					mockRepo.On("UpdateProduct", mock.Anything, "123", int64(2), tt.req).Return(&models.ProductResponse{ID: "123", Version: 3}, nil)
+				}
+			}
+
+			service := NewProductService(mockRepo)
+			result, err := service.UpdateProduct(context.Background(), "123", 2, tt.req)
+
+			if tt.errorContains != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorContains)
+				assert.Equal(t, tt.wantInvalid, errors.Is(err, ErrInvalidProduct))
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, int64(3), result.Version)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPatchProduct(t *testing.T) {
+	stored := &models.Product{
+		Name:       "Toy",
+		Price:      10,
+		Brand:      "Acme",
+		Colors:     []string{"red"},
+		Quantity:   7,
+		Dimensions: models.Dimensions{Height: 2, Width: 3},
+		Version:    4,
+	}
+
+	t.Run("merges into the stored product", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		mockRepo.On("LoadProduct", mock.Anything, "123").Return(stored, nil)
+		want := models.UpdateProductRequest{
+			Name:       "Toy",
+			Price:      12,
+			Colors:     []string{"blue", "green"},
+			Dimensions: models.Dimensions{Height: 2, Width: 5},
+		}
+		mockRepo.On("UpdateProduct", mock.Anything, "123", int64(4), want).Return(&models.ProductResponse{ID: "123", Version: 5}, nil)
+
+		patch := map[string]any{
+			"price":      12.0,
+			"brand":      nil,
+			"colors":     []any{"blue", "green"},
+			"dimensions": map[string]any{"width": 5.0},
+		}
+		result, err := NewProductService(mockRepo).PatchProduct(context.Background(), "123", 4, patch)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), result.Version)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("any version writes against the loaded one", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		mockRepo.On("LoadProduct", mock.Anything, "123").Return(stored, nil)
+		mockRepo.On("UpdateProduct", mock.Anything, "123", int64(4), mock.Anything).Return(&models.ProductResponse{ID: "123", Version: 5}, nil)
+
+		_, err := NewProductService(mockRepo).PatchProduct(context.Background(), "123", models.AnyVersion, map[string]any{"sku": "T-1"})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	tests := []struct {
+		name          string
+		version       int64
+		patch         map[string]any
+		loads         bool
+		wantInvalid   bool
+		errorContains string
+	}{
+		{name: "stale version", version: 3, patch: map[string]any{"price": 12.0}, loads: true, errorContains: "product version mismatch"},
+		{name: "read-only field", version: 4, patch: map[string]any{"quantity": 100.0}, wantInvalid: true, errorContains: "quantity cannot be changed"},
+		{name: "removing the name", version: 4, patch: map[string]any{"name": nil}, loads: true, wantInvalid: true, errorContains: "name is required"},
+		{name: "unknown field", version: 4, patch: map[string]any{"dimensions": map[string]any{"depth": 1.0}}, loads: true, wantInvalid: true, errorContains: "unknown field"},
+		{name: "wrong type", version: 4, patch: map[string]any{"price": "cheap"}, loads: true, wantInvalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockProductRepository)
+			if tt.loads {
+				mockRepo.On("LoadProduct", mock.Anything, "123").Return(stored, nil)
+			}
+
+			_, err := NewProductService(mockRepo).PatchProduct(context.Background(), "123", tt.version, tt.patch)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorContains)
+			assert.Equal(t, tt.wantInvalid, errors.Is(err, ErrInvalidProduct))
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReserveStock(t *testing.T) {
+	items := []models.ReservationItem{{ProductID: "p1", Quantity: 2}, {ProductID: "p2", Quantity: 1}}
+
+	t.Run("holds every item", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		mockRepo.On("ReserveStock", mock.Anything, "order-1", items, mock.Anything).Return(false, nil)
+
+		before := time.Now()
+		result, err := NewProductService(mockRepo).ReserveStock(context.Background(), models.CreateReservationRequest{OrderID: "order-1", Items: items, TTLSeconds: 60})
+		assert.NoError(t, err)
+		assert.Equal(t, "order-1", result.OrderID)
+		assert.False(t, result.Committed)
+		assert.WithinDuration(t, before.Add(time.Minute), result.ExpiresAt, time.Second)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("an item falls short", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		mockRepo.On("ReserveStock", mock.Anything, "order-1", items, mock.Anything).
+			Return(false, &repository.ReservationError{ProductID: "p2", Reason: "insufficient stock"})
+
+		_, err := NewProductService(mockRepo).ReserveStock(context.Background(), models.CreateReservationRequest{OrderID: "order-1", Items: items})
+		assert.ErrorIs(t, err, ErrInsufficientStock)
+		assert.Contains(t, err.Error(), "p2")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("unknown product", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		mockRepo.On("ReserveStock", mock.Anything, "order-1", items, mock.Anything).
+			Return(false, &repository.ReservationError{ProductID: "p1", Reason: "product not found"})
+
+		_, err := NewProductService(mockRepo).ReserveStock(context.Background(), models.CreateReservationRequest{OrderID: "order-1", Items: items})
+		assert.EqualError(t, err, "product not found")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("an order deducted already is committed", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		mockRepo.On("ReserveStock", mock.Anything, "order-1", items, mock.Anything).Return(true, nil)
+
+		result, err := NewProductService(mockRepo).ReserveStock(context.Background(), models.CreateReservationRequest{OrderID: "order-1", Items: items})
+		assert.NoError(t, err)
+		assert.True(t, result.Committed)
+		assert.True(t, result.ExpiresAt.IsZero())
+		mockRepo.AssertExpectations(t)
+	})
+
+	invalid := []struct {
+		name string
+		req  models.CreateReservationRequest
+	}{
+		{name: "no order", req: models.CreateReservationRequest{Items: items}},
+		{name: "no items", req: models.CreateReservationRequest{OrderID: "order-1"}},
+		{name: "zero quantity", req: models.CreateReservationRequest{OrderID: "order-1", Items: []models.ReservationItem{{ProductID: "p1"}}}},
+		{name: "duplicate product", req: models.CreateReservationRequest{OrderID: "order-1", Items: append(items, items[0])}},
+		{name: "ttl too long", req: models.CreateReservationRequest{OrderID: "order-1", Items: items, TTLSeconds: 7200}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockProductRepository)
+			_, err := NewProductService(mockRepo).ReserveStock(context.Background(), tt.req)
+			assert.ErrorIs(t, err, ErrInvalidReservation)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCommitAndReleaseReservation(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	mockRepo.On("CommitStock", mock.Anything, "order-1").Return(nil)
+	mockRepo.On("CommitStock", mock.Anything, "order-2").Return(errors.New("reservation not found"))
+	mockRepo.On("ReleaseStock", mock.Anything, "order-1").Return(nil)
+	mockRepo.On("ExpireHolds", mock.Anything, mock.Anything).Return(int64(3), nil)
+
+	service := NewProductService(mockRepo)
+	assert.NoError(t, service.CommitReservation(context.Background(), "order-1"))
+	assert.EqualError(t, service.CommitReservation(context.Background(), "order-2"), "reservation not found")
+	assert.ErrorIs(t, service.CommitReservation(context.Background(), " "), ErrInvalidReservation)
+	assert.NoError(t, service.ReleaseReservation(context.Background(), "order-1"))
+
+	changed, err := service.ExpireReservations(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), changed)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApplyInventoryBatch(t *testing.T) {
+	items := []models.InventoryChange{{ProductID: "p1", QuantityChange: -2}, {ProductID: "p2", QuantityChange: 1}}
+	req := models.InventoryBatchRequest{OrderID: "order-1", Items: items}
+
+	t.Run("applied", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		mockRepo.On("ApplyInventoryBatch", mock.Anything, "order-1", items).Return(&models.InventoryBatchResponse{OrderID: "order-1", Applied: true}, nil)
+
+		resp, err := NewProductService(mockRepo).ApplyInventoryBatch(context.Background(), req)
+		assert.NoError(t, err)
+		assert.True(t, resp.Applied)
+		assert.Empty(t, resp.Error)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("rejected names the failing line", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		mockRepo.On("ApplyInventoryBatch", mock.Anything, "order-1", items).Return(&models.InventoryBatchResponse{
+			OrderID: "order-1",
+			Items: []models.InventoryLineResult{
+				{ProductID: "p1", QuantityChange: -2, Status: models.InventoryLineInsufficientStock},
+				{ProductID: "p2", QuantityChange: 1, Status: models.InventoryLineNotApplied},
+			},
+		}, nil)
+
+		resp, err := NewProductService(mockRepo).ApplyInventoryBatch(context.Background(), req)
+		assert.NoError(t, err)
+		assert.False(t, resp.Applied)
+		assert.Equal(t, "insufficient stock for product p1", resp.Error)
+	})
+
+	t.Run("order applied with other items", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		mockRepo.On("ApplyInventoryBatch", mock.Anything, "order-1", items).Return(nil, errors.New("inventory batch mismatch"))
+
+		_, err := NewProductService(mockRepo).ApplyInventoryBatch(context.Background(), req)
+		assert.ErrorIs(t, err, ErrInvalidInventoryBatch)
+	})
+
+	invalid := []struct {
+		name string
+		req  models.InventoryBatchRequest
+	}{
+		{name: "no order", req: models.InventoryBatchRequest{Items: items}},
+		{name: "no items", req: models.InventoryBatchRequest{OrderID: "order-1"}},
+		{name: "zero change", req: models.InventoryBatchRequest{OrderID: "order-1", Items: []models.InventoryChange{{ProductID: "p1"}}}},
+		{name: "duplicate product", req: models.InventoryBatchRequest{OrderID: "order-1", Items: append(items, items[0])}},
+		{name: "too many items", req: models.InventoryBatchRequest{OrderID: "order-1", Items: make([]models.InventoryChange, MaxInventoryBatchLines+1)}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockProductRepository)
+			_, err := NewProductService(mockRepo).ApplyInventoryBatch(context.Background(), tt.req)
+			assert.ErrorIs(t, err, ErrInvalidInventoryBatch)
+			mockRepo.AssertNotCalled(t, "ApplyInventoryBatch", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
 func TestSyncProductCatalogMetric_Success(t *testing.T) {
 	mockRepo := new(MockProductRepository)
 	mockRepo.On("GetProductsCount", mock.Anything).Return(int64(10), nil)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/icl00ud/velure/services/product-service/internal/config"
 	"github.com/icl00ud/velure/services/product-service/internal/handler"
@@ -15,6 +16,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/icl00ud/velure/shared/auth"
 	"github.com/icl00ud/velure/shared/logger"
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus"
@@ -64,6 +66,13 @@ func main() {
 		UseColor:    os.Getenv("LOG_COLOR") != "false",
 	})
 
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		if err := runReconcile(defaultDeps, os.Args[2:], os.Stdout); err != nil {
+			fatalf("Inventory reconciliation failed:", err)
+		}
+		return
+	}
+
 	if err := run(defaultDeps); err != nil {
 		fatalf("Failed to start server:", err)
 	}
@@ -86,6 +95,13 @@ func run(deps appDependencies) error {
 		logger.String("mongodb_uri", maskURI(cfg.MongoURI)),
 		logger.String("redis_addr", cfg.RedisAddr))
 
+	var keys auth.KeySet
+	if cfg.JWKSURL != "" {
+		keys = auth.NewRemoteKeySet(cfg.JWKSURL, auth.DefaultJWKSCacheTTL)
+	} else {
+		log.Warn("AUTH_JWKS_URL is not set; catalog and inventory mutations will be rejected")
+	}
+
 	repo, mongoDisconnect, redisClose, err := deps.buildRepo(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to initialize repositories: %w", err)
@@ -106,7 +122,15 @@ func run(deps appDependencies) error {
 	service := deps.newSvc(repo)
 	service.SyncProductCatalogMetric(context.Background())
 
-	app := setupFiberApp(service)
+	sweepCtx, stopSweep := context.WithCancel(context.Background())
+	defer stopSweep()
+	go sweepReservations(sweepCtx, service, cfg.ReservationSweepInterval)
+
+	// Its own client: the repository's is not exposed.
+	idempotencyRedis := config.NewRedis(cfg.RedisAddr, cfg.RedisPassword)
+	defer idempotencyRedis.Close()
+
+	app := setupFiberApp(service, keys, middleware.Idempotency(idempotencyRedis, cfg.IdempotencyTTL))
 
 	port := cfg.Port
 	if port == "" {
@@ -117,7 +141,12 @@ func run(deps appDependencies) error {
 	return deps.listen(app, ":"+port)
 }
 
-func setupFiberApp(service services.ProductService) *fiber.App {
+// setupFiberApp builds the app. idempotent guards the create and inventory
+// endpoints against retries; nil leaves Idempotency-Key headers unhandled.
+func setupFiberApp(service services.ProductService, keys auth.KeySet, idempotent fiber.Handler) *fiber.App {
+	if idempotent == nil {
+		idempotent = func(c *fiber.Ctx) error { return c.Next() }
+	}
 	handler := handlers.NewProductHandler(service)
 	healthHandler := handlers.NewHealthHandler()
 
@@ -148,8 +177,9 @@ func setupFiberApp(service services.ProductService) *fiber.App {
 		return err
 	})
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: resolveAllowedOrigins(),
-		AllowHeaders: "Origin, Content-Type, Accept",
+		AllowOrigins:  resolveAllowedOrigins(),
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, If-Match, Idempotency-Key",
+		ExposeHeaders: "ETag, Idempotent-Replayed",
 	}))
 	app.Use(middleware.PrometheusMiddleware())
 
@@ -168,15 +198,44 @@ func setupFiberApp(service services.ProductService) *fiber.App {
 	products.Get("", handler.GetProducts)
 	products.Get("/categories", handler.GetCategories)
 	products.Get("/count", handler.GetProductsCount)
-	products.Post("", handler.CreateProduct)
-	products.Patch("/:id/inventory", handler.PatchProductInventory)
-	products.Put("/:id", handler.UpdateProduct)
-	products.Delete("/:id", handler.DeleteProductById)
+	catalogWriter := middleware.RequireRoles(keys, auth.RoleCatalogManager)
+	products.Post("", catalogWriter, idempotent, handler.CreateProduct)
+	inventoryWriter := middleware.RequireScope(keys, auth.ScopeInventoryWrite)
+	products.Patch("/:id/inventory", inventoryWriter, idempotent, handler.PatchProductInventory)
+	products.Get("/:id/inventory/history", catalogWriter, handler.GetInventoryHistory)
+	products.Post("/inventory/batch", inventoryWriter, idempotent, handler.ApplyInventoryBatch)
+	products.Post("/reservations", inventoryWriter, idempotent, handler.CreateReservation)
+	products.Post("/reservations/:orderId/commit", inventoryWriter, handler.CommitReservation)
+	products.Delete("/reservations/:orderId", inventoryWriter, handler.ReleaseReservation)
+	products.Put("/:id", catalogWriter, handler.UpdateProduct)
+	products.Patch("/:id", catalogWriter, handler.PatchProduct)
+	products.Delete("/:id", catalogWriter, handler.DeleteProductById)
 	products.Get("/:id", handler.GetProductById)
 
 	return app
 }
 
+// sweepReservations returns the stock of expired holds every interval until
+// ctx is done. Running it on every replica is safe: expiring is idempotent.
+func sweepReservations(ctx context.Context, service services.ProductService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := service.ExpireReservations(ctx)
+			if err != nil {
+				log.Error("failed to expire stock reservations", logger.Err(err))
+			} else if changed > 0 {
+				log.Info("expired stock reservations", logger.Int("products", int(changed)))
+			}
+		}
+	}
+}
+
 func resolveAllowedOrigins() string {
 	const defaultAllowedOrigins = "https://velure.local"
 
@@ -3,11 +3,13 @@ package main
 import (
 	"context"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/icl00ud/velure/services/product-service/internal/config"
 	"github.com/icl00ud/velure/services/product-service/internal/model"
@@ -15,6 +17,8 @@ import (
 	"github.com/icl00ud/velure/services/product-service/internal/service"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/icl00ud/velure/shared/auth"
+	"github.com/icl00ud/velure/shared/auth/authtest"
 	"github.com/icl00ud/velure/shared/logger"
 	"github.com/stretchr/testify/assert"
 )
@@ -169,6 +173,8 @@ type fakeRepo struct {
 	createCalls []models.CreateProductRequest
 	count       int64
 	countCalls  int
+	drift       []models.InventoryDrift
+	baselined   []string
 }
 
 func (f *fakeRepo) GetAllProducts(ctx context.Context) ([]models.ProductResponse, error) {
@@ -176,7 +182,7 @@ func (f *fakeRepo) GetAllProducts(ctx context.Context) ([]models.ProductResponse
 }
 
 func (f *fakeRepo) GetProductById(ctx context.Context, id string) (*models.ProductResponse, error) {
-	return nil, nil
+	return &models.ProductResponse{ID: id}, nil
 }
 
 func (f *fakeRepo) GetProductsByName(ctx context.Context, name string) ([]models.ProductResponse, error) {
@@ -217,7 +223,15 @@ func (f *fakeRepo) DeleteProductById(ctx context.Context, id string) error {
 	return nil
 }
 
-func (f *fakeRepo) UpdateProductQuantity(ctx context.Context, productID string, quantityChange int) error {
+func (f *fakeRepo) LoadProduct(ctx context.Context, id string) (*models.Product, error) {
+	return &models.Product{Name: "p", Price: 10, Version: 1}, nil
+}
+
+func (f *fakeRepo) UpdateProduct(ctx context.Context, id string, version int64, req models.UpdateProductRequest) (*models.ProductResponse, error) {
+	return &models.ProductResponse{ID: id, Name: req.Name, Price: req.Price, Version: version + 1}, nil
+}
+
+func (f *fakeRepo) UpdateProductQuantity(ctx context.Context, productID string, change models.InventoryAdjustment) error {
 	return nil
 }
 
@@ -225,6 +239,42 @@ func (f *fakeRepo) GetProductQuantity(ctx context.Context, productID string) (in
 	return 0, nil
 }
 
+func (f *fakeRepo) ReserveStock(ctx context.Context, orderID string, items []models.ReservationItem, expiresAt time.Time) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeRepo) CommitStock(ctx context.Context, orderID string) error {
+	return nil
+}
+
+func (f *fakeRepo) ReleaseStock(ctx context.Context, orderID string) error {
+	return nil
+}
+
+func (f *fakeRepo) ExpireHolds(ctx context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeRepo) ApplyInventoryBatch(ctx context.Context, orderID string, changes []models.InventoryChange) (*models.InventoryBatchResponse, error) {
+	return &models.InventoryBatchResponse{OrderID: orderID, Applied: true}, nil
+}
+
+func (f *fakeRepo) GetStockMovements(ctx context.Context, productID string, page, pageSize int) (*models.StockMovementsPage, error) {
+	return &models.StockMovementsPage{Movements: []models.StockMovement{}, Page: page, PageSize: pageSize}, nil
+}
+
+func (f *fakeRepo) InventoryDrift(ctx context.Context) (int, []models.InventoryDrift, error) {
+	return int(f.count), f.drift, nil
+}
+
+func (f *fakeRepo) RecordOpeningStock(ctx context.Context, productID string) (bool, error) {
+	if models.ActorFromContext(ctx) != reconcileActor {
+		return false, errors.New("missing actor")
+	}
+	f.baselined = append(f.baselined, productID)
+	return true, nil
+}
+
 func (f *fakeRepo) WarmupCache(ctx context.Context) error {
 	return nil
 }
@@ -348,23 +398,32 @@ func TestMain_FatalOnRunError(t *testing.T) {
 }
 
 func TestSetupFiberApp_RegistersCanonicalProductRoutes(t *testing.T) {
-	app := setupFiberApp(services.NewProductService(&fakeRepo{}))
+	issuer := authtest.NewIssuer(t)
+	app := setupFiberApp(services.NewProductService(&fakeRepo{}), issuer.Keys(), nil)
+	managerToken := issuer.Token(t, "1", auth.RoleCatalogManager)
+	inventoryToken := issuer.ClientToken(t, "process-order-service", auth.ScopeInventoryWrite)
 
 	tests := []struct {
 		name       string
 		method     string
 		path       string
 		body       string
+		token      string
 		wantStatus int
 	}{
 		{name: "products list", method: http.MethodGet, path: "/api/products", wantStatus: fiber.StatusOK},
 		{name: "product by id", method: http.MethodGet, path: "/api/products/507f1f77bcf86cd799439011", wantStatus: fiber.StatusOK},
-		{name: "create product", method: http.MethodPost, path: "/api/products", body: `{"name":"p","price":10}`, wantStatus: fiber.StatusCreated},
-		{name: "update product not implemented", method: http.MethodPut, path: "/api/products/507f1f77bcf86cd799439011", wantStatus: fiber.StatusNotImplemented},
-		{name: "delete product", method: http.MethodDelete, path: "/api/products/507f1f77bcf86cd799439011", wantStatus: fiber.StatusNoContent},
+		{name: "create product", method: http.MethodPost, path: "/api/products", body: `{"name":"p","price":10}`, token: managerToken, wantStatus: fiber.StatusCreated},
+		{name: "update product", method: http.MethodPut, path: "/api/products/507f1f77bcf86cd799439011", body: `{"name":"p","price":10,"version":1}`, token: managerToken, wantStatus: fiber.StatusOK},
+		{name: "patch product", method: http.MethodPatch, path: "/api/products/507f1f77bcf86cd799439011", body: `{"price":12,"version":1}`, token: managerToken, wantStatus: fiber.StatusOK},
+		{name: "delete product", method: http.MethodDelete, path: "/api/products/507f1f77bcf86cd799439011", token: managerToken, wantStatus: fiber.StatusNoContent},
 		{name: "categories", method: http.MethodGet, path: "/api/products/categories", wantStatus: fiber.StatusOK},
 		{name: "count", method: http.MethodGet, path: "/api/products/count", wantStatus: fiber.StatusOK},
-		{name: "inventory patch", method: http.MethodPatch, path: "/api/products/507f1f77bcf86cd799439011/inventory", body: `{"quantity_change":-1}`, wantStatus: fiber.StatusOK},
+		{name: "inventory patch", method: http.MethodPatch, path: "/api/products/507f1f77bcf86cd799439011/inventory", body: `{"quantity_change":-1}`, token: inventoryToken, wantStatus: fiber.StatusOK},
+		{name: "reserve stock", method: http.MethodPost, path: "/api/products/reservations", body: `{"order_id":"o1","items":[{"product_id":"507f1f77bcf86cd799439011","quantity":1}]}`, token: inventoryToken, wantStatus: fiber.StatusCreated},
+		{name: "commit reservation", method: http.MethodPost, path: "/api/products/reservations/o1/commit", token: inventoryToken, wantStatus: fiber.StatusNoContent},
+		{name: "release reservation", method: http.MethodDelete, path: "/api/products/reservations/o1", token: inventoryToken, wantStatus: fiber.StatusNoContent},
+		{name: "inventory batch", method: http.MethodPost, path: "/api/products/inventory/batch", body: `{"order_id":"o1","items":[{"product_id":"507f1f77bcf86cd799439011","quantity_change":-1}]}`, token: inventoryToken, wantStatus: fiber.StatusOK},
 		{name: "health", method: http.MethodGet, path: "/health", wantStatus: fiber.StatusOK},
 		{name: "metrics", method: http.MethodGet, path: "/metrics", wantStatus: fiber.StatusOK},
 	}
@@ -375,6 +434,9 @@ func TestSetupFiberApp_RegistersCanonicalProductRoutes(t *testing.T) {
 			if tt.body != "" {
 				req.Header.Set("Content-Type", "application/json")
 			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
 
 			resp, err := app.Test(req)
 			assert.NoError(t, err)
@@ -383,8 +445,83 @@ func TestSetupFiberApp_RegistersCanonicalProductRoutes(t *testing.T) {
 	}
 }
 
+func TestSetupFiberApp_CatalogMutationsRequireRole(t *testing.T) {
+	issuer := authtest.NewIssuer(t)
+	app := setupFiberApp(services.NewProductService(&fakeRepo{}), issuer.Keys(), nil)
+	customerToken := issuer.Token(t, "1", auth.RoleCustomer)
+
+	mutations := []struct {
+		method string
+		path   string
+	}{
+		{method: http.MethodPost, path: "/api/products"},
+		{method: http.MethodPut, path: "/api/products/507f1f77bcf86cd799439011"},
+		{method: http.MethodPatch, path: "/api/products/507f1f77bcf86cd799439011"},
+		{method: http.MethodDelete, path: "/api/products/507f1f77bcf86cd799439011"},
+		{method: http.MethodGet, path: "/api/products/507f1f77bcf86cd799439011/inventory/history"},
+	}
+
+	for _, m := range mutations {
+		req := httptest.NewRequest(m.method, m.path, strings.NewReader(`{"name":"p","price":10}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode, "%s %s without token", m.method, m.path)
+
+		req = httptest.NewRequest(m.method, m.path, strings.NewReader(`{"name":"p","price":10}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+customerToken)
+		resp, err = app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode, "%s %s as customer", m.method, m.path)
+	}
+}
+
+func TestSetupFiberApp_InventoryMutationsRequireScope(t *testing.T) {
+	issuer := authtest.NewIssuer(t)
+	app := setupFiberApp(services.NewProductService(&fakeRepo{}), issuer.Keys(), nil)
+
+	cases := map[string]struct {
+		token string
+		want  int
+	}{
+		"no token":                    {want: fiber.StatusUnauthorized},
+		"admin user":                  {token: issuer.Token(t, "1", auth.RoleAdmin), want: fiber.StatusForbidden},
+		"client without the scope":    {token: issuer.ClientToken(t, "reporting"), want: fiber.StatusForbidden},
+		"client with inventory:write": {token: issuer.ClientToken(t, "process-order-service", auth.ScopeInventoryWrite), want: fiber.StatusOK},
+	}
+	mutations := []struct {
+		method string
+		path   string
+		body   string
+		ok     int
+	}{
+		{method: http.MethodPatch, path: "/api/products/507f1f77bcf86cd799439011/inventory", body: `{"quantity_change":-1}`, ok: fiber.StatusOK},
+		{method: http.MethodPost, path: "/api/products/reservations", body: `{"order_id":"o1","items":[{"product_id":"507f1f77bcf86cd799439011","quantity":1}]}`, ok: fiber.StatusCreated},
+		{method: http.MethodPost, path: "/api/products/reservations/o1/commit", ok: fiber.StatusNoContent},
+		{method: http.MethodDelete, path: "/api/products/reservations/o1", ok: fiber.StatusNoContent},
+		{method: http.MethodPost, path: "/api/products/inventory/batch", body: `{"order_id":"o1","items":[{"product_id":"507f1f77bcf86cd799439011","quantity_change":-1}]}`, ok: fiber.StatusOK},
+	}
+	for _, m := range mutations {
+		for name, tc := range cases {
+			req := httptest.NewRequest(m.method, m.path, strings.NewReader(m.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			want := tc.want
+			if want == fiber.StatusOK {
+				want = m.ok
+			}
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, want, resp.StatusCode, "%s %s: %s", m.method, m.path, name)
+		}
+	}
+}
+
 func TestSetupFiberApp_DoesNotRegisterLegacyProductAliases(t *testing.T) {
-	app := setupFiberApp(services.NewProductService(&fakeRepo{}))
+	app := setupFiberApp(services.NewProductService(&fakeRepo{}), nil, nil)
 
 	legacyRoutes := []struct {
 		method string
@@ -404,3 +541,87 @@ func TestSetupFiberApp_DoesNotRegisterLegacyProductAliases(t *testing.T) {
 		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
 	}
 }
+
+func TestSetupFiberApp_IdempotentRoutesRunAfterAuth(t *testing.T) {
+	issuer := authtest.NewIssuer(t)
+	var seen []string
+	idempotent := func(c *fiber.Ctx) error {
+		seen = append(seen, c.Method()+" "+c.Path())
+		return c.Next()
+	}
+	app := setupFiberApp(services.NewProductService(&fakeRepo{}), issuer.Keys(), idempotent)
+
+	client := issuer.ClientToken(t, "process-order-service", auth.ScopeInventoryWrite)
+	requests := []struct {
+		method, path, token string
+	}{
+		{http.MethodPost, "/api/products/inventory/batch", ""},
+		{http.MethodPost, "/api/products/inventory/batch", client},
+		{http.MethodPatch, "/api/products/507f1f77bcf86cd799439011/inventory", client},
+		{http.MethodPost, "/api/products/reservations", client},
+		{http.MethodPost, "/api/products/reservations/o1/commit", client},
+		{http.MethodPost, "/api/products", issuer.Token(t, "1", auth.RoleAdmin)},
+	}
+	for _, r := range requests {
+		req := httptest.NewRequest(r.method, r.path, strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		if r.token != "" {
+			req.Header.Set("Authorization", "Bearer "+r.token)
+		}
+		_, err := app.Test(req)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{
+		"POST /api/products/inventory/batch",
+		"PATCH /api/products/507f1f77bcf86cd799439011/inventory",
+		"POST /api/products/reservations",
+		"POST /api/products",
+	}, seen)
+}
+
+func TestRunReconcile(t *testing.T) {
+	deps := func(repo *fakeRepo) appDependencies {
+		return appDependencies{
+			loadEnv: func() error { return nil },
+			buildRepo: func(cfg *config.Config) (repository.ProductRepository, func(context.Context) error, func(), error) {
+				return repo, nil, nil, nil
+			},
+			newSvc: services.NewProductService,
+		}
+	}
+	legacy := models.InventoryDrift{ProductID: "legacy", Name: "Toy", Quantity: 4, Drift: 4}
+	tracked := models.InventoryDrift{ProductID: "tracked", Name: "Leash", Quantity: 8, LedgerQuantity: 10, Drift: -2, Movements: 2}
+
+	t.Run("no drift", func(t *testing.T) {
+		var out strings.Builder
+		err := runReconcile(deps(&fakeRepo{count: 3}), nil, &out)
+		assert.NoError(t, err)
+		assert.Contains(t, out.String(), "Checked 3 products")
+		assert.Contains(t, out.String(), "No drift")
+	})
+
+	t.Run("reports drift and fails", func(t *testing.T) {
+		var out strings.Builder
+		repo := &fakeRepo{count: 3, drift: []models.InventoryDrift{legacy, tracked}}
+		err := runReconcile(deps(repo), nil, &out)
+		assert.ErrorIs(t, err, errInventoryDrift)
+		assert.Contains(t, out.String(), "legacy")
+		assert.Contains(t, out.String(), "-2")
+		assert.Empty(t, repo.baselined)
+	})
+
+	t.Run("baseline records opening stock", func(t *testing.T) {
+		var out strings.Builder
+		repo := &fakeRepo{count: 3, drift: []models.InventoryDrift{legacy, tracked}}
+		err := runReconcile(deps(repo), []string{"-baseline"}, &out)
+		assert.ErrorIs(t, err, errInventoryDrift)
+		assert.Equal(t, []string{"legacy"}, repo.baselined)
+		assert.Contains(t, out.String(), "Recorded opening stock for 1 products")
+		assert.NotContains(t, out.String(), "Toy")
+	})
+
+	t.Run("unknown flag", func(t *testing.T) {
+		assert.Error(t, runReconcile(deps(&fakeRepo{}), []string{"-fix"}, io.Discard))
+	})
+}
@@ -684,7 +684,27 @@ if (existing > 0) {
   print("ℹ️  Skipping seed — " + existing + " products already present");
   quit(0);
 }
-db.getCollection(collectionName).insertMany(realisticProducts);
+var inserted = db.getCollection(collectionName).insertMany(realisticProducts);
+
+// Estoque inicial no ledger, para que a reconciliação bata desde o início
+var now = new Date();
+var openingMovements = [];
+realisticProducts.forEach(function (product, i) {
+  if (product.quantity) {
+    openingMovements.push({
+      product_id: inserted.insertedIds[i],
+      type: "opening",
+      quantity_change: product.quantity,
+      quantity: product.quantity,
+      actor: "seed",
+      dt_created: now,
+    });
+  }
+});
+if (openingMovements.length > 0) {
+  db.getCollection("stock_movements").insertMany(openingMovements);
+}
+db.getCollection("stock_movements").createIndex({ product_id: 1, dt_created: -1 });
 
 printLargeText("✅ Database with realistic pet products created successfully!", 8);
 print(`📊 Inserted ${realisticProducts.length} realistic pet products`);
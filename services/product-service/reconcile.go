@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/icl00ud/velure/services/product-service/internal/config"
+	"github.com/icl00ud/velure/services/product-service/internal/model"
+
+	"github.com/icl00ud/velure/shared/logger"
+)
+
+// reconcileActor is recorded on the opening entries the command writes.
+const reconcileActor = "reconcile"
+
+// errInventoryDrift makes the command exit non-zero when stock and ledger
+// disagree, so it can run as a scheduled check.
+var errInventoryDrift = errors.New("inventory drift found")
+
+// runReconcile recomputes every product's quantity from its stock movements
+// and prints the products whose stored quantity differs. With -baseline,
+// products that have no movements yet, i.e. were created before the ledger,
+// first get an opening entry for their quantity.
+func runReconcile(deps appDependencies, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	flags.SetOutput(out)
+	baseline := flags.Bool("baseline", false, "record opening stock for products without any movements")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if err := deps.loadEnv(); err != nil {
+		log.Info("No .env file found, using system environment variables")
+	}
+
+	repo, mongoDisconnect, redisClose, err := deps.buildRepo(config.New())
+	if err != nil {
+		return fmt.Errorf("failed to initialize repositories: %w", err)
+	}
+	if mongoDisconnect != nil {
+		defer func() {
+			if err := mongoDisconnect(context.Background()); err != nil {
+				log.Error("Error disconnecting from MongoDB", logger.Err(err))
+			}
+		}()
+	}
+	if redisClose != nil {
+		defer redisClose()
+	}
+
+	ctx := models.WithActor(context.Background(), reconcileActor)
+	report, err := deps.newSvc(repo).ReconcileInventory(ctx, *baseline)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile inventory: %w", err)
+	}
+
+	fmt.Fprintf(out, "Checked %d products\n", report.Products)
+	if *baseline {
+		fmt.Fprintf(out, "Recorded opening stock for %d products\n", report.Baselined)
+	}
+	if len(report.Drift) == 0 {
+		fmt.Fprintln(out, "No drift")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PRODUCT\tNAME\tQUANTITY\tLEDGER\tDRIFT\tMOVEMENTS")
+	for _, d := range report.Drift {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%+d\t%d\n", d.ProductID, d.Name, d.Quantity, d.LedgerQuantity, d.Drift, d.Movements)
+	}
+	w.Flush()
+	return fmt.Errorf("%w in %d products", errInventoryDrift, len(report.Drift))
+}
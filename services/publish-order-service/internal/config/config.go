@@ -5,6 +5,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
@@ -14,10 +15,21 @@ type Config struct {
 	Exchange    string
 	Queue       string
 	Workers     int
-	JWTSecret   string
+	// JWKSURL is auth-service's /.well-known/jwks.json; access tokens are
+	// verified against the public keys published there.
+	JWKSURL      string
+	JWKSCacheTTL time.Duration
+	// AuthRedisAddr is auth-service's Redis (host:port), where it lists
+	// access tokens revoked before they expired. Empty disables the check:
+	// a logged-out token is then accepted until it expires.
+	AuthRedisAddr     string
+	AuthRedisPassword string
 	// RedisAddr enables the cross-replica SSE update bus when set (host:port).
 	// Empty means single-replica mode: updates are broadcast in-process only.
 	RedisAddr string
+	// RequireVerifiedEmail rejects orders from users whose access token does
+	// not carry email_verified.
+	RequireVerifiedEmail bool
 }
 
 func Load() (Config, error) {
@@ -65,10 +77,28 @@ func Load() (Config, error) {
 		c.RedisAddr = strings.TrimSpace(v)
 	}
 
-	if v, ok := os.LookupEnv("JWT_SECRET"); ok && strings.TrimSpace(v) != "" {
-		c.JWTSecret = v
+	if v, ok := os.LookupEnv("AUTH_JWKS_URL"); ok && strings.TrimSpace(v) != "" {
+		c.JWKSURL = strings.TrimSpace(v)
 	} else {
-		missing = append(missing, "JWT_SECRET")
+		missing = append(missing, "AUTH_JWKS_URL")
+	}
+
+	if v, ok := os.LookupEnv("AUTH_REDIS_ADDR"); ok && strings.TrimSpace(v) != "" {
+		c.AuthRedisAddr = strings.TrimSpace(v)
+	}
+	c.AuthRedisPassword = os.Getenv("AUTH_REDIS_PASSWORD")
+
+	c.JWKSCacheTTL = 5 * time.Minute
+	if v, ok := os.LookupEnv("AUTH_JWKS_CACHE_TTL"); ok && strings.TrimSpace(v) != "" {
+		if d, err := time.ParseDuration(strings.TrimSpace(v)); err == nil && d > 0 {
+			c.JWKSCacheTTL = d
+		}
+	}
+
+	if v, ok := os.LookupEnv("ORDERS_REQUIRE_VERIFIED_EMAIL"); ok && strings.TrimSpace(v) != "" {
+		if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			c.RequireVerifiedEmail = b
+		}
 	}
 
 	if len(missing) > 0 {
@@ -4,6 +4,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoad_Success(t *testing.T) {
@@ -14,7 +15,7 @@ func TestLoad_Success(t *testing.T) {
 	os.Setenv("ORDER_EXCHANGE", "orders")
 	os.Setenv("PUBLISHER_ORDER_QUEUE", "test-queue")
 	os.Setenv("PUBLISHER_CONSUMER_WORKERS", "5")
-	os.Setenv("JWT_SECRET", "test-secret")
+	os.Setenv("AUTH_JWKS_URL", "http://auth:3020/.well-known/jwks.json")
 	defer func() {
 		os.Unsetenv("PUBLISHER_ORDER_SERVICE_APP_PORT")
 		os.Unsetenv("POSTGRES_URL")
@@ -22,7 +23,7 @@ func TestLoad_Success(t *testing.T) {
 		os.Unsetenv("ORDER_EXCHANGE")
 		os.Unsetenv("PUBLISHER_ORDER_QUEUE")
 		os.Unsetenv("PUBLISHER_CONSUMER_WORKERS")
-		os.Unsetenv("JWT_SECRET")
+		os.Unsetenv("AUTH_JWKS_URL")
 	}()
 
 	cfg, err := Load()
@@ -48,8 +49,11 @@ func TestLoad_Success(t *testing.T) {
 	if cfg.Workers != 5 {
 		t.Errorf("expected Workers 5, got %d", cfg.Workers)
 	}
-	if cfg.JWTSecret != "test-secret" {
-		t.Errorf("expected JWTSecret test-secret, got %s", cfg.JWTSecret)
+	if cfg.JWKSURL != "http://auth:3020/.well-known/jwks.json" {
+		t.Errorf("expected JWKSURL http://auth:3020/.well-known/jwks.json, got %s", cfg.JWKSURL)
+	}
+	if cfg.JWKSCacheTTL != 5*time.Minute {
+		t.Errorf("expected default JWKSCacheTTL 5m, got %s", cfg.JWKSCacheTTL)
 	}
 }
 
@@ -59,13 +63,13 @@ func TestLoad_DefaultQueue(t *testing.T) {
 	os.Setenv("POSTGRES_URL", "postgres://localhost/testdb")
 	os.Setenv("PUBLISHER_RABBITMQ_URL", "amqp://localhost")
 	os.Setenv("ORDER_EXCHANGE", "orders")
-	os.Setenv("JWT_SECRET", "test-secret")
+	os.Setenv("AUTH_JWKS_URL", "http://auth:3020/.well-known/jwks.json")
 	defer func() {
 		os.Unsetenv("PUBLISHER_ORDER_SERVICE_APP_PORT")
 		os.Unsetenv("POSTGRES_URL")
 		os.Unsetenv("PUBLISHER_RABBITMQ_URL")
 		os.Unsetenv("ORDER_EXCHANGE")
-		os.Unsetenv("JWT_SECRET")
+		os.Unsetenv("AUTH_JWKS_URL")
 	}()
 
 	cfg, err := Load()
@@ -84,13 +88,13 @@ func TestLoad_DefaultWorkers(t *testing.T) {
 	os.Setenv("POSTGRES_URL", "postgres://localhost/testdb")
 	os.Setenv("PUBLISHER_RABBITMQ_URL", "amqp://localhost")
 	os.Setenv("ORDER_EXCHANGE", "orders")
-	os.Setenv("JWT_SECRET", "test-secret")
+	os.Setenv("AUTH_JWKS_URL", "http://auth:3020/.well-known/jwks.json")
 	defer func() {
 		os.Unsetenv("PUBLISHER_ORDER_SERVICE_APP_PORT")
 		os.Unsetenv("POSTGRES_URL")
 		os.Unsetenv("PUBLISHER_RABBITMQ_URL")
 		os.Unsetenv("ORDER_EXCHANGE")
-		os.Unsetenv("JWT_SECRET")
+		os.Unsetenv("AUTH_JWKS_URL")
 	}()
 
 	cfg, err := Load()
@@ -110,14 +114,14 @@ func TestLoad_InvalidWorkers(t *testing.T) {
 	os.Setenv("PUBLISHER_RABBITMQ_URL", "amqp://localhost")
 	os.Setenv("ORDER_EXCHANGE", "orders")
 	os.Setenv("PUBLISHER_CONSUMER_WORKERS", "invalid")
-	os.Setenv("JWT_SECRET", "test-secret")
+	os.Setenv("AUTH_JWKS_URL", "http://auth:3020/.well-known/jwks.json")
 	defer func() {
 		os.Unsetenv("PUBLISHER_ORDER_SERVICE_APP_PORT")
 		os.Unsetenv("POSTGRES_URL")
 		os.Unsetenv("PUBLISHER_RABBITMQ_URL")
 		os.Unsetenv("ORDER_EXCHANGE")
 		os.Unsetenv("PUBLISHER_CONSUMER_WORKERS")
-		os.Unsetenv("JWT_SECRET")
+		os.Unsetenv("AUTH_JWKS_URL")
 	}()
 
 	cfg, err := Load()
@@ -138,14 +142,14 @@ func TestLoad_ZeroWorkers(t *testing.T) {
 	os.Setenv("PUBLISHER_RABBITMQ_URL", "amqp://localhost")
 	os.Setenv("ORDER_EXCHANGE", "orders")
 	os.Setenv("PUBLISHER_CONSUMER_WORKERS", "0")
-	os.Setenv("JWT_SECRET", "test-secret")
+	os.Setenv("AUTH_JWKS_URL", "http://auth:3020/.well-known/jwks.json")
 	defer func() {
 		os.Unsetenv("PUBLISHER_ORDER_SERVICE_APP_PORT")
 		os.Unsetenv("POSTGRES_URL")
 		os.Unsetenv("PUBLISHER_RABBITMQ_URL")
 		os.Unsetenv("ORDER_EXCHANGE")
 		os.Unsetenv("PUBLISHER_CONSUMER_WORKERS")
-		os.Unsetenv("JWT_SECRET")
+		os.Unsetenv("AUTH_JWKS_URL")
 	}()
 
 	cfg, err := Load()
@@ -164,12 +168,12 @@ func TestLoad_MissingPort(t *testing.T) {
 	os.Setenv("POSTGRES_URL", "postgres://localhost/testdb")
 	os.Setenv("PUBLISHER_RABBITMQ_URL", "amqp://localhost")
 	os.Setenv("ORDER_EXCHANGE", "orders")
-	os.Setenv("JWT_SECRET", "test-secret")
+	os.Setenv("AUTH_JWKS_URL", "http://auth:3020/.well-known/jwks.json")
 	defer func() {
 		os.Unsetenv("POSTGRES_URL")
 		os.Unsetenv("PUBLISHER_RABBITMQ_URL")
 		os.Unsetenv("ORDER_EXCHANGE")
-		os.Unsetenv("JWT_SECRET")
+		os.Unsetenv("AUTH_JWKS_URL")
 	}()
 
 	_, err := Load()
@@ -198,13 +202,13 @@ func TestLoad_EmptyValues(t *testing.T) {
 	os.Setenv("POSTGRES_URL", "")
 	os.Setenv("PUBLISHER_RABBITMQ_URL", "  ")
 	os.Setenv("ORDER_EXCHANGE", "")
-	os.Setenv("JWT_SECRET", "  ")
+	os.Setenv("AUTH_JWKS_URL", "  ")
 	defer func() {
 		os.Unsetenv("PUBLISHER_ORDER_SERVICE_APP_PORT")
 		os.Unsetenv("POSTGRES_URL")
 		os.Unsetenv("PUBLISHER_RABBITMQ_URL")
 		os.Unsetenv("ORDER_EXCHANGE")
-		os.Unsetenv("JWT_SECRET")
+		os.Unsetenv("AUTH_JWKS_URL")
 	}()
 
 	_, err := Load()
@@ -212,3 +216,96 @@ func TestLoad_EmptyValues(t *testing.T) {
 		t.Fatal("expected error for empty env vars, got nil")
 	}
 }
+
+func TestLoad_JWKSCacheTTL(t *testing.T) {
+	os.Setenv("PUBLISHER_ORDER_SERVICE_APP_PORT", "8080")
+	os.Setenv("POSTGRES_URL", "postgres://localhost/testdb")
+	os.Setenv("PUBLISHER_RABBITMQ_URL", "amqp://localhost")
+	os.Setenv("ORDER_EXCHANGE", "orders")
+	os.Setenv("AUTH_JWKS_URL", "http://auth:3020/.well-known/jwks.json")
+	os.Setenv("AUTH_JWKS_CACHE_TTL", "30s")
+	defer func() {
+		os.Unsetenv("PUBLISHER_ORDER_SERVICE_APP_PORT")
+		os.Unsetenv("POSTGRES_URL")
+		os.Unsetenv("PUBLISHER_RABBITMQ_URL")
+		os.Unsetenv("ORDER_EXCHANGE")
+		os.Unsetenv("AUTH_JWKS_URL")
+		os.Unsetenv("AUTH_JWKS_CACHE_TTL")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.JWKSCacheTTL != 30*time.Second {
+		t.Errorf("expected JWKSCacheTTL 30s, got %s", cfg.JWKSCacheTTL)
+	}
+}
+
+func TestLoad_RequireVerifiedEmail(t *testing.T) {
+	os.Setenv("PUBLISHER_ORDER_SERVICE_APP_PORT", "8080")
+	os.Setenv("POSTGRES_URL", "postgres://localhost/testdb")
+	os.Setenv("PUBLISHER_RABBITMQ_URL", "amqp://localhost")
+	os.Setenv("ORDER_EXCHANGE", "orders")
+	os.Setenv("AUTH_JWKS_URL", "http://auth:3020/.well-known/jwks.json")
+	defer func() {
+		os.Unsetenv("PUBLISHER_ORDER_SERVICE_APP_PORT")
+		os.Unsetenv("POSTGRES_URL")
+		os.Unsetenv("PUBLISHER_RABBITMQ_URL")
+		os.Unsetenv("ORDER_EXCHANGE")
+		os.Unsetenv("AUTH_JWKS_URL")
+		os.Unsetenv("ORDERS_REQUIRE_VERIFIED_EMAIL")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.RequireVerifiedEmail {
+		t.Error("expected RequireVerifiedEmail to default to false")
+	}
+
+	os.Setenv("ORDERS_REQUIRE_VERIFIED_EMAIL", "true")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.RequireVerifiedEmail {
+		t.Error("expected RequireVerifiedEmail to be enabled")
+	}
+}
+
+func TestLoad_AuthRedis(t *testing.T) {
+	os.Setenv("PUBLISHER_ORDER_SERVICE_APP_PORT", "8080")
+	os.Setenv("POSTGRES_URL", "postgres://localhost/testdb")
+	os.Setenv("PUBLISHER_RABBITMQ_URL", "amqp://localhost")
+	os.Setenv("ORDER_EXCHANGE", "orders")
+	os.Setenv("AUTH_JWKS_URL", "http://auth:3020/.well-known/jwks.json")
+	defer func() {
+		os.Unsetenv("PUBLISHER_ORDER_SERVICE_APP_PORT")
+		os.Unsetenv("POSTGRES_URL")
+		os.Unsetenv("PUBLISHER_RABBITMQ_URL")
+		os.Unsetenv("ORDER_EXCHANGE")
+		os.Unsetenv("AUTH_JWKS_URL")
+		os.Unsetenv("AUTH_REDIS_ADDR")
+		os.Unsetenv("AUTH_REDIS_PASSWORD")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.AuthRedisAddr != "" {
+		t.Errorf("expected AuthRedisAddr to default to empty, got %q", cfg.AuthRedisAddr)
+	}
+
+	os.Setenv("AUTH_REDIS_ADDR", " redis:6379 ")
+	os.Setenv("AUTH_REDIS_PASSWORD", "secret")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.AuthRedisAddr != "redis:6379" || cfg.AuthRedisPassword != "secret" {
+		t.Errorf("unexpected auth redis settings %q %q", cfg.AuthRedisAddr, cfg.AuthRedisPassword)
+	}
+}
@@ -62,6 +62,15 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Orders support staff place while impersonating a customer are not the
+	// customer's own; this line is how they are found and flagged.
+	if actor := middleware.GetActor(r.Context()); actor != "" {
+		logger.Warn("order placed under impersonation",
+			logger.String("order_id", o.ID),
+			logger.String("user_id", userID),
+			logger.String("impersonated_by", actor))
+	}
+
 	metrics.OrdersCreated.WithLabelValues("success").Inc()
 	metrics.OrderCreationDuration.Observe(time.Since(start).Seconds())
 	metrics.OrderTotalValue.Observe(float64(o.Total))
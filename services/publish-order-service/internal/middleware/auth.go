@@ -2,19 +2,29 @@ package middleware
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"net/http"
 	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/icl00ud/velure/shared/auth"
 	"github.com/icl00ud/velure/shared/logger"
 )
 
 type contextKey string
 
-const UserIDKey contextKey = "user_id"
+const (
+	UserIDKey        contextKey = "user_id"
+	RolesKey         contextKey = "roles"
+	EmailVerifiedKey contextKey = "email_verified"
+	ActorKey         contextKey = "actor"
+)
 
-func Auth(jwtSecret string) func(http.Handler) http.Handler {
+// Auth verifies the caller's access token against keys, normally auth-service's
+// JWKS, refuses tokens on revocations (nil skips the check), and stores the
+// subject, roles, email verification state and, on impersonation tokens, the
+// acting admin in the request context. Tokens issued to machine clients are
+// refused: their subject is not a user.
+func Auth(keys auth.KeySet, revocations *auth.RevocationList) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Skip authentication for OPTIONS requests (CORS preflight)
@@ -43,37 +53,118 @@ func Auth(jwtSecret string) func(http.Handler) http.Handler {
 				http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
 				return
 			}
-			claims := &jwt.RegisteredClaims{}
-
-			token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-				}
-				return []byte(jwtSecret), nil
-			})
-
-			if err != nil || !token.Valid {
+			claims, err := auth.Parse(tokenString, keys)
+			if err != nil {
 				logger.Warn("invalid token", logger.Err(err))
 				http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
 				return
 			}
+			if revoked(r.Context(), revocations, claims) {
+				http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+				return
+			}
 
 			userID := claims.Subject
-			if userID == "" {
-				logger.Warn("missing user_id in token")
+			if userID == "" || claims.IsClient() {
+				logger.Warn("token does not belong to a user", logger.String("client_id", claims.ClientID))
 				http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), UserIDKey, userID)
+			ctx = context.WithValue(ctx, RolesKey, claims.EffectiveRoles())
+			ctx = context.WithValue(ctx, EmailVerifiedKey, claims.EmailVerified)
+			if claims.IsImpersonated() {
+				ctx = context.WithValue(ctx, ActorKey, claims.Actor.Subject)
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// revoked reports whether the token was revoked in auth-service, for example
+// by a logout. When Redis cannot be asked the token is let through: it still
+// expires on its own, and refusing every token would turn a Redis outage into
+// a full one.
+func revoked(ctx context.Context, revocations *auth.RevocationList, claims *auth.Claims) bool {
+	err := revocations.Check(ctx, claims)
+	if errors.Is(err, auth.ErrTokenRevoked) {
+		logger.Warn("revoked token", logger.String("user_id", claims.Subject))
+		return true
+	}
+	if err != nil {
+		logger.Warn("token revocation check failed", logger.Err(err))
+	}
+	return false
+}
+
 func GetUserID(ctx context.Context) string {
 	if userID, ok := ctx.Value(UserIDKey).(string); ok {
 		return userID
 	}
 	return ""
 }
+
+// GetRoles returns the roles of the token authenticated by Auth or SSEAuth.
+func GetRoles(ctx context.Context) []string {
+	if roles, ok := ctx.Value(RolesKey).([]string); ok {
+		return roles
+	}
+	return nil
+}
+
+// IsEmailVerified reports whether the token authenticated by Auth or SSEAuth
+// says the user has confirmed their email address.
+func IsEmailVerified(ctx context.Context) bool {
+	verified, _ := ctx.Value(EmailVerifiedKey).(bool)
+	return verified
+}
+
+// GetActor returns the ID of the admin impersonating the user, or "" when
+// the user is acting themselves.
+func GetActor(ctx context.Context) string {
+	actor, _ := ctx.Value(ActorKey).(string)
+	return actor
+}
+
+// RequireRoles rejects requests whose token does not grant at least one of
+// roles. It must run after Auth or SSEAuth.
+func RequireRoles(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !auth.HasAnyRole(GetRoles(r.Context()), roles...) {
+				logger.Warn("insufficient role",
+					logger.String("user_id", GetUserID(r.Context())),
+					logger.String("required", strings.Join(roles, ",")))
+				http.Error(w, `{"error":"forbidden"}`, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireVerifiedEmail rejects requests whose token does not carry a verified
+// email address. It must run after Auth or SSEAuth.
+func RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !IsEmailVerified(r.Context()) {
+			logger.Warn("email not verified", logger.String("user_id", GetUserID(r.Context())))
+			http.Error(w, `{"error":"email not verified"}`, http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
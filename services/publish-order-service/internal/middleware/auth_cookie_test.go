@@ -5,25 +5,15 @@ import (
 	"net/http/httptest"
 	"testing"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/icl00ud/velure/shared/auth/authtest"
 )
 
-func signTestToken(t *testing.T, secret, subject string) string {
-	t.Helper()
-	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: subject})
-	s, err := tok.SignedString([]byte(secret))
-	if err != nil {
-		t.Fatal(err)
-	}
-	return s
-}
-
 func TestAuth_AcceptsAccessTokenCookie(t *testing.T) {
-	const secret = "test-secret"
-	token := signTestToken(t, secret, "user-7")
+	issuer := authtest.NewIssuer(t)
+	token := issuer.Token(t, "user-7")
 
 	var gotUserID string
-	handler := Auth(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := Auth(issuer.Keys(), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		gotUserID = GetUserID(r.Context())
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -43,11 +33,11 @@ func TestAuth_AcceptsAccessTokenCookie(t *testing.T) {
 }
 
 func TestSSEAuth_AcceptsAccessTokenCookie(t *testing.T) {
-	const secret = "test-secret"
-	token := signTestToken(t, secret, "user-8")
+	issuer := authtest.NewIssuer(t)
+	token := issuer.Token(t, "user-8")
 
 	var gotUserID string
-	handler := SSEAuth(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := SSEAuth(issuer.Keys(), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		gotUserID = GetUserID(r.Context())
 		w.WriteHeader(http.StatusOK)
 	}))
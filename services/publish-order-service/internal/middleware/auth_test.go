@@ -7,32 +7,41 @@ import (
 	"testing"
 	"time"
 
+	miniredis "github.com/alicebob/miniredis/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/icl00ud/velure/shared/auth"
+	"github.com/icl00ud/velure/shared/auth/authtest"
+	"github.com/redis/go-redis/v9"
 )
 
 func TestAuth(t *testing.T) {
-	secret := "test-secret"
+	issuer := authtest.NewIssuer(t)
 
 	// Create a valid token
-	validToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+	validTokenString := issuer.Sign(t, jwt.RegisteredClaims{
 		Subject:   "user123",
 		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
 	})
-	validTokenString, _ := validToken.SignedString([]byte(secret))
 
 	// Create an expired token
-	expiredToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+	expiredTokenString := issuer.Sign(t, jwt.RegisteredClaims{
 		Subject:   "user123",
 		ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
 	})
-	expiredTokenString, _ := expiredToken.SignedString([]byte(secret))
 
-	// Create a token with wrong signature
-	wrongSecretToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+	// Create a token signed by a key auth-service never published
+	wrongSecretTokenString := authtest.NewIssuer(t).Sign(t, jwt.RegisteredClaims{
 		Subject:   "user123",
 		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
 	})
-	wrongSecretTokenString, _ := wrongSecretToken.SignedString([]byte("wrong-secret"))
+
+	// A shared-secret token must not be accepted even with a known kid
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   "user123",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	hmacToken.Header["kid"] = issuer.Keys().Keys[0].Kid
+	hmacTokenString, _ := hmacToken.SignedString([]byte("test-secret"))
 
 	tests := []struct {
 		name           string
@@ -90,6 +99,13 @@ func TestAuth(t *testing.T) {
 			expectedStatus: http.StatusUnauthorized,
 			expectUserID:   false,
 		},
+		{
+			name:           "shared-secret token",
+			authHeader:     "Bearer " + hmacTokenString,
+			method:         http.MethodGet,
+			expectedStatus: http.StatusUnauthorized,
+			expectUserID:   false,
+		},
 		{
 			name:           "invalid token format",
 			authHeader:     "Bearer invalid.token.here",
@@ -114,7 +130,7 @@ func TestAuth(t *testing.T) {
 			})
 
 			// Wrap with Auth middleware
-			authMiddleware := Auth(secret)
+			authMiddleware := Auth(issuer.Keys(), nil)
 			wrappedHandler := authMiddleware(handler)
 
 			// Create test request
@@ -136,19 +152,18 @@ func TestAuth(t *testing.T) {
 }
 
 func TestAuth_TokenWithoutSubject(t *testing.T) {
-	secret := "test-secret"
+	issuer := authtest.NewIssuer(t)
 
 	// Create a token without subject
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+	tokenString := issuer.Sign(t, jwt.RegisteredClaims{
 		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
 	})
-	tokenString, _ := token.SignedString([]byte(secret))
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	authMiddleware := Auth(secret)
+	authMiddleware := Auth(issuer.Keys(), nil)
 	wrappedHandler := authMiddleware(handler)
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
@@ -162,6 +177,28 @@ func TestAuth_TokenWithoutSubject(t *testing.T) {
 	}
 }
 
+func TestAuth_RejectsClientTokens(t *testing.T) {
+	issuer := authtest.NewIssuer(t)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for name, mw := range map[string]func(http.Handler) http.Handler{
+		"Auth":    Auth(issuer.Keys(), nil),
+		"SSEAuth": SSEAuth(issuer.Keys(), nil),
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+issuer.ClientToken(t, "process-order-service", auth.ScopeInventoryWrite))
+
+		rr := httptest.NewRecorder()
+		mw(handler).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("%s: expected status %d for a client token, got %d", name, http.StatusUnauthorized, rr.Code)
+		}
+	}
+}
+
 func TestGetUserID(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -207,3 +244,208 @@ func TestGetUserID(t *testing.T) {
 		})
 	}
 }
+
+func TestAuth_RolesInContext(t *testing.T) {
+	issuer := authtest.NewIssuer(t)
+
+	sign := func(roles []string) string {
+		return issuer.Token(t, "user123", roles...)
+	}
+
+	tests := []struct {
+		name     string
+		roles    []string
+		expected []string
+	}{
+		{name: "explicit roles", roles: []string{auth.RoleAdmin}, expected: []string{auth.RoleAdmin}},
+		{name: "legacy token defaults to customer", roles: nil, expected: []string{auth.RoleCustomer}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			handler := Auth(issuer.Keys(), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = GetRoles(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+sign(tt.roles))
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if len(got) != len(tt.expected) || got[0] != tt.expected[0] {
+				t.Errorf("expected roles %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRequireRoles(t *testing.T) {
+	tests := []struct {
+		name           string
+		roles          []string
+		method         string
+		expectedStatus int
+	}{
+		{name: "matching role", roles: []string{auth.RoleCustomer}, method: http.MethodPost, expectedStatus: http.StatusOK},
+		{name: "admin passes", roles: []string{auth.RoleAdmin}, method: http.MethodPost, expectedStatus: http.StatusOK},
+		{name: "missing role", roles: []string{auth.RoleCatalogManager}, method: http.MethodPost, expectedStatus: http.StatusForbidden},
+		{name: "no roles in context", roles: nil, method: http.MethodPost, expectedStatus: http.StatusForbidden},
+		{name: "options request - skip check", roles: nil, method: http.MethodOptions, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequireRoles(auth.RoleCustomer)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(tt.method, "/test", nil)
+			if tt.roles != nil {
+				req = req.WithContext(context.WithValue(req.Context(), RolesKey, tt.roles))
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestAuth_EmailVerifiedInContext(t *testing.T) {
+	issuer := authtest.NewIssuer(t)
+
+	for _, verified := range []bool{true, false} {
+		var got bool
+		handler := Auth(issuer.Keys(), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = IsEmailVerified(r.Context())
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+issuer.Sign(t, auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "user123",
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+			EmailVerified: verified,
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if got != verified {
+			t.Errorf("expected email verified %v, got %v", verified, got)
+		}
+	}
+}
+
+func TestAuth_ActorInContext(t *testing.T) {
+	issuer := authtest.NewIssuer(t)
+
+	for _, actor := range []string{"", "1"} {
+		claims := auth.Claims{RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user123",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}}
+		if actor != "" {
+			claims.Actor = &auth.Actor{Subject: actor}
+		}
+
+		got := "unset"
+		handler := Auth(issuer.Keys(), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = GetActor(r.Context())
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+issuer.Sign(t, claims))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if got != actor {
+			t.Errorf("expected actor %q, got %q", actor, got)
+		}
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	tests := []struct {
+		name           string
+		verified       *bool
+		method         string
+		expectedStatus int
+	}{
+		{name: "verified", verified: boolPtr(true), method: http.MethodPost, expectedStatus: http.StatusOK},
+		{name: "unverified", verified: boolPtr(false), method: http.MethodPost, expectedStatus: http.StatusForbidden},
+		{name: "nothing in context", verified: nil, method: http.MethodPost, expectedStatus: http.StatusForbidden},
+		{name: "options request - skip check", verified: nil, method: http.MethodOptions, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequireVerifiedEmail(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(tt.method, "/test", nil)
+			if tt.verified != nil {
+				req = req.WithContext(context.WithValue(req.Context(), EmailVerifiedKey, *tt.verified))
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestAuth_RevokedTokens(t *testing.T) {
+	issuer := authtest.NewIssuer(t)
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	revocations := auth.NewRevocationList(client)
+
+	expiresAt := time.Now().Add(time.Hour)
+	token := issuer.Sign(t, auth.Claims{RegisteredClaims: jwt.RegisteredClaims{
+		ID:        "jti-1",
+		Subject:   "user123",
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}})
+	if err := revocations.Revoke(context.Background(), "jti-1", expiresAt); err != nil {
+		t.Fatal(err)
+	}
+	other := issuer.Token(t, "user123", auth.RoleCustomer)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	middlewares := map[string]func(http.Handler) http.Handler{
+		"Auth":    Auth(issuer.Keys(), revocations),
+		"SSEAuth": SSEAuth(issuer.Keys(), revocations),
+	}
+	serve := func(mw func(http.Handler) http.Handler, token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/me/orders", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		mw(ok).ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	for name, mw := range middlewares {
+		if code := serve(mw, token); code != http.StatusUnauthorized {
+			t.Errorf("%s: expected revoked token to be rejected, got %d", name, code)
+		}
+		if code := serve(mw, other); code != http.StatusOK {
+			t.Errorf("%s: expected other tokens to pass, got %d", name, code)
+		}
+	}
+
+	// Without Redis the check is skipped rather than locking everyone out.
+	mr.Close()
+	for name, mw := range middlewares {
+		if code := serve(mw, token); code != http.StatusOK {
+			t.Errorf("%s: expected the token to pass while redis is down, got %d", name, code)
+		}
+	}
+}
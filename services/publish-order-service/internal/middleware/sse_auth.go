@@ -2,17 +2,17 @@ package middleware
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/icl00ud/velure/shared/auth"
 	"github.com/icl00ud/velure/shared/logger"
 )
 
 // SSEAuth is a middleware for SSE connections that accepts token from query parameter
 // EventSource doesn't support custom headers, so we need to accept token via URL
-func SSEAuth(jwtSecret string) func(http.Handler) http.Handler {
+// Revoked tokens are refused as in Auth.
+func SSEAuth(keys auth.KeySet, revocations *auth.RevocationList) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Skip authentication for OPTIONS requests (CORS preflight)
@@ -50,28 +50,30 @@ func SSEAuth(jwtSecret string) func(http.Handler) http.Handler {
 				return
 			}
 
-			claims := &jwt.RegisteredClaims{}
-			token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-				}
-				return []byte(jwtSecret), nil
-			})
-
-			if err != nil || !token.Valid {
+			claims, err := auth.Parse(tokenString, keys)
+			if err != nil {
 				logger.Warn("invalid token", logger.Err(err))
 				http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
 				return
 			}
+			if revoked(r.Context(), revocations, claims) {
+				http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+				return
+			}
 
 			userID := claims.Subject
-			if userID == "" {
-				logger.Warn("missing user_id in token")
+			if userID == "" || claims.IsClient() {
+				logger.Warn("token does not belong to a user", logger.String("client_id", claims.ClientID))
 				http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), UserIDKey, userID)
+			ctx = context.WithValue(ctx, RolesKey, claims.EffectiveRoles())
+			ctx = context.WithValue(ctx, EmailVerifiedKey, claims.EmailVerified)
+			if claims.IsImpersonated() {
+				ctx = context.WithValue(ctx, ActorKey, claims.Actor.Subject)
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
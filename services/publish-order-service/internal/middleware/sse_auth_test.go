@@ -7,17 +7,17 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/icl00ud/velure/shared/auth/authtest"
 )
 
 func TestSSEAuth_AllowsValidTokenFromQuery(t *testing.T) {
-	secret := "test"
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+	issuer := authtest.NewIssuer(t)
+	tokenStr := issuer.Sign(t, jwt.RegisteredClaims{
 		Subject:   "user123",
 		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
 	})
-	tokenStr, _ := token.SignedString([]byte(secret))
 
-	handler := SSEAuth(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := SSEAuth(issuer.Keys(), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if GetUserID(r.Context()) != "user123" {
 			t.Fatalf("expected user id in context")
 		}
@@ -34,7 +34,7 @@ func TestSSEAuth_AllowsValidTokenFromQuery(t *testing.T) {
 }
 
 func TestSSEAuth_RejectsMissingToken(t *testing.T) {
-	handler := SSEAuth("secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := SSEAuth(authtest.NewIssuer(t).Keys(), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -48,7 +48,7 @@ func TestSSEAuth_RejectsMissingToken(t *testing.T) {
 }
 
 func TestSSEAuth_RejectsInvalidToken(t *testing.T) {
-	handler := SSEAuth("secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := SSEAuth(authtest.NewIssuer(t).Keys(), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -62,14 +62,13 @@ func TestSSEAuth_RejectsInvalidToken(t *testing.T) {
 }
 
 func TestSSEAuth_AllowsAuthorizationHeader(t *testing.T) {
-	secret := "header-secret"
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+	issuer := authtest.NewIssuer(t)
+	tokenStr := issuer.Sign(t, jwt.RegisteredClaims{
 		Subject:   "user456",
 		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
 	})
-	tokenStr, _ := token.SignedString([]byte(secret))
 
-	handler := SSEAuth(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := SSEAuth(issuer.Keys(), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if GetUserID(r.Context()) != "user456" {
 			t.Fatalf("expected user id propagated from token")
 		}
@@ -87,13 +86,12 @@ func TestSSEAuth_AllowsAuthorizationHeader(t *testing.T) {
 }
 
 func TestSSEAuth_RejectsMissingSubject(t *testing.T) {
-	secret := "no-subject"
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+	issuer := authtest.NewIssuer(t)
+	tokenStr := issuer.Sign(t, jwt.RegisteredClaims{
 		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
 	})
-	tokenStr, _ := token.SignedString([]byte(secret))
 
-	handler := SSEAuth(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := SSEAuth(issuer.Keys(), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -107,7 +105,7 @@ func TestSSEAuth_RejectsMissingSubject(t *testing.T) {
 }
 
 func TestSSEAuth_AllowsOptionsWithoutAuth(t *testing.T) {
-	handler := SSEAuth("secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := SSEAuth(authtest.NewIssuer(t).Keys(), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusTeapot)
 	}))
 
@@ -24,6 +24,7 @@ import (
 	"github.com/icl00ud/velure/services/publish-order-service/internal/service"
 	"github.com/icl00ud/velure/services/publish-order-service/internal/supervisor"
 	"github.com/icl00ud/velure/services/publish-order-service/internal/telemetry"
+	"github.com/icl00ud/velure/shared/auth"
 	"github.com/icl00ud/velure/shared/logger"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -186,11 +187,24 @@ func run(parentCtx context.Context, deps appDeps) error {
 	defer cons.Close()
 
 	log.Info("Setting up middleware")
-	authMiddleware := middleware.Auth(cfg.JWTSecret)
-	sseAuthMiddleware := middleware.SSEAuth(cfg.JWTSecret)
+	keys := auth.NewRemoteKeySet(cfg.JWKSURL, cfg.JWKSCacheTTL)
+	var revocations *auth.RevocationList
+	if cfg.AuthRedisAddr != "" {
+		log.Info("Checking access tokens against auth-service's revocation list", logger.String("redis_addr", cfg.AuthRedisAddr))
+		authRedis := redis.NewClient(&redis.Options{Addr: cfg.AuthRedisAddr, Password: cfg.AuthRedisPassword})
+		defer authRedis.Close()
+		revocations = auth.NewRevocationList(authRedis)
+	} else {
+		log.Warn("AUTH_REDIS_ADDR not set, revoked access tokens are accepted until they expire")
+	}
+	authMiddleware := middleware.Auth(keys, revocations)
+	sseAuthMiddleware := middleware.SSEAuth(keys, revocations)
 
 	mux := http.NewServeMux()
-	registerRoutes(mux, oh, sseHandler, authMiddleware, sseAuthMiddleware)
+	if cfg.RequireVerifiedEmail {
+		log.Info("Orders require a verified email address")
+	}
+	registerRoutes(mux, oh, sseHandler, authMiddleware, sseAuthMiddleware, cfg.RequireVerifiedEmail)
 
 	mux.Handle("/metrics", promhttp.Handler())
 
@@ -278,8 +292,14 @@ func registerRoutes(
 	sseHandler *handler.SSEHandler,
 	authMiddleware func(http.Handler) http.Handler,
 	sseAuthMiddleware func(http.Handler) http.Handler,
+	requireVerifiedEmail bool,
 ) {
-	createOrder := middleware.CORS(middleware.Logging(middleware.Timeout(5 * time.Second)(authMiddleware(http.HandlerFunc(oh.CreateOrder)))))
+	requireCustomer := middleware.RequireRoles(auth.RoleCustomer)
+	var placeOrder http.Handler = http.HandlerFunc(oh.CreateOrder)
+	if requireVerifiedEmail {
+		placeOrder = middleware.RequireVerifiedEmail(placeOrder)
+	}
+	createOrder := middleware.CORS(middleware.Logging(middleware.Timeout(5 * time.Second)(authMiddleware(requireCustomer(placeOrder)))))
 	userOrders := middleware.CORS(middleware.Logging(middleware.Timeout(3 * time.Second)(authMiddleware(http.HandlerFunc(oh.GetUserOrders)))))
 	userOrderByID := middleware.CORS(middleware.Logging(middleware.Timeout(3 * time.Second)(authMiddleware(http.HandlerFunc(oh.GetUserOrderByID)))))
 	orderEvents := middleware.CORS(middleware.Logging(sseAuthMiddleware(http.HandlerFunc(sseHandler.StreamOrderStatus))))
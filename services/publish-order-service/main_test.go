@@ -15,6 +15,8 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/icl00ud/velure/services/publish-order-service/internal/handler"
 	"github.com/icl00ud/velure/services/publish-order-service/internal/middleware"
+	"github.com/icl00ud/velure/shared/auth"
+	"github.com/icl00ud/velure/shared/auth/authtest"
 	"github.com/icl00ud/velure/shared/logger"
 
 	"github.com/icl00ud/velure/services/publish-order-service/internal/config"
@@ -25,16 +27,16 @@ import (
 )
 
 func TestRegisterRoutes_CanonicalOnly(t *testing.T) {
-	const jwtSecret = "test-secret"
+	issuer := authtest.NewIssuer(t)
 
 	svc := &routingStubService{}
 	oh := handler.NewOrderHandler(svc)
 	sse := handler.NewSSEHandler(svc)
 
 	mux := http.NewServeMux()
-	registerRoutes(mux, oh, sse, middleware.Auth(jwtSecret), middleware.SSEAuth(jwtSecret))
+	registerRoutes(mux, oh, sse, middleware.Auth(issuer.Keys(), nil), middleware.SSEAuth(issuer.Keys(), nil), false)
 
-	authToken := issueTestJWT(t, jwtSecret, "user-1")
+	authToken := issuer.Token(t, "user-1")
 
 	tests := []struct {
 		name           string
@@ -92,16 +94,47 @@ func TestRegisterRoutes_CanonicalOnly(t *testing.T) {
 	}
 }
 
-func issueTestJWT(t *testing.T, secret, subject string) string {
-	t.Helper()
+func TestRegisterRoutes_RequireVerifiedEmail(t *testing.T) {
+	issuer := authtest.NewIssuer(t)
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: subject})
-	signed, err := token.SignedString([]byte(secret))
-	if err != nil {
-		t.Fatalf("failed to sign jwt: %v", err)
+	svc := &routingStubService{}
+	mux := http.NewServeMux()
+	registerRoutes(mux, handler.NewOrderHandler(svc), handler.NewSSEHandler(svc), middleware.Auth(issuer.Keys(), nil), middleware.SSEAuth(issuer.Keys(), nil), true)
+
+	token := func(verified bool) string {
+		return issuer.Sign(t, auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "user-1",
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+			Roles:         []string{auth.RoleCustomer},
+			EmailVerified: verified,
+		})
+	}
+	placeOrder := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/orders", strings.NewReader(`[{"product_id":"p1","quantity":1}]`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr.Code
 	}
 
-	return signed
+	if code := placeOrder(token(false)); code != http.StatusForbidden {
+		t.Fatalf("expected unverified users to be rejected, got %d", code)
+	}
+	if code := placeOrder(token(true)); code != http.StatusCreated {
+		t.Fatalf("expected verified users to place orders, got %d", code)
+	}
+
+	// Reading orders does not need a verified email.
+	req := httptest.NewRequest(http.MethodGet, "/api/me/orders", nil)
+	req.Header.Set("Authorization", "Bearer "+token(false))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected order history to stay readable, got %d", rr.Code)
+	}
 }
 
 type routingStubService struct {
@@ -136,7 +169,7 @@ func TestRunWithInjectedDependencies(t *testing.T) {
 		Exchange:    "orders",
 		Queue:       "queue",
 		Workers:     1,
-		JWTSecret:   "secret",
+		JWKSURL:     "http://auth.invalid/.well-known/jwks.json",
 	}
 
 	fakeRepo := &stubRepository{db: &sql.DB{}}
@@ -217,7 +250,7 @@ func TestMainUsesInjectedFactoryAndHandlesSignal(t *testing.T) {
 		Exchange:    "orders",
 		Queue:       "queue",
 		Workers:     1,
-		JWTSecret:   "secret",
+		JWKSURL:     "http://auth.invalid/.well-known/jwks.json",
 	}
 
 	fakeRepo := &stubRepository{db: &sql.DB{}}
@@ -276,7 +309,7 @@ func TestRunReturnsErrorWhenRepositoryCreationFails(t *testing.T) {
 				Exchange:    "orders",
 				Queue:       "queue",
 				Workers:     1,
-				JWTSecret:   "secret",
+				JWKSURL:     "http://auth.invalid/.well-known/jwks.json",
 			}, nil
 		},
 		newLogger: func() *logger.Logger { return logger.NewNop() },
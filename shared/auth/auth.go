@@ -0,0 +1,156 @@
+// Package auth holds the access-token claims and role checks shared by every
+// Velure service, so a token minted by auth-service is interpreted the same
+// way by product-service, publish-order-service and auth-service itself.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Roles a user can carry. Admin implicitly satisfies every role check.
+const (
+	RoleCustomer       = "customer"
+	RoleAdmin          = "admin"
+	RoleCatalogManager = "catalog-manager"
+)
+
+// KnownRoles lists every role auth-service can grant.
+var KnownRoles = []string{RoleCustomer, RoleAdmin, RoleCatalogManager}
+
+// Scopes a machine client can be granted. Unlike roles they are never given
+// to users.
+const (
+	ScopeInventoryWrite = "inventory:write"
+	ScopeUsersRead      = "users:read"
+)
+
+// KnownScopes lists every scope auth-service can grant.
+var KnownScopes = []string{ScopeInventoryWrite, ScopeUsersRead}
+
+// Claims are the claims carried by a Velure access token.
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+	// EmailVerified tells whether the user has confirmed their email address.
+	// Absent (false) on tokens minted before verification existed.
+	EmailVerified bool `json:"email_verified,omitempty"`
+	// SessionID identifies the login the token was issued for and survives
+	// refreshes. Absent on tokens minted before sessions were tracked in
+	// tokens and on client tokens.
+	SessionID string `json:"sid,omitempty"`
+	// ClientID is set on tokens issued to a machine client through the
+	// client_credentials grant. Their subject is the client, not a user.
+	ClientID string `json:"client_id,omitempty"`
+	// Scope is the space-separated list of scopes granted to the client.
+	Scope string `json:"scope,omitempty"`
+	// Actor is set on impersonation tokens: an admin acting as the subject,
+	// for example support staff reproducing a customer's problem.
+	Actor *Actor `json:"act,omitempty"`
+}
+
+// Actor is the party acting on the subject's behalf (the act claim of RFC
+// 8693). Subject is the acting user's ID.
+type Actor struct {
+	Subject string `json:"sub"`
+}
+
+// IsImpersonated reports whether someone other than the subject is acting
+// with the token.
+func (c *Claims) IsImpersonated() bool {
+	return c.Actor != nil
+}
+
+// IsClient reports whether the token was issued to a machine client rather
+// than a user.
+func (c *Claims) IsClient() bool {
+	return c.ClientID != ""
+}
+
+// HasScope reports whether the token grants scope.
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(strings.Fields(c.Scope), scope)
+}
+
+// EffectiveRoles returns the token's roles. Tokens minted before roles were
+// introduced carry none and are treated as plain customers, the least
+// privileged role. Machine clients have scopes instead and no role at all.
+func (c *Claims) EffectiveRoles() []string {
+	if c.IsClient() {
+		return nil
+	}
+	if len(c.Roles) == 0 {
+		return []string{RoleCustomer}
+	}
+	return c.Roles
+}
+
+// IsKnownRole reports whether role is one auth-service can grant.
+func IsKnownRole(role string) bool {
+	for _, known := range KnownRoles {
+		if role == known {
+			return true
+		}
+	}
+	return false
+}
+
+// IsKnownScope reports whether scope is one auth-service can grant.
+func IsKnownScope(scope string) bool {
+	return slices.Contains(KnownScopes, scope)
+}
+
+// HasAnyRole reports whether granted satisfies at least one of required.
+// An empty required list only demands authentication and always passes;
+// admin passes every check.
+func HasAnyRole(granted []string, required ...string) bool {
+	if len(required) == 0 {
+		return true
+	}
+	for _, g := range granted {
+		if g == RoleAdmin {
+			return true
+		}
+		for _, r := range required {
+			if g == r {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ErrInvalidToken is returned for any token that fails verification.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Algorithms accepted for access tokens. HMAC is deliberately absent: a shared
+// secret would let every verifying service mint tokens too.
+var signingAlgorithms = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+
+// Parse verifies an access token against the public key named by its kid
+// header and returns its claims.
+func Parse(tokenString string, keys KeySet) (*Claims, error) {
+	if keys == nil {
+		return nil, fmt.Errorf("%w: no key set configured", ErrInvalidToken)
+	}
+
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("missing kid header")
+		}
+		return keys.Key(kid)
+	}, jwt.WithValidMethods(signingAlgorithms))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestHasAnyRole(t *testing.T) {
+	tests := []struct {
+		name     string
+		granted  []string
+		required []string
+		want     bool
+	}{
+		{"no requirement", nil, nil, true},
+		{"matching role", []string{RoleCatalogManager}, []string{RoleCatalogManager}, true},
+		{"one of several", []string{RoleCustomer}, []string{RoleCatalogManager, RoleCustomer}, true},
+		{"admin passes everything", []string{RoleAdmin}, []string{RoleCatalogManager}, true},
+		{"missing role", []string{RoleCustomer}, []string{RoleCatalogManager}, false},
+		{"no roles", nil, []string{RoleCustomer}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasAnyRole(tt.granted, tt.required...); got != tt.want {
+				t.Fatalf("HasAnyRole(%v, %v) = %v, want %v", tt.granted, tt.required, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaims_EffectiveRoles(t *testing.T) {
+	legacy := &Claims{}
+	if got := legacy.EffectiveRoles(); len(got) != 1 || got[0] != RoleCustomer {
+		t.Fatalf("legacy token roles = %v, want [customer]", got)
+	}
+
+	admin := &Claims{Roles: []string{RoleAdmin}}
+	if got := admin.EffectiveRoles(); len(got) != 1 || got[0] != RoleAdmin {
+		t.Fatalf("admin token roles = %v, want [admin]", got)
+	}
+
+	client := &Claims{ClientID: "process-order-service", Scope: ScopeInventoryWrite}
+	if got := client.EffectiveRoles(); len(got) != 0 {
+		t.Fatalf("client token roles = %v, want none", got)
+	}
+}
+
+func TestClaims_HasScope(t *testing.T) {
+	claims := &Claims{ClientID: "svc", Scope: "orders:read " + ScopeInventoryWrite}
+	if !claims.IsClient() || !claims.HasScope(ScopeInventoryWrite) {
+		t.Fatalf("expected %q to grant %s", claims.Scope, ScopeInventoryWrite)
+	}
+	if claims.HasScope("inventory") {
+		t.Fatal("scopes must match whole words")
+	}
+	if user := (&Claims{Roles: []string{RoleAdmin}}); user.IsClient() || user.HasScope(ScopeInventoryWrite) {
+		t.Fatal("user tokens carry no scopes")
+	}
+}
+
+func newTestKeys(t *testing.T) (ed25519.PrivateKey, JWKS) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk, err := NewJWK("k1", pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv, JWKS{Keys: []JWK{jwk}}
+}
+
+func signEdDSA(t *testing.T, key ed25519.PrivateKey, kid string, claims Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestParse(t *testing.T) {
+	priv, keys := newTestKeys(t)
+
+	valid := signEdDSA(t, priv, "k1", Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "42",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Roles: []string{RoleCatalogManager},
+	})
+
+	claims, err := Parse(valid, keys)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if claims.Subject != "42" || len(claims.Roles) != 1 || claims.Roles[0] != RoleCatalogManager {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+	if claims.IsImpersonated() {
+		t.Fatal("a token without act must not be impersonated")
+	}
+
+	impersonated := signEdDSA(t, priv, "k1", Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "42",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Actor: &Actor{Subject: "1"},
+	})
+	if claims, err := Parse(impersonated, keys); err != nil || !claims.IsImpersonated() || claims.Actor.Subject != "1" {
+		t.Fatalf("expected the actor to be read, got %+v (err = %v)", claims, err)
+	}
+
+	_, otherKeys := newTestKeys(t)
+	if _, err := Parse(valid, otherKeys); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken for a foreign key, got %v", err)
+	}
+
+	if _, err := Parse(valid, nil); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken without a key set, got %v", err)
+	}
+
+	expired := signEdDSA(t, priv, "k1", Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   "42",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+	}})
+	if _, err := Parse(expired, keys); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken for expired token, got %v", err)
+	}
+
+	noKid := signEdDSA(t, priv, "", Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "42"}})
+	if _, err := Parse(noKid, keys); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken without kid, got %v", err)
+	}
+
+	unknownKid := signEdDSA(t, priv, "k2", Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "42"}})
+	if _, err := Parse(unknownKid, keys); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken for unknown kid, got %v", err)
+	}
+}
+
+func TestParse_RejectsHMAC(t *testing.T) {
+	_, keys := newTestKeys(t)
+
+	// An HS256 token "signed" with the public key bytes must not verify.
+	x := []byte(keys.Keys[0].X)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "42"}})
+	token.Header["kid"] = "k1"
+	s, err := token.SignedString(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Parse(s, keys); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken for HS256 token, got %v", err)
+	}
+}
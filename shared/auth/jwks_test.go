@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJWK_RoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaJWK, err := NewJWK("rsa", &rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("NewJWK(rsa) error = %v", err)
+	}
+	if rsaJWK.Kty != "RSA" || rsaJWK.Alg != "RS256" {
+		t.Fatalf("unexpected RSA JWK: %+v", rsaJWK)
+	}
+	edJWK, err := NewJWK("ed", edPub)
+	if err != nil {
+		t.Fatalf("NewJWK(ed25519) error = %v", err)
+	}
+	if edJWK.Kty != "OKP" || edJWK.Crv != "Ed25519" || edJWK.Alg != "EdDSA" {
+		t.Fatalf("unexpected Ed25519 JWK: %+v", edJWK)
+	}
+
+	raw, err := json.Marshal(JWKS{Keys: []JWK{rsaJWK, edJWK}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc JWKS
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := doc.Key("rsa")
+	if err != nil {
+		t.Fatalf("Key(rsa) error = %v", err)
+	}
+	if !rsaKey.PublicKey.Equal(got) {
+		t.Fatal("RSA key did not survive the round trip")
+	}
+
+	got, err = doc.Key("ed")
+	if err != nil {
+		t.Fatalf("Key(ed) error = %v", err)
+	}
+	if !edPub.Equal(got) {
+		t.Fatal("Ed25519 key did not survive the round trip")
+	}
+
+	if _, err := doc.Key("missing"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestJWK_RejectsMalformedKeys(t *testing.T) {
+	bad := []JWK{
+		{Kty: "EC", Kid: "ec"},
+		{Kty: "OKP", Kid: "x448", Crv: "X448", X: "AAAA"},
+		{Kty: "OKP", Kid: "short", Crv: "Ed25519", X: "AAAA"},
+		{Kty: "RSA", Kid: "nomod", E: "AQAB"},
+	}
+	for _, k := range bad {
+		if _, err := k.PublicKey(); err == nil {
+			t.Errorf("expected error for %+v", k)
+		}
+	}
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	pub1, _, _ := ed25519.GenerateKey(rand.Reader)
+	pub2, _, _ := ed25519.GenerateKey(rand.Reader)
+	k1, _ := NewJWK("k1", pub1)
+	k2, _ := NewJWK("k2", pub2)
+
+	var (
+		fetches atomic.Int32
+		doc     atomic.Value
+		fail    atomic.Bool
+	)
+	doc.Store(JWKS{Keys: []JWK{k1}})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(doc.Load())
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	keys := NewRemoteKeySet(srv.URL, time.Minute)
+	keys.now = func() time.Time { return now }
+
+	if _, err := keys.Key("k1"); err != nil {
+		t.Fatalf("Key(k1) error = %v", err)
+	}
+	if _, err := keys.Key("k1"); err != nil {
+		t.Fatalf("cached Key(k1) error = %v", err)
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Fatalf("expected 1 fetch, got %d", got)
+	}
+
+	// A rotated-in key is unknown until the next refresh, which an unknown
+	// kid triggers once the refresh throttle has passed.
+	doc.Store(JWKS{Keys: []JWK{k1, k2}})
+	if _, err := keys.Key("k2"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected throttled lookup to miss, got %v", err)
+	}
+	now = now.Add(minJWKSRefreshInterval)
+	if _, err := keys.Key("k2"); err != nil {
+		t.Fatalf("Key(k2) after rotation error = %v", err)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Fatalf("expected 2 fetches, got %d", got)
+	}
+
+	// Once stale, a failed refresh keeps serving the last good keys.
+	fail.Store(true)
+	now = now.Add(2 * time.Minute)
+	if _, err := keys.Key("k1"); err != nil {
+		t.Fatalf("expected stale key to be served, got %v", err)
+	}
+	if got := fetches.Load(); got != 3 {
+		t.Fatalf("expected 3 fetches, got %d", got)
+	}
+}
+
+func TestRemoteKeySet_FetchesOutsideTheLock(t *testing.T) {
+	pub1, _, _ := ed25519.GenerateKey(rand.Reader)
+	pub2, _, _ := ed25519.GenerateKey(rand.Reader)
+	k1, _ := NewJWK("k1", pub1)
+	k2, _ := NewJWK("k2", pub2)
+
+	var (
+		fetches atomic.Int32
+		block   atomic.Bool
+	)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if block.Load() {
+			<-release
+		}
+		_ = json.NewEncoder(w).Encode(JWKS{Keys: []JWK{k1, k2}})
+	}))
+	defer srv.Close()
+
+	keys := NewRemoteKeySet(srv.URL, time.Minute)
+	keys.fetchedAt = time.Now()
+	keys.cached = JWKS{Keys: []JWK{k1}}
+
+	// An unknown kid starts a fetch that hangs; a second one waits for it.
+	block.Store(true)
+	results := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := keys.Key("k2")
+			results <- err
+		}()
+	}
+	for deadline := time.Now().Add(time.Second); fetches.Load() == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("expected a fetch to start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := keys.Key("k1")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Key(k1) error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a known key waited for the fetch")
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-results; err != nil {
+			t.Fatalf("Key(k2) error = %v", err)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Fatalf("expected 1 fetch, got %d", got)
+	}
+}
+
+func TestRemoteKeySet_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	keys := NewRemoteKeySet(srv.URL, 0)
+	if _, err := keys.Key("k1"); err == nil {
+		t.Fatal("expected an error when the JWKS endpoint is unreachable")
+	}
+}
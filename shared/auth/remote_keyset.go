@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultJWKSCacheTTL is how long a fetched JWKS is trusted before the
+	// next lookup refreshes it.
+	DefaultJWKSCacheTTL = 5 * time.Minute
+
+	// minJWKSRefreshInterval stops tokens with made-up kids from turning every
+	// request into a fetch against auth-service.
+	minJWKSRefreshInterval = 10 * time.Second
+)
+
+// RemoteKeySet is a KeySet backed by auth-service's JWKS endpoint. Keys are
+// cached for TTL; an unknown kid triggers an early refresh so a freshly
+// rotated key is picked up without waiting for the cache to expire. When a
+// refresh fails the last good document keeps being served. Only one fetch
+// runs at a time, and lookups of known keys never wait for it.
+type RemoteKeySet struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+	now    func() time.Time
+
+	mu          sync.Mutex
+	cached      JWKS
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	fetching    chan struct{} // closed when the running fetch is done
+}
+
+// NewRemoteKeySet returns a KeySet that fetches keys from url. A non-positive
+// ttl selects DefaultJWKSCacheTTL.
+func NewRemoteKeySet(url string, ttl time.Duration) *RemoteKeySet {
+	if ttl <= 0 {
+		ttl = DefaultJWKSCacheTTL
+	}
+	return &RemoteKeySet{
+		url:    url,
+		ttl:    ttl,
+		client: &http.Client{Timeout: 5 * time.Second},
+		now:    time.Now,
+	}
+}
+
+// Key returns the public key for kid, fetching the JWKS when the cache is
+// stale or does not know kid yet.
+func (r *RemoteKeySet) Key(kid string) (crypto.PublicKey, error) {
+	r.mu.Lock()
+
+	now := r.now()
+	stale := r.fetchedAt.IsZero() || now.Sub(r.fetchedAt) >= r.ttl
+
+	key, err := r.cached.Key(kid)
+	if err == nil && !stale {
+		r.mu.Unlock()
+		return key, nil
+	}
+
+	// Another lookup is already fetching: a known key is served as is, an
+	// unknown one waits for the result.
+	if fetching := r.fetching; fetching != nil {
+		r.mu.Unlock()
+		if err == nil {
+			return key, nil
+		}
+		<-fetching
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.cached.Key(kid)
+	}
+
+	if !r.lastAttempt.IsZero() && now.Sub(r.lastAttempt) < minJWKSRefreshInterval {
+		r.mu.Unlock()
+		return key, err
+	}
+
+	r.lastAttempt = now
+	fetching := make(chan struct{})
+	r.fetching = fetching
+	r.mu.Unlock()
+
+	doc, fetchErr := r.fetch()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fetching = nil
+	close(fetching)
+	if fetchErr != nil {
+		if key != nil {
+			return key, nil
+		}
+		return nil, fetchErr
+	}
+	r.cached = doc
+	r.fetchedAt = now
+
+	return r.cached.Key(kid)
+}
+
+func (r *RemoteKeySet) fetch() (JWKS, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.client.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return JWKS{}, fmt.Errorf("jwks request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return JWKS{}, fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return JWKS{}, fmt.Errorf("fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var doc JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return JWKS{}, fmt.Errorf("decode jwks: %w", err)
+	}
+	return doc, nil
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRevocationList(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	ctx := context.Background()
+	list := NewRevocationList(client)
+	claims := &Claims{RegisteredClaims: jwt.RegisteredClaims{ID: "jti-1"}}
+
+	if err := list.Check(ctx, claims); err != nil {
+		t.Fatalf("Check() before revoking = %v", err)
+	}
+	if err := list.Revoke(ctx, "jti-1", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if err := list.Check(ctx, claims); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("Check() after revoking = %v, want ErrTokenRevoked", err)
+	}
+
+	// The entry lives exactly as long as the token would have.
+	mr.FastForward(time.Minute)
+	if err := list.Check(ctx, claims); err != nil {
+		t.Fatalf("Check() after expiry = %v", err)
+	}
+
+	if err := list.Revoke(ctx, "jti-2", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Revoke() of an expired token error = %v", err)
+	}
+	if mr.Exists(revokedKeyPrefix + "jti-2") {
+		t.Fatal("expired tokens must not be stored")
+	}
+
+	if err := list.Check(ctx, &Claims{}); err != nil {
+		t.Fatalf("tokens without jti must pass, got %v", err)
+	}
+	var disabled *RevocationList
+	if err := disabled.Check(ctx, claims); err != nil {
+		t.Fatalf("a nil list must accept every token, got %v", err)
+	}
+
+	mr.Close()
+	if err := list.Check(ctx, claims); err == nil || errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("expected a lookup error with redis down, got %v", err)
+	}
+}
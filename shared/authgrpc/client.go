@@ -0,0 +1,197 @@
+// Package authgrpc is the Go client for auth-service's gRPC API: token
+// introspection and user lookups. The protocol itself lives in authv1,
+// generated from authv1/auth.proto.
+package authgrpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative authv1/auth.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/icl00ud/velure/shared/authgrpc/authv1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// MaxBatchSize is the most user IDs BatchGetUsers accepts in one call.
+const MaxBatchSize = 100
+
+// ErrUserNotFound is returned by GetUser for an ID without a user.
+var ErrUserNotFound = errors.New("user not found")
+
+// Introspection describes an access token. Only Active is set for tokens
+// that are invalid, expired or revoked.
+type Introspection struct {
+	Active        bool
+	Subject       string
+	UserID        uint // 0 for client tokens
+	ClientID      string
+	Roles         []string
+	Scopes        []string
+	SessionID     string
+	EmailVerified bool
+	IssuedAt      time.Time
+	ExpiresAt     time.Time
+	// Actor is the ID of the admin impersonating the user, empty unless the
+	// token is an impersonation token.
+	Actor string
+}
+
+// User is an account as auth-service reports it.
+type User struct {
+	ID               uint
+	Email            string
+	Name             string
+	Roles            []string
+	EmailVerified    bool
+	TwoFactorEnabled bool
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	DisabledAt       *time.Time
+}
+
+// TokenFunc returns the access token a call is made with, typically a
+// client_credentials token carrying the users:read scope.
+type TokenFunc func(ctx context.Context) (string, error)
+
+// Client calls auth-service over gRPC. It is safe for concurrent use.
+type Client struct {
+	conn *grpc.ClientConn
+	rpc  authv1.AuthServiceClient
+}
+
+// Dial returns a client for the auth-service gRPC endpoint at target, such as
+// "auth-service:3021". Calls are made in plaintext, for use inside the
+// cluster; token authenticates GetUser and BatchGetUsers and may be nil if
+// only Introspect is used. opts are appended to the defaults.
+func Dial(target string, token TokenFunc, opts ...grpc.DialOption) (*Client, error) {
+	defaults := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if token != nil {
+		defaults = append(defaults, grpc.WithPerRPCCredentials(bearer(token)))
+	}
+	conn, err := grpc.NewClient(target, append(defaults, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("dial auth-service: %w", err)
+	}
+	return &Client{conn: conn, rpc: authv1.NewAuthServiceClient(conn)}, nil
+}
+
+// Close closes the underlying connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Introspect asks auth-service about token. An inactive token is not an
+// error; errors mean auth-service could not be asked.
+func (c *Client) Introspect(ctx context.Context, token string) (*Introspection, error) {
+	resp, err := c.rpc.Introspect(ctx, &authv1.IntrospectRequest{Token: token})
+	if err != nil {
+		return nil, fmt.Errorf("introspect token: %w", err)
+	}
+	if !resp.GetActive() {
+		return &Introspection{}, nil
+	}
+
+	result := &Introspection{
+		Active:        true,
+		Subject:       resp.GetSubject(),
+		ClientID:      resp.GetClientId(),
+		Roles:         resp.GetRoles(),
+		Scopes:        resp.GetScopes(),
+		SessionID:     resp.GetSessionId(),
+		EmailVerified: resp.GetEmailVerified(),
+		IssuedAt:      timeOf(resp.GetIssuedAt()),
+		ExpiresAt:     timeOf(resp.GetExpiresAt()),
+		Actor:         resp.GetActor(),
+	}
+	if result.ClientID == "" {
+		if id, err := strconv.ParseUint(result.Subject, 10, 32); err == nil {
+			result.UserID = uint(id)
+		}
+	}
+	return result, nil
+}
+
+// GetUser returns the user with the given ID, or ErrUserNotFound.
+func (c *Client) GetUser(ctx context.Context, id uint) (*User, error) {
+	resp, err := c.rpc.GetUser(ctx, &authv1.GetUserRequest{Id: uint64(id)})
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+	return userOf(resp.GetUser()), nil
+}
+
+// BatchGetUsers returns the users with the given IDs, keyed by ID. IDs
+// without a user are left out. Lists longer than MaxBatchSize are split into
+// several calls.
+func (c *Client) BatchGetUsers(ctx context.Context, ids []uint) (map[uint]*User, error) {
+	users := make(map[uint]*User, len(ids))
+	for start := 0; start < len(ids); start += MaxBatchSize {
+		end := min(start+MaxBatchSize, len(ids))
+		req := &authv1.BatchGetUsersRequest{Ids: make([]uint64, 0, end-start)}
+		for _, id := range ids[start:end] {
+			req.Ids = append(req.Ids, uint64(id))
+		}
+
+		resp, err := c.rpc.BatchGetUsers(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("batch get users: %w", err)
+		}
+		for _, u := range resp.GetUsers() {
+			users[uint(u.GetId())] = userOf(u)
+		}
+	}
+	return users, nil
+}
+
+func userOf(u *authv1.User) *User {
+	user := &User{
+		ID:               uint(u.GetId()),
+		Email:            u.GetEmail(),
+		Name:             u.GetName(),
+		Roles:            u.GetRoles(),
+		EmailVerified:    u.GetEmailVerified(),
+		TwoFactorEnabled: u.GetTwoFactorEnabled(),
+		CreatedAt:        timeOf(u.GetCreatedAt()),
+		UpdatedAt:        timeOf(u.GetUpdatedAt()),
+	}
+	if u.GetDisabledAt() != nil {
+		disabledAt := timeOf(u.GetDisabledAt())
+		user.DisabledAt = &disabledAt
+	}
+	return user
+}
+
+func timeOf(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// bearer sends the token from fn as "authorization: Bearer <token>" metadata.
+type bearer TokenFunc
+
+func (b bearer) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	token, err := b(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get access token: %w", err)
+	}
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+// RequireTransportSecurity allows plaintext connections inside the cluster.
+func (bearer) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,150 @@
+package authgrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/icl00ud/velure/shared/authgrpc/authv1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type fakeServer struct {
+	authv1.UnimplementedAuthServiceServer
+	authorization []string
+	batches       [][]uint64
+}
+
+func (f *fakeServer) Introspect(ctx context.Context, req *authv1.IntrospectRequest) (*authv1.IntrospectResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	f.authorization = md.Get("authorization")
+	switch req.GetToken() {
+	case "user-token":
+		return &authv1.IntrospectResponse{
+			Active:    true,
+			Subject:   "42",
+			Roles:     []string{"customer"},
+			SessionId: "family-1",
+			ExpiresAt: timestamppb.New(time.Unix(1700000000, 0)),
+		}, nil
+	case "client-token":
+		return &authv1.IntrospectResponse{Active: true, Subject: "orders", ClientId: "orders", Scopes: []string{"users:read"}}, nil
+	}
+	return &authv1.IntrospectResponse{}, nil
+}
+
+func (f *fakeServer) GetUser(_ context.Context, req *authv1.GetUserRequest) (*authv1.GetUserResponse, error) {
+	if req.GetId() != 42 {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return &authv1.GetUserResponse{User: &authv1.User{Id: 42, Email: "a@example.com", DisabledAt: timestamppb.New(time.Unix(1700000000, 0))}}, nil
+}
+
+func (f *fakeServer) BatchGetUsers(_ context.Context, req *authv1.BatchGetUsersRequest) (*authv1.BatchGetUsersResponse, error) {
+	f.batches = append(f.batches, req.GetIds())
+	resp := &authv1.BatchGetUsersResponse{}
+	for _, id := range req.GetIds() {
+		if id%2 == 0 {
+			resp.Users = append(resp.Users, &authv1.User{Id: id})
+		} else {
+			resp.MissingIds = append(resp.MissingIds, id)
+		}
+	}
+	return resp, nil
+}
+
+func newTestClient(t *testing.T, token TokenFunc) (*Client, *fakeServer) {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	fake := &fakeServer{}
+	srv := grpc.NewServer()
+	authv1.RegisterAuthServiceServer(srv, fake)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	client, err := Dial("passthrough:///bufnet", token, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client, fake
+}
+
+func TestClient_Introspect(t *testing.T) {
+	client, fake := newTestClient(t, func(context.Context) (string, error) { return "service-token", nil })
+	ctx := context.Background()
+
+	got, err := client.Introspect(ctx, "user-token")
+	if err != nil {
+		t.Fatalf("Introspect() error = %v", err)
+	}
+	if !got.Active || got.UserID != 42 || got.SessionID != "family-1" || len(got.Roles) != 1 || !got.ExpiresAt.Equal(time.Unix(1700000000, 0)) {
+		t.Fatalf("unexpected introspection %+v", got)
+	}
+	if len(fake.authorization) != 1 || fake.authorization[0] != "Bearer service-token" {
+		t.Fatalf("expected the service token to be sent, got %v", fake.authorization)
+	}
+
+	got, err = client.Introspect(ctx, "client-token")
+	if err != nil || !got.Active || got.UserID != 0 || got.ClientID != "orders" {
+		t.Fatalf("unexpected client introspection %+v, err = %v", got, err)
+	}
+
+	got, err = client.Introspect(ctx, "garbage")
+	if err != nil || got.Active {
+		t.Fatalf("expected an inactive token, got %+v, err = %v", got, err)
+	}
+}
+
+func TestClient_GetUser(t *testing.T) {
+	client, _ := newTestClient(t, nil)
+	ctx := context.Background()
+
+	user, err := client.GetUser(ctx, 42)
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+	if user.ID != 42 || user.Email != "a@example.com" || user.DisabledAt == nil {
+		t.Fatalf("unexpected user %+v", user)
+	}
+
+	if _, err := client.GetUser(ctx, 7); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestClient_BatchGetUsers(t *testing.T) {
+	client, fake := newTestClient(t, nil)
+
+	ids := make([]uint, 0, 150)
+	for id := uint(1); id <= 150; id++ {
+		ids = append(ids, id)
+	}
+	users, err := client.BatchGetUsers(context.Background(), ids)
+	if err != nil {
+		t.Fatalf("BatchGetUsers() error = %v", err)
+	}
+	if len(users) != 75 || users[150] == nil || users[1] != nil {
+		t.Fatalf("expected the 75 even IDs, got %d users", len(users))
+	}
+	if len(fake.batches) != 2 || len(fake.batches[0]) != MaxBatchSize || len(fake.batches[1]) != 50 {
+		t.Fatalf("expected the IDs to be split into batches of %d", MaxBatchSize)
+	}
+}
+
+func TestClient_TokenError(t *testing.T) {
+	client, _ := newTestClient(t, func(context.Context) (string, error) { return "", errors.New("auth down") })
+	if _, err := client.GetUser(context.Background(), 42); err == nil || status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected the call to fail without a token, got %v", err)
+	}
+}